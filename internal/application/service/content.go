@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/event"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+)
+
+// ErrNotRDF is returned when an operation needs RDF data and the resource holds other content
+var ErrNotRDF = errors.New("resource is not RDF")
+
+// IsRDF reports whether contentType is a supported RDF format. Bodies of
+// other media types are stored as non-RDF resources.
+func (s *ResourceService) IsRDF(contentType string) bool {
+	_, err := s.mediaType(contentType)
+	return err == nil
+}
+
+// CreateContent streams body into a new non-RDF resource inside
+// containerURI, naming it like Create does
+func (s *ResourceService) CreateContent(ctx context.Context, containerURI string, slug string, body io.Reader, contentType string) (entity.Resource, error) {
+	return s.createContent(ctx, containerURI, slug, func(uri string) (entity.Content, error) {
+		return s.storeContent(ctx, uri, body)
+	}, contentType)
+}
+
+// createContent creates a non-RDF resource inside containerURI from the
+// bytes store provides once the resource is named
+func (s *ResourceService) createContent(ctx context.Context, containerURI string, slug string, store func(uri string) (entity.Content, error), contentType string) (entity.Resource, error) {
+	containerURI = withTrailingSlash(containerURI)
+	uri, err := s.newChildURI(ctx, containerURI, slug, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkContentTarget(uri); err != nil {
+		return nil, err
+	}
+
+	content, err := store(uri)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := s.newContent(ctx, uri, content, contentType)
+	if err == nil {
+		err = s.save(ctx, resource)
+	}
+	if err != nil {
+		s.discardContent(ctx, uri, content)
+		return nil, err
+	}
+
+	s.logger.Info("Created resource", zap.String("uri", uri), zap.Int64("size", content.Size))
+	return resource, nil
+}
+
+// PutContent streams body into the non-RDF resource at uri, creating it or
+// replacing its data. created reports whether the resource did not exist
+// before or was deleted.
+func (s *ResourceService) PutContent(ctx context.Context, uri string, body io.Reader, contentType string) (resource entity.Resource, created bool, err error) {
+	if err := checkContentTarget(uri); err != nil {
+		return nil, false, err
+	}
+	content, err := s.storeContent(ctx, uri, body)
+	if err != nil {
+		return nil, false, err
+	}
+	if resource, created, err = s.putContent(ctx, uri, content, contentType); err != nil {
+		s.discardContent(ctx, uri, content)
+		return nil, false, err
+	}
+	return resource, created, nil
+}
+
+// putContent creates the non-RDF resource at uri from bytes already stored,
+// or replaces its data or content with them. The reference to the bytes is
+// released when the resource already has them.
+func (s *ResourceService) putContent(ctx context.Context, uri string, content entity.Content, contentType string) (resource entity.Resource, created bool, err error) {
+	existing, err := s.repository.GetByURI(ctx, uri)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, false, err
+	}
+
+	if existing == nil {
+		resource, err = s.newContent(ctx, uri, content, contentType)
+		created = true
+	} else {
+		if existing.IsDeleted() {
+			// Writing to a tombstone brings the resource back with the new content
+			existing.WithAuthor(authorFrom(ctx)).Restore()
+			created = true
+		}
+		resource, err = s.updateContent(ctx, existing, content, contentType)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	// Storing the same bytes again adds no version to hold the reference
+	stored := len(storedContent(resource.UncommittedEvents())) > 0
+	if err := s.save(ctx, resource); err != nil {
+		return nil, false, err
+	}
+	if !stored {
+		s.discardContent(ctx, uri, content)
+	}
+
+	s.logger.Info("Stored resource", zap.String("uri", uri), zap.Bool("created", created), zap.Int64("size", content.Size))
+	return resource, created, nil
+}
+
+// Open returns a reader of the bytes of a non-RDF resource. The caller
+// closes it.
+func (s *ResourceService) Open(ctx context.Context, resource entity.Resource) (io.ReadCloser, error) {
+	if !resource.IsNonRDF() {
+		return nil, fmt.Errorf("%w: %s has no content to open", ErrUnsupportedMediaType, resource.GetURI())
+	}
+	return s.blobs.Get(ctx, resource.GetContent().Key)
+}
+
+// OpenRange returns a reader of length bytes of a non-RDF resource,
+// starting at offset. The caller closes it.
+func (s *ResourceService) OpenRange(ctx context.Context, resource entity.Resource, offset int64, length int64) (io.ReadCloser, error) {
+	if !resource.IsNonRDF() {
+		return nil, fmt.Errorf("%w: %s has no content to open", ErrUnsupportedMediaType, resource.GetURI())
+	}
+	return s.blobs.GetRange(ctx, resource.GetContent().Key, offset, length)
+}
+
+// Link returns a URL clients can download the bytes of a non-RDF resource
+// from directly, or "" when the blob store cannot link to them and they
+// are served by the pod
+func (s *ResourceService) Link(ctx context.Context, resource entity.Resource) (string, error) {
+	linker, ok := s.blobs.(repository.BlobLinker)
+	if !ok || !resource.IsNonRDF() {
+		return "", nil
+	}
+	content := resource.GetContent()
+	return linker.Link(ctx, content.Key, content.Size, resource.GetContentType())
+}
+
+// checkContentTarget rejects non-RDF content for containers and auxiliary
+// resources, which must be RDF
+func checkContentTarget(uri string) error {
+	if strings.HasSuffix(uri, "/") || isAuxiliary(uri) {
+		return fmt.Errorf("%w: %s only accepts RDF", ErrUnsupportedMediaType, uri)
+	}
+	return nil
+}
+
+// newContent builds a non-RDF resource at uri from bytes already stored,
+// checking the container's shape trees allow it
+func (s *ResourceService) newContent(ctx context.Context, uri string, content entity.Content, contentType string) (entity.Resource, error) {
+	constraints, err := s.shapes.ContentConstraintsFor(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, err := contentMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := constraints.Apply(entity.NewBasicResourceWithValidator(s.rdfService)).
+		WithBase(uri).
+		WithAuthor(authorFrom(ctx)).
+		FromContent(content, mediaType).
+		WithURI(uri)
+	if resource.HasErrors() {
+		return nil, errors.Join(resource.GetErrors()...)
+	}
+	return resource, nil
+}
+
+// updateContent replaces the data or content of an existing resource with
+// bytes already stored
+func (s *ResourceService) updateContent(ctx context.Context, resource entity.Resource, content entity.Content, contentType string) (entity.Resource, error) {
+	constraints, err := s.shapes.ContentConstraintsFor(ctx, resource.GetURI())
+	if err != nil {
+		return nil, err
+	}
+	mediaType, err := contentMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	constraints.Apply(resource).WithAuthor(authorFrom(ctx)).UpdateContent(content, mediaType)
+	if resource.HasErrors() {
+		return nil, errors.Join(resource.GetErrors()...)
+	}
+	return resource, nil
+}
+
+// contentMediaType checks contentType is a media type and returns it in
+// canonical form, keeping its parameters
+func contentMediaType(contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+	return mime.FormatMediaType(mediaType, params), nil
+}
+
+// storeContent streams body into a new blob for the resource at uri,
+// hashing it on the way, and references it by its digest. Bytes the pod
+// already holds are kept once.
+func (s *ResourceService) storeContent(ctx context.Context, uri string, body io.Reader) (entity.Content, error) {
+	key, err := blobKey()
+	if err != nil {
+		return entity.Content{}, err
+	}
+	hash := sha256.New()
+	size, err := s.blobs.Put(ctx, key, io.TeeReader(body, hash))
+	if err != nil {
+		return entity.Content{}, fmt.Errorf("failed to store content: %w", err)
+	}
+	return s.addressContent(ctx, uri, entity.Content{Key: key, Size: size, Digest: hex.EncodeToString(hash.Sum(nil))})
+}
+
+// copyContent references the bytes of content once more for a copy of its
+// resource at uri. Bytes stored before they were addressed by digest are
+// copied.
+func (s *ResourceService) copyContent(ctx context.Context, uri string, content entity.Content) (entity.Content, error) {
+	if content.Digest != "" {
+		return s.retainDigest(ctx, uri, content.Digest)
+	}
+	reader, err := s.blobs.Get(ctx, content.Key)
+	if err != nil {
+		return entity.Content{}, err
+	}
+	defer reader.Close()
+	return s.storeContent(ctx, uri, reader)
+}
+
+// discardContent drops a reference to content of the resource at uri,
+// deleting its bytes once nothing refers to them. Failures are logged
+// since the bytes are only wasted space.
+func (s *ResourceService) discardContent(ctx context.Context, uri string, content entity.Content) {
+	var err error
+	if content.Digest != "" {
+		err = s.releaseContent(ctx, uri, content)
+	} else {
+		err = s.blobs.Delete(ctx, content.Key)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to delete unused content", zap.String("key", content.Key), zap.Error(err))
+	}
+}
+
+// storedContent returns the content stored by every version in events
+func storedContent(events []domain.Event) []entity.Content {
+	var contents []entity.Content
+	for _, evt := range events {
+		if stored, ok := evt.(*event.ResourceContentStoredEvent); ok {
+			contents = append(contents, entity.Content{Key: stored.Key(), Size: stored.Size(), Digest: stored.Digest()})
+		}
+	}
+	return contents
+}
+
+// blobKey returns a random key for a new blob
+func blobKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
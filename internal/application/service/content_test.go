@@ -0,0 +1,180 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestNonRDFContent(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const container = "https://pod.example.com/files/"
+	const uri = container + "hello.txt"
+
+	withFiles := func(t *testing.T) (*service.ResourceService, *service.SolidService, *repository.FileSystemBlobStore) {
+		blobs := repository.NewFileSystemBlobStore(t.TempDir())
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), blobs, rdfService, log)
+		_, _, err := resourceService.Put(context.Background(), container, `<> <http://purl.org/dc/terms/title> "Files" .`, "text/turtle")
+		require.NoError(t, err)
+		return resourceService, service.NewSolidService(&config.Config{}, log, resourceService, rdfService), blobs
+	}
+
+	serve := func(t *testing.T, handler func(context.Context, http.ResponseWriter, *http.Request) error, method, target, contentType, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		recorder := httptest.NewRecorder()
+		require.NoError(t, handler(request.Context(), recorder, request))
+		return recorder
+	}
+
+	t.Run("stores and streams bytes of any media type", func(t *testing.T) {
+		// Arrange
+		resourceService, solidService, _ := withFiles(t)
+
+		// Act
+		stored := serve(t, solidService.UpdateResource, http.MethodPut, uri, "text/plain; charset=utf-8", "hello")
+		recorder := serve(t, solidService.GetResource, http.MethodGet, uri, "", "")
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, stored.Code)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "hello", recorder.Body.String())
+		assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "5", recorder.Header().Get("Content-Length"))
+		assert.Equal(t, stored.Header().Get("ETag"), recorder.Header().Get("ETag"))
+		resource, _, err := resourceService.Get(context.Background(), uri, "text/turtle")
+		require.NoError(t, err)
+		assert.True(t, resource.IsNonRDF())
+		assert.Empty(t, resource.GetData(), "the bytes stay out of the event store")
+	})
+
+	t.Run("creates non-RDF resources in containers", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withFiles(t)
+		request := httptest.NewRequest(http.MethodPost, container, strings.NewReader("\x89PNG"))
+		request.Header.Set("Content-Type", "image/png")
+		request.Header.Set("Slug", "cat.png")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		require.NoError(t, solidService.CreateResource(request.Context(), recorder, request))
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, container+"cat.png", recorder.Header().Get("Location"))
+		fetched := serve(t, solidService.GetResource, http.MethodGet, container+"cat.png", "", "")
+		assert.Equal(t, "\x89PNG", fetched.Body.String())
+		assert.Equal(t, "image/png", fetched.Header().Get("Content-Type"))
+	})
+
+	t.Run("keeps the bytes of earlier versions", func(t *testing.T) {
+		// Arrange
+		resourceService, solidService, _ := withFiles(t)
+		serve(t, solidService.UpdateResource, http.MethodPut, uri, "text/plain", "first")
+		serve(t, solidService.UpdateResource, http.MethodPut, uri, "text/plain", "second")
+
+		// Act
+		memento := serve(t, solidService.GetResource, http.MethodGet, uri+"?version=1", "", "")
+		_, err := resourceService.Revert(context.Background(), uri, 1)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, "first", memento.Body.String())
+		assert.Equal(t, "first", serve(t, solidService.GetResource, http.MethodGet, uri, "", "").Body.String())
+		assert.Equal(t, http.StatusBadRequest, serve(t, solidService.GetResource, http.MethodGet, uri+"?from=1&to=2", "", "").Code)
+	})
+
+	t.Run("only accepts RDF for containers", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withFiles(t)
+
+		// Act
+		recorder := serve(t, solidService.UpdateResource, http.MethodPut, container+"sub/", "text/plain", "hello")
+
+		// Assert
+		assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+	})
+
+	t.Run("shares bytes with copies and purges them with the last one", func(t *testing.T) {
+		// Arrange
+		resourceService, _, blobs := withFiles(t)
+		original, _, err := resourceService.PutContent(context.Background(), uri, strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+		require.NoError(t, resourceService.Copy(context.Background(), uri, container+"copy.txt"))
+
+		// Act
+		require.NoError(t, resourceService.Delete(context.Background(), uri))
+		_, err = resourceService.Purge(context.Background(), time.Now().Add(time.Minute))
+		require.NoError(t, err)
+
+		// Assert
+		copied, _, err := resourceService.Get(context.Background(), container+"copy.txt", "text/turtle")
+		require.NoError(t, err)
+		assert.Equal(t, original.GetContent(), copied.GetContent())
+		reader, err := resourceService.Open(context.Background(), copied)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+
+		require.NoError(t, resourceService.Delete(context.Background(), container+"copy.txt"))
+		_, err = resourceService.Purge(context.Background(), time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		_, err = blobs.Get(context.Background(), original.GetContent().Key)
+		assert.ErrorIs(t, err, domainrepository.ErrBlobNotFound)
+	})
+
+	t.Run("redirects downloads the blob store links to", func(t *testing.T) {
+		// Arrange
+		blobs := &linkingBlobStore{MemoryBlobStore: repository.NewMemoryBlobStore(), minSize: 10}
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), blobs, rdfService, log)
+		solidService := service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+		serve(t, solidService.UpdateResource, http.MethodPut, uri, "text/plain", "hello world")
+		serve(t, solidService.UpdateResource, http.MethodPut, container+"hi.txt", "text/plain", "hi")
+
+		// Act
+		large := serve(t, solidService.GetResource, http.MethodGet, uri, "", "")
+		head := serve(t, solidService.GetResource, http.MethodHead, uri, "", "")
+		small := serve(t, solidService.GetResource, http.MethodGet, container+"hi.txt", "", "")
+
+		// Assert
+		assert.Equal(t, http.StatusTemporaryRedirect, large.Code)
+		assert.True(t, strings.HasPrefix(large.Header().Get("Location"), "https://blobs.example.com/"))
+		assert.Equal(t, http.StatusOK, head.Code)
+		assert.Equal(t, "11", head.Header().Get("Content-Length"))
+		assert.Equal(t, http.StatusOK, small.Code)
+		assert.Equal(t, "hi", small.Body.String())
+	})
+}
+
+// linkingBlobStore links to blobs of at least minSize bytes, as stores
+// presigning downloads do
+type linkingBlobStore struct {
+	*repository.MemoryBlobStore
+	minSize int64
+}
+
+func (s *linkingBlobStore) Link(ctx context.Context, key string, size int64, contentType string) (string, error) {
+	if size < s.minSize {
+		return "", nil
+	}
+	return "https://blobs.example.com/" + key, nil
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+// DatasetService imports and exports RDF datasets, mapping each named graph
+// to the resource whose URI is the graph name
+type DatasetService struct {
+	repository repository.ResourceRepository
+	rdfService domainservice.RDFValidationService
+	shapes     *ShapesResolver
+	shapeTrees *ShapeTreeService
+	skolemizer *Skolemizer
+	logger     logger.Logger
+}
+
+// NewDatasetService creates a new dataset service
+func NewDatasetService(repo repository.ResourceRepository, rdfService domainservice.RDFValidationService, logger logger.Logger) *DatasetService {
+	return &DatasetService{
+		repository: repo,
+		rdfService: rdfService,
+		shapes:     NewShapesResolver(repo, rdfService),
+		shapeTrees: NewShapeTreeService(repo, rdfService, logger),
+		skolemizer: NewSkolemizer(repo, rdfService),
+		logger:     logger,
+	}
+}
+
+// Import stores every named graph of a dataset as a resource inside
+// containerURI, creating new resources and updating existing ones. The
+// default graph must be empty and every graph name must lie within the
+// container, and each graph must conform to the shapes its container
+// declares. Nothing is saved unless every graph is valid.
+func (s *DatasetService) Import(ctx context.Context, containerURI string, data string, format string) ([]entity.Resource, error) {
+	dataset, err := s.rdfService.WithBase(containerURI).ParseDataset(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if dataset.Default().Len() > 0 {
+		return nil, errors.New("dataset import requires an empty default graph; put each resource in its own named graph")
+	}
+
+	resources := make([]entity.Resource, 0, len(dataset.GraphNames()))
+	for _, name := range dataset.GraphNames() {
+		uri, ok := name.(rdf.IRI)
+		if !ok {
+			return nil, fmt.Errorf("graph name %s must be an IRI", name)
+		}
+		if !isWithinContainer(string(uri), containerURI) {
+			return nil, fmt.Errorf("graph %s is outside container %s", uri, containerURI)
+		}
+		if strings.HasSuffix(string(uri), "/"+ShapeTreeSuffix) {
+			return nil, fmt.Errorf("%w: %s", ErrProtectedResource, uri)
+		}
+
+		graph, _ := dataset.NamedGraph(name)
+		if graph.Len() == 0 {
+			continue
+		}
+		turtle, err := s.rdfService.WithBase(string(uri)).SerializeGraph(graph, string(domainservice.FormatTurtle))
+		if err != nil {
+			return nil, err
+		}
+
+		resource, err := s.resourceFor(ctx, string(uri), turtle)
+		if err != nil {
+			return nil, err
+		}
+		if resource.HasErrors() {
+			return nil, fmt.Errorf("graph %s: %w", uri, errors.Join(resource.GetErrors()...))
+		}
+		resources = append(resources, resource)
+	}
+
+	for _, resource := range resources {
+		if err := s.repository.Save(ctx, resource); err != nil {
+			return nil, fmt.Errorf("failed to save %s: %w", resource.GetURI(), err)
+		}
+		if err := s.shapeTrees.Assign(ctx, resource); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("Imported dataset",
+		zap.String("container", containerURI),
+		zap.Int("resources", len(resources)),
+	)
+
+	return resources, nil
+}
+
+// resourceFor updates the resource at uri, restoring it when it was deleted,
+// or creates it when it does not exist
+func (s *DatasetService) resourceFor(ctx context.Context, uri string, turtle string) (entity.Resource, error) {
+	constraints, err := s.shapes.ConstraintsFor(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repository.GetByURI(ctx, uri)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, err
+	}
+	if turtle, _, err = s.skolemizer.Skolemize(ctx, uri, turtle, string(domainservice.FormatTurtle), existing); err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		existing.WithAuthor(authorFrom(ctx))
+		if existing.IsDeleted() {
+			existing.Restore()
+		}
+		return constraints.Apply(existing).Update(turtle, string(domainservice.FormatTurtle)), nil
+	}
+
+	return constraints.Apply(entity.NewBasicResourceWithValidator(s.rdfService)).
+		WithBase(uri).
+		WithAuthor(authorFrom(ctx)).
+		FromTurtle(turtle).
+		WithURI(uri), nil
+}
+
+// Export serializes the container and every resource below it as a single
+// dataset with one named graph per resource
+func (s *DatasetService) Export(ctx context.Context, containerURI string, format string) (string, error) {
+	dataset := rdf.NewDataset()
+
+	container, err := s.repository.GetByURI(ctx, containerURI)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		return "", err
+	}
+	if err == nil && container != nil && !container.IsDeleted() {
+		if err := s.addResourceGraph(dataset, container); err != nil {
+			return "", err
+		}
+	}
+
+	visited := map[string]bool{containerURI: true}
+	pending := []string{containerURI}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		children, err := s.repository.FindByContainer(ctx, current)
+		if err != nil {
+			return "", err
+		}
+		for _, child := range children {
+			uri := child.GetURI()
+			if visited[uri] || child.IsDeleted() {
+				continue
+			}
+			visited[uri] = true
+
+			if err := s.addResourceGraph(dataset, child); err != nil {
+				return "", err
+			}
+			if strings.HasSuffix(uri, "/") {
+				pending = append(pending, uri)
+			}
+		}
+	}
+
+	return s.rdfService.SerializeDataset(dataset, format)
+}
+
+// addResourceGraph adds the resource's triples as the named graph called by its URI
+func (s *DatasetService) addResourceGraph(dataset *rdf.Dataset, resource entity.Resource) error {
+	if resource.GetData() == "" {
+		return nil
+	}
+	graph, err := s.rdfService.WithBase(resource.GetURI()).ParseGraph(resource.GetData(), resource.GetContentType())
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", resource.GetURI(), err)
+	}
+	dataset.Graph(rdf.IRI(resource.GetURI())).Merge(graph)
+	return nil
+}
+
+// isWithinContainer reports whether uri is the container or lies below it
+func isWithinContainer(uri, containerURI string) bool {
+	if !strings.HasSuffix(containerURI, "/") {
+		containerURI += "/"
+	}
+	return uri == strings.TrimSuffix(containerURI, "/") || strings.HasPrefix(uri, containerURI)
+}
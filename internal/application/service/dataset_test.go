@@ -0,0 +1,149 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestDatasetService(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+
+	trig := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+
+<https://pod.example.com/people/alice> {
+    <https://pod.example.com/people/alice#me> foaf:name "Alice" .
+}
+
+<https://pod.example.com/people/bob> {
+    <https://pod.example.com/people/bob#me> foaf:name "Bob" .
+}
+`
+
+	t.Run("stores each named graph as a resource", func(t *testing.T) {
+		// Arrange
+		repo := &repository.ResourceRepositoryMock{
+			GetByURIFunc: func(ctx context.Context, uri string) (entity.Resource, error) {
+				return nil, repository.ErrResourceNotFound
+			},
+			SaveFunc: func(ctx context.Context, resource entity.Resource) error {
+				return nil
+			},
+		}
+		datasetService := service.NewDatasetService(repo, rdfService, log)
+
+		// Act
+		resources, err := datasetService.Import(context.Background(), "https://pod.example.com/people/", trig, string(domainservice.FormatTriG))
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, resources, 2)
+		assert.Equal(t, "https://pod.example.com/people/alice", resources[0].GetURI())
+		assert.Equal(t, "https://pod.example.com/people/bob", resources[1].GetURI())
+		assert.Contains(t, resources[0].GetData(), `"Alice"`)
+		assert.Len(t, repo.SaveCalls(), 2)
+	})
+
+	t.Run("updates resources that already exist", func(t *testing.T) {
+		// Arrange
+		existing := entity.NewBasicResourceWithValidator(rdfService).
+			FromTurtle(`<https://pod.example.com/people/alice#me> <http://xmlns.com/foaf/0.1/name> "Old" .`).
+			WithURI("https://pod.example.com/people/alice")
+		existing.MarkEventsAsCommitted()
+
+		repo := &repository.ResourceRepositoryMock{
+			GetByURIFunc: func(ctx context.Context, uri string) (entity.Resource, error) {
+				if uri == existing.GetURI() {
+					return existing, nil
+				}
+				return nil, repository.ErrResourceNotFound
+			},
+			SaveFunc: func(ctx context.Context, resource entity.Resource) error {
+				return nil
+			},
+		}
+		datasetService := service.NewDatasetService(repo, rdfService, log)
+
+		// Act
+		resources, err := datasetService.Import(context.Background(), "https://pod.example.com/people/", trig, string(domainservice.FormatTriG))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Same(t, existing, resources[0])
+		assert.Contains(t, existing.GetData(), `"Alice"`)
+		assert.Equal(t, 1, existing.UncommittedEventCount())
+	})
+
+	t.Run("rejects graphs outside the container without saving anything", func(t *testing.T) {
+		// Arrange
+		repo := &repository.ResourceRepositoryMock{
+			GetByURIFunc: func(ctx context.Context, uri string) (entity.Resource, error) {
+				return nil, repository.ErrResourceNotFound
+			},
+			SaveFunc: func(ctx context.Context, resource entity.Resource) error {
+				return nil
+			},
+		}
+		datasetService := service.NewDatasetService(repo, rdfService, log)
+
+		// Act
+		_, err := datasetService.Import(context.Background(), "https://pod.example.com/people/alice/", trig, string(domainservice.FormatTriG))
+
+		// Assert
+		assert.Error(t, err)
+		assert.Empty(t, repo.SaveCalls())
+	})
+
+	t.Run("rejects triples in the default graph", func(t *testing.T) {
+		// Arrange
+		datasetService := service.NewDatasetService(&repository.ResourceRepositoryMock{}, rdfService, log)
+
+		// Act
+		_, err := datasetService.Import(context.Background(), "https://pod.example.com/",
+			`<https://pod.example.com/a> <https://example.com/p> "o" .`, string(domainservice.FormatNQuads))
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("exports a container subtree as one dataset", func(t *testing.T) {
+		// Arrange
+		newResource := func(uri, turtle string) entity.Resource {
+			return entity.NewBasicResourceWithValidator(rdfService).FromTurtle(turtle).WithURI(uri)
+		}
+		children := map[string][]entity.Resource{
+			"https://pod.example.com/": {
+				newResource("https://pod.example.com/people/", `<https://pod.example.com/people/> a <http://www.w3.org/ns/ldp#Container> .`),
+			},
+			"https://pod.example.com/people/": {
+				newResource("https://pod.example.com/people/alice", `<https://pod.example.com/people/alice#me> <http://xmlns.com/foaf/0.1/name> "Alice" .`),
+			},
+		}
+		repo := &repository.ResourceRepositoryMock{
+			GetByURIFunc: func(ctx context.Context, uri string) (entity.Resource, error) {
+				return nil, repository.ErrResourceNotFound
+			},
+			FindByContainerFunc: func(ctx context.Context, containerURI string) ([]entity.Resource, error) {
+				return children[containerURI], nil
+			},
+		}
+		datasetService := service.NewDatasetService(repo, rdfService, log)
+
+		// Act
+		result, err := datasetService.Export(context.Background(), "https://pod.example.com/", string(domainservice.FormatNQuads))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Contains(t, result, `<https://pod.example.com/people/> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/ns/ldp#Container> <https://pod.example.com/people/> .`)
+		assert.Contains(t, result, `<https://pod.example.com/people/alice#me> <http://xmlns.com/foaf/0.1/name> "Alice" <https://pod.example.com/people/alice> .`)
+	})
+}
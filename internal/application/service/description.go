@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+)
+
+// LDPNamespace is the namespace of the Linked Data Platform vocabulary
+const LDPNamespace = "http://www.w3.org/ns/ldp#"
+
+// Server-managed metadata vocabulary
+const (
+	LDPResource       = rdf.IRI(LDPNamespace + "Resource")
+	LDPRDFSource      = rdf.IRI(LDPNamespace + "RDFSource")
+	LDPNonRDFSource   = rdf.IRI(LDPNamespace + "NonRDFSource")
+	LDPContainer      = rdf.IRI(LDPNamespace + "Container")
+	LDPBasicContainer = rdf.IRI(LDPNamespace + "BasicContainer")
+	LDPContains       = rdf.IRI(LDPNamespace + "contains")
+	DCTermsModified   = rdf.IRI("http://purl.org/dc/terms/modified")
+	StatSize          = rdf.IRI("http://www.w3.org/ns/posix/stat#size")
+	XSDDateTime       = rdf.IRI(rdf.XSDNamespace + "dateTime")
+	XSDInteger        = rdf.IRI(rdf.XSDNamespace + "integer")
+)
+
+// ErrProtectedTriple is returned when a description changes a triple the server manages
+var ErrProtectedTriple = errors.New("triple is managed by the server")
+
+// DescriptionURI returns the URI of the auxiliary resource describing the
+// resource at uri: uri.meta for resources and uri/.meta for containers
+func DescriptionURI(uri string) string {
+	return uri + MetaSuffix
+}
+
+// isDescription reports whether uri names a description resource
+func isDescription(uri string) bool {
+	return strings.HasSuffix(uri, MetaSuffix)
+}
+
+// describedURI returns the URI of the resource described by the description at uri
+func describedURI(uri string) string {
+	return strings.TrimSuffix(uri, MetaSuffix)
+}
+
+// getDescription returns the description at uri in format: the triples
+// stored in it merged with those the server manages for the described
+// resource. The description resource is returned when it is stored, and
+// the described resource otherwise.
+func (s *ResourceService) getDescription(ctx context.Context, uri string, format string) (entity.Resource, string, error) {
+	described, err := s.live(ctx, describedURI(uri))
+	if err != nil {
+		return nil, "", err
+	}
+
+	resource := described
+	graph := rdf.NewGraph()
+	meta, err := s.repository.GetByURI(ctx, uri)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, "", err
+	}
+	if err == nil && meta != nil && !meta.IsDeleted() {
+		if graph, err = s.graph(ctx, uri, meta); err != nil {
+			return nil, "", err
+		}
+		resource = meta
+	}
+	graph.Merge(serverManaged(described))
+
+	data, err := s.rdfService.WithBase(uri).SerializeGraph(graph, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return resource, data, nil
+}
+
+// withoutManaged returns the description data written to uri without the
+// triples the server manages for the described resource, or "" when only
+// those were written. Managed triples may be sent back as they are, but
+// not changed.
+func (s *ResourceService) withoutManaged(ctx context.Context, uri string, data string, contentType string) (string, string, error) {
+	described, err := s.live(ctx, describedURI(uri))
+	if err != nil {
+		return "", "", err
+	}
+	mediaType, err := s.mediaType(contentType)
+	if err != nil {
+		return "", "", err
+	}
+	parser := s.rdfService.WithBase(uri)
+	graph, err := parser.ParseGraph(data, mediaType)
+	if err != nil {
+		var validationErr *domainservice.ValidationError
+		if errors.As(err, &validationErr) {
+			return "", "", err
+		}
+		return "", "", domainservice.NewValidationError(domainservice.RDFFormat(mediaType), err.Error(), err)
+	}
+
+	managed := serverManaged(described)
+	subject := rdf.IRI(described.GetURI())
+	removed := false
+	for _, t := range graph.Triples() {
+		if t.Subject != subject || !isManaged(t) {
+			continue
+		}
+		if !managed.Contains(t) {
+			return "", "", fmt.Errorf("%w: %s %s %s", ErrProtectedTriple, t.Subject, t.Predicate, t.Object)
+		}
+		graph.Remove(t)
+		removed = true
+	}
+	if !removed {
+		return data, contentType, nil
+	}
+	if graph.Len() == 0 {
+		return "", contentType, nil
+	}
+
+	turtle, err := parser.SerializeGraph(graph, string(domainservice.FormatTurtle))
+	if err != nil {
+		return "", "", err
+	}
+	return turtle, string(domainservice.FormatTurtle), nil
+}
+
+// clearDescription deletes the triples stored in the description at uri,
+// leaving only those the server manages, and returns the described resource
+func (s *ResourceService) clearDescription(ctx context.Context, uri string) (entity.Resource, error) {
+	described, err := s.live(ctx, describedURI(uri))
+	if err != nil {
+		return nil, err
+	}
+	meta, err := s.repository.GetByURI(ctx, uri)
+	if errors.Is(err, repository.ErrResourceNotFound) || (err == nil && (meta == nil || meta.IsDeleted())) {
+		return described, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Delete(ctx, uri); err != nil {
+		return nil, err
+	}
+	return described, nil
+}
+
+// addContainment adds the live members of the container at uri to graph,
+// with the metadata the server manages for the container and each member
+func (s *ResourceService) addContainment(ctx context.Context, uri string, container entity.Resource, graph *rdf.Graph) error {
+	members, err := s.members(ctx, uri, false)
+	if err != nil {
+		return err
+	}
+	graph.Merge(serverManaged(container))
+	for _, member := range members {
+		graph.Add(rdf.Triple{Subject: rdf.IRI(uri), Predicate: LDPContains, Object: rdf.IRI(member.GetURI())})
+		graph.Merge(serverManaged(member))
+	}
+	return nil
+}
+
+// serverManaged returns the triples the server manages for resource: its
+// LDP types, when it was last modified and, for documents, its size
+func serverManaged(resource entity.Resource) *rdf.Graph {
+	subject := rdf.IRI(resource.GetURI())
+	types := []rdf.IRI{LDPResource, LDPRDFSource}
+	size := int64(len(resource.GetData()))
+	switch {
+	case strings.HasSuffix(resource.GetURI(), "/"):
+		types = append(types, LDPContainer, LDPBasicContainer)
+		size = -1
+	case resource.IsNonRDF():
+		types = []rdf.IRI{LDPResource, LDPNonRDFSource}
+		size = resource.GetContent().Size
+	}
+
+	graph := rdf.NewGraph()
+	for _, t := range types {
+		graph.Add(rdf.Triple{Subject: subject, Predicate: rdf.RDFType, Object: t})
+	}
+	modified := resource.GetLastModified().UTC().Format(time.RFC3339)
+	graph.Add(rdf.Triple{Subject: subject, Predicate: DCTermsModified, Object: rdf.NewTypedLiteral(modified, XSDDateTime)})
+	if size >= 0 {
+		graph.Add(rdf.Triple{Subject: subject, Predicate: StatSize, Object: rdf.NewTypedLiteral(strconv.FormatInt(size, 10), XSDInteger)})
+	}
+	return graph
+}
+
+// isManaged reports whether the server manages t when it is about the described resource
+func isManaged(t rdf.Triple) bool {
+	switch t.Predicate {
+	case DCTermsModified, StatSize:
+		return true
+	case rdf.RDFType:
+		return strings.HasPrefix(t.Object.Value(), LDPNamespace)
+	}
+	return false
+}
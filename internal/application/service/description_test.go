@@ -0,0 +1,135 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestDescriptionResources(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const container = "https://pod.example.com/notes/"
+	const uri = container + "today"
+	const meta = uri + service.MetaSuffix
+	title := rdf.IRI("http://purl.org/dc/terms/title")
+
+	withNote := func(t *testing.T) (*service.ResourceService, *service.SolidService) {
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), repository.NewMemoryBlobStore(), rdfService, log)
+		_, _, err := resourceService.Put(context.Background(), container, `<> <http://purl.org/dc/terms/title> "Notes" .`, "text/turtle")
+		require.NoError(t, err)
+		_, _, err = resourceService.Put(context.Background(), uri, `<> <http://purl.org/dc/terms/title> "Today" .`, "text/turtle")
+		require.NoError(t, err)
+		return resourceService, service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+	}
+
+	serve := func(t *testing.T, handler func(context.Context, http.ResponseWriter, *http.Request) error, method, target, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			request.Header.Set("Content-Type", "text/turtle")
+		}
+		recorder := httptest.NewRecorder()
+		require.NoError(t, handler(request.Context(), recorder, request))
+		return recorder
+	}
+
+	parse := func(t *testing.T, base string, data string) *rdf.Graph {
+		graph, err := rdfService.WithBase(base).ParseGraph(data, "text/turtle")
+		require.NoError(t, err)
+		return graph
+	}
+
+	t.Run("links resources to their description", func(t *testing.T) {
+		// Arrange
+		_, solidService := withNote(t)
+
+		// Act
+		recorder := serve(t, solidService.GetResource, http.MethodHead, uri, "")
+		description := serve(t, solidService.GetResource, http.MethodHead, meta, "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Header().Values("Link"), `<`+meta+`>; rel="describedby"`)
+		assert.NotContains(t, strings.Join(description.Header().Values("Link"), ","), "describedby")
+	})
+
+	t.Run("describes resources with server-managed metadata", func(t *testing.T) {
+		// Arrange
+		_, solidService := withNote(t)
+
+		// Act
+		recorder := serve(t, solidService.GetResource, http.MethodGet, meta, "")
+
+		// Assert
+		require.Equal(t, http.StatusOK, recorder.Code)
+		graph := parse(t, meta, recorder.Body.String())
+		assert.True(t, graph.Contains(rdf.Triple{Subject: rdf.IRI(uri), Predicate: rdf.RDFType, Object: service.LDPRDFSource}))
+		assert.Len(t, graph.Match(rdf.IRI(uri), service.DCTermsModified, nil), 1)
+		assert.Len(t, graph.Match(rdf.IRI(uri), service.StatSize, nil), 1)
+		assert.True(t, strings.HasPrefix(recorder.Header().Get("ETag"), `W/"`))
+	})
+
+	t.Run("stores triples users add to a description", func(t *testing.T) {
+		// Arrange
+		_, solidService := withNote(t)
+
+		// Act
+		stored := serve(t, solidService.UpdateResource, http.MethodPut, meta, `<today> <http://purl.org/dc/terms/title> "Described" .`)
+		recorder := serve(t, solidService.GetResource, http.MethodGet, meta, "")
+		invalid := serve(t, solidService.UpdateResource, http.MethodPut, meta, `this is not turtle`)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, stored.Code)
+		graph := parse(t, meta, recorder.Body.String())
+		assert.True(t, graph.Contains(rdf.Triple{Subject: rdf.IRI(uri), Predicate: title, Object: rdf.NewLiteral("Described")}))
+		assert.Len(t, graph.Match(rdf.IRI(uri), service.DCTermsModified, nil), 1)
+		assert.Equal(t, http.StatusBadRequest, invalid.Code)
+	})
+
+	t.Run("protects server-managed triples", func(t *testing.T) {
+		// Arrange
+		_, solidService := withNote(t)
+		current := serve(t, solidService.GetResource, http.MethodGet, meta, "").Body.String()
+
+		// Act
+		unchanged := serve(t, solidService.UpdateResource, http.MethodPut, meta, current)
+		modified := serve(t, solidService.UpdateResource, http.MethodPut, meta,
+			`<today> <http://purl.org/dc/terms/modified> "2000-01-01T00:00:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .`)
+		resized := serve(t, solidService.UpdateResource, http.MethodPut, meta,
+			`<today> <http://www.w3.org/ns/posix/stat#size> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .`)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, unchanged.Code, "managed triples may be sent back unchanged")
+		assert.Equal(t, http.StatusConflict, modified.Code)
+		assert.Equal(t, http.StatusConflict, resized.Code)
+	})
+
+	t.Run("lists container members with their metadata", func(t *testing.T) {
+		// Arrange
+		_, solidService := withNote(t)
+
+		// Act
+		recorder := serve(t, solidService.GetResource, http.MethodGet, container, "")
+
+		// Assert
+		require.Equal(t, http.StatusOK, recorder.Code)
+		graph := parse(t, container, recorder.Body.String())
+		assert.True(t, graph.Contains(rdf.Triple{Subject: rdf.IRI(container), Predicate: service.LDPContains, Object: rdf.IRI(uri)}))
+		assert.True(t, graph.Contains(rdf.Triple{Subject: rdf.IRI(container), Predicate: rdf.RDFType, Object: service.LDPBasicContainer}))
+		assert.Len(t, graph.Match(rdf.IRI(uri), service.DCTermsModified, nil), 1)
+		assert.Len(t, graph.Match(rdf.IRI(uri), service.StatSize, nil), 1)
+		assert.False(t, graph.Contains(rdf.Triple{Subject: rdf.IRI(container), Predicate: service.LDPContains, Object: rdf.IRI(meta)}))
+	})
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+)
+
+// refsSuffix is appended to the digest of stored bytes and the pod holding
+// them to name the blob recording where they are kept and how many
+// resource versions use them
+const refsSuffix = "_refs"
+
+var (
+	// ErrUnknownDigest is returned when the pod holds no bytes with the requested digest
+	ErrUnknownDigest = errors.New("no content has this digest")
+
+	// ErrDigestMismatch is returned when uploaded bytes do not have the digest sent with them
+	ErrDigestMismatch = errors.New("content does not match its digest")
+)
+
+// contentRefs records where the bytes with a digest are kept in a pod.
+// Every version of a resource storing them is a reference; once none is
+// left the bytes are deleted and the record keeps no key. Each pod has its
+// own records, so a pod cannot learn from a digest what another holds.
+type contentRefs struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+	Refs int    `json:"refs"`
+}
+
+// CreateContentByDigest creates a non-RDF resource inside containerURI from
+// bytes its pod already holds with the hex encoded SHA-256 digest, naming
+// it like Create does. It returns ErrUnknownDigest when there are none, so
+// the bytes must be uploaded.
+func (s *ResourceService) CreateContentByDigest(ctx context.Context, containerURI string, slug string, digest string, contentType string) (entity.Resource, error) {
+	return s.createContent(ctx, containerURI, slug, func(uri string) (entity.Content, error) {
+		return s.retainDigest(ctx, uri, digest)
+	}, contentType)
+}
+
+// PutContentByDigest makes bytes the pod of uri already holds with the hex
+// encoded SHA-256 digest the content of the non-RDF resource at uri, like
+// PutContent. It returns ErrUnknownDigest when there are none, so the
+// bytes must be uploaded.
+func (s *ResourceService) PutContentByDigest(ctx context.Context, uri string, digest string, contentType string) (resource entity.Resource, created bool, err error) {
+	if err := checkContentTarget(uri); err != nil {
+		return nil, false, err
+	}
+	content, err := s.retainDigest(ctx, uri, digest)
+	if err != nil {
+		return nil, false, err
+	}
+	if resource, created, err = s.putContent(ctx, uri, content, contentType); err != nil {
+		s.discardContent(ctx, uri, content)
+		return nil, false, err
+	}
+	return resource, created, nil
+}
+
+// addressContent files bytes just stored under content.Key for the
+// resource at uri by their digest. When its pod already holds bytes with
+// that digest the new blob is deleted and the existing bytes are used
+// instead.
+func (s *ResourceService) addressContent(ctx context.Context, uri string, content entity.Content) (entity.Content, error) {
+	refs, err := s.changeRefs(ctx, uri, content.Digest, func(refs *contentRefs) error {
+		if refs.Refs == 0 {
+			refs.Key, refs.Size = content.Key, content.Size
+		}
+		refs.Refs++
+		return nil
+	})
+	if err != nil {
+		s.blobs.Delete(ctx, content.Key)
+		return entity.Content{}, err
+	}
+	if refs.Key != content.Key {
+		if err := s.blobs.Delete(ctx, content.Key); err != nil {
+			s.logger.Warn("Failed to delete duplicate content", zap.String("key", content.Key), zap.Error(err))
+		}
+	}
+	return entity.Content{Key: refs.Key, Size: refs.Size, Digest: content.Digest}, nil
+}
+
+// retainDigest adds a reference to the bytes with digest in the pod of uri
+// and returns them
+func (s *ResourceService) retainDigest(ctx context.Context, uri string, digest string) (entity.Content, error) {
+	if !isDigest(digest) {
+		return entity.Content{}, fmt.Errorf("%w: %s", ErrUnknownDigest, digest)
+	}
+	refs, err := s.changeRefs(ctx, uri, digest, func(refs *contentRefs) error {
+		if refs.Refs == 0 {
+			return fmt.Errorf("%w: %s", ErrUnknownDigest, digest)
+		}
+		refs.Refs++
+		return nil
+	})
+	if err != nil {
+		return entity.Content{}, err
+	}
+	return entity.Content{Key: refs.Key, Size: refs.Size, Digest: digest}, nil
+}
+
+// retainContent adds a reference to content, which another version of the
+// resource at uri is about to store. Bytes stored before they were
+// addressed by digest are not counted.
+func (s *ResourceService) retainContent(ctx context.Context, uri string, content entity.Content) error {
+	if content.Digest == "" {
+		return nil
+	}
+	_, err := s.retainDigest(ctx, uri, content.Digest)
+	return err
+}
+
+// releaseContent removes a reference to content of the resource at uri,
+// deleting its bytes once no resource version refers to them
+func (s *ResourceService) releaseContent(ctx context.Context, uri string, content entity.Content) error {
+	key := content.Key
+	refs, err := s.changeRefs(ctx, uri, content.Digest, func(refs *contentRefs) error {
+		if refs.Refs == 0 {
+			return repository.ErrBlobNotFound
+		}
+		if refs.Refs--; refs.Refs == 0 {
+			key, refs.Key, refs.Size = refs.Key, "", 0
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return s.blobs.Delete(ctx, content.Key)
+	}
+	if err != nil {
+		return err
+	}
+	if refs.Refs > 0 {
+		return nil
+	}
+	// Nothing can refer to the bytes once the record keeps no key, so they
+	// are deleted after it is written
+	return s.blobs.Delete(ctx, key)
+}
+
+// changeRefs applies change to the record of the bytes with digest in the
+// pod of uri. change sees an empty record when there is none and can
+// refuse it by returning an error.
+func (s *ResourceService) changeRefs(ctx context.Context, uri string, digest string, change func(*contentRefs) error) (contentRefs, error) {
+	return changeRecord(ctx, s.blobs, refsKey(uri, digest), change)
+}
+
+// changeRecord applies change to the JSON record stored under key and
+// writes it back only if nobody else wrote it in between, reading it again
+// until the write succeeds. change sees the zero value when there is no
+// record and can refuse it by returning an error.
+func changeRecord[T any](ctx context.Context, blobs repository.BlobStore, key string, change func(*T) error) (T, error) {
+	var zero T
+	for {
+		var value T
+		record, tag, err := blobs.GetTagged(ctx, key)
+		if err != nil && !errors.Is(err, repository.ErrBlobNotFound) {
+			return zero, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(record, &value); err != nil {
+				return zero, fmt.Errorf("failed to read record %s: %w", key, err)
+			}
+		}
+		if err := change(&value); err != nil {
+			return zero, err
+		}
+
+		if record, err = json.Marshal(value); err != nil {
+			return zero, err
+		}
+		err = blobs.PutIf(ctx, key, tag, record)
+		if errors.Is(err, repository.ErrBlobChanged) {
+			continue
+		}
+		if err != nil {
+			return zero, fmt.Errorf("failed to write record %s: %w", key, err)
+		}
+		return value, nil
+	}
+}
+
+// digestOf reads the blob stored under key and returns its hex encoded
+// SHA-256 digest
+func (s *ResourceService) digestOf(ctx context.Context, key string) (string, error) {
+	reader, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// refsKey returns the key of the record of the bytes with digest in the
+// pod of uri
+func refsKey(uri string, digest string) string {
+	sum := sha256.Sum256([]byte(podOf(uri)))
+	return digest + "_" + hex.EncodeToString(sum[:8]) + refsSuffix
+}
+
+// podOf returns the pod holding the resource at uri, which is its origin
+func podOf(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host)
+}
+
+// isDigest reports whether digest is a hex encoded SHA-256 digest
+func isDigest(digest string) bool {
+	return len(digest) == 2*sha256.Size && strings.Trim(digest, "0123456789abcdef") == ""
+}
+
+// setDigestHeaders sends the SHA-256 digest of the bytes of a non-RDF
+// resource as Repr-Digest (RFC 9530) and as the older Digest (RFC 3230)
+func setDigestHeaders(w http.ResponseWriter, content entity.Content) {
+	sum, err := hex.DecodeString(content.Digest)
+	if err != nil || len(sum) != sha256.Size {
+		return
+	}
+	encoded := base64.StdEncoding.EncodeToString(sum)
+	w.Header().Set("Repr-Digest", "sha-256=:"+encoded+":")
+	w.Header().Set("Digest", "SHA-256="+encoded)
+}
+
+// requestDigest returns the hex encoded SHA-256 digest a client sent in a
+// Repr-Digest or Digest header, or "" when it sent none
+func requestDigest(r *http.Request) string {
+	for _, header := range []string{"Repr-Digest", "Digest"} {
+		for _, value := range r.Header.Values(header) {
+			for _, member := range strings.Split(value, ",") {
+				algorithm, encoded, ok := strings.Cut(strings.TrimSpace(member), "=")
+				if !ok || !strings.EqualFold(algorithm, "sha-256") {
+					continue
+				}
+				// Repr-Digest wraps the value in colons as a byte sequence
+				encoded = strings.TrimSuffix(strings.TrimPrefix(encoded, ":"), ":")
+				if sum, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(sum) == sha256.Size {
+					return hex.EncodeToString(sum)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// digestReader fails at the end of its bytes when they do not have the
+// expected digest
+type digestReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected string
+}
+
+// verifyDigest returns a reader of r that fails with ErrDigestMismatch
+// when the bytes read do not have the hex encoded SHA-256 digest
+func verifyDigest(r io.Reader, digest string) io.Reader {
+	return &digestReader{r: r, hash: sha256.New(), expected: digest}
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(r.hash.Sum(nil)) != r.expected {
+		return n, fmt.Errorf("%w: expected sha-256 %s", ErrDigestMismatch, r.expected)
+	}
+	return n, err
+}
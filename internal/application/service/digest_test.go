@@ -0,0 +1,236 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestContentDeduplication(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const container = "https://pod.example.com/files/"
+	const helloDigest = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	const helloBase64 = "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="
+
+	withBlobs := func(t *testing.T) (*service.ResourceService, *service.SolidService, *repository.FileSystemBlobStore) {
+		blobs := repository.NewFileSystemBlobStore(t.TempDir())
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), blobs, rdfService, log)
+		_, _, err := resourceService.Put(context.Background(), container, `<> <http://purl.org/dc/terms/title> "Files" .`, "text/turtle")
+		require.NoError(t, err)
+		return resourceService, service.NewSolidService(&config.Config{}, log, resourceService, rdfService), blobs
+	}
+
+	serve := func(t *testing.T, handler func(context.Context, http.ResponseWriter, *http.Request) error, method, target string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		require.NoError(t, handler(request.Context(), recorder, request))
+		return recorder
+	}
+
+	t.Run("stores identical bytes once", func(t *testing.T) {
+		// Arrange
+		resourceService, _, blobs := withBlobs(t)
+		ctx := context.Background()
+
+		// Act
+		first, _, err := resourceService.PutContent(ctx, container+"a.txt", strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+		second, _, err := resourceService.PutContent(ctx, container+"b.txt", strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, helloDigest, first.GetContent().Digest)
+		assert.Equal(t, first.GetContent(), second.GetContent())
+		reader, err := blobs.Get(ctx, first.GetContent().Key)
+		require.NoError(t, err)
+		reader.Close()
+	})
+
+	t.Run("deletes bytes once no version refers to them", func(t *testing.T) {
+		// Arrange
+		resourceService, _, blobs := withBlobs(t)
+		ctx := context.Background()
+		first, _, err := resourceService.PutContent(ctx, container+"a.txt", strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+		_, _, err = resourceService.PutContent(ctx, container+"b.txt", strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+		_, _, err = resourceService.PutContent(ctx, container+"b.txt", strings.NewReader("bye"), "text/plain")
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, resourceService.Delete(ctx, container+"a.txt"))
+		_, err = resourceService.Purge(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		_, kept := blobs.Get(ctx, first.GetContent().Key)
+		require.NoError(t, resourceService.Delete(ctx, container+"b.txt"))
+		_, err = resourceService.Purge(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		_, purged := blobs.Get(ctx, first.GetContent().Key)
+
+		// Assert
+		assert.NoError(t, kept, "an earlier version of b.txt still refers to the bytes")
+		assert.ErrorIs(t, purged, domainrepository.ErrBlobNotFound)
+	})
+
+	t.Run("releases bytes stored again unchanged", func(t *testing.T) {
+		// Arrange
+		resourceService, _, blobs := withBlobs(t)
+		ctx := context.Background()
+		first, _, err := resourceService.PutContent(ctx, container+"a.txt", strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+
+		// Act
+		for i := 0; i < 2; i++ {
+			_, _, err = resourceService.PutContent(ctx, container+"a.txt", strings.NewReader("hello"), "text/plain")
+			require.NoError(t, err)
+		}
+		_, _, err = resourceService.PutContentByDigest(ctx, container+"a.txt", helloDigest, "text/plain")
+		require.NoError(t, err)
+		require.NoError(t, resourceService.Delete(ctx, container+"a.txt"))
+		_, err = resourceService.Purge(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+
+		// Assert
+		_, err = blobs.Get(ctx, first.GetContent().Key)
+		assert.ErrorIs(t, err, domainrepository.ErrBlobNotFound)
+		_, _, err = resourceService.PutContentByDigest(ctx, container+"b.txt", helloDigest, "text/plain")
+		assert.ErrorIs(t, err, service.ErrUnknownDigest)
+	})
+
+	t.Run("looks digests up only in the pod of the resource", func(t *testing.T) {
+		// Arrange
+		resourceService, _, _ := withBlobs(t)
+		ctx := context.Background()
+		stored, _, err := resourceService.PutContent(ctx, container+"a.txt", strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+
+		// Act
+		_, _, byDigest := resourceService.PutContentByDigest(ctx, "https://other.example.com/a.txt", helloDigest, "text/plain")
+		other, _, err := resourceService.PutContent(ctx, "https://other.example.com/b.txt", strings.NewReader("hello"), "text/plain")
+
+		// Assert
+		assert.ErrorIs(t, byDigest, service.ErrUnknownDigest)
+		require.NoError(t, err)
+		assert.NotEqual(t, stored.GetContent().Key, other.GetContent().Key, "pods do not share bytes")
+	})
+
+	t.Run("counts references kept and dropped at the same time", func(t *testing.T) {
+		// Arrange
+		resourceService, _, blobs := withBlobs(t)
+		ctx := context.Background()
+		first, _, err := resourceService.PutContent(ctx, container+"a.txt", strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+		const writers = 8
+
+		// Act
+		var wg sync.WaitGroup
+		errs := make(chan error, 2*writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				uri := fmt.Sprintf("%sb%d.txt", container, i)
+				_, _, err := resourceService.PutContentByDigest(ctx, uri, helloDigest, "text/plain")
+				errs <- err
+				errs <- resourceService.Delete(ctx, uri)
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		_, err = resourceService.Purge(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		_, kept := blobs.Get(ctx, first.GetContent().Key)
+		require.NoError(t, resourceService.Delete(ctx, container+"a.txt"))
+		_, err = resourceService.Purge(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		_, purged := blobs.Get(ctx, first.GetContent().Key)
+
+		// Assert
+		assert.NoError(t, kept, "a.txt still refers to the bytes")
+		assert.ErrorIs(t, purged, domainrepository.ErrBlobNotFound)
+	})
+
+	t.Run("sends the digest of the bytes", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withBlobs(t)
+
+		// Act
+		stored := serve(t, solidService.UpdateResource, http.MethodPut, container+"a.txt", map[string]string{"Content-Type": "text/plain"}, "hello")
+		head := serve(t, solidService.GetResource, http.MethodHead, container+"a.txt", nil, "")
+
+		// Assert
+		assert.Equal(t, "sha-256=:"+helloBase64+":", stored.Header().Get("Repr-Digest"))
+		assert.Equal(t, "sha-256=:"+helloBase64+":", head.Header().Get("Repr-Digest"))
+		assert.Equal(t, "SHA-256="+helloBase64, head.Header().Get("Digest"))
+	})
+
+	t.Run("skips uploads of bytes the pod already has", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withBlobs(t)
+		serve(t, solidService.UpdateResource, http.MethodPut, container+"a.txt", map[string]string{"Content-Type": "text/plain"}, "hello")
+
+		// Act
+		put := serve(t, solidService.UpdateResource, http.MethodPut, container+"b.txt",
+			map[string]string{"Content-Type": "text/plain", "Repr-Digest": "sha-256=:" + helloBase64 + ":"}, "")
+		post := serve(t, solidService.CreateResource, http.MethodPost, container,
+			map[string]string{"Content-Type": "text/plain", "Slug": "c.txt", "Digest": "SHA-256=" + helloBase64}, "")
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, put.Code)
+		assert.Equal(t, http.StatusCreated, post.Code)
+		assert.Equal(t, "hello", serve(t, solidService.GetResource, http.MethodGet, container+"b.txt", nil, "").Body.String())
+		assert.Equal(t, "hello", serve(t, solidService.GetResource, http.MethodGet, container+"c.txt", nil, "").Body.String())
+	})
+
+	t.Run("rejects bytes that do not match their digest", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withBlobs(t)
+
+		// Act
+		recorder := serve(t, solidService.UpdateResource, http.MethodPut, container+"a.txt",
+			map[string]string{"Content-Type": "text/plain", "Repr-Digest": "sha-256=:" + helloBase64 + ":"}, "goodbye")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, http.StatusNotFound, serve(t, solidService.GetResource, http.MethodGet, container+"a.txt", nil, "").Code)
+	})
+
+	t.Run("addresses completed uploads by their digest", func(t *testing.T) {
+		// Arrange
+		resourceService, _, _ := withBlobs(t)
+		ctx := context.Background()
+		stored, _, err := resourceService.PutContent(ctx, container+"a.txt", strings.NewReader("hello"), "text/plain")
+		require.NoError(t, err)
+		upload, err := resourceService.StartUpload(ctx, container+"b.txt", "text/plain", 5)
+		require.NoError(t, err)
+
+		// Act
+		_, uploaded, _, err := resourceService.AppendUpload(ctx, container+"b.txt", upload.ID, 0, strings.NewReader("hello"))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, stored.GetContent(), uploaded.GetContent())
+	})
+}
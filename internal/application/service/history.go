@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/event"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+)
+
+var (
+	// ErrVersionNotFound is returned for versions a resource never had
+	ErrVersionNotFound = errors.New("version not found")
+
+	// ErrInvalidVersion is returned for version numbers that are not positive integers
+	ErrInvalidVersion = errors.New("invalid version number")
+)
+
+// Version is a state of a resource in its history
+type Version struct {
+	Number int       `json:"version"`
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Author string    `json:"author,omitempty"`
+}
+
+// VersionDiff holds the triples deleted and inserted between two versions
+type VersionDiff struct {
+	From    int
+	To      int
+	Deletes []rdf.Triple
+	Inserts []rdf.Triple
+}
+
+type authorKey struct{}
+
+// WithAuthor returns a context whose changes are recorded as made by author
+func WithAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// authorFrom returns the author recorded in ctx, or "" when there is none
+func authorFrom(ctx context.Context) string {
+	author, _ := ctx.Value(authorKey{}).(string)
+	return author
+}
+
+// Versions lists the states the resource at uri has had, oldest first. Each
+// event that changed its data is a version, numbered by the event version.
+func (s *ResourceService) Versions(ctx context.Context, uri string) ([]Version, error) {
+	_, events, err := s.history(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]Version, 0, len(events))
+	for _, evt := range events {
+		if !changesData(evt) {
+			continue
+		}
+		versions = append(versions, versionOf(evt))
+	}
+	return versions, nil
+}
+
+// GetVersion returns the resource at uri as it was at version, with its
+// data serialized in format
+func (s *ResourceService) GetVersion(ctx context.Context, uri string, version int, format string) (entity.Resource, string, error) {
+	resource, err := s.resourceAt(ctx, uri, version)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := s.serialize(ctx, uri, resource, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return resource, data, nil
+}
+
+// Diff returns the triples deleted and inserted between two versions of the
+// resource at uri
+func (s *ResourceService) Diff(ctx context.Context, uri string, from int, to int) (*VersionDiff, error) {
+	before, err := s.graphAt(ctx, uri, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.graphAt(ctx, uri, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &VersionDiff{From: from, To: to}
+	for _, t := range before.SortedTriples() {
+		if !after.Contains(t) {
+			diff.Deletes = append(diff.Deletes, t)
+		}
+	}
+	for _, t := range after.SortedTriples() {
+		if !before.Contains(t) {
+			diff.Inserts = append(diff.Inserts, t)
+		}
+	}
+	return diff, nil
+}
+
+// Revert replaces the data of the resource at uri with its data at version.
+// The revert is recorded as a new update, so the history is kept.
+func (s *ResourceService) Revert(ctx context.Context, uri string, version int) (entity.Resource, error) {
+	past, err := s.resourceAt(ctx, uri, version)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.live(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if !past.IsNonRDF() {
+		resource, err := s.updateResource(ctx, current, past.GetData(), past.GetContentType())
+		if err != nil {
+			return nil, err
+		}
+		if err := s.save(ctx, resource); err != nil {
+			return nil, err
+		}
+		return resource, nil
+	}
+
+	// The new version refers to the bytes of the past one
+	content := past.GetContent()
+	if err := s.retainContent(ctx, uri, content); err != nil {
+		return nil, err
+	}
+	resource, err := s.updateContent(ctx, current, content, past.GetContentType())
+	if err == nil {
+		err = s.save(ctx, resource)
+	}
+	if err != nil {
+		if content.Digest != "" {
+			s.discardContent(ctx, uri, content)
+		}
+		return nil, err
+	}
+	return resource, nil
+}
+
+// history returns the resource at uri and every event it has recorded
+func (s *ResourceService) history(ctx context.Context, uri string) (entity.Resource, []domain.Event, error) {
+	resource, err := s.repository.GetByURI(ctx, uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resource == nil {
+		return nil, nil, repository.ErrResourceNotFound
+	}
+	events, err := s.repository.LoadEvents(ctx, resource.ID())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load the history of %s: %w", uri, err)
+	}
+	return resource, events, nil
+}
+
+// resourceAt rebuilds the resource at uri from its events up to version
+func (s *ResourceService) resourceAt(ctx context.Context, uri string, version int) (entity.Resource, error) {
+	resource, events, err := s.history(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	var past []domain.Event
+	for _, evt := range events {
+		if evt.Version() > version {
+			break
+		}
+		found = found || evt.Version() == version && changesData(evt)
+		past = append(past, evt)
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s has no version %d", ErrVersionNotFound, uri, version)
+	}
+
+	rebuilt := entity.NewBasicResourceFromHistory(resource.ID(), past, s.rdfService)
+	if rebuilt.HasErrors() {
+		return nil, errors.Join(rebuilt.GetErrors()...)
+	}
+	return rebuilt, nil
+}
+
+// graphAt parses the data of the resource at uri as it was at version
+func (s *ResourceService) graphAt(ctx context.Context, uri string, version int) (*rdf.Graph, error) {
+	resource, err := s.resourceAt(ctx, uri, version)
+	if err != nil {
+		return nil, err
+	}
+	if resource.IsNonRDF() {
+		return nil, fmt.Errorf("%w: version %d of %s", ErrNotRDF, version, uri)
+	}
+	graph, err := s.rdfService.WithBase(uri).ParseGraph(resource.GetData(), resource.GetContentType())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version %d of %s: %w", version, uri, err)
+	}
+	return graph, nil
+}
+
+// versionOf describes the version recorded by evt
+func versionOf(evt domain.Event) Version {
+	version := Version{Number: evt.Version(), Type: evt.EventType(), Time: evt.OccurredAt()}
+	if authored, ok := evt.(event.Authored); ok {
+		version.Author = authored.Author()
+	}
+	return version
+}
+
+// changesData reports whether an event sets the data of a resource
+func changesData(evt domain.Event) bool {
+	switch evt.(type) {
+	case *event.ResourceCreatedEvent, *event.ResourceUpdatedEvent, *event.ResourceContentStoredEvent:
+		return true
+	}
+	return false
+}
+
+// N3Patch returns the diff as a Solid N3 Patch that turns the first version
+// into the second
+func (d *VersionDiff) N3Patch() string {
+	var b strings.Builder
+	b.WriteString("@prefix solid: <http://www.w3.org/ns/solid/terms#> .\n\n")
+	b.WriteString("_:patch a solid:InsertDeletePatch ;\n")
+	writeFormula(&b, "solid:deletes", d.Deletes)
+	b.WriteString(" ;\n")
+	writeFormula(&b, "solid:inserts", d.Inserts)
+	b.WriteString(" .\n")
+	return b.String()
+}
+
+// writeFormula writes triples as the N3 formula object of predicate
+func writeFormula(b *strings.Builder, predicate string, triples []rdf.Triple) {
+	b.WriteString("  " + predicate + " {")
+	for _, t := range triples {
+		b.WriteString("\n    " + t.String())
+	}
+	if len(triples) > 0 {
+		b.WriteString("\n  ")
+	}
+	b.WriteString("}")
+}
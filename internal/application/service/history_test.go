@@ -0,0 +1,132 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestResourceHistory(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const uri = "https://pod.example.com/notes/a"
+	const first = `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "First" .`
+	const second = `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "Second" .`
+
+	withHistory := func(t *testing.T) (*service.ResourceService, *service.SolidService) {
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), repository.NewMemoryBlobStore(), rdfService, log)
+		_, _, err := resourceService.Put(service.WithAuthor(context.Background(), "alice@example.com"), uri, first, "text/turtle")
+		require.NoError(t, err)
+		_, _, err = resourceService.Put(service.WithAuthor(context.Background(), "bob@example.com"), uri, second, "text/turtle")
+		require.NoError(t, err)
+		return resourceService, service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+	}
+
+	t.Run("lists versions with their authors", func(t *testing.T) {
+		// Arrange
+		_, solidService := withHistory(t)
+		request := httptest.NewRequest(http.MethodGet, uri+"?versions", nil)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err := solidService.GetResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var versions []service.Version
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&versions))
+		require.Len(t, versions, 2)
+		assert.Equal(t, "alice@example.com", versions[0].Author)
+		assert.Equal(t, "bob@example.com", versions[1].Author)
+		assert.Less(t, versions[0].Number, versions[1].Number)
+		assert.False(t, versions[0].Time.IsZero())
+	})
+
+	t.Run("serves a past version in the negotiated format", func(t *testing.T) {
+		// Arrange
+		resourceService, solidService := withHistory(t)
+		versions, err := resourceService.Versions(context.Background(), uri)
+		require.NoError(t, err)
+		request := httptest.NewRequest(http.MethodGet, uri+"?version=1", nil)
+		request.Header.Set("Accept", "application/n-triples")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err = solidService.GetResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, versions[0].Number)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/n-triples", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, first+"\n", recorder.Body.String())
+	})
+
+	t.Run("diffs two versions as an N3 Patch", func(t *testing.T) {
+		// Arrange
+		resourceService, solidService := withHistory(t)
+		versions, err := resourceService.Versions(context.Background(), uri)
+		require.NoError(t, err)
+		request := httptest.NewRequest(http.MethodGet, uri+"?from=1&to="+strconv.Itoa(versions[1].Number), nil)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err = solidService.GetResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "text/n3", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Body.String(), "solid:deletes {\n    "+first+"\n  }")
+		assert.Contains(t, recorder.Body.String(), "solid:inserts {\n    "+second+"\n  }")
+	})
+
+	t.Run("reverts to a past version as a new version", func(t *testing.T) {
+		// Arrange
+		resourceService, solidService := withHistory(t)
+		request := httptest.NewRequest(http.MethodPost, uri+"?revert=1", nil)
+		request = request.WithContext(service.WithAgent(request.Context(), "https://carol.example.com/profile/card#me"))
+		request.Header.Set("From", "mallory@example.com")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err := solidService.CreateResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		versions, err := resourceService.Versions(context.Background(), uri)
+		require.NoError(t, err)
+		require.Len(t, versions, 3)
+		assert.Equal(t, "https://carol.example.com/profile/card#me", versions[2].Author, "the authenticated agent, not the From header")
+		_, data, err := resourceService.Get(context.Background(), uri, string(domainservice.FormatNTriples))
+		require.NoError(t, err)
+		assert.Equal(t, first+"\n", data)
+	})
+
+	t.Run("responds 404 for versions the resource never had", func(t *testing.T) {
+		// Arrange
+		_, solidService := withHistory(t)
+		request := httptest.NewRequest(http.MethodGet, uri+"?version=99", nil)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err := solidService.GetResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
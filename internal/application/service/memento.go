@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wepala/vine-pod/internal/domain/repository"
+)
+
+// TimeMapFormat is the media type of Memento TimeMaps
+const TimeMapFormat = "application/link-format"
+
+// ErrInvalidDatetime is returned for Accept-Datetime values that are not HTTP dates
+var ErrInvalidDatetime = errors.New("invalid Accept-Datetime")
+
+// Memento returns version of the resource at uri, with the time it was
+// recorded as its Memento-Datetime
+func (s *ResourceService) Memento(ctx context.Context, uri string, version int) (Version, error) {
+	resource, err := s.repository.GetByURI(ctx, uri)
+	if err != nil {
+		return Version{}, err
+	}
+	if resource == nil {
+		return Version{}, repository.ErrResourceNotFound
+	}
+	events, err := s.repository.LoadEventsFromVersion(ctx, resource.ID(), version)
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to load the history of %s: %w", uri, err)
+	}
+	if len(events) == 0 || events[0].Version() != version || !changesData(events[0]) {
+		return Version{}, fmt.Errorf("%w: %s has no version %d", ErrVersionNotFound, uri, version)
+	}
+	return versionOf(events[0]), nil
+}
+
+// MementoAt returns the version of the resource at uri that was current at
+// datetime, or its first version when datetime is older than the resource
+func (s *ResourceService) MementoAt(ctx context.Context, uri string, datetime time.Time) (Version, error) {
+	versions, err := s.Versions(ctx, uri)
+	if err != nil {
+		return Version{}, err
+	}
+	if len(versions) == 0 {
+		return Version{}, fmt.Errorf("%w: %s has no versions", ErrVersionNotFound, uri)
+	}
+
+	// HTTP dates have second precision, so versions recorded within the
+	// requested second are current at it
+	memento := versions[0]
+	for _, version := range versions[1:] {
+		if version.Time.Truncate(time.Second).After(datetime) {
+			break
+		}
+		memento = version
+	}
+	return memento, nil
+}
+
+// MementoURI returns the URI of version of the resource at uri
+func MementoURI(uri string, version int) string {
+	return uri + "?version=" + strconv.Itoa(version)
+}
+
+// TimeMapURI returns the URI of the TimeMap of the resource at uri
+func TimeMapURI(uri string) string {
+	return uri + "?timemap"
+}
+
+// TimeMap lists the mementos of the resource at uri in the link format of
+// RFC 6690, as described by RFC 7089
+func TimeMap(uri string, versions []Version) string {
+	links := []string{
+		fmt.Sprintf(`<%s>; rel="original timegate"`, uri),
+	}
+	self := fmt.Sprintf(`<%s>; rel="self"; type="%s"`, TimeMapURI(uri), TimeMapFormat)
+	if len(versions) > 0 {
+		self += fmt.Sprintf(`; from="%s"; until="%s"`, httpDate(versions[0].Time), httpDate(versions[len(versions)-1].Time))
+	}
+	links = append(links, self)
+
+	for i, version := range versions {
+		rel := "memento"
+		switch {
+		case len(versions) == 1:
+			rel = "first last memento"
+		case i == 0:
+			rel = "first memento"
+		case i == len(versions)-1:
+			rel = "last memento"
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"; datetime="%s"`, MementoURI(uri, version.Number), rel, httpDate(version.Time)))
+	}
+	return strings.Join(links, ",\n") + "\n"
+}
+
+// httpDate formats t as an HTTP date
+func httpDate(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
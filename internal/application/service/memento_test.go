@@ -0,0 +1,101 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestMemento(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const uri = "https://pod.example.com/notes/a"
+
+	withHistory := func(t *testing.T) (*service.SolidService, []service.Version) {
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), repository.NewMemoryBlobStore(), rdfService, log)
+		for _, title := range []string{"First", "Second"} {
+			_, _, err := resourceService.Put(context.Background(), uri,
+				`<> <http://purl.org/dc/terms/title> "`+title+`" .`, "text/turtle")
+			require.NoError(t, err)
+		}
+		versions, err := resourceService.Versions(context.Background(), uri)
+		require.NoError(t, err)
+		return service.NewSolidService(&config.Config{}, log, resourceService, rdfService), versions
+	}
+
+	get := func(solidService *service.SolidService, target string, header http.Header) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			request.Header[name] = values
+		}
+		recorder := httptest.NewRecorder()
+		require.NoError(t, solidService.GetResource(request.Context(), recorder, request))
+		return recorder
+	}
+
+	t.Run("redirects from the TimeGate to the memento current at Accept-Datetime", func(t *testing.T) {
+		// Arrange
+		solidService, versions := withHistory(t)
+
+		// Act
+		past := get(solidService, uri, http.Header{"Accept-Datetime": {"Thu, 01 Jan 2015 00:00:00 GMT"}})
+		future := get(solidService, uri, http.Header{"Accept-Datetime": {"Fri, 01 Jan 2100 00:00:00 GMT"}})
+
+		// Assert
+		assert.Equal(t, http.StatusFound, past.Code)
+		assert.Equal(t, service.MementoURI(uri, versions[0].Number), past.Header().Get("Location"))
+		assert.Equal(t, service.MementoURI(uri, versions[1].Number), future.Header().Get("Location"))
+		assert.Equal(t, "Accept-Datetime", future.Header().Get("Vary"))
+		assert.Contains(t, future.Header().Values("Link"), `<`+uri+`>; rel="original timegate"`)
+	})
+
+	t.Run("serves mementos with their Memento-Datetime", func(t *testing.T) {
+		// Arrange
+		solidService, versions := withHistory(t)
+
+		// Act
+		recorder := get(solidService, service.MementoURI(uri, versions[0].Number), http.Header{"Accept": {"application/n-triples"}})
+
+		// Assert
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, versions[0].Time.UTC().Format(http.TimeFormat), recorder.Header().Get("Memento-Datetime"))
+		assert.Contains(t, recorder.Header().Values("Link"), `<`+uri+`?timemap>; rel="timemap"; type="application/link-format"`)
+		assert.Contains(t, recorder.Body.String(), `"First"`)
+	})
+
+	t.Run("lists mementos in the TimeMap", func(t *testing.T) {
+		// Arrange
+		solidService, versions := withHistory(t)
+
+		// Act
+		recorder := get(solidService, service.TimeMapURI(uri), nil)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, service.TimeMapFormat, recorder.Header().Get("Content-Type"))
+		assert.Equal(t, service.TimeMap(uri, versions), recorder.Body.String())
+		assert.Contains(t, recorder.Body.String(), `<`+service.MementoURI(uri, versions[0].Number)+`>; rel="first memento"; datetime="`)
+		assert.Contains(t, recorder.Body.String(), `<`+service.MementoURI(uri, versions[1].Number)+`>; rel="last memento"; datetime="`)
+	})
+
+	t.Run("rejects Accept-Datetime values that are not HTTP dates", func(t *testing.T) {
+		// Arrange
+		solidService, _ := withHistory(t)
+
+		// Act
+		recorder := get(solidService, uri, http.Header{"Accept-Datetime": {"yesterday"}})
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+)
+
+// ACLSuffix is appended to the URI of a resource to name its Web Access
+// Control document
+const ACLSuffix = ".acl"
+
+// ErrInvalidDestination is returned when a resource cannot be moved or copied to the requested URI
+var ErrInvalidDestination = errors.New("invalid destination")
+
+// Move relocates the resource at source to destination, along with its
+// auxiliary resources and, for a container, everything below it. Resources
+// keep their history and record the new URI as an event. Either every
+// resource moves or none.
+func (s *ResourceService) Move(ctx context.Context, source string, destination string) error {
+	return s.relocate(ctx, source, destination, true)
+}
+
+// Copy creates a copy of the resource at source at destination, along with
+// its auxiliary resources and, for a container, every live resource below
+// it. Copies start a new history. Either every resource is copied or none.
+func (s *ResourceService) Copy(ctx context.Context, source string, destination string) error {
+	return s.relocate(ctx, source, destination, false)
+}
+
+// relocate moves or copies the subtree at source to destination
+func (s *ResourceService) relocate(ctx context.Context, source string, destination string, move bool) (err error) {
+	if err := checkDestination(source, destination); err != nil {
+		return err
+	}
+	root, err := s.live(ctx, source)
+	if err != nil {
+		return err
+	}
+	// Trash at the destination is only discarded along with the batch
+	discarded, err := s.destinationTrash(ctx, destination)
+	if err != nil {
+		return err
+	}
+
+	// The destination container's shapes must accept the resource
+	var target entity.Resource
+	if root.IsNonRDF() {
+		target, err = s.newContent(ctx, destination, root.GetContent(), root.GetContentType())
+	} else {
+		target, err = s.newResource(ctx, destination, root.GetData(), root.GetContentType())
+	}
+	if err != nil {
+		return err
+	}
+
+	// Copied bytes are only kept once the copies are saved
+	var copies []entity.Content
+	defer func() {
+		if err != nil {
+			for _, content := range copies {
+				s.discardContent(ctx, destination, content)
+			}
+		}
+	}()
+
+	resources, err := s.subtree(ctx, source)
+	if err != nil {
+		return err
+	}
+	changes := make([]repository.Change, 0, len(discarded)+len(resources))
+	var discardedContent []entity.Content
+	for _, resource := range discarded {
+		events, err := s.repository.LoadEvents(ctx, resource.ID())
+		if err != nil {
+			return err
+		}
+		discardedContent = append(discardedContent, storedContent(events)...)
+		changes = append(changes, repository.Change{Resource: resource, Discard: true})
+	}
+	for _, resource := range resources {
+		from := resource.GetURI()
+		to := destination + strings.TrimPrefix(from, source)
+
+		// Auxiliary resources describe the resources they belong to, so their
+		// links follow them
+		data, contentType := resource.GetData(), resource.GetContentType()
+		relocated := false
+		if isAuxiliary(from) && data != "" {
+			if data, relocated, err = s.relocateIRIs(from, to, data, contentType, source, destination); err != nil {
+				return err
+			}
+			if relocated {
+				contentType = string(domainservice.FormatTurtle)
+			}
+		}
+
+		if move {
+			resource.WithAuthor(authorFrom(ctx)).WithURI(to)
+			if relocated {
+				resource.Update(data, contentType)
+			}
+			if resource.HasErrors() {
+				return errors.Join(resource.GetErrors()...)
+			}
+			changes = append(changes, repository.Change{Resource: resource, From: from})
+			continue
+		}
+
+		if resource.IsDeleted() {
+			continue
+		}
+		var copied entity.Resource
+		switch {
+		case resource.IsNonRDF():
+			content, err := s.copyContent(ctx, to, resource.GetContent())
+			if err != nil {
+				return err
+			}
+			copies = append(copies, content)
+			if copied, err = s.newContent(ctx, to, content, contentType); err != nil {
+				return err
+			}
+		case from == source:
+			copied = target
+		default:
+			if copied, err = s.newResource(ctx, to, data, contentType); err != nil {
+				return err
+			}
+		}
+		changes = append(changes, repository.Change{Resource: copied})
+	}
+
+	if err := s.repository.SaveAll(ctx, changes); err != nil {
+		return fmt.Errorf("failed to relocate %s to %s: %w", source, destination, err)
+	}
+	for _, content := range discardedContent {
+		s.discardContent(ctx, destination, content)
+	}
+	s.logger.Info("Relocated resource",
+		zap.String("source", source),
+		zap.String("destination", destination),
+		zap.Bool("move", move),
+		zap.Int("resources", len(changes)),
+	)
+	return s.shapeTrees.Assign(ctx, target)
+}
+
+// checkDestination rejects destinations that cannot hold the resource at source
+func checkDestination(source string, destination string) error {
+	switch {
+	case destination == source:
+		return fmt.Errorf("%w: %s is the source", ErrInvalidDestination, destination)
+	case podOf(destination) != podOf(source):
+		return fmt.Errorf("%w: %s is in another pod", ErrInvalidDestination, destination)
+	case strings.HasSuffix(source, "/") != strings.HasSuffix(destination, "/"):
+		return fmt.Errorf("%w: containers and resources cannot replace each other", ErrInvalidDestination)
+	case strings.HasSuffix(source, "/") && strings.HasPrefix(destination, source):
+		return fmt.Errorf("%w: %s is inside %s", ErrInvalidDestination, destination, source)
+	case isAuxiliary(source) || isAuxiliary(destination):
+		return fmt.Errorf("%w: auxiliary resources move with the resource they belong to", ErrInvalidDestination)
+	case parentContainer(destination) == "":
+		return fmt.Errorf("%w: %s is the root container", ErrInvalidDestination, destination)
+	}
+	return nil
+}
+
+// destinationTrash returns a deleted resource at destination and the
+// resources left below it, which a move or copy there replaces, and fails
+// when a live one is there
+func (s *ResourceService) destinationTrash(ctx context.Context, destination string) ([]entity.Resource, error) {
+	existing, err := s.repository.GetByURI(ctx, destination)
+	if errors.Is(err, repository.ErrResourceNotFound) || (err == nil && existing == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !existing.IsDeleted() {
+		return nil, fmt.Errorf("%w: %s", repository.ErrResourceExists, destination)
+	}
+	return s.subtree(ctx, destination)
+}
+
+// subtree returns the resource at uri, its auxiliary resources and, for a
+// container, every resource below it, deleted ones included
+func (s *ResourceService) subtree(ctx context.Context, uri string) ([]entity.Resource, error) {
+	root, err := s.repository.GetByURI(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	resources := []entity.Resource{root}
+	if !strings.HasSuffix(uri, "/") {
+		auxiliaries, err := s.auxiliaries(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		return append(resources, auxiliaries...), nil
+	}
+
+	pending := []string{uri}
+	for len(pending) > 0 {
+		container := pending[0]
+		pending = pending[1:]
+		members, err := s.repository.FindByContainer(ctx, container)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			resources = append(resources, member)
+			if strings.HasSuffix(member.GetURI(), "/") {
+				pending = append(pending, member.GetURI())
+			}
+		}
+	}
+	return resources, nil
+}
+
+// relocateIRIs rewrites the IRIs in data that name resources moved from
+// source to destination. It returns the data as Turtle relative to to, and
+// whether any IRI was rewritten.
+func (s *ResourceService) relocateIRIs(from string, to string, data string, contentType string, source string, destination string) (string, bool, error) {
+	graph, err := s.rdfService.WithBase(from).ParseGraph(data, contentType)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", from, err)
+	}
+
+	relocate := func(term rdf.Term) (rdf.Term, bool) {
+		iri, ok := term.(rdf.IRI)
+		if !ok {
+			return term, false
+		}
+		rest, ok := strings.CutPrefix(string(iri), source)
+		if !ok || !(strings.HasSuffix(source, "/") || rest == "" || rest == ACLSuffix || rest == MetaSuffix || strings.HasPrefix(rest, "#")) {
+			return term, false
+		}
+		return rdf.IRI(destination + rest), true
+	}
+
+	relocated := rdf.NewGraph()
+	changed := false
+	for _, t := range graph.Triples() {
+		subject, subjectChanged := relocate(t.Subject)
+		object, objectChanged := relocate(t.Object)
+		changed = changed || subjectChanged || objectChanged
+		relocated.Add(rdf.Triple{Subject: subject, Predicate: t.Predicate, Object: object})
+	}
+	if !changed {
+		return data, false, nil
+	}
+
+	turtle, err := s.rdfService.WithBase(to).SerializeGraph(relocated, string(domainservice.FormatTurtle))
+	if err != nil {
+		return "", false, err
+	}
+	return turtle, true, nil
+}
@@ -0,0 +1,208 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestMoveAndCopy(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const container = "https://pod.example.com/notes/"
+	const uri = container + "a"
+
+	withNotes := func(t *testing.T) (*service.ResourceService, *service.SolidService, *repository.MemoryResourceRepository) {
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		resourceService := service.NewResourceService(repo, repository.NewMemoryBlobStore(), rdfService, log)
+		for target, data := range map[string]string{
+			container:                  `<> <http://purl.org/dc/terms/title> "Notes" .`,
+			container + ".acl":         `<#owner> <http://www.w3.org/ns/auth/acl#accessTo> <https://pod.example.com/notes/> .`,
+			uri:                        `<> <http://purl.org/dc/terms/title> "A" .`,
+			uri + ".acl":               `<#owner> <http://www.w3.org/ns/auth/acl#accessTo> <https://pod.example.com/notes/a> .`,
+			container + "drafts/":      `<> <http://purl.org/dc/terms/title> "Drafts" .`,
+			container + "drafts/b":     `<> <http://purl.org/dc/terms/title> "B" .`,
+			"https://pod.example.com/": `<> <http://purl.org/dc/terms/title> "Root" .`,
+		} {
+			_, _, err := resourceService.Put(context.Background(), target, data, "text/turtle")
+			require.NoError(t, err)
+		}
+		return resourceService, service.NewSolidService(&config.Config{}, log, resourceService, rdfService), repo
+	}
+
+	relocate := func(t *testing.T, handler func(context.Context, http.ResponseWriter, *http.Request) error, source, destination string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("MOVE", source, nil)
+		request.Header.Set("Destination", destination)
+		recorder := httptest.NewRecorder()
+		require.NoError(t, handler(request.Context(), recorder, request))
+		return recorder
+	}
+
+	t.Run("moves a resource with its history and ACL", func(t *testing.T) {
+		// Arrange
+		resourceService, solidService, repo := withNotes(t)
+		const destination = container + "b"
+		original, err := repo.GetByURI(context.Background(), uri)
+		require.NoError(t, err)
+		history, err := repo.LoadEvents(context.Background(), original.ID())
+		require.NoError(t, err)
+
+		// Act
+		recorder := relocate(t, solidService.MoveResource, uri, "/notes/b")
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, destination, recorder.Header().Get("Location"))
+		_, err = repo.GetByURI(context.Background(), uri)
+		assert.Error(t, err)
+		moved, err := repo.GetByURI(context.Background(), destination)
+		require.NoError(t, err)
+		assert.Contains(t, moved.GetData(), `"A"`)
+		events, err := repo.LoadEvents(context.Background(), moved.ID())
+		require.NoError(t, err)
+		assert.Len(t, events, len(history)+1, "the move is recorded after the existing history")
+		versions, err := resourceService.Versions(context.Background(), destination)
+		require.NoError(t, err)
+		assert.Len(t, versions, 1)
+
+		acl, err := repo.GetByURI(context.Background(), destination+service.ACLSuffix)
+		require.NoError(t, err)
+		graph, err := rdfService.WithBase(acl.GetURI()).ParseGraph(acl.GetData(), acl.GetContentType())
+		require.NoError(t, err)
+		assert.Contains(t, graph.String(), "<"+destination+">")
+		assert.NotContains(t, graph.String(), "<"+uri+">")
+	})
+
+	t.Run("moves a container with everything below it", func(t *testing.T) {
+		// Arrange
+		_, solidService, repo := withNotes(t)
+		const destination = "https://pod.example.com/archive/"
+
+		// Act
+		recorder := relocate(t, solidService.MoveResource, container, destination)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		for _, path := range []string{"", ".acl", "a", "a.acl", "drafts/", "drafts/b"} {
+			_, err := repo.GetByURI(context.Background(), destination+path)
+			assert.NoError(t, err, path)
+			_, err = repo.GetByURI(context.Background(), container+path)
+			assert.Error(t, err, path)
+		}
+		members, err := repo.FindByContainer(context.Background(), destination+"drafts/")
+		require.NoError(t, err)
+		require.Len(t, members, 1)
+		assert.Equal(t, destination+"drafts/b", members[0].GetURI())
+	})
+
+	t.Run("copies a container as new resources", func(t *testing.T) {
+		// Arrange
+		resourceService, solidService, repo := withNotes(t)
+		const destination = "https://pod.example.com/copy/"
+		require.NoError(t, resourceService.Delete(context.Background(), container+"drafts/b"))
+
+		// Act
+		recorder := relocate(t, solidService.CopyResource, container, destination)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		original, err := repo.GetByURI(context.Background(), uri)
+		require.NoError(t, err)
+		copied, err := repo.GetByURI(context.Background(), destination+"a")
+		require.NoError(t, err)
+		assert.NotEqual(t, original.ID(), copied.ID())
+		versions, err := resourceService.Versions(context.Background(), destination+"a")
+		require.NoError(t, err)
+		assert.Len(t, versions, 1)
+		_, err = repo.GetByURI(context.Background(), destination+"drafts/b")
+		assert.Error(t, err, "deleted members are not copied")
+	})
+
+	t.Run("refuses to overwrite an existing resource", func(t *testing.T) {
+		// Arrange
+		_, solidService, repo := withNotes(t)
+
+		// Act
+		recorder := relocate(t, solidService.MoveResource, container, "https://pod.example.com/notes/drafts/")
+		overwrite := relocate(t, solidService.MoveResource, container+"drafts/", "https://pod.example.com/")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, "destination inside the source")
+		assert.Equal(t, http.StatusBadRequest, overwrite.Code, "root container")
+		recorder = relocate(t, solidService.MoveResource, container+"drafts/b", uri)
+		assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+		_, err := repo.GetByURI(context.Background(), container+"drafts/b")
+		assert.NoError(t, err)
+	})
+
+	t.Run("replaces trash at the destination", func(t *testing.T) {
+		// Arrange
+		_, solidService, repo := withNotes(t)
+		const destination = container + "drafts/b"
+		request := httptest.NewRequest(http.MethodDelete, destination, nil)
+		require.NoError(t, solidService.DeleteResource(request.Context(), httptest.NewRecorder(), request))
+
+		// Act
+		recorder := relocate(t, solidService.MoveResource, uri, destination)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		moved, err := repo.GetByURI(context.Background(), destination)
+		require.NoError(t, err)
+		assert.False(t, moved.IsDeleted())
+		assert.Contains(t, moved.GetData(), `"A"`)
+	})
+
+	t.Run("keeps trash at the destination when the move fails", func(t *testing.T) {
+		// Arrange
+		repo := &failingRepository{MemoryResourceRepository: repository.NewMemoryResourceRepository(rdfService)}
+		resourceService := service.NewResourceService(repo, repository.NewMemoryBlobStore(), rdfService, log)
+		ctx := context.Background()
+		for _, target := range []string{container, uri, container + "b"} {
+			_, _, err := resourceService.Put(ctx, target, `<> <http://purl.org/dc/terms/title> "Note" .`, "text/turtle")
+			require.NoError(t, err)
+		}
+		require.NoError(t, resourceService.Delete(ctx, container+"b"))
+
+		// Act
+		err := resourceService.Move(ctx, uri, container+"b")
+
+		// Assert
+		assert.Error(t, err)
+		restored, err := resourceService.Restore(ctx, container+"b")
+		require.NoError(t, err)
+		assert.False(t, restored.IsDeleted())
+	})
+
+	t.Run("refuses destinations on another server", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withNotes(t)
+
+		// Act
+		recorder := relocate(t, solidService.CopyResource, uri, "https://elsewhere.example.com/notes/a")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+// failingRepository fails every batch, as a store that cannot write does
+type failingRepository struct {
+	*repository.MemoryResourceRepository
+}
+
+func (r *failingRepository) SaveAll(ctx context.Context, changes []domainrepository.Change) error {
+	return errors.New("store unavailable")
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+)
+
+// maxRanges is the most ranges served in one response. Requests for more
+// are served the whole content.
+const maxRanges = 16
+
+// errRangeNotSatisfiable is returned when none of the requested ranges
+// overlap the content
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// byteRange is a part of a representation, as requested with a Range header
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+// contentRange returns the Content-Range of r in a representation of size bytes
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// parseRange returns the ranges requested by a Range header from a
+// representation of size bytes, leaving out those beyond its end. No
+// ranges are returned when the header is missing, malformed, in another
+// unit or asks for more than maxRanges, so the whole content is served.
+func parseRange(header string, size int64) ([]byteRange, error) {
+	specs, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok {
+		return nil, nil
+	}
+	parts := strings.Split(specs, ",")
+	if len(parts) > maxRanges {
+		return nil, nil
+	}
+
+	var ranges []byteRange
+	for _, part := range parts {
+		first, last, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, nil
+		}
+		if first == "" {
+			// A suffix range asks for the last bytes of the content
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, nil
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			start := max(size-n, 0)
+			ranges = append(ranges, byteRange{start: start, length: size - start})
+			continue
+		}
+
+		start, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return nil, nil
+		}
+		end := size - 1
+		if last != "" {
+			if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+				return nil, nil
+			}
+			end = min(end, size-1)
+		}
+		if start >= size {
+			continue
+		}
+		ranges = append(ranges, byteRange{start: start, length: end - start + 1})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("%w: %s", errRangeNotSatisfiable, header)
+	}
+	return ranges, nil
+}
+
+// ifRangeMatches reports whether the If-Range header of r, if any, names
+// the current version of resource. Only strong entity tags and exact
+// modification dates match.
+func ifRangeMatches(r *http.Request, resource entity.Resource) bool {
+	value := strings.TrimSpace(r.Header.Get("If-Range"))
+	switch {
+	case value == "":
+		return true
+	case strings.HasPrefix(value, `"`):
+		return value == resource.GetETag()
+	case strings.HasPrefix(value, "W/"):
+		return false
+	}
+	date, err := http.ParseTime(value)
+	return err == nil && date.Equal(resource.GetLastModified().Truncate(time.Second))
+}
@@ -0,0 +1,121 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestRangeRequests(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const uri = "https://pod.example.com/files/alphabet.txt"
+
+	withFile := func(t *testing.T) *service.SolidService {
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), repository.NewMemoryBlobStore(), rdfService, log)
+		_, _, err := resourceService.PutContent(context.Background(), uri, strings.NewReader("abcdefghijklmnopqrstuvwxyz"), "text/plain")
+		require.NoError(t, err)
+		return service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+	}
+
+	get := func(t *testing.T, solidService *service.SolidService, headers map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, uri, nil)
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		require.NoError(t, solidService.GetResource(request.Context(), recorder, request))
+		return recorder
+	}
+
+	t.Run("serves a single range as partial content", func(t *testing.T) {
+		// Arrange
+		solidService := withFile(t)
+
+		// Act
+		recorder := get(t, solidService, map[string]string{"Range": "bytes=2-4"})
+		suffix := get(t, solidService, map[string]string{"Range": "bytes=-3"})
+
+		// Assert
+		assert.Equal(t, http.StatusPartialContent, recorder.Code)
+		assert.Equal(t, "cde", recorder.Body.String())
+		assert.Equal(t, "bytes 2-4/26", recorder.Header().Get("Content-Range"))
+		assert.Equal(t, "3", recorder.Header().Get("Content-Length"))
+		assert.Equal(t, "bytes", recorder.Header().Get("Accept-Ranges"))
+		assert.Equal(t, "xyz", suffix.Body.String())
+		assert.Equal(t, "bytes 23-25/26", suffix.Header().Get("Content-Range"))
+	})
+
+	t.Run("serves several ranges as multipart byteranges", func(t *testing.T) {
+		// Arrange
+		solidService := withFile(t)
+
+		// Act
+		recorder := get(t, solidService, map[string]string{"Range": "bytes=0-1, 24-"})
+
+		// Assert
+		require.Equal(t, http.StatusPartialContent, recorder.Code)
+		mediaType, params, err := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/byteranges", mediaType)
+		reader := multipart.NewReader(recorder.Body, params["boundary"])
+		var parts, ranges []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			data, err := io.ReadAll(part)
+			require.NoError(t, err)
+			parts = append(parts, string(data))
+			ranges = append(ranges, part.Header.Get("Content-Range"))
+		}
+		assert.Equal(t, []string{"ab", "yz"}, parts)
+		assert.Equal(t, []string{"bytes 0-1/26", "bytes 24-25/26"}, ranges)
+	})
+
+	t.Run("rejects ranges beyond the content", func(t *testing.T) {
+		// Arrange
+		solidService := withFile(t)
+
+		// Act
+		recorder := get(t, solidService, map[string]string{"Range": "bytes=26-30"})
+
+		// Assert
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, recorder.Code)
+		assert.Equal(t, "bytes */26", recorder.Header().Get("Content-Range"))
+	})
+
+	t.Run("serves everything when If-Range names another version", func(t *testing.T) {
+		// Arrange
+		solidService := withFile(t)
+		etag := get(t, solidService, nil).Header().Get("ETag")
+
+		// Act
+		matching := get(t, solidService, map[string]string{"Range": "bytes=0-0", "If-Range": etag})
+		stale := get(t, solidService, map[string]string{"Range": "bytes=0-0", "If-Range": `"0123456789"`})
+		malformed := get(t, solidService, map[string]string{"Range": "bytes=z-0"})
+
+		// Assert
+		assert.Equal(t, http.StatusPartialContent, matching.Code)
+		assert.Equal(t, "a", matching.Body.String())
+		assert.Equal(t, http.StatusOK, stale.Code)
+		assert.Equal(t, "abcdefghijklmnopqrstuvwxyz", stale.Body.String())
+		assert.Equal(t, http.StatusOK, malformed.Code)
+	})
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+// ErrUnsupportedMediaType is returned when a request body is not a supported RDF format
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// ResourceService creates and replaces RDF resources, validating each one
+// against the shapes its container declares, and non-RDF resources whose
+// bytes are kept in a blob store
+type ResourceService struct {
+	repository repository.ResourceRepository
+	blobs      repository.BlobStore
+	rdfService domainservice.RDFValidationService
+	shapes     *ShapesResolver
+	shapeTrees *ShapeTreeService
+	skolemizer *Skolemizer
+	logger     logger.Logger
+
+	// uploadExpiry is how long uploads are kept without receiving any bytes
+	uploadExpiry time.Duration
+}
+
+// NewResourceService creates a new resource service
+func NewResourceService(repo repository.ResourceRepository, blobs repository.BlobStore, rdfService domainservice.RDFValidationService, logger logger.Logger) *ResourceService {
+	return &ResourceService{
+		repository: repo,
+		blobs:      blobs,
+		rdfService: rdfService,
+		shapes:     NewShapesResolver(repo, rdfService),
+		shapeTrees: NewShapeTreeService(repo, rdfService, logger),
+		skolemizer: NewSkolemizer(repo, rdfService),
+		logger:     logger,
+
+		uploadExpiry: DefaultUploadExpiry,
+	}
+}
+
+// Create stores data as a new resource inside containerURI. The slug is
+// used as the resource name when it is free; otherwise a name is generated.
+func (s *ResourceService) Create(ctx context.Context, containerURI string, slug string, data string, contentType string) (entity.Resource, error) {
+	return s.create(ctx, containerURI, slug, "", data, contentType)
+}
+
+// CreateContainer stores data as a new container inside containerURI,
+// naming it like Create does
+func (s *ResourceService) CreateContainer(ctx context.Context, containerURI string, slug string, data string, contentType string) (entity.Resource, error) {
+	return s.create(ctx, containerURI, slug, "/", data, contentType)
+}
+
+// create stores a new member of containerURI whose URI ends in suffix
+func (s *ResourceService) create(ctx context.Context, containerURI string, slug string, suffix string, data string, contentType string) (entity.Resource, error) {
+	containerURI = withTrailingSlash(containerURI)
+
+	uri, err := s.newChildURI(ctx, containerURI, slug, suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := s.newResource(ctx, uri, data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, resource); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Created resource", zap.String("uri", uri))
+	return resource, nil
+}
+
+// Put creates the resource at uri or replaces its data. created reports
+// whether the resource did not exist before or was deleted.
+func (s *ResourceService) Put(ctx context.Context, uri string, data string, contentType string) (resource entity.Resource, created bool, err error) {
+	if strings.HasSuffix(uri, "/"+ShapeTreeSuffix) {
+		return nil, false, fmt.Errorf("%w: %s", ErrProtectedResource, uri)
+	}
+	if isDescription(uri) {
+		if data, contentType, err = s.withoutManaged(ctx, uri, data, contentType); err != nil {
+			return nil, false, err
+		}
+		if data == "" {
+			resource, err = s.clearDescription(ctx, uri)
+			return resource, false, err
+		}
+	}
+
+	existing, err := s.repository.GetByURI(ctx, uri)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, false, err
+	}
+
+	switch {
+	case err != nil || existing == nil:
+		resource, err = s.newResource(ctx, uri, data, contentType)
+		created = true
+	case existing.IsDeleted():
+		// Writing to a tombstone brings the resource back with the new data
+		existing.WithAuthor(authorFrom(ctx)).Restore()
+		resource, err = s.updateResource(ctx, existing, data, contentType)
+		created = true
+	default:
+		resource, err = s.updateResource(ctx, existing, data, contentType)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.save(ctx, resource); err != nil {
+		return nil, false, err
+	}
+
+	s.logger.Info("Stored resource", zap.String("uri", uri), zap.Bool("created", created))
+	return resource, created, nil
+}
+
+// Get returns the resource at uri and its data serialized in format. Skolem
+// IRIs are turned back into blank nodes when its container asks for it.
+// Containers list their members, and descriptions include the metadata the
+// server manages for the resource they describe.
+func (s *ResourceService) Get(ctx context.Context, uri string, format string) (entity.Resource, string, error) {
+	if isDescription(uri) {
+		return s.getDescription(ctx, uri, format)
+	}
+	resource, err := s.live(ctx, uri)
+	if err != nil {
+		return nil, "", err
+	}
+	if resource.IsNonRDF() {
+		return resource, "", nil
+	}
+
+	graph, err := s.graph(ctx, uri, resource)
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.HasSuffix(uri, "/") {
+		if err := s.addContainment(ctx, uri, resource, graph); err != nil {
+			return nil, "", err
+		}
+	}
+	data, err := s.rdfService.WithBase(uri).SerializeGraph(graph, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return resource, data, nil
+}
+
+// serialize returns the data of the resource at uri in format. Non-RDF
+// resources have no data to serialize; their bytes are read with Open.
+func (s *ResourceService) serialize(ctx context.Context, uri string, resource entity.Resource, format string) (string, error) {
+	if resource.IsNonRDF() {
+		return "", nil
+	}
+	graph, err := s.graph(ctx, uri, resource)
+	if err != nil {
+		return "", err
+	}
+	return s.rdfService.WithBase(uri).SerializeGraph(graph, format)
+}
+
+// graph parses the data of the resource at uri, turning skolem IRIs back
+// into blank nodes when its container asks for it
+func (s *ResourceService) graph(ctx context.Context, uri string, resource entity.Resource) (*rdf.Graph, error) {
+	graph, err := s.rdfService.WithBase(uri).ParseGraph(resource.GetData(), resource.GetContentType())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+	return s.skolemizer.Deskolemize(ctx, uri, graph)
+}
+
+// save stores a resource and assigns shape trees to a new container
+func (s *ResourceService) save(ctx context.Context, resource entity.Resource) error {
+	if err := s.repository.Save(ctx, resource); err != nil {
+		return fmt.Errorf("failed to save %s: %w", resource.GetURI(), err)
+	}
+	return s.shapeTrees.Assign(ctx, resource)
+}
+
+// newResource builds a resource at uri from data, checking it against the
+// container's shapes
+func (s *ResourceService) newResource(ctx context.Context, uri string, data string, contentType string) (entity.Resource, error) {
+	constraints, err := s.shapes.ConstraintsFor(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, err := s.mediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if data, mediaType, err = s.skolemizer.Skolemize(ctx, uri, data, mediaType, nil); err != nil {
+		return nil, err
+	}
+
+	// Relative IRIs resolve against the target URI; the data keeps them
+	// relative so they follow the resource if it moves
+	resource := constraints.Apply(entity.NewBasicResourceWithValidator(s.rdfService)).
+		WithBase(uri).
+		WithAuthor(authorFrom(ctx))
+	switch domainservice.RDFFormat(mediaType) {
+	case domainservice.FormatJSONLD:
+		resource.FromJSONLD(data)
+	case domainservice.FormatTurtle:
+		resource.FromTurtle(data)
+	case domainservice.FormatRDFXML:
+		resource.FromRDFXML(data)
+	default:
+		turtle, err := s.rdfService.WithBase(uri).ConvertFormat(data, mediaType, string(domainservice.FormatTurtle))
+		if err != nil {
+			return nil, err
+		}
+		resource.FromTurtle(turtle)
+	}
+	resource.WithURI(uri)
+
+	if resource.HasErrors() {
+		return nil, errors.Join(resource.GetErrors()...)
+	}
+	return resource, nil
+}
+
+// updateResource replaces the data of an existing resource, checking it
+// against the container's shapes
+func (s *ResourceService) updateResource(ctx context.Context, resource entity.Resource, data string, contentType string) (entity.Resource, error) {
+	constraints, err := s.shapes.ConstraintsFor(ctx, resource.GetURI())
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, err := s.mediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if data, mediaType, err = s.skolemizer.Skolemize(ctx, resource.GetURI(), data, mediaType, resource); err != nil {
+		return nil, err
+	}
+
+	constraints.Apply(resource).WithAuthor(authorFrom(ctx)).Update(data, mediaType)
+	if resource.HasErrors() {
+		return nil, errors.Join(resource.GetErrors()...)
+	}
+	return resource, nil
+}
+
+// mediaType strips parameters from contentType and checks it is a supported RDF format
+func (s *ResourceService) mediaType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+	for _, format := range s.rdfService.SupportedFormats() {
+		if format == mediaType {
+			return mediaType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+}
+
+// newChildURI returns a URI inside containerURI that is not in use
+func (s *ResourceService) newChildURI(ctx context.Context, containerURI string, slug string, suffix string) (string, error) {
+	base := sanitizeSlug(slug)
+	for attempt := 0; attempt < 5; attempt++ {
+		name := base
+		if name == "" || attempt > 0 {
+			random, err := randomName()
+			if err != nil {
+				return "", err
+			}
+			name = strings.TrimPrefix(base+"-"+random, "-")
+		}
+
+		uri := containerURI + name + suffix
+		_, err := s.repository.GetByURI(ctx, uri)
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return uri, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("could not find a free name in %s", containerURI)
+}
+
+// sanitizeSlug keeps the characters of a Slug header that are safe in a path segment
+func sanitizeSlug(slug string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-_.~", r):
+			return r
+		case r == ' ':
+			return '-'
+		}
+		return -1
+	}, strings.Trim(slug, ". "))
+}
+
+// randomName returns a random resource name
+func randomName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,318 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+// newMapRepository returns a repository mock backed by a map of resources by URI
+func newMapRepository(resources map[string]entity.Resource) *repository.ResourceRepositoryMock {
+	return &repository.ResourceRepositoryMock{
+		GetByURIFunc: func(ctx context.Context, uri string) (entity.Resource, error) {
+			if resource, ok := resources[uri]; ok {
+				return resource, nil
+			}
+			return nil, repository.ErrResourceNotFound
+		},
+		SaveFunc: func(ctx context.Context, resource entity.Resource) error {
+			resources[resource.GetURI()] = resource
+			resource.MarkEventsAsCommitted()
+			return nil
+		},
+		FindByContainerFunc: func(ctx context.Context, containerURI string) ([]entity.Resource, error) {
+			var children []entity.Resource
+			for uri, resource := range resources {
+				name := strings.TrimPrefix(uri, containerURI)
+				if name != uri && name != "" && !strings.Contains(strings.TrimSuffix(name, "/"), "/") {
+					children = append(children, resource)
+				}
+			}
+			return children, nil
+		},
+		DeleteFunc: func(ctx context.Context, id string) error {
+			for uri, resource := range resources {
+				if resource.ID() == id {
+					delete(resources, uri)
+					return nil
+				}
+			}
+			return repository.ErrResourceNotFound
+		},
+	}
+}
+
+func TestResourceService(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+
+	newResource := func(uri, turtle string) entity.Resource {
+		return entity.NewBasicResourceWithValidator(rdfService).FromTurtle(turtle).WithURI(uri)
+	}
+	withShapes := func() map[string]entity.Resource {
+		return map[string]entity.Resource{
+			"https://pod.example.com/notes/.meta": newResource("https://pod.example.com/notes/.meta",
+				`<https://pod.example.com/notes/> <http://www.w3.org/ns/shacl#shapesGraph> <https://pod.example.com/shapes/note#shapes> .`),
+			"https://pod.example.com/shapes/note": newResource("https://pod.example.com/shapes/note", `@prefix sh: <http://www.w3.org/ns/shacl#> .
+@prefix dcterms: <http://purl.org/dc/terms/> .
+<https://pod.example.com/shapes/note#NoteShape> sh:targetSubjectsOf dcterms:created ;
+    sh:property [ sh:path dcterms:title ; sh:minCount 1 ; sh:message "A note needs a title" ] .`),
+		}
+	}
+
+	t.Run("creates a resource named by the slug", func(t *testing.T) {
+		// Arrange
+		resourceService := service.NewResourceService(newMapRepository(withShapes()), nil, rdfService, log)
+
+		// Act
+		resource, err := resourceService.Create(context.Background(), "https://pod.example.com/notes/", "first note",
+			`<https://pod.example.com/notes/first-note> <http://purl.org/dc/terms/created> "2024-01-01" ; <http://purl.org/dc/terms/title> "Hi" .`,
+			"text/turtle; charset=utf-8")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "https://pod.example.com/notes/first-note", resource.GetURI())
+	})
+
+	t.Run("rejects children that violate the container shapes", func(t *testing.T) {
+		// Arrange
+		resources := withShapes()
+		resourceService := service.NewResourceService(newMapRepository(resources), nil, rdfService, log)
+
+		// Act
+		_, _, err := resourceService.Put(context.Background(), "https://pod.example.com/notes/bad",
+			`<https://pod.example.com/notes/bad> <http://purl.org/dc/terms/created> "2024-01-01" .`, "text/turtle")
+
+		// Assert
+		var shapeErr *domainservice.ShapeValidationError
+		require.ErrorAs(t, err, &shapeErr)
+		assert.Equal(t, "A note needs a title", shapeErr.Report.Results[0].Messages[0].Lexical)
+		assert.NotContains(t, resources, "https://pod.example.com/notes/bad")
+	})
+
+	t.Run("resolves relative IRIs against the target URI", func(t *testing.T) {
+		// Arrange
+		resources := withShapes()
+		resourceService := service.NewResourceService(newMapRepository(resources), nil, rdfService, log)
+
+		// Act
+		_, _, invalid := resourceService.Put(context.Background(), "https://pod.example.com/notes/bad",
+			`<> <http://purl.org/dc/terms/created> "2024-01-01" .`, "text/turtle")
+		resource, _, valid := resourceService.Put(context.Background(), "https://pod.example.com/notes/good",
+			`<> <http://purl.org/dc/terms/created> "2024-01-01" ; <http://purl.org/dc/terms/title> "Hi" .`, "text/turtle")
+
+		// Assert
+		var shapeErr *domainservice.ShapeValidationError
+		require.ErrorAs(t, invalid, &shapeErr)
+		assert.Equal(t, rdf.IRI("https://pod.example.com/notes/bad"), shapeErr.Report.Results[0].FocusNode)
+		require.NoError(t, valid)
+		assert.Equal(t, "https://pod.example.com/notes/good", resource.ID())
+		assert.True(t, strings.HasPrefix(resources["https://pod.example.com/notes/good"].GetData(), "<>"))
+	})
+
+	t.Run("does not apply shapes outside the container", func(t *testing.T) {
+		// Arrange
+		resourceService := service.NewResourceService(newMapRepository(withShapes()), nil, rdfService, log)
+
+		// Act
+		_, created, err := resourceService.Put(context.Background(), "https://pod.example.com/other/bad",
+			`<https://pod.example.com/other/bad> <http://purl.org/dc/terms/created> "2024-01-01" .`, "text/turtle")
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, created)
+	})
+
+	t.Run("rejects unsupported media types", func(t *testing.T) {
+		// Arrange
+		resourceService := service.NewResourceService(newMapRepository(withShapes()), nil, rdfService, log)
+
+		// Act
+		_, _, err := resourceService.Put(context.Background(), "https://pod.example.com/a", "hello", "text/plain")
+
+		// Assert
+		assert.ErrorIs(t, err, service.ErrUnsupportedMediaType)
+	})
+
+	t.Run("responds to shape violations with a validation report", func(t *testing.T) {
+		// Arrange
+		resourceService := service.NewResourceService(newMapRepository(withShapes()), nil, rdfService, log)
+		solidService := service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+		request := httptest.NewRequest(http.MethodPost, "https://pod.example.com/notes/",
+			strings.NewReader(`<https://pod.example.com/notes/x> <http://purl.org/dc/terms/created> "2024-01-01" .`))
+		request.Header.Set("Content-Type", "text/turtle")
+		request.Header.Set("Accept", "application/n-triples")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err := solidService.CreateResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		assert.Equal(t, "application/n-triples", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Body.String(), `<http://www.w3.org/ns/shacl#conforms> "false"^^<http://www.w3.org/2001/XMLSchema#boolean>`)
+		assert.Contains(t, recorder.Body.String(), `<http://www.w3.org/ns/shacl#resultMessage> "A note needs a title"`)
+	})
+
+	t.Run("rejects members that do not conform to a ShEx schema stored in the pod", func(t *testing.T) {
+		// Arrange
+		resources := map[string]entity.Resource{
+			"https://pod.example.com/people/.meta": newResource("https://pod.example.com/people/.meta",
+				`<https://pod.example.com/people/> <http://www.w3.org/ns/ldp#constrainedBy> <https://pod.example.com/shapes/person#Person> .`),
+			"https://pod.example.com/shapes/person": entity.NewBasicResourceWithValidator(rdfService).FromJSONLD(`{
+  "@context": "http://www.w3.org/ns/shex.jsonld",
+  "@id": "https://pod.example.com/shapes/person",
+  "type": "Schema",
+  "shapes": [{
+    "id": "https://pod.example.com/shapes/person#Person",
+    "type": "Shape",
+    "expression": {"type": "TripleConstraint", "predicate": "http://xmlns.com/foaf/0.1/name"}
+  }]
+}`).WithURI("https://pod.example.com/shapes/person"),
+		}
+		resourceService := service.NewResourceService(newMapRepository(resources), nil, rdfService, log)
+
+		// Act
+		_, _, valid := resourceService.Put(context.Background(), "https://pod.example.com/people/alice",
+			`<https://pod.example.com/people/alice> <http://xmlns.com/foaf/0.1/name> "Alice" .`, "text/turtle")
+		_, _, invalid := resourceService.Put(context.Background(), "https://pod.example.com/people/bob",
+			`<https://pod.example.com/people/bob> <http://xmlns.com/foaf/0.1/nick> "Bob" .`, "text/turtle")
+
+		// Assert
+		require.NoError(t, valid)
+		var shapeErr *domainservice.ShapeValidationError
+		require.ErrorAs(t, invalid, &shapeErr)
+		assert.Equal(t, rdf.IRI("https://pod.example.com/people/bob"), shapeErr.Report.Results[0].FocusNode)
+		assert.Equal(t, rdf.IRI("https://pod.example.com/shapes/person#Person"), shapeErr.Report.Results[0].SourceShape)
+	})
+
+	t.Run("fetches remote ShExC schemas and uses their start shape", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/shex")
+			_, _ = w.Write([]byte(`PREFIX dcterms: <http://purl.org/dc/terms/>
+start = @<#Note>
+<#Note> { dcterms:title LITERAL MAXLENGTH 10 }`))
+		}))
+		defer server.Close()
+		resources := map[string]entity.Resource{
+			"https://pod.example.com/notes/.meta": newResource("https://pod.example.com/notes/.meta",
+				`<https://pod.example.com/notes/> <http://www.w3.org/ns/ldp#constrainedBy> <`+server.URL+`/note.shex> .`),
+		}
+		resourceService := service.NewResourceService(newMapRepository(resources), nil, rdfService, log)
+
+		// Act
+		_, _, err := resourceService.Put(context.Background(), "https://pod.example.com/notes/long",
+			`<https://pod.example.com/notes/long> <http://purl.org/dc/terms/title> "A very long title" .`, "text/turtle")
+
+		// Assert
+		var shapeErr *domainservice.ShapeValidationError
+		require.ErrorAs(t, err, &shapeErr)
+		assert.Contains(t, shapeErr.Report.Results[0].Messages[0].Lexical, "longer than 10")
+	})
+
+	t.Run("creates resources over HTTP", func(t *testing.T) {
+		// Arrange
+		resourceService := service.NewResourceService(newMapRepository(withShapes()), nil, rdfService, log)
+		solidService := service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+		request := httptest.NewRequest(http.MethodPut, "https://pod.example.com/people/alice",
+			strings.NewReader(`{"@id": "https://pod.example.com/people/alice#me", "http://xmlns.com/foaf/0.1/name": "Alice"}`))
+		request.Header.Set("Content-Type", "application/ld+json")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err := solidService.UpdateResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, "https://pod.example.com/people/alice", recorder.Header().Get("Location"))
+	})
+
+	t.Run("serves the JSON-LD form named by the Accept profile", func(t *testing.T) {
+		// Arrange
+		resourceService := service.NewResourceService(newMapRepository(withShapes()), nil, rdfService, log)
+		solidService := service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+		_, _, err := resourceService.Put(context.Background(), "https://pod.example.com/people/alice",
+			`<https://pod.example.com/people/alice#me> <http://xmlns.com/foaf/0.1/name> "Alice" .`, "text/turtle")
+		require.NoError(t, err)
+		get := func(accept string) *httptest.ResponseRecorder {
+			request := httptest.NewRequest(http.MethodGet, "https://pod.example.com/people/alice", nil)
+			request.Header.Set("Accept", accept)
+			recorder := httptest.NewRecorder()
+			require.NoError(t, solidService.GetResource(request.Context(), recorder, request))
+			return recorder
+		}
+
+		// Act
+		compacted := get(`application/ld+json; profile="http://www.w3.org/ns/json-ld#compacted"; q=0.9, text/html; q=0.1`)
+		expanded := get("application/ld+json")
+
+		// Assert
+		require.Equal(t, http.StatusOK, compacted.Code)
+		assert.Equal(t, `application/ld+json; profile="http://www.w3.org/ns/json-ld#compacted"`, compacted.Header().Get("Content-Type"))
+		assert.NotContains(t, compacted.Body.String(), "@graph")
+		assert.Contains(t, compacted.Body.String(), `"@id": "#me"`)
+		assert.Contains(t, expanded.Body.String(), "@graph")
+	})
+
+	t.Run("rejects request bodies over the size limit", func(t *testing.T) {
+		// Arrange
+		resourceService := service.NewResourceService(newMapRepository(withShapes()), nil, rdfService, log)
+		cfg := &config.Config{Solid: config.SolidConfig{MaxRDFBytes: 16}}
+		solidService := service.NewSolidService(cfg, log, resourceService, rdfService)
+		request := httptest.NewRequest(http.MethodPut, "https://pod.example.com/people/alice",
+			strings.NewReader(`<https://pod.example.com/people/alice> <http://xmlns.com/foaf/0.1/name> "Alice" .`))
+		request.Header.Set("Content-Type", "text/turtle")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err := solidService.UpdateResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+
+	t.Run("rejects request bodies with too many triples before reading the rest", func(t *testing.T) {
+		// Arrange
+		limited := domainservice.NewStandardRDFValidationServiceWithOptions(domainservice.RDFServiceOptions{
+			Limits: &domainservice.ParseLimits{MaxTriples: 1},
+		})
+		resourceService := service.NewResourceService(newMapRepository(withShapes()), nil, limited, log)
+		solidService := service.NewSolidService(&config.Config{}, log, resourceService, limited)
+		body := io.MultiReader(
+			strings.NewReader(`<https://pod.example.com/people/alice> <http://xmlns.com/foaf/0.1/name> "Alice" .
+<https://pod.example.com/people/alice> <http://xmlns.com/foaf/0.1/nick> "ali" .
+`),
+			iotest.ErrReader(errors.New("connection reset")),
+		)
+		request := httptest.NewRequest(http.MethodPut, "https://pod.example.com/people/alice", body)
+		request.Header.Set("Content-Type", "text/turtle")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err := solidService.UpdateResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+)
+
+// MetaSuffix is appended to the URI of a resource to name the auxiliary
+// resource that describes it
+const MetaSuffix = ".meta"
+
+// LDPConstrainedBy links a container to the ShEx shape its members must conform to
+const LDPConstrainedBy = rdf.IRI("http://www.w3.org/ns/ldp#constrainedBy")
+
+// maxSchemaBytes limits the size of a remotely fetched schema or shape tree
+const maxSchemaBytes = 1 << 20
+
+// Constraints are the shapes a container requires its members to conform to
+type Constraints struct {
+	// Shapes is the merged SHACL shapes graph
+	Shapes *rdf.Graph
+
+	// Schema and ShapeMap hold the ShEx shapes, with the member as focus node
+	Schema   *domainservice.ShExSchema
+	ShapeMap domainservice.ShapeMap
+}
+
+// Apply sets the constraints on a resource before it is created or updated
+func (c *Constraints) Apply(resource entity.Resource) entity.Resource {
+	if c == nil {
+		return resource
+	}
+	return resource.WithShapes(c.Shapes).WithShEx(c.Schema, c.ShapeMap)
+}
+
+// addShEx merges a ShEx schema and the associations to check against it
+func (c *Constraints) addShEx(schema *domainservice.ShExSchema, shapeMap domainservice.ShapeMap) {
+	if schema == nil {
+		return
+	}
+	if c.Schema == nil {
+		c.Schema = domainservice.NewShExSchema()
+	}
+	for label, expr := range schema.Shapes {
+		c.Schema.Shapes[label] = expr
+	}
+	c.ShapeMap = append(c.ShapeMap, shapeMap...)
+}
+
+// ShapesResolver finds the shapes that a container requires its children
+// to conform to. A container declares them in its .meta resource, as SHACL
+// shapes graphs or ShEx shapes:
+//
+//	</people/> sh:shapesGraph </shapes/person.ttl> .
+//	</people/> ldp:constrainedBy <https://example.com/person.shex#Person> .
+type ShapesResolver struct {
+	repository repository.ResourceRepository
+	rdfService domainservice.RDFValidationService
+	httpClient *http.Client
+}
+
+// NewShapesResolver creates a new shapes resolver
+func NewShapesResolver(repo repository.ResourceRepository, rdfService domainservice.RDFValidationService) *ShapesResolver {
+	return &ShapesResolver{
+		repository: repo,
+		rdfService: rdfService,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ConstraintsFor returns the SHACL, ShEx and shape tree constraints that
+// apply to the RDF resource at uri, or nil when its container declares none.
+// A *ShapeTreeError is returned when the shape trees managing the container
+// do not allow the resource at all.
+func (r *ShapesResolver) ConstraintsFor(ctx context.Context, uri string) (*Constraints, error) {
+	return r.constraintsFor(ctx, uri, resourceType(uri, false))
+}
+
+// ContentConstraintsFor returns the constraints that apply to the non-RDF
+// resource at uri, like ConstraintsFor does
+func (r *ShapesResolver) ContentConstraintsFor(ctx context.Context, uri string) (*Constraints, error) {
+	return r.constraintsFor(ctx, uri, resourceType(uri, true))
+}
+
+// constraintsFor returns the constraints that apply to the resource at uri,
+// whose shape tree resource type is expects
+func (r *ShapesResolver) constraintsFor(ctx context.Context, uri string, expects rdf.IRI) (*Constraints, error) {
+	container := parentContainer(uri)
+	if container == "" || isAuxiliary(uri) {
+		return nil, nil
+	}
+
+	meta, err := r.load(ctx, container+MetaSuffix)
+	if err != nil {
+		return nil, err
+	}
+	constraints := &Constraints{}
+	if meta != nil {
+		if constraints.Shapes, err = r.shapesGraph(ctx, container, meta); err != nil {
+			return nil, err
+		}
+		schema, shapeMap, err := r.shexShapes(ctx, container, meta, uri)
+		if err != nil {
+			return nil, err
+		}
+		constraints.addShEx(schema, shapeMap)
+	}
+	if err := r.addShapeTreeConstraints(ctx, container, uri, expects, constraints); err != nil {
+		return nil, err
+	}
+
+	if constraints.Shapes == nil && constraints.Schema == nil {
+		return nil, nil
+	}
+	return constraints, nil
+}
+
+// shapesGraph merges the SHACL shapes graphs declared for container
+func (r *ShapesResolver) shapesGraph(ctx context.Context, container string, meta *rdf.Graph) (*rdf.Graph, error) {
+	metaURI := container + MetaSuffix
+	declared := meta.Match(rdf.IRI(container), domainservice.SHShapesGraph, nil)
+	if len(declared) == 0 {
+		return nil, nil
+	}
+
+	shapes := rdf.NewGraph()
+	for _, t := range declared {
+		ref, ok := t.Object.(rdf.IRI)
+		if !ok {
+			return nil, fmt.Errorf("%s declares a shapes graph that is not an IRI: %s", metaURI, t.Object)
+		}
+		document, _, _ := strings.Cut(string(ref), "#")
+		if document == metaURI {
+			shapes.Merge(meta)
+			continue
+		}
+		graph, err := r.load(ctx, document)
+		if err != nil {
+			return nil, err
+		}
+		if graph == nil {
+			return nil, fmt.Errorf("shapes graph %s declared by %s was not found", document, metaURI)
+		}
+		shapes.Merge(graph)
+	}
+	return shapes, nil
+}
+
+// shexShapes builds a schema and shape map from the container's
+// ldp:constrainedBy links. A link without a fragment names the start shape
+// of its schema.
+func (r *ShapesResolver) shexShapes(ctx context.Context, container string, meta *rdf.Graph, uri string) (*domainservice.ShExSchema, domainservice.ShapeMap, error) {
+	declared := meta.Match(rdf.IRI(container), LDPConstrainedBy, nil)
+	if len(declared) == 0 {
+		return nil, nil, nil
+	}
+
+	merged := domainservice.NewShExSchema()
+	var shapeMap domainservice.ShapeMap
+	schemas := make(map[string]*domainservice.ShExSchema)
+	for _, t := range declared {
+		ref, ok := t.Object.(rdf.IRI)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s declares a constraint that is not an IRI: %s", container+MetaSuffix, t.Object)
+		}
+		document, fragment, _ := strings.Cut(string(ref), "#")
+
+		schema, ok := schemas[document]
+		if !ok {
+			var err error
+			if schema, err = r.loadSchema(ctx, document); err != nil {
+				return nil, nil, err
+			}
+			schemas[document] = schema
+			for label, expr := range schema.Shapes {
+				merged.Shapes[label] = expr
+			}
+		}
+
+		label := string(ref)
+		if fragment == "" {
+			if schema.Start == nil {
+				return nil, nil, fmt.Errorf("ShEx schema %s has no start shape", document)
+			}
+			merged.Shapes[label] = schema.Start
+		} else if _, ok := schema.Shapes[label]; !ok {
+			return nil, nil, fmt.Errorf("ShEx schema %s does not define %s", document, label)
+		}
+		shapeMap = append(shapeMap, domainservice.ShapeAssociation{Node: rdf.IRI(uri), Shape: label})
+	}
+	return merged, shapeMap, nil
+}
+
+// loadSchema reads a ShEx schema stored in the pod as ShExJ, or fetches it
+func (r *ShapesResolver) loadSchema(ctx context.Context, document string) (*domainservice.ShExSchema, error) {
+	resource, err := r.repository.GetByURI(ctx, document)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, err
+	}
+	if err == nil && resource != nil && !resource.IsDeleted() {
+		if resource.GetContentType() != string(domainservice.FormatJSONLD) {
+			return nil, fmt.Errorf("ShEx schema %s must be stored as ShExJ", document)
+		}
+		return domainservice.ParseShExJ(resource.GetData())
+	}
+	return r.fetchSchema(ctx, document)
+}
+
+// fetchSchema retrieves a remote ShEx schema in ShExC or ShExJ
+func (r *ShapesResolver) fetchSchema(ctx context.Context, document string) (*domainservice.ShExSchema, error) {
+	body, mediaType, err := r.fetch(ctx, document, "text/shex, application/shex+json;q=0.9, application/json;q=0.5")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ShEx schema: %w", err)
+	}
+	text := strings.TrimSpace(body)
+	if mediaType == string(domainservice.FormatShExJ) || mediaType == "application/json" ||
+		(mediaType != string(domainservice.FormatShExC) && strings.HasPrefix(text, "{")) {
+		return domainservice.ParseShExJ(text)
+	}
+	return domainservice.ParseShExC(text, document)
+}
+
+// fetchGraph retrieves and parses a remote RDF document
+func (r *ShapesResolver) fetchGraph(ctx context.Context, document string) (*rdf.Graph, error) {
+	body, mediaType, err := r.fetch(ctx, document, "text/turtle, application/ld+json;q=0.9")
+	if err != nil {
+		return nil, err
+	}
+	if mediaType == "" || mediaType == "text/plain" {
+		mediaType = string(domainservice.FormatTurtle)
+	}
+	graph, err := r.rdfService.WithBase(document).ParseGraph(body, mediaType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", document, err)
+	}
+	return graph, nil
+}
+
+// fetch GETs a remote document of at most maxSchemaBytes, returning its
+// body and media type
+func (r *ShapesResolver) fetch(ctx context.Context, document string, accept string) (string, string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, document, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid URL %s: %w", document, err)
+	}
+	request.Header.Set("Accept", accept)
+
+	response, err := r.httpClient.Do(request)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", document, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("%s: %s", document, response.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxSchemaBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", document, err)
+	}
+	if len(body) > maxSchemaBytes {
+		return "", "", fmt.Errorf("%s exceeds %d bytes", document, maxSchemaBytes)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	return string(body), mediaType, nil
+}
+
+// load parses the resource at uri, returning nil when it does not exist or
+// has been deleted
+func (r *ShapesResolver) load(ctx context.Context, uri string) (*rdf.Graph, error) {
+	resource, err := r.repository.GetByURI(ctx, uri)
+	if errors.Is(err, repository.ErrResourceNotFound) || (err == nil && (resource == nil || resource.IsDeleted())) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	graph, err := r.rdfService.WithBase(uri).ParseGraph(resource.GetData(), resource.GetContentType())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+	return graph, nil
+}
+
+// isAuxiliary reports whether uri names a description, a container's shape
+// tree locator or an ACL
+func isAuxiliary(uri string) bool {
+	return strings.HasSuffix(uri, MetaSuffix) || strings.HasSuffix(uri, "/"+ShapeTreeSuffix) || strings.HasSuffix(uri, ACLSuffix)
+}
+
+// parentContainer returns the URI of the container holding uri, or "" for the root
+func parentContainer(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "" {
+		return ""
+	}
+	u.Path = path[:strings.LastIndex(path, "/")+1]
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
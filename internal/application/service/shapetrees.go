@@ -0,0 +1,612 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+// STNamespace is the namespace of the Shape Trees vocabulary
+const STNamespace = "http://www.w3.org/ns/shapetrees#"
+
+// Shape Trees vocabulary
+const (
+	STShapeTree                = rdf.IRI(STNamespace + "ShapeTree")
+	STShapeTreeLocator         = rdf.IRI(STNamespace + "ShapeTreeLocator")
+	STShapeTreeLocation        = rdf.IRI(STNamespace + "ShapeTreeLocation")
+	STExpectsType              = rdf.IRI(STNamespace + "expectsType")
+	STContainer                = rdf.IRI(STNamespace + "Container")
+	STResource                 = rdf.IRI(STNamespace + "Resource")
+	STNonRDFResource           = rdf.IRI(STNamespace + "NonRDFResource")
+	STShape                    = rdf.IRI(STNamespace + "shape")
+	STContains                 = rdf.IRI(STNamespace + "contains")
+	STMatchesURITemplate       = rdf.IRI(STNamespace + "matchesUriTemplate")
+	STManages                  = rdf.IRI(STNamespace + "manages")
+	STHasShapeTreeLocation     = rdf.IRI(STNamespace + "hasShapeTreeLocation")
+	STHasShapeTree             = rdf.IRI(STNamespace + "hasShapeTree")
+	STHasRootShapeTreeLocation = rdf.IRI(STNamespace + "hasRootShapeTreeLocation")
+)
+
+// ShapeTreeSuffix names the auxiliary resource holding a container's shape tree locator
+const ShapeTreeSuffix = ".shapetree"
+
+var (
+	// ErrShapeTreeAlreadyPlanted is returned when planting a tree that already manages the container
+	ErrShapeTreeAlreadyPlanted = errors.New("shape tree is already planted")
+
+	// ErrShapeTreeNotPlanted is returned when unplanting a tree that does not manage the container
+	ErrShapeTreeNotPlanted = errors.New("shape tree is not planted")
+
+	// ErrShapeTreeNotRoot is returned when unplanting a tree that was assigned by a parent container
+	ErrShapeTreeNotRoot = errors.New("shape tree was assigned by a parent container")
+
+	// ErrProtectedResource is returned when a client writes a server-managed resource
+	ErrProtectedResource = errors.New("resource is managed by the server")
+)
+
+// ShapeTreeError reports a resource that the shape trees managing its
+// container do not allow
+type ShapeTreeError struct {
+	URI    string
+	Tree   string
+	Reason string
+}
+
+func (e *ShapeTreeError) Error() string {
+	return fmt.Sprintf("%s is not allowed by shape tree %s: %s", e.URI, e.Tree, e.Reason)
+}
+
+// ShapeTree describes the resources a shape tree allows
+type ShapeTree struct {
+	URI         string
+	ExpectsType rdf.IRI
+	Shape       string   // ShEx shape label; empty when any data is allowed
+	Contains    []string // trees members of a container must match; empty when unconstrained
+	URITemplate string   // template the member's name must match; empty for any name
+}
+
+// ShapeTreeLocation records that a shape tree manages a container
+type ShapeTreeLocation struct {
+	ID   string // IRI of the location in the locator
+	Tree string
+	Root string // ID of the location where the tree was planted
+}
+
+// locatorURI returns the URI of the shape tree locator of container
+func locatorURI(container string) string {
+	return container + ShapeTreeSuffix
+}
+
+// locationID returns a stable IRI for the location of tree in container's locator
+func locationID(container string, tree string) string {
+	sum := sha256.Sum256([]byte(tree))
+	return locatorURI(container) + "#" + hex.EncodeToString(sum[:6])
+}
+
+// shapeTreeLocations reads the locations of container's shape tree locator
+func (r *ShapesResolver) shapeTreeLocations(ctx context.Context, container string) ([]ShapeTreeLocation, error) {
+	locator := locatorURI(container)
+	graph, err := r.load(ctx, locator)
+	if err != nil || graph == nil {
+		return nil, err
+	}
+
+	var locations []ShapeTreeLocation
+	for _, t := range graph.Match(rdf.IRI(locator), STHasShapeTreeLocation, nil) {
+		location := ShapeTreeLocation{ID: t.Object.Value()}
+		if tree := firstObject(graph, t.Object, STHasShapeTree); tree != nil {
+			location.Tree = tree.Value()
+		}
+		if root := firstObject(graph, t.Object, STHasRootShapeTreeLocation); root != nil {
+			location.Root = root.Value()
+		}
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].ID < locations[j].ID })
+	return locations, nil
+}
+
+// loadShapeTree reads a shape tree definition from the pod or the web
+func (r *ShapesResolver) loadShapeTree(ctx context.Context, treeURI string) (*ShapeTree, error) {
+	document, _, _ := strings.Cut(treeURI, "#")
+	graph, err := r.load(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+	if graph == nil {
+		if graph, err = r.fetchGraph(ctx, document); err != nil {
+			return nil, fmt.Errorf("failed to load shape tree %s: %w", treeURI, err)
+		}
+	}
+
+	node := rdf.IRI(treeURI)
+	if len(graph.Match(node, rdf.RDFType, STShapeTree)) == 0 {
+		return nil, fmt.Errorf("%s is not a shape tree", treeURI)
+	}
+	tree := &ShapeTree{URI: treeURI, ExpectsType: STResource}
+	if expects, ok := firstObject(graph, node, STExpectsType).(rdf.IRI); ok {
+		tree.ExpectsType = expects
+	}
+	if shape := firstObject(graph, node, STShape); shape != nil {
+		tree.Shape = shape.Value()
+	}
+	if template := firstObject(graph, node, STMatchesURITemplate); template != nil {
+		tree.URITemplate = template.Value()
+	}
+	for _, t := range graph.Match(node, STContains, nil) {
+		tree.Contains = append(tree.Contains, t.Object.Value())
+	}
+	sort.Strings(tree.Contains)
+	return tree, nil
+}
+
+// resourceType returns the shape tree resource type of the resource at uri
+func resourceType(uri string, nonRDF bool) rdf.IRI {
+	switch {
+	case strings.HasSuffix(uri, "/"):
+		return STContainer
+	case nonRDF:
+		return STNonRDFResource
+	}
+	return STResource
+}
+
+// memberTrees returns the trees contained by tree whose type and name
+// template allow the member at uri of type expects
+func (r *ShapesResolver) memberTrees(ctx context.Context, tree *ShapeTree, container string, uri string, expects rdf.IRI) ([]*ShapeTree, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(uri, container), "/")
+
+	var candidates []*ShapeTree
+	for _, contained := range tree.Contains {
+		member, err := r.loadShapeTree(ctx, contained)
+		if err != nil {
+			return nil, err
+		}
+		if member.ExpectsType != expects {
+			continue
+		}
+		if member.URITemplate != "" && !matchesURITemplate(member.URITemplate, name) {
+			continue
+		}
+		candidates = append(candidates, member)
+	}
+	return candidates, nil
+}
+
+// addShapeTreeConstraints requires the resource at uri to conform to the
+// shape of a tree contained by every tree managing container
+func (r *ShapesResolver) addShapeTreeConstraints(ctx context.Context, container string, uri string, expects rdf.IRI, constraints *Constraints) error {
+	locations, err := r.shapeTreeLocations(ctx, container)
+	if err != nil {
+		return err
+	}
+
+	for _, location := range locations {
+		tree, err := r.loadShapeTree(ctx, location.Tree)
+		if err != nil {
+			return err
+		}
+		if len(tree.Contains) == 0 {
+			continue
+		}
+		candidates, err := r.memberTrees(ctx, tree, container, uri, expects)
+		if err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			return &ShapeTreeError{URI: uri, Tree: tree.URI, Reason: "no contained shape tree allows a member of this type and name"}
+		}
+
+		schema, exprs, err := r.treeShapes(ctx, candidates)
+		if err != nil || schema == nil {
+			return err
+		}
+		label := candidates[0].Shape
+		if len(exprs) > 1 {
+			label = location.ID
+			schema.Shapes[label] = &domainservice.ShapeOr{Exprs: exprs}
+		}
+		constraints.addShEx(schema, domainservice.ShapeMap{{Node: rdf.IRI(uri), Shape: label}})
+	}
+	return nil
+}
+
+// treeShapes loads the ShEx shapes of the candidate trees. The schema is
+// nil when a candidate has no shape, as any data is then allowed.
+func (r *ShapesResolver) treeShapes(ctx context.Context, candidates []*ShapeTree) (*domainservice.ShExSchema, []domainservice.ShapeExpr, error) {
+	schema := domainservice.NewShExSchema()
+	var exprs []domainservice.ShapeExpr
+	for _, candidate := range candidates {
+		if candidate.Shape == "" {
+			return nil, nil, nil
+		}
+		document, _, _ := strings.Cut(candidate.Shape, "#")
+		loaded, err := r.loadSchema(ctx, document)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := loaded.Shapes[candidate.Shape]; !ok {
+			return nil, nil, fmt.Errorf("ShEx schema %s does not define %s", document, candidate.Shape)
+		}
+		for label, expr := range loaded.Shapes {
+			schema.Shapes[label] = expr
+		}
+		exprs = append(exprs, &domainservice.ShapeRef{Label: candidate.Shape})
+	}
+	return schema, exprs, nil
+}
+
+// matchesURITemplate reports whether name matches a template such as
+// "{id}.ttl", where each variable matches one path segment
+func matchesURITemplate(template string, name string) bool {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for template != "" {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template, '}')
+		if start < 0 || end < start {
+			pattern.WriteString(regexp.QuoteMeta(template))
+			break
+		}
+		pattern.WriteString(regexp.QuoteMeta(template[:start]))
+		pattern.WriteString("[^/]+")
+		template = template[end+1:]
+	}
+	pattern.WriteString("$")
+	matched, err := regexp.MatchString(pattern.String(), name)
+	return err == nil && matched
+}
+
+// firstObject returns the first object matching subject and predicate, or nil
+func firstObject(g *rdf.Graph, subject rdf.Term, predicate rdf.Term) rdf.Term {
+	matches := g.Match(subject, predicate, nil)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches[0].Object
+}
+
+// ShapeTreeService plants shape trees on containers and assigns the
+// contained trees to sub-containers as they are created
+type ShapeTreeService struct {
+	repository repository.ResourceRepository
+	rdfService domainservice.RDFValidationService
+	shapes     *ShapesResolver
+	validator  domainservice.ShExValidationService
+	logger     logger.Logger
+}
+
+// NewShapeTreeService creates a new shape tree service
+func NewShapeTreeService(repo repository.ResourceRepository, rdfService domainservice.RDFValidationService, logger logger.Logger) *ShapeTreeService {
+	return &ShapeTreeService{
+		repository: repo,
+		rdfService: rdfService,
+		shapes:     NewShapesResolver(repo, rdfService),
+		validator:  domainservice.NewStandardShExValidationService(),
+		logger:     logger,
+	}
+}
+
+// Locations returns the shape tree locations of a container
+func (s *ShapeTreeService) Locations(ctx context.Context, containerURI string) ([]ShapeTreeLocation, error) {
+	return s.shapes.shapeTreeLocations(ctx, withTrailingSlash(containerURI))
+}
+
+// Plant makes a shape tree manage a container. The container's existing
+// members must be allowed by the tree; sub-containers are assigned the
+// trees they match. Nothing is written unless the whole hierarchy conforms.
+func (s *ShapeTreeService) Plant(ctx context.Context, containerURI string, treeURI string) error {
+	container := withTrailingSlash(containerURI)
+	tree, err := s.shapes.loadShapeTree(ctx, treeURI)
+	if err != nil {
+		return err
+	}
+	if tree.ExpectsType != STContainer {
+		return &ShapeTreeError{URI: container, Tree: treeURI, Reason: "the shape tree does not expect a container"}
+	}
+
+	locations, err := s.shapes.shapeTreeLocations(ctx, container)
+	if err != nil {
+		return err
+	}
+	for _, location := range locations {
+		if location.Tree == treeURI {
+			return fmt.Errorf("%w: %s on %s", ErrShapeTreeAlreadyPlanted, treeURI, container)
+		}
+	}
+
+	id := locationID(container, treeURI)
+	assignments := map[string][]ShapeTreeLocation{
+		container: append(locations, ShapeTreeLocation{ID: id, Tree: treeURI, Root: id}),
+	}
+	if err := s.assignMembers(ctx, container, tree, id, assignments); err != nil {
+		return err
+	}
+	if err := s.saveAssignments(ctx, assignments); err != nil {
+		return err
+	}
+
+	s.logger.Info("Planted shape tree", zap.String("container", container), zap.String("tree", treeURI))
+	return nil
+}
+
+// Unplant stops a shape tree managing a container and removes the trees it
+// assigned to sub-containers
+func (s *ShapeTreeService) Unplant(ctx context.Context, containerURI string, treeURI string) error {
+	container := withTrailingSlash(containerURI)
+	locations, err := s.shapes.shapeTreeLocations(ctx, container)
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, location := range locations {
+		if location.Tree == treeURI {
+			index = i
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("%w: %s on %s", ErrShapeTreeNotPlanted, treeURI, container)
+	}
+	root := locations[index]
+	if root.Root != root.ID {
+		return fmt.Errorf("%w: %s on %s", ErrShapeTreeNotRoot, treeURI, container)
+	}
+
+	assignments := map[string][]ShapeTreeLocation{
+		container: append(locations[:index:index], locations[index+1:]...),
+	}
+	if err := s.unassignMembers(ctx, container, root.ID, assignments); err != nil {
+		return err
+	}
+	if err := s.saveAssignments(ctx, assignments); err != nil {
+		return err
+	}
+
+	s.logger.Info("Unplanted shape tree", zap.String("container", container), zap.String("tree", treeURI))
+	return nil
+}
+
+// Assign gives a newly stored sub-container the trees it matches within
+// the trees managing its parent. Trees it already has are kept.
+func (s *ShapeTreeService) Assign(ctx context.Context, resource entity.Resource) error {
+	uri := resource.GetURI()
+	if !strings.HasSuffix(uri, "/") {
+		return nil
+	}
+	container := parentContainer(uri)
+	parentLocations, err := s.shapes.shapeTreeLocations(ctx, container)
+	if err != nil || len(parentLocations) == 0 {
+		return err
+	}
+	graph, err := s.rdfService.WithBase(uri).ParseGraph(resource.GetData(), resource.GetContentType())
+	if err != nil {
+		return err
+	}
+
+	locations, err := s.shapes.shapeTreeLocations(ctx, uri)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, parent := range parentLocations {
+		tree, err := s.shapes.loadShapeTree(ctx, parent.Tree)
+		if err != nil {
+			return err
+		}
+		if len(tree.Contains) == 0 {
+			continue
+		}
+		matched, err := s.match(ctx, container, tree, uri, STContainer, graph)
+		if err != nil {
+			return err
+		}
+		location := ShapeTreeLocation{ID: locationID(uri, matched.URI), Tree: matched.URI, Root: parent.Root}
+		if !containsLocation(locations, location.ID) {
+			locations = append(locations, location)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.saveLocator(ctx, uri, locations)
+}
+
+// assignMembers checks every member of container against tree, recording
+// the locations of matching sub-containers in assignments
+func (s *ShapeTreeService) assignMembers(ctx context.Context, container string, tree *ShapeTree, root string, assignments map[string][]ShapeTreeLocation) error {
+	if len(tree.Contains) == 0 {
+		return nil
+	}
+	members, err := s.repository.FindByContainer(ctx, container)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		uri := member.GetURI()
+		if isAuxiliary(uri) || member.IsDeleted() {
+			continue
+		}
+		graph := rdf.NewGraph()
+		if !member.IsNonRDF() {
+			if graph, err = s.rdfService.WithBase(uri).ParseGraph(member.GetData(), member.GetContentType()); err != nil {
+				return err
+			}
+		}
+		matched, err := s.match(ctx, container, tree, uri, resourceType(uri, member.IsNonRDF()), graph)
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(uri, "/") {
+			continue
+		}
+
+		locations, ok := assignments[uri]
+		if !ok {
+			if locations, err = s.shapes.shapeTreeLocations(ctx, uri); err != nil {
+				return err
+			}
+		}
+		assignments[uri] = append(locations, ShapeTreeLocation{ID: locationID(uri, matched.URI), Tree: matched.URI, Root: root})
+		if err := s.assignMembers(ctx, uri, matched, root, assignments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unassignMembers removes the locations planted from root below container
+func (s *ShapeTreeService) unassignMembers(ctx context.Context, container string, root string, assignments map[string][]ShapeTreeLocation) error {
+	members, err := s.repository.FindByContainer(ctx, container)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		uri := member.GetURI()
+		if !strings.HasSuffix(uri, "/") {
+			continue
+		}
+		locations, err := s.shapes.shapeTreeLocations(ctx, uri)
+		if err != nil {
+			return err
+		}
+		kept := make([]ShapeTreeLocation, 0, len(locations))
+		for _, location := range locations {
+			if location.Root != root {
+				kept = append(kept, location)
+			}
+		}
+		if len(kept) == len(locations) {
+			continue
+		}
+		assignments[uri] = kept
+		if err := s.unassignMembers(ctx, uri, root, assignments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// match returns the tree contained by tree that the member at uri of type
+// expects conforms to
+func (s *ShapeTreeService) match(ctx context.Context, container string, tree *ShapeTree, uri string, expects rdf.IRI, graph *rdf.Graph) (*ShapeTree, error) {
+	candidates, err := s.shapes.memberTrees(ctx, tree, container, uri, expects)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, &ShapeTreeError{URI: uri, Tree: tree.URI, Reason: "no contained shape tree allows a member of this type and name"}
+	}
+
+	var report *domainservice.ValidationReport
+	for _, candidate := range candidates {
+		if candidate.Shape == "" {
+			return candidate, nil
+		}
+		schema, _, err := s.shapes.treeShapes(ctx, []*ShapeTree{candidate})
+		if err != nil {
+			return nil, err
+		}
+		shapeMap := domainservice.ShapeMap{{Node: rdf.IRI(uri), Shape: candidate.Shape}}
+		if report, err = s.validator.Validate(graph, schema, shapeMap); err != nil {
+			return nil, err
+		}
+		if report.Conforms {
+			return candidate, nil
+		}
+	}
+	return nil, &domainservice.ShapeValidationError{Report: report}
+}
+
+// saveAssignments writes the locator of every container in assignments
+func (s *ShapeTreeService) saveAssignments(ctx context.Context, assignments map[string][]ShapeTreeLocation) error {
+	containers := make([]string, 0, len(assignments))
+	for container := range assignments {
+		containers = append(containers, container)
+	}
+	sort.Strings(containers)
+	for _, container := range containers {
+		if err := s.saveLocator(ctx, container, assignments[container]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveLocator stores container's locator, deleting it when no locations remain
+func (s *ShapeTreeService) saveLocator(ctx context.Context, container string, locations []ShapeTreeLocation) error {
+	locator := locatorURI(container)
+	existing, err := s.repository.GetByURI(ctx, locator)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		return err
+	}
+	if err != nil {
+		existing = nil
+	}
+
+	if len(locations) == 0 {
+		if existing == nil {
+			return nil
+		}
+		return s.repository.Delete(ctx, existing.ID())
+	}
+
+	graph := rdf.NewGraph()
+	graph.Add(rdf.Triple{Subject: rdf.IRI(locator), Predicate: rdf.RDFType, Object: STShapeTreeLocator})
+	graph.Add(rdf.Triple{Subject: rdf.IRI(locator), Predicate: STManages, Object: rdf.IRI(container)})
+	for _, location := range locations {
+		id := rdf.IRI(location.ID)
+		graph.Add(rdf.Triple{Subject: rdf.IRI(locator), Predicate: STHasShapeTreeLocation, Object: id})
+		graph.Add(rdf.Triple{Subject: id, Predicate: rdf.RDFType, Object: STShapeTreeLocation})
+		graph.Add(rdf.Triple{Subject: id, Predicate: STHasShapeTree, Object: rdf.IRI(location.Tree)})
+		graph.Add(rdf.Triple{Subject: id, Predicate: STHasRootShapeTreeLocation, Object: rdf.IRI(location.Root)})
+	}
+	turtle, err := s.rdfService.SerializeGraph(graph, string(domainservice.FormatTurtle))
+	if err != nil {
+		return err
+	}
+
+	var resource entity.Resource
+	if existing != nil {
+		resource = existing.Update(turtle, string(domainservice.FormatTurtle))
+	} else {
+		resource = entity.NewBasicResourceWithValidator(s.rdfService).WithBase(locator).FromTurtle(turtle).WithURI(locator)
+	}
+	if resource.HasErrors() {
+		return errors.Join(resource.GetErrors()...)
+	}
+	return s.repository.Save(ctx, resource)
+}
+
+// containsLocation reports whether locations has one with the given ID
+func containsLocation(locations []ShapeTreeLocation, id string) bool {
+	for _, location := range locations {
+		if location.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// withTrailingSlash returns uri ending in a slash
+func withTrailingSlash(uri string) string {
+	if strings.HasSuffix(uri, "/") {
+		return uri
+	}
+	return uri + "/"
+}
@@ -0,0 +1,154 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestShapeTreeService(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+
+	const journalTree = "https://pod.example.com/trees/journal#journal"
+	withTrees := func() map[string]entity.Resource {
+		return map[string]entity.Resource{
+			"https://pod.example.com/trees/journal": entity.NewBasicResourceWithValidator(rdfService).WithBase("https://pod.example.com/trees/journal").FromTurtle(`@prefix st: <http://www.w3.org/ns/shapetrees#> .
+<https://pod.example.com/trees/journal#journal> a st:ShapeTree ; st:expectsType st:Container ;
+    st:contains <https://pod.example.com/trees/journal#entry>, <https://pod.example.com/trees/journal#attachments> .
+<https://pod.example.com/trees/journal#entry> a st:ShapeTree ; st:expectsType st:Resource ;
+    st:shape <https://pod.example.com/shapes/entry#Entry> ; st:matchesUriTemplate "{id}.ttl" .
+<https://pod.example.com/trees/journal#attachments> a st:ShapeTree ; st:expectsType st:Container ;
+    st:contains <https://pod.example.com/trees/journal#attachment> .
+<https://pod.example.com/trees/journal#attachment> a st:ShapeTree ; st:expectsType st:Resource .`).
+				WithURI("https://pod.example.com/trees/journal"),
+			"https://pod.example.com/shapes/entry": entity.NewBasicResourceWithValidator(rdfService).FromJSONLD(`{
+  "@context": "http://www.w3.org/ns/shex.jsonld",
+  "@id": "https://pod.example.com/shapes/entry",
+  "type": "Schema",
+  "shapes": [{
+    "id": "https://pod.example.com/shapes/entry#Entry",
+    "type": "Shape",
+    "expression": {"type": "TripleConstraint", "predicate": "http://purl.org/dc/terms/title"}
+  }]
+}`).WithURI("https://pod.example.com/shapes/entry"),
+		}
+	}
+
+	t.Run("validates members of a planted container", func(t *testing.T) {
+		// Arrange
+		resources := withTrees()
+		repo := newMapRepository(resources)
+		resourceService := service.NewResourceService(repo, nil, rdfService, log)
+		require.NoError(t, service.NewShapeTreeService(repo, rdfService, log).Plant(context.Background(), "https://pod.example.com/journal/", journalTree))
+
+		// Act
+		_, _, valid := resourceService.Put(context.Background(), "https://pod.example.com/journal/monday.ttl",
+			`<https://pod.example.com/journal/monday.ttl> <http://purl.org/dc/terms/title> "Monday" .`, "text/turtle")
+		_, _, invalid := resourceService.Put(context.Background(), "https://pod.example.com/journal/tuesday.ttl",
+			`<https://pod.example.com/journal/tuesday.ttl> <http://purl.org/dc/terms/subject> "Tuesday" .`, "text/turtle")
+		_, _, misnamed := resourceService.Put(context.Background(), "https://pod.example.com/journal/wednesday",
+			`<https://pod.example.com/journal/wednesday> <http://purl.org/dc/terms/title> "Wednesday" .`, "text/turtle")
+
+		// Assert
+		require.NoError(t, valid)
+		var shapeErr *domainservice.ShapeValidationError
+		assert.ErrorAs(t, invalid, &shapeErr)
+		var treeErr *service.ShapeTreeError
+		require.ErrorAs(t, misnamed, &treeErr)
+		assert.Equal(t, journalTree, treeErr.Tree)
+	})
+
+	t.Run("refuses to plant on a container with non-conforming members", func(t *testing.T) {
+		// Arrange
+		resources := withTrees()
+		resources["https://pod.example.com/journal/old.ttl"] = entity.NewBasicResourceWithValidator(rdfService).
+			FromTurtle(`<https://pod.example.com/journal/old.ttl> <http://purl.org/dc/terms/subject> "Old" .`).
+			WithURI("https://pod.example.com/journal/old.ttl")
+		shapeTrees := service.NewShapeTreeService(newMapRepository(resources), rdfService, log)
+
+		// Act
+		err := shapeTrees.Plant(context.Background(), "https://pod.example.com/journal/", journalTree)
+
+		// Assert
+		var shapeErr *domainservice.ShapeValidationError
+		require.ErrorAs(t, err, &shapeErr)
+		assert.NotContains(t, resources, "https://pod.example.com/journal/.shapetree")
+	})
+
+	t.Run("assigns contained trees to new sub-containers and unplants them", func(t *testing.T) {
+		// Arrange
+		resources := withTrees()
+		repo := newMapRepository(resources)
+		resourceService := service.NewResourceService(repo, nil, rdfService, log)
+		shapeTrees := service.NewShapeTreeService(repo, rdfService, log)
+		require.NoError(t, shapeTrees.Plant(context.Background(), "https://pod.example.com/journal/", journalTree))
+
+		// Act
+		container, err := resourceService.CreateContainer(context.Background(), "https://pod.example.com/journal/", "attachments",
+			`<https://pod.example.com/journal/attachments/> a <http://www.w3.org/ns/ldp#BasicContainer> .`, "text/turtle")
+		require.NoError(t, err)
+		locations, err := shapeTrees.Locations(context.Background(), container.GetURI())
+		require.NoError(t, err)
+		unplanted := shapeTrees.Unplant(context.Background(), "https://pod.example.com/journal/", journalTree)
+
+		// Assert
+		assert.Equal(t, "https://pod.example.com/journal/attachments/", container.GetURI())
+		require.Len(t, locations, 1)
+		assert.Equal(t, "https://pod.example.com/trees/journal#attachments", locations[0].Tree)
+		require.NoError(t, unplanted)
+		assert.NotContains(t, resources, "https://pod.example.com/journal/.shapetree")
+		assert.NotContains(t, resources, "https://pod.example.com/journal/attachments/.shapetree")
+	})
+
+	t.Run("rejects planting a tree twice and unplanting an assigned tree", func(t *testing.T) {
+		// Arrange
+		resources := withTrees()
+		repo := newMapRepository(resources)
+		shapeTrees := service.NewShapeTreeService(repo, rdfService, log)
+		require.NoError(t, shapeTrees.Plant(context.Background(), "https://pod.example.com/journal/", journalTree))
+		_, err := service.NewResourceService(repo, nil, rdfService, log).CreateContainer(context.Background(), "https://pod.example.com/journal/", "files",
+			`<https://pod.example.com/journal/files/> a <http://www.w3.org/ns/ldp#BasicContainer> .`, "text/turtle")
+		require.NoError(t, err)
+
+		// Act
+		twice := shapeTrees.Plant(context.Background(), "https://pod.example.com/journal/", journalTree)
+		assigned := shapeTrees.Unplant(context.Background(), "https://pod.example.com/journal/files/", "https://pod.example.com/trees/journal#attachments")
+
+		// Assert
+		assert.ErrorIs(t, twice, service.ErrShapeTreeAlreadyPlanted)
+		assert.ErrorIs(t, assigned, service.ErrShapeTreeNotRoot)
+	})
+
+	t.Run("plants trees over HTTP and protects the locator", func(t *testing.T) {
+		// Arrange
+		resources := withTrees()
+		resourceService := service.NewResourceService(newMapRepository(resources), nil, rdfService, log)
+		solidService := service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+		request := httptest.NewRequest(http.MethodPut, "https://pod.example.com/journal/.shapetree", strings.NewReader(""))
+		request.Header.Set("Link", `<`+journalTree+`>; rel="http://www.w3.org/ns/shapetrees#ShapeTree"`)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err := solidService.UpdateResource(request.Context(), recorder, request)
+		_, _, protected := resourceService.Put(context.Background(), "https://pod.example.com/journal/.shapetree",
+			`<https://pod.example.com/journal/.shapetree> <http://purl.org/dc/terms/title> "Mine" .`, "text/turtle")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Contains(t, resources, "https://pod.example.com/journal/.shapetree")
+		assert.ErrorIs(t, protected, service.ErrProtectedResource)
+	})
+}
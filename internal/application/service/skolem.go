@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+)
+
+// PodNamespace is the vocabulary of the settings a container declares for
+// its members in its .meta resource
+const PodNamespace = "https://github.com/wepala/vine-pod/ns#"
+
+// Container settings
+const (
+	// PodSkolemize replaces the blank nodes of members with skolem IRIs when they are stored
+	PodSkolemize = rdf.IRI(PodNamespace + "skolemize")
+
+	// PodDeskolemize turns the skolem IRIs of members back into blank nodes when they are served
+	PodDeskolemize = rdf.IRI(PodNamespace + "deskolemize")
+)
+
+// Skolemizer replaces the blank nodes of resources with IRIs under
+// /.well-known/genid/, so they can be addressed and keep their names when
+// the resource is serialized again. A container enables it for its members
+// in its .meta resource:
+//
+//	</notes/> pod:skolemize true ; pod:deskolemize true .
+type Skolemizer struct {
+	shapes     *ShapesResolver
+	rdfService domainservice.RDFValidationService
+}
+
+// NewSkolemizer creates a new skolemizer
+func NewSkolemizer(repo repository.ResourceRepository, rdfService domainservice.RDFValidationService) *Skolemizer {
+	return &Skolemizer{
+		shapes:     NewShapesResolver(repo, rdfService),
+		rdfService: rdfService,
+	}
+}
+
+// Skolemize returns data as Turtle with its blank nodes replaced by skolem
+// IRIs when the container of uri enables it, and data unchanged otherwise.
+// A blank node with the same triples as one in the previous version of the
+// resource keeps that node's IRI, so IRIs are stable across updates.
+func (s *Skolemizer) Skolemize(ctx context.Context, uri string, data string, mediaType string, previous entity.Resource) (string, string, error) {
+	enabled, err := s.enabled(ctx, uri, PodSkolemize)
+	if err != nil || !enabled {
+		return data, mediaType, err
+	}
+
+	parser := s.rdfService.WithBase(uri)
+	graph, err := parser.ParseGraph(data, mediaType)
+	if err != nil {
+		return "", "", err
+	}
+	signatures := rdf.BlankNodeSignatures(graph)
+	if len(signatures) == 0 {
+		return data, mediaType, nil
+	}
+
+	known, err := s.knownSignatures(parser, uri, previous)
+	if err != nil {
+		return "", "", err
+	}
+	uses := make(map[string]int, len(signatures))
+	for _, signature := range signatures {
+		uses[signature]++
+	}
+
+	skolems := make(map[rdf.BlankNode]rdf.IRI, len(signatures))
+	for node, signature := range signatures {
+		id, ok := known[signature]
+		if !ok || uses[signature] > 1 {
+			if id, err = randomName(); err != nil {
+				return "", "", err
+			}
+		}
+		skolems[node] = rdf.SkolemIRI(uri, id)
+	}
+
+	turtle, err := parser.SerializeGraph(rdf.Skolemize(graph, skolems), string(domainservice.FormatTurtle))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to skolemize %s: %w", uri, err)
+	}
+	return turtle, string(domainservice.FormatTurtle), nil
+}
+
+// Deskolemize returns graph with the skolem IRIs of the resource at uri
+// turned back into blank nodes when its container enables it
+func (s *Skolemizer) Deskolemize(ctx context.Context, uri string, graph *rdf.Graph) (*rdf.Graph, error) {
+	enabled, err := s.enabled(ctx, uri, PodDeskolemize)
+	if err != nil || !enabled {
+		return graph, err
+	}
+	return rdf.Deskolemize(graph, uri), nil
+}
+
+// knownSignatures maps the signature of each skolem IRI in the previous
+// version of a resource to its id, leaving out signatures shared by several
+func (s *Skolemizer) knownSignatures(parser domainservice.RDFValidationService, uri string, previous entity.Resource) (map[string]string, error) {
+	known := make(map[string]string)
+	if previous == nil || previous.GetData() == "" {
+		return known, nil
+	}
+	graph, err := parser.ParseGraph(previous.GetData(), previous.GetContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[rdf.BlankNode]bool)
+	for _, t := range graph.Triples() {
+		for _, term := range []rdf.Term{t.Subject, t.Object} {
+			if iri, ok := term.(rdf.IRI); ok {
+				if id, ok := rdf.SkolemID(iri, uri); ok {
+					ids[rdf.BlankNode(id)] = true
+				}
+			}
+		}
+	}
+
+	shared := make(map[string]bool)
+	for node, signature := range rdf.BlankNodeSignatures(rdf.Deskolemize(graph, uri)) {
+		if !ids[node] {
+			continue
+		}
+		if _, ok := known[signature]; ok {
+			shared[signature] = true
+		}
+		known[signature] = string(node)
+	}
+	for signature := range shared {
+		delete(known, signature)
+	}
+	return known, nil
+}
+
+// enabled reports whether the container of uri sets setting to true
+func (s *Skolemizer) enabled(ctx context.Context, uri string, setting rdf.IRI) (bool, error) {
+	container := parentContainer(uri)
+	if container == "" || isAuxiliary(uri) {
+		return false, nil
+	}
+	meta, err := s.shapes.load(ctx, container+MetaSuffix)
+	if err != nil || meta == nil {
+		return false, err
+	}
+	for _, t := range meta.Match(rdf.IRI(container), setting, nil) {
+		if literal, ok := t.Object.(rdf.Literal); ok && (literal.Lexical == "true" || literal.Lexical == "1") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestSkolemizer(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	genID := regexp.MustCompile(`https://pod\.example\.com/\.well-known/genid/[0-9a-f]+`)
+
+	withSettings := func(settings string) map[string]entity.Resource {
+		return map[string]entity.Resource{
+			"https://pod.example.com/notes/.meta": entity.NewBasicResourceWithValidator(rdfService).
+				FromTurtle(`<https://pod.example.com/notes/> ` + settings + ` .`).
+				WithURI("https://pod.example.com/notes/.meta"),
+		}
+	}
+	const note = `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/creator> [ <http://xmlns.com/foaf/0.1/name> "Alice" ] .`
+
+	t.Run("replaces blank nodes with stable skolem IRIs", func(t *testing.T) {
+		// Arrange
+		resources := withSettings(`<` + string(service.PodSkolemize) + `> true`)
+		resourceService := service.NewResourceService(newMapRepository(resources), nil, rdfService, log)
+		_, _, err := resourceService.Put(context.Background(), "https://pod.example.com/notes/a", note, "text/turtle")
+		require.NoError(t, err)
+		skolems := genID.FindAllString(resources["https://pod.example.com/notes/a"].GetData(), -1)
+
+		// Act
+		_, _, err = resourceService.Put(context.Background(), "https://pod.example.com/notes/a",
+			note+` <https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`, "text/turtle")
+
+		// Assert
+		require.NoError(t, err)
+		require.NotEmpty(t, skolems)
+		assert.Equal(t, skolems, genID.FindAllString(resources["https://pod.example.com/notes/a"].GetData(), -1))
+	})
+
+	t.Run("serves skolem IRIs as blank nodes when asked to", func(t *testing.T) {
+		// Arrange
+		resources := withSettings(`<` + string(service.PodSkolemize) + `> true ; <` + string(service.PodDeskolemize) + `> true`)
+		resourceService := service.NewResourceService(newMapRepository(resources), nil, rdfService, log)
+		solidService := service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+		_, _, err := resourceService.Put(context.Background(), "https://pod.example.com/notes/a", note, "text/turtle")
+		require.NoError(t, err)
+		request := httptest.NewRequest(http.MethodGet, "https://pod.example.com/notes/a", nil)
+		request.Header.Set("Accept", "application/n-triples")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		err = solidService.GetResource(request.Context(), recorder, request)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/n-triples", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Body.String(), `<http://purl.org/dc/terms/creator> _:`)
+		assert.NotRegexp(t, genID, recorder.Body.String())
+		assert.Regexp(t, genID, resources["https://pod.example.com/notes/a"].GetData())
+	})
+
+	t.Run("keeps blank nodes in other containers", func(t *testing.T) {
+		// Arrange
+		resources := withSettings(`<http://purl.org/dc/terms/title> "Notes"`)
+		resourceService := service.NewResourceService(newMapRepository(resources), nil, rdfService, log)
+
+		// Act
+		_, _, err := resourceService.Put(context.Background(), "https://pod.example.com/notes/a", note, "text/turtle")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, note, resources["https://pod.example.com/notes/a"].GetData())
+	})
+}
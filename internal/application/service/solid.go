@@ -1,12 +1,27 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
 	"github.com/wepala/vine-pod/internal/infrastructure/config"
 	"github.com/wepala/vine-pod/pkg/logger"
 	"github.com/wepala/vine-pod/pkg/version"
@@ -14,15 +29,19 @@ import (
 
 // SolidService handles Solid Protocol operations
 type SolidService struct {
-	config *config.Config
-	logger logger.Logger
+	config     *config.Config
+	logger     logger.Logger
+	resources  *ResourceService
+	rdfService domainservice.RDFValidationService
 }
 
 // NewSolidService creates a new Solid service
-func NewSolidService(cfg *config.Config, logger logger.Logger) *SolidService {
+func NewSolidService(cfg *config.Config, logger logger.Logger, resources *ResourceService, rdfService domainservice.RDFValidationService) *SolidService {
 	return &SolidService{
-		config: cfg,
-		logger: logger,
+		config:     cfg,
+		logger:     logger,
+		resources:  resources,
+		rdfService: rdfService,
 	}
 }
 
@@ -61,7 +80,8 @@ func (s *SolidService) GetRoot(ctx context.Context, w http.ResponseWriter, r *ht
 	return nil
 }
 
-// GetResource handles Solid protocol resource GET requests (placeholder)
+// GetResource handles Solid protocol GET requests, serving the target
+// resource in the format the Accept header prefers
 func (s *SolidService) GetResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	s.logger.Info("Solid GET resource request",
 		zap.String("method", r.Method),
@@ -69,27 +89,42 @@ func (s *SolidService) GetResource(ctx context.Context, w http.ResponseWriter, r
 		zap.String("user_agent", r.Header.Get("User-Agent")),
 	)
 
-	// Placeholder implementation
-	response := map[string]interface{}{
-		"message": "Solid GET resource endpoint",
-		"method":  r.Method,
-		"path":    r.URL.Path,
-		"status":  "not_implemented",
-		"note":    "This endpoint will be implemented with full Solid protocol support",
+	query := r.URL.Query()
+	switch {
+	case query.Has("upload"):
+		return s.getUpload(ctx, w, r)
+	case query.Has("versions"):
+		return s.listVersions(ctx, w, r)
+	case query.Has("trash"):
+		return s.listTrash(ctx, w, r)
+	case query.Has("from") || query.Has("to"):
+		return s.diffVersions(ctx, w, r)
+	case query.Has("timemap"):
+		return s.getTimeMap(ctx, w, r)
+	case query.Has("version"):
+		return s.getMemento(ctx, w, r)
+	case r.Header.Get("Accept-Datetime") != "":
+		return s.negotiateDatetime(ctx, w, r)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		s.logger.Error("Failed to encode resource response", zap.Error(err))
-		return err
+	format := negotiateFormat(r.Header.Get("Accept"), s.rdfService.SupportedFormats(), string(domainservice.FormatTurtle))
+	resource, data, err := s.resources.Get(ctx, requestURI(r), format)
+	if err != nil {
+		return s.writeError(w, r, err)
 	}
 
-	return nil
+	// The resource is its own Memento TimeGate
+	setMementoLinks(w, requestURI(r))
+	setDescribedByLink(w, requestURI(r))
+	w.Header().Set("Vary", "Accept, Accept-Datetime")
+	if resource.IsNonRDF() {
+		return s.writeContent(ctx, w, r, resource)
+	}
+	return writeRepresentation(w, r, resource, format, data)
 }
 
-// CreateResource handles Solid protocol resource POST requests (placeholder)
+// CreateResource handles Solid protocol POST requests, creating a resource
+// inside the target container
 func (s *SolidService) CreateResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	s.logger.Info("Solid POST resource request",
 		zap.String("method", r.Method),
@@ -97,27 +132,56 @@ func (s *SolidService) CreateResource(ctx context.Context, w http.ResponseWriter
 		zap.String("content_type", r.Header.Get("Content-Type")),
 	)
 
-	// Placeholder implementation
-	response := map[string]interface{}{
-		"message": "Solid POST resource endpoint",
-		"method":  r.Method,
-		"path":    r.URL.Path,
-		"status":  "not_implemented",
-		"note":    "This endpoint will be implemented with full Solid protocol support",
+	ctx = withRequestAuthor(ctx, r)
+	switch query := r.URL.Query(); {
+	case query.Has("revert"):
+		return s.revertResource(ctx, w, r)
+	case query.Has("restore"):
+		return s.restoreResource(ctx, w, r)
+	case query.Has("upload"):
+		return s.startUpload(ctx, w, r)
+	}
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE, MOVE, COPY")
+		http.Error(w, "POST is only allowed on containers", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	container := isContainerType(linkTargets(r.Header.Values("Link"), "type"))
+	if !container && !s.resources.IsRDF(r.Header.Get("Content-Type")) {
+		resource, err := s.createContent(ctx, r)
+		if err != nil {
+			return s.writeError(w, r, err)
+		}
+		setDigestHeaders(w, resource.GetContent())
+		w.Header().Set("Location", resource.GetURI())
+		setDescribedByLink(w, resource.GetURI())
+		w.WriteHeader(http.StatusCreated)
+		return nil
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
+	body, err := s.readBody(r)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		s.logger.Error("Failed to encode resource response", zap.Error(err))
-		return err
+	create := s.resources.Create
+	if container {
+		create = s.resources.CreateContainer
+	}
+	resource, err := create(ctx, requestURI(r), r.Header.Get("Slug"), string(body), r.Header.Get("Content-Type"))
+	if err != nil {
+		return s.writeError(w, r, err)
 	}
 
+	w.Header().Set("Location", resource.GetURI())
+	setDescribedByLink(w, resource.GetURI())
+	w.WriteHeader(http.StatusCreated)
 	return nil
 }
 
-// UpdateResource handles Solid protocol resource PUT requests (placeholder)
+// UpdateResource handles Solid protocol PUT requests, creating or replacing
+// the target resource
 func (s *SolidService) UpdateResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	s.logger.Info("Solid PUT resource request",
 		zap.String("method", r.Method),
@@ -125,49 +189,804 @@ func (s *SolidService) UpdateResource(ctx context.Context, w http.ResponseWriter
 		zap.String("content_type", r.Header.Get("Content-Type")),
 	)
 
-	// Placeholder implementation
-	response := map[string]interface{}{
-		"message": "Solid PUT resource endpoint",
-		"method":  r.Method,
-		"path":    r.URL.Path,
-		"status":  "not_implemented",
-		"note":    "This endpoint will be implemented with full Solid protocol support",
+	ctx = withRequestAuthor(ctx, r)
+	if strings.HasSuffix(r.URL.Path, "/"+ShapeTreeSuffix) {
+		return s.plantShapeTrees(ctx, w, r)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		s.logger.Error("Failed to encode resource response", zap.Error(err))
-		return err
+	var resource entity.Resource
+	var created bool
+	if s.resources.IsRDF(r.Header.Get("Content-Type")) {
+		body, err := s.readBody(r)
+		if err != nil {
+			return s.writeError(w, r, err)
+		}
+		resource, created, err = s.resources.Put(ctx, requestURI(r), string(body), r.Header.Get("Content-Type"))
+		if err != nil {
+			return s.writeError(w, r, err)
+		}
+	} else {
+		var err error
+		resource, created, err = s.putContent(ctx, r)
+		if err != nil {
+			return s.writeError(w, r, err)
+		}
+		setDigestHeaders(w, resource.GetContent())
 	}
 
+	w.Header().Set("ETag", resource.GetETag())
+	setDescribedByLink(w, resource.GetURI())
+	if created {
+		w.Header().Set("Location", resource.GetURI())
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
 
-// DeleteResource handles Solid protocol resource DELETE requests (placeholder)
+// createContent stores the body of a POST as a new non-RDF resource. When
+// the pod already holds bytes with the digest the client sent, the body is
+// not read, so clients waiting for 100 Continue skip the upload.
+func (s *SolidService) createContent(ctx context.Context, r *http.Request) (entity.Resource, error) {
+	var body io.Reader = r.Body
+	if digest := requestDigest(r); digest != "" {
+		resource, err := s.resources.CreateContentByDigest(ctx, requestURI(r), r.Header.Get("Slug"), digest, r.Header.Get("Content-Type"))
+		if !errors.Is(err, ErrUnknownDigest) {
+			return resource, err
+		}
+		body = verifyDigest(r.Body, digest)
+	}
+	return s.resources.CreateContent(ctx, requestURI(r), r.Header.Get("Slug"), body, r.Header.Get("Content-Type"))
+}
+
+// putContent stores the body of a PUT as the non-RDF resource at the
+// request URI, skipping the upload like createContent
+func (s *SolidService) putContent(ctx context.Context, r *http.Request) (entity.Resource, bool, error) {
+	var body io.Reader = r.Body
+	if digest := requestDigest(r); digest != "" {
+		resource, created, err := s.resources.PutContentByDigest(ctx, requestURI(r), digest, r.Header.Get("Content-Type"))
+		if !errors.Is(err, ErrUnknownDigest) {
+			return resource, created, err
+		}
+		body = verifyDigest(r.Body, digest)
+	}
+	return s.resources.PutContent(ctx, requestURI(r), body, r.Header.Get("Content-Type"))
+}
+
+// DeleteResource handles Solid protocol DELETE requests, moving the target
+// resource to its container's trash
 func (s *SolidService) DeleteResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	s.logger.Info("Solid DELETE resource request",
 		zap.String("method", r.Method),
 		zap.String("path", r.URL.Path),
 	)
 
-	// Placeholder implementation
-	response := map[string]interface{}{
-		"message": "Solid DELETE resource endpoint",
-		"method":  r.Method,
-		"path":    r.URL.Path,
-		"status":  "not_implemented",
-		"note":    "This endpoint will be implemented with full Solid protocol support",
+	ctx = withRequestAuthor(ctx, r)
+	if strings.HasSuffix(r.URL.Path, "/"+ShapeTreeSuffix) {
+		return s.unplantShapeTrees(ctx, w, r)
+	}
+	if r.URL.Query().Has("upload") {
+		return s.abortUpload(ctx, w, r)
+	}
+
+	if err := s.resources.Delete(ctx, requestURI(r)); err != nil {
+		return s.writeError(w, r, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// PatchResource handles PATCH requests, which append bytes to a resumable
+// upload
+func (s *SolidService) PatchResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	s.logger.Info("Solid PATCH resource request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("content_type", r.Header.Get("Content-Type")),
+	)
+
+	if !r.URL.Query().Has("upload") {
+		w.Header().Set("Allow", "GET, HEAD, POST, PUT, DELETE, MOVE, COPY")
+		http.Error(w, "PATCH is only allowed on uploads", http.StatusMethodNotAllowed)
+		return nil
+	}
+	return s.appendUpload(withRequestAuthor(ctx, r), w, r)
+}
+
+// MoveResource handles WebDAV MOVE requests, moving the target resource and
+// everything below it to the URI in the Destination header
+func (s *SolidService) MoveResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	s.logger.Info("Solid MOVE resource request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+	return s.relocateResource(ctx, w, r, s.resources.Move)
+}
+
+// CopyResource handles WebDAV COPY requests, copying the target resource and
+// everything below it to the URI in the Destination header
+func (s *SolidService) CopyResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	s.logger.Info("Solid COPY resource request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	)
+	return s.relocateResource(ctx, w, r, s.resources.Copy)
+}
+
+// relocateResource moves or copies the target resource to its Destination
+func (s *SolidService) relocateResource(ctx context.Context, w http.ResponseWriter, r *http.Request, relocate func(ctx context.Context, source string, destination string) error) error {
+	destination, err := destinationURI(r)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+	if err := relocate(withRequestAuthor(ctx, r), requestURI(r), destination); err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	w.Header().Set("Location", destination)
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// plantShapeTrees plants the shape trees given as Link headers with
+// rel="http://www.w3.org/ns/shapetrees#ShapeTree" on the locator's container
+func (s *SolidService) plantShapeTrees(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	trees := linkTargets(r.Header.Values("Link"), string(STShapeTree))
+	if len(trees) == 0 {
+		http.Error(w, "a Link header with rel=\""+string(STShapeTree)+"\" is required to plant a shape tree", http.StatusBadRequest)
+		return nil
 	}
 
+	locator := requestURI(r)
+	container := strings.TrimSuffix(locator, ShapeTreeSuffix)
+	for _, tree := range trees {
+		if err := s.resources.shapeTrees.Plant(ctx, container, tree); err != nil {
+			return s.writeError(w, r, err)
+		}
+	}
+
+	w.Header().Set("Location", locator)
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// unplantShapeTrees unplants the shape trees given as Link headers from the
+// locator's container, or every tree planted on it when none are given
+func (s *SolidService) unplantShapeTrees(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	container := strings.TrimSuffix(requestURI(r), ShapeTreeSuffix)
+	trees := linkTargets(r.Header.Values("Link"), string(STShapeTree))
+	if len(trees) == 0 {
+		locations, err := s.resources.shapeTrees.Locations(ctx, container)
+		if err != nil {
+			return s.writeError(w, r, err)
+		}
+		for _, location := range locations {
+			if location.Root == location.ID {
+				trees = append(trees, location.Tree)
+			}
+		}
+		if len(trees) == 0 {
+			http.Error(w, "no shape tree is planted on "+container, http.StatusNotFound)
+			return nil
+		}
+	}
+
+	for _, tree := range trees {
+		if err := s.resources.shapeTrees.Unplant(ctx, container, tree); err != nil {
+			return s.writeError(w, r, err)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// startUpload handles POST ?upload, starting a resumable upload of the
+// number of bytes in the Upload-Length header to the target resource
+func (s *SolidService) startUpload(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	length, err := uploadHeader(r, "Upload-Length")
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+	upload, err := s.resources.StartUpload(ctx, requestURI(r), r.Header.Get("Content-Type"), length)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Location", UploadURI(upload.URI, upload.ID))
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// getUpload responds with the progress of the upload named by the upload
+// query parameter, as headers and as JSON
+func (s *SolidService) getUpload(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	upload, err := s.resources.Upload(ctx, requestURI(r), r.URL.Query().Get("upload"))
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	setUploadHeaders(w, upload)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	return json.NewEncoder(w).Encode(upload)
+}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		s.logger.Error("Failed to encode resource response", zap.Error(err))
+// appendUpload handles PATCH ?upload=ID, adding the body to the upload at
+// the offset in the Upload-Offset header. The response to the request that
+// completes the upload is that of a PUT of the resource.
+func (s *SolidService) appendUpload(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	offset, err := uploadHeader(r, "Upload-Offset")
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+	upload, resource, created, err := s.resources.AppendUpload(ctx, requestURI(r), r.URL.Query().Get("upload"), offset, r.Body)
+	if err != nil {
+		if upload.ID != "" {
+			setUploadHeaders(w, upload)
+		}
+		return s.writeError(w, r, err)
+	}
+
+	setUploadHeaders(w, upload)
+	if resource == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("ETag", resource.GetETag())
+	setDigestHeaders(w, resource.GetContent())
+	setDescribedByLink(w, resource.GetURI())
+	if created {
+		w.Header().Set("Location", resource.GetURI())
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// abortUpload handles DELETE ?upload=ID, discarding the upload
+func (s *SolidService) abortUpload(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := s.resources.AbortUpload(ctx, requestURI(r), r.URL.Query().Get("upload")); err != nil {
+		return s.writeError(w, r, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// setUploadHeaders reports the progress of upload
+func setUploadHeaders(w http.ResponseWriter, upload Upload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if !upload.Expires.IsZero() {
+		w.Header().Set("Upload-Expires", upload.Expires.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// uploadHeader reads a byte count from the header name
+func uploadHeader(r *http.Request, name string) (int64, error) {
+	value := r.Header.Get(name)
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s: %q", ErrInvalidUpload, name, value)
+	}
+	return n, nil
+}
+
+// getMemento responds with a past version of the target resource
+func (s *SolidService) getMemento(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	uri := requestURI(r)
+	version, err := versionParam(r, "version")
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+	memento, err := s.resources.Memento(ctx, uri, version)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+	format := negotiateFormat(r.Header.Get("Accept"), s.rdfService.SupportedFormats(), string(domainservice.FormatTurtle))
+	resource, data, err := s.resources.GetVersion(ctx, uri, version, format)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	w.Header().Set("Memento-Datetime", httpDate(memento.Time))
+	setMementoLinks(w, uri)
+	w.Header().Set("Vary", "Accept")
+	if resource.IsNonRDF() {
+		return s.writeContent(ctx, w, r, resource)
+	}
+	return writeRepresentation(w, r, resource, format, data)
+}
+
+// negotiateDatetime redirects to the version of the target resource that
+// was current at the Accept-Datetime of the request
+func (s *SolidService) negotiateDatetime(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	uri := requestURI(r)
+	value := r.Header.Get("Accept-Datetime")
+	datetime, err := http.ParseTime(value)
+	if err != nil {
+		return s.writeError(w, r, fmt.Errorf("%w: %q", ErrInvalidDatetime, value))
+	}
+	memento, err := s.resources.MementoAt(ctx, uri, datetime)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	setMementoLinks(w, uri)
+	w.Header().Set("Vary", "Accept-Datetime")
+	w.Header().Set("Location", MementoURI(uri, memento.Number))
+	w.WriteHeader(http.StatusFound)
+	return nil
+}
+
+// getTimeMap responds with the TimeMap of the target resource
+func (s *SolidService) getTimeMap(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	uri := requestURI(r)
+	versions, err := s.resources.Versions(ctx, uri)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	w.Header().Set("Content-Type", TimeMapFormat)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err = io.WriteString(w, TimeMap(uri, versions))
+	return err
+}
+
+// listVersions responds with the versions of the target resource as JSON
+func (s *SolidService) listVersions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	versions, err := s.resources.Versions(ctx, requestURI(r))
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	return json.NewEncoder(w).Encode(versions)
+}
+
+// diffVersions responds with the changes between the from and to versions of
+// the target resource as an N3 Patch
+func (s *SolidService) diffVersions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	from, err := versionParam(r, "from")
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+	to, err := versionParam(r, "to")
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+	diff, err := s.resources.Diff(ctx, requestURI(r), from, to)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	w.Header().Set("Content-Type", "text/n3")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err = io.WriteString(w, diff.N3Patch())
+	return err
+}
+
+// revertResource handles POST ?revert=N, restoring the target resource to
+// version N as a new version
+func (s *SolidService) revertResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	version, err := versionParam(r, "revert")
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+	resource, err := s.resources.Revert(ctx, requestURI(r), version)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	w.Header().Set("ETag", resource.GetETag())
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// writeRepresentation responds with data, the representation of resource in
+// format. Container listings and descriptions include data the server
+// derives from other resources, so their entity tag is a digest of data.
+func writeRepresentation(w http.ResponseWriter, r *http.Request, resource entity.Resource, format string, data string) error {
+	etag := resource.GetETag()
+	if strings.HasSuffix(r.URL.Path, "/") || isDescription(r.URL.Path) {
+		etag = fmt.Sprintf(`W/"%x"`, sha256.Sum256([]byte(data)))
+	}
+	w.Header().Set("Content-Type", format)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", httpDate(resource.GetLastModified()))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err := io.WriteString(w, data)
+	return err
+}
+
+// writeContent streams the bytes of a non-RDF resource, or the parts of
+// them asked for with a Range header
+func (s *SolidService) writeContent(ctx context.Context, w http.ResponseWriter, r *http.Request, resource entity.Resource) error {
+	setDigestHeaders(w, resource.GetContent())
+
+	// Large downloads go straight to the blob store when it can link to them
+	if r.Method == http.MethodGet {
+		link, err := s.resources.Link(ctx, resource)
+		if err != nil {
+			return s.writeError(w, r, err)
+		}
+		if link != "" {
+			w.Header().Set("ETag", resource.GetETag())
+			http.Redirect(w, r, link, http.StatusTemporaryRedirect)
+			return nil
+		}
+	}
+
+	size := resource.GetContent().Size
+	var ranges []byteRange
+	if r.Method == http.MethodGet && ifRangeMatches(r, resource) {
+		var err error
+		if ranges, err = parseRange(r.Header.Get("Range"), size); err != nil {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+			return nil
+		}
+	}
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("ETag", resource.GetETag())
+	w.Header().Set("Last-Modified", httpDate(resource.GetLastModified()))
+	if len(ranges) > 1 {
+		return s.writeRanges(ctx, w, r, resource, ranges)
+	}
+
+	var body io.ReadCloser
+	if r.Method != http.MethodHead {
+		var err error
+		if len(ranges) == 1 {
+			body, err = s.resources.OpenRange(ctx, resource, ranges[0].start, ranges[0].length)
+		} else {
+			body, err = s.resources.Open(ctx, resource)
+		}
+		if err != nil {
+			return s.writeError(w, r, err)
+		}
+		defer body.Close()
+	}
+
+	w.Header().Set("Content-Type", resource.GetContentType())
+	if len(ranges) == 1 {
+		w.Header().Set("Content-Range", ranges[0].contentRange(size))
+		w.Header().Set("Content-Length", strconv.FormatInt(ranges[0].length, 10))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+	}
+	if body == nil {
+		return nil
+	}
+	_, err := io.Copy(w, body)
+	return err
+}
+
+// writeRanges streams several ranges of a non-RDF resource as a
+// multipart/byteranges body
+func (s *SolidService) writeRanges(ctx context.Context, w http.ResponseWriter, r *http.Request, resource entity.Resource, ranges []byteRange) error {
+	// The first range is opened before responding, so a missing blob is still reported
+	first, err := s.resources.OpenRange(ctx, resource, ranges[0].start, ranges[0].length)
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	parts := multipart.NewWriter(w)
+	writePart := func(body io.ReadCloser, rng byteRange) error {
+		defer body.Close()
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":  {resource.GetContentType()},
+			"Content-Range": {rng.contentRange(resource.GetContent().Size)},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, body)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "multipart/byteranges; boundary="+parts.Boundary())
+	w.WriteHeader(http.StatusPartialContent)
+	if err := writePart(first, ranges[0]); err != nil {
 		return err
 	}
+	for _, rng := range ranges[1:] {
+		body, err := s.resources.OpenRange(ctx, resource, rng.start, rng.length)
+		if err != nil {
+			return err
+		}
+		if err := writePart(body, rng); err != nil {
+			return err
+		}
+	}
+	return parts.Close()
+}
+
+// setMementoLinks links a response to the original resource at uri, which
+// is its own TimeGate, and to its TimeMap
+func setMementoLinks(w http.ResponseWriter, uri string) {
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="original timegate"`, uri))
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="timemap"; type="%s"`, TimeMapURI(uri), TimeMapFormat))
+}
+
+// setDescribedByLink links a response to the description of the resource at
+// uri. Auxiliary resources are not described.
+func setDescribedByLink(w http.ResponseWriter, uri string) {
+	if isAuxiliary(uri) {
+		return
+	}
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="describedby"`, DescriptionURI(uri)))
+}
+
+// listTrash responds with the deleted members of the target container as JSON
+func (s *SolidService) listTrash(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		http.Error(w, "only containers have a trash", http.StatusBadRequest)
+		return nil
+	}
+	entries, err := s.resources.Trash(ctx, requestURI(r))
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// restoreResource handles POST ?restore, bringing the target resource back
+// from the trash
+func (s *SolidService) restoreResource(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	resource, err := s.resources.Restore(ctx, requestURI(r))
+	if err != nil {
+		return s.writeError(w, r, err)
+	}
+
+	w.Header().Set("ETag", resource.GetETag())
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
 
+// versionParam reads a version number from the query parameter name
+func versionParam(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("%w: %s=%q", ErrInvalidVersion, name, value)
+	}
+	return version, nil
+}
+
+type agentKey struct{}
+
+// WithAgent returns a request context authenticated as the agent with the
+// WebID. The layer authenticating requests sets it; the service takes no
+// client's word for who it is.
+func WithAgent(ctx context.Context, webID string) context.Context {
+	return context.WithValue(ctx, agentKey{}, webID)
+}
+
+// withRequestAuthor records the agent a request was authenticated as as the
+// author of the changes it makes. Changes of unauthenticated requests have
+// no author.
+func withRequestAuthor(ctx context.Context, r *http.Request) context.Context {
+	if agent, _ := r.Context().Value(agentKey{}).(string); agent != "" {
+		return WithAuthor(ctx, agent)
+	}
+	return ctx
+}
+
+// writeError maps a resource error to an HTTP response. Shape violations
+// are returned as an sh:ValidationReport in the negotiated RDF format.
+func (s *SolidService) writeError(w http.ResponseWriter, r *http.Request, err error) error {
+	var shapeErr *domainservice.ShapeValidationError
+	var validationErr *domainservice.ValidationError
+	var treeErr *ShapeTreeError
+	switch {
+	case errors.As(err, &shapeErr):
+		format := negotiateFormat(r.Header.Get("Accept"), s.rdfService.SupportedFormats(), string(domainservice.FormatTurtle))
+		report, serializeErr := s.rdfService.SerializeGraph(shapeErr.Report.Graph(), format)
+		if serializeErr != nil {
+			return serializeErr
+		}
+		w.Header().Set("Content-Type", format)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, writeErr := io.WriteString(w, report)
+		return writeErr
+	case errors.As(err, &treeErr):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrProtectedResource), errors.Is(err, ErrProtectedTriple), errors.Is(err, ErrShapeTreeAlreadyPlanted), errors.Is(err, ErrShapeTreeNotRoot),
+		errors.Is(err, ErrResourceNotDeleted), errors.Is(err, ErrContainerNotEmpty), errors.Is(err, ErrContainerDeleted),
+		errors.Is(err, repository.ErrBlobOffset), errors.Is(err, repository.ErrResourceConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repository.ErrResourceExists):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, ErrShapeTreeNotPlanted), errors.Is(err, repository.ErrResourceNotFound), errors.Is(err, ErrVersionNotFound),
+		errors.Is(err, ErrUploadNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrResourceGone):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, domainservice.ErrInputTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, domainservice.ErrInputTooComplex), errors.Is(err, domainservice.ErrAmbiguousIdentity):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrUnsupportedMediaType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.As(err, &validationErr), errors.Is(err, ErrInvalidVersion), errors.Is(err, ErrInvalidDatetime),
+		errors.Is(err, ErrInvalidDestination), errors.Is(err, ErrNotRDF), errors.Is(err, ErrInvalidUpload), errors.Is(err, ErrDigestMismatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		s.logger.Error("Resource request failed", zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
 	return nil
 }
+
+// readBody reads an RDF request body, parsing it as it arrives so that a
+// body over the size, triple or literal limits is refused before the rest
+// of it is read. Other parse errors are left for the resource service to
+// report.
+func (s *SolidService) readBody(r *http.Request) ([]byte, error) {
+	var body bytes.Buffer
+	var input io.Reader = r.Body
+	max := s.config.Solid.MaxRDFBytes
+	if max > 0 {
+		input = io.LimitReader(r.Body, max+1)
+	}
+	input = io.TeeReader(input, &body)
+
+	_, err := s.rdfService.WithBase(requestURI(r)).ParseDatasetReader(input, r.Header.Get("Content-Type"))
+	var limitErr *domainservice.LimitError
+	if errors.As(err, &limitErr) {
+		return nil, err
+	}
+	if _, err := io.Copy(io.Discard, input); err != nil {
+		return nil, err
+	}
+
+	if max > 0 && int64(body.Len()) > max {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		cause := &domainservice.LimitError{Limit: domainservice.LimitBytes, Max: max}
+		return nil, domainservice.NewValidationError(domainservice.RDFFormat(mediaType), cause.Error(), cause)
+	}
+	return body.Bytes(), nil
+}
+
+// requestURI returns the absolute URI targeted by the request
+func requestURI(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + r.Host + r.URL.EscapedPath()
+}
+
+// destinationURI resolves the Destination header against the request URI.
+// Destinations on another server are refused.
+func destinationURI(r *http.Request) (string, error) {
+	header := strings.TrimSpace(r.Header.Get("Destination"))
+	if header == "" {
+		return "", fmt.Errorf("%w: missing Destination header", ErrInvalidDestination)
+	}
+	base, err := url.Parse(requestURI(r))
+	if err != nil {
+		return "", err
+	}
+	destination, err := base.Parse(header)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDestination, header)
+	}
+	if !strings.EqualFold(destination.Host, base.Host) {
+		return "", fmt.Errorf("%w: %s is on another server", ErrInvalidDestination, header)
+	}
+	return base.Scheme + "://" + base.Host + destination.EscapedPath(), nil
+}
+
+// linkTargets returns the targets of the Link header values with relation rel
+func linkTargets(values []string, rel string) []string {
+	var targets []string
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, relation := range strings.Fields(strings.Trim(value, `"`)) {
+					if relation == rel {
+						targets = append(targets, target[1:len(target)-1])
+					}
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// isContainerType reports whether the type links name an LDP container
+func isContainerType(types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "http://www.w3.org/ns/ldp#Container", "http://www.w3.org/ns/ldp#BasicContainer":
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateFormat picks the supported media type the Accept header prefers,
+// falling back to fallback when nothing acceptable is supported. The picked
+// type keeps the parameters it was accepted with, such as a JSON-LD profile.
+func negotiateFormat(accept string, supported []string, fallback string) string {
+	type candidate struct {
+		mediaType string
+		params    map[string]string
+		quality   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		delete(params, "q")
+		if quality > 0 {
+			candidates = append(candidates, candidate{mediaType: mediaType, params: params, quality: quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		if c.mediaType == "*/*" || c.mediaType == "text/*" && strings.HasPrefix(fallback, "text/") {
+			return fallback
+		}
+		for _, format := range supported {
+			if format == c.mediaType {
+				return mime.FormatMediaType(format, c.params)
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+var (
+	// ErrResourceGone is returned for resources that have been deleted and not yet purged
+	ErrResourceGone = errors.New("resource has been deleted")
+
+	// ErrResourceNotDeleted is returned when restoring a resource that is not deleted
+	ErrResourceNotDeleted = errors.New("resource is not deleted")
+
+	// ErrContainerNotEmpty is returned when deleting a container that still has members
+	ErrContainerNotEmpty = errors.New("container is not empty")
+
+	// ErrContainerDeleted is returned when restoring a member of a deleted container
+	ErrContainerDeleted = errors.New("container has been deleted")
+)
+
+// TrashEntry is a deleted resource waiting to be restored or purged
+type TrashEntry struct {
+	URI       string    `json:"uri"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// Delete turns the resource at uri into a tombstone. Containers can only be
+// deleted once their members are.
+func (s *ResourceService) Delete(ctx context.Context, uri string) error {
+	resource, err := s.live(ctx, uri)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(uri, "/") {
+		members, err := s.members(ctx, uri, false)
+		if err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			return fmt.Errorf("%w: %s has %d members", ErrContainerNotEmpty, uri, len(members))
+		}
+	}
+
+	resource.WithAuthor(authorFrom(ctx)).Delete()
+	if resource.HasErrors() {
+		return errors.Join(resource.GetErrors()...)
+	}
+	if err := s.repository.Save(ctx, resource); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", uri, err)
+	}
+
+	s.logger.Info("Deleted resource", zap.String("uri", uri))
+	return nil
+}
+
+// Trash lists the deleted members of the container at containerURI
+func (s *ResourceService) Trash(ctx context.Context, containerURI string) ([]TrashEntry, error) {
+	members, err := s.members(ctx, containerURI, true)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]TrashEntry, 0, len(members))
+	for _, member := range members {
+		entries = append(entries, TrashEntry{URI: member.GetURI(), DeletedAt: member.GetDeletedAt()})
+	}
+	return entries, nil
+}
+
+// Restore brings back the deleted resource at uri as a new event
+func (s *ResourceService) Restore(ctx context.Context, uri string) (entity.Resource, error) {
+	resource, err := s.repository.GetByURI(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if resource == nil {
+		return nil, repository.ErrResourceNotFound
+	}
+	if !resource.IsDeleted() {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotDeleted, uri)
+	}
+	if container := parentContainer(uri); container != "" {
+		parent, err := s.repository.GetByURI(ctx, container)
+		if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+			return nil, err
+		}
+		if err == nil && parent != nil && parent.IsDeleted() {
+			return nil, fmt.Errorf("%w: restore %s first", ErrContainerDeleted, container)
+		}
+	}
+
+	resource.WithAuthor(authorFrom(ctx)).Restore()
+	if resource.HasErrors() {
+		return nil, errors.Join(resource.GetErrors()...)
+	}
+	if err := s.repository.Save(ctx, resource); err != nil {
+		return nil, fmt.Errorf("failed to restore %s: %w", uri, err)
+	}
+
+	s.logger.Info("Restored resource", zap.String("uri", uri))
+	return resource, nil
+}
+
+// Purge removes the events and content of every resource deleted before
+// cutoff, along with their auxiliary resources, and returns how many
+// resources were removed
+func (s *ResourceService) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	resources, err := s.repository.FindDeletedBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, resource := range resources {
+		auxiliaries, err := s.auxiliaries(ctx, resource.GetURI())
+		if err != nil {
+			return purged, err
+		}
+		for _, aux := range auxiliaries {
+			if err := s.remove(ctx, aux); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if err := s.remove(ctx, resource); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// remove deletes the events of resource and the bytes stored by each of
+// its versions
+func (s *ResourceService) remove(ctx context.Context, resource entity.Resource) error {
+	events, err := s.repository.LoadEvents(ctx, resource.ID())
+	if err != nil {
+		return err
+	}
+	if err := s.repository.Delete(ctx, resource.ID()); err != nil {
+		return fmt.Errorf("failed to purge %s: %w", resource.GetURI(), err)
+	}
+	for _, content := range storedContent(events) {
+		s.discardContent(ctx, resource.GetURI(), content)
+	}
+	return nil
+}
+
+// auxiliaries returns the auxiliary resources of the resource at uri: the
+// description and ACL of a resource, or those and the shape tree locator
+// of a container
+func (s *ResourceService) auxiliaries(ctx context.Context, uri string) ([]entity.Resource, error) {
+	if strings.HasSuffix(uri, "/") {
+		members, err := s.repository.FindByContainer(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		var auxiliaries []entity.Resource
+		for _, member := range members {
+			if isAuxiliary(member.GetURI()) {
+				auxiliaries = append(auxiliaries, member)
+			}
+		}
+		return auxiliaries, nil
+	}
+
+	var auxiliaries []entity.Resource
+	for _, aux := range []string{DescriptionURI(uri), uri + ACLSuffix} {
+		resource, err := s.repository.GetByURI(ctx, aux)
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resource != nil {
+			auxiliaries = append(auxiliaries, resource)
+		}
+	}
+	return auxiliaries, nil
+}
+
+// live returns the resource at uri, or ErrResourceGone when it is a tombstone
+func (s *ResourceService) live(ctx context.Context, uri string) (entity.Resource, error) {
+	resource, err := s.repository.GetByURI(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if resource == nil {
+		return nil, repository.ErrResourceNotFound
+	}
+	if resource.IsDeleted() {
+		return nil, fmt.Errorf("%w: %s", ErrResourceGone, uri)
+	}
+	return resource, nil
+}
+
+// members returns the deleted or the live members of a container, leaving
+// out its auxiliary resources
+func (s *ResourceService) members(ctx context.Context, containerURI string, deleted bool) ([]entity.Resource, error) {
+	resources, err := s.repository.FindByContainer(ctx, containerURI)
+	if err != nil {
+		return nil, err
+	}
+	var members []entity.Resource
+	for _, resource := range resources {
+		if resource.IsDeleted() == deleted && !isAuxiliary(resource.GetURI()) {
+			members = append(members, resource)
+		}
+	}
+	return members, nil
+}
+
+// TrashPurger periodically purges resources that have been in the trash
+// for longer than the retention window
+type TrashPurger struct {
+	resources *ResourceService
+	retention time.Duration
+	interval  time.Duration
+	logger    logger.Logger
+}
+
+// NewTrashPurger creates a purger that runs every interval. A retention of
+// zero or less keeps deleted resources forever.
+func NewTrashPurger(resources *ResourceService, retention time.Duration, interval time.Duration, logger logger.Logger) *TrashPurger {
+	return &TrashPurger{
+		resources: resources,
+		retention: retention,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run purges expired resources every interval until ctx is done
+func (p *TrashPurger) Run(ctx context.Context) {
+	if p.retention <= 0 || p.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.PurgeExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired purges the resources deleted more than the retention window ago
+func (p *TrashPurger) PurgeExpired(ctx context.Context) {
+	purged, err := p.resources.Purge(ctx, time.Now().Add(-p.retention))
+	if err != nil {
+		p.logger.Error("Failed to purge deleted resources", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		p.logger.Info("Purged deleted resources", zap.Int("count", purged))
+	}
+}
@@ -0,0 +1,127 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestTrash(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const container = "https://pod.example.com/notes/"
+	const uri = container + "a"
+
+	withNote := func(t *testing.T) (*service.ResourceService, *service.SolidService, *repository.MemoryResourceRepository) {
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		resourceService := service.NewResourceService(repo, repository.NewMemoryBlobStore(), rdfService, log)
+		_, _, err := resourceService.Put(context.Background(), container, `<> <http://purl.org/dc/terms/title> "Notes" .`, "text/turtle")
+		require.NoError(t, err)
+		_, _, err = resourceService.Put(context.Background(), uri, `<> <http://purl.org/dc/terms/title> "A" .`, "text/turtle")
+		require.NoError(t, err)
+		return resourceService, service.NewSolidService(&config.Config{}, log, resourceService, rdfService), repo
+	}
+
+	serve := func(t *testing.T, handler func(context.Context, http.ResponseWriter, *http.Request) error, method, target string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, nil)
+		recorder := httptest.NewRecorder()
+		require.NoError(t, handler(request.Context(), recorder, request))
+		return recorder
+	}
+
+	t.Run("keeps deleted resources as tombstones", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withNote(t)
+
+		// Act
+		deleted := serve(t, solidService.DeleteResource, http.MethodDelete, uri)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, deleted.Code)
+		assert.Equal(t, http.StatusGone, serve(t, solidService.GetResource, http.MethodGet, uri).Code)
+		assert.Equal(t, http.StatusGone, serve(t, solidService.DeleteResource, http.MethodDelete, uri).Code)
+		assert.Equal(t, http.StatusOK, serve(t, solidService.GetResource, http.MethodGet, uri+"?versions").Code)
+	})
+
+	t.Run("lists the trash of a container", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withNote(t)
+		serve(t, solidService.DeleteResource, http.MethodDelete, uri)
+
+		// Act
+		recorder := serve(t, solidService.GetResource, http.MethodGet, container+"?trash")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var entries []service.TrashEntry
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&entries))
+		require.Len(t, entries, 1)
+		assert.Equal(t, uri, entries[0].URI)
+		assert.False(t, entries[0].DeletedAt.IsZero())
+	})
+
+	t.Run("restores a resource with a new event", func(t *testing.T) {
+		// Arrange
+		resourceService, solidService, repo := withNote(t)
+		serve(t, solidService.DeleteResource, http.MethodDelete, uri)
+
+		// Act
+		recorder := serve(t, solidService.CreateResource, http.MethodPost, uri+"?restore")
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		_, data, err := resourceService.Get(context.Background(), uri, string(domainservice.FormatNTriples))
+		require.NoError(t, err)
+		assert.Contains(t, data, `"A"`)
+		resource, err := repo.GetByURI(context.Background(), uri)
+		require.NoError(t, err)
+		events, err := repo.LoadEvents(context.Background(), resource.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "resource.restored", events[len(events)-1].EventType())
+		assert.Equal(t, http.StatusConflict, serve(t, solidService.CreateResource, http.MethodPost, uri+"?restore").Code)
+	})
+
+	t.Run("refuses to delete containers with members", func(t *testing.T) {
+		// Arrange
+		_, solidService, _ := withNote(t)
+
+		// Act
+		recorder := serve(t, solidService.DeleteResource, http.MethodDelete, container)
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("purges resources deleted before the cutoff", func(t *testing.T) {
+		// Arrange
+		resourceService, _, repo := withNote(t)
+		require.NoError(t, resourceService.Delete(context.Background(), uri))
+		purger := service.NewTrashPurger(resourceService, time.Hour, time.Hour, log)
+
+		// Act
+		purger.PurgeExpired(context.Background())
+		kept, keptErr := repo.GetByURI(context.Background(), uri)
+		purged, err := resourceService.Purge(context.Background(), time.Now().Add(time.Second))
+
+		// Assert
+		require.NoError(t, keptErr)
+		assert.True(t, kept.IsDeleted())
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		_, err = repo.GetByURI(context.Background(), uri)
+		assert.ErrorIs(t, err, domainrepository.ErrResourceNotFound)
+	})
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+const (
+	// uploadSuffix is appended to the blob key of an upload to name the blob
+	// recording it
+	uploadSuffix = "_upload"
+
+	// pendingUploadsKey names the blob listing the uploads in progress with
+	// when each may have expired, so they can be swept
+	pendingUploadsKey = "pending_uploads"
+
+	// DefaultUploadExpiry is how long an upload is kept without receiving
+	// any bytes
+	DefaultUploadExpiry = 24 * time.Hour
+)
+
+var (
+	// ErrUploadNotFound is returned when a resource has no upload with the requested ID
+	ErrUploadNotFound = errors.New("upload not found")
+
+	// ErrInvalidUpload is returned when an upload is started or continued without a valid length or offset
+	ErrInvalidUpload = errors.New("invalid upload")
+)
+
+// Upload is a resumable upload of a non-RDF resource. Its bytes are staged
+// in the blob store and only become the resource's content once all Length
+// bytes have arrived. An upload receiving no bytes until Expires is
+// discarded.
+type Upload struct {
+	ID          string    `json:"id"`
+	URI         string    `json:"uri"`
+	ContentType string    `json:"contentType"`
+	Length      int64     `json:"length"`
+	Offset      int64     `json:"offset"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires,omitempty"`
+}
+
+// ExpireUploadsAfter sets how long uploads are kept without receiving any
+// bytes. Zero or less keeps them until they are completed or aborted.
+func (s *ResourceService) ExpireUploadsAfter(expiry time.Duration) {
+	s.uploadExpiry = expiry
+}
+
+// UploadURI returns the URI of the upload with the given ID to the resource at uri
+func UploadURI(uri string, id string) string {
+	return uri + "?upload=" + id
+}
+
+// StartUpload starts a resumable upload of length bytes of contentType to
+// the non-RDF resource at uri. Nothing changes at uri until the upload is
+// complete.
+func (s *ResourceService) StartUpload(ctx context.Context, uri string, contentType string, length int64) (Upload, error) {
+	if err := checkContentTarget(uri); err != nil {
+		return Upload{}, err
+	}
+	if s.IsRDF(contentType) {
+		return Upload{}, fmt.Errorf("%w: RDF resources cannot be uploaded in parts", ErrUnsupportedMediaType)
+	}
+	if length < 1 {
+		return Upload{}, fmt.Errorf("%w: length must be at least 1, not %d", ErrInvalidUpload, length)
+	}
+	mediaType, err := contentMediaType(contentType)
+	if err != nil {
+		return Upload{}, err
+	}
+	// Refuse what the container's shape trees will not accept before any bytes are sent
+	if _, err := s.shapes.ContentConstraintsFor(ctx, uri); err != nil {
+		return Upload{}, err
+	}
+
+	id, err := blobKey()
+	if err != nil {
+		return Upload{}, err
+	}
+	upload := Upload{ID: id, URI: uri, ContentType: mediaType, Length: length, Created: time.Now().UTC()}
+	if s.uploadExpiry > 0 {
+		upload.Expires = upload.Created.Add(s.uploadExpiry)
+	}
+	// Listed first, so an upload whose start fails part way is still swept
+	if s.uploadExpiry > 0 {
+		if _, err := changeRecord(ctx, s.blobs, pendingUploadsKey, func(pending *map[string]time.Time) error {
+			if *pending == nil {
+				*pending = make(map[string]time.Time)
+			}
+			(*pending)[id] = upload.Expires
+			return nil
+		}); err != nil {
+			return Upload{}, fmt.Errorf("failed to start upload: %w", err)
+		}
+	}
+	if _, err := s.blobs.Append(ctx, id, 0, bytes.NewReader(nil)); err != nil {
+		return Upload{}, fmt.Errorf("failed to start upload: %w", err)
+	}
+	if err := s.writeUpload(ctx, upload); err != nil {
+		s.discardContent(ctx, uri, entity.Content{Key: id})
+		return Upload{}, fmt.Errorf("failed to start upload: %w", err)
+	}
+
+	s.logger.Info("Started upload", zap.String("uri", uri), zap.String("upload", id), zap.Int64("length", length))
+	return upload, nil
+}
+
+// Upload returns the upload with the given ID to the resource at uri, with
+// the number of bytes received so far as its offset. Expired uploads are
+// not found, even before they are swept.
+func (s *ResourceService) Upload(ctx context.Context, uri string, id string) (Upload, error) {
+	if !isUploadID(id) {
+		return Upload{}, fmt.Errorf("%w: %s", ErrUploadNotFound, id)
+	}
+	upload, err := s.readUpload(ctx, id)
+	if err != nil {
+		return Upload{}, err
+	}
+	if upload.URI != uri || upload.expired(time.Now()) {
+		return Upload{}, fmt.Errorf("%w: %s", ErrUploadNotFound, id)
+	}
+	if upload.Offset, err = s.blobs.Staged(ctx, id); err != nil {
+		return Upload{}, fmt.Errorf("failed to read upload %s: %w", id, err)
+	}
+	return upload, nil
+}
+
+// AppendUpload adds body to the upload with the given ID, which must have
+// received offset bytes so far. Bytes past the length of the upload are
+// ignored. Once all bytes have arrived the upload becomes the content of
+// its resource, which is returned along with whether it was created.
+func (s *ResourceService) AppendUpload(ctx context.Context, uri string, id string, offset int64, body io.Reader) (upload Upload, resource entity.Resource, created bool, err error) {
+	if upload, err = s.Upload(ctx, uri, id); err != nil {
+		return Upload{}, nil, false, err
+	}
+	if offset < 0 || offset > upload.Length {
+		return upload, nil, false, fmt.Errorf("%w: offset %d is outside the %d bytes of the upload", ErrInvalidUpload, offset, upload.Length)
+	}
+
+	// A client whose connection drops asks for the offset to resume from
+	size, err := s.blobs.Append(ctx, id, offset, io.LimitReader(body, upload.Length-offset))
+	if err != nil {
+		return upload, nil, false, err
+	}
+	upload.Offset = size
+	if size < upload.Length {
+		// Bytes arriving keep the upload alive
+		if s.uploadExpiry > 0 {
+			upload.Expires = time.Now().UTC().Add(s.uploadExpiry)
+			if err := s.writeUpload(ctx, upload); err != nil {
+				return upload, nil, false, fmt.Errorf("failed to extend upload %s: %w", id, err)
+			}
+		}
+		return upload, nil, false, nil
+	}
+
+	resource, created, err = s.commitUpload(ctx, upload)
+	return upload, resource, created, err
+}
+
+// AbortUpload discards the upload with the given ID and the bytes it received
+func (s *ResourceService) AbortUpload(ctx context.Context, uri string, id string) error {
+	if _, err := s.Upload(ctx, uri, id); err != nil {
+		return err
+	}
+	if err := s.removeUpload(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("Aborted upload", zap.String("uri", uri), zap.String("upload", id))
+	return nil
+}
+
+// SweepUploads discards the uploads that expired before now along with the
+// bytes they received, and returns how many there were
+func (s *ResourceService) SweepUploads(ctx context.Context, now time.Time) (int, error) {
+	pending, _, err := s.blobs.GetTagged(ctx, pendingUploadsKey)
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var expiries map[string]time.Time
+	if err := json.Unmarshal(pending, &expiries); err != nil {
+		return 0, fmt.Errorf("failed to read pending uploads: %w", err)
+	}
+
+	// The list only tells when an upload may have expired; its record says
+	// whether bytes arrived since
+	swept := 0
+	extended := make(map[string]time.Time)
+	for id, expires := range expiries {
+		if expires.After(now) {
+			continue
+		}
+		upload, err := s.readUpload(ctx, id)
+		switch {
+		case errors.Is(err, ErrUploadNotFound):
+			s.unlistUpload(ctx, id)
+		case err != nil:
+			return swept, err
+		case !upload.expired(now):
+			extended[id] = upload.Expires
+		default:
+			if err := s.removeUpload(ctx, id); err != nil {
+				return swept, err
+			}
+			swept++
+		}
+	}
+	if len(extended) > 0 {
+		if _, err := changeRecord(ctx, s.blobs, pendingUploadsKey, func(pending *map[string]time.Time) error {
+			for id, expires := range extended {
+				if _, ok := (*pending)[id]; ok {
+					(*pending)[id] = expires
+				}
+			}
+			return nil
+		}); err != nil {
+			return swept, err
+		}
+	}
+	return swept, nil
+}
+
+// readUpload reads the record of the upload with the given ID
+func (s *ResourceService) readUpload(ctx context.Context, id string) (Upload, error) {
+	reader, err := s.blobs.Get(ctx, id+uploadSuffix)
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return Upload{}, fmt.Errorf("%w: %s", ErrUploadNotFound, id)
+	}
+	if err != nil {
+		return Upload{}, err
+	}
+	defer reader.Close()
+
+	var upload Upload
+	if err := json.NewDecoder(reader).Decode(&upload); err != nil {
+		return Upload{}, fmt.Errorf("failed to read upload %s: %w", id, err)
+	}
+	return upload, nil
+}
+
+// writeUpload replaces the record of upload. Its offset is not recorded,
+// since the staged blob knows it.
+func (s *ResourceService) writeUpload(ctx context.Context, upload Upload) error {
+	upload.Offset = 0
+	record, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	_, err = s.blobs.Put(ctx, upload.ID+uploadSuffix, bytes.NewReader(record))
+	return err
+}
+
+// removeUpload deletes the bytes and the record of the upload with the
+// given ID and takes it off the pending uploads
+func (s *ResourceService) removeUpload(ctx context.Context, id string) error {
+	if err := s.blobs.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.blobs.Delete(ctx, id+uploadSuffix); err != nil {
+		return err
+	}
+	s.unlistUpload(ctx, id)
+	return nil
+}
+
+// unlistUpload takes the upload with the given ID off the pending uploads.
+// Failures are logged since the sweep tries again.
+func (s *ResourceService) unlistUpload(ctx context.Context, id string) {
+	if s.uploadExpiry <= 0 {
+		return
+	}
+	_, err := changeRecord(ctx, s.blobs, pendingUploadsKey, func(pending *map[string]time.Time) error {
+		delete(*pending, id)
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("Failed to unlist upload", zap.String("upload", id), zap.Error(err))
+	}
+}
+
+// expired reports whether the upload received no bytes until before now
+func (u Upload) expired(now time.Time) bool {
+	return !u.Expires.IsZero() && !u.Expires.After(now)
+}
+
+// commitUpload makes the bytes of a complete upload the content of its
+// resource, referenced by their digest like any other content. Once its
+// bytes are committed the upload is gone, whether the resource can be
+// stored or not.
+func (s *ResourceService) commitUpload(ctx context.Context, upload Upload) (entity.Resource, bool, error) {
+	if err := s.blobs.Commit(ctx, upload.ID); err != nil {
+		return nil, false, fmt.Errorf("failed to commit upload %s: %w", upload.ID, err)
+	}
+	defer s.unlistUpload(ctx, upload.ID)
+	defer s.discardContent(ctx, upload.URI, entity.Content{Key: upload.ID + uploadSuffix})
+
+	digest, err := s.digestOf(ctx, upload.ID)
+	if err != nil {
+		s.discardContent(ctx, upload.URI, entity.Content{Key: upload.ID})
+		return nil, false, fmt.Errorf("failed to commit upload %s: %w", upload.ID, err)
+	}
+	content, err := s.addressContent(ctx, upload.URI, entity.Content{Key: upload.ID, Size: upload.Offset, Digest: digest})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to commit upload %s: %w", upload.ID, err)
+	}
+
+	resource, created, err := s.putContent(ctx, upload.URI, content, upload.ContentType)
+	if err != nil {
+		s.discardContent(ctx, upload.URI, content)
+		return nil, false, err
+	}
+	return resource, created, nil
+}
+
+// isUploadID reports whether id could be the blob key of an upload
+func isUploadID(id string) bool {
+	return len(id) == 32 && strings.Trim(id, "0123456789abcdef") == ""
+}
+
+// UploadSweeper periodically discards uploads that stopped receiving bytes
+// for longer than their expiry
+type UploadSweeper struct {
+	resources *ResourceService
+	interval  time.Duration
+	logger    logger.Logger
+}
+
+// NewUploadSweeper creates a sweeper that runs every interval
+func NewUploadSweeper(resources *ResourceService, interval time.Duration, logger logger.Logger) *UploadSweeper {
+	return &UploadSweeper{
+		resources: resources,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run sweeps expired uploads every interval until ctx is done
+func (p *UploadSweeper) Run(ctx context.Context) {
+	if p.resources.uploadExpiry <= 0 || p.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.SweepExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SweepExpired discards the uploads that have expired
+func (p *UploadSweeper) SweepExpired(ctx context.Context) {
+	swept, err := p.resources.SweepUploads(ctx, time.Now())
+	if err != nil {
+		p.logger.Error("Failed to sweep expired uploads", zap.Error(err))
+		return
+	}
+	if swept > 0 {
+		p.logger.Info("Swept expired uploads", zap.Int("count", swept))
+	}
+}
@@ -0,0 +1,150 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestResumableUploads(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const uri = "https://pod.example.com/files/movie.mp4"
+
+	withBlobs := func(t *testing.T) *service.SolidService {
+		blobs := repository.NewFileSystemBlobStore(t.TempDir())
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), blobs, rdfService, log)
+		return service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+	}
+
+	serve := func(t *testing.T, handler func(context.Context, http.ResponseWriter, *http.Request) error, method, target string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		require.NoError(t, handler(request.Context(), recorder, request))
+		return recorder
+	}
+
+	start := func(t *testing.T, solidService *service.SolidService, length string) string {
+		recorder := serve(t, solidService.CreateResource, http.MethodPost, uri+"?upload",
+			map[string]string{"Content-Type": "video/mp4", "Upload-Length": length}, "")
+		require.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, "0", recorder.Header().Get("Upload-Offset"))
+		return recorder.Header().Get("Location")
+	}
+
+	t.Run("commits the resource once every byte has arrived", func(t *testing.T) {
+		// Arrange
+		solidService := withBlobs(t)
+		upload := start(t, solidService, "11")
+
+		// Act
+		first := serve(t, solidService.PatchResource, http.MethodPatch, upload, map[string]string{"Upload-Offset": "0"}, "hello ")
+		partial := serve(t, solidService.GetResource, http.MethodGet, uri, nil, "")
+		progress := serve(t, solidService.GetResource, http.MethodHead, upload, nil, "")
+		last := serve(t, solidService.PatchResource, http.MethodPatch, upload, map[string]string{"Upload-Offset": "6"}, "world")
+		recorder := serve(t, solidService.GetResource, http.MethodGet, uri, nil, "")
+		finished := serve(t, solidService.GetResource, http.MethodHead, upload, nil, "")
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, first.Code)
+		assert.Equal(t, "6", first.Header().Get("Upload-Offset"))
+		assert.Equal(t, http.StatusNotFound, partial.Code, "partial uploads are not visible")
+		assert.Equal(t, "6", progress.Header().Get("Upload-Offset"))
+		assert.Equal(t, "11", progress.Header().Get("Upload-Length"))
+		assert.Equal(t, http.StatusCreated, last.Code)
+		assert.Equal(t, uri, last.Header().Get("Location"))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "hello world", recorder.Body.String())
+		assert.Equal(t, "video/mp4", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, http.StatusNotFound, finished.Code)
+	})
+
+	t.Run("resumes from the offset the server has", func(t *testing.T) {
+		// Arrange
+		solidService := withBlobs(t)
+		upload := start(t, solidService, "5")
+		serve(t, solidService.PatchResource, http.MethodPatch, upload, map[string]string{"Upload-Offset": "0"}, "he")
+
+		// Act
+		replayed := serve(t, solidService.PatchResource, http.MethodPatch, upload, map[string]string{"Upload-Offset": "0"}, "hello")
+		resumed := serve(t, solidService.PatchResource, http.MethodPatch, upload, map[string]string{"Upload-Offset": "2"}, "llo")
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, replayed.Code)
+		assert.Equal(t, "2", replayed.Header().Get("Upload-Offset"))
+		assert.Equal(t, http.StatusCreated, resumed.Code)
+	})
+
+	t.Run("discards aborted uploads", func(t *testing.T) {
+		// Arrange
+		solidService := withBlobs(t)
+		upload := start(t, solidService, "5")
+
+		// Act
+		aborted := serve(t, solidService.DeleteResource, http.MethodDelete, upload, nil, "")
+		patched := serve(t, solidService.PatchResource, http.MethodPatch, upload, map[string]string{"Upload-Offset": "0"}, "hello")
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, aborted.Code)
+		assert.Equal(t, http.StatusNotFound, patched.Code)
+	})
+
+	t.Run("sweeps uploads that expired", func(t *testing.T) {
+		// Arrange
+		blobs := repository.NewFileSystemBlobStore(t.TempDir())
+		resourceService := service.NewResourceService(repository.NewMemoryResourceRepository(rdfService), blobs, rdfService, log)
+		resourceService.ExpireUploadsAfter(time.Hour)
+		solidService := service.NewSolidService(&config.Config{}, log, resourceService, rdfService)
+		upload := start(t, solidService, "5")
+		id := strings.TrimPrefix(upload, uri+"?upload=")
+		ctx := context.Background()
+
+		// Act
+		early, err := resourceService.SweepUploads(ctx, time.Now().Add(30*time.Minute))
+		require.NoError(t, err)
+		progress := serve(t, solidService.GetResource, http.MethodHead, upload, nil, "")
+		late, err := resourceService.SweepUploads(ctx, time.Now().Add(2*time.Hour))
+		require.NoError(t, err)
+		patched := serve(t, solidService.PatchResource, http.MethodPatch, upload, map[string]string{"Upload-Offset": "0"}, "hello")
+
+		// Assert
+		assert.Zero(t, early)
+		expires, err := http.ParseTime(progress.Header().Get("Upload-Expires"))
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), expires, time.Minute)
+		assert.Equal(t, 1, late)
+		assert.Equal(t, http.StatusNotFound, patched.Code)
+		_, err = blobs.Staged(ctx, id)
+		assert.ErrorIs(t, err, domainrepository.ErrBlobNotFound, "the bytes are gone")
+	})
+
+	t.Run("rejects uploads without a length or of RDF", func(t *testing.T) {
+		// Arrange
+		solidService := withBlobs(t)
+
+		// Act
+		missing := serve(t, solidService.CreateResource, http.MethodPost, uri+"?upload", map[string]string{"Content-Type": "video/mp4"}, "")
+		turtle := serve(t, solidService.CreateResource, http.MethodPost, uri+"?upload",
+			map[string]string{"Content-Type": "text/turtle", "Upload-Length": "10"}, "")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, missing.Code)
+		assert.Equal(t, http.StatusUnsupportedMediaType, turtle.Code)
+	})
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+// ApplyExternalEdit stores a change made to the resource at edit.URI from
+// outside the pod as if it had been sent to the pod, so it is validated and
+// recorded as events like any other write. A removed representation deletes
+// the resource, and a new container without one is created empty.
+func (s *ResourceService) ApplyExternalEdit(ctx context.Context, edit repository.ExternalEdit) error {
+	switch {
+	case edit.Removed:
+		return s.Delete(ctx, edit.URI)
+	case edit.Open == nil:
+		_, _, err := s.Put(ctx, edit.URI, fmt.Sprintf("<> a <%s> .", LDPBasicContainer), "text/turtle")
+		return err
+	}
+
+	body, err := edit.Open()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if !s.IsRDF(edit.ContentType) {
+		_, _, err = s.PutContent(ctx, edit.URI, body, edit.ContentType)
+		return err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	// Files are edited by hand, so a replacement is parsed before it is stored
+	if _, err := s.rdfService.WithBase(edit.URI).ParseGraph(string(data), edit.ContentType); err != nil {
+		return fmt.Errorf("failed to read %s: %w", edit.URI, err)
+	}
+	_, _, err = s.Put(ctx, edit.URI, string(data), edit.ContentType)
+	return err
+}
+
+// EditWatcher periodically brings changes made to stored resources from
+// outside the pod, such as files edited on disk, into the pod
+type EditWatcher struct {
+	resources *ResourceService
+	source    repository.EditSource
+	interval  time.Duration
+	logger    logger.Logger
+
+	// seen holds the version of the edits found by the last sync, so an
+	// edit still pending after it was tried is not tried again
+	seen map[string]string
+}
+
+// NewEditWatcher creates a watcher that looks for edits in source every
+// interval. A nil source or an interval of zero or less disables it.
+func NewEditWatcher(resources *ResourceService, source repository.EditSource, interval time.Duration, logger logger.Logger) *EditWatcher {
+	return &EditWatcher{
+		resources: resources,
+		source:    source,
+		interval:  interval,
+		logger:    logger,
+		seen:      make(map[string]string),
+	}
+}
+
+// Run applies external edits every interval until ctx is done
+func (w *EditWatcher) Run(ctx context.Context) {
+	if w.source == nil || w.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.Sync(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync applies the external edits made since the last sync. Edits the pod
+// rejects, such as invalid Turtle, are logged and skipped until the
+// resource is edited again.
+func (w *EditWatcher) Sync(ctx context.Context) {
+	edits, err := w.source.ExternalEdits(ctx)
+	if err != nil {
+		w.logger.Error("Failed to look for external edits", zap.Error(err))
+		return
+	}
+	seen := make(map[string]string, len(edits))
+	defer func() { w.seen = seen }()
+	for _, edit := range edits {
+		seen[edit.URI] = edit.Version
+		if w.seen[edit.URI] == edit.Version {
+			continue
+		}
+		if err := w.resources.ApplyExternalEdit(ctx, edit); err != nil {
+			w.logger.Warn("Rejected external edit", zap.String("uri", edit.URI), zap.Error(err))
+			continue
+		}
+		w.logger.Info("Applied external edit", zap.String("uri", edit.URI), zap.Bool("removed", edit.Removed))
+	}
+}
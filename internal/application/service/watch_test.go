@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+func TestEditWatcher(t *testing.T) {
+	rdfService := domainservice.NewStandardRDFValidationService()
+	log := logger.New("error")
+	const container = "https://pod.example.com/notes/"
+	const uri = container + "a"
+
+	withNote := func(t *testing.T) (*service.ResourceService, *service.EditWatcher, string) {
+		root := t.TempDir()
+		blobs := repository.NewMemoryBlobStore()
+		repo := repository.NewFileSystemResourceRepository(root, blobs, rdfService)
+		resourceService := service.NewResourceService(repo, blobs, rdfService, log)
+		_, _, err := resourceService.Put(context.Background(), container, `<> <http://purl.org/dc/terms/title> "Notes" .`, "text/turtle")
+		require.NoError(t, err)
+		_, _, err = resourceService.Put(context.Background(), uri, `<> <http://purl.org/dc/terms/title> "A" .`, "text/turtle")
+		require.NoError(t, err)
+		return resourceService, service.NewEditWatcher(resourceService, repo, 0, log), filepath.Join(root, "https", "pod.example.com", "notes")
+	}
+
+	t.Run("applies files edited on disk", func(t *testing.T) {
+		// Arrange
+		resourceService, watcher, dir := withNote(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a$.ttl"), []byte(`<> <http://purl.org/dc/terms/title> "Edited" .`), 0o644))
+
+		// Act
+		watcher.Sync(context.Background())
+
+		// Assert
+		_, data, err := resourceService.Get(context.Background(), uri, string(domainservice.FormatNTriples))
+		require.NoError(t, err)
+		assert.Contains(t, data, `"Edited"`)
+		assert.NotContains(t, data, `"A"`)
+	})
+
+	t.Run("creates resources for new files", func(t *testing.T) {
+		// Arrange
+		resourceService, watcher, dir := withNote(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "b.md"), []byte("# B"), 0o644))
+
+		// Act
+		watcher.Sync(context.Background())
+
+		// Assert
+		resource, _, err := resourceService.Get(context.Background(), container+"b.md", "text/turtle")
+		require.NoError(t, err)
+		assert.True(t, resource.IsNonRDF())
+		body, err := resourceService.Open(context.Background(), resource)
+		require.NoError(t, err)
+		defer body.Close()
+		content, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, "# B", string(content))
+	})
+
+	t.Run("deletes resources whose files were removed", func(t *testing.T) {
+		// Arrange
+		resourceService, watcher, dir := withNote(t)
+		require.NoError(t, os.Remove(filepath.Join(dir, "a$.ttl")))
+
+		// Act
+		watcher.Sync(context.Background())
+
+		// Assert
+		_, _, err := resourceService.Get(context.Background(), uri, "text/turtle")
+		assert.ErrorIs(t, err, service.ErrResourceGone)
+	})
+
+	t.Run("keeps the stored resource when an edit is rejected", func(t *testing.T) {
+		// Arrange
+		resourceService, watcher, dir := withNote(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a$.ttl"), []byte(`<> <http://purl.org/dc/terms/title> "unterminated .`), 0o644))
+
+		// Act
+		watcher.Sync(context.Background())
+
+		// Assert
+		_, data, err := resourceService.Get(context.Background(), uri, string(domainservice.FormatNTriples))
+		require.NoError(t, err)
+		assert.Contains(t, data, `"A"`)
+	})
+}
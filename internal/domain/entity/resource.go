@@ -8,6 +8,7 @@ import (
 
 	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
 	"github.com/wepala/vine-pod/internal/domain/event"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
 	"github.com/wepala/vine-pod/internal/domain/service"
 )
 
@@ -30,16 +31,36 @@ type Resource interface {
 	FromJSONLD(data string) Resource
 	FromTurtle(data string) Resource
 	FromRDFXML(data string) Resource
+	FromContent(content Content, contentType string) Resource
 	WithURI(uri string) Resource
+	WithBase(base string) Resource
+	WithShapes(shapes *rdf.Graph) Resource
+	WithShEx(schema *service.ShExSchema, shapeMap service.ShapeMap) Resource
+	WithAuthor(author string) Resource
 
 	// Resource operations
 	Update(data string, contentType string) Resource
+	UpdateContent(content Content, contentType string) Resource
 	Delete() Resource
+	Restore() Resource
 
 	// Resource metadata
+	GetURI() string
+	GetData() string
 	GetContentType() string
+	GetContent() Content
+	IsNonRDF() bool
 	GetLastModified() time.Time
 	GetETag() string
+	IsDeleted() bool
+	GetDeletedAt() time.Time
+}
+
+// Content locates the bytes of a non-RDF resource in the blob store
+type Content struct {
+	Key    string // Blob store key of the bytes
+	Size   int64  // Length of the bytes
+	Digest string // Hex encoded SHA-256 hash of the bytes; empty for bytes stored before blobs were addressed by it
 }
 
 // BasicResource is the concrete implementation of Resource interface
@@ -50,34 +71,48 @@ type BasicResource struct {
 	uri          string
 	contentType  string
 	data         string
+	content      Content // set instead of data for non-RDF resources
 	lastModified time.Time
 	etag         string
 	errors       []error
+	base         string
+	author       string
+	deletedAt    time.Time // zero unless the resource is a tombstone
 
 	// Dependencies
-	rdfValidator service.RDFValidationService
+	rdfValidator   service.RDFValidationService
+	shapeValidator service.SHACLValidationService
+	shapes         *rdf.Graph
+	shexValidator  service.ShExValidationService
+	shexSchema     *service.ShExSchema
+	shapeMap       service.ShapeMap
 }
 
 // NewBasicResource creates a new BasicResource instance
 func NewBasicResource() Resource {
-	return &BasicResource{
-		Entity:       domain.NewEntity(""), // ID will be set when resource is created
-		errors:       make([]error, 0),
-		lastModified: time.Now(),
-		rdfValidator: service.NewStandardRDFValidationService(),
-	}
+	return NewBasicResourceWithValidator(service.NewStandardRDFValidationService())
 }
 
 // NewBasicResourceWithValidator creates a new BasicResource instance with a custom RDF validator
 func NewBasicResourceWithValidator(validator service.RDFValidationService) Resource {
 	return &BasicResource{
-		Entity:       domain.NewEntity(""), // ID will be set when resource is created
-		errors:       make([]error, 0),
-		lastModified: time.Now(),
-		rdfValidator: validator,
+		Entity:         domain.NewEntity(""), // ID will be set when resource is created
+		errors:         make([]error, 0),
+		lastModified:   time.Now(),
+		rdfValidator:   validator,
+		shapeValidator: service.NewStandardSHACLValidationService(),
+		shexValidator:  service.NewStandardShExValidationService(),
 	}
 }
 
+// NewBasicResourceFromHistory rebuilds the resource with the given ID from its committed events
+func NewBasicResourceFromHistory(id string, events []domain.Event, validator service.RDFValidationService) Resource {
+	r := NewBasicResourceWithValidator(validator).(*BasicResource)
+	r.Entity = domain.NewEntity(id)
+	r.LoadFromHistory(events)
+	return r
+}
+
 // FromJSONLD creates a resource from JSON-LD data
 func (r *BasicResource) FromJSONLD(data string) Resource {
 	if data == "" {
@@ -91,12 +126,18 @@ func (r *BasicResource) FromJSONLD(data string) Resource {
 	}
 
 	// Use the RDF validation service to validate and extract the resource ID
-	resourceID, err := r.rdfValidator.ValidateJSONLD(data)
+	resourceID, err := r.parser().ValidateJSONLD(data)
 	if err != nil {
 		r.AddError(fmt.Errorf("JSON-LD validation failed: %w", err))
 		return r
 	}
 
+	// Check the data against the shapes that apply to it
+	if err := r.validateShapes(data, "application/ld+json"); err != nil {
+		r.AddError(err)
+		return r
+	}
+
 	// Initialize entity with ID extracted from the resource content if not already set
 	if r.ID() == "" {
 		r.Entity = domain.NewEntity(resourceID)
@@ -125,12 +166,18 @@ func (r *BasicResource) FromTurtle(data string) Resource {
 	}
 
 	// Use the RDF validation service to validate and extract the resource ID
-	resourceID, err := r.rdfValidator.ValidateTurtle(data)
+	resourceID, err := r.parser().ValidateTurtle(data)
 	if err != nil {
 		r.AddError(fmt.Errorf("Turtle validation failed: %w", err))
 		return r
 	}
 
+	// Check the data against the shapes that apply to it
+	if err := r.validateShapes(data, "text/turtle"); err != nil {
+		r.AddError(err)
+		return r
+	}
+
 	// Initialize entity with ID extracted from the resource content if not already set
 	if r.ID() == "" {
 		r.Entity = domain.NewEntity(resourceID)
@@ -159,12 +206,18 @@ func (r *BasicResource) FromRDFXML(data string) Resource {
 	}
 
 	// Use the RDF validation service to validate and extract the resource ID
-	resourceID, err := r.rdfValidator.ValidateRDFXML(data)
+	resourceID, err := r.parser().ValidateRDFXML(data)
 	if err != nil {
 		r.AddError(fmt.Errorf("RDF/XML validation failed: %w", err))
 		return r
 	}
 
+	// Check the data against the shapes that apply to it
+	if err := r.validateShapes(data, "application/rdf+xml"); err != nil {
+		r.AddError(err)
+		return r
+	}
+
 	// Initialize entity with ID extracted from the resource content if not already set
 	if r.ID() == "" {
 		r.Entity = domain.NewEntity(resourceID)
@@ -180,6 +233,44 @@ func (r *BasicResource) FromRDFXML(data string) Resource {
 	return r
 }
 
+// FromContent creates a non-RDF resource from bytes already in the blob
+// store. The content names no subject, so the resource is identified by its
+// base URI.
+func (r *BasicResource) FromContent(content Content, contentType string) Resource {
+	if content.Key == "" {
+		r.AddError(errors.New("empty content key"))
+		return r
+	}
+
+	// Don't process if there are already errors
+	if r.hasErrorsInChain() {
+		return r
+	}
+
+	// Content has no triples, so it only conforms to shapes that require none
+	if err := r.validateShapes("", string(service.FormatTurtle)); err != nil {
+		r.AddError(err)
+		return r
+	}
+
+	if r.ID() == "" {
+		if r.base == "" {
+			r.AddError(errors.New("non-RDF content needs a base URI to identify it"))
+			return r
+		}
+		r.Entity = domain.NewEntity(r.base)
+	}
+
+	// Create and add the event
+	storedEvent := event.NewResourceContentStoredEvent(r.ID(), content.Key, content.Size, content.Digest, contentType)
+	r.AddEvent(storedEvent)
+
+	// Apply the event to update state
+	r.applyResourceContentStoredEvent(storedEvent)
+
+	return r
+}
+
 // WithURI assigns a URI to the resource
 func (r *BasicResource) WithURI(uri string) Resource {
 	if uri == "" {
@@ -202,7 +293,51 @@ func (r *BasicResource) WithURI(uri string) Resource {
 	return r
 }
 
-// Update updates the resource with new data
+// WithBase sets the IRI that relative IRIs in the data resolve against
+// until a URI is assigned; the data is stored as given, so relative IRIs
+// always resolve against the resource's current URI. It must be called
+// before From*.
+func (r *BasicResource) WithBase(base string) Resource {
+	r.base = base
+	return r
+}
+
+// WithShapes sets the SHACL shapes that data must conform to when the
+// resource is created or updated. It must be called before From* or Update.
+func (r *BasicResource) WithShapes(shapes *rdf.Graph) Resource {
+	r.shapes = shapes
+	return r
+}
+
+// WithShEx sets the ShEx schema and shape map that data must conform to
+// when the resource is created or updated. It must be called before From*
+// or Update.
+func (r *BasicResource) WithShEx(schema *service.ShExSchema, shapeMap service.ShapeMap) Resource {
+	r.shexSchema = schema
+	r.shapeMap = shapeMap
+	return r
+}
+
+// WithAuthor sets the agent recorded on the events the resource emits from
+// now on
+func (r *BasicResource) WithAuthor(author string) Resource {
+	r.author = author
+	return r
+}
+
+// AddEvent records an event, stamping it with the resource's author
+func (r *BasicResource) AddEvent(e domain.Event) {
+	if authored, ok := e.(event.Authored); ok && r.author != "" {
+		authored.SetAuthor(r.author)
+	}
+	r.Entity.AddEvent(e)
+}
+
+// Update updates the resource with new data. Data that describes the same
+// graph as the current data is a no-op and records no event. The event keeps
+// the new document as submitted; RDF changes also record the triples deleted
+// and inserted, while other content, and graphs with blank nodes, which
+// cannot be matched triple by triple, record the document they replace.
 func (r *BasicResource) Update(data string, contentType string) Resource {
 	if r.hasErrorsInChain() {
 		return r // Don't process if there are already errors
@@ -213,11 +348,25 @@ func (r *BasicResource) Update(data string, contentType string) Resource {
 		return r
 	}
 
-	// Store previous data for the event
-	previousData := r.data
+	// Skip updates that describe the same graph as the current data
+	if r.isSameGraph(data, contentType) {
+		return r
+	}
+
+	// Check the data against the shapes that apply to it
+	if err := r.validateShapes(data, contentType); err != nil {
+		r.AddError(err)
+		return r
+	}
 
-	// Create and add the event
-	updateEvent := event.NewResourceUpdatedEvent(r.ID(), previousData, data, contentType)
+	// RDF changes also record the triples deleted and inserted, in place of
+	// the previous document
+	var updateEvent *event.ResourceUpdatedEvent
+	if inserts, deletes, ok := r.delta(data, contentType); ok {
+		updateEvent = event.NewResourceUpdatedEventWithDelta(r.ID(), data, contentType, inserts, deletes)
+	} else {
+		updateEvent = event.NewResourceUpdatedEvent(r.ID(), r.data, data, contentType)
+	}
 	r.AddEvent(updateEvent)
 
 	// Apply the event to update state
@@ -226,12 +375,52 @@ func (r *BasicResource) Update(data string, contentType string) Resource {
 	return r
 }
 
-// Delete marks the resource as deleted
+// UpdateContent replaces the data or content of the resource with bytes
+// already in the blob store. The previous bytes are left in place so past
+// versions can still be read.
+func (r *BasicResource) UpdateContent(content Content, contentType string) Resource {
+	if r.hasErrorsInChain() {
+		return r // Don't process if there are already errors
+	}
+
+	if content.Key == "" {
+		r.AddError(errors.New("empty content key"))
+		return r
+	}
+
+	// Skip updates that store the same bytes again
+	if content == r.content && contentType == r.contentType {
+		return r
+	}
+
+	// Content has no triples, so it only conforms to shapes that require none
+	if err := r.validateShapes("", string(service.FormatTurtle)); err != nil {
+		r.AddError(err)
+		return r
+	}
+
+	// Create and add the event
+	storedEvent := event.NewResourceContentStoredEvent(r.ID(), content.Key, content.Size, content.Digest, contentType)
+	r.AddEvent(storedEvent)
+
+	// Apply the event to update state
+	r.applyResourceContentStoredEvent(storedEvent)
+
+	return r
+}
+
+// Delete turns the resource into a tombstone. Its data and history are kept
+// so it can be restored.
 func (r *BasicResource) Delete() Resource {
 	if r.hasErrorsInChain() {
 		return r // Don't process if there are already errors
 	}
 
+	if r.IsDeleted() {
+		r.AddError(errors.New("resource is already deleted"))
+		return r
+	}
+
 	// Create and add the event
 	deleteEvent := event.NewResourceDeletedEvent(r.ID(), r.uri)
 	r.AddEvent(deleteEvent)
@@ -242,22 +431,84 @@ func (r *BasicResource) Delete() Resource {
 	return r
 }
 
+// Restore brings a deleted resource back with the data it had when deleted
+func (r *BasicResource) Restore() Resource {
+	if r.hasErrorsInChain() {
+		return r // Don't process if there are already errors
+	}
+
+	if !r.IsDeleted() {
+		r.AddError(errors.New("resource is not deleted"))
+		return r
+	}
+
+	// Create and add the event
+	restoreEvent := event.NewResourceRestoredEvent(r.ID(), r.uri)
+	r.AddEvent(restoreEvent)
+
+	// Apply the event to update state
+	r.applyResourceRestoredEvent(restoreEvent)
+
+	return r
+}
+
+// IsDeleted reports whether the resource is a tombstone
+func (r *BasicResource) IsDeleted() bool {
+	return !r.deletedAt.IsZero()
+}
+
+// GetDeletedAt returns when the resource was deleted, or the zero time
+func (r *BasicResource) GetDeletedAt() time.Time {
+	return r.deletedAt
+}
+
+// GetURI returns the URI assigned to the resource
+func (r *BasicResource) GetURI() string {
+	return r.uri
+}
+
+// GetData returns the current RDF representation of the resource
+func (r *BasicResource) GetData() string {
+	return r.data
+}
+
 // GetContentType returns the content type of the resource
 func (r *BasicResource) GetContentType() string {
 	return r.contentType
 }
 
+// GetContent returns where the bytes of a non-RDF resource are stored, or
+// the zero Content for RDF resources
+func (r *BasicResource) GetContent() Content {
+	return r.content
+}
+
+// IsNonRDF reports whether the resource holds bytes in the blob store
+// rather than RDF data
+func (r *BasicResource) IsNonRDF() bool {
+	return r.content.Key != ""
+}
+
 // GetLastModified returns the last modified time
 func (r *BasicResource) GetLastModified() time.Time {
 	return r.lastModified
 }
 
-// GetETag returns the entity tag for caching
+// GetETag returns the entity tag for caching. It is a weak validator derived
+// from the canonical form of the graph, so every serialization of the same
+// graph shares it; data that cannot be canonicalized falls back to a
+// digest of the content and last modified time. Non-RDF resources have a
+// strong validator, as each upload is stored under a new key.
 func (r *BasicResource) GetETag() string {
 	if r.etag == "" {
-		// Generate ETag based on content and last modified time
-		content := fmt.Sprintf("%s-%d", r.data, r.lastModified.Unix())
-		r.etag = fmt.Sprintf(`"%x"`, md5.Sum([]byte(content)))
+		if r.IsNonRDF() {
+			r.etag = fmt.Sprintf(`"%s"`, r.content.Key)
+		} else if hash, err := r.canonicalizer().Hash(r.data, r.contentType); err == nil && r.data != "" {
+			r.etag = fmt.Sprintf(`W/"%s"`, hash)
+		} else {
+			content := fmt.Sprintf("%s-%d", r.data, r.lastModified.Unix())
+			r.etag = fmt.Sprintf(`"%x"`, md5.Sum([]byte(content)))
+		}
 	}
 	return r.etag
 }
@@ -283,6 +534,115 @@ func (r *BasicResource) hasErrorsInChain() bool {
 	return r.HasErrors()
 }
 
+// parser returns the RDF service resolving relative IRIs against the
+// resource's URI, or against its base before a URI is assigned
+func (r *BasicResource) parser() service.RDFValidationService {
+	base := r.uri
+	if base == "" {
+		base = r.base
+	}
+	if base == "" {
+		return r.rdfValidator
+	}
+	return r.rdfValidator.WithBase(base)
+}
+
+// canonicalizer returns the canonicalization service for the resource's data
+func (r *BasicResource) canonicalizer() service.CanonicalizationService {
+	return service.NewStandardCanonicalizationService(r.parser())
+}
+
+// isSameGraph reports whether data is isomorphic to the current data. Data
+// that cannot be parsed is never considered the same, and documents without
+// triples are only the same when their text is.
+func (r *BasicResource) isSameGraph(data string, contentType string) bool {
+	if r.data == "" {
+		return false
+	}
+	same, err := r.canonicalizer().Isomorphic(r.data, r.contentType, data, contentType)
+	if err != nil || !same {
+		return false
+	}
+	if graph, err := r.parser().ParseGraph(data, contentType); err != nil || graph.Len() == 0 {
+		return data == r.data && contentType == r.contentType
+	}
+	return true
+}
+
+// delta returns the triples to insert and delete to turn the current graph
+// into the graph of data. ok is false when either document is not RDF or
+// has blank nodes.
+func (r *BasicResource) delta(data string, contentType string) (inserts, deletes []rdf.Triple, ok bool) {
+	if r.data == "" {
+		return nil, nil, false
+	}
+	current, err := r.parser().ParseGraph(r.data, r.contentType)
+	if err != nil || hasBlankNodes(current) {
+		return nil, nil, false
+	}
+	next, err := r.parser().ParseGraph(data, contentType)
+	if err != nil || hasBlankNodes(next) {
+		return nil, nil, false
+	}
+
+	for _, t := range current.SortedTriples() {
+		if !next.Contains(t) {
+			deletes = append(deletes, t)
+		}
+	}
+	for _, t := range next.SortedTriples() {
+		if !current.Contains(t) {
+			inserts = append(inserts, t)
+		}
+	}
+	return inserts, deletes, true
+}
+
+// hasBlankNodes reports whether any triple of graph has a blank node
+func hasBlankNodes(graph *rdf.Graph) bool {
+	for _, t := range graph.Triples() {
+		if t.Subject.Kind() == rdf.KindBlankNode || t.Object.Kind() == rdf.KindBlankNode {
+			return true
+		}
+	}
+	return false
+}
+
+// validateShapes checks data against the resource's shapes and returns a
+// *service.ShapeValidationError carrying the report when it does not conform
+func (r *BasicResource) validateShapes(data string, contentType string) error {
+	hasShapes := r.shapes != nil && r.shapes.Len() > 0
+	hasShEx := r.shexSchema != nil && len(r.shapeMap) > 0
+	if !hasShapes && !hasShEx {
+		return nil
+	}
+
+	graph, err := r.parser().ParseGraph(data, contentType)
+	if err != nil {
+		return fmt.Errorf("shape validation failed: %w", err)
+	}
+
+	report := &service.ValidationReport{Conforms: true}
+	if hasShapes {
+		if report, err = r.shapeValidator.Validate(graph, r.shapes); err != nil {
+			return fmt.Errorf("shape validation failed: %w", err)
+		}
+	}
+	if hasShEx {
+		shexReport, err := r.shexValidator.Validate(graph, r.shexSchema, r.shapeMap)
+		if err != nil {
+			return fmt.Errorf("shape validation failed: %w", err)
+		}
+		report.Conforms = report.Conforms && shexReport.Conforms
+		report.Results = append(report.Results, shexReport.Results...)
+	}
+
+	if !report.Conforms {
+		return &service.ShapeValidationError{Report: report}
+	}
+	return nil
+}
+
 // Universal event application interface for all resource created events
 type ResourceCreatedEventInterface interface {
 	Data() string
@@ -292,6 +652,7 @@ type ResourceCreatedEventInterface interface {
 
 func (r *BasicResource) applyResourceCreatedEvent(event ResourceCreatedEventInterface) {
 	r.data = event.Data()
+	r.content = Content{}
 	r.contentType = event.ContentType()
 	r.lastModified = event.OccurredAt()
 	r.etag = "" // Reset ETag so it will be recalculated
@@ -305,12 +666,28 @@ func (r *BasicResource) applyResourceURIAssignedEvent(event *event.ResourceURIAs
 
 func (r *BasicResource) applyResourceUpdatedEvent(event *event.ResourceUpdatedEvent) {
 	r.data = event.NewData()
+	r.content = Content{}
+	r.contentType = event.ContentType()
+	r.lastModified = event.OccurredAt()
+	r.etag = "" // Reset ETag so it will be recalculated
+}
+
+func (r *BasicResource) applyResourceContentStoredEvent(event *event.ResourceContentStoredEvent) {
+	r.data = ""
+	r.content = Content{Key: event.Key(), Size: event.Size(), Digest: event.Digest()}
 	r.contentType = event.ContentType()
 	r.lastModified = event.OccurredAt()
 	r.etag = "" // Reset ETag so it will be recalculated
 }
 
 func (r *BasicResource) applyResourceDeletedEvent(event *event.ResourceDeletedEvent) {
+	r.deletedAt = event.OccurredAt()
+	r.lastModified = event.OccurredAt()
+	r.etag = "" // Reset ETag so it will be recalculated
+}
+
+func (r *BasicResource) applyResourceRestoredEvent(event *event.ResourceRestoredEvent) {
+	r.deletedAt = time.Time{}
 	r.lastModified = event.OccurredAt()
 	r.etag = "" // Reset ETag so it will be recalculated
 }
@@ -321,6 +698,12 @@ func (r *BasicResource) LoadFromHistory(events []domain.Event) {
 	if r.rdfValidator == nil {
 		r.rdfValidator = service.NewStandardRDFValidationService()
 	}
+	if r.shapeValidator == nil {
+		r.shapeValidator = service.NewStandardSHACLValidationService()
+	}
+	if r.shexValidator == nil {
+		r.shexValidator = service.NewStandardShExValidationService()
+	}
 
 	for _, evt := range events {
 		switch e := evt.(type) {
@@ -332,6 +715,10 @@ func (r *BasicResource) LoadFromHistory(events []domain.Event) {
 			r.applyResourceUpdatedEvent(e)
 		case *event.ResourceDeletedEvent:
 			r.applyResourceDeletedEvent(e)
+		case *event.ResourceRestoredEvent:
+			r.applyResourceRestoredEvent(e)
+		case *event.ResourceContentStoredEvent:
+			r.applyResourceContentStoredEvent(e)
 		}
 	}
 	// Call base implementation to update version and sequence
@@ -1,12 +1,16 @@
 package entity_test
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wepala/vine-pod/internal/domain/entity"
 	"github.com/wepala/vine-pod/internal/domain/event"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/service"
 )
 
 func TestResource_FromJSONLD(t *testing.T) {
@@ -191,6 +195,60 @@ func TestResource_FromRDFXML(t *testing.T) {
 	})
 }
 
+func TestResource_FromContent(t *testing.T) {
+	t.Run("creates a non-RDF resource identified by its base", func(t *testing.T) {
+		// Arrange
+		uri := "https://example.com/photos/cat.png"
+
+		// Act
+		resource := entity.NewBasicResource().
+			WithBase(uri).
+			FromContent(entity.Content{Key: "0a1b", Size: 42}, "image/png").
+			WithURI(uri)
+
+		// Assert
+		require.False(t, resource.HasErrors())
+		assert.Equal(t, uri, resource.ID())
+		assert.True(t, resource.IsNonRDF())
+		assert.Equal(t, entity.Content{Key: "0a1b", Size: 42}, resource.GetContent())
+		assert.Equal(t, "image/png", resource.GetContentType())
+		assert.Empty(t, resource.GetData())
+		assert.Equal(t, `"0a1b"`, resource.GetETag())
+	})
+
+	t.Run("replays content replaced by RDF data", func(t *testing.T) {
+		// Arrange
+		uri := "https://example.com/notes/a"
+		resource := entity.NewBasicResource().
+			WithBase(uri).
+			FromContent(entity.Content{Key: "0a1b", Size: 5}, "text/plain").
+			WithURI(uri).
+			UpdateContent(entity.Content{Key: "2c3d", Size: 6, Digest: "9f86d081"}, "text/plain").
+			Update(`<> <http://purl.org/dc/terms/title> "A" .`, "text/turtle")
+		require.False(t, resource.HasErrors())
+
+		// Act
+		events := resource.UncommittedEvents()
+		replayed := entity.NewBasicResourceFromHistory(resource.ID(), events[:3], service.NewStandardRDFValidationService())
+		current := entity.NewBasicResourceFromHistory(resource.ID(), events, service.NewStandardRDFValidationService())
+
+		// Assert
+		assert.Equal(t, entity.Content{Key: "2c3d", Size: 6, Digest: "9f86d081"}, replayed.GetContent())
+		assert.False(t, current.IsNonRDF())
+		assert.Equal(t, "text/turtle", current.GetContentType())
+	})
+
+	t.Run("adds error without a content key or base", func(t *testing.T) {
+		// Act
+		withoutKey := entity.NewBasicResource().WithBase("https://example.com/a").FromContent(entity.Content{}, "text/plain")
+		withoutBase := entity.NewBasicResource().FromContent(entity.Content{Key: "0a1b"}, "text/plain")
+
+		// Assert
+		assert.True(t, withoutKey.HasErrors())
+		assert.True(t, withoutBase.HasErrors())
+	})
+}
+
 func TestResource_WithURI(t *testing.T) {
 	t.Run("assigns URI to resource", func(t *testing.T) {
 		// Arrange
@@ -261,6 +319,107 @@ func TestResource_Update(t *testing.T) {
 		assert.Equal(t, contentType, updateEvent.ContentType())
 	})
 
+	t.Run("records the triples each update deletes and inserts", func(t *testing.T) {
+		// Arrange
+		resource := entity.NewBasicResource().
+			FromJSONLD(`{"@id": "https://example.com/resource1", "http://purl.org/dc/terms/title": "Initial Title"}`)
+		resource.MarkEventsAsCommitted()
+		newData := `<https://example.com/resource1> <http://purl.org/dc/terms/title> "Updated Title" .`
+
+		// Act
+		resource.Update(newData, "text/turtle")
+
+		// Assert
+		require.False(t, resource.HasErrors())
+		require.Len(t, resource.UncommittedEvents(), 1)
+		updateEvent, ok := resource.UncommittedEvents()[0].(*event.ResourceUpdatedEvent)
+		require.True(t, ok)
+		title := rdf.IRI("http://purl.org/dc/terms/title")
+		assert.True(t, updateEvent.HasDelta())
+		assert.Equal(t, []rdf.Triple{{Subject: rdf.IRI("https://example.com/resource1"), Predicate: title, Object: rdf.NewLiteral("Updated Title")}}, updateEvent.Inserts())
+		assert.Equal(t, []rdf.Triple{{Subject: rdf.IRI("https://example.com/resource1"), Predicate: title, Object: rdf.NewLiteral("Initial Title")}}, updateEvent.Deletes())
+		assert.Empty(t, updateEvent.PreviousData())
+		assert.Equal(t, newData, updateEvent.NewData())
+		assert.Equal(t, newData, resource.GetData())
+		assert.Equal(t, "text/turtle", resource.GetContentType())
+	})
+
+	t.Run("replays triple-level updates", func(t *testing.T) {
+		// Arrange
+		resource := entity.NewBasicResource().
+			FromTurtle(`<https://example.com/resource1> <http://purl.org/dc/terms/title> "A" .`).
+			WithURI("https://example.com/resource1")
+		resource.Update(`<https://example.com/resource1> <http://purl.org/dc/terms/title> "B" .`, "text/turtle")
+		resource.Update(`<https://example.com/resource1> <http://purl.org/dc/terms/title> "B" ; <http://purl.org/dc/terms/subject> "C" .`, "text/turtle")
+		require.False(t, resource.HasErrors())
+
+		// Act
+		replayed := entity.NewBasicResourceFromHistory(resource.ID(), resource.UncommittedEvents(), service.NewStandardRDFValidationService())
+
+		// Assert
+		assert.False(t, replayed.HasErrors())
+		assert.Equal(t, resource.GetData(), replayed.GetData())
+		assert.Equal(t, resource.GetETag(), replayed.GetETag())
+		assert.Contains(t, replayed.GetData(), `"C"`)
+		assert.NotContains(t, replayed.GetData(), `"A"`)
+	})
+
+	t.Run("records whole documents for non-RDF content and blank nodes", func(t *testing.T) {
+		// Arrange
+		resource := entity.NewBasicResource().
+			FromTurtle(`<https://example.com/resource1> <http://purl.org/dc/terms/title> "A" .`).
+			WithURI("https://example.com/resource1")
+		resource.MarkEventsAsCommitted()
+
+		// Act
+		resource.Update(`<https://example.com/resource1> <http://purl.org/dc/terms/creator> [ <http://xmlns.com/foaf/0.1/name> "Alice" ] .`, "text/turtle")
+		resource.Update("plain text", "text/plain")
+
+		// Assert
+		require.Len(t, resource.UncommittedEvents(), 2)
+		for _, evt := range resource.UncommittedEvents() {
+			updateEvent, ok := evt.(*event.ResourceUpdatedEvent)
+			require.True(t, ok)
+			assert.False(t, updateEvent.HasDelta())
+			assert.NotEmpty(t, updateEvent.PreviousData())
+		}
+		assert.Equal(t, "plain text", resource.GetData())
+	})
+
+	t.Run("records the author of each change", func(t *testing.T) {
+		// Arrange
+		resource := entity.NewBasicResource().
+			WithAuthor("alice@example.com").
+			FromTurtle(`<https://example.com/resource1> <http://purl.org/dc/terms/title> "A" .`)
+
+		// Act
+		resource.WithAuthor("bob@example.com").
+			Update(`<https://example.com/resource1> <http://purl.org/dc/terms/title> "B" .`, "text/turtle")
+
+		// Assert
+		events := resource.UncommittedEvents()
+		require.Len(t, events, 2)
+		assert.Equal(t, "alice@example.com", events[0].(event.Authored).Author())
+		assert.Equal(t, "bob@example.com", events[1].(event.Authored).Author())
+	})
+
+	t.Run("skips updates that describe the same graph", func(t *testing.T) {
+		// Arrange
+		resource := entity.NewBasicResource()
+		resource.FromJSONLD(`{"@id": "https://example.com/resource1", "http://purl.org/dc/terms/title": "Title"}`)
+		resource.MarkEventsAsCommitted() // Simulate persistence
+
+		sameGraph := `<https://example.com/resource1> <http://purl.org/dc/terms/title> "Title" .`
+
+		// Act
+		result := resource.Update(sameGraph, "text/turtle")
+
+		// Assert
+		assert.False(t, result.HasErrors())
+		assert.False(t, result.HasUncommittedEvents())
+		assert.Equal(t, "application/ld+json", result.GetContentType())
+	})
+
 	t.Run("adds error when update data is empty", func(t *testing.T) {
 		// Arrange
 		resource := entity.NewBasicResource()
@@ -301,6 +460,33 @@ func TestResource_Delete(t *testing.T) {
 		deleteEvent, ok := events[0].(*event.ResourceDeletedEvent)
 		assert.True(t, ok)
 		assert.Equal(t, "resource.deleted", deleteEvent.EventType())
+		assert.True(t, result.IsDeleted())
+		assert.Equal(t, deleteEvent.OccurredAt(), result.GetDeletedAt())
+	})
+
+	t.Run("restores a tombstone with its data", func(t *testing.T) {
+		// Arrange
+		resource := entity.NewBasicResource().
+			FromTurtle(`<https://example.com/resource1> <http://purl.org/dc/terms/title> "A" .`).
+			WithURI("https://example.com/resource1").
+			Delete()
+		history := resource.UncommittedEvents()
+
+		// Act
+		resource.Restore()
+		replayed := entity.NewBasicResourceFromHistory(resource.ID(), resource.UncommittedEvents(), nil)
+
+		// Assert
+		assert.False(t, resource.HasErrors())
+		assert.True(t, entity.NewBasicResourceFromHistory(resource.ID(), history, nil).IsDeleted())
+		assert.False(t, replayed.IsDeleted())
+		assert.Equal(t, resource.GetData(), replayed.GetData())
+		assert.IsType(t, &event.ResourceRestoredEvent{}, resource.UncommittedEvents()[3])
+	})
+
+	t.Run("adds error when deleting a tombstone or restoring a live resource", func(t *testing.T) {
+		assert.True(t, entity.NewBasicResource().Restore().HasErrors())
+		assert.True(t, entity.NewBasicResource().Delete().Delete().HasErrors())
 	})
 }
 
@@ -343,6 +529,139 @@ func TestResource_Metadata(t *testing.T) {
 		// Assert
 		assert.NotEmpty(t, etag)
 	})
+
+	t.Run("GetETag is the same for equivalent serializations", func(t *testing.T) {
+		// Arrange
+		fromJSONLD := entity.NewBasicResource().
+			FromJSONLD(`{"@id": "https://example.com/resource1", "http://purl.org/dc/terms/title": "Title"}`)
+		fromTurtle := entity.NewBasicResource().
+			FromTurtle(`<https://example.com/resource1> <http://purl.org/dc/terms/title> "Title" .`)
+
+		// Act
+		jsonLDTag := fromJSONLD.GetETag()
+		turtleTag := fromTurtle.GetETag()
+
+		// Assert
+		assert.Equal(t, jsonLDTag, turtleTag)
+		assert.True(t, strings.HasPrefix(jsonLDTag, `W/"`))
+	})
+}
+
+func TestResource_WithShapes(t *testing.T) {
+	shapesTurtle := `@prefix sh: <http://www.w3.org/ns/shacl#> .
+@prefix dcterms: <http://purl.org/dc/terms/> .
+<https://example.com/shapes#NoteShape> sh:targetSubjectsOf dcterms:created ;
+    sh:property [ sh:path dcterms:title ; sh:minCount 1 ] .`
+	shapes, err := service.NewStandardRDFValidationService().ParseGraph(shapesTurtle, string(service.FormatTurtle))
+	require.NoError(t, err)
+
+	t.Run("accepts data that conforms to the shapes", func(t *testing.T) {
+		// Act
+		resource := entity.NewBasicResource().
+			WithShapes(shapes).
+			FromTurtle(`<https://example.com/note> <http://purl.org/dc/terms/created> "2024-01-01" ; <http://purl.org/dc/terms/title> "Note" .`)
+
+		// Assert
+		assert.False(t, resource.HasErrors())
+		assert.Len(t, resource.UncommittedEvents(), 1)
+	})
+
+	t.Run("rejects data that violates the shapes with a report", func(t *testing.T) {
+		// Act
+		resource := entity.NewBasicResource().
+			WithShapes(shapes).
+			FromTurtle(`<https://example.com/note> <http://purl.org/dc/terms/created> "2024-01-01" .`)
+
+		// Assert
+		require.True(t, resource.HasErrors())
+		var shapeErr *service.ShapeValidationError
+		require.ErrorAs(t, resource.GetErrors()[0], &shapeErr)
+		assert.False(t, shapeErr.Report.Conforms)
+		assert.Empty(t, resource.UncommittedEvents())
+	})
+
+	t.Run("validates updates", func(t *testing.T) {
+		// Arrange
+		resource := entity.NewBasicResource().
+			WithShapes(shapes).
+			FromTurtle(`<https://example.com/note> <http://purl.org/dc/terms/created> "2024-01-01" ; <http://purl.org/dc/terms/title> "Note" .`)
+		resource.MarkEventsAsCommitted()
+
+		// Act
+		resource.Update(`<https://example.com/note> <http://purl.org/dc/terms/created> "2024-01-02" .`, "text/turtle")
+
+		// Assert
+		assert.True(t, resource.HasErrors())
+		assert.Empty(t, resource.UncommittedEvents())
+		assert.Contains(t, resource.GetData(), `"Note"`)
+	})
+}
+
+func TestResource_WithShEx(t *testing.T) {
+	schema, err := service.ParseShExC(`PREFIX dcterms: <http://purl.org/dc/terms/>
+<https://example.com/shapes#Note> { dcterms:title . ; dcterms:created . ? }`, "")
+	require.NoError(t, err)
+	shapeMap := service.ShapeMap{{Node: rdf.IRI("https://example.com/note"), Shape: "https://example.com/shapes#Note"}}
+
+	t.Run("accepts data that conforms to the schema", func(t *testing.T) {
+		// Act
+		resource := entity.NewBasicResource().
+			WithShEx(schema, shapeMap).
+			FromTurtle(`<https://example.com/note> <http://purl.org/dc/terms/title> "Note" .`)
+
+		// Assert
+		assert.False(t, resource.HasErrors())
+	})
+
+	t.Run("rejects data that violates the schema with a report", func(t *testing.T) {
+		// Act
+		resource := entity.NewBasicResource().
+			WithShEx(schema, shapeMap).
+			FromTurtle(`<https://example.com/note> <http://purl.org/dc/terms/created> "2024-01-01" .`)
+
+		// Assert
+		require.True(t, resource.HasErrors())
+		var shapeErr *service.ShapeValidationError
+		require.ErrorAs(t, resource.GetErrors()[0], &shapeErr)
+		assert.Equal(t, rdf.IRI("https://example.com/note"), shapeErr.Report.Results[0].FocusNode)
+	})
+}
+
+func TestResource_WithBase(t *testing.T) {
+	t.Run("resolves relative IRIs against the base and keeps them relative", func(t *testing.T) {
+		// Act
+		resource := entity.NewBasicResource().
+			WithBase("https://example.com/notes/a").
+			FromTurtle(`<> <http://purl.org/dc/terms/title> "A" .`).
+			WithURI("https://example.com/notes/a")
+
+		// Assert
+		require.False(t, resource.HasErrors())
+		assert.Equal(t, "https://example.com/notes/a", resource.ID())
+		assert.Equal(t, `<> <http://purl.org/dc/terms/title> "A" .`, resource.GetData())
+	})
+
+	t.Run("resolves relative IRIs against the current URI after a move", func(t *testing.T) {
+		// Arrange
+		shapes, err := service.NewStandardRDFValidationService().ParseGraph(`@prefix sh: <http://www.w3.org/ns/shacl#> .
+<https://example.com/shapes#Note> sh:targetNode <https://example.com/archive/a> ;
+    sh:property [ sh:path <http://purl.org/dc/terms/title> ; sh:maxCount 1 ] .`, "text/turtle")
+		require.NoError(t, err)
+		resource := entity.NewBasicResource().
+			WithBase("https://example.com/notes/a").
+			FromTurtle(`<> <http://purl.org/dc/terms/title> "A" .`).
+			WithURI("https://example.com/notes/a").
+			WithURI("https://example.com/archive/a")
+
+		// Act
+		resource.WithShapes(shapes).Update(`<> <http://purl.org/dc/terms/title> "A", "B" .`, "text/turtle")
+
+		// Assert
+		require.True(t, resource.HasErrors())
+		var shapeErr *service.ShapeValidationError
+		require.ErrorAs(t, resource.GetErrors()[0], &shapeErr)
+		assert.Equal(t, rdf.IRI("https://example.com/archive/a"), shapeErr.Report.Results[0].FocusNode)
+	})
 }
 
 func TestResource_ChainedOperations(t *testing.T) {
@@ -0,0 +1,154 @@
+package event
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// Record is the serializable form of a resource event, for stores that keep
+// events outside memory. Only the fields of its type are set.
+type Record struct {
+	Type        string    `json:"type"`
+	AggregateID string    `json:"aggregateId"`
+	Version     int       `json:"version"`
+	OccurredAt  time.Time `json:"occurredAt"`
+	Author      string    `json:"author,omitempty"`
+
+	URI          string `json:"uri,omitempty"`
+	Data         string `json:"data,omitempty"`
+	PreviousData string `json:"previousData,omitempty"`
+	ContentType  string `json:"contentType,omitempty"`
+	ExtractedID  string `json:"extractedId,omitempty"`
+	Key          string `json:"key,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+	Digest       string `json:"digest,omitempty"`
+
+	Delta   bool           `json:"delta,omitempty"`
+	Inserts []TripleRecord `json:"inserts,omitempty"`
+	Deletes []TripleRecord `json:"deletes,omitempty"`
+}
+
+// TripleRecord is the serializable form of a triple
+type TripleRecord [3]TermRecord
+
+// TermRecord is the serializable form of an RDF term
+type TermRecord struct {
+	Kind     string `json:"kind"` // "iri", "blank" or "literal"
+	Value    string `json:"value"`
+	Datatype string `json:"datatype,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// NewRecord returns the record of a resource event
+func NewRecord(evt domain.Event) (Record, error) {
+	record := Record{
+		Type:        evt.EventType(),
+		AggregateID: evt.AggregateID(),
+		Version:     evt.Version(),
+		OccurredAt:  evt.OccurredAt(),
+	}
+	if authored, ok := evt.(Authored); ok {
+		record.Author = authored.Author()
+	}
+
+	switch e := evt.(type) {
+	case *ResourceCreatedEvent:
+		record.Data, record.ContentType, record.ExtractedID = e.data, e.contentType, e.extractedID
+	case *ResourceURIAssignedEvent:
+		record.URI = e.uri
+	case *ResourceUpdatedEvent:
+		record.PreviousData, record.Data, record.ContentType = e.previousData, e.newData, e.contentType
+		if e.hasDelta {
+			record.Delta, record.Inserts, record.Deletes = true, newTripleRecords(e.inserts), newTripleRecords(e.deletes)
+		}
+	case *ResourceDeletedEvent:
+		record.URI = e.uri
+	case *ResourceRestoredEvent:
+		record.URI = e.uri
+	case *ResourceContentStoredEvent:
+		record.Key, record.Size, record.Digest, record.ContentType = e.key, e.size, e.digest, e.contentType
+	default:
+		return Record{}, fmt.Errorf("cannot record %s events", evt.EventType())
+	}
+	return record, nil
+}
+
+// Event returns the resource event the record was made from
+func (r Record) Event() (domain.Event, error) {
+	var evt domain.Event
+	switch r.Type {
+	case "resource.created":
+		evt = &ResourceCreatedEvent{resourceID: r.AggregateID, data: r.Data, contentType: r.ContentType, extractedID: r.ExtractedID,
+			occurredAt: r.OccurredAt, version: r.Version, author: r.Author}
+	case "resource.uri_assigned":
+		evt = &ResourceURIAssignedEvent{resourceID: r.AggregateID, uri: r.URI, occurredAt: r.OccurredAt, version: r.Version, author: r.Author}
+	case "resource.updated":
+		updated := &ResourceUpdatedEvent{resourceID: r.AggregateID, previousData: r.PreviousData, newData: r.Data, contentType: r.ContentType,
+			hasDelta: r.Delta, occurredAt: r.OccurredAt, version: r.Version, author: r.Author}
+		if r.Delta {
+			var err error
+			if updated.inserts, err = tripleRecordsToTriples(r.Inserts); err != nil {
+				return nil, err
+			}
+			if updated.deletes, err = tripleRecordsToTriples(r.Deletes); err != nil {
+				return nil, err
+			}
+		}
+		evt = updated
+	case "resource.deleted":
+		evt = &ResourceDeletedEvent{resourceID: r.AggregateID, uri: r.URI, occurredAt: r.OccurredAt, version: r.Version, author: r.Author}
+	case "resource.restored":
+		evt = &ResourceRestoredEvent{resourceID: r.AggregateID, uri: r.URI, occurredAt: r.OccurredAt, version: r.Version, author: r.Author}
+	case "resource.content_stored":
+		evt = &ResourceContentStoredEvent{resourceID: r.AggregateID, key: r.Key, size: r.Size, digest: r.Digest, contentType: r.ContentType,
+			occurredAt: r.OccurredAt, version: r.Version, author: r.Author}
+	default:
+		return nil, fmt.Errorf("unknown event type %q", r.Type)
+	}
+	return evt, nil
+}
+
+// newTripleRecords returns the records of triples
+func newTripleRecords(triples []rdf.Triple) []TripleRecord {
+	records := make([]TripleRecord, 0, len(triples))
+	for _, triple := range triples {
+		records = append(records, TripleRecord{newTermRecord(triple.Subject), newTermRecord(triple.Predicate), newTermRecord(triple.Object)})
+	}
+	return records
+}
+
+// newTermRecord returns the record of an RDF term
+func newTermRecord(term rdf.Term) TermRecord {
+	switch t := term.(type) {
+	case rdf.BlankNode:
+		return TermRecord{Kind: "blank", Value: t.Value()}
+	case rdf.Literal:
+		return TermRecord{Kind: "literal", Value: t.Lexical, Datatype: string(t.Datatype), Language: t.Language}
+	}
+	return TermRecord{Kind: "iri", Value: term.Value()}
+}
+
+// tripleRecordsToTriples returns the triples of records
+func tripleRecordsToTriples(records []TripleRecord) ([]rdf.Triple, error) {
+	triples := make([]rdf.Triple, 0, len(records))
+	for _, record := range records {
+		var terms [3]rdf.Term
+		for i, term := range record {
+			switch term.Kind {
+			case "iri":
+				terms[i] = rdf.IRI(term.Value)
+			case "blank":
+				terms[i] = rdf.BlankNode(term.Value)
+			case "literal":
+				terms[i] = rdf.Literal{Lexical: term.Value, Datatype: rdf.IRI(term.Datatype), Language: term.Language}
+			default:
+				return nil, fmt.Errorf("unknown term kind %q", term.Kind)
+			}
+		}
+		triples = append(triples, rdf.Triple{Subject: terms[0], Predicate: terms[1], Object: terms[2]})
+	}
+	return triples, nil
+}
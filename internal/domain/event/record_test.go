@@ -0,0 +1,83 @@
+package event_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
+	"github.com/wepala/vine-pod/internal/domain/event"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+func TestRecord(t *testing.T) {
+	roundTrip := func(t *testing.T, evt domain.Event) domain.Event {
+		record, err := event.NewRecord(evt)
+		require.NoError(t, err)
+		data, err := json.Marshal(record)
+		require.NoError(t, err)
+		var decoded event.Record
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		restored, err := decoded.Event()
+		require.NoError(t, err)
+		return restored
+	}
+
+	t.Run("restores events with their version, time and author", func(t *testing.T) {
+		// Arrange
+		created := event.NewResourceCreatedEvent("https://pod.example.com/notes", "<> a <#Note> .", "text/turtle", "https://pod.example.com/notes")
+		created.SetVersion(3)
+		created.SetAuthor("https://alice.example/#me")
+
+		// Act
+		restored := roundTrip(t, created)
+
+		// Assert
+		require.IsType(t, &event.ResourceCreatedEvent{}, restored)
+		assert.Equal(t, created.Data(), restored.(*event.ResourceCreatedEvent).Data())
+		assert.Equal(t, created.ContentType(), restored.(*event.ResourceCreatedEvent).ContentType())
+		assert.Equal(t, 3, restored.Version())
+		assert.True(t, created.OccurredAt().Equal(restored.OccurredAt()))
+		assert.Equal(t, "https://alice.example/#me", restored.(event.Authored).Author())
+	})
+
+	t.Run("restores the triples of updates", func(t *testing.T) {
+		// Arrange
+		inserts := []rdf.Triple{{Subject: rdf.BlankNode("b0"), Predicate: rdf.RDFType, Object: rdf.NewLangLiteral("note", "en")}}
+		deletes := []rdf.Triple{{Subject: rdf.IRI("https://pod.example.com/notes"), Predicate: rdf.IRI("http://purl.org/dc/terms/title"), Object: rdf.NewLiteral("Notes")}}
+
+		// Act
+		restored := roundTrip(t, event.NewResourceUpdatedEventWithDelta("https://pod.example.com/notes", "<> a \"note\"@en .", "text/turtle", inserts, deletes))
+
+		// Assert
+		require.IsType(t, &event.ResourceUpdatedEvent{}, restored)
+		updated := restored.(*event.ResourceUpdatedEvent)
+		assert.True(t, updated.HasDelta())
+		assert.Equal(t, inserts, updated.Inserts())
+		assert.Equal(t, deletes, updated.Deletes())
+		assert.Equal(t, "<> a \"note\"@en .", updated.NewData())
+		assert.Equal(t, "text/turtle", updated.ContentType())
+	})
+
+	t.Run("restores stored content", func(t *testing.T) {
+		// Act
+		restored := roundTrip(t, event.NewResourceContentStoredEvent("https://pod.example.com/cat.png", "0a1b2c", 42, "9f86d081", "image/png"))
+
+		// Assert
+		require.IsType(t, &event.ResourceContentStoredEvent{}, restored)
+		stored := restored.(*event.ResourceContentStoredEvent)
+		assert.Equal(t, "0a1b2c", stored.Key())
+		assert.Equal(t, int64(42), stored.Size())
+		assert.Equal(t, "9f86d081", stored.Digest())
+		assert.Equal(t, "image/png", stored.ContentType())
+	})
+
+	t.Run("rejects unknown event types", func(t *testing.T) {
+		// Act
+		_, err := event.Record{Type: "resource.renamed"}.Event()
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
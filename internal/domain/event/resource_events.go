@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
 )
 
 // ResourceCreatedEvent is emitted when a resource is created from RDF data
@@ -14,6 +15,7 @@ type ResourceCreatedEvent struct {
 	extractedID string
 	occurredAt  time.Time
 	version     int
+	author      string
 }
 
 // NewResourceCreatedEvent creates a new ResourceCreatedEvent
@@ -68,6 +70,16 @@ func (e *ResourceCreatedEvent) SetVersion(version int) {
 	e.version = version
 }
 
+// Author returns the agent that caused this event, or "" when unknown
+func (e *ResourceCreatedEvent) Author() string {
+	return e.author
+}
+
+// SetAuthor records the agent that caused this event
+func (e *ResourceCreatedEvent) SetAuthor(author string) {
+	e.author = author
+}
+
 // Data returns the resource data
 func (e *ResourceCreatedEvent) Data() string {
 	return e.data
@@ -89,6 +101,7 @@ type ResourceURIAssignedEvent struct {
 	uri        string
 	occurredAt time.Time
 	version    int
+	author     string
 }
 
 // NewResourceURIAssignedEvent creates a new ResourceURIAssignedEvent
@@ -126,19 +139,36 @@ func (e *ResourceURIAssignedEvent) SetVersion(version int) {
 	e.version = version
 }
 
+// Author returns the agent that caused this event, or "" when unknown
+func (e *ResourceURIAssignedEvent) Author() string {
+	return e.author
+}
+
+// SetAuthor records the agent that caused this event
+func (e *ResourceURIAssignedEvent) SetAuthor(author string) {
+	e.author = author
+}
+
 // URI returns the assigned URI
 func (e *ResourceURIAssignedEvent) URI() string {
 	return e.uri
 }
 
-// ResourceUpdatedEvent is emitted when a resource is updated
+// ResourceUpdatedEvent is emitted when a resource is updated with a new
+// document. Updates of RDF resources also carry the triples deleted and
+// inserted, and then leave out the previous document, which earlier events
+// already hold.
 type ResourceUpdatedEvent struct {
 	resourceID   string
 	previousData string
 	newData      string
 	contentType  string
+	inserts      []rdf.Triple
+	deletes      []rdf.Triple
+	hasDelta     bool
 	occurredAt   time.Time
 	version      int
+	author       string
 }
 
 // NewResourceUpdatedEvent creates a new ResourceUpdatedEvent
@@ -153,6 +183,21 @@ func NewResourceUpdatedEvent(resourceID, previousData, newData, contentType stri
 	}
 }
 
+// NewResourceUpdatedEventWithDelta creates a new ResourceUpdatedEvent that
+// records the triples the new document deletes and inserts
+func NewResourceUpdatedEventWithDelta(resourceID, newData, contentType string, inserts, deletes []rdf.Triple) *ResourceUpdatedEvent {
+	return &ResourceUpdatedEvent{
+		resourceID:  resourceID,
+		newData:     newData,
+		contentType: contentType,
+		inserts:     inserts,
+		deletes:     deletes,
+		hasDelta:    true,
+		occurredAt:  time.Now(),
+		version:     1,
+	}
+}
+
 // EventType returns the event type identifier
 func (e *ResourceUpdatedEvent) EventType() string {
 	return "resource.updated"
@@ -178,7 +223,17 @@ func (e *ResourceUpdatedEvent) SetVersion(version int) {
 	e.version = version
 }
 
-// PreviousData returns the previous data
+// Author returns the agent that caused this event, or "" when unknown
+func (e *ResourceUpdatedEvent) Author() string {
+	return e.author
+}
+
+// SetAuthor records the agent that caused this event
+func (e *ResourceUpdatedEvent) SetAuthor(author string) {
+	e.author = author
+}
+
+// PreviousData returns the previous data, or "" when the event records a delta
 func (e *ResourceUpdatedEvent) PreviousData() string {
 	return e.previousData
 }
@@ -193,12 +248,28 @@ func (e *ResourceUpdatedEvent) ContentType() string {
 	return e.contentType
 }
 
+// HasDelta reports whether the event records the triples deleted and inserted
+func (e *ResourceUpdatedEvent) HasDelta() bool {
+	return e.hasDelta
+}
+
+// Inserts returns the triples added to the resource
+func (e *ResourceUpdatedEvent) Inserts() []rdf.Triple {
+	return e.inserts
+}
+
+// Deletes returns the triples removed from the resource
+func (e *ResourceUpdatedEvent) Deletes() []rdf.Triple {
+	return e.deletes
+}
+
 // ResourceDeletedEvent is emitted when a resource is deleted
 type ResourceDeletedEvent struct {
 	resourceID string
 	uri        string
 	occurredAt time.Time
 	version    int
+	author     string
 }
 
 // NewResourceDeletedEvent creates a new ResourceDeletedEvent
@@ -236,13 +307,172 @@ func (e *ResourceDeletedEvent) SetVersion(version int) {
 	e.version = version
 }
 
+// Author returns the agent that caused this event, or "" when unknown
+func (e *ResourceDeletedEvent) Author() string {
+	return e.author
+}
+
+// SetAuthor records the agent that caused this event
+func (e *ResourceDeletedEvent) SetAuthor(author string) {
+	e.author = author
+}
+
 // URI returns the URI of the deleted resource
 func (e *ResourceDeletedEvent) URI() string {
 	return e.uri
 }
 
+// ResourceRestoredEvent is emitted when a deleted resource is restored
+type ResourceRestoredEvent struct {
+	resourceID string
+	uri        string
+	occurredAt time.Time
+	version    int
+	author     string
+}
+
+// NewResourceRestoredEvent creates a new ResourceRestoredEvent
+func NewResourceRestoredEvent(resourceID, uri string) *ResourceRestoredEvent {
+	return &ResourceRestoredEvent{
+		resourceID: resourceID,
+		uri:        uri,
+		occurredAt: time.Now(),
+		version:    1,
+	}
+}
+
+// EventType returns the event type identifier
+func (e *ResourceRestoredEvent) EventType() string {
+	return "resource.restored"
+}
+
+// AggregateID returns the ID of the aggregate that generated this event
+func (e *ResourceRestoredEvent) AggregateID() string {
+	return e.resourceID
+}
+
+// Version returns the version of the aggregate when this event occurred
+func (e *ResourceRestoredEvent) Version() int {
+	return e.version
+}
+
+// OccurredAt returns the timestamp when this event occurred
+func (e *ResourceRestoredEvent) OccurredAt() time.Time {
+	return e.occurredAt
+}
+
+// SetVersion sets the event version (called by Entity when adding event)
+func (e *ResourceRestoredEvent) SetVersion(version int) {
+	e.version = version
+}
+
+// Author returns the agent that caused this event, or "" when unknown
+func (e *ResourceRestoredEvent) Author() string {
+	return e.author
+}
+
+// SetAuthor records the agent that caused this event
+func (e *ResourceRestoredEvent) SetAuthor(author string) {
+	e.author = author
+}
+
+// URI returns the URI of the restored resource
+func (e *ResourceRestoredEvent) URI() string {
+	return e.uri
+}
+
+// ResourceContentStoredEvent is emitted when the bytes of a non-RDF resource
+// are stored in the blob store
+type ResourceContentStoredEvent struct {
+	resourceID  string
+	key         string
+	size        int64
+	digest      string
+	contentType string
+	occurredAt  time.Time
+	version     int
+	author      string
+}
+
+// NewResourceContentStoredEvent creates a new ResourceContentStoredEvent
+func NewResourceContentStoredEvent(resourceID, key string, size int64, digest string, contentType string) *ResourceContentStoredEvent {
+	return &ResourceContentStoredEvent{
+		resourceID:  resourceID,
+		key:         key,
+		size:        size,
+		digest:      digest,
+		contentType: contentType,
+		occurredAt:  time.Now(),
+		version:     1,
+	}
+}
+
+// EventType returns the event type identifier
+func (e *ResourceContentStoredEvent) EventType() string {
+	return "resource.content_stored"
+}
+
+// AggregateID returns the ID of the aggregate that generated this event
+func (e *ResourceContentStoredEvent) AggregateID() string {
+	return e.resourceID
+}
+
+// Version returns the version of the aggregate when this event occurred
+func (e *ResourceContentStoredEvent) Version() int {
+	return e.version
+}
+
+// OccurredAt returns the timestamp when this event occurred
+func (e *ResourceContentStoredEvent) OccurredAt() time.Time {
+	return e.occurredAt
+}
+
+// SetVersion sets the event version (called by Entity when adding event)
+func (e *ResourceContentStoredEvent) SetVersion(version int) {
+	e.version = version
+}
+
+// Author returns the agent that caused this event, or "" when unknown
+func (e *ResourceContentStoredEvent) Author() string {
+	return e.author
+}
+
+// SetAuthor records the agent that caused this event
+func (e *ResourceContentStoredEvent) SetAuthor(author string) {
+	e.author = author
+}
+
+// Key returns the blob store key of the stored bytes
+func (e *ResourceContentStoredEvent) Key() string {
+	return e.key
+}
+
+// Size returns the length of the stored bytes
+func (e *ResourceContentStoredEvent) Size() int64 {
+	return e.size
+}
+
+// Digest returns the hex encoded SHA-256 hash of the stored bytes, or ""
+// for bytes stored before blobs were addressed by their hash
+func (e *ResourceContentStoredEvent) Digest() string {
+	return e.digest
+}
+
+// ContentType returns the media type of the stored bytes
+func (e *ResourceContentStoredEvent) ContentType() string {
+	return e.contentType
+}
+
+// Authored is implemented by events that record the agent that caused them
+type Authored interface {
+	Author() string
+	SetAuthor(author string)
+}
+
 // Ensure all events implement the domain.Event interface
 var _ domain.Event = (*ResourceCreatedEvent)(nil)
 var _ domain.Event = (*ResourceURIAssignedEvent)(nil)
 var _ domain.Event = (*ResourceUpdatedEvent)(nil)
 var _ domain.Event = (*ResourceDeletedEvent)(nil)
+var _ domain.Event = (*ResourceRestoredEvent)(nil)
+var _ domain.Event = (*ResourceContentStoredEvent)(nil)
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
 	"github.com/wepala/vine-pod/internal/domain/event"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
 )
 
 func TestResourceCreatedEvent(t *testing.T) {
@@ -176,6 +177,31 @@ func TestResourceUpdatedEvent(t *testing.T) {
 	})
 }
 
+func TestResourceUpdatedEventWithDelta(t *testing.T) {
+	t.Run("NewResourceUpdatedEventWithDelta creates event with correct properties", func(t *testing.T) {
+		// Arrange
+		title := rdf.IRI("http://purl.org/dc/terms/title")
+		inserts := []rdf.Triple{{Subject: rdf.IRI("https://example.com/resource5"), Predicate: title, Object: rdf.NewLiteral("New Title")}}
+		deletes := []rdf.Triple{{Subject: rdf.IRI("https://example.com/resource5"), Predicate: title, Object: rdf.NewLiteral("Old Title")}}
+		newData := `<https://example.com/resource5> <http://purl.org/dc/terms/title> "New Title" .`
+
+		// Act
+		event := event.NewResourceUpdatedEventWithDelta("resource-jkl", newData, "text/turtle", inserts, deletes)
+
+		// Assert
+		assert.Equal(t, "resource.updated", event.EventType())
+		assert.Equal(t, "resource-jkl", event.AggregateID())
+		assert.Equal(t, 1, event.Version())
+		assert.True(t, event.HasDelta())
+		assert.Equal(t, inserts, event.Inserts())
+		assert.Equal(t, deletes, event.Deletes())
+		assert.Equal(t, newData, event.NewData())
+		assert.Equal(t, "text/turtle", event.ContentType())
+		assert.Empty(t, event.PreviousData())
+		assert.WithinDuration(t, time.Now(), event.OccurredAt(), time.Second)
+	})
+}
+
 func TestResourceDeletedEvent(t *testing.T) {
 	t.Run("NewResourceDeletedEvent creates event with correct properties", func(t *testing.T) {
 		// Arrange
@@ -214,6 +240,42 @@ func TestResourceDeletedEvent(t *testing.T) {
 	})
 }
 
+func TestResourceRestoredEvent(t *testing.T) {
+	t.Run("NewResourceRestoredEvent creates event with correct properties", func(t *testing.T) {
+		// Arrange
+		uri := "https://alice.example.com/notes/note1"
+
+		// Act
+		event := event.NewResourceRestoredEvent("resource-mno", uri)
+		event.SetAuthor("alice@example.com")
+
+		// Assert
+		assert.Equal(t, "resource.restored", event.EventType())
+		assert.Equal(t, "resource-mno", event.AggregateID())
+		assert.Equal(t, 1, event.Version())
+		assert.Equal(t, uri, event.URI())
+		assert.Equal(t, "alice@example.com", event.Author())
+		assert.WithinDuration(t, time.Now(), event.OccurredAt(), time.Second)
+	})
+}
+
+func TestResourceContentStoredEvent(t *testing.T) {
+	t.Run("NewResourceContentStoredEvent creates event with correct properties", func(t *testing.T) {
+		// Act
+		event := event.NewResourceContentStoredEvent("resource-pqr", "0a1b2c", 1024, "9f86d081", "image/png")
+
+		// Assert
+		assert.Equal(t, "resource.content_stored", event.EventType())
+		assert.Equal(t, "resource-pqr", event.AggregateID())
+		assert.Equal(t, 1, event.Version())
+		assert.Equal(t, "0a1b2c", event.Key())
+		assert.Equal(t, int64(1024), event.Size())
+		assert.Equal(t, "9f86d081", event.Digest())
+		assert.Equal(t, "image/png", event.ContentType())
+		assert.WithinDuration(t, time.Now(), event.OccurredAt(), time.Second)
+	})
+}
+
 // Integration tests to verify all events implement the domain.Event interface
 func TestAllEventsImplementDomainEventInterface(t *testing.T) {
 	testCases := []struct {
@@ -248,6 +310,14 @@ func TestAllEventsImplementDomainEventInterface(t *testing.T) {
 			name:  "ResourceDeletedEvent",
 			event: event.NewResourceDeletedEvent("id", "https://example.com/resource"),
 		},
+		{
+			name:  "ResourceRestoredEvent",
+			event: event.NewResourceRestoredEvent("id", "https://example.com/resource"),
+		},
+		{
+			name:  "ResourceContentStoredEvent",
+			event: event.NewResourceContentStoredEvent("id", "key", 0, "", "text/plain"),
+		},
 	}
 
 	for _, tc := range testCases {
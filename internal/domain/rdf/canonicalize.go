@@ -0,0 +1,435 @@
+package rdf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CanonicalBlankNodePrefix is the prefix of blank node labels issued by RDFC-1.0
+const CanonicalBlankNodePrefix = "c14n"
+
+// DefaultCanonicalizationCallLimit bounds the number of Hash N-Degree Quads
+// calls so that crafted inputs cannot make canonicalization run forever
+const DefaultCanonicalizationCallLimit = 10000
+
+// ErrCanonicalizationLimit is returned when a dataset needs more work to
+// canonicalize than the configured limit allows
+var ErrCanonicalizationLimit = errors.New("canonicalization exceeded the work limit")
+
+// Canonical is the result of canonicalizing a dataset
+type Canonical struct {
+	// NQuads is the canonical N-Quads document, one sorted quad per line
+	NQuads string
+
+	// Labels maps each input blank node to its canonical label
+	Labels map[BlankNode]BlankNode
+}
+
+// Hash returns the hex encoded SHA-256 digest of the canonical N-Quads
+func (c Canonical) Hash() string {
+	sum := sha256.Sum256([]byte(c.NQuads))
+	return hex.EncodeToString(sum[:])
+}
+
+// Canonicalize applies the RDF Dataset Canonicalization algorithm (RDFC-1.0)
+// with SHA-256, relabelling blank nodes deterministically
+func Canonicalize(dataset *Dataset) (Canonical, error) {
+	return CanonicalizeWithLimit(dataset, DefaultCanonicalizationCallLimit)
+}
+
+// CanonicalizeGraph canonicalizes a graph as a dataset with only a default graph
+func CanonicalizeGraph(graph *Graph) (Canonical, error) {
+	return Canonicalize(NewDatasetFromGraph(graph))
+}
+
+// CanonicalizeWithLimit canonicalizes a dataset, failing with
+// ErrCanonicalizationLimit after limit Hash N-Degree Quads calls
+func CanonicalizeWithLimit(dataset *Dataset, limit int) (Canonical, error) {
+	c := newCanonicalizer(dataset, limit)
+	if err := c.run(); err != nil {
+		return Canonical{}, err
+	}
+	return c.result(), nil
+}
+
+// Isomorphic reports whether two graphs are identical up to blank node labels
+func Isomorphic(a, b *Graph) (bool, error) {
+	if a.Len() != b.Len() {
+		return false, nil
+	}
+	ca, err := CanonicalizeGraph(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := CanonicalizeGraph(b)
+	if err != nil {
+		return false, err
+	}
+	return ca.NQuads == cb.NQuads, nil
+}
+
+// DatasetsIsomorphic reports whether two datasets are identical up to blank node labels
+func DatasetsIsomorphic(a, b *Dataset) (bool, error) {
+	if a.Len() != b.Len() {
+		return false, nil
+	}
+	ca, err := Canonicalize(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := Canonicalize(b)
+	if err != nil {
+		return false, err
+	}
+	return ca.NQuads == cb.NQuads, nil
+}
+
+// quad is a triple together with its graph name, nil for the default graph
+type quad struct {
+	subject, predicate, object, graph Term
+}
+
+// identifierIssuer issues sequential blank node identifiers and remembers the order
+type identifierIssuer struct {
+	prefix  string
+	counter int
+	issued  map[BlankNode]string
+	order   []BlankNode
+}
+
+func newIdentifierIssuer(prefix string) *identifierIssuer {
+	return &identifierIssuer{prefix: prefix, issued: make(map[BlankNode]string)}
+}
+
+// issue returns the identifier for b, issuing a new one when needed
+func (i *identifierIssuer) issue(b BlankNode) string {
+	if id, ok := i.issued[b]; ok {
+		return id
+	}
+	id := fmt.Sprintf("%s%d", i.prefix, i.counter)
+	i.counter++
+	i.issued[b] = id
+	i.order = append(i.order, b)
+	return id
+}
+
+// copy returns an independent copy of the issuer
+func (i *identifierIssuer) copy() *identifierIssuer {
+	c := &identifierIssuer{
+		prefix:  i.prefix,
+		counter: i.counter,
+		issued:  make(map[BlankNode]string, len(i.issued)),
+		order:   append([]BlankNode(nil), i.order...),
+	}
+	for k, v := range i.issued {
+		c.issued[k] = v
+	}
+	return c
+}
+
+// canonicalizer holds the canonicalization state of RDFC-1.0
+type canonicalizer struct {
+	quads          []quad
+	blankNodeQuads map[BlankNode][]quad
+	canonical      *identifierIssuer
+	limit          int
+	calls          int
+}
+
+func newCanonicalizer(dataset *Dataset, limit int) *canonicalizer {
+	c := &canonicalizer{
+		blankNodeQuads: make(map[BlankNode][]quad),
+		canonical:      newIdentifierIssuer(CanonicalBlankNodePrefix),
+		limit:          limit,
+	}
+
+	add := func(graph *Graph, name Term) {
+		for _, t := range graph.Triples() {
+			q := quad{subject: t.Subject, predicate: t.Predicate, object: t.Object, graph: name}
+			c.quads = append(c.quads, q)
+			for _, term := range []Term{q.subject, q.object, q.graph} {
+				if b, ok := term.(BlankNode); ok {
+					c.blankNodeQuads[b] = append(c.blankNodeQuads[b], q)
+				}
+			}
+		}
+	}
+	add(dataset.Default(), nil)
+	for _, name := range dataset.GraphNames() {
+		graph, _ := dataset.NamedGraph(name)
+		add(graph, name)
+	}
+
+	return c
+}
+
+// run issues canonical identifiers for every blank node
+func (c *canonicalizer) run() error {
+	// Group blank nodes by their first degree hash
+	hashToBlankNodes := make(map[string][]BlankNode)
+	for b := range c.blankNodeQuads {
+		h := c.hashFirstDegreeQuads(b)
+		hashToBlankNodes[h] = append(hashToBlankNodes[h], b)
+	}
+	hashes := make([]string, 0, len(hashToBlankNodes))
+	for h := range hashToBlankNodes {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	// Blank nodes with a unique first degree hash get labels right away
+	var shared []string
+	for _, h := range hashes {
+		if nodes := hashToBlankNodes[h]; len(nodes) == 1 {
+			c.canonical.issue(nodes[0])
+		} else {
+			shared = append(shared, h)
+		}
+	}
+
+	// The rest are distinguished by the paths to their neighbours
+	for _, h := range shared {
+		type pathResult struct {
+			hash   string
+			issuer *identifierIssuer
+		}
+		var results []pathResult
+		for _, b := range hashToBlankNodes[h] {
+			if _, issued := c.canonical.issued[b]; issued {
+				continue
+			}
+			temporary := newIdentifierIssuer("b")
+			temporary.issue(b)
+			hash, issuer, err := c.hashNDegreeQuads(b, temporary)
+			if err != nil {
+				return err
+			}
+			results = append(results, pathResult{hash: hash, issuer: issuer})
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].hash < results[j].hash
+		})
+		for _, result := range results {
+			for _, b := range result.issuer.order {
+				c.canonical.issue(b)
+			}
+		}
+	}
+
+	return nil
+}
+
+// result relabels the quads with the canonical identifiers
+func (c *canonicalizer) result() Canonical {
+	labels := make(map[BlankNode]BlankNode, len(c.canonical.issued))
+	for b, id := range c.canonical.issued {
+		labels[b] = BlankNode(id)
+	}
+	relabel := func(term Term) Term {
+		if b, ok := term.(BlankNode); ok {
+			return labels[b]
+		}
+		return term
+	}
+
+	lines := make([]string, 0, len(c.quads))
+	seen := make(map[string]bool, len(c.quads))
+	for _, q := range c.quads {
+		line := canonicalQuad(relabel(q.subject), q.predicate, relabel(q.object), relabel(q.graph))
+		if !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+
+	return Canonical{NQuads: strings.Join(lines, ""), Labels: labels}
+}
+
+// hashFirstDegreeQuads hashes the quads of a blank node with itself written
+// as _:a and every other blank node as _:z
+func (c *canonicalizer) hashFirstDegreeQuads(reference BlankNode) string {
+	relabel := func(term Term) Term {
+		if b, ok := term.(BlankNode); ok {
+			if b == reference {
+				return BlankNode("a")
+			}
+			return BlankNode("z")
+		}
+		return term
+	}
+
+	quads := c.blankNodeQuads[reference]
+	lines := make([]string, 0, len(quads))
+	for _, q := range quads {
+		lines = append(lines, canonicalQuad(relabel(q.subject), q.predicate, relabel(q.object), relabel(q.graph)))
+	}
+	sort.Strings(lines)
+	return sha256Hex(strings.Join(lines, ""))
+}
+
+// hashRelatedBlankNode hashes a blank node adjacent to the one being hashed
+func (c *canonicalizer) hashRelatedBlankNode(related BlankNode, q quad, issuer *identifierIssuer, position string) string {
+	var identifier string
+	if id, ok := c.canonical.issued[related]; ok {
+		identifier = "_:" + id
+	} else if id, ok := issuer.issued[related]; ok {
+		identifier = "_:" + id
+	} else {
+		identifier = c.hashFirstDegreeQuads(related)
+	}
+
+	input := position
+	if position != "g" {
+		input += "<" + q.predicate.Value() + ">"
+	}
+	return sha256Hex(input + identifier)
+}
+
+// hashNDegreeQuads computes the hash of the paths from identifier to its
+// related blank nodes, choosing the lexicographically least path
+func (c *canonicalizer) hashNDegreeQuads(identifier BlankNode, issuer *identifierIssuer) (string, *identifierIssuer, error) {
+	c.calls++
+	if c.limit > 0 && c.calls > c.limit {
+		return "", nil, ErrCanonicalizationLimit
+	}
+
+	hashToRelated := make(map[string][]BlankNode)
+	for _, q := range c.blankNodeQuads[identifier] {
+		for _, component := range []struct {
+			term     Term
+			position string
+		}{{q.subject, "s"}, {q.object, "o"}, {q.graph, "g"}} {
+			b, ok := component.term.(BlankNode)
+			if !ok || b == identifier {
+				continue
+			}
+			h := c.hashRelatedBlankNode(b, q, issuer, component.position)
+			hashToRelated[h] = append(hashToRelated[h], b)
+		}
+	}
+
+	relatedHashes := make([]string, 0, len(hashToRelated))
+	for h := range hashToRelated {
+		relatedHashes = append(relatedHashes, h)
+	}
+	sort.Strings(relatedHashes)
+
+	var data strings.Builder
+	for _, relatedHash := range relatedHashes {
+		data.WriteString(relatedHash)
+
+		var chosenPath string
+		var chosenIssuer *identifierIssuer
+		var permutationErr error
+		permute(hashToRelated[relatedHash], func(permutation []BlankNode) bool {
+			issuerCopy := issuer.copy()
+			var path strings.Builder
+			var recursionList []BlankNode
+
+			worse := func() bool {
+				return chosenPath != "" && path.Len() >= len(chosenPath) && path.String() > chosenPath
+			}
+
+			for _, related := range permutation {
+				if id, ok := c.canonical.issued[related]; ok {
+					path.WriteString("_:" + id)
+				} else {
+					if _, ok := issuerCopy.issued[related]; !ok {
+						recursionList = append(recursionList, related)
+					}
+					path.WriteString("_:" + issuerCopy.issue(related))
+				}
+				if worse() {
+					return true
+				}
+			}
+
+			for _, related := range recursionList {
+				hash, resultIssuer, err := c.hashNDegreeQuads(related, issuerCopy)
+				if err != nil {
+					permutationErr = err
+					return false
+				}
+				path.WriteString("_:" + issuerCopy.issue(related))
+				path.WriteString("<" + hash + ">")
+				issuerCopy = resultIssuer
+				if worse() {
+					return true
+				}
+			}
+
+			if chosenPath == "" || path.String() < chosenPath {
+				chosenPath = path.String()
+				chosenIssuer = issuerCopy
+			}
+			return true
+		})
+		if permutationErr != nil {
+			return "", nil, permutationErr
+		}
+
+		data.WriteString(chosenPath)
+		issuer = chosenIssuer
+	}
+
+	return sha256Hex(data.String()), issuer, nil
+}
+
+// permute calls visit with every permutation of nodes until visit returns false
+func permute(nodes []BlankNode, visit func([]BlankNode) bool) {
+	sorted := append([]BlankNode(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var generate func(k int) bool
+	generate = func(k int) bool {
+		if k == len(sorted) {
+			return visit(append([]BlankNode(nil), sorted...))
+		}
+		for i := k; i < len(sorted); i++ {
+			sorted[k], sorted[i] = sorted[i], sorted[k]
+			if !generate(k + 1) {
+				return false
+			}
+			sorted[k], sorted[i] = sorted[i], sorted[k]
+		}
+		return true
+	}
+	generate(0)
+}
+
+// canonicalQuad writes a quad in canonical N-Quads form including the newline
+func canonicalQuad(subject, predicate, object, graph Term) string {
+	line := canonicalTerm(subject) + " " + canonicalTerm(predicate) + " " + canonicalTerm(object)
+	if graph != nil {
+		line += " " + canonicalTerm(graph)
+	}
+	return line + " .\n"
+}
+
+// canonicalTerm writes a term in canonical N-Quads form; language tags are
+// lowercased because literals compare them case-insensitively
+func canonicalTerm(term Term) string {
+	if l, ok := term.(Literal); ok {
+		quoted := `"` + EscapeString(l.Lexical) + `"`
+		switch dt := l.datatype(); {
+		case l.Language != "":
+			return quoted + "@" + strings.ToLower(l.Language)
+		case dt == XSDString:
+			return quoted
+		default:
+			return quoted + "^^" + dt.String()
+		}
+	}
+	return term.String()
+}
+
+// sha256Hex returns the hex encoded SHA-256 digest of s
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,95 @@
+package rdf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+func TestCanonicalize(t *testing.T) {
+	knows := rdf.IRI("http://xmlns.com/foaf/0.1/knows")
+	name := rdf.IRI("http://xmlns.com/foaf/0.1/name")
+
+	t.Run("relabels blank nodes independently of their input labels", func(t *testing.T) {
+		// Arrange
+		a := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: rdf.BlankNode("x"), Predicate: knows, Object: rdf.BlankNode("y")},
+			{Subject: rdf.BlankNode("y"), Predicate: name, Object: rdf.NewLiteral("Bob")},
+		})
+		b := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: rdf.BlankNode("q"), Predicate: name, Object: rdf.NewLiteral("Bob")},
+			{Subject: rdf.BlankNode("p"), Predicate: knows, Object: rdf.BlankNode("q")},
+		})
+
+		// Act
+		ca, err := rdf.CanonicalizeGraph(a)
+		require.NoError(t, err)
+		cb, err := rdf.CanonicalizeGraph(b)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, ca.NQuads, cb.NQuads)
+		assert.Equal(t, ca.Hash(), cb.Hash())
+		assert.Equal(t, rdf.BlankNode("c14n0"), ca.Labels[rdf.BlankNode("y")])
+		assert.Equal(t, rdf.BlankNode("c14n0"), cb.Labels[rdf.BlankNode("q")])
+		assert.Equal(t, "_:c14n0 <http://xmlns.com/foaf/0.1/name> \"Bob\" .\n_:c14n1 <http://xmlns.com/foaf/0.1/knows> _:c14n0 .\n", ca.NQuads)
+	})
+
+	t.Run("distinguishes blank nodes with identical first degree quads", func(t *testing.T) {
+		// Arrange: a cycle of three blank nodes and a chain of three
+		cycle := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: rdf.BlankNode("a"), Predicate: knows, Object: rdf.BlankNode("b")},
+			{Subject: rdf.BlankNode("b"), Predicate: knows, Object: rdf.BlankNode("c")},
+			{Subject: rdf.BlankNode("c"), Predicate: knows, Object: rdf.BlankNode("a")},
+		})
+		rotated := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: rdf.BlankNode("z"), Predicate: knows, Object: rdf.BlankNode("x")},
+			{Subject: rdf.BlankNode("x"), Predicate: knows, Object: rdf.BlankNode("y")},
+			{Subject: rdf.BlankNode("y"), Predicate: knows, Object: rdf.BlankNode("z")},
+		})
+		chain := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: rdf.BlankNode("a"), Predicate: knows, Object: rdf.BlankNode("b")},
+			{Subject: rdf.BlankNode("b"), Predicate: knows, Object: rdf.BlankNode("c")},
+			{Subject: rdf.BlankNode("c"), Predicate: knows, Object: rdf.BlankNode("d")},
+		})
+
+		// Act
+		same, err := rdf.Isomorphic(cycle, rotated)
+		require.NoError(t, err)
+		different, err := rdf.Isomorphic(cycle, chain)
+		require.NoError(t, err)
+
+		// Assert
+		assert.True(t, same)
+		assert.False(t, different)
+	})
+
+	t.Run("includes graph names in canonical N-Quads", func(t *testing.T) {
+		// Arrange
+		d := rdf.NewDataset()
+		require.NoError(t, d.Add(rdf.BlankNode("g"), rdf.Triple{Subject: rdf.IRI("https://example.com/s"), Predicate: name, Object: rdf.NewLangLiteral("Bob", "EN")}))
+
+		// Act
+		c, err := rdf.Canonicalize(d)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "<https://example.com/s> <http://xmlns.com/foaf/0.1/name> \"Bob\"@en _:c14n0 .\n", c.NQuads)
+	})
+
+	t.Run("fails when the work limit is exceeded", func(t *testing.T) {
+		// Arrange
+		cycle := rdf.NewDataset()
+		for _, pair := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}} {
+			require.NoError(t, cycle.Add(nil, rdf.Triple{Subject: rdf.BlankNode(pair[0]), Predicate: knows, Object: rdf.BlankNode(pair[1])}))
+		}
+
+		// Act
+		_, err := rdf.CanonicalizeWithLimit(cycle, 1)
+
+		// Assert
+		assert.ErrorIs(t, err, rdf.ErrCanonicalizationLimit)
+	})
+}
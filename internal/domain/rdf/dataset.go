@@ -0,0 +1,107 @@
+package rdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dataset is a default graph plus any number of named graphs
+type Dataset struct {
+	defaultGraph *Graph
+	names        []Term
+	named        map[string]*Graph
+}
+
+// NewDataset creates an empty dataset
+func NewDataset() *Dataset {
+	return &Dataset{
+		defaultGraph: NewGraph(),
+		names:        make([]Term, 0),
+		named:        make(map[string]*Graph),
+	}
+}
+
+// NewDatasetFromGraph creates a dataset whose default graph is graph
+func NewDatasetFromGraph(graph *Graph) *Dataset {
+	d := NewDataset()
+	d.defaultGraph.Merge(graph)
+	return d
+}
+
+// Default returns the default graph
+func (d *Dataset) Default() *Graph {
+	return d.defaultGraph
+}
+
+// Graph returns the named graph, creating it when it does not exist.
+// A nil name returns the default graph.
+func (d *Dataset) Graph(name Term) *Graph {
+	if name == nil {
+		return d.defaultGraph
+	}
+	key := termKey(name)
+	if g, ok := d.named[key]; ok {
+		return g
+	}
+	g := NewGraph()
+	d.named[key] = g
+	d.names = append(d.names, name)
+	return g
+}
+
+// NamedGraph returns the named graph if it exists
+func (d *Dataset) NamedGraph(name Term) (*Graph, bool) {
+	g, ok := d.named[termKey(name)]
+	return g, ok
+}
+
+// Add inserts a triple into the graph called name (nil for the default graph)
+func (d *Dataset) Add(name Term, t Triple) error {
+	if name != nil && name.Kind() == KindLiteral {
+		return fmt.Errorf("graph name %s must be an IRI or blank node", name)
+	}
+	d.Graph(name).Add(t)
+	return nil
+}
+
+// GraphNames returns the names of the named graphs in sorted order
+func (d *Dataset) GraphNames() []Term {
+	names := make([]Term, len(d.names))
+	copy(names, d.names)
+	sort.SliceStable(names, func(i, j int) bool {
+		return CompareTerms(names[i], names[j]) < 0
+	})
+	return names
+}
+
+// Len returns the number of quads in the dataset
+func (d *Dataset) Len() int {
+	n := d.defaultGraph.Len()
+	for _, g := range d.named {
+		n += g.Len()
+	}
+	return n
+}
+
+// Union returns a graph holding the triples of every graph in the dataset
+func (d *Dataset) Union() *Graph {
+	union := NewGraph()
+	union.Merge(d.defaultGraph)
+	for _, name := range d.names {
+		union.Merge(d.named[termKey(name)])
+	}
+	return union
+}
+
+// String returns the dataset as sorted N-Quads, default graph first
+func (d *Dataset) String() string {
+	var b strings.Builder
+	b.WriteString(d.defaultGraph.String())
+	for _, name := range d.GraphNames() {
+		for _, t := range d.named[termKey(name)].SortedTriples() {
+			b.WriteString(t.Subject.String() + " " + t.Predicate.String() + " " + t.Object.String() + " " + name.String() + " .\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package rdf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+func TestDataset(t *testing.T) {
+	alice := rdf.IRI("https://example.com/alice")
+	name := rdf.IRI("http://xmlns.com/foaf/0.1/name")
+	profile := rdf.IRI("https://example.com/profile")
+	contacts := rdf.IRI("https://example.com/contacts")
+
+	t.Run("keeps triples in separate graphs", func(t *testing.T) {
+		d := rdf.NewDataset()
+		triple := rdf.Triple{Subject: alice, Predicate: name, Object: rdf.NewLiteral("Alice")}
+
+		assert.NoError(t, d.Add(nil, triple))
+		assert.NoError(t, d.Add(profile, triple))
+		assert.NoError(t, d.Add(contacts, triple))
+
+		assert.Equal(t, 3, d.Len())
+		assert.Equal(t, 1, d.Default().Len())
+		assert.Equal(t, 1, d.Union().Len())
+		assert.Equal(t, []rdf.Term{contacts, profile}, d.GraphNames())
+
+		g, ok := d.NamedGraph(profile)
+		assert.True(t, ok)
+		assert.True(t, g.Contains(triple))
+	})
+
+	t.Run("rejects literal graph names", func(t *testing.T) {
+		d := rdf.NewDataset()
+
+		err := d.Add(rdf.NewLiteral("graph"), rdf.Triple{Subject: alice, Predicate: name, Object: rdf.NewLiteral("Alice")})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("writes sorted N-Quads with the default graph first", func(t *testing.T) {
+		d := rdf.NewDataset()
+		_ = d.Add(profile, rdf.Triple{Subject: alice, Predicate: name, Object: rdf.NewLiteral("Alice")})
+		_ = d.Add(nil, rdf.Triple{Subject: alice, Predicate: name, Object: rdf.NewLiteral("A")})
+
+		expected := `<https://example.com/alice> <http://xmlns.com/foaf/0.1/name> "A" .
+<https://example.com/alice> <http://xmlns.com/foaf/0.1/name> "Alice" <https://example.com/profile> .
+`
+		assert.Equal(t, expected, d.String())
+	})
+}
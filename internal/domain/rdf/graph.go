@@ -0,0 +1,176 @@
+package rdf
+
+import (
+	"sort"
+	"strings"
+)
+
+// Graph is a set of triples that remembers insertion order
+type Graph struct {
+	triples []Triple
+	index   map[string]struct{}
+}
+
+// NewGraph creates an empty graph
+func NewGraph() *Graph {
+	return &Graph{
+		triples: make([]Triple, 0),
+		index:   make(map[string]struct{}),
+	}
+}
+
+// NewGraphFromTriples creates a graph containing the given triples
+func NewGraphFromTriples(triples []Triple) *Graph {
+	g := NewGraph()
+	for _, t := range triples {
+		g.Add(t)
+	}
+	return g
+}
+
+// Add inserts a triple and reports whether it was not already present
+func (g *Graph) Add(t Triple) bool {
+	key := t.key()
+	if _, exists := g.index[key]; exists {
+		return false
+	}
+	g.index[key] = struct{}{}
+	g.triples = append(g.triples, t)
+	return true
+}
+
+// Merge adds every triple of other to the graph
+func (g *Graph) Merge(other *Graph) {
+	for _, t := range other.triples {
+		g.Add(t)
+	}
+}
+
+// Remove deletes a triple and reports whether it was present
+func (g *Graph) Remove(t Triple) bool {
+	key := t.key()
+	if _, exists := g.index[key]; !exists {
+		return false
+	}
+	delete(g.index, key)
+	for i, existing := range g.triples {
+		if existing.key() == key {
+			g.triples = append(g.triples[:i], g.triples[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Contains reports whether the graph contains the triple
+func (g *Graph) Contains(t Triple) bool {
+	_, exists := g.index[t.key()]
+	return exists
+}
+
+// Len returns the number of triples in the graph
+func (g *Graph) Len() int {
+	return len(g.triples)
+}
+
+// Triples returns a copy of the triples in insertion order
+func (g *Graph) Triples() []Triple {
+	result := make([]Triple, len(g.triples))
+	copy(result, g.triples)
+	return result
+}
+
+// SortedTriples returns the triples ordered by subject, predicate and object
+// so that serializations are deterministic
+func (g *Graph) SortedTriples() []Triple {
+	result := g.Triples()
+	sort.SliceStable(result, func(i, j int) bool {
+		return compareTriples(result[i], result[j]) < 0
+	})
+	return result
+}
+
+// Match returns the triples matching the pattern; nil terms act as wildcards
+func (g *Graph) Match(subject, predicate, object Term) []Triple {
+	var result []Triple
+	for _, t := range g.triples {
+		if subject != nil && !termKeyEqual(t.Subject, subject) {
+			continue
+		}
+		if predicate != nil && !termKeyEqual(t.Predicate, predicate) {
+			continue
+		}
+		if object != nil && !termKeyEqual(t.Object, object) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// Subjects returns the distinct subjects of the graph in insertion order
+func (g *Graph) Subjects() []Term {
+	seen := make(map[string]struct{})
+	var result []Term
+	for _, t := range g.triples {
+		key := termKey(t.Subject)
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, t.Subject)
+	}
+	return result
+}
+
+// Equal reports whether both graphs contain exactly the same triples,
+// comparing blank nodes by label
+func (g *Graph) Equal(other *Graph) bool {
+	if other == nil || g.Len() != other.Len() {
+		return false
+	}
+	for key := range g.index {
+		if _, exists := other.index[key]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the graph as sorted N-Triples
+func (g *Graph) String() string {
+	var b strings.Builder
+	for _, t := range g.SortedTriples() {
+		b.WriteString(t.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// termKeyEqual compares terms using their normalized identity keys
+func termKeyEqual(a, b Term) bool {
+	return termKey(a) == termKey(b)
+}
+
+// CompareTerms orders terms: IRIs first, then blank nodes, then literals,
+// each group ordered by their N-Triples representation
+func CompareTerms(a, b Term) int {
+	if a.Kind() != b.Kind() {
+		if a.Kind() < b.Kind() {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(termKey(a), termKey(b))
+}
+
+// compareTriples orders triples by subject, predicate and object
+func compareTriples(a, b Triple) int {
+	if c := CompareTerms(a.Subject, b.Subject); c != 0 {
+		return c
+	}
+	if c := CompareTerms(a.Predicate, b.Predicate); c != 0 {
+		return c
+	}
+	return CompareTerms(a.Object, b.Object)
+}
@@ -0,0 +1,59 @@
+package rdf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+func TestGraph(t *testing.T) {
+	alice := rdf.IRI("https://example.com/alice")
+	bob := rdf.IRI("https://example.com/bob")
+	name := rdf.IRI("http://xmlns.com/foaf/0.1/name")
+	knows := rdf.IRI("http://xmlns.com/foaf/0.1/knows")
+
+	t.Run("ignores duplicate triples", func(t *testing.T) {
+		g := rdf.NewGraph()
+
+		assert.True(t, g.Add(rdf.Triple{Subject: alice, Predicate: name, Object: rdf.NewLiteral("Alice")}))
+		assert.False(t, g.Add(rdf.Triple{Subject: alice, Predicate: name, Object: rdf.Literal{Lexical: "Alice"}}))
+		assert.Equal(t, 1, g.Len())
+	})
+
+	t.Run("matches triples using wildcards", func(t *testing.T) {
+		g := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: alice, Predicate: name, Object: rdf.NewLiteral("Alice")},
+			{Subject: alice, Predicate: knows, Object: bob},
+			{Subject: bob, Predicate: name, Object: rdf.NewLiteral("Bob")},
+		})
+
+		assert.Len(t, g.Match(alice, nil, nil), 2)
+		assert.Len(t, g.Match(nil, name, nil), 2)
+		assert.Len(t, g.Match(nil, nil, bob), 1)
+		assert.Equal(t, []rdf.Term{alice, bob}, g.Subjects())
+	})
+
+	t.Run("removes triples", func(t *testing.T) {
+		triple := rdf.Triple{Subject: alice, Predicate: knows, Object: bob}
+		g := rdf.NewGraphFromTriples([]rdf.Triple{triple})
+
+		assert.True(t, g.Remove(triple))
+		assert.False(t, g.Contains(triple))
+		assert.Equal(t, 0, g.Len())
+	})
+
+	t.Run("compares graphs independent of insertion order", func(t *testing.T) {
+		first := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: alice, Predicate: knows, Object: bob},
+			{Subject: bob, Predicate: name, Object: rdf.NewLiteral("Bob")},
+		})
+		second := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: bob, Predicate: name, Object: rdf.NewLiteral("Bob")},
+			{Subject: alice, Predicate: knows, Object: bob},
+		})
+
+		assert.True(t, first.Equal(second))
+		assert.Equal(t, first.String(), second.String())
+	})
+}
@@ -0,0 +1,147 @@
+package rdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Namespace is a prefix bound to a namespace IRI
+type Namespace struct {
+	Prefix string
+	IRI    string
+}
+
+// WellKnownNamespaces lists the vocabularies commonly found in Solid pods
+var WellKnownNamespaces = []Namespace{
+	{Prefix: "rdf", IRI: RDFNamespace},
+	{Prefix: "rdfs", IRI: "http://www.w3.org/2000/01/rdf-schema#"},
+	{Prefix: "xsd", IRI: XSDNamespace},
+	{Prefix: "owl", IRI: "http://www.w3.org/2002/07/owl#"},
+	{Prefix: "dc", IRI: "http://purl.org/dc/elements/1.1/"},
+	{Prefix: "dcterms", IRI: "http://purl.org/dc/terms/"},
+	{Prefix: "foaf", IRI: "http://xmlns.com/foaf/0.1/"},
+	{Prefix: "schema", IRI: "http://schema.org/"},
+	{Prefix: "vcard", IRI: "http://www.w3.org/2006/vcard/ns#"},
+	{Prefix: "ldp", IRI: "http://www.w3.org/ns/ldp#"},
+	{Prefix: "solid", IRI: "http://www.w3.org/ns/solid/terms#"},
+	{Prefix: "acl", IRI: "http://www.w3.org/ns/auth/acl#"},
+	{Prefix: "pim", IRI: "http://www.w3.org/ns/pim/space#"},
+	{Prefix: "stat", IRI: "http://www.w3.org/ns/posix/stat#"},
+	{Prefix: "as", IRI: "https://www.w3.org/ns/activitystreams#"},
+	{Prefix: "sh", IRI: "http://www.w3.org/ns/shacl#"},
+	{Prefix: "skos", IRI: "http://www.w3.org/2004/02/skos/core#"},
+	{Prefix: "prov", IRI: "http://www.w3.org/ns/prov#"},
+}
+
+// PrefixMap binds prefixes to namespace IRIs and generates new bindings on demand
+type PrefixMap struct {
+	byPrefix    map[string]string
+	byNamespace map[string]string
+	generated   int
+}
+
+// NewPrefixMap creates a prefix map seeded with the given namespaces.
+// Earlier bindings win when a prefix or namespace is declared twice.
+func NewPrefixMap(namespaces ...Namespace) *PrefixMap {
+	pm := &PrefixMap{
+		byPrefix:    make(map[string]string),
+		byNamespace: make(map[string]string),
+	}
+	for _, ns := range namespaces {
+		pm.Bind(ns.Prefix, ns.IRI)
+	}
+	return pm
+}
+
+// Bind associates prefix with namespace unless either is already bound
+func (pm *PrefixMap) Bind(prefix, namespace string) bool {
+	if _, exists := pm.byPrefix[prefix]; exists {
+		return false
+	}
+	if _, exists := pm.byNamespace[namespace]; exists {
+		return false
+	}
+	pm.byPrefix[prefix] = namespace
+	pm.byNamespace[namespace] = prefix
+	return true
+}
+
+// PrefixFor returns the prefix bound to namespace
+func (pm *PrefixMap) PrefixFor(namespace string) (string, bool) {
+	prefix, ok := pm.byNamespace[namespace]
+	return prefix, ok
+}
+
+// Namespace returns the namespace bound to prefix
+func (pm *PrefixMap) Namespace(prefix string) (string, bool) {
+	namespace, ok := pm.byPrefix[prefix]
+	return namespace, ok
+}
+
+// Ensure returns the prefix for namespace, generating "nsN" if none is bound
+func (pm *PrefixMap) Ensure(namespace string) string {
+	if prefix, ok := pm.byNamespace[namespace]; ok {
+		return prefix
+	}
+	for {
+		prefix := fmt.Sprintf("ns%d", pm.generated)
+		pm.generated++
+		if pm.Bind(prefix, namespace) {
+			return prefix
+		}
+	}
+}
+
+// Namespaces returns all bindings sorted by prefix
+func (pm *PrefixMap) Namespaces() []Namespace {
+	result := make([]Namespace, 0, len(pm.byPrefix))
+	for prefix, namespace := range pm.byPrefix {
+		result = append(result, Namespace{Prefix: prefix, IRI: namespace})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Prefix < result[j].Prefix
+	})
+	return result
+}
+
+// SplitIRI splits an IRI into a namespace and a local name that is a valid
+// XML NCName. ok is false when no such split exists.
+func SplitIRI(iri string) (namespace, local string, ok bool) {
+	// Find the longest NCName suffix, then trim leading characters that
+	// may not start a name
+	start := len(iri)
+	for start > 0 {
+		r := rune(iri[start-1])
+		if r >= 0x80 || !isNameChar(r) {
+			break
+		}
+		start--
+	}
+	for start < len(iri) && !isNameStartChar(rune(iri[start])) {
+		start++
+	}
+	if start == 0 || start >= len(iri) {
+		return "", "", false
+	}
+	return iri[:start], iri[start:], true
+}
+
+// isNameStartChar reports whether r may start an XML NCName (ASCII subset)
+func isNameStartChar(r rune) bool {
+	return r == '_' || (r < 0x80 && unicode.IsLetter(r))
+}
+
+// isNameChar reports whether r may appear inside an XML NCName (ASCII subset)
+func isNameChar(r rune) bool {
+	return isNameStartChar(r) || r == '-' || r == '.' || (r >= '0' && r <= '9')
+}
+
+// IsNCName reports whether s is a valid XML NCName using the ASCII subset
+func IsNCName(s string) bool {
+	if s == "" || !isNameStartChar(rune(s[0])) {
+		return false
+	}
+	return strings.IndexFunc(s, func(r rune) bool { return !isNameChar(r) }) < 0
+}
@@ -0,0 +1,55 @@
+package rdf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+func TestSplitIRI(t *testing.T) {
+	t.Run("splits hash and slash namespaces", func(t *testing.T) {
+		ns, local, ok := rdf.SplitIRI("http://xmlns.com/foaf/0.1/name")
+		assert.True(t, ok)
+		assert.Equal(t, "http://xmlns.com/foaf/0.1/", ns)
+		assert.Equal(t, "name", local)
+
+		ns, local, ok = rdf.SplitIRI("http://www.w3.org/ns/ldp#BasicContainer")
+		assert.True(t, ok)
+		assert.Equal(t, "http://www.w3.org/ns/ldp#", ns)
+		assert.Equal(t, "BasicContainer", local)
+	})
+
+	t.Run("skips characters that cannot start a name", func(t *testing.T) {
+		ns, local, ok := rdf.SplitIRI("https://example.com/terms/2nd-item")
+		assert.True(t, ok)
+		assert.Equal(t, "https://example.com/terms/2", ns)
+		assert.Equal(t, "nd-item", local)
+	})
+
+	t.Run("fails when no local name is available", func(t *testing.T) {
+		_, _, ok := rdf.SplitIRI("https://example.com/p/123")
+		assert.False(t, ok)
+	})
+}
+
+func TestPrefixMap(t *testing.T) {
+	t.Run("reuses well-known prefixes", func(t *testing.T) {
+		pm := rdf.NewPrefixMap(rdf.WellKnownNamespaces...)
+		assert.Equal(t, "foaf", pm.Ensure("http://xmlns.com/foaf/0.1/"))
+	})
+
+	t.Run("generates prefixes for unknown namespaces", func(t *testing.T) {
+		pm := rdf.NewPrefixMap(rdf.Namespace{Prefix: "ns0", IRI: "https://taken.example.com/"})
+		assert.Equal(t, "ns1", pm.Ensure("https://new.example.com/"))
+		assert.Equal(t, "ns1", pm.Ensure("https://new.example.com/"))
+	})
+
+	t.Run("keeps the first binding for a prefix", func(t *testing.T) {
+		pm := rdf.NewPrefixMap()
+		assert.True(t, pm.Bind("ex", "https://one.example.com/"))
+		assert.False(t, pm.Bind("ex", "https://two.example.com/"))
+		ns, _ := pm.Namespace("ex")
+		assert.Equal(t, "https://one.example.com/", ns)
+	})
+}
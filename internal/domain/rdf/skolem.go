@@ -0,0 +1,81 @@
+package rdf
+
+import (
+	"net/url"
+	"strings"
+)
+
+// GenIDPath is the well-known path of skolem IRIs, which stand in for blank
+// nodes (RDF 1.1 Concepts, section 3.5)
+const GenIDPath = "/.well-known/genid/"
+
+// SkolemIRI returns the skolem IRI with the given id on the origin of uri
+func SkolemIRI(uri string, id string) IRI {
+	return IRI(origin(uri) + GenIDPath + id)
+}
+
+// SkolemID returns the id of a skolem IRI on the origin of uri; ok is false
+// for any other IRI
+func SkolemID(iri IRI, uri string) (id string, ok bool) {
+	id, ok = strings.CutPrefix(string(iri), origin(uri)+GenIDPath)
+	return id, ok && id != "" && !strings.ContainsAny(id, "/?#")
+}
+
+// Skolemize returns a copy of graph with its blank nodes replaced by the
+// IRIs in skolems; blank nodes without one are kept
+func Skolemize(graph *Graph, skolems map[BlankNode]IRI) *Graph {
+	replace := func(term Term) Term {
+		if b, ok := term.(BlankNode); ok {
+			if iri, ok := skolems[b]; ok {
+				return iri
+			}
+		}
+		return term
+	}
+
+	result := NewGraph()
+	for _, t := range graph.Triples() {
+		result.Add(Triple{Subject: replace(t.Subject), Predicate: t.Predicate, Object: replace(t.Object)})
+	}
+	return result
+}
+
+// Deskolemize returns a copy of graph with the skolem IRIs on the origin of
+// uri replaced by blank nodes labelled with their id
+func Deskolemize(graph *Graph, uri string) *Graph {
+	replace := func(term Term) Term {
+		if iri, ok := term.(IRI); ok {
+			if id, ok := SkolemID(iri, uri); ok {
+				return BlankNode(id)
+			}
+		}
+		return term
+	}
+
+	result := NewGraph()
+	for _, t := range graph.Triples() {
+		result.Add(Triple{Subject: replace(t.Subject), Predicate: t.Predicate, Object: replace(t.Object)})
+	}
+	return result
+}
+
+// BlankNodeSignatures returns the RDFC-1.0 first degree hash of every blank
+// node in graph. A blank node keeps its signature while its own triples do
+// not change, whatever happens to the rest of the graph.
+func BlankNodeSignatures(graph *Graph) map[BlankNode]string {
+	c := newCanonicalizer(NewDatasetFromGraph(graph), DefaultCanonicalizationCallLimit)
+	signatures := make(map[BlankNode]string, len(c.blankNodeQuads))
+	for b := range c.blankNodeQuads {
+		signatures[b] = c.hashFirstDegreeQuads(b)
+	}
+	return signatures
+}
+
+// origin returns the scheme and authority of uri
+func origin(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
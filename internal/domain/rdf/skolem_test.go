@@ -0,0 +1,55 @@
+package rdf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+func TestSkolemization(t *testing.T) {
+	const resource = "https://pod.example.com/notes/a"
+	alice := rdf.IRI("https://example.com/alice")
+	knows := rdf.IRI("http://xmlns.com/foaf/0.1/knows")
+	name := rdf.IRI("http://xmlns.com/foaf/0.1/name")
+
+	t.Run("mints skolem IRIs on the origin of the resource", func(t *testing.T) {
+		iri := rdf.SkolemIRI(resource, "b1")
+		id, ok := rdf.SkolemID(iri, resource)
+		_, foreign := rdf.SkolemID(rdf.SkolemIRI("https://other.example.com/x", "b1"), resource)
+
+		assert.Equal(t, rdf.IRI("https://pod.example.com/.well-known/genid/b1"), iri)
+		assert.True(t, ok)
+		assert.Equal(t, "b1", id)
+		assert.False(t, foreign)
+	})
+
+	t.Run("replaces blank nodes and restores them", func(t *testing.T) {
+		g := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: alice, Predicate: knows, Object: rdf.BlankNode("x")},
+			{Subject: rdf.BlankNode("x"), Predicate: name, Object: rdf.NewLiteral("Bob")},
+		})
+
+		skolemized := rdf.Skolemize(g, map[rdf.BlankNode]rdf.IRI{"x": rdf.SkolemIRI(resource, "b1")})
+		restored := rdf.Deskolemize(skolemized, resource)
+
+		assert.True(t, skolemized.Contains(rdf.Triple{Subject: alice, Predicate: knows, Object: rdf.SkolemIRI(resource, "b1")}))
+		assert.True(t, restored.Contains(rdf.Triple{Subject: rdf.BlankNode("b1"), Predicate: name, Object: rdf.NewLiteral("Bob")}))
+	})
+
+	t.Run("signs blank nodes by their own triples", func(t *testing.T) {
+		before := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: rdf.BlankNode("x"), Predicate: name, Object: rdf.NewLiteral("Bob")},
+		})
+		after := rdf.NewGraphFromTriples([]rdf.Triple{
+			{Subject: alice, Predicate: name, Object: rdf.NewLiteral("Alice")},
+			{Subject: rdf.BlankNode("y"), Predicate: name, Object: rdf.NewLiteral("Bob")},
+			{Subject: rdf.BlankNode("z"), Predicate: name, Object: rdf.NewLiteral("Carol")},
+		})
+
+		signatures := rdf.BlankNodeSignatures(after)
+
+		assert.Equal(t, rdf.BlankNodeSignatures(before)["x"], signatures["y"])
+		assert.NotEqual(t, signatures["y"], signatures["z"])
+	})
+}
@@ -0,0 +1,268 @@
+package rdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Well-known namespaces used throughout the RDF tooling
+const (
+	RDFNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	XSDNamespace = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// Well-known IRIs referenced by parsers and serializers
+const (
+	RDFType       IRI = RDFNamespace + "type"
+	RDFLangString IRI = RDFNamespace + "langString"
+	XSDString     IRI = XSDNamespace + "string"
+)
+
+// TermKind identifies the kind of an RDF term
+type TermKind int
+
+const (
+	KindIRI TermKind = iota
+	KindBlankNode
+	KindLiteral
+)
+
+// Term is an RDF term: an IRI, a blank node or a literal
+type Term interface {
+	// Kind returns the kind of the term
+	Kind() TermKind
+
+	// Value returns the raw value of the term (IRI string, blank node label or lexical form)
+	Value() string
+
+	// String returns the N-Triples representation of the term
+	String() string
+
+	// Equal reports whether the term is identical to another term
+	Equal(other Term) bool
+}
+
+// IRI is an absolute IRI reference
+type IRI string
+
+// Kind returns KindIRI
+func (i IRI) Kind() TermKind {
+	return KindIRI
+}
+
+// Value returns the IRI string
+func (i IRI) Value() string {
+	return string(i)
+}
+
+// String returns the IRI enclosed in angle brackets
+func (i IRI) String() string {
+	return "<" + escapeIRI(string(i)) + ">"
+}
+
+// Equal reports whether other is the same IRI
+func (i IRI) Equal(other Term) bool {
+	o, ok := other.(IRI)
+	return ok && o == i
+}
+
+// BlankNode is a blank node identified by a document-scoped label
+type BlankNode string
+
+// Kind returns KindBlankNode
+func (b BlankNode) Kind() TermKind {
+	return KindBlankNode
+}
+
+// Value returns the blank node label without the "_:" prefix
+func (b BlankNode) Value() string {
+	return string(b)
+}
+
+// String returns the blank node in "_:label" form
+func (b BlankNode) String() string {
+	return "_:" + string(b)
+}
+
+// Equal reports whether other is a blank node with the same label
+func (b BlankNode) Equal(other Term) bool {
+	o, ok := other.(BlankNode)
+	return ok && o == b
+}
+
+// Literal is an RDF literal with a datatype and an optional language tag
+type Literal struct {
+	Lexical  string
+	Datatype IRI
+	Language string
+}
+
+// NewLiteral creates a plain xsd:string literal
+func NewLiteral(lexical string) Literal {
+	return Literal{Lexical: lexical, Datatype: XSDString}
+}
+
+// NewLangLiteral creates a language-tagged rdf:langString literal
+func NewLangLiteral(lexical, language string) Literal {
+	return Literal{Lexical: lexical, Datatype: RDFLangString, Language: language}
+}
+
+// NewTypedLiteral creates a literal with the given datatype, defaulting to xsd:string
+func NewTypedLiteral(lexical string, datatype IRI) Literal {
+	if datatype == "" {
+		datatype = XSDString
+	}
+	return Literal{Lexical: lexical, Datatype: datatype}
+}
+
+// Kind returns KindLiteral
+func (l Literal) Kind() TermKind {
+	return KindLiteral
+}
+
+// Value returns the lexical form of the literal
+func (l Literal) Value() string {
+	return l.Lexical
+}
+
+// String returns the N-Triples representation of the literal
+func (l Literal) String() string {
+	quoted := `"` + EscapeString(l.Lexical) + `"`
+	switch {
+	case l.Language != "":
+		return quoted + "@" + l.Language
+	case l.Datatype == "" || l.Datatype == XSDString:
+		return quoted
+	default:
+		return quoted + "^^" + l.Datatype.String()
+	}
+}
+
+// Equal reports whether other is a literal with the same lexical form, datatype and language
+func (l Literal) Equal(other Term) bool {
+	o, ok := other.(Literal)
+	if !ok {
+		return false
+	}
+	return l.Lexical == o.Lexical &&
+		l.datatype() == o.datatype() &&
+		strings.EqualFold(l.Language, o.Language)
+}
+
+// datatype returns the effective datatype of the literal
+func (l Literal) datatype() IRI {
+	if l.Language != "" {
+		return RDFLangString
+	}
+	if l.Datatype == "" {
+		return XSDString
+	}
+	return l.Datatype
+}
+
+// EffectiveDatatype returns the datatype implied by the literal's fields
+func (l Literal) EffectiveDatatype() IRI {
+	return l.datatype()
+}
+
+// Triple is a single RDF statement
+type Triple struct {
+	Subject   Term
+	Predicate Term
+	Object    Term
+}
+
+// NewTriple creates a triple after checking each term is valid in its position
+func NewTriple(subject, predicate, object Term) (Triple, error) {
+	t := Triple{Subject: subject, Predicate: predicate, Object: object}
+	if err := t.Validate(); err != nil {
+		return Triple{}, err
+	}
+	return t, nil
+}
+
+// Validate checks that subject, predicate and object are allowed in their positions
+func (t Triple) Validate() error {
+	if t.Subject == nil || t.Predicate == nil || t.Object == nil {
+		return fmt.Errorf("triple has a missing term")
+	}
+	if t.Subject.Kind() == KindLiteral {
+		return fmt.Errorf("literal %s is not allowed as a subject", t.Subject)
+	}
+	if t.Predicate.Kind() != KindIRI {
+		return fmt.Errorf("%s is not allowed as a predicate", t.Predicate)
+	}
+	return nil
+}
+
+// Equal reports whether two triples have identical terms
+func (t Triple) Equal(other Triple) bool {
+	return t.Subject.Equal(other.Subject) &&
+		t.Predicate.Equal(other.Predicate) &&
+		t.Object.Equal(other.Object)
+}
+
+// String returns the triple as an N-Triples statement without the trailing newline
+func (t Triple) String() string {
+	return t.Subject.String() + " " + t.Predicate.String() + " " + t.Object.String() + " ."
+}
+
+// key returns a normalized identity key used for set membership
+func (t Triple) key() string {
+	return termKey(t.Subject) + " " + termKey(t.Predicate) + " " + termKey(t.Object)
+}
+
+// termKey returns a normalized identity key for a term
+func termKey(term Term) string {
+	if l, ok := term.(Literal); ok {
+		return `"` + EscapeString(l.Lexical) + `"@` + strings.ToLower(l.Language) + "^^" + string(l.datatype())
+	}
+	return term.String()
+}
+
+// EscapeString escapes a lexical form for use inside a double-quoted N-Triples or Turtle string
+func EscapeString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// escapeIRI escapes characters that are not allowed inside an IRIREF
+func escapeIRI(s string) string {
+	if !strings.ContainsAny(s, "<>\"{}|^`\\ \t\n\r") {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r <= 0x20, strings.ContainsRune("<>\"{}|^`\\", r):
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
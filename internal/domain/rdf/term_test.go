@@ -0,0 +1,60 @@
+package rdf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+func TestTerm_String(t *testing.T) {
+	t.Run("serializes IRIs in angle brackets", func(t *testing.T) {
+		assert.Equal(t, "<https://example.com/a>", rdf.IRI("https://example.com/a").String())
+	})
+
+	t.Run("serializes blank nodes with the _: prefix", func(t *testing.T) {
+		assert.Equal(t, "_:b0", rdf.BlankNode("b0").String())
+	})
+
+	t.Run("serializes literals with escaping, language and datatype", func(t *testing.T) {
+		assert.Equal(t, `"say \"hi\"\n"`, rdf.NewLiteral("say \"hi\"\n").String())
+		assert.Equal(t, `"hello"@en`, rdf.NewLangLiteral("hello", "en").String())
+		assert.Equal(t, `"1"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+			rdf.NewTypedLiteral("1", rdf.XSDNamespace+"integer").String())
+	})
+}
+
+func TestTerm_Equal(t *testing.T) {
+	t.Run("distinguishes term kinds with the same value", func(t *testing.T) {
+		assert.False(t, rdf.IRI("x").Equal(rdf.BlankNode("x")))
+		assert.False(t, rdf.IRI("x").Equal(rdf.NewLiteral("x")))
+	})
+
+	t.Run("treats an untyped literal as xsd:string", func(t *testing.T) {
+		assert.True(t, rdf.Literal{Lexical: "a"}.Equal(rdf.NewLiteral("a")))
+	})
+
+	t.Run("distinguishes datatypes and languages", func(t *testing.T) {
+		assert.False(t, rdf.NewLiteral("1").Equal(rdf.NewTypedLiteral("1", rdf.XSDNamespace+"integer")))
+		assert.False(t, rdf.NewLangLiteral("a", "en").Equal(rdf.NewLangLiteral("a", "fr")))
+		assert.True(t, rdf.NewLangLiteral("a", "en").Equal(rdf.NewLangLiteral("a", "EN")))
+	})
+}
+
+func TestNewTriple(t *testing.T) {
+	t.Run("rejects literal subjects", func(t *testing.T) {
+		_, err := rdf.NewTriple(rdf.NewLiteral("a"), rdf.RDFType, rdf.IRI("https://example.com/T"))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects non-IRI predicates", func(t *testing.T) {
+		_, err := rdf.NewTriple(rdf.IRI("https://example.com/a"), rdf.BlankNode("p"), rdf.IRI("https://example.com/T"))
+		assert.Error(t, err)
+	})
+
+	t.Run("accepts blank node subjects", func(t *testing.T) {
+		triple, err := rdf.NewTriple(rdf.BlankNode("b"), rdf.RDFType, rdf.IRI("https://example.com/T"))
+		assert.NoError(t, err)
+		assert.Equal(t, "_:b <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://example.com/T> .", triple.String())
+	})
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrBlobNotFound is returned when no blob is stored under the requested key
+	ErrBlobNotFound = errors.New("blob not found")
+
+	// ErrBlobOffset is returned when bytes are appended to a staged blob
+	// anywhere but at its end
+	ErrBlobOffset = errors.New("offset does not match the staged blob")
+
+	// ErrBlobChanged is returned when a conditional write finds the blob
+	// changed since it was read
+	ErrBlobChanged = errors.New("blob changed since it was read")
+)
+
+//go:generate moq -out blob_store_mock.go . BlobStore
+
+// BlobStore keeps the bytes of non-RDF resources outside the event store.
+// Bytes are streamed in and out rather than held in memory.
+type BlobStore interface {
+	// Put stores everything read from r under key and returns the number of
+	// bytes written. A failed Put leaves nothing under key.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+
+	// Get opens the blob stored under key. The caller closes the reader.
+	// It returns ErrBlobNotFound when there is no such blob.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// GetRange opens length bytes of the blob stored under key, starting at
+	// offset. The caller closes the reader.
+	GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error)
+
+	// Append adds everything read from r to the blob staged under key and
+	// returns its size afterwards. offset must be the size of the staged
+	// blob, and 0 starts one; otherwise ErrBlobOffset is returned. Bytes read
+	// before a failure are kept, so an interrupted upload can resume.
+	Append(ctx context.Context, key string, offset int64, r io.Reader) (int64, error)
+
+	// Staged returns the size of the blob staged under key, or
+	// ErrBlobNotFound when none is
+	Staged(ctx context.Context, key string) (int64, error)
+
+	// Commit makes the blob staged under key available to Get. Staged blobs
+	// are never visible before.
+	Commit(ctx context.Context, key string) error
+
+	// Delete removes the blob stored or staged under key. Deleting a missing
+	// blob is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// GetTagged reads the whole blob stored under key with a tag that
+	// changes whenever the blob is replaced. It is meant for small records
+	// that several writers update with PutIf.
+	GetTagged(ctx context.Context, key string) ([]byte, string, error)
+
+	// PutIf stores data under key only when the blob there still has tag,
+	// or, when tag is "", only when there is no blob under key. Otherwise it
+	// returns ErrBlobChanged and the caller reads the blob again.
+	PutIf(ctx context.Context, key string, tag string, data []byte) error
+}
+
+// BlobLinker is implemented by blob stores that can send clients straight
+// to the bytes of a blob, so large downloads bypass the pod
+type BlobLinker interface {
+	// Link returns a temporary URL serving the size bytes of contentType
+	// stored under key, or "" when the blob is better served by the pod
+	Link(ctx context.Context, key string, size int64, contentType string) (string, error)
+}
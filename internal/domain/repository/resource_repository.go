@@ -2,23 +2,57 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"io"
+	"time"
 
 	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
 	"github.com/wepala/vine-pod/internal/domain/entity"
 )
 
+// ErrResourceNotFound is returned when no resource matches the requested ID or URI
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ErrResourceExists is returned when a resource is moved to a URI that is taken
+var ErrResourceExists = errors.New("resource already exists")
+
+// ErrResourceConflict is returned when a resource is saved after another
+// change to it was stored since it was read
+var ErrResourceConflict = errors.New("resource changed since it was read")
+
+// Change is a resource to save as part of a batch. From is set when the
+// resource was assigned a new URI, and names the URI it is stored at.
+// Discard removes the resource and its events instead of saving it, which
+// frees its URI for the rest of the batch.
+type Change struct {
+	Resource entity.Resource
+	From     string
+	Discard  bool
+}
+
 //go:generate moq -out resource_repository_mock.go . ResourceRepository
 
 // ResourceRepository defines the interface for resource persistence operations
 // It works with the Resource domain entity interface and supports event sourcing
 type ResourceRepository interface {
-	// Save persists a resource entity and its uncommitted events
+	// Save persists a resource entity and its uncommitted events. It returns
+	// ErrResourceConflict when events were stored for the resource since it
+	// was read, or a new resource's URI is taken.
 	Save(ctx context.Context, resource entity.Resource) error
 
-	// GetByID retrieves a resource by its ID and reconstructs it from events
+	// SaveAll persists a batch of changes atomically: either every change is
+	// stored or none is. Discarded resources are removed first. A moved
+	// resource takes the events stored at From to its new URI. It returns
+	// ErrResourceExists when that URI is taken, and ErrResourceConflict like
+	// Save.
+	SaveAll(ctx context.Context, changes []Change) error
+
+	// GetByID retrieves a resource by its ID and reconstructs it from events.
+	// It returns ErrResourceNotFound when the resource does not exist.
 	GetByID(ctx context.Context, id string) (entity.Resource, error)
 
-	// GetByURI retrieves a resource by its URI
+	// GetByURI retrieves a resource by its URI.
+	// It returns ErrResourceNotFound when the resource does not exist.
 	GetByURI(ctx context.Context, uri string) (entity.Resource, error)
 
 	// Delete removes a resource by ID
@@ -30,9 +64,33 @@ type ResourceRepository interface {
 	// FindByContainer retrieves all resources in a container
 	FindByContainer(ctx context.Context, containerURI string) ([]entity.Resource, error)
 
+	// FindDeletedBefore retrieves the resources deleted before cutoff and not
+	// restored since, without rebuilding any other resource
+	FindDeletedBefore(ctx context.Context, cutoff time.Time) ([]entity.Resource, error)
+
 	// LoadEvents retrieves all events for a specific resource aggregate
 	LoadEvents(ctx context.Context, aggregateID string) ([]domain.Event, error)
 
 	// LoadEventsFromVersion retrieves events for a resource starting from a specific version
 	LoadEventsFromVersion(ctx context.Context, aggregateID string, version int) ([]domain.Event, error)
 }
+
+// ExternalEdit is a change made to a stored resource from outside the pod,
+// such as a file edited on disk
+type ExternalEdit struct {
+	URI         string
+	ContentType string // Media type of the new representation; empty for a new container without one
+	Removed     bool   // The representation was removed
+	Version     string // Identifies the edited representation, so a rejected edit is not retried until it changes again
+
+	// Open reads the new representation. It is nil when the edit has none.
+	Open func() (io.ReadCloser, error)
+}
+
+// EditSource is implemented by repositories whose resources can be edited
+// from outside the pod
+type EditSource interface {
+	// ExternalEdits returns the resources changed since they were last saved
+	// through the repository
+	ExternalEdits(ctx context.Context) ([]ExternalEdit, error)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
 	"github.com/wepala/vine-pod/internal/domain/entity"
 	"sync"
+	"time"
 )
 
 // Ensure, that ResourceRepositoryMock does implement ResourceRepository.
@@ -26,6 +27,9 @@ var _ ResourceRepository = &ResourceRepositoryMock{}
 //			FindByContainerFunc: func(ctx context.Context, containerURI string) ([]entity.Resource, error) {
 //				panic("mock out the FindByContainer method")
 //			},
+//			FindDeletedBeforeFunc: func(ctx context.Context, cutoff time.Time) ([]entity.Resource, error) {
+//				panic("mock out the FindDeletedBefore method")
+//			},
 //			GetByIDFunc: func(ctx context.Context, id string) (entity.Resource, error) {
 //				panic("mock out the GetByID method")
 //			},
@@ -44,6 +48,9 @@ var _ ResourceRepository = &ResourceRepositoryMock{}
 //			SaveFunc: func(ctx context.Context, resource entity.Resource) error {
 //				panic("mock out the Save method")
 //			},
+//			SaveAllFunc: func(ctx context.Context, changes []Change) error {
+//				panic("mock out the SaveAll method")
+//			},
 //		}
 //
 //		// use mockedResourceRepository in code that requires ResourceRepository
@@ -57,6 +64,9 @@ type ResourceRepositoryMock struct {
 	// FindByContainerFunc mocks the FindByContainer method.
 	FindByContainerFunc func(ctx context.Context, containerURI string) ([]entity.Resource, error)
 
+	// FindDeletedBeforeFunc mocks the FindDeletedBefore method.
+	FindDeletedBeforeFunc func(ctx context.Context, cutoff time.Time) ([]entity.Resource, error)
+
 	// GetByIDFunc mocks the GetByID method.
 	GetByIDFunc func(ctx context.Context, id string) (entity.Resource, error)
 
@@ -75,6 +85,9 @@ type ResourceRepositoryMock struct {
 	// SaveFunc mocks the Save method.
 	SaveFunc func(ctx context.Context, resource entity.Resource) error
 
+	// SaveAllFunc mocks the SaveAll method.
+	SaveAllFunc func(ctx context.Context, changes []Change) error
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// Delete holds details about calls to the Delete method.
@@ -91,6 +104,13 @@ type ResourceRepositoryMock struct {
 			// ContainerURI is the containerURI argument value.
 			ContainerURI string
 		}
+		// FindDeletedBefore holds details about calls to the FindDeletedBefore method.
+		FindDeletedBefore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cutoff is the cutoff argument value.
+			Cutoff time.Time
+		}
 		// GetByID holds details about calls to the GetByID method.
 		GetByID []struct {
 			// Ctx is the ctx argument value.
@@ -137,15 +157,24 @@ type ResourceRepositoryMock struct {
 			// Resource is the resource argument value.
 			Resource entity.Resource
 		}
+		// SaveAll holds details about calls to the SaveAll method.
+		SaveAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Changes is the changes argument value.
+			Changes []Change
+		}
 	}
 	lockDelete                sync.RWMutex
 	lockFindByContainer       sync.RWMutex
+	lockFindDeletedBefore     sync.RWMutex
 	lockGetByID               sync.RWMutex
 	lockGetByURI              sync.RWMutex
 	lockList                  sync.RWMutex
 	lockLoadEvents            sync.RWMutex
 	lockLoadEventsFromVersion sync.RWMutex
 	lockSave                  sync.RWMutex
+	lockSaveAll               sync.RWMutex
 }
 
 // Delete calls DeleteFunc.
@@ -220,6 +249,42 @@ func (mock *ResourceRepositoryMock) FindByContainerCalls() []struct {
 	return calls
 }
 
+// FindDeletedBefore calls FindDeletedBeforeFunc.
+func (mock *ResourceRepositoryMock) FindDeletedBefore(ctx context.Context, cutoff time.Time) ([]entity.Resource, error) {
+	if mock.FindDeletedBeforeFunc == nil {
+		panic("ResourceRepositoryMock.FindDeletedBeforeFunc: method is nil but ResourceRepository.FindDeletedBefore was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Cutoff time.Time
+	}{
+		Ctx:    ctx,
+		Cutoff: cutoff,
+	}
+	mock.lockFindDeletedBefore.Lock()
+	mock.calls.FindDeletedBefore = append(mock.calls.FindDeletedBefore, callInfo)
+	mock.lockFindDeletedBefore.Unlock()
+	return mock.FindDeletedBeforeFunc(ctx, cutoff)
+}
+
+// FindDeletedBeforeCalls gets all the calls that were made to FindDeletedBefore.
+// Check the length with:
+//
+//	len(mockedResourceRepository.FindDeletedBeforeCalls())
+func (mock *ResourceRepositoryMock) FindDeletedBeforeCalls() []struct {
+	Ctx    context.Context
+	Cutoff time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Cutoff time.Time
+	}
+	mock.lockFindDeletedBefore.RLock()
+	calls = mock.calls.FindDeletedBefore
+	mock.lockFindDeletedBefore.RUnlock()
+	return calls
+}
+
 // GetByID calls GetByIDFunc.
 func (mock *ResourceRepositoryMock) GetByID(ctx context.Context, id string) (entity.Resource, error) {
 	if mock.GetByIDFunc == nil {
@@ -443,3 +508,39 @@ func (mock *ResourceRepositoryMock) SaveCalls() []struct {
 	mock.lockSave.RUnlock()
 	return calls
 }
+
+// SaveAll calls SaveAllFunc.
+func (mock *ResourceRepositoryMock) SaveAll(ctx context.Context, changes []Change) error {
+	if mock.SaveAllFunc == nil {
+		panic("ResourceRepositoryMock.SaveAllFunc: method is nil but ResourceRepository.SaveAll was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Changes []Change
+	}{
+		Ctx:     ctx,
+		Changes: changes,
+	}
+	mock.lockSaveAll.Lock()
+	mock.calls.SaveAll = append(mock.calls.SaveAll, callInfo)
+	mock.lockSaveAll.Unlock()
+	return mock.SaveAllFunc(ctx, changes)
+}
+
+// SaveAllCalls gets all the calls that were made to SaveAll.
+// Check the length with:
+//
+//	len(mockedResourceRepository.SaveAllCalls())
+func (mock *ResourceRepositoryMock) SaveAllCalls() []struct {
+	Ctx     context.Context
+	Changes []Change
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Changes []Change
+	}
+	mock.lockSaveAll.RLock()
+	calls = mock.calls.SaveAll
+	mock.lockSaveAll.RUnlock()
+	return calls
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// CanonicalizationService compares RDF representations by meaning rather
+// than by syntax, using RDF Dataset Canonicalization (RDFC-1.0)
+type CanonicalizationService interface {
+	// Canonicalize returns the canonical N-Quads of data in the given format
+	Canonicalize(data string, format string) (string, error)
+
+	// Hash returns the SHA-256 digest of the canonical N-Quads of data
+	Hash(data string, format string) (string, error)
+
+	// Isomorphic reports whether two representations describe the same dataset
+	Isomorphic(a string, aFormat string, b string, bFormat string) (bool, error)
+}
+
+// StandardCanonicalizationService implements CanonicalizationService on top of an RDF parser
+type StandardCanonicalizationService struct {
+	rdfService RDFValidationService
+}
+
+// NewStandardCanonicalizationService creates a canonicalization service that parses with rdfService
+func NewStandardCanonicalizationService(rdfService RDFValidationService) CanonicalizationService {
+	return &StandardCanonicalizationService{rdfService: rdfService}
+}
+
+// Canonicalize returns the canonical N-Quads of data in the given format
+func (s *StandardCanonicalizationService) Canonicalize(data string, format string) (string, error) {
+	canonical, err := s.canonicalize(data, format)
+	if err != nil {
+		return "", err
+	}
+	return canonical.NQuads, nil
+}
+
+// Hash returns the SHA-256 digest of the canonical N-Quads of data
+func (s *StandardCanonicalizationService) Hash(data string, format string) (string, error) {
+	canonical, err := s.canonicalize(data, format)
+	if err != nil {
+		return "", err
+	}
+	return canonical.Hash(), nil
+}
+
+// Isomorphic reports whether two representations describe the same dataset
+func (s *StandardCanonicalizationService) Isomorphic(a string, aFormat string, b string, bFormat string) (bool, error) {
+	ca, err := s.canonicalize(a, aFormat)
+	if err != nil {
+		return false, err
+	}
+	cb, err := s.canonicalize(b, bFormat)
+	if err != nil {
+		return false, err
+	}
+	return ca.NQuads == cb.NQuads, nil
+}
+
+// canonicalize parses data as a dataset and canonicalizes it
+func (s *StandardCanonicalizationService) canonicalize(data string, format string) (rdf.Canonical, error) {
+	dataset, err := s.rdfService.ParseDataset(data, format)
+	if err != nil {
+		return rdf.Canonical{}, err
+	}
+	return rdf.Canonicalize(dataset)
+}
@@ -0,0 +1,78 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestCanonicalizationService(t *testing.T) {
+	canonicalizer := service.NewStandardCanonicalizationService(service.NewStandardRDFValidationService())
+
+	turtle := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+<https://example.com/alice> foaf:name "Alice" ;
+    foaf:knows [ foaf:name "Bob" ] .`
+
+	jsonLD := `{
+		"@id": "https://example.com/alice",
+		"http://xmlns.com/foaf/0.1/knows": {"http://xmlns.com/foaf/0.1/name": "Bob"},
+		"http://xmlns.com/foaf/0.1/name": "Alice"
+	}`
+
+	t.Run("treats equivalent serializations as isomorphic", func(t *testing.T) {
+		// Act
+		same, err := canonicalizer.Isomorphic(turtle, string(service.FormatTurtle), jsonLD, string(service.FormatJSONLD))
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, same)
+	})
+
+	t.Run("detects a changed literal", func(t *testing.T) {
+		// Arrange
+		changed := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+<https://example.com/alice> foaf:name "Alice" ;
+    foaf:knows [ foaf:name "Robert" ] .`
+
+		// Act
+		same, err := canonicalizer.Isomorphic(turtle, string(service.FormatTurtle), changed, string(service.FormatTurtle))
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, same)
+	})
+
+	t.Run("produces the same hash for both serializations", func(t *testing.T) {
+		// Act
+		turtleHash, err := canonicalizer.Hash(turtle, string(service.FormatTurtle))
+		require.NoError(t, err)
+		jsonLDHash, err := canonicalizer.Hash(jsonLD, string(service.FormatJSONLD))
+		require.NoError(t, err)
+
+		// Assert
+		assert.Len(t, turtleHash, 64)
+		assert.Equal(t, turtleHash, jsonLDHash)
+	})
+
+	t.Run("writes canonical N-Quads", func(t *testing.T) {
+		// Act
+		nquads, err := canonicalizer.Canonicalize(turtle, string(service.FormatTurtle))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, `<https://example.com/alice> <http://xmlns.com/foaf/0.1/knows> _:c14n0 .
+<https://example.com/alice> <http://xmlns.com/foaf/0.1/name> "Alice" .
+_:c14n0 <http://xmlns.com/foaf/0.1/name> "Bob" .
+`, nquads)
+	})
+
+	t.Run("returns parse errors", func(t *testing.T) {
+		// Act
+		_, err := canonicalizer.Hash("not turtle <", string(service.FormatTurtle))
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// IdentityStrategy selects the IRI that identifies the resource a document
+// describes
+type IdentityStrategy string
+
+const (
+	// IdentityTargetURI identifies a document by the URI it is stored at.
+	// Without a target URI, JSON-LD uses its top-level @id, RDF/XML the
+	// rdf:about of its first node and other formats their only IRI subject.
+	IdentityTargetURI IdentityStrategy = "target"
+
+	// IdentityDocumentSubject uses the document URI when it is a subject,
+	// or else the only subject that is a fragment of it
+	IdentityDocumentSubject IdentityStrategy = "document"
+
+	// IdentityPrimaryTopic uses the foaf:primaryTopic of the document
+	IdentityPrimaryTopic IdentityStrategy = "primary-topic"
+
+	// IdentitySubject uses the only IRI subject of the document
+	IdentitySubject IdentityStrategy = "subject"
+)
+
+const foafPrimaryTopic rdf.IRI = "http://xmlns.com/foaf/0.1/primaryTopic"
+
+// ErrAmbiguousIdentity is wrapped by errors for documents that could
+// describe more than one resource
+var ErrAmbiguousIdentity = errors.New("ambiguous resource identity")
+
+// ParseIdentityStrategy returns the strategy with the given name; an empty
+// name selects IdentityTargetURI
+func ParseIdentityStrategy(name string) (IdentityStrategy, error) {
+	switch strategy := IdentityStrategy(name); strategy {
+	case "":
+		return IdentityTargetURI, nil
+	case IdentityTargetURI, IdentityDocumentSubject, IdentityPrimaryTopic, IdentitySubject:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown identity strategy %q", name)
+	}
+}
+
+// IdentityError reports a document that the identity strategy matches to
+// more than one IRI
+type IdentityError struct {
+	Strategy   IdentityStrategy
+	Candidates []string
+}
+
+func (e *IdentityError) Error() string {
+	return fmt.Sprintf("the %s identity strategy matches %d resources: <%s>",
+		e.Strategy, len(e.Candidates), strings.Join(e.Candidates, ">, <"))
+}
+
+func (e *IdentityError) Unwrap() error {
+	return ErrAmbiguousIdentity
+}
+
+// identify returns the IRI that identifies the resource graph describes,
+// using the strategy with the document at base
+func identify(graph *rdf.Graph, strategy IdentityStrategy, base string, format RDFFormat, noSubject string) (string, error) {
+	document := base
+	if i := strings.Index(document, "#"); i >= 0 {
+		document = document[:i]
+	}
+
+	if strategy == IdentityTargetURI || strategy == "" {
+		if base != "" {
+			return base, nil
+		}
+		strategy = IdentitySubject
+	}
+
+	candidates := make(map[string]bool)
+	switch strategy {
+	case IdentityDocumentSubject:
+		if document == "" {
+			return "", NewValidationError(format, "Document identity requires a target URI", nil)
+		}
+		for _, triple := range graph.Triples() {
+			subject := triple.Subject.Value()
+			if triple.Subject.Kind() != rdf.KindIRI {
+				continue
+			}
+			if subject == document {
+				return document, nil
+			}
+			if strings.HasPrefix(subject, document+"#") {
+				candidates[subject] = true
+			}
+		}
+		noSubject = "No subject matches the document URI"
+	case IdentityPrimaryTopic:
+		for _, triple := range graph.Match(nil, foafPrimaryTopic, nil) {
+			if document != "" && triple.Subject.Value() != document {
+				continue
+			}
+			if triple.Object.Kind() == rdf.KindIRI {
+				candidates[triple.Object.Value()] = true
+			}
+		}
+		noSubject = "No foaf:primaryTopic found"
+	default:
+		for _, triple := range graph.Triples() {
+			if triple.Subject.Kind() == rdf.KindIRI {
+				candidates[triple.Subject.Value()] = true
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", NewValidationError(format, noSubject, nil)
+	case 1:
+		for candidate := range candidates {
+			return candidate, nil
+		}
+	}
+
+	sorted := make([]string, 0, len(candidates))
+	for candidate := range candidates {
+		sorted = append(sorted, candidate)
+	}
+	sort.Strings(sorted)
+	cause := &IdentityError{Strategy: strategy, Candidates: sorted}
+	return "", NewValidationError(format, cause.Error(), cause)
+}
@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestStandardRDFValidationService_Identity(t *testing.T) {
+	const document = "https://pod.example.com/profile/card"
+	newService := func(strategy service.IdentityStrategy) service.RDFValidationService {
+		return service.NewStandardRDFValidationServiceWithOptions(service.RDFServiceOptions{Identity: strategy}).WithBase(document)
+	}
+	profile := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+<> foaf:primaryTopic <#me> .
+<#me> foaf:name "Alice" ; foaf:knows <https://bob.example.com/profile/card#me> .
+<https://bob.example.com/profile/card#me> foaf:name "Bob" .`
+
+	t.Run("identifies documents by the target URI by default", func(t *testing.T) {
+		// Act
+		resourceID, err := service.NewStandardRDFValidationService().WithBase(document).ValidateTurtle(profile)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, document, resourceID)
+	})
+
+	t.Run("selects the resource with the configured strategy", func(t *testing.T) {
+		// Act
+		documentID, documentErr := newService(service.IdentityDocumentSubject).ValidateTurtle(`<#me> <http://xmlns.com/foaf/0.1/name> "Alice" .
+<https://bob.example.com/profile/card#me> <http://xmlns.com/foaf/0.1/name> "Bob" .`)
+		topicID, topicErr := newService(service.IdentityPrimaryTopic).ValidateTurtle(profile)
+
+		// Assert
+		require.NoError(t, documentErr)
+		require.NoError(t, topicErr)
+		assert.Equal(t, document+"#me", documentID)
+		assert.Equal(t, document+"#me", topicID)
+	})
+
+	t.Run("rejects ambiguous documents", func(t *testing.T) {
+		// Act
+		_, err := newService(service.IdentitySubject).ValidateTurtle(profile)
+
+		// Assert
+		var identityErr *service.IdentityError
+		require.ErrorAs(t, err, &identityErr)
+		assert.ErrorIs(t, err, service.ErrAmbiguousIdentity)
+		assert.Equal(t, []string{"https://bob.example.com/profile/card#me", document, document + "#me"}, identityErr.Candidates)
+	})
+
+	t.Run("falls back to the only subject without a target URI", func(t *testing.T) {
+		// Arrange
+		rdfService := service.NewStandardRDFValidationService()
+
+		// Act
+		_, ambiguous := rdfService.ValidateNTriples("<https://example.com/a> <https://example.com/p> <https://example.com/b> .\n" +
+			"<https://example.com/b> <https://example.com/p> <https://example.com/a> .\n")
+
+		// Assert
+		assert.ErrorIs(t, ambiguous, service.ErrAmbiguousIdentity)
+	})
+
+	t.Run("rejects unknown strategy names", func(t *testing.T) {
+		// Act
+		strategy, err := service.ParseIdentityStrategy("")
+		_, unknown := service.ParseIdentityStrategy("first")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, service.IdentityTargetURI, strategy)
+		assert.Error(t, unknown)
+	})
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"bytes"
+	"container/list"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+//go:embed contexts/*.jsonld
+var bundledContextFiles embed.FS
+
+// bundledContexts maps well-known context URLs to the copies shipped with the server
+var bundledContexts = map[string]string{
+	"http://schema.org":                                      "schema.org.jsonld",
+	"http://schema.org/":                                     "schema.org.jsonld",
+	"https://schema.org":                                     "schema.org.jsonld",
+	"https://schema.org/":                                    "schema.org.jsonld",
+	"https://schema.org/docs/jsonldcontext.json":             "schema.org.jsonld",
+	"https://schema.org/docs/jsonldcontext.jsonld":           "schema.org.jsonld",
+	"https://www.w3.org/ns/activitystreams":                  "activitystreams.jsonld",
+	"https://www.w3.org/ns/activitystreams.jsonld":           "activitystreams.jsonld",
+	"http://www.w3.org/ns/activitystreams":                   "activitystreams.jsonld",
+	"http://www.w3.org/ns/activitystreams.jsonld":            "activitystreams.jsonld",
+	"https://w3id.org/security/v1":                           "security-v1.jsonld",
+	"https://w3id.org/security/v1.jsonld":                    "security-v1.jsonld",
+	"https://www.w3.org/ns/solid/oidc-context.jsonld":        "solid-oidc.jsonld",
+	"http://www.w3.org/ns/solid/oidc-context.jsonld":         "solid-oidc.jsonld",
+	"https://solid.github.io/solid-oidc/oidc-context.jsonld": "solid-oidc.jsonld",
+	"http://www.w3.org/ns/shex.jsonld":                       "shex.jsonld",
+	"https://www.w3.org/ns/shex.jsonld":                      "shex.jsonld",
+}
+
+const (
+	defaultContextCacheSize        = 64
+	defaultContextMaxDocumentBytes = 1 << 20
+)
+
+// ContextLoaderOptions configures a ContextDocumentLoader
+type ContextLoaderOptions struct {
+	// Offline rejects any context that is neither bundled nor registered
+	Offline bool
+
+	// ManifestPath is a JSON file mapping context URLs to local files,
+	// resolved relative to the manifest, e.g. {"https://example.com/ctx": "ctx.jsonld"}
+	ManifestPath string
+
+	// CacheSize is the number of remotely fetched contexts kept in memory (default 64)
+	CacheSize int
+
+	// MaxDocumentBytes limits the size of a fetched or registered context (default 1 MiB)
+	MaxDocumentBytes int64
+
+	// HTTPClient is used for remote fetches when not offline
+	HTTPClient *http.Client
+}
+
+// ContextDocumentLoader resolves JSON-LD contexts from bundled copies,
+// operator-registered files and, unless offline, the network with an LRU cache
+type ContextDocumentLoader struct {
+	offline    bool
+	maxBytes   int64
+	httpClient *http.Client
+
+	mu         sync.Mutex
+	registered map[string]interface{}
+	cacheSize  int
+	cache      map[string]*list.Element
+	lru        *list.List
+}
+
+// contextCacheEntry is a fetched document held in the LRU cache
+type contextCacheEntry struct {
+	url      string
+	document interface{}
+}
+
+// NewContextDocumentLoader creates a document loader with the bundled contexts
+// and any contexts listed in the configured manifest
+func NewContextDocumentLoader(options ContextLoaderOptions) (*ContextDocumentLoader, error) {
+	loader := &ContextDocumentLoader{
+		offline:    options.Offline,
+		maxBytes:   options.MaxDocumentBytes,
+		httpClient: options.HTTPClient,
+		registered: make(map[string]interface{}),
+		cacheSize:  options.CacheSize,
+		cache:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+	if loader.maxBytes <= 0 {
+		loader.maxBytes = defaultContextMaxDocumentBytes
+	}
+	if loader.cacheSize <= 0 {
+		loader.cacheSize = defaultContextCacheSize
+	}
+	if loader.httpClient == nil {
+		loader.httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if options.ManifestPath != "" {
+		if err := loader.RegisterManifest(options.ManifestPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return loader, nil
+}
+
+// NewOfflineContextDocumentLoader creates a loader that only serves bundled contexts
+func NewOfflineContextDocumentLoader() *ContextDocumentLoader {
+	loader, _ := NewContextDocumentLoader(ContextLoaderOptions{Offline: true})
+	return loader
+}
+
+// RegisterManifest registers every context listed in a manifest file
+func (l *ContextDocumentLoader) RegisterManifest(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read context manifest: %w", err)
+	}
+
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("invalid context manifest %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for url, file := range entries {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if err := l.RegisterFile(url, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterFile serves the context document in file for url
+func (l *ContextDocumentLoader) RegisterFile(url string, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open context %s: %w", url, err)
+	}
+	defer f.Close()
+
+	document, err := l.readDocument(f)
+	if err != nil {
+		return fmt.Errorf("failed to load context %s from %s: %w", url, file, err)
+	}
+
+	l.Register(url, document)
+	return nil
+}
+
+// Register serves an already parsed context document for url
+func (l *ContextDocumentLoader) Register(url string, document interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.registered[normalizeContextURL(url)] = document
+}
+
+// LoadDocument implements ld.DocumentLoader
+func (l *ContextDocumentLoader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	url := normalizeContextURL(u)
+
+	if document, ok := l.lookup(url); ok {
+		return &ld.RemoteDocument{DocumentURL: url, Document: document}, nil
+	}
+
+	if file, ok := bundledContexts[url]; ok {
+		document, err := loadBundledContext(file)
+		if err != nil {
+			return nil, ld.NewJsonLdError(ld.LoadingDocumentFailed, err)
+		}
+		return &ld.RemoteDocument{DocumentURL: url, Document: document}, nil
+	}
+
+	if l.offline {
+		return nil, ld.NewJsonLdError(ld.LoadingDocumentFailed,
+			fmt.Sprintf("context %s is not available offline", url))
+	}
+
+	document, err := l.fetch(url)
+	if err != nil {
+		return nil, ld.NewJsonLdError(ld.LoadingDocumentFailed, err)
+	}
+	l.store(url, document)
+
+	return &ld.RemoteDocument{DocumentURL: url, Document: document}, nil
+}
+
+// lookup returns a registered or cached document, marking cache hits as recently used
+func (l *ContextDocumentLoader) lookup(url string) (interface{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if document, ok := l.registered[url]; ok {
+		return document, true
+	}
+	if element, ok := l.cache[url]; ok {
+		l.lru.MoveToFront(element)
+		return element.Value.(*contextCacheEntry).document, true
+	}
+	return nil, false
+}
+
+// store adds a fetched document to the cache, evicting the least recently used one when full
+func (l *ContextDocumentLoader) store(url string, document interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.cache[url]; ok {
+		element.Value.(*contextCacheEntry).document = document
+		l.lru.MoveToFront(element)
+		return
+	}
+	l.cache[url] = l.lru.PushFront(&contextCacheEntry{url: url, document: document})
+	for l.lru.Len() > l.cacheSize {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.cache, oldest.Value.(*contextCacheEntry).url)
+	}
+}
+
+// CachedCount returns the number of remotely fetched contexts held in the cache
+func (l *ContextDocumentLoader) CachedCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lru.Len()
+}
+
+// fetch downloads a context document over HTTP
+func (l *ContextDocumentLoader) fetch(url string) (interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/ld+json, application/json;q=0.9")
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s returned status %d", url, resp.StatusCode)
+	}
+
+	return l.readDocument(resp.Body)
+}
+
+// readDocument parses a JSON document no larger than the configured limit
+func (l *ContextDocumentLoader) readDocument(r io.Reader) (interface{}, error) {
+	data, err := io.ReadAll(io.LimitReader(r, l.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > l.maxBytes {
+		return nil, fmt.Errorf("context document exceeds %d bytes", l.maxBytes)
+	}
+	return ld.DocumentFromReader(bytes.NewReader(data))
+}
+
+// loadBundledContext parses one of the embedded context files
+func loadBundledContext(file string) (interface{}, error) {
+	data, err := bundledContextFiles.ReadFile("contexts/" + file)
+	if err != nil {
+		return nil, err
+	}
+	return ld.DocumentFromReader(bytes.NewReader(data))
+}
+
+// normalizeContextURL strips the fragment from a context URL
+func normalizeContextURL(url string) string {
+	if i := strings.IndexByte(url, '#'); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
@@ -0,0 +1,172 @@
+package service_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestContextDocumentLoader(t *testing.T) {
+	t.Run("resolves every bundled context offline", func(t *testing.T) {
+		// Arrange
+		rdfService := service.NewStandardRDFValidationService()
+		contexts := []string{
+			"https://schema.org/",
+			"https://www.w3.org/ns/activitystreams",
+			"https://w3id.org/security/v1",
+			"https://www.w3.org/ns/solid/oidc-context.jsonld",
+		}
+
+		for _, context := range contexts {
+			document := fmt.Sprintf(`{"@context": %q, "@id": "https://example.com/thing"}`, context)
+
+			// Act
+			id, err := rdfService.ValidateJSONLD(document)
+
+			// Assert
+			require.NoError(t, err, context)
+			assert.Equal(t, "https://example.com/thing", id)
+		}
+	})
+
+	t.Run("expands an ActivityStreams document without network access", func(t *testing.T) {
+		// Arrange
+		rdfService := service.NewStandardRDFValidationService()
+		note := `{
+			"@context": "https://www.w3.org/ns/activitystreams",
+			"id": "https://example.com/notes/1",
+			"type": "Note",
+			"content": "Hello"
+		}`
+
+		// Act
+		graph, err := rdfService.ParseGraph(note, string(service.FormatJSONLD))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, graph.Match(
+			rdf.IRI("https://example.com/notes/1"),
+			rdf.RDFType,
+			rdf.IRI("https://www.w3.org/ns/activitystreams#Note"),
+		), 1)
+	})
+
+	t.Run("rejects unknown remote contexts when offline", func(t *testing.T) {
+		// Arrange
+		rdfService := service.NewStandardRDFValidationService()
+		document := `{"@context": "https://unknown.example.com/context.jsonld", "@id": "https://example.com/thing"}`
+
+		// Act
+		_, err := rdfService.ValidateJSONLD(document)
+
+		// Assert
+		var validationErr *service.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Contains(t, validationErr.Cause.Error(), "not available offline")
+	})
+
+	t.Run("serves contexts registered through a manifest", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "example.jsonld"),
+			[]byte(`{"@context": {"ex": "https://example.com/ns#", "label": "ex:label"}}`), 0o644))
+		manifest := filepath.Join(dir, "contexts.json")
+		require.NoError(t, os.WriteFile(manifest,
+			[]byte(`{"https://example.com/context.jsonld": "example.jsonld"}`), 0o644))
+
+		loader, err := service.NewContextDocumentLoader(service.ContextLoaderOptions{Offline: true, ManifestPath: manifest})
+		require.NoError(t, err)
+		rdfService := service.NewStandardRDFValidationServiceWithOptions(service.RDFServiceOptions{DocumentLoader: loader})
+
+		// Act
+		graph, err := rdfService.ParseGraph(
+			`{"@context": "https://example.com/context.jsonld", "@id": "https://example.com/thing", "label": "Thing"}`,
+			string(service.FormatJSONLD))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, graph.Len())
+		assert.Len(t, graph.Match(nil, rdf.IRI("https://example.com/ns#label"), rdf.NewLiteral("Thing")), 1)
+	})
+
+	t.Run("fails when a manifest entry cannot be read", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		manifest := filepath.Join(dir, "contexts.json")
+		require.NoError(t, os.WriteFile(manifest, []byte(`{"https://example.com/context.jsonld": "missing.jsonld"}`), 0o644))
+
+		// Act
+		_, err := service.NewContextDocumentLoader(service.ContextLoaderOptions{ManifestPath: manifest})
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("caches remote contexts with least recently used eviction", func(t *testing.T) {
+		// Arrange
+		fetches := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches++
+			w.Header().Set("Content-Type", "application/ld+json")
+			fmt.Fprint(w, `{"@context": {"name": "http://xmlns.com/foaf/0.1/name"}}`)
+		}))
+		defer server.Close()
+
+		loader, err := service.NewContextDocumentLoader(service.ContextLoaderOptions{CacheSize: 2})
+		require.NoError(t, err)
+
+		// Act
+		for _, path := range []string{"/a", "/b", "/a", "/c", "/a", "/b"} {
+			_, err := loader.LoadDocument(server.URL + path)
+			require.NoError(t, err)
+		}
+
+		// Assert
+		// /a stays cached because it is used again before each eviction; /b is evicted by /c
+		assert.Equal(t, 4, fetches)
+		assert.Equal(t, 2, loader.CachedCount())
+	})
+
+	t.Run("rejects remote contexts larger than the size limit", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"@context": {"name": %q}}`, strings.Repeat("x", 200))
+		}))
+		defer server.Close()
+
+		loader, err := service.NewContextDocumentLoader(service.ContextLoaderOptions{MaxDocumentBytes: 100})
+		require.NoError(t, err)
+
+		// Act
+		_, err = loader.LoadDocument(server.URL)
+
+		// Assert
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds 100 bytes")
+	})
+
+	t.Run("compacts output with a bundled context requested by profile", func(t *testing.T) {
+		// Arrange
+		rdfService := service.NewStandardRDFValidationService()
+		graph, err := rdfService.ParseGraph(
+			`<https://example.com/notes/1> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://www.w3.org/ns/activitystreams#Note> .`,
+			string(service.FormatNTriples))
+		require.NoError(t, err)
+
+		// Act
+		result, err := rdfService.SerializeGraph(graph, `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Contains(t, result, `"type": "Note"`)
+	})
+}
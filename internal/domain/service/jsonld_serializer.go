@@ -0,0 +1,160 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/piprate/json-gold/ld"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// JSONLDForm identifies a JSON-LD document form, using the profile IRIs
+// defined by the JSON-LD specification
+type JSONLDForm string
+
+const (
+	JSONLDExpanded  JSONLDForm = "http://www.w3.org/ns/json-ld#expanded"
+	JSONLDCompacted JSONLDForm = "http://www.w3.org/ns/json-ld#compacted"
+	JSONLDFlattened JSONLDForm = "http://www.w3.org/ns/json-ld#flattened"
+	JSONLDFramed    JSONLDForm = "http://www.w3.org/ns/json-ld#framed"
+)
+
+// JSONLDOutputOptions controls the shape of serialized JSON-LD
+type JSONLDOutputOptions struct {
+	// Form selects expanded, compacted, flattened or framed output (default expanded)
+	Form JSONLDForm
+
+	// Context is a context IRI or an inline context document used for
+	// compacted, flattened and framed output
+	Context interface{}
+
+	// Frame is a frame IRI or an inline frame document, required for framed output
+	Frame interface{}
+}
+
+// JSONLDOutputOptionsFromMediaType reads the profile parameter of a JSON-LD
+// media type such as `application/ld+json; profile="http://www.w3.org/ns/json-ld#compacted https://www.w3.org/ns/activitystreams"`.
+// Profile IRIs outside the JSON-LD namespace are taken as the frame for framed
+// output and as the context otherwise; a bare context IRI implies compaction.
+func JSONLDOutputOptionsFromMediaType(mediaType string) (JSONLDOutputOptions, error) {
+	options := JSONLDOutputOptions{}
+
+	base, params, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return options, fmt.Errorf("invalid media type %q: %w", mediaType, err)
+	}
+	if base != string(FormatJSONLD) {
+		return options, fmt.Errorf("media type %q is not JSON-LD", base)
+	}
+
+	var others []string
+	for _, profile := range strings.Fields(params["profile"]) {
+		switch JSONLDForm(profile) {
+		case JSONLDExpanded, JSONLDCompacted, JSONLDFlattened, JSONLDFramed:
+			options.Form = JSONLDForm(profile)
+		default:
+			others = append(others, profile)
+		}
+	}
+
+	if len(others) > 0 {
+		if options.Form == JSONLDFramed {
+			options.Frame = others[0]
+		} else {
+			if options.Form == "" {
+				options.Form = JSONLDCompacted
+			}
+			options.Context = others[0]
+		}
+	}
+
+	return options, nil
+}
+
+// SerializeJSONLD serializes a graph as JSON-LD in the requested form
+func (s *StandardRDFValidationService) SerializeJSONLD(graph *rdf.Graph, options JSONLDOutputOptions) (string, error) {
+	return s.serializeJSONLDDataset(rdf.NewDatasetFromGraph(graph), options)
+}
+
+// serializeJSONLDDataset serializes a dataset as JSON-LD; named graphs become
+// nodes with their own @graph
+func (s *StandardRDFValidationService) serializeJSONLDDataset(dataset *rdf.Dataset, options JSONLDOutputOptions) (string, error) {
+	opts := s.jsonLDOptions()
+
+	expanded, err := ld.NewJsonLdApi().FromRDF(toJSONLDDataset(dataset), opts)
+	if err != nil {
+		return "", NewValidationError(FormatJSONLD, "RDF to JSON-LD conversion failed", err)
+	}
+
+	var document interface{}
+	switch options.Form {
+	case "", JSONLDExpanded:
+		document = map[string]interface{}{"@graph": expanded}
+	case JSONLDCompacted:
+		document, err = s.jsonLDProcessor.Compact(expanded, jsonLDContextOrEmpty(options.Context), opts)
+	case JSONLDFlattened:
+		document, err = s.jsonLDProcessor.Flatten(expanded, jsonLDContextOrEmpty(options.Context), opts)
+	case JSONLDFramed:
+		document, err = s.frameJSONLD(expanded, options, opts)
+	default:
+		return "", fmt.Errorf("unsupported JSON-LD form: %s", options.Form)
+	}
+	if err != nil {
+		return "", NewValidationError(FormatJSONLD, fmt.Sprintf("JSON-LD %s output failed", jsonLDFormName(options.Form)), err)
+	}
+
+	result, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+// frameJSONLD applies a frame, injecting the requested context when the frame has none
+func (s *StandardRDFValidationService) frameJSONLD(expanded []interface{}, options JSONLDOutputOptions, opts *ld.JsonLdOptions) (interface{}, error) {
+	if options.Frame == nil {
+		return nil, fmt.Errorf("framed output requires a frame")
+	}
+
+	frame := options.Frame
+	if frameMap, ok := frame.(map[string]interface{}); ok && options.Context != nil {
+		if _, hasContext := frameMap["@context"]; !hasContext {
+			withContext := make(map[string]interface{}, len(frameMap)+1)
+			for k, v := range frameMap {
+				withContext[k] = v
+			}
+			withContext["@context"] = options.Context
+			frame = withContext
+		}
+	}
+
+	// JSON-LD 1.1 omits the top-level @graph when a single node matches the frame
+	framingOpts := opts.Copy()
+	framingOpts.OmitGraph = true
+
+	return s.jsonLDProcessor.Frame(expanded, frame, framingOpts)
+}
+
+// jsonLDOptions returns the processor options shared by all JSON-LD operations
+func (s *StandardRDFValidationService) jsonLDOptions() *ld.JsonLdOptions {
+	opts := ld.NewJsonLdOptions(s.base)
+	opts.DocumentLoader = s.documentLoader
+	return opts
+}
+
+// jsonLDContextOrEmpty returns the context, or an empty context when none was requested
+func jsonLDContextOrEmpty(context interface{}) interface{} {
+	if context == nil {
+		return map[string]interface{}{}
+	}
+	return context
+}
+
+// jsonLDFormName returns the short name of a JSON-LD form for error messages
+func jsonLDFormName(form JSONLDForm) string {
+	return strings.TrimPrefix(string(form), "http://www.w3.org/ns/json-ld#")
+}
@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestJSONLDSerialization(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService()
+
+	turtle := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+
+<https://example.com/alice> a foaf:Person ;
+    foaf:name "Alice" ;
+    foaf:knows <https://example.com/bob>, <https://example.com/carol> .
+<https://example.com/bob> a foaf:Person ;
+    foaf:name "Bob" .`
+
+	graph, err := rdfService.ParseGraph(turtle, string(service.FormatTurtle))
+	require.NoError(t, err)
+
+	context := map[string]interface{}{
+		"foaf":  "http://xmlns.com/foaf/0.1/",
+		"name":  "foaf:name",
+		"knows": map[string]interface{}{"@id": "foaf:knows", "@type": "@id"},
+	}
+
+	t.Run("writes every subject and repeated predicate in an expanded @graph", func(t *testing.T) {
+		// Act
+		result, err := rdfService.SerializeJSONLD(graph, service.JSONLDOutputOptions{})
+		require.NoError(t, err)
+
+		// Assert
+		var document map[string][]map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(result), &document))
+		assert.Len(t, document["@graph"], 2)
+		for _, node := range document["@graph"] {
+			if node["@id"] == "https://example.com/alice" {
+				assert.Len(t, node["http://xmlns.com/foaf/0.1/knows"], 2)
+			}
+		}
+
+		roundTripped, err := rdfService.ParseGraph(result, string(service.FormatJSONLD))
+		require.NoError(t, err)
+		assert.True(t, graph.Equal(roundTripped))
+	})
+
+	t.Run("compacts against a requested context", func(t *testing.T) {
+		// Act
+		result, err := rdfService.SerializeJSONLD(graph, service.JSONLDOutputOptions{
+			Form:    service.JSONLDCompacted,
+			Context: context,
+		})
+		require.NoError(t, err)
+
+		// Assert
+		var document map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(result), &document))
+		assert.Contains(t, document, "@context")
+		nodes, ok := document["@graph"].([]interface{})
+		require.True(t, ok)
+		assert.Len(t, nodes, 2)
+		assert.Contains(t, result, `"name": "Alice"`)
+		assert.Contains(t, result, `"knows": [`)
+
+		roundTripped, err := rdfService.ParseGraph(result, string(service.FormatJSONLD))
+		require.NoError(t, err)
+		assert.True(t, graph.Equal(roundTripped))
+	})
+
+	t.Run("frames output around matching nodes", func(t *testing.T) {
+		// Act
+		result, err := rdfService.SerializeJSONLD(graph, service.JSONLDOutputOptions{
+			Form:    service.JSONLDFramed,
+			Context: context,
+			Frame: map[string]interface{}{
+				"@id": "https://example.com/alice",
+			},
+		})
+		require.NoError(t, err)
+
+		// Assert
+		var document map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(result), &document))
+		assert.Equal(t, "https://example.com/alice", document["@id"])
+		assert.Equal(t, "Alice", document["name"])
+		knows, ok := document["knows"].([]interface{})
+		require.True(t, ok)
+		assert.Len(t, knows, 2)
+	})
+
+	t.Run("requires a frame for framed output", func(t *testing.T) {
+		_, err := rdfService.SerializeJSONLD(graph, service.JSONLDOutputOptions{Form: service.JSONLDFramed})
+		assert.Error(t, err)
+	})
+}
+
+func TestJSONLDOutputOptionsFromMediaType(t *testing.T) {
+	t.Run("defaults to expanded output", func(t *testing.T) {
+		options, err := service.JSONLDOutputOptionsFromMediaType("application/ld+json")
+		assert.NoError(t, err)
+		assert.Equal(t, service.JSONLDOutputOptions{}, options)
+	})
+
+	t.Run("reads the form and context from the profile", func(t *testing.T) {
+		options, err := service.JSONLDOutputOptionsFromMediaType(
+			`application/ld+json; profile="http://www.w3.org/ns/json-ld#compacted https://www.w3.org/ns/activitystreams"`)
+		assert.NoError(t, err)
+		assert.Equal(t, service.JSONLDCompacted, options.Form)
+		assert.Equal(t, "https://www.w3.org/ns/activitystreams", options.Context)
+	})
+
+	t.Run("treats a bare context IRI as a compaction request", func(t *testing.T) {
+		options, err := service.JSONLDOutputOptionsFromMediaType(`application/ld+json; profile="https://schema.org/"`)
+		assert.NoError(t, err)
+		assert.Equal(t, service.JSONLDCompacted, options.Form)
+		assert.Equal(t, "https://schema.org/", options.Context)
+	})
+
+	t.Run("reads the frame IRI for framed output", func(t *testing.T) {
+		options, err := service.JSONLDOutputOptionsFromMediaType(
+			`application/ld+json; profile="http://www.w3.org/ns/json-ld#framed https://example.com/frame.jsonld"`)
+		assert.NoError(t, err)
+		assert.Equal(t, service.JSONLDFramed, options.Form)
+		assert.Equal(t, "https://example.com/frame.jsonld", options.Frame)
+	})
+
+	t.Run("rejects other media types", func(t *testing.T) {
+		_, err := service.JSONLDOutputOptionsFromMediaType("text/turtle")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// Names of the limits reported by LimitError
+const (
+	LimitBytes            = "size"
+	LimitTriples          = "triple count"
+	LimitJSONDepth        = "JSON nesting depth"
+	LimitEntityExpansions = "XML entity expansions"
+	LimitLiteralLength    = "literal length"
+)
+
+var (
+	// ErrInputTooLarge is wrapped by errors for documents over the size limit
+	ErrInputTooLarge = errors.New("input is too large")
+
+	// ErrInputTooComplex is wrapped by errors for documents over any other limit
+	ErrInputTooComplex = errors.New("input is too complex")
+)
+
+// ParseLimits bound the resources a single document may use while it is
+// parsed. A zero field disables that limit.
+type ParseLimits struct {
+	MaxBytes            int64 // size of the serialized document
+	MaxTriples          int   // triples or quads in the document
+	MaxJSONDepth        int   // nesting of JSON-LD objects and arrays
+	MaxEntityExpansions int   // expansions of entities declared in an RDF/XML DTD
+	MaxLiteralLength    int   // bytes in the lexical form of a literal
+}
+
+// DefaultParseLimits returns the limits used when none are configured
+func DefaultParseLimits() ParseLimits {
+	return ParseLimits{
+		MaxBytes:            16 << 20,
+		MaxTriples:          1_000_000,
+		MaxJSONDepth:        128,
+		MaxEntityExpansions: 1_000,
+		MaxLiteralLength:    1 << 20,
+	}
+}
+
+// LimitError reports a document that exceeds one of the parse limits. It
+// wraps ErrInputTooLarge for the size limit and ErrInputTooComplex otherwise.
+type LimitError struct {
+	Limit string
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s exceeds the limit of %d", e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	if e.Limit == LimitBytes {
+		return ErrInputTooLarge
+	}
+	return ErrInputTooComplex
+}
+
+// limitExceeded returns the validation error for a breached limit
+func limitExceeded(format RDFFormat, limit string, max int64) *ValidationError {
+	cause := &LimitError{Limit: limit, Max: max}
+	return NewValidationError(format, cause.Error(), cause)
+}
+
+// limitedReader fails once more than max bytes have been read. The error is
+// kept so it can be reported even when a parser replaces it with its own.
+type limitedReader struct {
+	r         io.Reader
+	format    RDFFormat
+	max       int64
+	remaining int64
+	err       error
+}
+
+// newLimitedReader wraps r so that reading past max bytes fails; a max of
+// zero reads without limit
+func newLimitedReader(r io.Reader, format RDFFormat, max int64) *limitedReader {
+	return &limitedReader{r: r, format: format, max: max, remaining: max}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.max <= 0 {
+		return l.r.Read(p)
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		l.err = limitExceeded(l.format, LimitBytes, l.max)
+		return int(l.remaining), l.err
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// tripleLimiter counts the triples of a document and checks their literals
+type tripleLimiter struct {
+	limits  ParseLimits
+	format  RDFFormat
+	triples int
+}
+
+// check accounts for one more triple; a nil limiter accepts everything
+func (c *tripleLimiter) check(t rdf.Triple) error {
+	if c == nil {
+		return nil
+	}
+	c.triples++
+	if c.limits.MaxTriples > 0 && c.triples > c.limits.MaxTriples {
+		return limitExceeded(c.format, LimitTriples, int64(c.limits.MaxTriples))
+	}
+	if literal, ok := t.Object.(rdf.Literal); ok && c.limits.MaxLiteralLength > 0 && len(literal.Lexical) > c.limits.MaxLiteralLength {
+		return limitExceeded(c.format, LimitLiteralLength, int64(c.limits.MaxLiteralLength))
+	}
+	return nil
+}
+
+// decodeJSONValue reads one JSON value, failing as soon as objects and
+// arrays nest deeper than max
+func decodeJSONValue(decoder *json.Decoder, depth int, max int) (interface{}, error) {
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	if max > 0 && depth >= max {
+		return nil, limitExceeded(FormatJSONLD, LimitJSONDepth, int64(max))
+	}
+
+	var value interface{}
+	switch delim {
+	case '{':
+		object := make(map[string]interface{})
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid object key %v", key)
+			}
+			if object[name], err = decodeJSONValue(decoder, depth+1, max); err != nil {
+				return nil, err
+			}
+		}
+		value = object
+	case '[':
+		array := make([]interface{}, 0)
+		for decoder.More() {
+			item, err := decodeJSONValue(decoder, depth+1, max)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		value = array
+	default:
+		return nil, fmt.Errorf("unexpected %v", delim)
+	}
+
+	// The closing delimiter
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+var (
+	xmlEntityDeclaration = regexp.MustCompile(`<!ENTITY\s+(%\s+)?([^\s%;&]+)\s+(?:"([^"]*)"|'([^']*)')`)
+	xmlEntityReference   = regexp.MustCompile(`[&%]([A-Za-z_:][\w.:-]*);`)
+	xmlPartialReference  = regexp.MustCompile(`^([A-Za-z_:][\w.:-]*)?$`)
+)
+
+// limitXMLEntities returns a reader over the RDF/XML document in r that
+// fails once the entities declared in its internal DTD subset have been
+// referenced more than max times, nested expansions included. Only the
+// prologue is read ahead; the rest of the document is counted as it is read.
+func limitXMLEntities(r io.Reader, max int) *entityLimitReader {
+	var prologue bytes.Buffer
+	decoder := xml.NewDecoder(io.TeeReader(r, &prologue))
+	decoder.Strict = false
+	entities := make(map[string]string)
+	var body int64
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			break
+		}
+		if directive, ok := token.(xml.Directive); ok {
+			for _, m := range xmlEntityDeclaration.FindAllSubmatch(directive, -1) {
+				entities[string(m[2])] = string(m[3]) + string(m[4])
+			}
+			body = decoder.InputOffset()
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			break
+		}
+	}
+
+	limited := &entityLimitReader{r: io.MultiReader(bytes.NewReader(prologue.Bytes()), r), skip: body}
+	if max > 0 && len(entities) > 0 {
+		limited.counter = &entityCounter{entities: entities, max: int64(max), counts: make(map[string]int64), active: make(map[string]bool)}
+		for name := range entities {
+			if len(name) > limited.longest {
+				limited.longest = len(name)
+			}
+		}
+	}
+	return limited
+}
+
+// entityLimitReader counts the entity references in the document body as
+// it is read. The error is kept so it can be reported even when a parser
+// replaces it with its own.
+type entityLimitReader struct {
+	r       io.Reader
+	counter *entityCounter
+	skip    int64  // bytes of DTD before the body
+	offset  int64  // bytes read so far
+	pending string // a reference that may continue in the next read
+	longest int    // length of the longest declared entity name
+	total   int64
+	err     error
+}
+
+func (e *entityLimitReader) Read(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.r.Read(p)
+	if e.counter == nil {
+		return n, err
+	}
+
+	chunk := p[:n]
+	if e.offset < e.skip {
+		chunk = chunk[min(int64(n), e.skip-e.offset):]
+	}
+	e.offset += int64(n)
+
+	text := e.pending + string(chunk)
+	e.pending = ""
+	if cut := strings.LastIndexAny(text, "&%"); cut >= 0 && len(text)-cut <= e.longest && xmlPartialReference.MatchString(text[cut+1:]) {
+		text, e.pending = text[:cut], text[cut:]
+	}
+	e.total += e.counter.count(text)
+	if e.total > e.counter.max {
+		e.err = limitExceeded(FormatRDFXML, LimitEntityExpansions, e.counter.max)
+		return n, e.err
+	}
+	return n, err
+}
+
+// entityCounter totals entity expansions, saturating just above max so
+// exponential declarations cannot overflow
+type entityCounter struct {
+	entities map[string]string
+	max      int64
+	counts   map[string]int64
+	active   map[string]bool
+}
+
+// count returns the expansions caused by the declared entities referenced in text
+func (c *entityCounter) count(text string) int64 {
+	var total int64
+	for _, m := range xmlEntityReference.FindAllStringSubmatch(text, -1) {
+		name := m[1]
+		value, declared := c.entities[name]
+		if !declared {
+			continue
+		}
+		expansions, ok := c.counts[name]
+		if !ok {
+			if c.active[name] {
+				// A recursive entity never finishes expanding
+				return c.max + 1
+			}
+			c.active[name] = true
+			expansions = 1 + c.count(value)
+			c.active[name] = false
+			c.counts[name] = expansions
+		}
+		total += expansions
+		if total > c.max {
+			return c.max + 1
+		}
+	}
+	return total
+}
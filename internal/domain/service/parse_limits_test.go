@@ -0,0 +1,211 @@
+package service_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestStandardRDFValidationService_ParseLimits(t *testing.T) {
+	newService := func(limits service.ParseLimits) service.RDFValidationService {
+		return service.NewStandardRDFValidationServiceWithOptions(service.RDFServiceOptions{Limits: &limits})
+	}
+
+	t.Run("parses documents from a reader", func(t *testing.T) {
+		// Arrange
+		rdfService := service.NewStandardRDFValidationService()
+		data := strings.NewReader("<https://example.com/a> <https://example.com/p> \"x\" .\n")
+
+		// Act
+		graph, err := rdfService.ParseGraphReader(data, "application/n-triples")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, graph.Len())
+	})
+
+	t.Run("rejects documents over the size limit", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxBytes: 32})
+		data := strings.NewReader(`<https://example.com/a> <https://example.com/p> "a value that does not fit" .`)
+
+		// Act
+		_, err := rdfService.ParseGraphReader(data, "text/turtle")
+
+		// Assert
+		var validationErr *service.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.ErrorIs(t, err, service.ErrInputTooLarge)
+		assert.Equal(t, service.FormatTurtle, validationErr.Format)
+	})
+
+	t.Run("rejects documents with too many triples", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxTriples: 2})
+		data := "<https://example.com/a> <https://example.com/p> <https://example.com/b> .\n" +
+			"<https://example.com/a> <https://example.com/p> <https://example.com/c> .\n" +
+			"<https://example.com/a> <https://example.com/p> <https://example.com/d> .\n"
+
+		// Act
+		_, err := rdfService.ParseGraph(data, "application/n-triples")
+
+		// Assert
+		var limitErr *service.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, service.LimitTriples, limitErr.Limit)
+		assert.ErrorIs(t, err, service.ErrInputTooComplex)
+	})
+
+	t.Run("rejects Turtle as soon as it has too many triples", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxTriples: 2})
+		data := io.MultiReader(
+			strings.NewReader("@prefix ex: <https://example.com/> .\n"+
+				"ex:a ex:p ex:b, ex:c ;\n"+
+				"  ex:q \"d; e, f.\" .\n"),
+			iotest.ErrReader(errors.New("the rest of the document is never read")),
+		)
+
+		// Act
+		_, err := rdfService.ParseGraphReader(data, "text/turtle")
+
+		// Assert
+		var limitErr *service.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, service.LimitTriples, limitErr.Limit)
+	})
+
+	t.Run("rejects RDF/XML as soon as it has too many triples", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxTriples: 2})
+		data := io.MultiReader(
+			strings.NewReader(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="https://example.com/">
+  <rdf:Description rdf:about="https://example.com/a" ex:p="b" ex:q="c" ex:r="d"/>`),
+			iotest.ErrReader(errors.New("the rest of the document is never read")),
+		)
+
+		// Act
+		_, err := rdfService.ParseGraphReader(data, "application/rdf+xml")
+
+		// Assert
+		var limitErr *service.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, service.LimitTriples, limitErr.Limit)
+	})
+
+	t.Run("rejects unterminated Turtle literals without hanging", func(t *testing.T) {
+		for _, data := range []string{
+			`<https://example.com/a> <https://example.com/p> "unterminated .`,
+			`<https://example.com/a> <https://example.com/p> """unterminated .`,
+			`<https://example.com/a> <https://example.com/p> "escaped \`,
+			`<https://example.com/a> <https://example.com/p`,
+		} {
+			// Arrange
+			rdfService := service.NewStandardRDFValidationService()
+			done := make(chan error, 1)
+
+			// Act
+			go func() {
+				_, err := rdfService.ParseGraph(data, "text/turtle")
+				done <- err
+			}()
+
+			// Assert
+			select {
+			case err := <-done:
+				var validationErr *service.ValidationError
+				assert.ErrorAs(t, err, &validationErr, data)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("parsing %q did not finish", data)
+			}
+		}
+	})
+
+	t.Run("rejects deeply nested JSON-LD", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxJSONDepth: 8})
+		data := `{"@id": "https://example.com/a", "https://example.com/p": ` + strings.Repeat("[", 20) + strings.Repeat("]", 20) + `}`
+
+		// Act
+		_, err := rdfService.ValidateJSONLD(data)
+
+		// Assert
+		var limitErr *service.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, service.LimitJSONDepth, limitErr.Limit)
+	})
+
+	t.Run("rejects JSON-LD with too many triples", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxTriples: 2})
+		data := `{"@id": "https://example.com/a", "https://example.com/p": ["b", "c", "d"]}`
+
+		// Act
+		_, err := rdfService.ValidateJSONLD(data)
+
+		// Assert
+		var limitErr *service.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, service.LimitTriples, limitErr.Limit)
+		assert.ErrorIs(t, err, service.ErrInputTooComplex)
+	})
+
+	t.Run("rejects JSON-LD literals over the length limit", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxLiteralLength: 16})
+		data := `{"@id": "https://example.com/a", "https://example.com/p": "` + strings.Repeat("x", 17) + `"}`
+
+		// Act
+		_, err := rdfService.ValidateJSONLD(data)
+
+		// Assert
+		var limitErr *service.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, service.LimitLiteralLength, limitErr.Limit)
+	})
+
+	t.Run("rejects RDF/XML with exponential entity expansion", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxEntityExpansions: 1000})
+		data := `<?xml version="1.0"?>
+<!DOCTYPE rdf:RDF [
+  <!ENTITY lol "lol">
+  <!ENTITY lol1 "&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;">
+  <!ENTITY lol2 "&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;">
+  <!ENTITY lol3 "&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;">
+]>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="https://example.com/">
+  <rdf:Description rdf:about="https://example.com/a"><ex:p>&lol3;</ex:p></rdf:Description>
+</rdf:RDF>`
+
+		// Act
+		_, err := rdfService.ParseGraph(data, "application/rdf+xml")
+
+		// Assert
+		var limitErr *service.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, service.LimitEntityExpansions, limitErr.Limit)
+	})
+
+	t.Run("rejects literals over the length limit", func(t *testing.T) {
+		// Arrange
+		rdfService := newService(service.ParseLimits{MaxLiteralLength: 16})
+		data := `<https://example.com/a> <https://example.com/p> "` + strings.Repeat("x", 17) + `" .`
+
+		// Act
+		_, err := rdfService.ValidateTurtle(data)
+
+		// Assert
+		var limitErr *service.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, service.LimitLiteralLength, limitErr.Limit)
+	})
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/piprate/json-gold/ld"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// Adapters between the third-party RDF libraries and the shared rdf term model
+
+// fromJSONLDNode converts a json-gold node into an rdf.Term
+func fromJSONLDNode(node ld.Node) (rdf.Term, error) {
+	switch n := node.(type) {
+	case ld.IRI:
+		return rdf.IRI(n.Value), nil
+	case ld.BlankNode:
+		return rdf.BlankNode(strings.TrimPrefix(n.Attribute, "_:")), nil
+	case ld.Literal:
+		if n.Language != "" {
+			return rdf.NewLangLiteral(n.Value, n.Language), nil
+		}
+		return rdf.NewTypedLiteral(n.Value, rdf.IRI(n.Datatype)), nil
+	default:
+		return nil, fmt.Errorf("unsupported node type %T", node)
+	}
+}
+
+// toJSONLDNode converts an rdf.Term into a json-gold node
+func toJSONLDNode(term rdf.Term) ld.Node {
+	switch t := term.(type) {
+	case rdf.IRI:
+		return ld.NewIRI(string(t))
+	case rdf.BlankNode:
+		return ld.NewBlankNode(t.String())
+	case rdf.Literal:
+		if t.Language != "" {
+			return ld.NewLiteral(t.Lexical, string(rdf.RDFLangString), t.Language)
+		}
+		return ld.NewLiteral(t.Lexical, string(t.EffectiveDatatype()), "")
+	default:
+		return nil
+	}
+}
+
+// fromJSONLDGraphName converts a json-gold graph name into an rdf.Term
+func fromJSONLDGraphName(name string) rdf.Term {
+	if strings.HasPrefix(name, "_:") {
+		return rdf.BlankNode(strings.TrimPrefix(name, "_:"))
+	}
+	return rdf.IRI(name)
+}
+
+// toJSONLDDataset converts a dataset into a json-gold dataset
+func toJSONLDDataset(dataset *rdf.Dataset) *ld.RDFDataset {
+	result := ld.NewRDFDataset()
+	result.Graphs["@default"] = toJSONLDQuads(dataset.Default(), "@default")
+	for _, name := range dataset.GraphNames() {
+		graph, _ := dataset.NamedGraph(name)
+		graphName := name.Value()
+		if name.Kind() == rdf.KindBlankNode {
+			graphName = name.String()
+		}
+		result.Graphs[graphName] = toJSONLDQuads(graph, graphName)
+	}
+	return result
+}
+
+// toJSONLDQuads converts the triples of a graph into sorted json-gold quads
+func toJSONLDQuads(graph *rdf.Graph, graphName string) []*ld.Quad {
+	quads := make([]*ld.Quad, 0, graph.Len())
+	for _, t := range graph.SortedTriples() {
+		quads = append(quads, ld.NewQuad(
+			toJSONLDNode(t.Subject),
+			toJSONLDNode(t.Predicate),
+			toJSONLDNode(t.Object),
+			graphName,
+		))
+	}
+	return quads
+}
@@ -1,30 +1,70 @@
 package service
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
 
 //go:generate moq -out rdf_validation_service_mock.go . RDFValidationService
 
 // RDFValidationService defines the interface for validating RDF data formats
-// and extracting resource identifiers from different RDF serializations
+// and identifying the resource a document describes. The resource ID is the
+// target URI set with WithBase unless another IdentityStrategy is configured.
 type RDFValidationService interface {
-	// ValidateJSONLD validates JSON-LD data and extracts the @id field
+	// ValidateJSONLD validates JSON-LD data and returns the resource ID,
+	// falling back to the top-level @id without a target URI
 	ValidateJSONLD(data string) (resourceID string, err error)
 
-	// ValidateTurtle validates Turtle data and extracts the subject URI
+	// ValidateTurtle validates Turtle data and returns the resource ID,
+	// falling back to the only IRI subject without a target URI
 	ValidateTurtle(data string) (resourceID string, err error)
 
-	// ValidateRDFXML validates RDF/XML data and extracts the rdf:about URI
+	// ValidateRDFXML validates RDF/XML data and returns the resource ID,
+	// falling back to the first rdf:about URI without a target URI
 	ValidateRDFXML(data string) (resourceID string, err error)
 
-	// ValidateN3 validates N3 data and extracts the subject URI
+	// ValidateN3 validates N3 data and returns the resource ID, falling back
+	// to the only IRI subject without a target URI
 	ValidateN3(data string) (resourceID string, err error)
 
-	// ValidateNTriples validates N-Triples data and extracts the subject URI
+	// ValidateNTriples validates N-Triples data and returns the resource ID,
+	// falling back to the only IRI subject without a target URI
 	ValidateNTriples(data string) (resourceID string, err error)
 
 	// ConvertFormat converts RDF data from one format to another
 	ConvertFormat(data string, fromFormat, toFormat string) (string, error)
 
+	// ParseGraph parses RDF data in the given format into a graph
+	ParseGraph(data string, format string) (*rdf.Graph, error)
+
+	// ParseGraphReader parses an RDF document from a reader into a graph,
+	// enforcing the configured parse limits while it reads
+	ParseGraphReader(r io.Reader, format string) (*rdf.Graph, error)
+
+	// SerializeGraph serializes a graph into the given format; the format may
+	// include media type parameters such as a JSON-LD profile
+	SerializeGraph(graph *rdf.Graph, format string) (string, error)
+
+	// ParseDataset parses RDF data into a dataset; triple formats fill the default graph
+	ParseDataset(data string, format string) (*rdf.Dataset, error)
+
+	// ParseDatasetReader parses an RDF document from a reader into a dataset
+	// within the configured parse limits
+	ParseDatasetReader(r io.Reader, format string) (*rdf.Dataset, error)
+
+	// SerializeDataset serializes a dataset; named graphs require TriG, N-Quads or JSON-LD
+	SerializeDataset(dataset *rdf.Dataset, format string) (string, error)
+
+	// SerializeJSONLD serializes a graph as expanded, compacted, flattened or framed JSON-LD
+	SerializeJSONLD(graph *rdf.Graph, options JSONLDOutputOptions) (string, error)
+
+	// WithBase returns a service that resolves relative IRIs against base when
+	// parsing, and writes the base document and its fragments as relative
+	// references in Turtle
+	WithBase(base string) RDFValidationService
+
 	// SupportedFormats returns a list of supported RDF formats
 	SupportedFormats() []string
 }
@@ -39,6 +79,8 @@ const (
 	FormatN3       RDFFormat = "text/n3"
 	FormatNTriples RDFFormat = "application/n-triples"
 	FormatRDFJSON  RDFFormat = "application/rdf+json"
+	FormatTriG     RDFFormat = "application/trig"
+	FormatNQuads   RDFFormat = "application/n-quads"
 )
 
 // ValidationError represents an RDF validation error with format-specific details
@@ -2,47 +2,120 @@ package service
 
 import (
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"strings"
 
-	"github.com/deiu/rdf2go"
-	"github.com/knakk/rdf"
 	"github.com/piprate/json-gold/ld"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
 )
 
 // StandardRDFValidationService implements the RDFValidationService interface
 // using professional RDF libraries for proper validation and parsing
 type StandardRDFValidationService struct {
 	jsonLDProcessor *ld.JsonLdProcessor
+	prefixes        []rdf.Namespace
+	documentLoader  ld.DocumentLoader
+	limits          ParseLimits
+	identity        IdentityStrategy
+	base            string
+}
+
+// RDFServiceOptions configures a StandardRDFValidationService
+type RDFServiceOptions struct {
+	// Prefixes are namespace bindings used in serialized output in addition
+	// to the well-known vocabularies; they take precedence on conflicts
+	Prefixes []rdf.Namespace
+
+	// DocumentLoader resolves remote JSON-LD contexts; defaults to an
+	// offline loader serving only the bundled contexts
+	DocumentLoader ld.DocumentLoader
+
+	// Limits bound every parsed document; defaults to DefaultParseLimits
+	Limits *ParseLimits
+
+	// Identity selects the resource ID the Validate methods return; defaults
+	// to IdentityTargetURI
+	Identity IdentityStrategy
 }
 
 // NewStandardRDFValidationService creates a new instance of StandardRDFValidationService
 func NewStandardRDFValidationService() RDFValidationService {
+	return NewStandardRDFValidationServiceWithOptions(RDFServiceOptions{})
+}
+
+// NewStandardRDFValidationServiceWithOptions creates a new instance of StandardRDFValidationService with custom options
+func NewStandardRDFValidationServiceWithOptions(options RDFServiceOptions) RDFValidationService {
+	prefixes := make([]rdf.Namespace, 0, len(options.Prefixes)+len(rdf.WellKnownNamespaces))
+	prefixes = append(prefixes, options.Prefixes...)
+	prefixes = append(prefixes, rdf.WellKnownNamespaces...)
+
+	documentLoader := options.DocumentLoader
+	if documentLoader == nil {
+		documentLoader = NewOfflineContextDocumentLoader()
+	}
+
+	limits := DefaultParseLimits()
+	if options.Limits != nil {
+		limits = *options.Limits
+	}
+
 	return &StandardRDFValidationService{
 		jsonLDProcessor: ld.NewJsonLdProcessor(),
+		prefixes:        prefixes,
+		documentLoader:  documentLoader,
+		limits:          limits,
+		identity:        options.Identity,
 	}
 }
 
-// ValidateJSONLD validates JSON-LD data and extracts the @id field
+// WithBase returns a copy of the service that resolves relative IRIs against base
+func (s *StandardRDFValidationService) WithBase(base string) RDFValidationService {
+	based := *s
+	based.base = base
+	return &based
+}
+
+// ValidateJSONLD validates JSON-LD data and returns the ID of the resource it
+// describes
 func (s *StandardRDFValidationService) ValidateJSONLD(data string) (resourceID string, err error) {
+	if s.usesDocumentStructure() {
+		return s.identifyDocument(data, FormatJSONLD, "JSON-LD parsing failed", "No subject URI found in JSON-LD data")
+	}
+
 	// Parse as JSON first
-	var jsonData interface{}
-	if err := json.Unmarshal([]byte(data), &jsonData); err != nil {
-		return "", NewValidationError(FormatJSONLD, "Invalid JSON syntax", err)
+	jsonData, err := s.decodeJSON(newLimitedReader(strings.NewReader(data), FormatJSONLD, s.limits.MaxBytes))
+	if err != nil {
+		return "", err
 	}
 
 	// Validate JSON-LD structure using json-gold
-	expanded, err := s.jsonLDProcessor.Expand(jsonData, nil)
+	expanded, err := s.jsonLDProcessor.Expand(jsonData, s.jsonLDOptions())
 	if err != nil {
 		return "", NewValidationError(FormatJSONLD, "JSON-LD expansion failed", err)
 	}
 
-	// Convert to RDF to ensure it's valid RDF
-	_, err = s.jsonLDProcessor.ToRDF(expanded, nil)
+	// Convert to RDF to ensure it's valid RDF within the limits
+	result, err := s.jsonLDProcessor.ToRDF(expanded, s.jsonLDOptions())
 	if err != nil {
 		return "", NewValidationError(FormatJSONLD, "JSON-LD to RDF conversion failed", err)
 	}
+	_, err = datasetFromJSONLD(result, &tripleLimiter{limits: s.limits, format: FormatJSONLD})
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return "", err
+	}
+	if err != nil {
+		return "", NewValidationError(FormatJSONLD, "JSON-LD to RDF conversion failed", err)
+	}
+
+	if s.base != "" {
+		return s.base, nil
+	}
 
 	// Extract @id from the original JSON-LD
 	if jsonMap, ok := jsonData.(map[string]interface{}); ok {
@@ -53,126 +126,244 @@ func (s *StandardRDFValidationService) ValidateJSONLD(data string) (resourceID s
 		}
 	}
 
-	// The expanded form is an array of objects in JSON-LD 1.1
-	// Try to extract @id from the expanded form if not found in original
-
 	return "", NewValidationError(FormatJSONLD, "No @id field found in JSON-LD", nil)
 }
 
-// ValidateTurtle validates Turtle data and extracts the subject URI
+// ValidateTurtle validates Turtle data and returns the ID of the resource it
+// describes
 func (s *StandardRDFValidationService) ValidateTurtle(data string) (resourceID string, err error) {
-	graph := rdf2go.NewGraph("")
-	err = graph.Parse(strings.NewReader(data), "text/turtle")
-	if err != nil {
-		return "", NewValidationError(FormatTurtle, "Turtle parsing failed", err)
-	}
-
-	// Find the first subject URI
-	for triple := range graph.IterTriples() {
-		if triple.Subject.RawValue() != "" {
-			// Return the first subject URI we find
-			return triple.Subject.RawValue(), nil
-		}
-	}
-
-	return "", NewValidationError(FormatTurtle, "No subject URI found in Turtle data", nil)
+	return s.identifyDocument(data, FormatTurtle, "Turtle parsing failed", "No subject URI found in Turtle data")
 }
 
-// ValidateRDFXML validates RDF/XML data and extracts the rdf:about URI
+// ValidateRDFXML validates RDF/XML data and returns the ID of the resource it
+// describes
 func (s *StandardRDFValidationService) ValidateRDFXML(data string) (resourceID string, err error) {
-	decoder := rdf.NewTripleDecoder(strings.NewReader(data), rdf.RDFXML)
+	if s.usesDocumentStructure() {
+		return s.identifyDocument(data, FormatRDFXML, "RDF/XML parsing failed", "No rdf:about URI found in RDF/XML data")
+	}
 
-	// Try to decode the first triple to validate the syntax
-	triple, err := decoder.Decode()
-	if err != nil && err != io.EOF {
+	// The whole document is decoded so that errors after the first
+	// description are found
+	input := newLimitedReader(strings.NewReader(data), FormatRDFXML, s.limits.MaxBytes)
+	_, first, err := s.decodeRDFXML(input, &tripleLimiter{limits: s.limits, format: FormatRDFXML})
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return "", err
+	}
+	if err != nil {
 		return "", NewValidationError(FormatRDFXML, "RDF/XML parsing failed", err)
 	}
 
-	// If we got a valid triple, extract the subject
-	if triple.Subj.Type() == rdf.TermIRI {
-		return triple.Subj.String(), nil
+	if s.base != "" {
+		return s.base, nil
+	}
+	if subject, ok := first.(rdf.IRI); ok {
+		return string(subject), nil
 	}
-
 	return "", NewValidationError(FormatRDFXML, "No rdf:about URI found in RDF/XML data", nil)
 }
 
-// ValidateN3 validates N3 data and extracts the subject URI
+// ValidateN3 validates N3 data and returns the ID of the resource it describes
 func (s *StandardRDFValidationService) ValidateN3(data string) (resourceID string, err error) {
-	// N3 is an extension of Turtle, so we can use the same parser
-	graph := rdf2go.NewGraph("")
-	err = graph.Parse(strings.NewReader(data), "text/n3")
+	// N3 is an extension of Turtle, so the same parser is used
+	return s.identifyDocument(data, FormatN3, "N3 parsing failed", "No subject URI found in N3 data")
+}
+
+// ValidateNTriples validates N-Triples data and returns the ID of the
+// resource it describes
+func (s *StandardRDFValidationService) ValidateNTriples(data string) (resourceID string, err error) {
+	return s.identifyDocument(data, FormatNTriples, "N-Triples parsing failed", "No subject URI found in N-Triples data")
+}
+
+// usesDocumentStructure reports whether the identity strategy needs the
+// parsed graph, rather than the target URI or the top-level node of
+// JSON-LD and RDF/XML
+func (s *StandardRDFValidationService) usesDocumentStructure() bool {
+	return s.identity != IdentityTargetURI && s.identity != ""
+}
+
+// identifyDocument parses data within the limits and returns the IRI the
+// identity strategy selects
+func (s *StandardRDFValidationService) identifyDocument(data string, format RDFFormat, parseFailed string, noSubject string) (string, error) {
+	graph, err := s.parseGraph(strings.NewReader(data), format)
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return "", err
+	}
 	if err != nil {
-		return "", NewValidationError(FormatN3, "N3 parsing failed", err)
+		return "", NewValidationError(format, parseFailed, err)
 	}
+	return identify(graph, s.identity, s.base, format, noSubject)
+}
 
-	// Find the first subject URI
-	for triple := range graph.IterTriples() {
-		if triple.Subject.RawValue() != "" {
-			return triple.Subject.RawValue(), nil
+// ConvertFormat converts RDF data from one format to another
+func (s *StandardRDFValidationService) ConvertFormat(data string, fromFormat, toFormat string) (string, error) {
+	if !s.isSupportedFormat(toFormat) {
+		return "", fmt.Errorf("unsupported target format: %s", toFormat)
+	}
+
+	// Formats that carry named graphs convert through a dataset so no graph is lost
+	if supportsNamedGraphs(toFormat) {
+		dataset, err := s.ParseDataset(data, fromFormat)
+		if err != nil {
+			return "", err
 		}
+		return s.SerializeDataset(dataset, toFormat)
+	}
+
+	// First, parse the source data into a graph
+	graph, err := s.ParseGraph(data, fromFormat)
+	if err != nil {
+		return "", err
 	}
 
-	return "", NewValidationError(FormatN3, "No subject URI found in N3 data", nil)
+	// Serialize the graph in the target format
+	return s.SerializeGraph(graph, toFormat)
 }
 
-// ValidateNTriples validates N-Triples data and extracts the subject URI
-func (s *StandardRDFValidationService) ValidateNTriples(data string) (resourceID string, err error) {
-	decoder := rdf.NewTripleDecoder(strings.NewReader(data), rdf.NTriples)
+// ParseGraph parses RDF data in the given format into a graph.
+// For TriG and N-Quads the graph is the union of all graphs in the dataset.
+func (s *StandardRDFValidationService) ParseGraph(data string, format string) (*rdf.Graph, error) {
+	return s.ParseGraphReader(strings.NewReader(data), format)
+}
 
-	// Try to decode the first triple to validate the syntax
-	triple, err := decoder.Decode()
-	if err != nil && err != io.EOF {
-		return "", NewValidationError(FormatNTriples, "N-Triples parsing failed", err)
+// ParseGraphReader parses an RDF document read from r into a graph,
+// failing with a ValidationError as soon as it exceeds the parse limits
+func (s *StandardRDFValidationService) ParseGraphReader(r io.Reader, format string) (*rdf.Graph, error) {
+	switch base := mediaTypeBase(format); base {
+	case string(FormatTriG), string(FormatNQuads):
+		dataset, err := s.ParseDatasetReader(r, format)
+		if err != nil {
+			return nil, err
+		}
+		return dataset.Union(), nil
+	case string(FormatJSONLD), string(FormatTurtle), string(FormatRDFXML), string(FormatN3), string(FormatNTriples):
+		graph, err := s.parseGraph(r, RDFFormat(base))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse source data: %w", err)
+		}
+		return graph, nil
+	default:
+		return nil, fmt.Errorf("unsupported source format: %s", format)
 	}
+}
+
+// parseGraph parses a document in a triple format within the parse limits
+func (s *StandardRDFValidationService) parseGraph(r io.Reader, format RDFFormat) (*rdf.Graph, error) {
+	input := newLimitedReader(r, format, s.limits.MaxBytes)
+	limiter := &tripleLimiter{limits: s.limits, format: format}
 
-	// If we got a valid triple, extract the subject
-	if triple.Subj.Type() == rdf.TermIRI {
-		return triple.Subj.String(), nil
+	var graph *rdf.Graph
+	var err error
+	switch format {
+	case FormatJSONLD:
+		graph, err = s.parseJSONLDToGraph(input, limiter)
+	case FormatTurtle, FormatN3, FormatNTriples:
+		// N3 can be parsed as Turtle
+		var dataset *rdf.Dataset
+		if dataset, err = parseTurtle(input, format, s.base, limiter); err == nil {
+			graph = dataset.Default()
+		}
+	case FormatRDFXML:
+		graph, _, err = s.decodeRDFXML(input, limiter)
+	default:
+		return nil, fmt.Errorf("unsupported source format: %s", format)
 	}
 
-	return "", NewValidationError(FormatNTriples, "No subject URI found in N-Triples data", nil)
+	if input.err != nil {
+		return nil, input.err
+	}
+	return graph, err
 }
 
-// ConvertFormat converts RDF data from one format to another
-func (s *StandardRDFValidationService) ConvertFormat(data string, fromFormat, toFormat string) (string, error) {
-	// First, validate and parse the source data to extract triples
-	var triples []rdfTriple
-	var err error
-
-	switch fromFormat {
+// SerializeGraph serializes a graph into the given format
+// The format may carry media type parameters, such as a JSON-LD profile.
+func (s *StandardRDFValidationService) SerializeGraph(graph *rdf.Graph, format string) (string, error) {
+	switch mediaTypeBase(format) {
 	case string(FormatJSONLD):
-		triples, err = s.parseJSONLDToTriples(data)
+		options, err := JSONLDOutputOptionsFromMediaType(format)
+		if err != nil {
+			return "", err
+		}
+		return s.SerializeJSONLD(graph, options)
 	case string(FormatTurtle):
-		triples, err = s.parseTurtleToTriples(data)
+		return s.serializeGraphToTurtle(graph)
 	case string(FormatRDFXML):
-		triples, err = s.parseRDFXMLToTriples(data)
+		return s.serializeGraphToRDFXML(graph)
 	case string(FormatN3):
-		triples, err = s.parseN3ToTriples(data)
+		return s.serializeGraphToN3(graph)
 	case string(FormatNTriples):
-		triples, err = s.parseNTriplesToTriples(data)
+		return s.serializeGraphToNTriples(graph)
+	case string(FormatTriG), string(FormatNQuads):
+		return s.SerializeDataset(rdf.NewDatasetFromGraph(graph), format)
 	default:
-		return "", fmt.Errorf("unsupported source format: %s", fromFormat)
+		return "", fmt.Errorf("unsupported target format: %s", format)
+	}
+}
+
+// ParseDataset parses RDF data into a dataset. Triple formats produce a
+// dataset with only a default graph.
+func (s *StandardRDFValidationService) ParseDataset(data string, format string) (*rdf.Dataset, error) {
+	return s.ParseDatasetReader(strings.NewReader(data), format)
+}
+
+// ParseDatasetReader parses an RDF document read from r into a dataset
+// within the parse limits
+func (s *StandardRDFValidationService) ParseDatasetReader(r io.Reader, format string) (*rdf.Dataset, error) {
+	base := RDFFormat(mediaTypeBase(format))
+	input := newLimitedReader(r, base, s.limits.MaxBytes)
+	limiter := &tripleLimiter{limits: s.limits, format: base}
+
+	var dataset *rdf.Dataset
+	var err error
+	switch base {
+	case FormatTriG, FormatNQuads:
+		dataset, err = parseTurtle(input, base, s.base, limiter)
+	case FormatJSONLD:
+		dataset, err = s.parseJSONLDToDataset(input, limiter)
+	default:
+		graph, err := s.ParseGraphReader(input, format)
+		if err != nil {
+			return nil, err
+		}
+		return rdf.NewDatasetFromGraph(graph), nil
 	}
 
+	if input.err != nil {
+		err = input.err
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to parse source data: %w", err)
+		return nil, fmt.Errorf("failed to parse source data: %w", err)
 	}
 
-	// Convert triples to target format
-	switch toFormat {
+	return dataset, nil
+}
+
+// SerializeDataset serializes a dataset. Triple formats can only be used
+// when the dataset has no named graphs.
+func (s *StandardRDFValidationService) SerializeDataset(dataset *rdf.Dataset, format string) (string, error) {
+	switch mediaTypeBase(format) {
+	case string(FormatTriG):
+		writer := newTriGWriter(rdf.NewPrefixMap(s.prefixes...))
+		writer.turtle.base = s.base
+		return writer.Write(dataset), nil
+	case string(FormatNQuads):
+		return dataset.String(), nil
 	case string(FormatJSONLD):
-		return s.serializeTriplesToJSONLD(triples)
-	case string(FormatTurtle):
-		return s.serializeTriplesToTurtle(triples)
-	case string(FormatRDFXML):
-		return s.serializeTriplesToRDFXML(triples)
-	case string(FormatN3):
-		return s.serializeTriplesToN3(triples)
-	case string(FormatNTriples):
-		return s.serializeTriplesToNTriples(triples)
-	default:
-		return "", fmt.Errorf("unsupported target format: %s", toFormat)
+		options, err := JSONLDOutputOptionsFromMediaType(format)
+		if err != nil {
+			return "", err
+		}
+		return s.serializeJSONLDDataset(dataset, options)
 	}
+
+	if !s.isSupportedFormat(format) {
+		return "", fmt.Errorf("unsupported target format: %s", format)
+	}
+	if len(dataset.GraphNames()) > 0 {
+		return "", fmt.Errorf("format %s cannot represent named graphs", mediaTypeBase(format))
+	}
+	return s.SerializeGraph(dataset.Default(), format)
 }
 
 // SupportedFormats returns a list of supported RDF formats
@@ -183,228 +374,189 @@ func (s *StandardRDFValidationService) SupportedFormats() []string {
 		string(FormatRDFXML),
 		string(FormatN3),
 		string(FormatNTriples),
+		string(FormatTriG),
+		string(FormatNQuads),
 	}
 }
 
-// rdfTriple represents an RDF triple for internal processing
-type rdfTriple struct {
-	Subject   string
-	Predicate string
-	Object    string
-	IsLiteral bool
+func (s *StandardRDFValidationService) isSupportedFormat(format string) bool {
+	base := mediaTypeBase(format)
+	for _, supported := range s.SupportedFormats() {
+		if supported == base {
+			return true
+		}
+	}
+	return false
 }
 
-// Helper methods for parsing different formats to triples
+// supportsNamedGraphs reports whether the format can carry a whole dataset
+func supportsNamedGraphs(format string) bool {
+	switch mediaTypeBase(format) {
+	case string(FormatTriG), string(FormatNQuads), string(FormatJSONLD):
+		return true
+	default:
+		return false
+	}
+}
 
-func (s *StandardRDFValidationService) parseJSONLDToTriples(data string) ([]rdfTriple, error) {
-	var jsonData interface{}
-	if err := json.Unmarshal([]byte(data), &jsonData); err != nil {
-		return nil, err
+// mediaTypeBase strips parameters from a media type, returning it unchanged if it cannot be parsed
+func mediaTypeBase(format string) string {
+	if base, _, err := mime.ParseMediaType(format); err == nil {
+		return base
 	}
+	return format
+}
+
+// Helper methods for parsing different formats into graphs
 
-	expanded, err := s.jsonLDProcessor.Expand(jsonData, nil)
+func (s *StandardRDFValidationService) parseJSONLDToGraph(input *limitedReader, limiter *tripleLimiter) (*rdf.Graph, error) {
+	dataset, err := s.parseJSONLDToDataset(input, limiter)
 	if err != nil {
 		return nil, err
 	}
+	return dataset.Default(), nil
+}
 
-	_, err = s.jsonLDProcessor.ToRDF(expanded, nil)
+func (s *StandardRDFValidationService) parseJSONLDToDataset(input *limitedReader, limiter *tripleLimiter) (*rdf.Dataset, error) {
+	jsonData, err := s.decodeJSON(input)
 	if err != nil {
 		return nil, err
 	}
 
-	// For now, create a simple triple from the JSON-LD @id
-	var triples []rdfTriple
-	if jsonMap, ok := jsonData.(map[string]interface{}); ok {
-		if id, exists := jsonMap["@id"]; exists {
-			if idStr, ok := id.(string); ok {
-				// Create a simple triple - this is a simplified implementation
-				triple := rdfTriple{
-					Subject:   idStr,
-					Predicate: "http://www.w3.org/1999/02/22-rdf-syntax-ns#type",
-					Object:    "http://www.w3.org/2000/01/rdf-schema#Resource",
-					IsLiteral: false,
-				}
-				triples = append(triples, triple)
-			}
-		}
+	result, err := s.jsonLDProcessor.ToRDF(jsonData, s.jsonLDOptions())
+	if err != nil {
+		return nil, err
 	}
-
-	return triples, nil
+	return datasetFromJSONLD(result, limiter)
 }
 
-func (s *StandardRDFValidationService) parseTurtleToTriples(data string) ([]rdfTriple, error) {
-	graph := rdf2go.NewGraph("")
-	err := graph.Parse(strings.NewReader(data), "text/turtle")
-	if err != nil {
-		return nil, err
+// datasetFromJSONLD converts the result of a json-gold ToRDF call into a
+// dataset, checking each triple against the limiter
+func datasetFromJSONLD(result interface{}, limiter *tripleLimiter) (*rdf.Dataset, error) {
+	jsonLDDataset, ok := result.(*ld.RDFDataset)
+	if !ok {
+		return nil, fmt.Errorf("unexpected JSON-LD to RDF result %T", result)
 	}
 
-	var triples []rdfTriple
-	for triple := range graph.IterTriples() {
-		t := rdfTriple{
-			Subject:   triple.Subject.RawValue(),
-			Predicate: triple.Predicate.RawValue(),
-			Object:    triple.Object.RawValue(),
-			IsLiteral: strings.HasPrefix(triple.Object.RawValue(), "\""),
+	dataset := rdf.NewDataset()
+	for name, quads := range jsonLDDataset.Graphs {
+		var graphName rdf.Term
+		if name != "@default" {
+			graphName = fromJSONLDGraphName(name)
+		}
+		graph := dataset.Graph(graphName)
+		for _, quad := range quads {
+			triple, err := tripleFromJSONLDQuad(quad)
+			if err != nil {
+				return nil, err
+			}
+			if err := limiter.check(triple); err != nil {
+				return nil, err
+			}
+			graph.Add(triple)
 		}
-		triples = append(triples, t)
 	}
 
-	return triples, nil
+	return dataset, nil
 }
 
-func (s *StandardRDFValidationService) parseRDFXMLToTriples(data string) ([]rdfTriple, error) {
-	decoder := rdf.NewTripleDecoder(strings.NewReader(data), rdf.RDFXML)
-	var triples []rdfTriple
-
-	for {
-		triple, err := decoder.Decode()
-		if err == io.EOF {
-			break
+// decodeJSON decodes a JSON document as it is read, within the size and
+// nesting limits
+func (s *StandardRDFValidationService) decodeJSON(input *limitedReader) (interface{}, error) {
+	decoder := json.NewDecoder(input)
+	jsonData, err := decodeJSONValue(decoder, 0, s.limits.MaxJSONDepth)
+	if err == nil {
+		if _, trailing := decoder.Token(); trailing != io.EOF {
+			err = fmt.Errorf("unexpected data after the JSON document")
 		}
-		if err != nil {
-			return nil, err
-		}
-
-		t := rdfTriple{
-			Subject:   triple.Subj.String(),
-			Predicate: triple.Pred.String(),
-			Object:    triple.Obj.String(),
-			IsLiteral: triple.Obj.Type() == rdf.TermLiteral,
-		}
-		triples = append(triples, t)
 	}
 
-	return triples, nil
+	var limitErr *LimitError
+	switch {
+	case input.err != nil:
+		return nil, input.err
+	case errors.As(err, &limitErr):
+		return nil, err
+	case err != nil:
+		return nil, NewValidationError(FormatJSONLD, "Invalid JSON syntax", err)
+	}
+	return jsonData, nil
 }
 
-func (s *StandardRDFValidationService) parseN3ToTriples(data string) ([]rdfTriple, error) {
-	// N3 can be parsed as Turtle
-	return s.parseTurtleToTriples(data)
+// decodeRDFXML decodes a whole RDF/XML document as it is read, checking
+// each triple against the limiter, and returns its graph and the subject of
+// its first triple
+func (s *StandardRDFValidationService) decodeRDFXML(input *limitedReader, limiter *tripleLimiter) (*rdf.Graph, rdf.Term, error) {
+	entities := limitXMLEntities(input, s.limits.MaxEntityExpansions)
+	graph, first, err := parseRDFXML(entities, s.base, limiter)
+	switch {
+	case input.err != nil:
+		return nil, nil, input.err
+	case entities.err != nil:
+		return nil, nil, entities.err
+	case err != nil:
+		return nil, nil, err
+	}
+	return graph, first, nil
 }
 
-func (s *StandardRDFValidationService) parseNTriplesToTriples(data string) ([]rdfTriple, error) {
-	decoder := rdf.NewTripleDecoder(strings.NewReader(data), rdf.NTriples)
-	var triples []rdfTriple
-
-	for {
-		triple, err := decoder.Decode()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		t := rdfTriple{
-			Subject:   triple.Subj.String(),
-			Predicate: triple.Pred.String(),
-			Object:    triple.Obj.String(),
-			IsLiteral: triple.Obj.Type() == rdf.TermLiteral,
-		}
-		triples = append(triples, t)
+// relativeToDocument returns the reference to iri relative to the document
+// base: "" for the document itself and "#fragment" for its fragments
+func relativeToDocument(iri string, base string) (string, bool) {
+	if base == "" {
+		return "", false
 	}
-
-	return triples, nil
+	document, _, _ := strings.Cut(base, "#")
+	if iri == document {
+		return "", true
+	}
+	if fragment, ok := strings.CutPrefix(iri, document+"#"); ok {
+		return "#" + fragment, true
+	}
+	return "", false
 }
 
-// Helper methods for serializing triples to different formats
-
-func (s *StandardRDFValidationService) serializeTriplesToJSONLD(triples []rdfTriple) (string, error) {
-	// Create a simple JSON-LD structure
-	jsonLD := make(map[string]interface{})
-
-	if len(triples) > 0 {
-		jsonLD["@id"] = triples[0].Subject
-
-		for _, triple := range triples {
-			predicate := triple.Predicate
-			if triple.IsLiteral {
-				jsonLD[predicate] = triple.Object
-			} else {
-				jsonLD[predicate] = map[string]string{"@id": triple.Object}
-			}
-		}
+// tripleFromJSONLDQuad converts a json-gold quad into an rdf.Triple, ignoring its graph name
+func tripleFromJSONLDQuad(quad *ld.Quad) (rdf.Triple, error) {
+	subject, err := fromJSONLDNode(quad.Subject)
+	if err != nil {
+		return rdf.Triple{}, err
 	}
-
-	result, err := json.MarshalIndent(jsonLD, "", "  ")
+	predicate, err := fromJSONLDNode(quad.Predicate)
 	if err != nil {
-		return "", err
+		return rdf.Triple{}, err
 	}
-
-	return string(result), nil
-}
-
-func (s *StandardRDFValidationService) serializeTriplesToTurtle(triples []rdfTriple) (string, error) {
-	var turtle strings.Builder
-
-	for _, triple := range triples {
-		turtle.WriteString(fmt.Sprintf("<%s> <%s> ", triple.Subject, triple.Predicate))
-
-		if triple.IsLiteral {
-			turtle.WriteString(fmt.Sprintf(`"%s"`, triple.Object))
-		} else {
-			turtle.WriteString(fmt.Sprintf("<%s>", triple.Object))
-		}
-
-		turtle.WriteString(" .\n")
+	object, err := fromJSONLDNode(quad.Object)
+	if err != nil {
+		return rdf.Triple{}, err
 	}
-
-	return turtle.String(), nil
+	return rdf.Triple{Subject: subject, Predicate: predicate, Object: object}, nil
 }
 
-func (s *StandardRDFValidationService) serializeTriplesToRDFXML(triples []rdfTriple) (string, error) {
-	var rdfxml strings.Builder
-
-	rdfxml.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-	rdfxml.WriteString(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
-
-	if len(triples) > 0 {
-		subject := triples[0].Subject
-		rdfxml.WriteString(fmt.Sprintf(`  <rdf:Description rdf:about="%s">`, subject) + "\n")
-
-		for _, triple := range triples {
-			if triple.Subject == subject {
-				predicate := triple.Predicate
-				rdfxml.WriteString(fmt.Sprintf(`    <%s>`, predicate))
-
-				if triple.IsLiteral {
-					rdfxml.WriteString(triple.Object)
-				} else {
-					rdfxml.WriteString(fmt.Sprintf(` rdf:resource="%s"`, triple.Object))
-				}
-
-				rdfxml.WriteString(fmt.Sprintf(`</%s>`, predicate) + "\n")
-			}
-		}
-
-		rdfxml.WriteString("  </rdf:Description>\n")
-	}
+// Helper methods for serializing graphs to different formats
 
-	rdfxml.WriteString("</rdf:RDF>\n")
+func (s *StandardRDFValidationService) serializeGraphToTurtle(graph *rdf.Graph) (string, error) {
+	writer := newTurtleWriter(rdf.NewPrefixMap(s.prefixes...))
+	writer.base = s.base
+	return writer.Write(graph), nil
+}
 
-	return rdfxml.String(), nil
+func (s *StandardRDFValidationService) serializeGraphToRDFXML(graph *rdf.Graph) (string, error) {
+	return newRDFXMLWriter(rdf.NewPrefixMap(s.prefixes...)).Write(graph)
 }
 
-func (s *StandardRDFValidationService) serializeTriplesToN3(triples []rdfTriple) (string, error) {
+func (s *StandardRDFValidationService) serializeGraphToN3(graph *rdf.Graph) (string, error) {
 	// N3 can be serialized as Turtle
-	return s.serializeTriplesToTurtle(triples)
+	return s.serializeGraphToTurtle(graph)
 }
 
-func (s *StandardRDFValidationService) serializeTriplesToNTriples(triples []rdfTriple) (string, error) {
-	var ntriples strings.Builder
-
-	for _, triple := range triples {
-		ntriples.WriteString(fmt.Sprintf("<%s> <%s> ", triple.Subject, triple.Predicate))
-
-		if triple.IsLiteral {
-			ntriples.WriteString(fmt.Sprintf(`"%s"`, triple.Object))
-		} else {
-			ntriples.WriteString(fmt.Sprintf("<%s>", triple.Object))
-		}
-
-		ntriples.WriteString(" .\n")
-	}
+func (s *StandardRDFValidationService) serializeGraphToNTriples(graph *rdf.Graph) (string, error) {
+	return graph.String(), nil
+}
 
-	return ntriples.String(), nil
+// xmlEscape escapes text for use in XML character data and attribute values
+func xmlEscape(value string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
 }
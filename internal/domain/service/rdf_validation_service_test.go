@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
 	"github.com/wepala/vine-pod/internal/domain/service"
 )
 
@@ -169,6 +170,29 @@ func TestStandardRDFValidationService_ValidateRDFXML(t *testing.T) {
 		assert.Contains(t, err.Error(), "RDF/XML parsing failed")
 	})
 
+	t.Run("returns error for invalid RDF/XML after the first description", func(t *testing.T) {
+		// Arrange
+		invalidRDFXML := `<?xml version="1.0" encoding="UTF-8"?>
+<rdf:RDF
+  xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+  xmlns:foaf="http://xmlns.com/foaf/0.1/">
+  <rdf:Description rdf:about="https://example.com/resource">
+    <foaf:name>Resource</foaf:name>
+  </rdf:Description>
+  <rdf:Description rdf:about="https://example.com/other">
+    <foaf:name>Other</foaf:nick>
+  </rdf:Description>
+</rdf:RDF>`
+
+		// Act
+		resourceID, err := rdfService.ValidateRDFXML(invalidRDFXML)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Empty(t, resourceID)
+		assert.Contains(t, err.Error(), "RDF/XML parsing failed")
+	})
+
 	t.Run("validates minimal RDF/XML data", func(t *testing.T) {
 		// Arrange
 		rdfxml := `<?xml version="1.0"?>
@@ -233,9 +257,8 @@ func TestStandardRDFValidationService_ConvertFormat(t *testing.T) {
 		// Assert
 		assert.NoError(t, err)
 		assert.NotEmpty(t, result)
-		assert.Contains(t, result, "https://example.com/resource1")
-		// Note: The simplified implementation creates a basic triple
-		assert.Contains(t, result, "Resource")
+		assert.Contains(t, result, "<https://example.com/resource1>")
+		assert.Contains(t, result, "a foaf:Person")
 	})
 
 	t.Run("converts Turtle to JSON-LD", func(t *testing.T) {
@@ -251,6 +274,38 @@ func TestStandardRDFValidationService_ConvertFormat(t *testing.T) {
 		assert.Contains(t, result, "https://example.com/resource1")
 	})
 
+	t.Run("preserves datatypes, language tags and blank nodes on round trips", func(t *testing.T) {
+		// Arrange
+		turtle := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+<https://example.com/alice> foaf:name "Alice \"Al\" Smith" ;
+    foaf:nick "alice"@en ;
+    foaf:age "42"^^xsd:integer ;
+    foaf:knows _:bob .
+_:bob foaf:name "Bob" .`
+		original, err := rdfService.ParseGraph(turtle, string(service.FormatTurtle))
+		assert.NoError(t, err)
+
+		for _, format := range []service.RDFFormat{service.FormatNTriples, service.FormatJSONLD, service.FormatTurtle} {
+			// Act
+			converted, err := rdfService.ConvertFormat(turtle, string(service.FormatTurtle), string(format))
+			assert.NoError(t, err)
+			roundTripped, err := rdfService.ParseGraph(converted, string(format))
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, original.Len(), roundTripped.Len(), "format %s", format)
+			assert.Len(t, roundTripped.Match(nil, rdf.IRI("http://xmlns.com/foaf/0.1/age"), rdf.NewTypedLiteral("42", rdf.XSDNamespace+"integer")), 1, "format %s", format)
+			assert.Len(t, roundTripped.Match(nil, nil, rdf.NewLangLiteral("alice", "en")), 1, "format %s", format)
+			assert.Len(t, roundTripped.Match(nil, nil, rdf.NewLiteral(`Alice "Al" Smith`)), 1, "format %s", format)
+			knows := roundTripped.Match(rdf.IRI("https://example.com/alice"), rdf.IRI("http://xmlns.com/foaf/0.1/knows"), nil)
+			if assert.Len(t, knows, 1, "format %s", format) {
+				assert.Equal(t, rdf.KindBlankNode, knows[0].Object.Kind())
+			}
+		}
+	})
+
 	t.Run("returns error for unsupported source format", func(t *testing.T) {
 		// Arrange
 		data := `some data`
@@ -278,6 +333,55 @@ func TestStandardRDFValidationService_ConvertFormat(t *testing.T) {
 	})
 }
 
+func TestStandardRDFValidationService_WithBase(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService().WithBase("https://pod.example.com/people/alice")
+
+	t.Run("resolves relative IRIs in every format", func(t *testing.T) {
+		// Arrange
+		rdfService := service.NewStandardRDFValidationServiceWithOptions(service.RDFServiceOptions{Identity: service.IdentityDocumentSubject}).
+			WithBase("https://pod.example.com/people/alice")
+
+		// Act
+		turtleID, turtleErr := rdfService.ValidateTurtle(`<#me> <http://xmlns.com/foaf/0.1/name> "Alice" .`)
+		jsonLDID, jsonLDErr := rdfService.ValidateJSONLD(`{"@id": "#me", "http://xmlns.com/foaf/0.1/name": "Alice"}`)
+		rdfXMLID, rdfXMLErr := rdfService.ValidateRDFXML(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:foaf="http://xmlns.com/foaf/0.1/">
+  <rdf:Description rdf:about="#me"><foaf:name>Alice</foaf:name></rdf:Description>
+</rdf:RDF>`)
+		graph, graphErr := rdfService.ParseGraph(`<> <http://xmlns.com/foaf/0.1/primaryTopic> <#me> ; <http://www.w3.org/2000/01/rdf-schema#seeAlso> <bob> .`, "text/turtle")
+
+		// Assert
+		assert.NoError(t, turtleErr)
+		assert.NoError(t, jsonLDErr)
+		assert.NoError(t, rdfXMLErr)
+		assert.NoError(t, graphErr)
+		assert.Equal(t, "https://pod.example.com/people/alice#me", turtleID)
+		assert.Equal(t, "https://pod.example.com/people/alice#me", jsonLDID)
+		assert.Equal(t, "https://pod.example.com/people/alice#me", rdfXMLID)
+		assert.True(t, graph.Contains(rdf.Triple{
+			Subject:   rdf.IRI("https://pod.example.com/people/alice"),
+			Predicate: rdf.IRI("http://www.w3.org/2000/01/rdf-schema#seeAlso"),
+			Object:    rdf.IRI("https://pod.example.com/people/bob"),
+		}))
+	})
+
+	t.Run("writes the base document and its fragments as relative references", func(t *testing.T) {
+		// Arrange
+		graph := rdf.NewGraph()
+		graph.Add(rdf.Triple{
+			Subject:   rdf.IRI("https://pod.example.com/people/alice"),
+			Predicate: rdf.IRI("http://xmlns.com/foaf/0.1/primaryTopic"),
+			Object:    rdf.IRI("https://pod.example.com/people/alice#me"),
+		})
+
+		// Act
+		turtle, err := rdfService.SerializeGraph(graph, string(service.FormatTurtle))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Contains(t, turtle, "<>\n    foaf:primaryTopic <#me> .")
+	})
+}
+
 func TestStandardRDFValidationService_SupportedFormats(t *testing.T) {
 	rdfService := service.NewStandardRDFValidationService()
 
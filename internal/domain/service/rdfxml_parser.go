@@ -0,0 +1,629 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// maxRDFXMLNesting bounds how deeply node and property elements may nest,
+// so that a document cannot exhaust the stack
+const maxRDFXMLNesting = 256
+
+// xmlNamespace is the namespace of the xml: attributes
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+const (
+	rdfXMLLiteral rdf.IRI = rdf.RDFNamespace + "XMLLiteral"
+	rdfStatement  rdf.IRI = rdf.RDFNamespace + "Statement"
+	rdfSubject    rdf.IRI = rdf.RDFNamespace + "subject"
+	rdfPredicate  rdf.IRI = rdf.RDFNamespace + "predicate"
+	rdfObject     rdf.IRI = rdf.RDFNamespace + "object"
+)
+
+// rdfXMLSyntaxNames are the rdf: names that may not be used as node or
+// property elements
+var rdfXMLSyntaxNames = map[string]bool{
+	"RDF": true, "ID": true, "about": true, "bagID": true, "parseType": true,
+	"resource": true, "nodeID": true, "datatype": true,
+	"aboutEach": true, "aboutEachPrefix": true,
+}
+
+// parseRDFXML reads an RDF/XML document from r into a graph, resolving
+// relative IRIs against base unless the document sets xml:base, and
+// returns the graph with the subject of its first triple. Elements are
+// read one at a time and each triple is checked against the limiter as
+// soon as it is read, so an oversized document fails without being held
+// in memory.
+func parseRDFXML(r io.Reader, base string, limiter *tripleLimiter) (*rdf.Graph, rdf.Term, error) {
+	p := &rdfXMLParser{
+		decoder: xml.NewDecoder(r),
+		labels:  make(map[string]rdf.BlankNode),
+		used:    make(map[rdf.BlankNode]bool),
+		graph:   rdf.NewGraph(),
+		limiter: limiter,
+	}
+	if limiter != nil {
+		p.maxLiteral = limiter.limits.MaxLiteralLength
+	}
+
+	root, err := p.nextElement()
+	if err == io.EOF {
+		return p.graph, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if root == nil {
+		return nil, nil, p.errorf("unexpected end element")
+	}
+
+	scope := rdfXMLScope{base: base}
+	if isRDFName(root.Name, "RDF") {
+		if err := p.parseNodeElementList(scope.enter(root)); err != nil {
+			return nil, nil, err
+		}
+	} else if _, err := p.parseNodeElement(root, scope); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := p.nextElement(); err != io.EOF {
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, p.errorf("unexpected content after the root element")
+	}
+	return p.graph, p.first, nil
+}
+
+// rdfXMLScope holds the base IRI and language in force for an element
+type rdfXMLScope struct {
+	base string
+	lang string
+}
+
+// enter returns the scope inside an element, applying its xml:base and
+// xml:lang attributes
+func (s rdfXMLScope) enter(element *xml.StartElement) rdfXMLScope {
+	for _, attr := range element.Attr {
+		if attr.Name.Space != xmlNamespace {
+			continue
+		}
+		switch attr.Name.Local {
+		case "base":
+			s.base = s.resolve(attr.Value)
+		case "lang":
+			s.lang = attr.Value
+		}
+	}
+	return s
+}
+
+// resolve resolves an IRI reference against the base of the scope
+func (s rdfXMLScope) resolve(ref string) string {
+	if absoluteIRI.MatchString(ref) {
+		return ref
+	}
+	return resolveIRI(s.base, ref)
+}
+
+// literal returns a plain literal, tagged with the language of the scope
+func (s rdfXMLScope) literal(lexical string) rdf.Literal {
+	if s.lang != "" {
+		return rdf.NewLangLiteral(lexical, s.lang)
+	}
+	return rdf.NewLiteral(lexical)
+}
+
+// rdfXMLParser reads the node and property elements of an RDF/XML document
+type rdfXMLParser struct {
+	decoder    *xml.Decoder
+	labels     map[string]rdf.BlankNode
+	used       map[rdf.BlankNode]bool
+	anonymous  int
+	depth      int
+	maxLiteral int
+	graph      *rdf.Graph
+	first      rdf.Term
+	limiter    *tripleLimiter
+}
+
+func (p *rdfXMLParser) errorf(format string, args ...interface{}) error {
+	line, column := p.decoder.InputPos()
+	return NewValidationErrorWithPosition(FormatRDFXML, line, column, fmt.Sprintf(format, args...), nil)
+}
+
+// token reads the next XML token, reporting malformed XML as a validation
+// error
+func (p *rdfXMLParser) token() (xml.Token, error) {
+	token, err := p.decoder.Token()
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return nil, p.errorf("%s", syntaxErr.Msg)
+	}
+	return token, err
+}
+
+// nextElement skips comments, processing instructions and whitespace and
+// returns the next start element, nil at an end element, or io.EOF at the
+// end of the document
+func (p *rdfXMLParser) nextElement() (*xml.StartElement, error) {
+	for {
+		token, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return &t, nil
+		case xml.EndElement:
+			return nil, nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return nil, p.errorf("unexpected text %q", strings.TrimSpace(string(t)))
+			}
+		}
+	}
+}
+
+// nested tracks one more level of element nesting
+func (p *rdfXMLParser) nested() (func(), error) {
+	p.depth++
+	done := func() { p.depth-- }
+	if p.depth > maxRDFXMLNesting {
+		done()
+		return nil, p.errorf("elements nest too deeply")
+	}
+	return done, nil
+}
+
+// add checks a triple against the limits and adds it to the graph
+func (p *rdfXMLParser) add(subject, predicate, object rdf.Term) error {
+	t := rdf.Triple{Subject: subject, Predicate: predicate, Object: object}
+	if err := p.limiter.check(t); err != nil {
+		return err
+	}
+	if p.first == nil {
+		p.first = subject
+	}
+	p.graph.Add(t)
+	return nil
+}
+
+// parseNodeElementList reads node elements up to the end of their parent
+func (p *rdfXMLParser) parseNodeElementList(scope rdfXMLScope) error {
+	for {
+		element, err := p.nextElement()
+		if err == io.EOF {
+			return p.errorf("unterminated element")
+		}
+		if err != nil || element == nil {
+			return err
+		}
+		if _, err := p.parseNodeElement(element, scope); err != nil {
+			return err
+		}
+	}
+}
+
+// parseNodeElement reads a node element with its properties and returns
+// the node it describes
+func (p *rdfXMLParser) parseNodeElement(element *xml.StartElement, scope rdfXMLScope) (rdf.Term, error) {
+	done, err := p.nested()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	name, err := p.elementIRI(element.Name)
+	if err != nil {
+		return nil, err
+	}
+	if isRDFName(element.Name, "li") || rdfXMLSyntaxNames[rdfLocalName(element.Name)] {
+		return nil, p.errorf("%s cannot be used as a node element", name)
+	}
+	scope = scope.enter(element)
+
+	var subject rdf.Term
+	var properties []xml.Attr
+	for _, attr := range element.Attr {
+		var node rdf.Term
+		switch {
+		case isRDFName(attr.Name, "about"):
+			node = rdf.IRI(scope.resolve(attr.Value))
+		case isRDFName(attr.Name, "ID"):
+			node = rdf.IRI(scope.resolve("#" + attr.Value))
+		case isRDFName(attr.Name, "nodeID"):
+			node = p.blankNode(attr.Value)
+		case isPropertyAttribute(attr.Name):
+			properties = append(properties, attr)
+			continue
+		default:
+			continue
+		}
+		if subject != nil {
+			return nil, p.errorf("node element %s has more than one of rdf:about, rdf:ID and rdf:nodeID", name)
+		}
+		subject = node
+	}
+	if subject == nil {
+		subject = p.freshBlankNode()
+	}
+
+	if !isRDFName(element.Name, "Description") {
+		if err := p.add(subject, rdf.RDFType, rdf.IRI(name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.addPropertyAttributes(subject, properties, scope); err != nil {
+		return nil, err
+	}
+	return subject, p.parsePropertyElementList(subject, scope)
+}
+
+// addPropertyAttributes adds the triples given as attributes of an element
+func (p *rdfXMLParser) addPropertyAttributes(subject rdf.Term, attrs []xml.Attr, scope rdfXMLScope) error {
+	for _, attr := range attrs {
+		predicate, err := p.elementIRI(attr.Name)
+		if err != nil {
+			return err
+		}
+		var object rdf.Term = scope.literal(attr.Value)
+		if isRDFName(attr.Name, "type") {
+			object = rdf.IRI(scope.resolve(attr.Value))
+		}
+		if err := p.add(subject, rdf.IRI(predicate), object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parsePropertyElementList reads property elements up to the end of the
+// node element they describe
+func (p *rdfXMLParser) parsePropertyElementList(subject rdf.Term, scope rdfXMLScope) error {
+	members := 0
+	for {
+		element, err := p.nextElement()
+		if err == io.EOF {
+			return p.errorf("unterminated element")
+		}
+		if err != nil || element == nil {
+			return err
+		}
+		if err := p.parsePropertyElement(element, subject, scope, &members); err != nil {
+			return err
+		}
+	}
+}
+
+// parsePropertyElement reads one property element of subject. members
+// counts the rdf:li elements of the subject so far.
+func (p *rdfXMLParser) parsePropertyElement(element *xml.StartElement, subject rdf.Term, scope rdfXMLScope, members *int) error {
+	done, err := p.nested()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	name, err := p.elementIRI(element.Name)
+	if err != nil {
+		return err
+	}
+	if isRDFName(element.Name, "Description") || rdfXMLSyntaxNames[rdfLocalName(element.Name)] {
+		return p.errorf("%s cannot be used as a property element", name)
+	}
+	predicate := rdf.IRI(name)
+	if isRDFName(element.Name, "li") {
+		*members++
+		predicate = rdf.IRI(rdf.RDFNamespace + "_" + strconv.Itoa(*members))
+	}
+	scope = scope.enter(element)
+
+	var id, datatype, parseType string
+	var resource rdf.Term
+	var properties []xml.Attr
+	for _, attr := range element.Attr {
+		switch {
+		case isRDFName(attr.Name, "ID"):
+			id = attr.Value
+		case isRDFName(attr.Name, "datatype"):
+			datatype = scope.resolve(attr.Value)
+		case isRDFName(attr.Name, "parseType"):
+			parseType = attr.Value
+		case isRDFName(attr.Name, "resource"), isRDFName(attr.Name, "nodeID"):
+			if resource != nil {
+				return p.errorf("property element %s has both rdf:resource and rdf:nodeID", name)
+			}
+			if attr.Name.Local == "resource" {
+				resource = rdf.IRI(scope.resolve(attr.Value))
+			} else {
+				resource = p.blankNode(attr.Value)
+			}
+		case isPropertyAttribute(attr.Name):
+			properties = append(properties, attr)
+		}
+	}
+
+	var object rdf.Term
+	switch parseType {
+	case "":
+		if object, err = p.parsePropertyContent(subject, predicate, scope, datatype, resource, properties); err != nil {
+			return err
+		}
+	case "Resource":
+		object = p.freshBlankNode()
+		if err := p.add(subject, predicate, object); err != nil {
+			return err
+		}
+		if err := p.parsePropertyElementList(object, scope); err != nil {
+			return err
+		}
+	case "Collection":
+		if object, err = p.parseCollection(scope); err != nil {
+			return err
+		}
+		if err := p.add(subject, predicate, object); err != nil {
+			return err
+		}
+	default:
+		// Literal and any other parse type keep the content as XML
+		content, err := p.readXMLLiteral()
+		if err != nil {
+			return err
+		}
+		object = rdf.NewTypedLiteral(content, rdfXMLLiteral)
+		if err := p.add(subject, predicate, object); err != nil {
+			return err
+		}
+	}
+
+	if id == "" {
+		return nil
+	}
+	return p.reify(rdf.IRI(scope.resolve("#"+id)), subject, predicate, object)
+}
+
+// parsePropertyContent reads the content of a property element without
+// rdf:parseType: a single node element, a literal, or nothing. It adds the
+// triple and returns its object.
+func (p *rdfXMLParser) parsePropertyContent(subject, predicate rdf.Term, scope rdfXMLScope, datatype string, resource rdf.Term, properties []xml.Attr) (rdf.Term, error) {
+	var text strings.Builder
+	for {
+		token, err := p.token()
+		if err == io.EOF {
+			return nil, p.errorf("unterminated element")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			if p.maxLiteral > 0 && text.Len()+len(t) > p.maxLiteral {
+				return nil, limitExceeded(FormatRDFXML, LimitLiteralLength, int64(p.maxLiteral))
+			}
+			text.Write(t)
+		case xml.StartElement:
+			if strings.TrimSpace(text.String()) != "" || datatype != "" || resource != nil || len(properties) > 0 {
+				return nil, p.errorf("property element %s mixes a node element with text or attributes", predicate)
+			}
+			object, err := p.parseNodeElement(&t, scope)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.add(subject, predicate, object); err != nil {
+				return nil, err
+			}
+			if element, err := p.nextElement(); err != nil || element != nil {
+				if err != nil && err != io.EOF {
+					return nil, err
+				}
+				return nil, p.errorf("property element %s has more than one node element", predicate)
+			}
+			return object, nil
+		case xml.EndElement:
+			return p.addPropertyValue(subject, predicate, scope, text.String(), datatype, resource, properties)
+		}
+	}
+}
+
+// addPropertyValue adds the triple of a property element that holds text
+// or is empty and returns its object
+func (p *rdfXMLParser) addPropertyValue(subject, predicate rdf.Term, scope rdfXMLScope, text string, datatype string, resource rdf.Term, properties []xml.Attr) (rdf.Term, error) {
+	if resource == nil && len(properties) == 0 {
+		var object rdf.Term = scope.literal(text)
+		if datatype != "" {
+			object = rdf.NewTypedLiteral(text, rdf.IRI(datatype))
+		}
+		return object, p.add(subject, predicate, object)
+	}
+
+	if strings.TrimSpace(text) != "" || datatype != "" {
+		return nil, p.errorf("property element %s has both a resource and a literal value", predicate)
+	}
+	object := resource
+	if object == nil {
+		object = p.freshBlankNode()
+	}
+	if err := p.add(subject, predicate, object); err != nil {
+		return nil, err
+	}
+	return object, p.addPropertyAttributes(object, properties, scope)
+}
+
+// parseCollection reads the node elements of rdf:parseType="Collection"
+// as an RDF list and returns its head
+func (p *rdfXMLParser) parseCollection(scope rdfXMLScope) (rdf.Term, error) {
+	var items []rdf.Term
+	for {
+		element, err := p.nextElement()
+		if err == io.EOF {
+			return nil, p.errorf("unterminated collection")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if element == nil {
+			break
+		}
+		item, err := p.parseNodeElement(element, scope)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	nodes := make([]rdf.Term, len(items))
+	for i := range items {
+		nodes[i] = p.freshBlankNode()
+	}
+	for i, item := range items {
+		var rest rdf.Term = rdfNil
+		if i+1 < len(nodes) {
+			rest = nodes[i+1]
+		}
+		if err := p.add(nodes[i], rdfFirst, item); err != nil {
+			return nil, err
+		}
+		if err := p.add(nodes[i], rdfRest, rest); err != nil {
+			return nil, err
+		}
+	}
+	if len(nodes) == 0 {
+		return rdfNil, nil
+	}
+	return nodes[0], nil
+}
+
+// readXMLLiteral re-serializes the content of the current element up to
+// its end, declaring the namespaces each element uses
+func (p *rdfXMLParser) readXMLLiteral() (string, error) {
+	var content limitedBuffer
+	content.max = p.maxLiteral
+	encoder := xml.NewEncoder(&content)
+	for depth := 0; ; {
+		token, err := p.token()
+		if err == io.EOF {
+			return "", p.errorf("unterminated element")
+		}
+		if err != nil {
+			return "", err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				if err := encoder.Flush(); err != nil {
+					return "", err
+				}
+				return content.String(), nil
+			}
+			depth--
+		}
+		if err := encoder.EncodeToken(token); err != nil {
+			return "", err
+		}
+	}
+}
+
+// limitedBuffer is a buffer that refuses to grow past max bytes when max
+// is positive
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if b.max > 0 && b.Len()+len(data) > b.max {
+		return 0, limitExceeded(FormatRDFXML, LimitLiteralLength, int64(b.max))
+	}
+	return b.Buffer.Write(data)
+}
+
+// reify adds the triples that describe a statement named by rdf:ID
+func (p *rdfXMLParser) reify(statement rdf.IRI, subject, predicate, object rdf.Term) error {
+	for _, t := range []rdf.Triple{
+		{Subject: statement, Predicate: rdf.RDFType, Object: rdfStatement},
+		{Subject: statement, Predicate: rdfSubject, Object: subject},
+		{Subject: statement, Predicate: rdfPredicate, Object: predicate},
+		{Subject: statement, Predicate: rdfObject, Object: object},
+	} {
+		if err := p.add(t.Subject, t.Predicate, t.Object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// elementIRI returns the IRI an element or attribute name stands for
+func (p *rdfXMLParser) elementIRI(name xml.Name) (string, error) {
+	if name.Space == "" {
+		return "", p.errorf("%s is not in a namespace", name.Local)
+	}
+	return name.Space + name.Local, nil
+}
+
+// blankNode returns the node for an rdf:nodeID
+func (p *rdfXMLParser) blankNode(label string) rdf.BlankNode {
+	if node, ok := p.labels[label]; ok {
+		return node
+	}
+	node := rdf.BlankNode(label)
+	if p.used[node] {
+		node = p.freshBlankNode()
+	}
+	p.labels[label] = node
+	p.used[node] = true
+	return node
+}
+
+// freshBlankNode returns a node for an element without an identifier, with
+// a label not used anywhere else in the document
+func (p *rdfXMLParser) freshBlankNode() rdf.BlankNode {
+	for {
+		p.anonymous++
+		node := rdf.BlankNode(fmt.Sprintf("b%d", p.anonymous))
+		if !p.used[node] {
+			p.used[node] = true
+			return node
+		}
+	}
+}
+
+// isRDFName reports whether name is local in the RDF namespace
+func isRDFName(name xml.Name, local string) bool {
+	return name.Space == rdf.RDFNamespace && name.Local == local
+}
+
+// rdfLocalName returns the local part of a name in the RDF namespace, or
+// "" for names in other namespaces
+func rdfLocalName(name xml.Name) string {
+	if name.Space != rdf.RDFNamespace {
+		return ""
+	}
+	return name.Local
+}
+
+// isPropertyAttribute reports whether an attribute states a property
+// rather than being part of the RDF/XML or XML syntax
+func isPropertyAttribute(name xml.Name) bool {
+	switch {
+	case name.Space == "xmlns", name.Space == "" && name.Local == "xmlns", name.Space == xmlNamespace:
+		return false
+	case name.Space == "":
+		// Unqualified attributes are not allowed; they are ignored like
+		// other RDF/XML parsers do
+		return false
+	case rdfXMLSyntaxNames[rdfLocalName(name)], isRDFName(name, "li"), isRDFName(name, "Description"):
+		return false
+	}
+	return true
+}
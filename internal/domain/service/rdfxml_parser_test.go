@@ -0,0 +1,130 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestRDFXMLParsing(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService().WithBase("https://example.com/doc")
+
+	foaf := "http://xmlns.com/foaf/0.1/"
+	alice := rdf.IRI("https://example.com/alice")
+	header := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:foaf="http://xmlns.com/foaf/0.1/">`
+
+	t.Run("reads node elements, property attributes and scheme IRIs", func(t *testing.T) {
+		// Arrange
+		data := header + `
+  <foaf:Person rdf:about="alice" foaf:nick="ali" xml:lang="en">
+    <foaf:mbox rdf:resource="mailto:alice@example.com"/>
+    <foaf:account rdf:resource="urn:uuid:1234"/>
+    <foaf:age rdf:datatype="http://www.w3.org/2001/XMLSchema#integer">42</foaf:age>
+    <foaf:name xml:lang="">Alice</foaf:name>
+  </foaf:Person>
+</rdf:RDF>`
+
+		// Act
+		graph, err := rdfService.ParseGraph(data, string(service.FormatRDFXML))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 6, graph.Len())
+		for _, triple := range []rdf.Triple{
+			{Subject: alice, Predicate: rdf.RDFType, Object: rdf.IRI(foaf + "Person")},
+			{Subject: alice, Predicate: rdf.IRI(foaf + "nick"), Object: rdf.NewLangLiteral("ali", "en")},
+			{Subject: alice, Predicate: rdf.IRI(foaf + "mbox"), Object: rdf.IRI("mailto:alice@example.com")},
+			{Subject: alice, Predicate: rdf.IRI(foaf + "account"), Object: rdf.IRI("urn:uuid:1234")},
+			{Subject: alice, Predicate: rdf.IRI(foaf + "age"), Object: rdf.NewTypedLiteral("42", rdf.IRI("http://www.w3.org/2001/XMLSchema#integer"))},
+			{Subject: alice, Predicate: rdf.IRI(foaf + "name"), Object: rdf.NewLiteral("Alice")},
+		} {
+			assert.True(t, graph.Contains(triple), triple.String())
+		}
+	})
+
+	t.Run("reads nested nodes, blank nodes and parse types", func(t *testing.T) {
+		// Arrange
+		data := header + `
+  <rdf:Description rdf:about="https://example.com/alice" xml:base="https://example.com/people/">
+    <foaf:knows>
+      <foaf:Person rdf:about="bob"/>
+    </foaf:knows>
+    <foaf:knows rdf:nodeID="carol"/>
+    <foaf:based_near rdf:parseType="Resource">
+      <foaf:name>Paris</foaf:name>
+    </foaf:based_near>
+    <foaf:interest rdf:parseType="Collection">
+      <rdf:Description rdf:about="https://example.com/music"/>
+      <rdf:Description rdf:about="https://example.com/chess"/>
+    </foaf:interest>
+    <foaf:status rdf:parseType="Literal"><b>busy</b></foaf:status>
+  </rdf:Description>
+  <rdf:Description rdf:nodeID="carol" foaf:name="Carol"/>
+</rdf:RDF>`
+
+		// Act
+		graph, err := rdfService.ParseGraph(data, string(service.FormatRDFXML))
+
+		// Assert
+		require.NoError(t, err)
+		knows := graph.Match(alice, rdf.IRI(foaf+"knows"), nil)
+		require.Len(t, knows, 2)
+		assert.True(t, graph.Contains(rdf.Triple{Subject: alice, Predicate: rdf.IRI(foaf + "knows"), Object: rdf.IRI("https://example.com/people/bob")}))
+		assert.Len(t, graph.Match(nil, rdf.IRI(foaf+"name"), rdf.NewLiteral("Carol")), 1)
+
+		place := graph.Match(alice, rdf.IRI(foaf+"based_near"), nil)
+		require.Len(t, place, 1)
+		assert.True(t, graph.Contains(rdf.Triple{Subject: place[0].Object, Predicate: rdf.IRI(foaf + "name"), Object: rdf.NewLiteral("Paris")}))
+
+		interests := graph.Match(alice, rdf.IRI(foaf+"interest"), nil)
+		require.Len(t, interests, 1)
+		assert.Len(t, graph.Match(nil, rdf.IRI(rdf.RDFNamespace+"first"), nil), 2)
+		assert.True(t, graph.Contains(rdf.Triple{Subject: interests[0].Object, Predicate: rdf.IRI(rdf.RDFNamespace + "first"), Object: rdf.IRI("https://example.com/music")}))
+
+		status := graph.Match(alice, rdf.IRI(foaf+"status"), nil)
+		require.Len(t, status, 1)
+		literal, ok := status[0].Object.(rdf.Literal)
+		require.True(t, ok)
+		assert.Equal(t, rdf.IRI(rdf.RDFNamespace+"XMLLiteral"), literal.Datatype)
+		assert.Contains(t, literal.Lexical, "busy")
+	})
+
+	t.Run("numbers rdf:li members", func(t *testing.T) {
+		// Arrange
+		data := header + `
+  <rdf:Seq rdf:about="https://example.com/list">
+    <rdf:li>first</rdf:li>
+    <rdf:li>second</rdf:li>
+  </rdf:Seq>
+</rdf:RDF>`
+
+		// Act
+		graph, err := rdfService.ParseGraph(data, string(service.FormatRDFXML))
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, graph.Contains(rdf.Triple{Subject: rdf.IRI("https://example.com/list"), Predicate: rdf.IRI(rdf.RDFNamespace + "_2"), Object: rdf.NewLiteral("second")}))
+	})
+
+	t.Run("rejects malformed documents", func(t *testing.T) {
+		for _, data := range []string{
+			header + `<rdf:Description rdf:about="a"><foaf:name>x</foaf:nick></rdf:Description></rdf:RDF>`,
+			header + `<rdf:Description rdf:about="a"><foaf:name>x</foaf:name>`,
+			header + `<rdf:Description rdf:about="a" rdf:nodeID="b"/></rdf:RDF>`,
+			header + `<rdf:Description rdf:about="a"><foaf:knows rdf:resource="b">text</foaf:knows></rdf:Description></rdf:RDF>`,
+			header + `<rdf:Description rdf:about="a">text</rdf:Description></rdf:RDF>`,
+			header + `<rdf:Description rdf:about="a"><unqualified>x</unqualified></rdf:Description></rdf:RDF>`,
+			header + `</rdf:RDF><rdf:Description/>`,
+		} {
+			// Act
+			_, err := rdfService.ParseGraph(data, string(service.FormatRDFXML))
+
+			// Assert
+			var validationErr *service.ValidationError
+			assert.ErrorAs(t, err, &validationErr, data)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// rdfXMLWriter serializes a graph as RDF/XML. Triples are grouped into one
+// rdf:Description per subject, predicates are written as qualified names
+// with generated namespace declarations, and blank nodes use rdf:nodeID.
+type rdfXMLWriter struct {
+	prefixes *rdf.PrefixMap
+	nodeIDs  map[rdf.BlankNode]string
+	usedIDs  map[string]bool
+}
+
+// newRDFXMLWriter creates a writer that prefers the given prefixes and
+// generates new ones for any other namespace
+func newRDFXMLWriter(prefixes *rdf.PrefixMap) *rdfXMLWriter {
+	return &rdfXMLWriter{
+		prefixes: prefixes,
+		nodeIDs:  make(map[rdf.BlankNode]string),
+		usedIDs:  make(map[string]bool),
+	}
+}
+
+// Write serializes the graph
+func (w *rdfXMLWriter) Write(graph *rdf.Graph) (string, error) {
+	triples := graph.SortedTriples()
+
+	// Resolve predicate names first so that only used namespaces are declared
+	used := map[string]string{"rdf": rdf.RDFNamespace}
+	qnames := make(map[rdf.Term]string)
+	for _, t := range triples {
+		if _, done := qnames[t.Predicate]; done {
+			continue
+		}
+		namespace, local, ok := rdf.SplitIRI(t.Predicate.Value())
+		if !ok {
+			return "", fmt.Errorf("predicate %s cannot be expressed as an XML qualified name", t.Predicate)
+		}
+		prefix := w.prefixes.Ensure(namespace)
+		used[prefix] = namespace
+		qnames[t.Predicate] = prefix + ":" + local
+	}
+
+	var body strings.Builder
+	var current rdf.Term
+	for _, t := range triples {
+		if current == nil || !current.Equal(t.Subject) {
+			if current != nil {
+				body.WriteString("  </rdf:Description>\n")
+			}
+			current = t.Subject
+			body.WriteString("  <rdf:Description " + w.subjectAttribute(t.Subject) + ">\n")
+		}
+		body.WriteString("    " + w.propertyElement(qnames[t.Predicate], t.Object) + "\n")
+	}
+	if current != nil {
+		body.WriteString("  </rdf:Description>\n")
+	}
+
+	var out strings.Builder
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.WriteString("<rdf:RDF")
+	for _, prefix := range sortedKeys(used) {
+		out.WriteString(fmt.Sprintf("\n  xmlns:%s=\"%s\"", prefix, xmlEscape(used[prefix])))
+	}
+	out.WriteString(">\n")
+	out.WriteString(body.String())
+	out.WriteString("</rdf:RDF>\n")
+
+	return out.String(), nil
+}
+
+// subjectAttribute returns the rdf:about or rdf:nodeID attribute for a subject
+func (w *rdfXMLWriter) subjectAttribute(subject rdf.Term) string {
+	if b, ok := subject.(rdf.BlankNode); ok {
+		return `rdf:nodeID="` + w.nodeID(b) + `"`
+	}
+	return `rdf:about="` + xmlEscape(subject.Value()) + `"`
+}
+
+// propertyElement returns the property element for a predicate and object
+func (w *rdfXMLWriter) propertyElement(qname string, object rdf.Term) string {
+	switch o := object.(type) {
+	case rdf.IRI:
+		return "<" + qname + ` rdf:resource="` + xmlEscape(string(o)) + `"/>`
+	case rdf.BlankNode:
+		return "<" + qname + ` rdf:nodeID="` + w.nodeID(o) + `"/>`
+	case rdf.Literal:
+		attrs := ""
+		if o.Language != "" {
+			attrs = ` xml:lang="` + xmlEscape(o.Language) + `"`
+		} else if dt := o.EffectiveDatatype(); dt != rdf.XSDString {
+			attrs = ` rdf:datatype="` + xmlEscape(string(dt)) + `"`
+		}
+		return "<" + qname + attrs + ">" + xmlEscape(o.Lexical) + "</" + qname + ">"
+	default:
+		return ""
+	}
+}
+
+// nodeID returns an NCName-safe identifier for a blank node, stable within the document
+func (w *rdfXMLWriter) nodeID(b rdf.BlankNode) string {
+	if id, ok := w.nodeIDs[b]; ok {
+		return id
+	}
+	id := string(b)
+	for n := len(w.nodeIDs); !rdf.IsNCName(id) || w.usedIDs[id]; n++ {
+		id = fmt.Sprintf("b%d", n)
+	}
+	w.nodeIDs[b] = id
+	w.usedIDs[id] = true
+	return id
+}
+
+// sortedKeys returns the keys of a string map in ascending order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestRDFXMLSerialization(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService()
+
+	turtle := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+@prefix ex: <https://vocab.example.com/terms#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+<https://example.com/alice> a foaf:Person ;
+    foaf:name "Alice <& \"Co\">" ;
+    foaf:nick "ali"@en ;
+    ex:score "9.5"^^xsd:decimal ;
+    foaf:knows _:friend, <https://example.com/carol> .
+_:friend foaf:name "Bob" .
+<https://example.com/carol> foaf:name "Carol" .`
+
+	t.Run("produces well-formed XML with namespace declarations", func(t *testing.T) {
+		// Act
+		result, err := rdfService.ConvertFormat(turtle, string(service.FormatTurtle), string(service.FormatRDFXML))
+
+		// Assert
+		require.NoError(t, err)
+		decoder := xml.NewDecoder(strings.NewReader(result))
+		for {
+			_, err := decoder.Token()
+			if err != nil {
+				assert.Equal(t, "EOF", err.Error())
+				break
+			}
+		}
+		assert.Contains(t, result, `xmlns:foaf="http://xmlns.com/foaf/0.1/"`)
+		assert.Contains(t, result, `xmlns:ns0="https://vocab.example.com/terms#"`)
+		assert.Contains(t, result, `<ns0:score rdf:datatype="http://www.w3.org/2001/XMLSchema#decimal">9.5</ns0:score>`)
+		assert.Contains(t, result, `<foaf:nick xml:lang="en">ali</foaf:nick>`)
+		assert.Contains(t, result, `rdf:nodeID=`)
+		assert.Equal(t, 3, strings.Count(result, "<rdf:Description "))
+	})
+
+	t.Run("parses back to the same graph", func(t *testing.T) {
+		// Arrange
+		original, err := rdfService.ParseGraph(turtle, string(service.FormatTurtle))
+		require.NoError(t, err)
+
+		// Act
+		result, err := rdfService.SerializeGraph(original, string(service.FormatRDFXML))
+		require.NoError(t, err)
+		roundTripped, err := rdfService.ParseGraph(result, string(service.FormatRDFXML))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, original.Len(), roundTripped.Len())
+		assert.Len(t, roundTripped.Match(nil, nil, rdf.NewLiteral(`Alice <& "Co">`)), 1)
+		assert.Len(t, roundTripped.Match(nil, nil, rdf.NewLangLiteral("ali", "en")), 1)
+		assert.Len(t, roundTripped.Match(nil, rdf.IRI("https://vocab.example.com/terms#score"),
+			rdf.NewTypedLiteral("9.5", rdf.XSDNamespace+"decimal")), 1)
+		assert.Len(t, roundTripped.Match(rdf.IRI("https://example.com/carol"), nil, nil), 1)
+
+		resourceID, err := rdfService.ValidateRDFXML(result)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resourceID)
+	})
+
+	t.Run("rejects predicates without a valid XML local name", func(t *testing.T) {
+		// Arrange
+		ntriples := `<https://example.com/a> <https://example.com/p/123> "x" .`
+
+		// Act
+		_, err := rdfService.ConvertFormat(ntriples, string(service.FormatNTriples), string(service.FormatRDFXML))
+
+		// Assert
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "XML qualified name")
+	})
+}
@@ -0,0 +1,814 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// SHACL constraint parameters
+const (
+	shClass                        rdf.IRI = SHNamespace + "class"
+	shDatatype                     rdf.IRI = SHNamespace + "datatype"
+	shNodeKind                     rdf.IRI = SHNamespace + "nodeKind"
+	shMinCount                     rdf.IRI = SHNamespace + "minCount"
+	shMaxCount                     rdf.IRI = SHNamespace + "maxCount"
+	shMinExclusive                 rdf.IRI = SHNamespace + "minExclusive"
+	shMinInclusive                 rdf.IRI = SHNamespace + "minInclusive"
+	shMaxExclusive                 rdf.IRI = SHNamespace + "maxExclusive"
+	shMaxInclusive                 rdf.IRI = SHNamespace + "maxInclusive"
+	shMinLength                    rdf.IRI = SHNamespace + "minLength"
+	shMaxLength                    rdf.IRI = SHNamespace + "maxLength"
+	shPattern                      rdf.IRI = SHNamespace + "pattern"
+	shFlags                        rdf.IRI = SHNamespace + "flags"
+	shLanguageIn                   rdf.IRI = SHNamespace + "languageIn"
+	shUniqueLang                   rdf.IRI = SHNamespace + "uniqueLang"
+	shEquals                       rdf.IRI = SHNamespace + "equals"
+	shDisjoint                     rdf.IRI = SHNamespace + "disjoint"
+	shLessThan                     rdf.IRI = SHNamespace + "lessThan"
+	shLessThanOrEquals             rdf.IRI = SHNamespace + "lessThanOrEquals"
+	shNot                          rdf.IRI = SHNamespace + "not"
+	shAnd                          rdf.IRI = SHNamespace + "and"
+	shOr                           rdf.IRI = SHNamespace + "or"
+	shXone                         rdf.IRI = SHNamespace + "xone"
+	shNode                         rdf.IRI = SHNamespace + "node"
+	shProperty                     rdf.IRI = SHNamespace + "property"
+	shQualifiedValueShape          rdf.IRI = SHNamespace + "qualifiedValueShape"
+	shQualifiedMinCount            rdf.IRI = SHNamespace + "qualifiedMinCount"
+	shQualifiedMaxCount            rdf.IRI = SHNamespace + "qualifiedMaxCount"
+	shQualifiedValueShapesDisjoint rdf.IRI = SHNamespace + "qualifiedValueShapesDisjoint"
+	shClosed                       rdf.IRI = SHNamespace + "closed"
+	shIgnoredProperties            rdf.IRI = SHNamespace + "ignoredProperties"
+	shHasValue                     rdf.IRI = SHNamespace + "hasValue"
+	shIn                           rdf.IRI = SHNamespace + "in"
+	shIRI                          rdf.IRI = SHNamespace + "IRI"
+	shBlankNode                    rdf.IRI = SHNamespace + "BlankNode"
+	shLiteral                      rdf.IRI = SHNamespace + "Literal"
+	shBlankNodeOrIRI               rdf.IRI = SHNamespace + "BlankNodeOrIRI"
+	shBlankNodeOrLiteral           rdf.IRI = SHNamespace + "BlankNodeOrLiteral"
+	shIRIOrLiteral                 rdf.IRI = SHNamespace + "IRIOrLiteral"
+)
+
+// constraintComponent evaluates one SHACL Core constraint component
+type constraintComponent func(c *shapeContext) ([]ValidationResult, error)
+
+// constraintComponents lists the supported SHACL Core components in the
+// order their results are reported. It is a function because the logical
+// components recurse back into shape validation.
+func constraintComponents() []constraintComponent {
+	return []constraintComponent{
+		checkClass,
+		checkDatatype,
+		checkNodeKind,
+		checkCount,
+		checkRange,
+		checkLength,
+		checkPattern,
+		checkLanguageIn,
+		checkUniqueLang,
+		checkPropertyPairs,
+		checkNot,
+		checkLogical,
+		checkNode,
+		checkProperty,
+		checkQualifiedValueShape,
+		checkClosed,
+		checkHasValue,
+		checkIn,
+	}
+}
+
+// shapeContext is a focus node being validated against one shape
+type shapeContext struct {
+	validator *shaclValidator
+	shape     rdf.Term
+	focus     rdf.Term
+	path      rdf.Term
+	values    []rdf.Term
+}
+
+// parameters returns the values of a constraint parameter on the shape
+func (c *shapeContext) parameters(parameter rdf.IRI) []rdf.Term {
+	return objects(c.validator.shapes, c.shape, parameter)
+}
+
+// parameter returns the first value of a constraint parameter on the shape
+func (c *shapeContext) parameter(parameter rdf.IRI) rdf.Term {
+	return object(c.validator.shapes, c.shape, parameter)
+}
+
+// result builds a validation result for the named component. sh:message
+// values on the shape replace the default message.
+func (c *shapeContext) result(component string, value rdf.Term, format string, args ...interface{}) ValidationResult {
+	severity := SHViolation
+	if s, ok := c.parameter(shSeverity).(rdf.IRI); ok {
+		severity = s
+	}
+
+	var messages []rdf.Literal
+	for _, m := range c.parameters(shMessage) {
+		if l, ok := m.(rdf.Literal); ok {
+			messages = append(messages, l)
+		}
+	}
+	if len(messages) == 0 {
+		messages = []rdf.Literal{rdf.NewLiteral(fmt.Sprintf(format, args...))}
+	}
+
+	return ValidationResult{
+		FocusNode:   c.focus,
+		Path:        c.path,
+		Value:       value,
+		SourceShape: c.shape,
+		Component:   rdf.IRI(SHNamespace + component + shConstraintSuffix),
+		Severity:    severity,
+		Messages:    messages,
+		pathTriples: c.validator.pathTriples(c.path),
+	}
+}
+
+func checkClass(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, class := range c.parameters(shClass) {
+		for _, value := range c.values {
+			if !c.validator.isInstanceOf(value, class) {
+				results = append(results, c.result("Class", value, "Value is not an instance of %s", class))
+			}
+		}
+	}
+	return results, nil
+}
+
+func checkDatatype(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, datatype := range c.parameters(shDatatype) {
+		for _, value := range c.values {
+			l, ok := value.(rdf.Literal)
+			if !ok || !l.EffectiveDatatype().Equal(datatype) || !isWellFormed(l) {
+				results = append(results, c.result("Datatype", value, "Value does not have datatype %s", datatype))
+			}
+		}
+	}
+	return results, nil
+}
+
+func checkNodeKind(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, kind := range c.parameters(shNodeKind) {
+		if !isNodeKind(kind) {
+			return nil, fmt.Errorf("invalid sh:nodeKind %s", kind)
+		}
+		for _, value := range c.values {
+			if !matchesNodeKind(value, kind) {
+				results = append(results, c.result("NodeKind", value, "Value does not have node kind %s", kind))
+			}
+		}
+	}
+	return results, nil
+}
+
+// matchesNodeKind reports whether term is of the given sh:nodeKind
+func matchesNodeKind(term rdf.Term, kind rdf.Term) bool {
+	switch kind {
+	case shIRI:
+		return term.Kind() == rdf.KindIRI
+	case shBlankNode:
+		return term.Kind() == rdf.KindBlankNode
+	case shLiteral:
+		return term.Kind() == rdf.KindLiteral
+	case shBlankNodeOrIRI:
+		return term.Kind() != rdf.KindLiteral
+	case shBlankNodeOrLiteral:
+		return term.Kind() != rdf.KindIRI
+	case shIRIOrLiteral:
+		return term.Kind() != rdf.KindBlankNode
+	}
+	return false
+}
+
+// isNodeKind reports whether kind is one of the six SHACL node kinds
+func isNodeKind(kind rdf.Term) bool {
+	switch kind {
+	case shIRI, shBlankNode, shLiteral, shBlankNodeOrIRI, shBlankNodeOrLiteral, shIRIOrLiteral:
+		return true
+	}
+	return false
+}
+
+func checkCount(c *shapeContext) ([]ValidationResult, error) {
+	if c.path == nil {
+		return nil, nil
+	}
+	min, hasMin, err := c.integerParameter(shMinCount)
+	if err != nil {
+		return nil, err
+	}
+	max, hasMax, err := c.integerParameter(shMaxCount)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ValidationResult
+	if hasMin && len(c.values) < min {
+		results = append(results, c.result("MinCount", nil, "Less than %d values", min))
+	}
+	if hasMax && len(c.values) > max {
+		results = append(results, c.result("MaxCount", nil, "More than %d values", max))
+	}
+	return results, nil
+}
+
+func checkRange(c *shapeContext) ([]ValidationResult, error) {
+	bounds := []struct {
+		parameter rdf.IRI
+		component string
+		accepts   func(int) bool
+		message   string
+	}{
+		{shMinExclusive, "MinExclusive", func(cmp int) bool { return cmp > 0 }, "Value is not greater than %s"},
+		{shMinInclusive, "MinInclusive", func(cmp int) bool { return cmp >= 0 }, "Value is less than %s"},
+		{shMaxExclusive, "MaxExclusive", func(cmp int) bool { return cmp < 0 }, "Value is not less than %s"},
+		{shMaxInclusive, "MaxInclusive", func(cmp int) bool { return cmp <= 0 }, "Value is greater than %s"},
+	}
+
+	var results []ValidationResult
+	for _, bound := range bounds {
+		for _, limit := range c.parameters(bound.parameter) {
+			for _, value := range c.values {
+				cmp, ok := compareValues(value, limit)
+				if !ok || !bound.accepts(cmp) {
+					results = append(results, c.result(bound.component, value, bound.message, limit))
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+func checkLength(c *shapeContext) ([]ValidationResult, error) {
+	min, hasMin, err := c.integerParameter(shMinLength)
+	if err != nil {
+		return nil, err
+	}
+	max, hasMax, err := c.integerParameter(shMaxLength)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ValidationResult
+	for _, value := range c.values {
+		length := utf8.RuneCountInString(value.Value())
+		isBlank := value.Kind() == rdf.KindBlankNode
+		if hasMin && (isBlank || length < min) {
+			results = append(results, c.result("MinLength", value, "Value has less than %d characters", min))
+		}
+		if hasMax && (isBlank || length > max) {
+			results = append(results, c.result("MaxLength", value, "Value has more than %d characters", max))
+		}
+	}
+	return results, nil
+}
+
+func checkPattern(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, pattern := range c.parameters(shPattern) {
+		expression := pattern.Value()
+		if flags := c.parameter(shFlags); flags != nil {
+			supported := strings.Map(func(r rune) rune {
+				if strings.ContainsRune("ims", r) {
+					return r
+				}
+				return -1
+			}, flags.Value())
+			if supported != "" {
+				expression = "(?" + supported + ")" + expression
+			}
+		}
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sh:pattern %q: %w", pattern.Value(), err)
+		}
+		for _, value := range c.values {
+			if value.Kind() == rdf.KindBlankNode || !re.MatchString(value.Value()) {
+				results = append(results, c.result("Pattern", value, "Value does not match pattern %q", pattern.Value()))
+			}
+		}
+	}
+	return results, nil
+}
+
+func checkLanguageIn(c *shapeContext) ([]ValidationResult, error) {
+	list := c.parameter(shLanguageIn)
+	if list == nil {
+		return nil, nil
+	}
+	ranges, err := readList(c.validator.shapes, list)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ValidationResult
+	for _, value := range c.values {
+		l, ok := value.(rdf.Literal)
+		matched := false
+		for _, r := range ranges {
+			if ok && languageMatches(l.Language, r.Value()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			results = append(results, c.result("LanguageIn", value, "Language is not one of the allowed languages"))
+		}
+	}
+	return results, nil
+}
+
+// languageMatches implements basic language range matching
+func languageMatches(tag, languageRange string) bool {
+	if tag == "" {
+		return false
+	}
+	if languageRange == "*" {
+		return true
+	}
+	tag = strings.ToLower(tag)
+	languageRange = strings.ToLower(languageRange)
+	return tag == languageRange || strings.HasPrefix(tag, languageRange+"-")
+}
+
+func checkUniqueLang(c *shapeContext) ([]ValidationResult, error) {
+	if c.path == nil || !isTrue(c.parameter(shUniqueLang)) {
+		return nil, nil
+	}
+	counts := make(map[string]int)
+	var languages []string
+	for _, value := range c.values {
+		if l, ok := value.(rdf.Literal); ok && l.Language != "" {
+			language := strings.ToLower(l.Language)
+			if counts[language] == 0 {
+				languages = append(languages, language)
+			}
+			counts[language]++
+		}
+	}
+
+	var results []ValidationResult
+	for _, language := range languages {
+		if counts[language] > 1 {
+			results = append(results, c.result("UniqueLang", nil, "Language %q is used more than once", language))
+		}
+	}
+	return results, nil
+}
+
+func checkPropertyPairs(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, property := range c.parameters(shEquals) {
+		others := objects(c.validator.data, c.focus, property)
+		for _, value := range c.values {
+			if !containsTerm(others, value) {
+				results = append(results, c.result("Equals", value, "Value is not a value of %s", property))
+			}
+		}
+		for _, other := range others {
+			if !containsTerm(c.values, other) {
+				results = append(results, c.result("Equals", other, "Value of %s is missing", property))
+			}
+		}
+	}
+	for _, property := range c.parameters(shDisjoint) {
+		others := objects(c.validator.data, c.focus, property)
+		for _, value := range c.values {
+			if containsTerm(others, value) {
+				results = append(results, c.result("Disjoint", value, "Value is also a value of %s", property))
+			}
+		}
+	}
+
+	comparisons := []struct {
+		parameter rdf.IRI
+		component string
+		accepts   func(int) bool
+		message   string
+	}{
+		{shLessThan, "LessThan", func(cmp int) bool { return cmp < 0 }, "Value is not less than the values of %s"},
+		{shLessThanOrEquals, "LessThanOrEquals", func(cmp int) bool { return cmp <= 0 }, "Value is greater than the values of %s"},
+	}
+	for _, comparison := range comparisons {
+		for _, property := range c.parameters(comparison.parameter) {
+			others := objects(c.validator.data, c.focus, property)
+			for _, value := range c.values {
+				for _, other := range others {
+					cmp, ok := compareValues(value, other)
+					if !ok || !comparison.accepts(cmp) {
+						results = append(results, c.result(comparison.component, value, comparison.message, property))
+						break
+					}
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+func checkNot(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, shape := range c.parameters(shNot) {
+		for _, value := range c.values {
+			conforms, err := c.validator.conforms(shape, value)
+			if err != nil {
+				return nil, err
+			}
+			if conforms {
+				results = append(results, c.result("Not", value, "Value conforms to shape %s", shape))
+			}
+		}
+	}
+	return results, nil
+}
+
+func checkLogical(c *shapeContext) ([]ValidationResult, error) {
+	operators := []struct {
+		parameter rdf.IRI
+		component string
+		accepts   func(conforming, total int) bool
+		message   string
+	}{
+		{shAnd, "And", func(conforming, total int) bool { return conforming == total }, "Value does not conform to all shapes"},
+		{shOr, "Or", func(conforming, total int) bool { return conforming > 0 }, "Value does not conform to any shape"},
+		{shXone, "Xone", func(conforming, total int) bool { return conforming == 1 }, "Value does not conform to exactly one shape"},
+	}
+
+	var results []ValidationResult
+	for _, operator := range operators {
+		for _, list := range c.parameters(operator.parameter) {
+			shapes, err := readList(c.validator.shapes, list)
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range c.values {
+				conforming := 0
+				for _, shape := range shapes {
+					ok, err := c.validator.conforms(shape, value)
+					if err != nil {
+						return nil, err
+					}
+					if ok {
+						conforming++
+					}
+				}
+				if !operator.accepts(conforming, len(shapes)) {
+					results = append(results, c.result(operator.component, value, operator.message))
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+func checkNode(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, shape := range c.parameters(shNode) {
+		for _, value := range c.values {
+			conforms, err := c.validator.conforms(shape, value)
+			if err != nil {
+				return nil, err
+			}
+			if !conforms {
+				results = append(results, c.result("Node", value, "Value does not conform to shape %s", shape))
+			}
+		}
+	}
+	return results, nil
+}
+
+func checkProperty(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, property := range c.parameters(shProperty) {
+		for _, value := range c.values {
+			nested, err := c.validator.validateShape(property, value)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, nested...)
+		}
+	}
+	return results, nil
+}
+
+func checkQualifiedValueShape(c *shapeContext) ([]ValidationResult, error) {
+	qualified := c.parameter(shQualifiedValueShape)
+	if qualified == nil || c.path == nil {
+		return nil, nil
+	}
+	min, hasMin, err := c.integerParameter(shQualifiedMinCount)
+	if err != nil {
+		return nil, err
+	}
+	max, hasMax, err := c.integerParameter(shQualifiedMaxCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sibling shapes are the qualified value shapes of the other property
+	// shapes that share a parent with this one
+	var siblings []rdf.Term
+	if isTrue(c.parameter(shQualifiedValueShapesDisjoint)) {
+		for _, parent := range c.validator.shapes.Match(nil, shProperty, c.shape) {
+			for _, property := range objects(c.validator.shapes, parent.Subject, shProperty) {
+				if property.Equal(c.shape) {
+					continue
+				}
+				if sibling := object(c.validator.shapes, property, shQualifiedValueShape); sibling != nil {
+					siblings = append(siblings, sibling)
+				}
+			}
+		}
+	}
+
+	count := 0
+	for _, value := range c.values {
+		conforms, err := c.validator.conforms(qualified, value)
+		if err != nil {
+			return nil, err
+		}
+		for _, sibling := range siblings {
+			if !conforms {
+				break
+			}
+			matchesSibling, err := c.validator.conforms(sibling, value)
+			if err != nil {
+				return nil, err
+			}
+			conforms = !matchesSibling
+		}
+		if conforms {
+			count++
+		}
+	}
+
+	var results []ValidationResult
+	if hasMin && count < min {
+		results = append(results, c.result("QualifiedMinCount", nil, "Less than %d values conform to shape %s", min, qualified))
+	}
+	if hasMax && count > max {
+		results = append(results, c.result("QualifiedMaxCount", nil, "More than %d values conform to shape %s", max, qualified))
+	}
+	return results, nil
+}
+
+func checkClosed(c *shapeContext) ([]ValidationResult, error) {
+	if !isTrue(c.parameter(shClosed)) {
+		return nil, nil
+	}
+
+	allowed := make(map[string]bool)
+	for _, property := range c.parameters(shProperty) {
+		if path, ok := object(c.validator.shapes, property, shPath).(rdf.IRI); ok {
+			allowed[path.String()] = true
+		}
+	}
+	if list := c.parameter(shIgnoredProperties); list != nil {
+		ignored, err := readList(c.validator.shapes, list)
+		if err != nil {
+			return nil, err
+		}
+		for _, predicate := range ignored {
+			allowed[predicate.String()] = true
+		}
+	}
+
+	var results []ValidationResult
+	for _, value := range c.values {
+		for _, t := range c.validator.data.Match(value, nil, nil) {
+			if allowed[t.Predicate.String()] {
+				continue
+			}
+			result := c.result("Closed", t.Object, "Predicate %s is not allowed (closed shape)", t.Predicate)
+			result.FocusNode = value
+			result.Path = t.Predicate
+			result.pathTriples = nil
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func checkHasValue(c *shapeContext) ([]ValidationResult, error) {
+	var results []ValidationResult
+	for _, expected := range c.parameters(shHasValue) {
+		if !containsTerm(c.values, expected) {
+			results = append(results, c.result("HasValue", nil, "Missing expected value %s", expected))
+		}
+	}
+	return results, nil
+}
+
+func checkIn(c *shapeContext) ([]ValidationResult, error) {
+	list := c.parameter(shIn)
+	if list == nil {
+		return nil, nil
+	}
+	members, err := readList(c.validator.shapes, list)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ValidationResult
+	for _, value := range c.values {
+		if !containsTerm(members, value) {
+			results = append(results, c.result("In", value, "Value is not one of the allowed values"))
+		}
+	}
+	return results, nil
+}
+
+// integerParameter reads a non-negative integer constraint parameter,
+// failing when the shapes graph gives it any other value
+func (c *shapeContext) integerParameter(parameter rdf.IRI) (int, bool, error) {
+	term := c.parameter(parameter)
+	if term == nil {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(term.Value())
+	if err != nil || n < 0 || term.Kind() != rdf.KindLiteral {
+		return 0, false, fmt.Errorf("%s must be a non-negative integer, not %s", parameter, term)
+	}
+	return n, true, nil
+}
+
+// XSD datatypes with value-based comparison and lexical checks
+const (
+	xsdInteger  rdf.IRI = rdf.XSDNamespace + "integer"
+	xsdDecimal  rdf.IRI = rdf.XSDNamespace + "decimal"
+	xsdDouble   rdf.IRI = rdf.XSDNamespace + "double"
+	xsdFloat    rdf.IRI = rdf.XSDNamespace + "float"
+	xsdDate     rdf.IRI = rdf.XSDNamespace + "date"
+	xsdDateTime rdf.IRI = rdf.XSDNamespace + "dateTime"
+)
+
+// integerDatatypes maps the derived integer types to their bit size; zero
+// means unbounded
+var integerDatatypes = map[rdf.IRI]int{
+	xsdInteger:                              0,
+	rdf.XSDNamespace + "long":               64,
+	rdf.XSDNamespace + "int":                32,
+	rdf.XSDNamespace + "short":              16,
+	rdf.XSDNamespace + "byte":               8,
+	rdf.XSDNamespace + "nonNegativeInteger": 0,
+	rdf.XSDNamespace + "positiveInteger":    0,
+	rdf.XSDNamespace + "nonPositiveInteger": 0,
+	rdf.XSDNamespace + "negativeInteger":    0,
+	rdf.XSDNamespace + "unsignedLong":       64,
+	rdf.XSDNamespace + "unsignedInt":        32,
+	rdf.XSDNamespace + "unsignedShort":      16,
+	rdf.XSDNamespace + "unsignedByte":       8,
+}
+
+var (
+	integerPattern = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	decimalPattern = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+)
+
+// isWellFormed reports whether the lexical form is valid for the literal's datatype
+func isWellFormed(l rdf.Literal) bool {
+	datatype := l.EffectiveDatatype()
+	if bits, ok := integerDatatypes[datatype]; ok {
+		if !integerPattern.MatchString(l.Lexical) {
+			return false
+		}
+		n, _ := new(big.Int).SetString(strings.TrimPrefix(l.Lexical, "+"), 10)
+		name := strings.TrimPrefix(string(datatype), rdf.XSDNamespace)
+		switch {
+		case name == "positiveInteger" && n.Sign() <= 0,
+			name == "negativeInteger" && n.Sign() >= 0,
+			name == "nonPositiveInteger" && n.Sign() > 0,
+			(name == "nonNegativeInteger" || strings.HasPrefix(name, "unsigned")) && n.Sign() < 0:
+			return false
+		}
+		if bits > 0 {
+			if strings.HasPrefix(name, "unsigned") {
+				return n.BitLen() <= bits
+			}
+			limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+			return n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0
+		}
+		return true
+	}
+
+	switch datatype {
+	case xsdDecimal:
+		return decimalPattern.MatchString(l.Lexical)
+	case xsdDouble, xsdFloat:
+		_, ok := parseDouble(l.Lexical)
+		return ok
+	case xsdBoolean:
+		switch l.Lexical {
+		case "true", "false", "1", "0":
+			return true
+		}
+		return false
+	case xsdDate, xsdDateTime:
+		_, ok := parseTemporal(l.Lexical, datatype)
+		return ok
+	}
+	return true
+}
+
+// compareValues compares two literals by value. ok is false when the
+// values are not comparable.
+func compareValues(a, b rdf.Term) (int, bool) {
+	la, okA := a.(rdf.Literal)
+	lb, okB := b.(rdf.Literal)
+	if !okA || !okB || !isWellFormed(la) || !isWellFormed(lb) {
+		return 0, false
+	}
+	da, db := la.EffectiveDatatype(), lb.EffectiveDatatype()
+
+	if isNumeric(da) && isNumeric(db) {
+		if isExactNumeric(da) && isExactNumeric(db) {
+			ra, _ := new(big.Rat).SetString(la.Lexical)
+			rb, _ := new(big.Rat).SetString(lb.Lexical)
+			return ra.Cmp(rb), true
+		}
+		fa, _ := parseDouble(la.Lexical)
+		fb, _ := parseDouble(lb.Lexical)
+		switch {
+		case fa != fa || fb != fb:
+			return 0, false
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if da != db {
+		return 0, false
+	}
+	switch da {
+	case xsdDate, xsdDateTime:
+		ta, _ := parseTemporal(la.Lexical, da)
+		tb, _ := parseTemporal(lb.Lexical, db)
+		return ta.Compare(tb), true
+	case rdf.XSDString:
+		return strings.Compare(la.Lexical, lb.Lexical), true
+	case rdf.RDFLangString:
+		if !strings.EqualFold(la.Language, lb.Language) {
+			return 0, false
+		}
+		return strings.Compare(la.Lexical, lb.Lexical), true
+	}
+	return 0, false
+}
+
+// isNumeric reports whether datatype is one of the XSD numeric types
+func isNumeric(datatype rdf.IRI) bool {
+	return isExactNumeric(datatype) || datatype == xsdDouble || datatype == xsdFloat
+}
+
+// isExactNumeric reports whether datatype is xsd:decimal or derived from it
+func isExactNumeric(datatype rdf.IRI) bool {
+	_, isInteger := integerDatatypes[datatype]
+	return isInteger || datatype == xsdDecimal
+}
+
+// parseDouble parses an xsd:double lexical form, including INF and NaN
+func parseDouble(lexical string) (float64, bool) {
+	switch lexical {
+	case "INF", "+INF":
+		lexical = "+Inf"
+	case "-INF":
+		lexical = "-Inf"
+	case "NaN":
+	default:
+		if strings.ContainsAny(lexical, "nN") {
+			return 0, false
+		}
+	}
+	f, err := strconv.ParseFloat(lexical, 64)
+	return f, err == nil
+}
+
+// parseTemporal parses an xsd:date or xsd:dateTime lexical form; values
+// without a timezone are treated as UTC
+func parseTemporal(lexical string, datatype rdf.IRI) (time.Time, bool) {
+	layouts := []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}
+	if datatype == xsdDate {
+		layouts = []string{"2006-01-02Z07:00", "2006-01-02"}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, lexical); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// SHACL vocabulary used in shapes graphs and validation reports
+const (
+	SHNamespace = "http://www.w3.org/ns/shacl#"
+
+	SHValidationReport rdf.IRI = SHNamespace + "ValidationReport"
+	SHValidationResult rdf.IRI = SHNamespace + "ValidationResult"
+	SHConforms         rdf.IRI = SHNamespace + "conforms"
+	SHResult           rdf.IRI = SHNamespace + "result"
+	SHFocusNode        rdf.IRI = SHNamespace + "focusNode"
+	SHResultPath       rdf.IRI = SHNamespace + "resultPath"
+	SHValue            rdf.IRI = SHNamespace + "value"
+	SHSourceShape      rdf.IRI = SHNamespace + "sourceShape"
+	SHSourceComponent  rdf.IRI = SHNamespace + "sourceConstraintComponent"
+	SHResultSeverity   rdf.IRI = SHNamespace + "resultSeverity"
+	SHResultMessage    rdf.IRI = SHNamespace + "resultMessage"
+	SHViolation        rdf.IRI = SHNamespace + "Violation"
+	SHWarning          rdf.IRI = SHNamespace + "Warning"
+	SHInfo             rdf.IRI = SHNamespace + "Info"
+	SHShapesGraph      rdf.IRI = SHNamespace + "shapesGraph"
+)
+
+// ValidationResult is a single sh:ValidationResult
+type ValidationResult struct {
+	FocusNode   rdf.Term
+	Path        rdf.Term
+	Value       rdf.Term
+	SourceShape rdf.Term
+	Component   rdf.IRI
+	Severity    rdf.IRI
+	Messages    []rdf.Literal
+
+	// pathTriples describe Path when it is a blank node
+	pathTriples []rdf.Triple
+}
+
+// ValidationReport is the outcome of validating a data graph against shapes
+type ValidationReport struct {
+	Conforms bool
+	Results  []ValidationResult
+}
+
+// Graph returns the report as an sh:ValidationReport graph
+func (r *ValidationReport) Graph() *rdf.Graph {
+	g := rdf.NewGraph()
+	report := rdf.BlankNode("report")
+	g.Add(rdf.Triple{Subject: report, Predicate: rdf.RDFType, Object: SHValidationReport})
+	g.Add(rdf.Triple{Subject: report, Predicate: SHConforms, Object: rdf.NewTypedLiteral(strconv.FormatBool(r.Conforms), rdf.XSDNamespace+"boolean")})
+
+	for i, result := range r.Results {
+		node := rdf.BlankNode(fmt.Sprintf("result%d", i))
+		g.Add(rdf.Triple{Subject: report, Predicate: SHResult, Object: node})
+		g.Add(rdf.Triple{Subject: node, Predicate: rdf.RDFType, Object: SHValidationResult})
+		g.Add(rdf.Triple{Subject: node, Predicate: SHFocusNode, Object: result.FocusNode})
+		if result.Path != nil {
+			g.Add(rdf.Triple{Subject: node, Predicate: SHResultPath, Object: result.Path})
+			for _, t := range result.pathTriples {
+				g.Add(t)
+			}
+		}
+		if result.Value != nil {
+			g.Add(rdf.Triple{Subject: node, Predicate: SHValue, Object: result.Value})
+		}
+		if result.SourceShape != nil {
+			g.Add(rdf.Triple{Subject: node, Predicate: SHSourceShape, Object: result.SourceShape})
+		}
+		g.Add(rdf.Triple{Subject: node, Predicate: SHSourceComponent, Object: result.Component})
+		g.Add(rdf.Triple{Subject: node, Predicate: SHResultSeverity, Object: result.Severity})
+		for _, message := range result.Messages {
+			g.Add(rdf.Triple{Subject: node, Predicate: SHResultMessage, Object: message})
+		}
+	}
+	return g
+}
+
+// ShapeValidationError is returned when data does not conform to the shapes
+// that apply to it. The report describes every failure and can be returned
+// to clients as an sh:ValidationReport.
+type ShapeValidationError struct {
+	Report *ValidationReport
+}
+
+func (e *ShapeValidationError) Error() string {
+	if e.Report == nil || len(e.Report.Results) == 0 {
+		return "shape validation failed"
+	}
+	first := e.Report.Results[0]
+	message := string(first.Component)
+	if len(first.Messages) > 0 {
+		message = first.Messages[0].Lexical
+	}
+	if len(e.Report.Results) == 1 {
+		return fmt.Sprintf("shape validation failed for %s: %s", first.FocusNode, message)
+	}
+	return fmt.Sprintf("shape validation failed with %d results; first for %s: %s",
+		len(e.Report.Results), first.FocusNode, message)
+}
@@ -0,0 +1,447 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// maxShapeDepth bounds how deeply shapes may reference other shapes, which
+// guards against recursive shapes graphs
+const maxShapeDepth = 64
+
+// ErrRecursiveShapes is returned when shapes reference each other too deeply
+var ErrRecursiveShapes = errors.New("shapes graph is recursive or nested too deeply")
+
+// SHACL vocabulary read from shapes graphs
+const (
+	shNodeShape        rdf.IRI = SHNamespace + "NodeShape"
+	shPropertyShape    rdf.IRI = SHNamespace + "PropertyShape"
+	shTargetNode       rdf.IRI = SHNamespace + "targetNode"
+	shTargetClass      rdf.IRI = SHNamespace + "targetClass"
+	shTargetSubjectsOf rdf.IRI = SHNamespace + "targetSubjectsOf"
+	shTargetObjectsOf  rdf.IRI = SHNamespace + "targetObjectsOf"
+	shDeactivated      rdf.IRI = SHNamespace + "deactivated"
+	shSeverity         rdf.IRI = SHNamespace + "severity"
+	shMessage          rdf.IRI = SHNamespace + "message"
+	shPath             rdf.IRI = SHNamespace + "path"
+	shInversePath      rdf.IRI = SHNamespace + "inversePath"
+	shAlternativePath  rdf.IRI = SHNamespace + "alternativePath"
+	shZeroOrMorePath   rdf.IRI = SHNamespace + "zeroOrMorePath"
+	shOneOrMorePath    rdf.IRI = SHNamespace + "oneOrMorePath"
+	shZeroOrOnePath    rdf.IRI = SHNamespace + "zeroOrOnePath"
+	rdfFirst           rdf.IRI = rdf.RDFNamespace + "first"
+	rdfRest            rdf.IRI = rdf.RDFNamespace + "rest"
+	rdfNil             rdf.IRI = rdf.RDFNamespace + "nil"
+	rdfsClass          rdf.IRI = "http://www.w3.org/2000/01/rdf-schema#Class"
+	rdfsSubClassOf     rdf.IRI = "http://www.w3.org/2000/01/rdf-schema#subClassOf"
+	xsdBoolean         rdf.IRI = rdf.XSDNamespace + "boolean"
+	shConstraintSuffix         = "ConstraintComponent"
+)
+
+//go:generate moq -out shacl_validation_service_mock.go . SHACLValidationService
+
+// SHACLValidationService validates RDF graphs against SHACL shapes graphs
+type SHACLValidationService interface {
+	// Validate checks data against every shape with a target in shapes and
+	// returns the resulting report. An error means the shapes graph itself
+	// could not be evaluated.
+	Validate(data *rdf.Graph, shapes *rdf.Graph) (*ValidationReport, error)
+}
+
+// StandardSHACLValidationService implements the SHACL Core constraint components
+type StandardSHACLValidationService struct{}
+
+// NewStandardSHACLValidationService creates a new SHACL validation service
+func NewStandardSHACLValidationService() SHACLValidationService {
+	return &StandardSHACLValidationService{}
+}
+
+// Validate checks data against every shape with a target in shapes
+func (s *StandardSHACLValidationService) Validate(data *rdf.Graph, shapes *rdf.Graph) (*ValidationReport, error) {
+	if data == nil {
+		data = rdf.NewGraph()
+	}
+	report := &ValidationReport{Conforms: true}
+	if shapes == nil {
+		return report, nil
+	}
+
+	v := &shaclValidator{data: data, shapes: shapes}
+	for _, shape := range v.targetedShapes() {
+		for _, focus := range v.targetNodes(shape) {
+			results, err := v.validateShape(shape, focus)
+			if err != nil {
+				return nil, err
+			}
+			report.Results = append(report.Results, results...)
+		}
+	}
+	report.Conforms = len(report.Results) == 0
+	return report, nil
+}
+
+// shaclValidator holds the graphs of a single validation run
+type shaclValidator struct {
+	data   *rdf.Graph
+	shapes *rdf.Graph
+	depth  int
+}
+
+// targetedShapes returns the shapes that declare targets, in shapes graph order
+func (v *shaclValidator) targetedShapes() []rdf.Term {
+	var shapes []rdf.Term
+	seen := make(map[string]bool)
+	add := func(shape rdf.Term) {
+		if !seen[shape.String()] {
+			seen[shape.String()] = true
+			shapes = append(shapes, shape)
+		}
+	}
+
+	for _, t := range v.shapes.Triples() {
+		switch t.Predicate {
+		case shTargetNode, shTargetClass, shTargetSubjectsOf, shTargetObjectsOf:
+			add(t.Subject)
+		case rdf.RDFType:
+			if v.isImplicitClassTarget(t.Subject) {
+				add(t.Subject)
+			}
+		}
+	}
+	return shapes
+}
+
+// isImplicitClassTarget reports whether shape is both a shape and an rdfs:Class
+func (v *shaclValidator) isImplicitClassTarget(shape rdf.Term) bool {
+	isClass := len(v.shapes.Match(shape, rdf.RDFType, rdfsClass)) > 0
+	isShape := len(v.shapes.Match(shape, rdf.RDFType, shNodeShape)) > 0 ||
+		len(v.shapes.Match(shape, rdf.RDFType, shPropertyShape)) > 0
+	return isClass && isShape
+}
+
+// targetNodes returns the focus nodes selected by the shape's targets
+func (v *shaclValidator) targetNodes(shape rdf.Term) []rdf.Term {
+	var nodes termSet
+	for _, node := range objects(v.shapes, shape, shTargetNode) {
+		nodes.add(node)
+	}
+	for _, class := range objects(v.shapes, shape, shTargetClass) {
+		nodes.add(v.instancesOf(class)...)
+	}
+	if v.isImplicitClassTarget(shape) {
+		nodes.add(v.instancesOf(shape)...)
+	}
+	for _, predicate := range objects(v.shapes, shape, shTargetSubjectsOf) {
+		for _, t := range v.data.Match(nil, predicate, nil) {
+			nodes.add(t.Subject)
+		}
+	}
+	for _, predicate := range objects(v.shapes, shape, shTargetObjectsOf) {
+		for _, t := range v.data.Match(nil, predicate, nil) {
+			nodes.add(t.Object)
+		}
+	}
+	return nodes.terms
+}
+
+// instancesOf returns the SHACL instances of class in the data graph
+func (v *shaclValidator) instancesOf(class rdf.Term) []rdf.Term {
+	var instances termSet
+	for _, c := range v.subClassesOf(class) {
+		for _, t := range v.data.Match(nil, rdf.RDFType, c) {
+			instances.add(t.Subject)
+		}
+	}
+	return instances.terms
+}
+
+// subClassesOf returns class and every class that is transitively an
+// rdfs:subClassOf it in the data graph
+func (v *shaclValidator) subClassesOf(class rdf.Term) []rdf.Term {
+	var classes termSet
+	classes.add(class)
+	for i := 0; i < len(classes.terms); i++ {
+		for _, t := range v.data.Match(nil, rdfsSubClassOf, classes.terms[i]) {
+			classes.add(t.Subject)
+		}
+	}
+	return classes.terms
+}
+
+// isInstanceOf reports whether node is a SHACL instance of class
+func (v *shaclValidator) isInstanceOf(node rdf.Term, class rdf.Term) bool {
+	if node.Kind() == rdf.KindLiteral {
+		return false
+	}
+	for _, c := range v.subClassesOf(class) {
+		if len(v.data.Match(node, rdf.RDFType, c)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validateShape validates a focus node against a shape and returns its results
+func (v *shaclValidator) validateShape(shape rdf.Term, focus rdf.Term) ([]ValidationResult, error) {
+	if isTrue(object(v.shapes, shape, shDeactivated)) {
+		return nil, nil
+	}
+
+	v.depth++
+	defer func() { v.depth-- }()
+	if v.depth > maxShapeDepth {
+		return nil, ErrRecursiveShapes
+	}
+
+	c := &shapeContext{validator: v, shape: shape, focus: focus}
+	if path := object(v.shapes, shape, shPath); path != nil {
+		values, err := v.evaluatePath(focus, path, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.path = path
+		c.values = values
+	} else {
+		c.values = []rdf.Term{focus}
+	}
+
+	var results []ValidationResult
+	for _, check := range constraintComponents() {
+		found, err := check(c)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+	return results, nil
+}
+
+// conforms reports whether node produces no results against shape
+func (v *shaclValidator) conforms(shape rdf.Term, node rdf.Term) (bool, error) {
+	results, err := v.validateShape(shape, node)
+	if err != nil {
+		return false, err
+	}
+	return len(results) == 0, nil
+}
+
+// evaluatePath returns the value nodes reached from focus along a SHACL property path
+func (v *shaclValidator) evaluatePath(focus rdf.Term, path rdf.Term, depth int) ([]rdf.Term, error) {
+	if depth > maxShapeDepth {
+		return nil, fmt.Errorf("property path %s is nested too deeply", path)
+	}
+	if path.Kind() == rdf.KindIRI {
+		var values termSet
+		for _, t := range v.data.Match(focus, path, nil) {
+			values.add(t.Object)
+		}
+		return values.terms, nil
+	}
+	if path.Kind() != rdf.KindBlankNode {
+		return nil, fmt.Errorf("invalid property path %s", path)
+	}
+
+	if object(v.shapes, path, rdfFirst) != nil {
+		steps, err := readList(v.shapes, path)
+		if err != nil {
+			return nil, err
+		}
+		current := []rdf.Term{focus}
+		for _, step := range steps {
+			var next termSet
+			for _, node := range current {
+				values, err := v.evaluatePath(node, step, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				next.add(values...)
+			}
+			current = next.terms
+		}
+		return current, nil
+	}
+
+	if inverse := object(v.shapes, path, shInversePath); inverse != nil {
+		var values termSet
+		if inverse.Kind() == rdf.KindIRI {
+			for _, t := range v.data.Match(nil, inverse, focus) {
+				values.add(t.Subject)
+			}
+			return values.terms, nil
+		}
+		for _, candidate := range v.data.Subjects() {
+			reached, err := v.evaluatePath(candidate, inverse, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if containsTerm(reached, focus) {
+				values.add(candidate)
+			}
+		}
+		return values.terms, nil
+	}
+
+	if alternatives := object(v.shapes, path, shAlternativePath); alternatives != nil {
+		options, err := readList(v.shapes, alternatives)
+		if err != nil {
+			return nil, err
+		}
+		var values termSet
+		for _, option := range options {
+			reached, err := v.evaluatePath(focus, option, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			values.add(reached...)
+		}
+		return values.terms, nil
+	}
+
+	if inner := object(v.shapes, path, shZeroOrOnePath); inner != nil {
+		var values termSet
+		values.add(focus)
+		reached, err := v.evaluatePath(focus, inner, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		values.add(reached...)
+		return values.terms, nil
+	}
+
+	zeroOrMore := object(v.shapes, path, shZeroOrMorePath)
+	oneOrMore := object(v.shapes, path, shOneOrMorePath)
+	if zeroOrMore != nil || oneOrMore != nil {
+		inner := zeroOrMore
+		var values termSet
+		if zeroOrMore != nil {
+			values.add(focus)
+		} else {
+			inner = oneOrMore
+		}
+		var visited termSet
+		pending := []rdf.Term{focus}
+		for len(pending) > 0 {
+			node := pending[0]
+			pending = pending[1:]
+			reached, err := v.evaluatePath(node, inner, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			for _, next := range reached {
+				values.add(next)
+				if visited.add(next) {
+					pending = append(pending, next)
+				}
+			}
+		}
+		return values.terms, nil
+	}
+
+	return nil, fmt.Errorf("unsupported property path %s", path)
+}
+
+// pathTriples returns the shapes graph triples that describe a blank node path
+func (v *shaclValidator) pathTriples(path rdf.Term) []rdf.Triple {
+	if path == nil || path.Kind() != rdf.KindBlankNode {
+		return nil
+	}
+	var triples []rdf.Triple
+	var visited termSet
+	pending := []rdf.Term{path}
+	visited.add(path)
+	for len(pending) > 0 {
+		node := pending[0]
+		pending = pending[1:]
+		for _, t := range v.shapes.Match(node, nil, nil) {
+			triples = append(triples, t)
+			if t.Object.Kind() == rdf.KindBlankNode && visited.add(t.Object) {
+				pending = append(pending, t.Object)
+			}
+		}
+	}
+	return triples
+}
+
+// termSet is an ordered set of terms
+type termSet struct {
+	terms []rdf.Term
+	seen  map[string]bool
+}
+
+// add inserts terms and reports whether the last one was new
+func (s *termSet) add(terms ...rdf.Term) bool {
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	added := false
+	for _, term := range terms {
+		key := term.String()
+		added = !s.seen[key]
+		if added {
+			s.seen[key] = true
+			s.terms = append(s.terms, term)
+		}
+	}
+	return added
+}
+
+// objects returns the objects of triples matching subject and predicate
+func objects(g *rdf.Graph, subject rdf.Term, predicate rdf.Term) []rdf.Term {
+	matches := g.Match(subject, predicate, nil)
+	result := make([]rdf.Term, 0, len(matches))
+	for _, t := range matches {
+		result = append(result, t.Object)
+	}
+	// Parsers do not all preserve document order, so sort for stable reports
+	sort.Slice(result, func(i, j int) bool { return rdf.CompareTerms(result[i], result[j]) < 0 })
+	return result
+}
+
+// object returns the first object matching subject and predicate, or nil
+func object(g *rdf.Graph, subject rdf.Term, predicate rdf.Term) rdf.Term {
+	matches := g.Match(subject, predicate, nil)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches[0].Object
+}
+
+// readList returns the members of the RDF collection starting at head
+func readList(g *rdf.Graph, head rdf.Term) ([]rdf.Term, error) {
+	var members []rdf.Term
+	visited := make(map[string]bool)
+	for node := head; !rdfNil.Equal(node); {
+		if visited[node.String()] {
+			return nil, fmt.Errorf("RDF list %s is cyclic", head)
+		}
+		visited[node.String()] = true
+
+		first := object(g, node, rdfFirst)
+		rest := object(g, node, rdfRest)
+		if first == nil || rest == nil {
+			return nil, fmt.Errorf("malformed RDF list at %s", node)
+		}
+		members = append(members, first)
+		node = rest
+	}
+	return members, nil
+}
+
+// containsTerm reports whether terms contains term
+func containsTerm(terms []rdf.Term, term rdf.Term) bool {
+	for _, t := range terms {
+		if t.Equal(term) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrue reports whether term is the literal true
+func isTrue(term rdf.Term) bool {
+	l, ok := term.(rdf.Literal)
+	return ok && (l.Lexical == "true" || l.Lexical == "1")
+}
@@ -0,0 +1,445 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestSHACLValidationService(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService()
+	validator := service.NewStandardSHACLValidationService()
+
+	parse := func(t *testing.T, turtle string) *rdf.Graph {
+		t.Helper()
+		graph, err := rdfService.ParseGraph(turtle, string(service.FormatTurtle))
+		require.NoError(t, err)
+		return graph
+	}
+
+	prefixes := `@prefix sh: <http://www.w3.org/ns/shacl#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+@prefix ex: <https://example.com/ns#> .
+@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+`
+
+	personShape := prefixes + `
+ex:PersonShape a sh:NodeShape ;
+    sh:targetClass foaf:Person ;
+    sh:property [
+        sh:path foaf:name ;
+        sh:minCount 1 ;
+        sh:maxCount 1 ;
+        sh:datatype xsd:string ;
+    ] ;
+    sh:property [
+        sh:path foaf:age ;
+        sh:datatype xsd:integer ;
+        sh:minInclusive 0 ;
+        sh:message "Age must be a non-negative integer" ;
+    ] .
+`
+
+	t.Run("conforming data produces an empty report", func(t *testing.T) {
+		// Arrange
+		data := parse(t, prefixes+`<https://example.com/alice> a foaf:Person ; foaf:name "Alice" ; foaf:age 30 .`)
+
+		// Act
+		report, err := validator.Validate(data, parse(t, personShape))
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, report.Conforms)
+		assert.Empty(t, report.Results)
+	})
+
+	t.Run("reports cardinality and value violations", func(t *testing.T) {
+		// Arrange
+		data := parse(t, prefixes+`<https://example.com/alice> a foaf:Person ; foaf:age -3 .`)
+
+		// Act
+		report, err := validator.Validate(data, parse(t, personShape))
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, report.Conforms)
+		require.Len(t, report.Results, 2)
+		assert.Equal(t, rdf.IRI("http://www.w3.org/ns/shacl#MinCountConstraintComponent"), report.Results[0].Component)
+		assert.Equal(t, rdf.IRI("http://xmlns.com/foaf/0.1/name"), report.Results[0].Path)
+		assert.Equal(t, rdf.IRI("http://www.w3.org/ns/shacl#MinInclusiveConstraintComponent"), report.Results[1].Component)
+		assert.Equal(t, "Age must be a non-negative integer", report.Results[1].Messages[0].Lexical)
+		assert.Equal(t, service.SHViolation, report.Results[1].Severity)
+	})
+
+	t.Run("ignores nodes that are not targeted", func(t *testing.T) {
+		// Arrange
+		data := parse(t, prefixes+`<https://example.com/thing> foaf:age "old" .`)
+
+		// Act
+		report, err := validator.Validate(data, parse(t, personShape))
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, report.Conforms)
+	})
+
+	t.Run("evaluates property paths and logical components", func(t *testing.T) {
+		// Arrange
+		shapes := parse(t, prefixes+`
+ex:DocumentShape sh:targetSubjectsOf ex:author ;
+    sh:property [
+        sh:path ( ex:author foaf:name ) ;
+        sh:minCount 1 ;
+        sh:pattern "^[A-Z]" ;
+    ] ;
+    sh:or ( [ sh:path ex:status ; sh:in ( "draft" "final" ) ; sh:minCount 1 ] [ sh:path ex:published ; sh:minCount 1 ] ) ;
+    sh:closed true ;
+    sh:ignoredProperties ( ex:author ex:status ex:published ) .
+`)
+		data := parse(t, prefixes+`
+<https://example.com/doc> ex:author [ foaf:name "bob" ] ; ex:status "pending" ; ex:extra 1 .
+`)
+
+		// Act
+		report, err := validator.Validate(data, shapes)
+
+		// Assert
+		require.NoError(t, err)
+		components := make([]string, 0, len(report.Results))
+		for _, result := range report.Results {
+			components = append(components, result.Component.Value())
+		}
+		assert.Equal(t, []string{
+			"http://www.w3.org/ns/shacl#OrConstraintComponent",
+			"http://www.w3.org/ns/shacl#PatternConstraintComponent",
+			"http://www.w3.org/ns/shacl#ClosedConstraintComponent",
+		}, components)
+	})
+
+	t.Run("serializes the report as an sh:ValidationReport graph", func(t *testing.T) {
+		// Arrange
+		data := parse(t, prefixes+`<https://example.com/alice> a foaf:Person .`)
+		report, err := validator.Validate(data, parse(t, personShape))
+		require.NoError(t, err)
+
+		// Act
+		graph := report.Graph()
+
+		// Assert
+		assert.Len(t, graph.Match(nil, rdf.RDFType, service.SHValidationReport), 1)
+		assert.Len(t, graph.Match(nil, service.SHConforms, rdf.NewTypedLiteral("false", rdf.XSDNamespace+"boolean")), 1)
+		assert.Len(t, graph.Match(nil, service.SHFocusNode, rdf.IRI("https://example.com/alice")), 1)
+		assert.Len(t, graph.Match(nil, service.SHResultPath, rdf.IRI("http://xmlns.com/foaf/0.1/name")), 1)
+	})
+
+	t.Run("rejects recursive shapes", func(t *testing.T) {
+		// Arrange
+		shapes := parse(t, prefixes+`
+ex:Loop sh:targetNode <https://example.com/a> ; sh:node ex:Loop .
+`)
+
+		// Act
+		_, err := validator.Validate(rdf.NewGraph(), shapes)
+
+		// Assert
+		assert.ErrorIs(t, err, service.ErrRecursiveShapes)
+	})
+
+	t.Run("ignores deactivated shapes", func(t *testing.T) {
+		// Arrange
+		shapes := parse(t, prefixes+`
+ex:Off sh:targetNode <https://example.com/a> ; sh:deactivated true ; sh:property [ sh:path ex:p ; sh:minCount 1 ] .
+`)
+
+		// Act
+		report, err := validator.Validate(rdf.NewGraph(), shapes)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, report.Conforms)
+	})
+
+	// Cases adapted from the core tests of the W3C SHACL test suite. Each
+	// shape targets conforming and violating nodes; want lists the
+	// component and focus node of every expected result.
+	constraintCases := []struct {
+		name   string
+		shapes string
+		data   string
+		want   []string
+	}{
+		{
+			name:   "core/node/class-001",
+			shapes: `ex:S sh:targetNode ex:alice, ex:bob, ex:rex, "alice" ; sh:class ex:Person .`,
+			data:   `ex:alice a ex:Person . ex:bob a ex:Student . ex:Student rdfs:subClassOf ex:Person . ex:rex a ex:Dog .`,
+			want:   []string{"Class ex:rex", `Class "alice"`},
+		},
+		{
+			name:   "core/property/datatype-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:age ; sh:property [ sh:path ex:age ; sh:datatype xsd:integer ] .`,
+			data:   `ex:a ex:age 42 . ex:b ex:age "42" . ex:c ex:age "forty"^^xsd:integer . ex:d ex:age ex:old .`,
+			want:   []string{"Datatype ex:b", "Datatype ex:c", "Datatype ex:d"},
+		},
+		{
+			name:   "core/property/nodeKind-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:p ; sh:property [ sh:path ex:p ; sh:nodeKind sh:IRI ] .`,
+			data:   `ex:a ex:p ex:x . ex:b ex:p "x" . ex:c ex:p [] .`,
+			want:   []string{"NodeKind ex:b", "NodeKind ex:c"},
+		},
+		{
+			name:   "core/property/minCount-001",
+			shapes: `ex:S sh:targetClass ex:Person ; sh:property [ sh:path ex:name ; sh:minCount 1 ] .`,
+			data:   `ex:a a ex:Person ; ex:name "A" . ex:b a ex:Person .`,
+			want:   []string{"MinCount ex:b"},
+		},
+		{
+			name:   "core/property/maxCount-001",
+			shapes: `ex:S sh:targetClass ex:Person ; sh:property [ sh:path ex:name ; sh:maxCount 1 ] .`,
+			data:   `ex:a a ex:Person ; ex:name "A" . ex:b a ex:Person ; ex:name "B", "Bee" .`,
+			want:   []string{"MaxCount ex:b"},
+		},
+		{
+			name:   "core/node/minExclusive-001",
+			shapes: `ex:S sh:targetNode 4, 5, "five" ; sh:minExclusive 4 .`,
+			want:   []string{`MinExclusive "4"^^xsd:integer`, `MinExclusive "five"`},
+		},
+		{
+			name:   "core/node/minInclusive-001",
+			shapes: `ex:S sh:targetNode 3, 4, 4.5 ; sh:minInclusive 4 .`,
+			want:   []string{`MinInclusive "3"^^xsd:integer`},
+		},
+		{
+			name:   "core/node/maxExclusive-001",
+			shapes: `ex:S sh:targetNode "2020-01-01"^^xsd:date, "2021-01-01"^^xsd:date ; sh:maxExclusive "2021-01-01"^^xsd:date .`,
+			want:   []string{`MaxExclusive "2021-01-01"^^xsd:date`},
+		},
+		{
+			name:   "core/node/maxInclusive-001",
+			shapes: `ex:S sh:targetNode 4, 5, ex:five ; sh:maxInclusive 4 .`,
+			want:   []string{`MaxInclusive "5"^^xsd:integer`, "MaxInclusive ex:five"},
+		},
+		{
+			name:   "core/node/minLength-001",
+			shapes: `ex:S sh:targetNode "abc", "abcd", ex:abcdef ; sh:minLength 4 .`,
+			want:   []string{`MinLength "abc"`},
+		},
+		{
+			name:   "core/property/maxLength-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:p ; sh:property [ sh:path ex:p ; sh:maxLength 3 ] .`,
+			data:   `ex:a ex:p "abc" . ex:b ex:p "abcd" . ex:c ex:p [] .`,
+			want:   []string{"MaxLength ex:b", "MaxLength ex:c"},
+		},
+		{
+			name:   "core/node/pattern-001",
+			shapes: `ex:S sh:targetNode "Hello", "hello", "Bonjour", [] ; sh:pattern "^h" ; sh:flags "i" .`,
+			want:   []string{`Pattern "Bonjour"`, "Pattern _:"},
+		},
+		{
+			name:   "core/node/languageIn-001",
+			shapes: `ex:S sh:targetNode "hi"@en-GB, "salut"@fr, "hallo"@de, "plain" ; sh:languageIn ( "en" "fr" ) .`,
+			want:   []string{`LanguageIn "hallo"@de`, `LanguageIn "plain"`},
+		},
+		{
+			name:   "core/property/uniqueLang-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:label ; sh:property [ sh:path ex:label ; sh:uniqueLang true ] .`,
+			data:   `ex:a ex:label "a"@en, "a"@fr, "a" , "b" . ex:b ex:label "a"@en, "b"@EN .`,
+			want:   []string{"UniqueLang ex:b"},
+		},
+		{
+			name:   "core/property/equals-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:p ; sh:property [ sh:path ex:p ; sh:equals ex:q ] .`,
+			data:   `ex:a ex:p 1 ; ex:q 1 . ex:b ex:p 1 ; ex:q 2 .`,
+			want:   []string{"Equals ex:b", "Equals ex:b"},
+		},
+		{
+			name:   "core/property/disjoint-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:p ; sh:property [ sh:path ex:p ; sh:disjoint ex:q ] .`,
+			data:   `ex:a ex:p 1 ; ex:q 2 . ex:b ex:p 1, 2 ; ex:q 2 .`,
+			want:   []string{"Disjoint ex:b"},
+		},
+		{
+			name:   "core/property/lessThan-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:start ; sh:property [ sh:path ex:start ; sh:lessThan ex:end ] .`,
+			data:   `ex:a ex:start 1 ; ex:end 2 . ex:b ex:start 2 ; ex:end 2 . ex:c ex:start 1 ; ex:end "two" .`,
+			want:   []string{"LessThan ex:b", "LessThan ex:c"},
+		},
+		{
+			name:   "core/property/lessThanOrEquals-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:start ; sh:property [ sh:path ex:start ; sh:lessThanOrEquals ex:end ] .`,
+			data:   `ex:a ex:start 2 ; ex:end 2 . ex:b ex:start 3 ; ex:end 2 .`,
+			want:   []string{"LessThanOrEquals ex:b"},
+		},
+		{
+			name:   "core/node/not-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b ; sh:not [ sh:property [ sh:path ex:p ; sh:minCount 1 ] ] .`,
+			data:   `ex:b ex:p 1 .`,
+			want:   []string{"Not ex:b"},
+		},
+		{
+			name: "core/node/and-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b, ex:c ;
+    sh:and ( [ sh:property [ sh:path ex:p ; sh:minCount 1 ] ] [ sh:property [ sh:path ex:q ; sh:minCount 1 ] ] ) .`,
+			data: `ex:a ex:p 1 ; ex:q 1 . ex:b ex:p 1 .`,
+			want: []string{"And ex:b", "And ex:c"},
+		},
+		{
+			name: "core/node/or-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b, ex:c ;
+    sh:or ( [ sh:property [ sh:path ex:p ; sh:minCount 1 ] ] [ sh:property [ sh:path ex:q ; sh:minCount 1 ] ] ) .`,
+			data: `ex:a ex:p 1 ; ex:q 1 . ex:b ex:q 1 .`,
+			want: []string{"Or ex:c"},
+		},
+		{
+			name: "core/node/xone-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b, ex:c ;
+    sh:xone ( [ sh:property [ sh:path ex:p ; sh:minCount 1 ] ] [ sh:property [ sh:path ex:q ; sh:minCount 1 ] ] ) .`,
+			data: `ex:a ex:p 1 ; ex:q 1 . ex:b ex:q 1 .`,
+			want: []string{"Xone ex:a", "Xone ex:c"},
+		},
+		{
+			name: "core/property/node-001",
+			shapes: `ex:S sh:targetSubjectsOf ex:address ; sh:property [ sh:path ex:address ; sh:node ex:AddressShape ] .
+ex:AddressShape sh:property [ sh:path ex:postalCode ; sh:maxCount 1 ] .`,
+			data: `ex:a ex:address [ ex:postalCode "1" ] . ex:b ex:address [ ex:postalCode "1", "2" ] .`,
+			want: []string{"Node ex:b"},
+		},
+		{
+			name:   "core/node/property-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b ; sh:property [ sh:path ex:p ; sh:hasValue 1 ] .`,
+			data:   `ex:a ex:p 1 . ex:b ex:p 2 .`,
+			want:   []string{"HasValue ex:b"},
+		},
+		{
+			name: "core/property/qualifiedValueShape-001",
+			shapes: `ex:S sh:targetClass ex:Child ;
+    sh:property [ sh:path ex:parent ; sh:qualifiedValueShape [ sh:class ex:Female ] ; sh:qualifiedMinCount 1 ] ;
+    sh:property [ sh:path ex:parent ; sh:qualifiedValueShape [ sh:class ex:Male ] ; sh:qualifiedMaxCount 1 ] .`,
+			data: `ex:a a ex:Child ; ex:parent ex:mum, ex:dad .
+ex:b a ex:Child ; ex:parent ex:dad, ex:grandad .
+ex:mum a ex:Female . ex:dad a ex:Male . ex:grandad a ex:Male .`,
+			want: []string{"QualifiedMinCount ex:b", "QualifiedMaxCount ex:b"},
+		},
+		{
+			name: "core/node/qualifiedValueShapesDisjoint-001",
+			shapes: `ex:S sh:targetNode ex:left, ex:right ;
+    sh:property [ sh:path ex:digit ; sh:qualifiedValueShape [ sh:class ex:Thumb ] ; sh:qualifiedMinCount 1 ; sh:qualifiedValueShapesDisjoint true ] ;
+    sh:property [ sh:path ex:digit ; sh:qualifiedValueShape [ sh:class ex:Finger ] ; sh:qualifiedMinCount 2 ; sh:qualifiedValueShapesDisjoint true ] .`,
+			data: `ex:left ex:digit ex:thumb, ex:f1, ex:f2 .
+ex:right ex:digit ex:both, ex:f1, ex:f2 .
+ex:thumb a ex:Thumb . ex:both a ex:Thumb, ex:Finger . ex:f1 a ex:Finger . ex:f2 a ex:Finger .`,
+			want: []string{"QualifiedMinCount ex:right"},
+		},
+		{
+			name: "core/node/closed-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b ; sh:closed true ; sh:ignoredProperties ( rdf:type ) ;
+    sh:property [ sh:path ex:p ] .`,
+			data: `ex:a a ex:Thing ; ex:p 1 . ex:b ex:p 1 ; ex:q 2 .`,
+			want: []string{"Closed ex:b"},
+		},
+		{
+			name:   "core/node/hasValue-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b ; sh:hasValue ex:a .`,
+			want:   []string{"HasValue ex:b"},
+		},
+		{
+			name:   "core/node/in-001",
+			shapes: `ex:S sh:targetNode ex:green, "green", ex:blue ; sh:in ( ex:green ex:red ) .`,
+			want:   []string{`In "green"`, "In ex:blue"},
+		},
+		{
+			name:   "core/path/path-inverse-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b ; sh:property [ sh:path [ sh:inversePath ex:child ] ; sh:minCount 1 ] .`,
+			data:   `ex:parent ex:child ex:a .`,
+			want:   []string{"MinCount ex:b"},
+		},
+		{
+			name:   "core/path/path-alternative-001",
+			shapes: `ex:S sh:targetNode ex:a, ex:b ; sh:property [ sh:path [ sh:alternativePath ( ex:p ex:q ) ] ; sh:maxCount 1 ] .`,
+			data:   `ex:a ex:q 1 . ex:b ex:p 1 ; ex:q 2 .`,
+			want:   []string{"MaxCount ex:b"},
+		},
+		{
+			name:   "core/path/path-oneOrMore-001",
+			shapes: `ex:S sh:targetNode ex:a ; sh:property [ sh:path [ sh:oneOrMorePath ex:next ] ; sh:maxCount 2 ] .`,
+			data:   `ex:a ex:next ex:b . ex:b ex:next ex:c . ex:c ex:next ex:a .`,
+			want:   []string{"MaxCount ex:a"},
+		},
+	}
+
+	t.Run("reports each constraint component", func(t *testing.T) {
+		for _, tc := range constraintCases {
+			t.Run(tc.name, func(t *testing.T) {
+				// Arrange
+				shapes := parse(t, prefixes+tc.shapes)
+				data := parse(t, prefixes+tc.data)
+
+				// Act
+				report, err := validator.Validate(data, shapes)
+
+				// Assert
+				require.NoError(t, err)
+				got := make([]string, 0, len(report.Results))
+				for _, result := range report.Results {
+					got = append(got, strings.TrimSuffix(strings.TrimPrefix(result.Component.Value(), service.SHNamespace), "ConstraintComponent")+" "+shorten(result.FocusNode))
+				}
+				assert.ElementsMatch(t, tc.want, got)
+				assert.Equal(t, len(tc.want) == 0, report.Conforms)
+			})
+		}
+	})
+
+	t.Run("rejects malformed shapes graphs", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			shapes string
+		}{
+			{"sh:in is not a list", `ex:S sh:targetNode ex:a ; sh:in ex:green .`},
+			{"sh:in list has no rest", `ex:S sh:targetNode ex:a ; sh:in _:list . _:list rdf:first ex:green .`},
+			{"sh:or list is cyclic", `ex:S sh:targetNode ex:a ; sh:or _:list . _:list rdf:first ex:T ; rdf:rest _:list .`},
+			{"sh:languageIn is a literal", `ex:S sh:targetNode "hi"@en ; sh:languageIn "en" .`},
+			{"sh:ignoredProperties is not a list", `ex:S sh:targetNode ex:a ; sh:closed true ; sh:ignoredProperties ex:p .`},
+			{"sh:pattern is not a regular expression", `ex:S sh:targetNode "a" ; sh:pattern "([a-z" .`},
+			{"sh:path is a literal", `ex:S sh:targetNode ex:a ; sh:property [ sh:path "p" ; sh:minCount 1 ] .`},
+			{"sh:path is not a known path", `ex:S sh:targetNode ex:a ; sh:property [ sh:path [ ex:step ex:p ] ] .`},
+			{"sh:minCount is negative", `ex:S sh:targetNode ex:a ; sh:property [ sh:path ex:p ; sh:minCount -1 ] .`},
+			{"sh:maxCount is not an integer", `ex:S sh:targetNode ex:a ; sh:property [ sh:path ex:p ; sh:maxCount "one" ] .`},
+			{"sh:minLength is a decimal", `ex:S sh:targetNode "a" ; sh:minLength 1.5 .`},
+			{"sh:qualifiedMinCount is an IRI", `ex:S sh:targetNode ex:a ; sh:property [ sh:path ex:p ; sh:qualifiedValueShape ex:T ; sh:qualifiedMinCount ex:one ] .`},
+			{"sh:nodeKind is unknown", `ex:S sh:targetNode ex:a ; sh:nodeKind sh:Node .`},
+			{"shapes refer to themselves", `ex:S sh:targetNode ex:a ; sh:node ex:T . ex:T sh:node ex:S .`},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				// Act
+				_, err := validator.Validate(parse(t, prefixes+`ex:a ex:p 1 .`), parse(t, prefixes+tc.shapes))
+
+				// Assert
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+// shorten writes term the way the cases above do, with the ex: and xsd:
+// prefixes and without blank node labels
+func shorten(term rdf.Term) string {
+	switch term.Kind() {
+	case rdf.KindBlankNode:
+		return "_:"
+	case rdf.KindIRI:
+		return strings.Replace(term.Value(), "https://example.com/ns#", "ex:", 1)
+	}
+	l := term.(rdf.Literal)
+	switch {
+	case l.Language != "":
+		return `"` + l.Lexical + `"@` + l.Language
+	case l.Datatype == "" || l.Datatype == rdf.XSDNamespace+"string":
+		return `"` + l.Lexical + `"`
+	}
+	return `"` + l.Lexical + `"^^xsd:` + strings.TrimPrefix(string(l.Datatype), rdf.XSDNamespace)
+}
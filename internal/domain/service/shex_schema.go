@@ -0,0 +1,346 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// ShEx schema formats
+const (
+	FormatShExC RDFFormat = "text/shex"
+	FormatShExJ RDFFormat = "application/shex+json"
+)
+
+// ShExNamespace is the namespace of the ShEx vocabulary
+const ShExNamespace = "http://www.w3.org/ns/shex#"
+
+// Unbounded is the maximum cardinality of a triple expression with no upper bound
+const Unbounded = -1
+
+// ShExSchema is a parsed ShEx schema
+type ShExSchema struct {
+	// Start is the shape expression used for START in shape maps
+	Start ShapeExpr
+
+	// Shapes holds the labelled shape expressions
+	Shapes map[string]ShapeExpr
+
+	// Prefixes declared by a ShExC schema, used to read shape maps
+	Prefixes map[string]string
+
+	// Base is the base IRI of the schema
+	Base string
+}
+
+// NewShExSchema creates an empty schema
+func NewShExSchema() *ShExSchema {
+	return &ShExSchema{
+		Shapes:   make(map[string]ShapeExpr),
+		Prefixes: make(map[string]string),
+	}
+}
+
+// ShapeExpr is a ShEx shape expression
+type ShapeExpr interface {
+	shapeExpr()
+}
+
+// ShapeOr is satisfied when any of its expressions is satisfied
+type ShapeOr struct {
+	Exprs []ShapeExpr
+}
+
+// ShapeAnd is satisfied when all of its expressions are satisfied
+type ShapeAnd struct {
+	Exprs []ShapeExpr
+}
+
+// ShapeNot is satisfied when its expression is not
+type ShapeNot struct {
+	Expr ShapeExpr
+}
+
+// ShapeRef refers to a labelled shape expression
+type ShapeRef struct {
+	Label string
+}
+
+// ShapeExternal is a shape expression defined outside the schema
+type ShapeExternal struct{}
+
+// NodeConstraint restricts the kind, datatype, value or lexical form of a node
+type NodeConstraint struct {
+	NodeKind string // "iri", "bnode", "literal" or "nonliteral"
+	Datatype rdf.IRI
+	Values   []ValueSetValue
+
+	Length         *int
+	MinLength      *int
+	MaxLength      *int
+	Pattern        string
+	Flags          string
+	MinInclusive   *rdf.Literal
+	MinExclusive   *rdf.Literal
+	MaxInclusive   *rdf.Literal
+	MaxExclusive   *rdf.Literal
+	TotalDigits    *int
+	FractionDigits *int
+}
+
+// Shape constrains the triples around a node
+type Shape struct {
+	Closed     bool
+	Extra      []rdf.IRI
+	Expression TripleExpr
+}
+
+func (*ShapeOr) shapeExpr()        {}
+func (*ShapeAnd) shapeExpr()       {}
+func (*ShapeNot) shapeExpr()       {}
+func (*ShapeRef) shapeExpr()       {}
+func (*ShapeExternal) shapeExpr()  {}
+func (*NodeConstraint) shapeExpr() {}
+func (*Shape) shapeExpr()          {}
+
+// TripleExpr is a ShEx triple expression
+type TripleExpr interface {
+	cardinality() (min, max int)
+}
+
+// EachOf is satisfied when every expression is satisfied by a distinct set of triples
+type EachOf struct {
+	Exprs []TripleExpr
+	Min   int
+	Max   int
+}
+
+// OneOf is satisfied when exactly one of its expressions is satisfied
+type OneOf struct {
+	Exprs []TripleExpr
+	Min   int
+	Max   int
+}
+
+// TripleConstraint matches triples with a predicate whose value satisfies an expression
+type TripleConstraint struct {
+	Predicate rdf.IRI
+	Inverse   bool
+	ValueExpr ShapeExpr // nil matches any value
+	Min       int
+	Max       int
+}
+
+func (e *EachOf) cardinality() (int, int)           { return e.Min, e.Max }
+func (e *OneOf) cardinality() (int, int)            { return e.Min, e.Max }
+func (e *TripleConstraint) cardinality() (int, int) { return e.Min, e.Max }
+
+// ValueSetValue is a member of a node constraint's value set
+type ValueSetValue interface {
+	matches(term rdf.Term) bool
+}
+
+// ObjectValue matches exactly one IRI or literal
+type ObjectValue struct {
+	Term rdf.Term
+}
+
+// IRIStem matches IRIs starting with Stem, except those excluded. An
+// empty stem is a wildcard.
+type IRIStem struct {
+	Stem       string
+	Exclusions []Exclusion
+}
+
+// LiteralStem matches literals whose lexical form starts with Stem, except those excluded
+type LiteralStem struct {
+	Stem       string
+	Exclusions []Exclusion
+}
+
+// LanguageStem matches language-tagged literals whose tag is Stem or
+// starts with Stem and "-", except those excluded. Exact matches Stem only.
+type LanguageStem struct {
+	Stem       string
+	Exact      bool
+	Exclusions []Exclusion
+}
+
+// Exclusion removes a value, or every value with a prefix, from a stem range
+type Exclusion struct {
+	Value  string
+	IsStem bool
+}
+
+func (v ObjectValue) matches(term rdf.Term) bool {
+	return v.Term.Equal(term)
+}
+
+func (v IRIStem) matches(term rdf.Term) bool {
+	iri, ok := term.(rdf.IRI)
+	return ok && strings.HasPrefix(string(iri), v.Stem) && !excluded(string(iri), v.Exclusions)
+}
+
+func (v LiteralStem) matches(term rdf.Term) bool {
+	l, ok := term.(rdf.Literal)
+	return ok && strings.HasPrefix(l.Lexical, v.Stem) && !excluded(l.Lexical, v.Exclusions)
+}
+
+func (v LanguageStem) matches(term rdf.Term) bool {
+	l, ok := term.(rdf.Literal)
+	if !ok || l.Language == "" {
+		return false
+	}
+	if v.Exact {
+		return strings.EqualFold(l.Language, v.Stem)
+	}
+	if v.Stem != "" && !languageMatches(l.Language, v.Stem) {
+		return false
+	}
+	for _, exclusion := range v.Exclusions {
+		if (exclusion.IsStem && languageMatches(l.Language, exclusion.Value)) || strings.EqualFold(l.Language, exclusion.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// excluded reports whether s is removed by any exclusion
+func excluded(s string, exclusions []Exclusion) bool {
+	for _, exclusion := range exclusions {
+		if exclusion.IsStem && strings.HasPrefix(s, exclusion.Value) || !exclusion.IsStem && s == exclusion.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStructure rejects schemas that refer to undefined shapes, hold
+// invalid patterns, include triple expressions in themselves or refer back
+// to a shape through a negation, none of which the ShEx specification allows
+func (s *ShExSchema) checkStructure() error {
+	labels := make([]string, 0, len(s.Shapes))
+	for label := range s.Shapes {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	refs := make(map[string][]shapeReference)
+	if err := s.collectReferences("", s.Start, false, refs); err != nil {
+		return err
+	}
+	for _, label := range labels {
+		if err := s.collectReferences(label, s.Shapes[label], false, refs); err != nil {
+			return err
+		}
+	}
+
+	for _, label := range labels {
+		for _, ref := range refs[label] {
+			if ref.negated && reaches(refs, ref.label, label) {
+				return fmt.Errorf("shape %s refers to itself through a negation", labelTerm(label))
+			}
+		}
+	}
+	return nil
+}
+
+// shapeReference is an edge of the dependency graph between shape labels
+type shapeReference struct {
+	label   string
+	negated bool
+}
+
+// collectReferences records the shapes expr refers to as dependencies of
+// from, marking those reached under a NOT
+func (s *ShExSchema) collectReferences(from string, expr ShapeExpr, negated bool, refs map[string][]shapeReference) error {
+	switch e := expr.(type) {
+	case *ShapeOr:
+		for _, child := range e.Exprs {
+			if err := s.collectReferences(from, child, negated, refs); err != nil {
+				return err
+			}
+		}
+	case *ShapeAnd:
+		for _, child := range e.Exprs {
+			if err := s.collectReferences(from, child, negated, refs); err != nil {
+				return err
+			}
+		}
+	case *ShapeNot:
+		return s.collectReferences(from, e.Expr, true, refs)
+	case *ShapeRef:
+		if _, ok := s.Shapes[e.Label]; !ok {
+			return fmt.Errorf("shape %s is not defined", labelTerm(e.Label))
+		}
+		refs[from] = append(refs[from], shapeReference{label: e.Label, negated: negated})
+	case *NodeConstraint:
+		if e.Pattern != "" {
+			if _, err := compilePattern(e.Pattern, e.Flags); err != nil {
+				return err
+			}
+		}
+	case *Shape:
+		if err := checkInclusions(e.Expression, make(map[TripleExpr]bool)); err != nil {
+			return err
+		}
+		for _, tc := range tripleConstraints(e.Expression) {
+			if tc.ValueExpr == nil {
+				continue
+			}
+			if err := s.collectReferences(from, tc.ValueExpr, negated, refs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkInclusions rejects triple expressions that include themselves.
+// active holds the expressions whose inclusions are being followed.
+func checkInclusions(expr TripleExpr, active map[TripleExpr]bool) error {
+	switch e := expr.(type) {
+	case *EachOf:
+		for _, child := range e.Exprs {
+			if err := checkInclusions(child, active); err != nil {
+				return err
+			}
+		}
+	case *OneOf:
+		for _, child := range e.Exprs {
+			if err := checkInclusions(child, active); err != nil {
+				return err
+			}
+		}
+	case *tripleExprInclusion:
+		if active[e.target] {
+			return fmt.Errorf("triple expression %s includes itself", labelTerm(e.label))
+		}
+		active[e.target] = true
+		defer delete(active, e.target)
+		return checkInclusions(e.target, active)
+	}
+	return nil
+}
+
+// reaches reports whether to can be reached from from in the dependency graph
+func reaches(refs map[string][]shapeReference, from, to string) bool {
+	visited := map[string]bool{from: true}
+	pending := []string{from}
+	for len(pending) > 0 {
+		label := pending[0]
+		pending = pending[1:]
+		if label == to {
+			return true
+		}
+		for _, ref := range refs[label] {
+			if !visited[ref.label] {
+				visited[ref.label] = true
+				pending = append(pending, ref.label)
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// ShapeAssociation asks whether a node, or every node selected by a
+// pattern, conforms to a shape
+type ShapeAssociation struct {
+	// Node is the focus node. It is nil when Pattern selects the nodes.
+	Node rdf.Term
+
+	// Pattern selects focus nodes from the data graph
+	Pattern *FocusPattern
+
+	// Shape is the shape label. An empty label means the schema's start shape.
+	Shape string
+}
+
+// FocusPattern selects focus nodes with {FOCUS p o} or, when Inverse is
+// set, {s p FOCUS}. A nil Value matches any node.
+type FocusPattern struct {
+	Predicate rdf.IRI
+	Value     rdf.Term
+	Inverse   bool
+}
+
+// ShapeMap is a list of shape associations
+type ShapeMap []ShapeAssociation
+
+// ParseShapeMap parses a query shape map such as
+// "<http://a.example/s>@<http://a.example/S>, {FOCUS a ex:T}@START".
+// Prefixed names are expanded with the schema's prefixes.
+func ParseShapeMap(text string, schema *ShExSchema) (ShapeMap, error) {
+	tokens, err := lexShExC(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &shexcParser{tokens: tokens, schema: schema}
+	if schema == nil {
+		p.schema = NewShExSchema()
+	}
+
+	var shapeMap ShapeMap
+	for p.peek().kind != tokEOF {
+		association, err := p.parseShapeAssociation()
+		if err != nil {
+			return nil, err
+		}
+		shapeMap = append(shapeMap, association)
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %q in shape map", tok.value)
+	}
+	return shapeMap, nil
+}
+
+// FocusNodes returns the nodes of data selected by the association
+func (a ShapeAssociation) FocusNodes(data *rdf.Graph) []rdf.Term {
+	if a.Pattern == nil {
+		return []rdf.Term{a.Node}
+	}
+	nodes := &termSet{}
+	if a.Pattern.Inverse {
+		for _, t := range data.Match(a.Pattern.Value, a.Pattern.Predicate, nil) {
+			nodes.add(t.Object)
+		}
+	} else {
+		for _, t := range data.Match(nil, a.Pattern.Predicate, a.Pattern.Value) {
+			nodes.add(t.Subject)
+		}
+	}
+	return nodes.terms
+}
+
+func (p *shexcParser) parseShapeAssociation() (ShapeAssociation, error) {
+	var association ShapeAssociation
+	if p.isPunct("{") {
+		pattern, err := p.parseFocusPattern()
+		if err != nil {
+			return association, err
+		}
+		association.Pattern = pattern
+	} else {
+		node, err := p.parseMapNode()
+		if err != nil {
+			return association, err
+		}
+		association.Node = node
+	}
+
+	tok := p.next()
+	switch {
+	case tok.kind == tokLangTag && strings.EqualFold(tok.value, "START"):
+	case tok.kind == tokShapeRef && tok.ref == tokIRI:
+		association.Shape = p.resolve(tok.value)
+	case tok.kind == tokShapeRef && tok.ref == tokPName:
+		label, err := p.expand(tok)
+		if err != nil {
+			return association, err
+		}
+		association.Shape = label
+	case tok.kind == tokShapeRef:
+		association.Shape = tok.value
+	default:
+		return association, p.errorf(tok, "expected @shape or @START, found %q", tok.value)
+	}
+	return association, nil
+}
+
+func (p *shexcParser) parseFocusPattern() (*FocusPattern, error) {
+	p.next()
+	pattern := &FocusPattern{}
+	inverse := !p.isKeyword("FOCUS")
+	if inverse {
+		value, err := p.parsePatternValue()
+		if err != nil {
+			return nil, err
+		}
+		pattern.Value, pattern.Inverse = value, true
+	} else {
+		p.next()
+	}
+
+	predicate, err := p.parsePredicate()
+	if err != nil {
+		return nil, err
+	}
+	pattern.Predicate = predicate
+
+	if inverse {
+		if !p.isKeyword("FOCUS") {
+			return nil, p.errorf(p.peek(), "a focus pattern needs FOCUS as its subject or object")
+		}
+		p.next()
+	} else if pattern.Value, err = p.parsePatternValue(); err != nil {
+		return nil, err
+	}
+	return pattern, p.expectPunct("}")
+}
+
+// parsePatternValue reads a node or the "_" wildcard
+func (p *shexcParser) parsePatternValue() (rdf.Term, error) {
+	if p.isKeyword("_") {
+		p.next()
+		return nil, nil
+	}
+	return p.parseMapNode()
+}
+
+func (p *shexcParser) parseMapNode() (rdf.Term, error) {
+	switch tok := p.peek(); tok.kind {
+	case tokIRI, tokPName:
+		return p.parseIRI()
+	case tokBlankNode:
+		p.next()
+		return rdf.BlankNode(strings.TrimPrefix(tok.value, "_:")), nil
+	case tokString:
+		// "x"@START reads as a plain literal associated with the start shape
+		if next := p.tokens[p.pos+1]; next.kind == tokLangTag && strings.EqualFold(next.value, "START") {
+			p.next()
+			return rdf.NewLiteral(tok.value), nil
+		}
+	}
+	return p.parseLiteral()
+}
@@ -0,0 +1,639 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// ErrShapeMatchTooComplex is returned when matching a neighbourhood would
+// try more triple assignments than the validator allows
+var ErrShapeMatchTooComplex = errors.New("shape matching is too complex")
+
+// maxShapeAssignments bounds the triple assignments tried for one node and shape
+const maxShapeAssignments = 10000
+
+// ShExValidationService validates data graphs against ShEx schemas
+type ShExValidationService interface {
+	// Validate checks every association of the shape map. The report lists
+	// one result per node that does not conform to its shape.
+	Validate(data *rdf.Graph, schema *ShExSchema, shapeMap ShapeMap) (*ValidationReport, error)
+}
+
+// StandardShExValidationService implements ShExValidationService
+type StandardShExValidationService struct{}
+
+// NewStandardShExValidationService creates a new ShEx validation service
+func NewStandardShExValidationService() *StandardShExValidationService {
+	return &StandardShExValidationService{}
+}
+
+// Validate implements ShExValidationService
+func (s *StandardShExValidationService) Validate(data *rdf.Graph, schema *ShExSchema, shapeMap ShapeMap) (*ValidationReport, error) {
+	v := &shexValidator{
+		data:       data,
+		schema:     schema,
+		inProgress: make(map[string]bool),
+		patterns:   make(map[string]*regexp.Regexp),
+	}
+	report := &ValidationReport{Conforms: true}
+
+	for _, association := range shapeMap {
+		expr := schema.Start
+		label := rdf.Term(rdf.IRI(ShExNamespace + "Start"))
+		if association.Shape != "" {
+			expr = schema.Shapes[association.Shape]
+			label = labelTerm(association.Shape)
+		}
+		if expr == nil {
+			return nil, fmt.Errorf("shape %s is not defined in the schema", label)
+		}
+
+		for _, node := range association.FocusNodes(data) {
+			ok, reason, err := v.satisfies(node, expr)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				continue
+			}
+			report.Conforms = false
+			report.Results = append(report.Results, ValidationResult{
+				FocusNode:   node,
+				SourceShape: label,
+				Component:   rdf.IRI(ShExNamespace + expressionType(expr)),
+				Severity:    SHViolation,
+				Messages:    []rdf.Literal{rdf.NewLiteral(reason)},
+			})
+		}
+	}
+	return report, nil
+}
+
+// shexValidator checks nodes against shape expressions. Shape references
+// under evaluation are assumed to hold, so recursive shapes terminate.
+type shexValidator struct {
+	data       *rdf.Graph
+	schema     *ShExSchema
+	inProgress map[string]bool
+	patterns   map[string]*regexp.Regexp
+	depth      int
+}
+
+// satisfies reports whether node conforms to expr and, if not, why
+func (v *shexValidator) satisfies(node rdf.Term, expr ShapeExpr) (bool, string, error) {
+	v.depth++
+	defer func() { v.depth-- }()
+	if v.depth > maxShapeDepth {
+		return false, "", ErrRecursiveShapes
+	}
+
+	switch e := expr.(type) {
+	case *ShapeOr:
+		var reasons []string
+		for _, child := range e.Exprs {
+			ok, reason, err := v.satisfies(node, child)
+			if err != nil || ok {
+				return ok, "", err
+			}
+			reasons = append(reasons, reason)
+		}
+		return false, "none of the alternatives hold: " + strings.Join(reasons, "; "), nil
+	case *ShapeAnd:
+		for _, child := range e.Exprs {
+			if ok, reason, err := v.satisfies(node, child); err != nil || !ok {
+				return false, reason, err
+			}
+		}
+		return true, "", nil
+	case *ShapeNot:
+		ok, _, err := v.satisfies(node, e.Expr)
+		if err != nil || !ok {
+			return true, "", err
+		}
+		return false, fmt.Sprintf("%s matches a negated shape", node), nil
+	case *ShapeRef:
+		return v.satisfiesLabel(node, e.Label)
+	case *ShapeExternal:
+		return false, "external shapes are not available", nil
+	case *NodeConstraint:
+		reason, err := v.checkNodeConstraint(node, e)
+		return reason == "", reason, err
+	case *Shape:
+		return v.matchShape(node, e)
+	}
+	return false, "", fmt.Errorf("unsupported shape expression %T", expr)
+}
+
+// satisfiesLabel checks node against a labelled shape expression
+func (v *shexValidator) satisfiesLabel(node rdf.Term, label string) (bool, string, error) {
+	expr, ok := v.schema.Shapes[label]
+	if !ok {
+		return false, "", fmt.Errorf("shape %s is not defined in the schema", label)
+	}
+	key := node.String() + " @" + label
+	if v.inProgress[key] {
+		return true, "", nil
+	}
+	v.inProgress[key] = true
+	defer delete(v.inProgress, key)
+
+	ok, reason, err := v.satisfies(node, expr)
+	if err != nil || ok {
+		return ok, "", err
+	}
+	return false, fmt.Sprintf("%s does not conform to %s: %s", node, labelTerm(label), reason), nil
+}
+
+// checkNodeConstraint returns why node violates the constraint, or ""
+func (v *shexValidator) checkNodeConstraint(node rdf.Term, c *NodeConstraint) (string, error) {
+	literal, isLiteral := node.(rdf.Literal)
+
+	switch c.NodeKind {
+	case "iri":
+		if node.Kind() != rdf.KindIRI {
+			return fmt.Sprintf("%s is not an IRI", node), nil
+		}
+	case "bnode":
+		if node.Kind() != rdf.KindBlankNode {
+			return fmt.Sprintf("%s is not a blank node", node), nil
+		}
+	case "literal":
+		if !isLiteral {
+			return fmt.Sprintf("%s is not a literal", node), nil
+		}
+	case "nonliteral":
+		if isLiteral {
+			return fmt.Sprintf("%s is a literal", node), nil
+		}
+	}
+
+	if c.Datatype != "" && (!isLiteral || literal.EffectiveDatatype() != c.Datatype || !isWellFormed(literal)) {
+		return fmt.Sprintf("%s is not a valid %s", node, c.Datatype), nil
+	}
+
+	if c.Values != nil {
+		matched := false
+		for _, value := range c.Values {
+			if value.matches(node) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("%s is not in the value set", node), nil
+		}
+	}
+
+	if reason, err := v.checkStringFacets(node, c); reason != "" || err != nil {
+		return reason, err
+	}
+	return checkNumericFacets(node, c), nil
+}
+
+func (v *shexValidator) checkStringFacets(node rdf.Term, c *NodeConstraint) (string, error) {
+	if c.Length == nil && c.MinLength == nil && c.MaxLength == nil && c.Pattern == "" {
+		return "", nil
+	}
+	if node.Kind() == rdf.KindBlankNode {
+		return fmt.Sprintf("%s has no lexical form", node), nil
+	}
+
+	length := utf8.RuneCountInString(node.Value())
+	switch {
+	case c.Length != nil && length != *c.Length:
+		return fmt.Sprintf("%s does not have length %d", node, *c.Length), nil
+	case c.MinLength != nil && length < *c.MinLength:
+		return fmt.Sprintf("%s is shorter than %d", node, *c.MinLength), nil
+	case c.MaxLength != nil && length > *c.MaxLength:
+		return fmt.Sprintf("%s is longer than %d", node, *c.MaxLength), nil
+	}
+
+	if c.Pattern != "" {
+		re, err := v.pattern(c.Pattern, c.Flags)
+		if err != nil {
+			return "", err
+		}
+		if !re.MatchString(node.Value()) {
+			return fmt.Sprintf("%s does not match /%s/", node, c.Pattern), nil
+		}
+	}
+	return "", nil
+}
+
+// pattern compiles and caches a ShEx regular expression
+func (v *shexValidator) pattern(pattern, flags string) (*regexp.Regexp, error) {
+	key := flags + "/" + pattern
+	if re, ok := v.patterns[key]; ok {
+		return re, nil
+	}
+	re, err := compilePattern(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	v.patterns[key] = re
+	return re, nil
+}
+
+// compilePattern compiles a ShEx regular expression with its flags
+func compilePattern(pattern, flags string) (*regexp.Regexp, error) {
+	expression := pattern
+	if strings.Contains(flags, "x") {
+		expression = strings.Join(strings.Fields(expression), "")
+	}
+	if supported := strings.ReplaceAll(flags, "x", ""); supported != "" {
+		expression = "(?" + supported + ")" + expression
+	}
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern /%s/: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func checkNumericFacets(node rdf.Term, c *NodeConstraint) string {
+	if c.MinInclusive == nil && c.MinExclusive == nil && c.MaxInclusive == nil && c.MaxExclusive == nil &&
+		c.TotalDigits == nil && c.FractionDigits == nil {
+		return ""
+	}
+	literal, ok := node.(rdf.Literal)
+	if !ok || !isNumeric(literal.EffectiveDatatype()) || !isWellFormed(literal) {
+		return fmt.Sprintf("%s is not numeric", node)
+	}
+
+	bounds := []struct {
+		bound *rdf.Literal
+		keep  func(int) bool
+		name  string
+	}{
+		{c.MinInclusive, func(n int) bool { return n >= 0 }, "MININCLUSIVE"},
+		{c.MinExclusive, func(n int) bool { return n > 0 }, "MINEXCLUSIVE"},
+		{c.MaxInclusive, func(n int) bool { return n <= 0 }, "MAXINCLUSIVE"},
+		{c.MaxExclusive, func(n int) bool { return n < 0 }, "MAXEXCLUSIVE"},
+	}
+	for _, b := range bounds {
+		if b.bound == nil {
+			continue
+		}
+		if n, ok := compareValues(literal, *b.bound); !ok || !b.keep(n) {
+			return fmt.Sprintf("%s violates %s %s", node, b.name, b.bound.Lexical)
+		}
+	}
+
+	if c.TotalDigits != nil || c.FractionDigits != nil {
+		if !isExactNumeric(literal.EffectiveDatatype()) {
+			return fmt.Sprintf("%s is not a decimal", node)
+		}
+		total, fraction := countDigits(literal.Lexical)
+		if c.TotalDigits != nil && total > *c.TotalDigits {
+			return fmt.Sprintf("%s has more than %d digits", node, *c.TotalDigits)
+		}
+		if c.FractionDigits != nil && fraction > *c.FractionDigits {
+			return fmt.Sprintf("%s has more than %d fraction digits", node, *c.FractionDigits)
+		}
+	}
+	return ""
+}
+
+// countDigits counts the significant total and fraction digits of a decimal
+func countDigits(lexical string) (int, int) {
+	lexical = strings.TrimLeft(lexical, "+-")
+	integer, fraction, _ := strings.Cut(lexical, ".")
+	integer = strings.TrimLeft(integer, "0")
+	fraction = strings.TrimRight(fraction, "0")
+	if integer == "" && fraction == "" {
+		return 1, 0
+	}
+	return len(integer) + len(fraction), len(fraction)
+}
+
+// matchShape checks the neighbourhood of node against a shape
+func (v *shexValidator) matchShape(node rdf.Term, shape *Shape) (bool, string, error) {
+	constraints := tripleConstraints(shape.Expression)
+	extra := make(map[rdf.IRI]bool, len(shape.Extra))
+	for _, predicate := range shape.Extra {
+		extra[predicate] = true
+	}
+
+	// Each matched triple is described by the constraints it could count
+	// towards and whether EXTRA lets it stay unmatched. Triples with the
+	// same description are interchangeable, so only their number matters.
+	groups := make(map[string]*tripleGroup)
+	var order []string
+	outgoing := v.data.Match(node, nil, nil)
+	for i, t := range append(outgoing, v.data.Match(nil, nil, node)...) {
+		inverse := i >= len(outgoing)
+		predicate, _ := t.Predicate.(rdf.IRI)
+		value := t.Object
+		if inverse {
+			value = t.Subject
+		}
+
+		mentioned := false
+		var candidates []int
+		for i, tc := range constraints {
+			if tc.Predicate != predicate || tc.Inverse != inverse {
+				continue
+			}
+			mentioned = true
+			ok := tc.ValueExpr == nil
+			if !ok {
+				var err error
+				if ok, _, err = v.satisfies(value, tc.ValueExpr); err != nil {
+					return false, "", err
+				}
+			}
+			if ok {
+				candidates = append(candidates, i)
+			}
+		}
+
+		switch {
+		case !mentioned && !inverse && shape.Closed:
+			return false, fmt.Sprintf("%s is not allowed in a closed shape", predicate), nil
+		case !mentioned:
+			continue
+		case len(candidates) == 0 && !extra[predicate]:
+			_, reason, err := v.valueReason(value, constraints, predicate, inverse)
+			return false, reason, err
+		case len(candidates) == 0:
+			continue
+		}
+
+		key := fmt.Sprint(candidates, extra[predicate])
+		group, ok := groups[key]
+		if !ok {
+			group = &tripleGroup{candidates: candidates, optional: extra[predicate]}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.count++
+	}
+	if shape.Expression == nil {
+		return true, "", nil
+	}
+
+	m := &bagMatcher{memo: make(map[string]bool)}
+	counts := make([]int, len(constraints))
+	tries := 0
+	var assign func(int) (bool, error)
+	assign = func(g int) (bool, error) {
+		if g == len(order) {
+			tries++
+			if tries > maxShapeAssignments {
+				return false, ErrShapeMatchTooComplex
+			}
+			return m.matches(shape.Expression, counts, constraints), nil
+		}
+		group := groups[order[g]]
+		return distribute(group, counts, func() (bool, error) { return assign(g + 1) })
+	}
+	ok, err := assign(0)
+	if err != nil || ok {
+		return ok, "", err
+	}
+	return false, cardinalityReason(node, shape, constraints, groups), nil
+}
+
+// valueReason explains why a value fits none of the constraints on its predicate
+func (v *shexValidator) valueReason(value rdf.Term, constraints []*TripleConstraint, predicate rdf.IRI, inverse bool) (bool, string, error) {
+	for _, tc := range constraints {
+		if tc.Predicate == predicate && tc.Inverse == inverse && tc.ValueExpr != nil {
+			_, reason, err := v.satisfies(value, tc.ValueExpr)
+			return false, fmt.Sprintf("value of %s: %s", predicate, reason), err
+		}
+	}
+	return false, fmt.Sprintf("value of %s is not allowed", predicate), nil
+}
+
+// cardinalityReason reports the first constraint whose triple count is out
+// of range, falling back to a general message
+func cardinalityReason(node rdf.Term, shape *Shape, constraints []*TripleConstraint, groups map[string]*tripleGroup) string {
+	totals := make([]int, len(constraints))
+	for _, group := range groups {
+		for _, i := range group.candidates {
+			totals[i] += group.count
+		}
+	}
+	for i, tc := range constraints {
+		if totals[i] < tc.Min || (tc.Max != Unbounded && totals[i] > tc.Max) {
+			return fmt.Sprintf("%s has %d values for %s, expected %s", node, totals[i], tc.Predicate, cardinalityText(tc.Min, tc.Max))
+		}
+	}
+	return fmt.Sprintf("the triples of %s do not match the shape", node)
+}
+
+func cardinalityText(min, max int) string {
+	switch {
+	case max == Unbounded:
+		return fmt.Sprintf("at least %d", min)
+	case min == max:
+		return strconv.Itoa(min)
+	}
+	return fmt.Sprintf("%d to %d", min, max)
+}
+
+// tripleGroup is a number of interchangeable neighbourhood triples
+type tripleGroup struct {
+	candidates []int
+	optional   bool
+	count      int
+}
+
+// distribute tries every way of spreading a group's triples over its
+// candidate constraints, leaving EXTRA triples unmatched where allowed
+func distribute(group *tripleGroup, counts []int, next func() (bool, error)) (bool, error) {
+	slots := len(group.candidates)
+	if group.optional {
+		slots++
+	}
+	var place func(slot, remaining int) (bool, error)
+	place = func(slot, remaining int) (bool, error) {
+		if slot == slots-1 {
+			if slot < len(group.candidates) {
+				counts[group.candidates[slot]] += remaining
+				defer func() { counts[group.candidates[slot]] -= remaining }()
+			}
+			return next()
+		}
+		for n := remaining; n >= 0; n-- {
+			counts[group.candidates[slot]] += n
+			ok, err := place(slot+1, remaining-n)
+			counts[group.candidates[slot]] -= n
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	return place(0, group.count)
+}
+
+// tripleConstraints lists the distinct triple constraints of an expression
+func tripleConstraints(expr TripleExpr) []*TripleConstraint {
+	var constraints []*TripleConstraint
+	seen := make(map[*TripleConstraint]bool)
+	var walk func(TripleExpr)
+	walk = func(expr TripleExpr) {
+		switch e := expr.(type) {
+		case *TripleConstraint:
+			if !seen[e] {
+				seen[e] = true
+				constraints = append(constraints, e)
+			}
+		case *EachOf:
+			for _, child := range e.Exprs {
+				walk(child)
+			}
+		case *OneOf:
+			for _, child := range e.Exprs {
+				walk(child)
+			}
+		case *tripleExprInclusion:
+			walk(e.target)
+		}
+	}
+	walk(expr)
+	return constraints
+}
+
+// bagMatcher decides whether triple counts per constraint satisfy a triple
+// expression
+type bagMatcher struct {
+	memo map[string]bool
+}
+
+// matches checks expr including its cardinality
+func (m *bagMatcher) matches(expr TripleExpr, counts []int, constraints []*TripleConstraint) bool {
+	if inclusion, ok := expr.(*tripleExprInclusion); ok {
+		return m.matches(inclusion.target, counts, constraints)
+	}
+	if tc, ok := expr.(*TripleConstraint); ok {
+		n := counts[indexOf(constraints, tc)]
+		return n >= tc.Min && (tc.Max == Unbounded || n <= tc.Max)
+	}
+	min, max := expr.cardinality()
+	if min == 1 && max == 1 {
+		return m.once(expr, counts, constraints)
+	}
+	return m.repeated(expr, counts, constraints, min, max)
+}
+
+// once checks a single repetition of a group
+func (m *bagMatcher) once(expr TripleExpr, counts []int, constraints []*TripleConstraint) bool {
+	switch e := expr.(type) {
+	case *EachOf:
+		for _, child := range e.Exprs {
+			if !m.matches(child, restrict(counts, constraints, child), constraints) {
+				return false
+			}
+		}
+		return true
+	case *OneOf:
+		for _, child := range e.Exprs {
+			sub := restrict(counts, constraints, child)
+			if equalCounts(sub, counts) && m.matches(child, sub, constraints) {
+				return true
+			}
+		}
+		return false
+	}
+	return m.matches(expr, counts, constraints)
+}
+
+// repeated checks that counts split into between min and max repetitions
+// of a group
+func (m *bagMatcher) repeated(expr TripleExpr, counts []int, constraints []*TripleConstraint, min, max int) bool {
+	key := fmt.Sprintf("%p %v %d %d", expr, counts, min, max)
+	if result, ok := m.memo[key]; ok {
+		return result
+	}
+	m.memo[key] = false
+
+	result := false
+	if isZero(counts) {
+		result = min <= 0 || m.once(expr, counts, constraints)
+	} else if max != 0 {
+		nextMin, nextMax := min-1, max-1
+		if max == Unbounded {
+			nextMax = Unbounded
+		}
+		sub := make([]int, len(counts))
+		rest := make([]int, len(counts))
+		var split func(i int) bool
+		split = func(i int) bool {
+			if i == len(counts) {
+				if isZero(sub) {
+					return false
+				}
+				return m.once(expr, sub, constraints) && m.repeated(expr, rest, constraints, nextMin, nextMax)
+			}
+			for n := counts[i]; n >= 0; n-- {
+				sub[i], rest[i] = n, counts[i]-n
+				if split(i + 1) {
+					return true
+				}
+			}
+			return false
+		}
+		result = split(0)
+	}
+	m.memo[key] = result
+	return result
+}
+
+// restrict zeroes the counts of constraints outside expr
+func restrict(counts []int, constraints []*TripleConstraint, expr TripleExpr) []int {
+	sub := make([]int, len(counts))
+	for _, tc := range tripleConstraints(expr) {
+		i := indexOf(constraints, tc)
+		sub[i] = counts[i]
+	}
+	return sub
+}
+
+func indexOf(constraints []*TripleConstraint, tc *TripleConstraint) int {
+	for i, c := range constraints {
+		if c == tc {
+			return i
+		}
+	}
+	return -1
+}
+
+func isZero(counts []int) bool {
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func equalCounts(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// labelTerm returns the term naming a shape label
+func labelTerm(label string) rdf.Term {
+	if id, ok := strings.CutPrefix(label, "_:"); ok {
+		return rdf.BlankNode(id)
+	}
+	return rdf.IRI(label)
+}
+
+// expressionType returns the ShEx vocabulary name of a shape expression's type
+func expressionType(expr ShapeExpr) string {
+	name := fmt.Sprintf("%T", expr)
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,572 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestShExValidationService(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService()
+	validator := service.NewStandardShExValidationService()
+
+	parse := func(t *testing.T, turtle string) *rdf.Graph {
+		t.Helper()
+		graph, err := rdfService.ParseGraph(`@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+@prefix ex: <https://example.com/ns#> .
+`+turtle, string(service.FormatTurtle))
+		require.NoError(t, err)
+		return graph
+	}
+
+	personSchema := `PREFIX foaf: <http://xmlns.com/foaf/0.1/>
+PREFIX xsd: <http://www.w3.org/2001/XMLSchema#>
+PREFIX ex: <https://example.com/ns#>
+
+# A person has one name, an optional age and knows other people
+ex:Person CLOSED EXTRA a {
+  a [foaf:Person] ? ;
+  foaf:name xsd:string ;
+  foaf:age xsd:integer MININCLUSIVE 0 MAXEXCLUSIVE 150 ? ;
+  foaf:knows @ex:Person * ;
+  foaf:mbox IRI /^mailto:/ *
+}`
+
+	validate := func(t *testing.T, schemaText, shapeMapText, turtle string) *service.ValidationReport {
+		t.Helper()
+		schema, err := service.ParseShExC(schemaText, "")
+		require.NoError(t, err)
+		shapeMap, err := service.ParseShapeMap(shapeMapText, schema)
+		require.NoError(t, err)
+		report, err := validator.Validate(parse(t, turtle), schema, shapeMap)
+		require.NoError(t, err)
+		return report
+	}
+
+	t.Run("conforming nodes produce an empty report", func(t *testing.T) {
+		// Act
+		report := validate(t, personSchema, `<https://example.com/alice>@ex:Person`, `
+<https://example.com/alice> a foaf:Person, ex:Admin ; foaf:name "Alice" ; foaf:age 30 ;
+    foaf:knows <https://example.com/bob> ; foaf:mbox <mailto:alice@example.com> .
+<https://example.com/bob> foaf:name "Bob" ; foaf:knows <https://example.com/alice> .`)
+
+		// Assert
+		assert.True(t, report.Conforms)
+		assert.Empty(t, report.Results)
+	})
+
+	t.Run("reports cardinality violations", func(t *testing.T) {
+		// Act
+		report := validate(t, personSchema, `<https://example.com/alice>@ex:Person`,
+			`<https://example.com/alice> foaf:name "Alice", "Alicia" .`)
+
+		// Assert
+		assert.False(t, report.Conforms)
+		require.Len(t, report.Results, 1)
+		result := report.Results[0]
+		assert.Equal(t, rdf.IRI("https://example.com/alice"), result.FocusNode)
+		assert.Equal(t, rdf.IRI("https://example.com/ns#Person"), result.SourceShape)
+		assert.Equal(t, rdf.IRI(service.ShExNamespace+"Shape"), result.Component)
+		assert.Contains(t, result.Messages[0].Lexical, "2 values for <http://xmlns.com/foaf/0.1/name>, expected 1")
+	})
+
+	t.Run("checks referenced shapes and node constraints", func(t *testing.T) {
+		// Act
+		report := validate(t, personSchema, `<https://example.com/alice>@ex:Person, <https://example.com/carol>@ex:Person`, `
+<https://example.com/alice> foaf:name "Alice" ; foaf:knows <https://example.com/bob> .
+<https://example.com/bob> foaf:age 20 .
+<https://example.com/carol> foaf:name "Carol" ; foaf:age -1 .`)
+
+		// Assert
+		require.Len(t, report.Results, 2)
+		assert.Contains(t, report.Results[0].Messages[0].Lexical, "https://example.com/bob")
+		assert.Contains(t, report.Results[1].Messages[0].Lexical, "foaf/0.1/age")
+	})
+
+	t.Run("rejects predicates outside a closed shape", func(t *testing.T) {
+		// Act
+		report := validate(t, personSchema, `<https://example.com/alice>@ex:Person`,
+			`<https://example.com/alice> foaf:name "Alice" ; ex:nickname "Al" .`)
+
+		// Assert
+		require.Len(t, report.Results, 1)
+		assert.Contains(t, report.Results[0].Messages[0].Lexical, "not allowed in a closed shape")
+	})
+
+	t.Run("matches one-of, repeated groups and value sets", func(t *testing.T) {
+		// Arrange
+		schema := `PREFIX ex: <https://example.com/ns#>
+start = @<#Contact>
+<#Contact> {
+  ( ex:email LITERAL | ex:phone LITERAL ) ;
+  ( ex:tag [ex:~ - ex:secret] ; ex:weight . ){0,2} ;
+  ex:lang [@en~ @fr]?
+}`
+
+		// Act
+		valid := validate(t, schema, `{FOCUS ex:email _}@START, {FOCUS ex:phone _}@START`, `
+ex:a ex:email "a@example.com" ; ex:tag ex:one, ex:two ; ex:weight 1, 2 ; ex:lang "hi"@en-GB .`)
+		invalid := validate(t, schema, `{FOCUS ex:email _}@START, {FOCUS ex:phone _}@START`, `
+ex:b ex:email "b@example.com" ; ex:phone "123" .
+ex:c ex:email "c@example.com" ; ex:tag ex:secret ; ex:weight 1 .
+ex:d ex:email "d@example.com" ; ex:tag ex:one ; ex:weight 1, 2 .`)
+
+		// Assert
+		assert.True(t, valid.Conforms)
+		require.Len(t, invalid.Results, 4)
+		nodes := []rdf.Term{}
+		for _, result := range invalid.Results {
+			nodes = append(nodes, result.FocusNode)
+		}
+		assert.ElementsMatch(t, []rdf.Term{
+			rdf.IRI("https://example.com/ns#b"), rdf.IRI("https://example.com/ns#b"),
+			rdf.IRI("https://example.com/ns#c"), rdf.IRI("https://example.com/ns#d"),
+		}, nodes)
+	})
+
+	t.Run("parses ShExJ schemas", func(t *testing.T) {
+		// Arrange
+		schema, err := service.ParseShExJ(`{
+  "@context": "http://www.w3.org/ns/shex.jsonld",
+  "type": "Schema",
+  "shapes": [{
+    "type": "ShapeDecl",
+    "id": "https://example.com/ns#Person",
+    "shapeExpr": {
+      "type": "Shape",
+      "expression": {
+        "type": "TripleConstraint",
+        "predicate": "http://xmlns.com/foaf/0.1/name",
+        "valueExpr": {"type": "NodeConstraint", "datatype": "http://www.w3.org/2001/XMLSchema#string", "maxlength": 5}
+      }
+    }
+  }]
+}`)
+		require.NoError(t, err)
+		shapeMap := service.ShapeMap{
+			{Node: rdf.IRI("https://example.com/alice"), Shape: "https://example.com/ns#Person"},
+			{Node: rdf.IRI("https://example.com/bob"), Shape: "https://example.com/ns#Person"},
+		}
+
+		// Act
+		report, err := validator.Validate(parse(t, `
+<https://example.com/alice> foaf:name "Alice" .
+<https://example.com/bob> foaf:name "Robert" .`), schema, shapeMap)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, report.Results, 1)
+		assert.Equal(t, rdf.IRI("https://example.com/bob"), report.Results[0].FocusNode)
+	})
+
+	t.Run("reports syntax errors with their position", func(t *testing.T) {
+		// Act
+		_, err := service.ParseShExC("PREFIX ex: <https://example.com/ns#>\nex:S { ex:p [ex:a", "")
+
+		// Assert
+		var validationErr *service.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, service.FormatShExC, validationErr.Format)
+		assert.Equal(t, 2, validationErr.Line)
+	})
+
+	// Cases adapted from the validation tests of the W3C ShEx test suite.
+	// Every node in pass and fail is checked against ex:S.
+	prefixes := "PREFIX ex: <https://example.com/ns#>\nPREFIX xsd: <http://www.w3.org/2001/XMLSchema#>\n"
+	constraintCases := []struct {
+		name   string
+		schema string
+		data   string
+		pass   []string
+		fail   []string
+	}{
+		{
+			name:   "1dot",
+			schema: `ex:S { ex:p . }`,
+			data:   `ex:a ex:p 1 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1literal",
+			schema: `ex:S { ex:p LITERAL }`,
+			data:   `ex:a ex:p "x" . ex:b ex:p ex:x .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1iri",
+			schema: `ex:S { ex:p IRI }`,
+			data:   `ex:a ex:p ex:x . ex:b ex:p "x" . ex:c ex:p [] .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1bnode",
+			schema: `ex:S { ex:p BNODE }`,
+			data:   `ex:a ex:p [] . ex:b ex:p ex:x .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1nonliteral",
+			schema: `ex:S { ex:p NONLITERAL }`,
+			data:   `ex:a ex:p ex:x . ex:b ex:p [] . ex:c ex:p "x" .`,
+			pass:   []string{"ex:a", "ex:b"},
+			fail:   []string{"ex:c"},
+		},
+		{
+			name:   "1datatype",
+			schema: `ex:S { ex:p xsd:integer }`,
+			data:   `ex:a ex:p 1 . ex:b ex:p "1" . ex:c ex:p "one"^^xsd:integer .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1literalLength",
+			schema: `ex:S { ex:p LITERAL LENGTH 3 }`,
+			data:   `ex:a ex:p "abc" . ex:b ex:p "ab" . ex:c ex:p "abcd" .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1literalMinlength",
+			schema: `ex:S { ex:p LITERAL MINLENGTH 3 }`,
+			data:   `ex:a ex:p "abcd" . ex:b ex:p "ab" .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1iriMaxlength",
+			schema: `ex:S { ex:p IRI MAXLENGTH 27 }`,
+			data:   `ex:a ex:p ex:x . ex:b ex:p ex:toolong . ex:c ex:p [] .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1literalPattern",
+			schema: `ex:S { ex:p LITERAL /^ab+$/ }`,
+			data:   `ex:a ex:p "abbb" . ex:b ex:p "ba" .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1literalPattern_i",
+			schema: `ex:S { ex:p /^AB/i }`,
+			data:   `ex:a ex:p "abc" . ex:b ex:p "cab" .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1literalMininclusive",
+			schema: `ex:S { ex:p MININCLUSIVE 5 }`,
+			data:   `ex:a ex:p 5 . ex:b ex:p 4.5 . ex:c ex:p "5" .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1literalMinexclusive",
+			schema: `ex:S { ex:p MINEXCLUSIVE 5 }`,
+			data:   `ex:a ex:p 5.5 . ex:b ex:p 5 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1literalMaxinclusive",
+			schema: `ex:S { ex:p MAXINCLUSIVE 5 }`,
+			data:   `ex:a ex:p 5.0 . ex:b ex:p 6 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1literalMaxexclusive",
+			schema: `ex:S { ex:p MAXEXCLUSIVE 5 }`,
+			data:   `ex:a ex:p 4 . ex:b ex:p 5 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1literalTotaldigits",
+			schema: `ex:S { ex:p TOTALDIGITS 3 }`,
+			data:   `ex:a ex:p 12.30 . ex:b ex:p 1234 . ex:c ex:p 1.5e0 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1literalFractiondigits",
+			schema: `ex:S { ex:p FRACTIONDIGITS 1 }`,
+			data:   `ex:a ex:p 1.50 . ex:b ex:p 1.25 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1val1IRIREF",
+			schema: `ex:S { ex:p [ex:o] }`,
+			data:   `ex:a ex:p ex:o . ex:b ex:p ex:other .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1val1IRIStemMinusIRI",
+			schema: `ex:S { ex:p [ex:~ - ex:secret] }`,
+			data:   `ex:a ex:p ex:open . ex:b ex:p ex:secret . ex:c ex:p <https://example.org/x> .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1val1literalStem",
+			schema: `ex:S { ex:p ["ab"~] }`,
+			data:   `ex:a ex:p "abc" . ex:b ex:p "ba" .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1val1language",
+			schema: `ex:S { ex:p [@fr] }`,
+			data:   `ex:a ex:p "oui"@fr . ex:b ex:p "yes"@en . ex:c ex:p "oui"@fr-BE .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1val1languageStem",
+			schema: `ex:S { ex:p [@fr~] }`,
+			data:   `ex:a ex:p "oui"@fr-BE . ex:b ex:p "oui"@fry . ex:c ex:p "oui" .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1card2",
+			schema: `ex:S { ex:p .{2} }`,
+			data:   `ex:a ex:p 1, 2 . ex:b ex:p 1 . ex:c ex:p 1, 2, 3 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b", "ex:c"},
+		},
+		{
+			name:   "1card25",
+			schema: `ex:S { ex:p .{2,5} }`,
+			data:   `ex:a ex:p 1, 2, 3, 4, 5 . ex:b ex:p 1, 2, 3, 4, 5, 6 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1cardOpt",
+			schema: `ex:S { ex:p . ? }`,
+			data:   `ex:a ex:p 1 . ex:c ex:p 1, 2 .`,
+			pass:   []string{"ex:a", "ex:b"},
+			fail:   []string{"ex:c"},
+		},
+		{
+			name:   "1cardPlus",
+			schema: `ex:S { ex:p . + }`,
+			data:   `ex:a ex:p 1, 2, 3 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1cardStar",
+			schema: `ex:S { ex:p LITERAL * }`,
+			data:   `ex:a ex:p 1, 2 . ex:c ex:p ex:x .`,
+			pass:   []string{"ex:a", "ex:b"},
+			fail:   []string{"ex:c"},
+		},
+		{
+			name:   "2Eachdot",
+			schema: `ex:S { ex:p . ; ex:q . }`,
+			data:   `ex:a ex:p 1 ; ex:q 1 . ex:b ex:p 1 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "2OneOfdot",
+			schema: `ex:S { ex:p . | ex:q . }`,
+			data:   `ex:a ex:p 1 . ex:b ex:q 1 . ex:c ex:p 1 ; ex:q 1 .`,
+			pass:   []string{"ex:a", "ex:b"},
+			fail:   []string{"ex:c", "ex:d"},
+		},
+		{
+			name:   "1inversedot",
+			schema: `ex:S { ^ex:p . }`,
+			data:   `ex:x ex:p ex:a . ex:b ex:p ex:x .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1dotRef1",
+			schema: `ex:S { ex:p @ex:T } ex:T { ex:q . }`,
+			data:   `ex:a ex:p ex:x . ex:x ex:q 1 . ex:b ex:p ex:y .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "3circRefS1",
+			schema: `ex:S { ex:p @ex:S ? ; ex:q LITERAL }`,
+			data:   `ex:a ex:p ex:b ; ex:q "a" . ex:b ex:p ex:a ; ex:q "b" . ex:c ex:p ex:d ; ex:q "c" . ex:d ex:q ex:x .`,
+			pass:   []string{"ex:a", "ex:b"},
+			fail:   []string{"ex:c"},
+		},
+		{
+			name:   "1dotClosed",
+			schema: `ex:S CLOSED { ex:p . }`,
+			data:   `ex:a ex:p 1 . ex:b ex:p 1 ; ex:q 1 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1dotExtra1",
+			schema: `ex:S EXTRA ex:p { ex:p [1] }`,
+			data:   `ex:a ex:p 1, 2 . ex:b ex:p 2 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1dotNoExtra",
+			schema: `ex:S { ex:p [1] }`,
+			data:   `ex:a ex:p 1 . ex:b ex:p 1, 2 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1NOTRefdot",
+			schema: `ex:S NOT @ex:T ex:T { ex:p . }`,
+			data:   `ex:a ex:q 1 . ex:b ex:p 1 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1dotAND1dot",
+			schema: `ex:S { ex:p . } AND { ex:q . }`,
+			data:   `ex:a ex:p 1 ; ex:q 1 . ex:b ex:p 1 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+		{
+			name:   "1dotOR1dot",
+			schema: `ex:S { ex:p . } OR { ex:q . }`,
+			data:   `ex:a ex:p 1 . ex:b ex:q 1 .`,
+			pass:   []string{"ex:a", "ex:b"},
+			fail:   []string{"ex:c"},
+		},
+		{
+			name:   "1focusIRI_dot",
+			schema: `ex:S IRI MAXLENGTH 26 AND { ex:p . }`,
+			data:   `ex:a ex:p 1 . ex:long ex:p 1 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:long"},
+		},
+		{
+			name:   "1include1",
+			schema: `ex:S { &ex:e ; ex:q . } ex:T { $ex:e ex:p . }`,
+			data:   `ex:a ex:p 1 ; ex:q 1 . ex:b ex:q 1 .`,
+			pass:   []string{"ex:a"},
+			fail:   []string{"ex:b"},
+		},
+	}
+
+	t.Run("checks each kind of constraint", func(t *testing.T) {
+		for _, tc := range constraintCases {
+			t.Run(tc.name, func(t *testing.T) {
+				// Arrange
+				associations := make([]string, 0, len(tc.pass)+len(tc.fail))
+				for _, node := range append(append([]string{}, tc.pass...), tc.fail...) {
+					associations = append(associations, node+"@ex:S")
+				}
+
+				// Act
+				report := validate(t, prefixes+tc.schema, strings.Join(associations, ", "), tc.data)
+
+				// Assert
+				failed := make([]string, 0, len(report.Results))
+				for _, result := range report.Results {
+					failed = append(failed, strings.Replace(result.FocusNode.Value(), "https://example.com/ns#", "ex:", 1))
+				}
+				assert.ElementsMatch(t, tc.fail, failed)
+			})
+		}
+	})
+
+	t.Run("rejects malformed and ill-structured ShExC schemas", func(t *testing.T) {
+		// Cases adapted from the negativeSyntax and negativeStructure tests
+		// of the W3C ShEx test suite
+		for _, tc := range []struct {
+			name   string
+			schema string
+		}{
+			{"1dotUnterminated", `ex:S { ex:p .`},
+			{"1unknownPrefix", `ex:S { foo:p . }`},
+			{"1unterminatedIRI", `ex:S { <https://example.com/p . }`},
+			{"1unterminatedString", `ex:S { ex:p ["abc] }`},
+			{"1unterminatedPattern", `ex:S { ex:p /abc }`},
+			{"1valsUnterminated", `ex:S { ex:p [ex:a ex:b }`},
+			{"1bareAtSign", `ex:S { ex:p @ }`},
+			{"1inverseNoPredicate", `ex:S { ^ . }`},
+			{"1cardMaxLessThanMin", `ex:S { ex:p .{3,2} }`},
+			{"1cardNotNumeric", `ex:S { ex:p .{a} }`},
+			{"1lengthNegative", `ex:S { ex:p LITERAL LENGTH -1 }`},
+			{"1mininclusiveString", `ex:S { ex:p MININCLUSIVE "five" }`},
+			{"1badEscape", `ex:S { ex:p ["\q"] }`},
+			{"1patternInvalid", `ex:S { ex:p /([a-z/ }`},
+			{"1shapeWithoutLabel", `{ ex:p . }`},
+			{"IMPORT", `IMPORT <https://example.com/other.shex> ex:S { ex:p . }`},
+			{"1MissingRef", `ex:S { ex:p @ex:T }`},
+			{"1focusMissingRefdot", `ex:S IRI AND @ex:T`},
+			{"1MissingStart", `start = @ex:T ex:S { ex:p . }`},
+			{"MissingInclusion", `ex:S { &ex:e }`},
+			{"includeCycle", `ex:S { $ex:e ( ex:p . ; &ex:e ) }`},
+			{"Cycle1Negation1", `ex:S NOT @ex:S`},
+			{"Cycle1Negation3", `ex:S { ex:p @ex:T } ex:T NOT { ex:q @ex:S }`},
+			{"TwoNegation", `ex:S NOT @ex:T ex:T NOT @ex:S`},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				// Act
+				_, err := service.ParseShExC(prefixes+tc.schema, "")
+
+				// Assert
+				var validationErr *service.ValidationError
+				require.ErrorAs(t, err, &validationErr)
+				assert.Equal(t, service.FormatShExC, validationErr.Format)
+			})
+		}
+	})
+
+	t.Run("accepts recursion without negation", func(t *testing.T) {
+		// Act
+		_, err := service.ParseShExC(prefixes+`ex:S { ex:p @ex:T } ex:T NOT { ex:q . } AND { ex:r @ex:S }`, "")
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects malformed and ill-structured ShExJ schemas", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			schema string
+		}{
+			{"invalid JSON", `{"type": "Schema", "shapes": [`},
+			{"not a schema", `{"type": "Shape"}`},
+			{"shape without an id", `{"type": "Schema", "shapes": [{"type": "Shape"}]}`},
+			{"unknown shape expression", `{"type": "Schema", "shapes": [{"id": "https://example.com/ns#S", "type": "ShapeXor"}]}`},
+			{"shape expression is a number", `{"type": "Schema", "shapes": [{"type": "ShapeDecl", "id": "https://example.com/ns#S", "shapeExpr": 5}]}`},
+			{"triple constraint without a predicate", `{"type": "Schema", "shapes": [{"id": "https://example.com/ns#S", "type": "Shape", "expression": {"type": "TripleConstraint"}}]}`},
+			{"max below min", `{"type": "Schema", "shapes": [{"id": "https://example.com/ns#S", "type": "Shape", "expression": {"type": "TripleConstraint", "predicate": "https://example.com/ns#p", "min": 2, "max": 1}}]}`},
+			{"1MissingRef", `{"type": "Schema", "shapes": [{"id": "https://example.com/ns#S", "type": "Shape", "expression": {"type": "TripleConstraint", "predicate": "https://example.com/ns#p", "valueExpr": "https://example.com/ns#T"}}]}`},
+			{"MissingInclusion", `{"type": "Schema", "shapes": [{"id": "https://example.com/ns#S", "type": "Shape", "expression": "https://example.com/ns#e"}]}`},
+			{"includeCycle", `{"type": "Schema", "shapes": [{"id": "https://example.com/ns#S", "type": "Shape", "expression": {"id": "https://example.com/ns#e", "type": "EachOf", "expressions": [{"type": "TripleConstraint", "predicate": "https://example.com/ns#p"}, "https://example.com/ns#e"]}}]}`},
+			{"Cycle1Negation1", `{"type": "Schema", "shapes": [{"id": "https://example.com/ns#S", "type": "ShapeNot", "shapeExpr": "https://example.com/ns#S"}]}`},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				// Act
+				_, err := service.ParseShExJ(tc.schema)
+
+				// Assert
+				var validationErr *service.ValidationError
+				require.ErrorAs(t, err, &validationErr)
+				assert.Equal(t, service.FormatShExJ, validationErr.Format)
+			})
+		}
+	})
+}
@@ -0,0 +1,1239 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// ParseShExC parses a schema in the ShEx compact syntax. Relative IRIs are
+// resolved against base. Annotations and semantic actions are ignored.
+func ParseShExC(text string, base string) (*ShExSchema, error) {
+	tokens, err := lexShExC(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &shexcParser{
+		tokens: tokens,
+		schema: NewShExSchema(),
+		named:  make(map[string]TripleExpr),
+	}
+	p.schema.Base = base
+	if err := p.parseSchema(); err != nil {
+		return nil, err
+	}
+	if err := p.resolveInclusions(); err != nil {
+		return nil, err
+	}
+	if err := p.schema.checkStructure(); err != nil {
+		return nil, NewValidationError(FormatShExC, err.Error(), nil)
+	}
+	return p.schema, nil
+}
+
+// shexcTokenKind identifies a ShExC token
+type shexcTokenKind int
+
+const (
+	tokEOF shexcTokenKind = iota
+	tokIRI
+	tokPName
+	tokBlankNode
+	tokShapeRef // @<iri>, @prefix:local or @_:label
+	tokLangTag
+	tokString
+	tokInteger
+	tokDecimal
+	tokDouble
+	tokRegexp
+	tokRepeat
+	tokKeyword
+	tokPunct
+)
+
+// shexcToken is a lexical token with its position
+type shexcToken struct {
+	kind   shexcTokenKind
+	value  string
+	flags  string // regular expression flags
+	ref    shexcTokenKind
+	line   int
+	column int
+}
+
+// shexcLexer splits ShExC text into tokens
+type shexcLexer struct {
+	input  string
+	pos    int
+	line   int
+	column int
+}
+
+// lexShExC tokenizes a ShExC document, dropping comments and semantic actions
+func lexShExC(text string) ([]shexcToken, error) {
+	l := &shexcLexer{input: text, line: 1, column: 1}
+	var tokens []shexcToken
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.kind == tokEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *shexcLexer) errorf(format string, args ...interface{}) error {
+	return NewValidationErrorWithPosition(FormatShExC, l.line, l.column, fmt.Sprintf(format, args...), nil)
+}
+
+func (l *shexcLexer) peek(offset int) byte {
+	if l.pos+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos+offset]
+}
+
+func (l *shexcLexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.input); i++ {
+		if l.input[l.pos] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.pos++
+	}
+}
+
+// skipSpace skips whitespace and comments
+func (l *shexcLexer) skipSpace() {
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			l.advance(1)
+		case c == '#':
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+				l.advance(1)
+			}
+		case c == '/' && l.peek(1) == '*':
+			end := strings.Index(l.input[l.pos+2:], "*/")
+			if end < 0 {
+				l.advance(len(l.input) - l.pos)
+				return
+			}
+			l.advance(end + 4)
+		default:
+			return
+		}
+	}
+}
+
+func (l *shexcLexer) next() (shexcToken, error) {
+	l.skipSpace()
+	tok := shexcToken{line: l.line, column: l.column}
+	if l.pos >= len(l.input) {
+		tok.kind = tokEOF
+		return tok, nil
+	}
+
+	c := l.input[l.pos]
+	switch {
+	case c == '<':
+		iri, err := l.readIRI()
+		tok.kind, tok.value = tokIRI, iri
+		return tok, err
+	case c == '"' || c == '\'':
+		s, err := l.readString()
+		tok.kind, tok.value = tokString, s
+		return tok, err
+	case c == '@':
+		return l.readAt(tok)
+	case c == '%':
+		if err := l.skipSemanticAction(); err != nil {
+			return tok, err
+		}
+		return l.next()
+	case c == '/' && l.peek(1) == '/':
+		l.advance(2)
+		tok.kind, tok.value = tokPunct, "//"
+		return tok, nil
+	case c == '/':
+		pattern, flags, err := l.readRegexp()
+		tok.kind, tok.value, tok.flags = tokRegexp, pattern, flags
+		return tok, err
+	case c == '^' && l.peek(1) == '^':
+		l.advance(2)
+		tok.kind, tok.value = tokPunct, "^^"
+		return tok, nil
+	case c == '{' && l.isRepeat():
+		end := strings.IndexByte(l.input[l.pos:], '}')
+		tok.kind, tok.value = tokRepeat, strings.ReplaceAll(l.input[l.pos+1:l.pos+end], " ", "")
+		l.advance(end + 1)
+		return tok, nil
+	case c == '_' && l.peek(1) == ':':
+		l.advance(2)
+		tok.kind, tok.value = tokBlankNode, "_:"+l.readName()
+		return tok, nil
+	case isDigit(c) || ((c == '+' || c == '-' || c == '.') && (isDigit(l.peek(1)) || (l.peek(1) == '.' && isDigit(l.peek(2))))):
+		return l.readNumber(tok), nil
+	case strings.IndexByte("{}()[],;|=*+?^.~-&$!", c) >= 0:
+		l.advance(1)
+		tok.kind, tok.value = tokPunct, string(c)
+		return tok, nil
+	case c == ':' || isNameStart(rune(c)) || c >= 0x80:
+		name := l.readName()
+		if l.peek(0) == ':' {
+			l.advance(1)
+			tok.kind, tok.value = tokPName, name+":"+l.readName()
+			return tok, nil
+		}
+		tok.kind, tok.value = tokKeyword, name
+		return tok, nil
+	}
+	return tok, l.errorf("unexpected character %q", c)
+}
+
+// isRepeat reports whether a '{' starts a repeat range such as {2,5}
+func (l *shexcLexer) isRepeat() bool {
+	i := l.pos + 1
+	for i < len(l.input) && (l.input[i] == ' ' || l.input[i] == '\t') {
+		i++
+	}
+	return i < len(l.input) && isDigit(l.input[i])
+}
+
+func (l *shexcLexer) readIRI() (string, error) {
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == '>':
+			l.advance(1)
+			return b.String(), nil
+		case c == '\\':
+			r, err := l.readEscape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		case c == '\n' || c == ' ':
+			return "", l.errorf("unterminated IRI")
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+	return "", l.errorf("unterminated IRI")
+}
+
+func (l *shexcLexer) readString() (string, error) {
+	quote := l.input[l.pos]
+	long := l.peek(1) == quote && l.peek(2) == quote
+	if long {
+		l.advance(3)
+	} else {
+		l.advance(1)
+	}
+
+	var b strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == quote && !long:
+			l.advance(1)
+			return b.String(), nil
+		case c == quote && long && l.peek(1) == quote && l.peek(2) == quote:
+			l.advance(3)
+			return b.String(), nil
+		case c == '\\':
+			r, err := l.readEscape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		case c == '\n' && !long:
+			return "", l.errorf("unterminated string")
+		default:
+			r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+			b.WriteRune(r)
+			l.advance(size)
+		}
+	}
+	return "", l.errorf("unterminated string")
+}
+
+// readEscape reads a backslash escape sequence
+func (l *shexcLexer) readEscape() (rune, error) {
+	c := l.peek(1)
+	simple := map[byte]rune{'t': '\t', 'n': '\n', 'r': '\r', 'b': '\b', 'f': '\f', '"': '"', '\'': '\'', '\\': '\\'}
+	if r, ok := simple[c]; ok {
+		l.advance(2)
+		return r, nil
+	}
+	size := map[byte]int{'u': 4, 'U': 8}[c]
+	if size == 0 || l.pos+2+size > len(l.input) {
+		return 0, l.errorf("invalid escape sequence")
+	}
+	code, err := strconv.ParseUint(l.input[l.pos+2:l.pos+2+size], 16, 32)
+	if err != nil {
+		return 0, l.errorf("invalid escape sequence")
+	}
+	l.advance(2 + size)
+	return rune(code), nil
+}
+
+// readAt reads a shape reference or a language tag
+func (l *shexcLexer) readAt(tok shexcToken) (shexcToken, error) {
+	l.advance(1)
+	c := l.peek(0)
+	switch {
+	case c == '<':
+		iri, err := l.readIRI()
+		tok.kind, tok.value, tok.ref = tokShapeRef, iri, tokIRI
+		return tok, err
+	case c == '_' && l.peek(1) == ':':
+		l.advance(2)
+		tok.kind, tok.value, tok.ref = tokShapeRef, "_:"+l.readName(), tokBlankNode
+		return tok, nil
+	case c == '~':
+		tok.kind, tok.value = tokPunct, "@"
+		return tok, nil
+	}
+
+	name := l.readName()
+	if l.peek(0) == ':' {
+		l.advance(1)
+		tok.kind, tok.value, tok.ref = tokShapeRef, name+":"+l.readName(), tokPName
+		return tok, nil
+	}
+	if name == "" {
+		return tok, l.errorf("expected a shape reference or language tag after @")
+	}
+	tok.kind, tok.value = tokLangTag, name
+	return tok, nil
+}
+
+// readName reads the name characters of a prefix, local name or keyword
+func (l *shexcLexer) readName() string {
+	start := l.pos
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if r == '\\' && l.pos+1 < len(l.input) {
+			l.advance(2)
+			continue
+		}
+		if !(isNameStart(r) || unicode.IsDigit(r) || r == '-' || r == '%' ||
+			(r == '.' && l.pos+size < len(l.input) && isNameChar(l.input[l.pos+size]))) {
+			break
+		}
+		l.advance(size)
+	}
+	name := l.input[start:l.pos]
+	return strings.NewReplacer(`\`, "").Replace(name)
+}
+
+func (l *shexcLexer) readNumber(tok shexcToken) shexcToken {
+	start := l.pos
+	if c := l.peek(0); c == '+' || c == '-' {
+		l.advance(1)
+	}
+	tok.kind = tokInteger
+	for isDigit(l.peek(0)) {
+		l.advance(1)
+	}
+	if l.peek(0) == '.' && isDigit(l.peek(1)) {
+		tok.kind = tokDecimal
+		l.advance(1)
+		for isDigit(l.peek(0)) {
+			l.advance(1)
+		}
+	}
+	if c := l.peek(0); c == 'e' || c == 'E' {
+		tok.kind = tokDouble
+		l.advance(1)
+		if c := l.peek(0); c == '+' || c == '-' {
+			l.advance(1)
+		}
+		for isDigit(l.peek(0)) {
+			l.advance(1)
+		}
+	}
+	tok.value = l.input[start:l.pos]
+	return tok
+}
+
+func (l *shexcLexer) readRegexp() (string, string, error) {
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == '/':
+			l.advance(1)
+			start := l.pos
+			for strings.IndexByte("smix", l.peek(0)) >= 0 && l.peek(0) != 0 {
+				l.advance(1)
+			}
+			return b.String(), l.input[start:l.pos], nil
+		case c == '\\' && l.peek(1) == '/':
+			b.WriteByte('/')
+			l.advance(2)
+		case c == '\\':
+			b.WriteString(l.input[l.pos : l.pos+2])
+			l.advance(2)
+		case c == '\n':
+			return "", "", l.errorf("unterminated regular expression")
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+	return "", "", l.errorf("unterminated regular expression")
+}
+
+// skipSemanticAction skips %name{ code %} and %name%
+func (l *shexcLexer) skipSemanticAction() error {
+	l.advance(1)
+	if l.peek(0) == '<' {
+		if _, err := l.readIRI(); err != nil {
+			return err
+		}
+	} else {
+		l.readName()
+		if l.peek(0) == ':' {
+			l.advance(1)
+			l.readName()
+		}
+	}
+	l.skipSpace()
+	switch l.peek(0) {
+	case '%':
+		l.advance(1)
+		return nil
+	case '{':
+		end := strings.Index(l.input[l.pos:], "%}")
+		if end < 0 {
+			return l.errorf("unterminated semantic action")
+		}
+		l.advance(end + 2)
+		return nil
+	}
+	return l.errorf("invalid semantic action")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameChar(c byte) bool {
+	return isDigit(c) || c == '_' || c == '-' || c == ':' || c >= 0x80 || unicode.IsLetter(rune(c))
+}
+
+// shexcParser is a recursive descent parser over ShExC tokens
+type shexcParser struct {
+	tokens []shexcToken
+	pos    int
+	schema *ShExSchema
+	named  map[string]TripleExpr
+
+	inclusions []*tripleExprInclusion
+}
+
+// tripleExprInclusion is a placeholder for &label until all labels are known
+type tripleExprInclusion struct {
+	label  string
+	target TripleExpr
+}
+
+func (e *tripleExprInclusion) cardinality() (int, int) {
+	if e.target == nil {
+		return 1, 1
+	}
+	return e.target.cardinality()
+}
+
+func (p *shexcParser) peek() shexcToken {
+	return p.tokens[p.pos]
+}
+
+func (p *shexcParser) next() shexcToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *shexcParser) errorf(tok shexcToken, format string, args ...interface{}) error {
+	return NewValidationErrorWithPosition(FormatShExC, tok.line, tok.column, fmt.Sprintf(format, args...), nil)
+}
+
+// isPunct reports whether the next token is the punctuation s
+func (p *shexcParser) isPunct(s string) bool {
+	tok := p.peek()
+	return tok.kind == tokPunct && tok.value == s
+}
+
+// isKeyword reports whether the next token is one of the case-insensitive keywords
+func (p *shexcParser) isKeyword(keywords ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokKeyword {
+		return false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(tok.value, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *shexcParser) expectPunct(s string) error {
+	if !p.isPunct(s) {
+		return p.errorf(p.peek(), "expected %q, found %q", s, p.peek().value)
+	}
+	p.next()
+	return nil
+}
+
+func (p *shexcParser) parseSchema() error {
+	for p.peek().kind != tokEOF {
+		switch {
+		case p.isKeyword("PREFIX"):
+			p.next()
+			prefix := p.next()
+			if prefix.kind != tokPName || !strings.HasSuffix(prefix.value, ":") {
+				return p.errorf(prefix, "expected a prefix declaration")
+			}
+			iri := p.next()
+			if iri.kind != tokIRI {
+				return p.errorf(iri, "expected an IRI for prefix %s", prefix.value)
+			}
+			p.schema.Prefixes[strings.TrimSuffix(prefix.value, ":")] = p.resolve(iri.value)
+		case p.isKeyword("BASE"):
+			p.next()
+			iri := p.next()
+			if iri.kind != tokIRI {
+				return p.errorf(iri, "expected a base IRI")
+			}
+			p.schema.Base = p.resolve(iri.value)
+		case p.isKeyword("IMPORT"):
+			return p.errorf(p.peek(), "IMPORT is not supported")
+		case p.isKeyword("start"):
+			p.next()
+			if err := p.expectPunct("="); err != nil {
+				return err
+			}
+			expr, err := p.parseShapeExpression()
+			if err != nil {
+				return err
+			}
+			p.schema.Start = expr
+		default:
+			if err := p.parseShapeDeclaration(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *shexcParser) parseShapeDeclaration() error {
+	if p.isKeyword("ABSTRACT") {
+		p.next()
+	}
+	label, err := p.parseLabel()
+	if err != nil {
+		return err
+	}
+	if p.isKeyword("EXTERNAL") {
+		p.next()
+		p.schema.Shapes[label] = &ShapeExternal{}
+		return nil
+	}
+	expr, err := p.parseShapeExpression()
+	if err != nil {
+		return err
+	}
+	p.schema.Shapes[label] = expr
+	return nil
+}
+
+// parseLabel reads a shape or triple expression label
+func (p *shexcParser) parseLabel() (string, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokIRI:
+		return p.resolve(tok.value), nil
+	case tokPName:
+		return p.expand(tok)
+	case tokBlankNode:
+		return tok.value, nil
+	}
+	return "", p.errorf(tok, "expected a shape label, found %q", tok.value)
+}
+
+func (p *shexcParser) parseShapeExpression() (ShapeExpr, error) {
+	first, err := p.parseShapeAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []ShapeExpr{first}
+	for p.isKeyword("OR") {
+		p.next()
+		expr, err := p.parseShapeAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return &ShapeOr{Exprs: exprs}, nil
+}
+
+func (p *shexcParser) parseShapeAnd() (ShapeExpr, error) {
+	first, err := p.parseShapeNot()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []ShapeExpr{first}
+	for p.isKeyword("AND") {
+		p.next()
+		expr, err := p.parseShapeNot()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return &ShapeAnd{Exprs: exprs}, nil
+}
+
+func (p *shexcParser) parseShapeNot() (ShapeExpr, error) {
+	if p.isKeyword("NOT") || p.isPunct("!") {
+		p.next()
+		expr, err := p.parseShapeAtom()
+		if err != nil {
+			return nil, err
+		}
+		return &ShapeNot{Expr: expr}, nil
+	}
+	return p.parseShapeAtom()
+}
+
+func (p *shexcParser) parseShapeAtom() (ShapeExpr, error) {
+	tok := p.peek()
+	switch {
+	case p.isPunct("("):
+		p.next()
+		expr, err := p.parseShapeExpression()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expectPunct(")")
+	case p.isPunct("."):
+		p.next()
+		return &NodeConstraint{}, nil
+	case tok.kind == tokShapeRef || p.isShapeDefinitionStart():
+		shape, err := p.parseShapeOrRef()
+		if err != nil {
+			return nil, err
+		}
+		if p.isNonLiteralConstraintStart() {
+			constraint, err := p.parseNonLiteralConstraint()
+			if err != nil {
+				return nil, err
+			}
+			return &ShapeAnd{Exprs: []ShapeExpr{shape, constraint}}, nil
+		}
+		return shape, nil
+	case p.isNonLiteralConstraintStart():
+		constraint, err := p.parseNonLiteralConstraint()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind == tokShapeRef || p.isShapeDefinitionStart() {
+			shape, err := p.parseShapeOrRef()
+			if err != nil {
+				return nil, err
+			}
+			return &ShapeAnd{Exprs: []ShapeExpr{constraint, shape}}, nil
+		}
+		return constraint, nil
+	}
+	return p.parseLiteralConstraint()
+}
+
+func (p *shexcParser) isShapeDefinitionStart() bool {
+	return p.isPunct("{") || p.isKeyword("CLOSED", "EXTRA")
+}
+
+func (p *shexcParser) isNonLiteralConstraintStart() bool {
+	return p.isKeyword("IRI", "BNODE", "NONLITERAL") || p.isStringFacetStart()
+}
+
+func (p *shexcParser) isStringFacetStart() bool {
+	return p.isKeyword("LENGTH", "MINLENGTH", "MAXLENGTH") || p.peek().kind == tokRegexp
+}
+
+func (p *shexcParser) isNumericFacetStart() bool {
+	return p.isKeyword("MININCLUSIVE", "MINEXCLUSIVE", "MAXINCLUSIVE", "MAXEXCLUSIVE", "TOTALDIGITS", "FRACTIONDIGITS")
+}
+
+func (p *shexcParser) parseShapeOrRef() (ShapeExpr, error) {
+	tok := p.peek()
+	if tok.kind != tokShapeRef {
+		return p.parseShapeDefinition()
+	}
+	p.next()
+	switch tok.ref {
+	case tokIRI:
+		return &ShapeRef{Label: p.resolve(tok.value)}, nil
+	case tokPName:
+		label, err := p.expand(tok)
+		return &ShapeRef{Label: label}, err
+	}
+	return &ShapeRef{Label: tok.value}, nil
+}
+
+func (p *shexcParser) parseShapeDefinition() (ShapeExpr, error) {
+	shape := &Shape{}
+	for p.isKeyword("CLOSED", "EXTRA") {
+		if p.isKeyword("CLOSED") {
+			p.next()
+			shape.Closed = true
+			continue
+		}
+		p.next()
+		for p.isPredicateStart() {
+			predicate, err := p.parsePredicate()
+			if err != nil {
+				return nil, err
+			}
+			shape.Extra = append(shape.Extra, predicate)
+		}
+	}
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	if !p.isPunct("}") {
+		expr, err := p.parseTripleExpression()
+		if err != nil {
+			return nil, err
+		}
+		shape.Expression = expr
+	}
+	if err := p.expectPunct("}"); err != nil {
+		return nil, err
+	}
+	return shape, p.skipAnnotations()
+}
+
+func (p *shexcParser) parseNonLiteralConstraint() (ShapeExpr, error) {
+	constraint := &NodeConstraint{}
+	if p.isKeyword("IRI", "BNODE", "NONLITERAL") {
+		constraint.NodeKind = strings.ToLower(p.next().value)
+	}
+	for p.isStringFacetStart() {
+		if err := p.parseFacet(constraint); err != nil {
+			return nil, err
+		}
+	}
+	return constraint, nil
+}
+
+func (p *shexcParser) parseLiteralConstraint() (ShapeExpr, error) {
+	constraint := &NodeConstraint{}
+	tok := p.peek()
+	switch {
+	case p.isKeyword("LITERAL"):
+		p.next()
+		constraint.NodeKind = "literal"
+	case tok.kind == tokIRI || tok.kind == tokPName:
+		datatype, err := p.parseIRI()
+		if err != nil {
+			return nil, err
+		}
+		constraint.Datatype = datatype
+	case p.isPunct("["):
+		values, err := p.parseValueSet()
+		if err != nil {
+			return nil, err
+		}
+		constraint.Values = values
+	case p.isNumericFacetStart():
+	default:
+		return nil, p.errorf(tok, "expected a shape expression, found %q", tok.value)
+	}
+	for p.isStringFacetStart() || p.isNumericFacetStart() {
+		if err := p.parseFacet(constraint); err != nil {
+			return nil, err
+		}
+	}
+	return constraint, nil
+}
+
+func (p *shexcParser) parseFacet(constraint *NodeConstraint) error {
+	tok := p.next()
+	if tok.kind == tokRegexp {
+		constraint.Pattern = tok.value
+		constraint.Flags = tok.flags
+		return nil
+	}
+
+	keyword := strings.ToUpper(tok.value)
+	switch keyword {
+	case "LENGTH", "MINLENGTH", "MAXLENGTH", "TOTALDIGITS", "FRACTIONDIGITS":
+		n := p.next()
+		value, err := strconv.Atoi(n.value)
+		if n.kind != tokInteger || err != nil || value < 0 {
+			return p.errorf(n, "%s requires a non-negative integer", keyword)
+		}
+		target := map[string]**int{
+			"LENGTH": &constraint.Length, "MINLENGTH": &constraint.MinLength, "MAXLENGTH": &constraint.MaxLength,
+			"TOTALDIGITS": &constraint.TotalDigits, "FRACTIONDIGITS": &constraint.FractionDigits,
+		}[keyword]
+		*target = &value
+		return nil
+	}
+
+	literal, err := p.parseLiteral()
+	if err != nil {
+		return err
+	}
+	if !isNumeric(literal.EffectiveDatatype()) {
+		return p.errorf(tok, "%s requires a numeric literal", keyword)
+	}
+	target := map[string]**rdf.Literal{
+		"MININCLUSIVE": &constraint.MinInclusive, "MINEXCLUSIVE": &constraint.MinExclusive,
+		"MAXINCLUSIVE": &constraint.MaxInclusive, "MAXEXCLUSIVE": &constraint.MaxExclusive,
+	}[keyword]
+	*target = &literal
+	return nil
+}
+
+func (p *shexcParser) parseValueSet() ([]ValueSetValue, error) {
+	p.next()
+	var values []ValueSetValue
+	for !p.isPunct("]") {
+		value, err := p.parseValueSetValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	p.next()
+	return values, nil
+}
+
+func (p *shexcParser) parseValueSetValue() (ValueSetValue, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == tokIRI || tok.kind == tokPName:
+		iri, err := p.parseIRI()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isPunct("~") {
+			return ObjectValue{Term: iri}, nil
+		}
+		p.next()
+		exclusions, err := p.parseExclusions(tokIRI)
+		return IRIStem{Stem: string(iri), Exclusions: exclusions}, err
+	case tok.kind == tokLangTag:
+		p.next()
+		if !p.isPunct("~") {
+			return LanguageStem{Stem: tok.value, Exact: true}, nil
+		}
+		p.next()
+		exclusions, err := p.parseExclusions(tokLangTag)
+		return LanguageStem{Stem: tok.value, Exclusions: exclusions}, err
+	case p.isPunct("@"):
+		p.next()
+		if err := p.expectPunct("~"); err != nil {
+			return nil, err
+		}
+		exclusions, err := p.parseExclusions(tokLangTag)
+		return LanguageStem{Exclusions: exclusions}, err
+	case p.isPunct("."):
+		p.next()
+		kind := tokIRI
+		if next := p.tokens[p.pos+1]; p.isPunct("-") {
+			switch {
+			case next.kind == tokLangTag:
+				kind = tokLangTag
+			case next.kind != tokIRI && next.kind != tokPName:
+				kind = tokString
+			}
+		}
+		exclusions, err := p.parseExclusions(kind)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case tokLangTag:
+			return LanguageStem{Exclusions: exclusions}, nil
+		case tokString:
+			return LiteralStem{Exclusions: exclusions}, nil
+		}
+		return IRIStem{Exclusions: exclusions}, nil
+	}
+
+	literal, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isPunct("~") {
+		return ObjectValue{Term: literal}, nil
+	}
+	p.next()
+	exclusions, err := p.parseExclusions(tokString)
+	return LiteralStem{Stem: literal.Lexical, Exclusions: exclusions}, err
+}
+
+// parseExclusions reads "- value" and "- value~" exclusions of the given kind
+func (p *shexcParser) parseExclusions(kind shexcTokenKind) ([]Exclusion, error) {
+	var exclusions []Exclusion
+	for p.isPunct("-") {
+		p.next()
+		var value string
+		switch kind {
+		case tokIRI:
+			iri, err := p.parseIRI()
+			if err != nil {
+				return nil, err
+			}
+			value = string(iri)
+		case tokLangTag:
+			tok := p.next()
+			if tok.kind != tokLangTag {
+				return nil, p.errorf(tok, "expected a language tag exclusion")
+			}
+			value = tok.value
+		default:
+			literal, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			value = literal.Lexical
+		}
+		isStem := p.isPunct("~")
+		if isStem {
+			p.next()
+		}
+		exclusions = append(exclusions, Exclusion{Value: value, IsStem: isStem})
+	}
+	return exclusions, nil
+}
+
+func (p *shexcParser) parseTripleExpression() (TripleExpr, error) {
+	first, err := p.parseGroup()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []TripleExpr{first}
+	for p.isPunct("|") {
+		p.next()
+		expr, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return &OneOf{Exprs: exprs, Min: 1, Max: 1}, nil
+}
+
+func (p *shexcParser) parseGroup() (TripleExpr, error) {
+	first, err := p.parseUnaryTripleExpr()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []TripleExpr{first}
+	for p.isPunct(";") {
+		p.next()
+		if p.isPunct("}") || p.isPunct(")") || p.isPunct("|") {
+			break
+		}
+		expr, err := p.parseUnaryTripleExpr()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return &EachOf{Exprs: exprs, Min: 1, Max: 1}, nil
+}
+
+func (p *shexcParser) parseUnaryTripleExpr() (TripleExpr, error) {
+	if p.isPunct("&") {
+		p.next()
+		label, err := p.parseLabel()
+		if err != nil {
+			return nil, err
+		}
+		inclusion := &tripleExprInclusion{label: label}
+		p.inclusions = append(p.inclusions, inclusion)
+		return inclusion, nil
+	}
+
+	var label string
+	if p.isPunct("$") {
+		p.next()
+		var err error
+		if label, err = p.parseLabel(); err != nil {
+			return nil, err
+		}
+	}
+
+	var expr TripleExpr
+	var err error
+	if p.isPunct("(") {
+		expr, err = p.parseBracketedTripleExpr()
+	} else {
+		expr, err = p.parseTripleConstraint()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if label != "" {
+		p.named[label] = expr
+	}
+	return expr, nil
+}
+
+func (p *shexcParser) parseBracketedTripleExpr() (TripleExpr, error) {
+	p.next()
+	inner, err := p.parseTripleExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+
+	if p.isCardinalityStart() {
+		min, max, err := p.parseCardinality()
+		if err != nil {
+			return nil, err
+		}
+		switch group := inner.(type) {
+		case *EachOf:
+			group.Min, group.Max = min, max
+		case *OneOf:
+			group.Min, group.Max = min, max
+		default:
+			inner = &EachOf{Exprs: []TripleExpr{inner}, Min: min, Max: max}
+		}
+	}
+	return inner, p.skipAnnotations()
+}
+
+func (p *shexcParser) parseTripleConstraint() (TripleExpr, error) {
+	constraint := &TripleConstraint{Min: 1, Max: 1}
+	if p.isPunct("^") {
+		p.next()
+		constraint.Inverse = true
+	}
+	predicate, err := p.parsePredicate()
+	if err != nil {
+		return nil, err
+	}
+	constraint.Predicate = predicate
+
+	valueExpr, err := p.parseShapeExpression()
+	if err != nil {
+		return nil, err
+	}
+	if nc, ok := valueExpr.(*NodeConstraint); !ok || !nc.isEmpty() {
+		constraint.ValueExpr = valueExpr
+	}
+
+	if p.isCardinalityStart() {
+		if constraint.Min, constraint.Max, err = p.parseCardinality(); err != nil {
+			return nil, err
+		}
+	}
+	return constraint, p.skipAnnotations()
+}
+
+func (p *shexcParser) isCardinalityStart() bool {
+	return p.isPunct("*") || p.isPunct("+") || p.isPunct("?") || p.peek().kind == tokRepeat
+}
+
+func (p *shexcParser) parseCardinality() (int, int, error) {
+	tok := p.next()
+	switch tok.value {
+	case "*":
+		return 0, Unbounded, nil
+	case "+":
+		return 1, Unbounded, nil
+	case "?":
+		return 0, 1, nil
+	}
+
+	minText, maxText, hasComma := strings.Cut(tok.value, ",")
+	min, err := strconv.Atoi(minText)
+	if err != nil {
+		return 0, 0, p.errorf(tok, "invalid repeat range {%s}", tok.value)
+	}
+	switch {
+	case !hasComma:
+		return min, min, nil
+	case maxText == "" || maxText == "*":
+		return min, Unbounded, nil
+	}
+	max, err := strconv.Atoi(maxText)
+	if err != nil || max < min {
+		return 0, 0, p.errorf(tok, "invalid repeat range {%s}", tok.value)
+	}
+	return min, max, nil
+}
+
+// skipAnnotations skips "// predicate object" annotations
+func (p *shexcParser) skipAnnotations() error {
+	for p.isPunct("//") {
+		p.next()
+		if _, err := p.parsePredicate(); err != nil {
+			return err
+		}
+		if tok := p.peek(); tok.kind == tokIRI || tok.kind == tokPName {
+			if _, err := p.parseIRI(); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := p.parseLiteral(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *shexcParser) isPredicateStart() bool {
+	tok := p.peek()
+	return tok.kind == tokIRI || tok.kind == tokPName || (tok.kind == tokKeyword && tok.value == "a")
+}
+
+func (p *shexcParser) parsePredicate() (rdf.IRI, error) {
+	if tok := p.peek(); tok.kind == tokKeyword && tok.value == "a" {
+		p.next()
+		return rdf.RDFType, nil
+	}
+	return p.parseIRI()
+}
+
+func (p *shexcParser) parseIRI() (rdf.IRI, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokIRI:
+		return rdf.IRI(p.resolve(tok.value)), nil
+	case tokPName:
+		iri, err := p.expand(tok)
+		return rdf.IRI(iri), err
+	}
+	return "", p.errorf(tok, "expected an IRI, found %q", tok.value)
+}
+
+func (p *shexcParser) parseLiteral() (rdf.Literal, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		if p.peek().kind == tokLangTag {
+			return rdf.NewLangLiteral(tok.value, p.next().value), nil
+		}
+		if p.isPunct("^^") {
+			p.next()
+			datatype, err := p.parseIRI()
+			if err != nil {
+				return rdf.Literal{}, err
+			}
+			return rdf.NewTypedLiteral(tok.value, datatype), nil
+		}
+		return rdf.NewLiteral(tok.value), nil
+	case tokInteger:
+		return rdf.NewTypedLiteral(tok.value, xsdInteger), nil
+	case tokDecimal:
+		return rdf.NewTypedLiteral(tok.value, xsdDecimal), nil
+	case tokDouble:
+		return rdf.NewTypedLiteral(tok.value, xsdDouble), nil
+	case tokKeyword:
+		if tok.value == "true" || tok.value == "false" {
+			return rdf.NewTypedLiteral(tok.value, xsdBoolean), nil
+		}
+	}
+	return rdf.Literal{}, p.errorf(tok, "expected a literal, found %q", tok.value)
+}
+
+// expand turns a prefixed name into an IRI
+func (p *shexcParser) expand(tok shexcToken) (string, error) {
+	prefix, local, _ := strings.Cut(tok.value, ":")
+	namespace, ok := p.schema.Prefixes[prefix]
+	if !ok {
+		return "", p.errorf(tok, "undefined prefix %q", prefix)
+	}
+	return namespace + local, nil
+}
+
+// resolve resolves an IRI reference against the schema base
+func (p *shexcParser) resolve(iri string) string {
+	return resolveIRI(p.schema.Base, iri)
+}
+
+// resolveInclusions points every &label at its named triple expression
+func (p *shexcParser) resolveInclusions() error {
+	for _, inclusion := range p.inclusions {
+		target, ok := p.named[inclusion.label]
+		if !ok {
+			return NewValidationError(FormatShExC, fmt.Sprintf("triple expression %s is not defined", inclusion.label), nil)
+		}
+		inclusion.target = target
+	}
+	return nil
+}
+
+// isEmpty reports whether the constraint places no restriction on a node
+func (c *NodeConstraint) isEmpty() bool {
+	return c.NodeKind == "" && c.Datatype == "" && c.Values == nil &&
+		c.Length == nil && c.MinLength == nil && c.MaxLength == nil && c.Pattern == "" &&
+		c.MinInclusive == nil && c.MinExclusive == nil && c.MaxInclusive == nil && c.MaxExclusive == nil &&
+		c.TotalDigits == nil && c.FractionDigits == nil
+}
+
+// resolveIRI resolves ref against base, returning ref unchanged when either is not a valid IRI
+func resolveIRI(base string, ref string) string {
+	if base == "" {
+		return ref
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
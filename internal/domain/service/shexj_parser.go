@@ -0,0 +1,336 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// ParseShExJ parses a schema in the ShEx JSON syntax
+func ParseShExJ(data string) (*ShExSchema, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, NewValidationError(FormatShExJ, "invalid JSON", err)
+	}
+	if doc["type"] != "Schema" {
+		return nil, NewValidationError(FormatShExJ, `expected an object of type "Schema"`, nil)
+	}
+
+	p := &shexjParser{schema: NewShExSchema(), named: make(map[string]TripleExpr)}
+	if start, ok := doc["start"]; ok {
+		expr, err := p.shapeExpr(start)
+		if err != nil {
+			return nil, err
+		}
+		p.schema.Start = expr
+	}
+
+	shapes, _ := doc["shapes"].([]interface{})
+	for _, item := range shapes {
+		decl, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, p.errorf("shape declarations must be objects")
+		}
+		id, _ := decl["id"].(string)
+		if id == "" {
+			return nil, p.errorf("shape declaration without an id")
+		}
+		value := item
+		if decl["type"] == "ShapeDecl" {
+			value = decl["shapeExpr"]
+		}
+		expr, err := p.shapeExpr(value)
+		if err != nil {
+			return nil, err
+		}
+		p.schema.Shapes[id] = expr
+	}
+
+	for _, inclusion := range p.inclusions {
+		target, ok := p.named[inclusion.label]
+		if !ok {
+			return nil, p.errorf("triple expression %s is not defined", inclusion.label)
+		}
+		inclusion.target = target
+	}
+	if err := p.schema.checkStructure(); err != nil {
+		return nil, p.errorf("%s", err)
+	}
+	return p.schema, nil
+}
+
+// shexjParser builds a schema from decoded ShExJ
+type shexjParser struct {
+	schema     *ShExSchema
+	named      map[string]TripleExpr
+	inclusions []*tripleExprInclusion
+}
+
+func (p *shexjParser) errorf(format string, args ...interface{}) error {
+	return NewValidationError(FormatShExJ, fmt.Sprintf(format, args...), nil)
+}
+
+func (p *shexjParser) shapeExpr(value interface{}) (ShapeExpr, error) {
+	if label, ok := value.(string); ok {
+		return &ShapeRef{Label: label}, nil
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, p.errorf("shape expressions must be strings or objects")
+	}
+
+	switch obj["type"] {
+	case "ShapeOr", "ShapeAnd":
+		items, _ := obj["shapeExprs"].([]interface{})
+		exprs := make([]ShapeExpr, 0, len(items))
+		for _, item := range items {
+			expr, err := p.shapeExpr(item)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, expr)
+		}
+		if obj["type"] == "ShapeOr" {
+			return &ShapeOr{Exprs: exprs}, nil
+		}
+		return &ShapeAnd{Exprs: exprs}, nil
+	case "ShapeNot":
+		expr, err := p.shapeExpr(obj["shapeExpr"])
+		if err != nil {
+			return nil, err
+		}
+		return &ShapeNot{Expr: expr}, nil
+	case "ShapeExternal":
+		return &ShapeExternal{}, nil
+	case "NodeConstraint":
+		return p.nodeConstraint(obj)
+	case "Shape":
+		return p.shape(obj)
+	}
+	return nil, p.errorf("unknown shape expression type %v", obj["type"])
+}
+
+func (p *shexjParser) shape(obj map[string]interface{}) (ShapeExpr, error) {
+	shape := &Shape{}
+	shape.Closed, _ = obj["closed"].(bool)
+	extra, _ := obj["extra"].([]interface{})
+	for _, item := range extra {
+		iri, _ := item.(string)
+		shape.Extra = append(shape.Extra, rdf.IRI(iri))
+	}
+	if expression, ok := obj["expression"]; ok {
+		expr, err := p.tripleExpr(expression)
+		if err != nil {
+			return nil, err
+		}
+		shape.Expression = expr
+	}
+	return shape, nil
+}
+
+func (p *shexjParser) tripleExpr(value interface{}) (TripleExpr, error) {
+	if label, ok := value.(string); ok {
+		inclusion := &tripleExprInclusion{label: label}
+		p.inclusions = append(p.inclusions, inclusion)
+		return inclusion, nil
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, p.errorf("triple expressions must be strings or objects")
+	}
+
+	min, max, err := p.cardinality(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var expr TripleExpr
+	switch obj["type"] {
+	case "EachOf", "OneOf":
+		items, _ := obj["expressions"].([]interface{})
+		exprs := make([]TripleExpr, 0, len(items))
+		for _, item := range items {
+			child, err := p.tripleExpr(item)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, child)
+		}
+		if obj["type"] == "EachOf" {
+			expr = &EachOf{Exprs: exprs, Min: min, Max: max}
+		} else {
+			expr = &OneOf{Exprs: exprs, Min: min, Max: max}
+		}
+	case "TripleConstraint":
+		predicate, _ := obj["predicate"].(string)
+		if predicate == "" {
+			return nil, p.errorf("triple constraint without a predicate")
+		}
+		constraint := &TripleConstraint{Predicate: rdf.IRI(predicate), Min: min, Max: max}
+		constraint.Inverse, _ = obj["inverse"].(bool)
+		if valueExpr, ok := obj["valueExpr"]; ok {
+			if constraint.ValueExpr, err = p.shapeExpr(valueExpr); err != nil {
+				return nil, err
+			}
+		}
+		expr = constraint
+	default:
+		return nil, p.errorf("unknown triple expression type %v", obj["type"])
+	}
+
+	if id, ok := obj["id"].(string); ok {
+		p.named[id] = expr
+	}
+	return expr, nil
+}
+
+// cardinality reads min and max, defaulting to exactly one
+func (p *shexjParser) cardinality(obj map[string]interface{}) (int, int, error) {
+	min, max := 1, 1
+	if value, ok := obj["min"]; ok {
+		n, err := p.integer(value)
+		if err != nil {
+			return 0, 0, err
+		}
+		min = n
+	}
+	if value, ok := obj["max"]; ok {
+		n, err := p.integer(value)
+		if err != nil {
+			return 0, 0, err
+		}
+		max = n
+	}
+	if max != Unbounded && max < min {
+		return 0, 0, p.errorf("max cardinality %d is less than min %d", max, min)
+	}
+	return min, max, nil
+}
+
+func (p *shexjParser) integer(value interface{}) (int, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, p.errorf("expected an integer, found %v", value)
+	}
+	n, err := number.Int64()
+	if err != nil {
+		return 0, p.errorf("expected an integer, found %v", value)
+	}
+	return int(n), nil
+}
+
+func (p *shexjParser) nodeConstraint(obj map[string]interface{}) (ShapeExpr, error) {
+	constraint := &NodeConstraint{}
+	constraint.NodeKind, _ = obj["nodeKind"].(string)
+	if datatype, ok := obj["datatype"].(string); ok {
+		constraint.Datatype = rdf.IRI(datatype)
+	}
+	constraint.Pattern, _ = obj["pattern"].(string)
+	constraint.Flags, _ = obj["flags"].(string)
+
+	for key, target := range map[string]**int{
+		"length": &constraint.Length, "minlength": &constraint.MinLength, "maxlength": &constraint.MaxLength,
+		"totaldigits": &constraint.TotalDigits, "fractiondigits": &constraint.FractionDigits,
+	} {
+		if value, ok := obj[key]; ok {
+			n, err := p.integer(value)
+			if err != nil {
+				return nil, err
+			}
+			*target = &n
+		}
+	}
+	for key, target := range map[string]**rdf.Literal{
+		"mininclusive": &constraint.MinInclusive, "minexclusive": &constraint.MinExclusive,
+		"maxinclusive": &constraint.MaxInclusive, "maxexclusive": &constraint.MaxExclusive,
+	} {
+		if value, ok := obj[key]; ok {
+			number, ok := value.(json.Number)
+			if !ok {
+				return nil, p.errorf("%s requires a number", key)
+			}
+			literal := numberLiteral(string(number))
+			*target = &literal
+		}
+	}
+
+	values, _ := obj["values"].([]interface{})
+	for _, item := range values {
+		value, err := p.valueSetValue(item)
+		if err != nil {
+			return nil, err
+		}
+		constraint.Values = append(constraint.Values, value)
+	}
+	return constraint, nil
+}
+
+func (p *shexjParser) valueSetValue(item interface{}) (ValueSetValue, error) {
+	if iri, ok := item.(string); ok {
+		return ObjectValue{Term: rdf.IRI(iri)}, nil
+	}
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		return nil, p.errorf("value set values must be strings or objects")
+	}
+	if value, ok := obj["value"].(string); ok {
+		if language, ok := obj["language"].(string); ok {
+			return ObjectValue{Term: rdf.NewLangLiteral(value, language)}, nil
+		}
+		if datatype, ok := obj["type"].(string); ok {
+			return ObjectValue{Term: rdf.NewTypedLiteral(value, rdf.IRI(datatype))}, nil
+		}
+		return ObjectValue{Term: rdf.NewLiteral(value)}, nil
+	}
+
+	stem, _ := obj["stem"].(string) // a Wildcard object leaves the stem empty
+	exclusions, err := p.exclusions(obj["exclusions"])
+	if err != nil {
+		return nil, err
+	}
+	switch obj["type"] {
+	case "IriStem", "IriStemRange":
+		return IRIStem{Stem: stem, Exclusions: exclusions}, nil
+	case "LiteralStem", "LiteralStemRange":
+		return LiteralStem{Stem: stem, Exclusions: exclusions}, nil
+	case "LanguageStem", "LanguageStemRange":
+		return LanguageStem{Stem: stem, Exclusions: exclusions}, nil
+	case "Language":
+		tag, _ := obj["languageTag"].(string)
+		return LanguageStem{Stem: tag, Exact: true}, nil
+	}
+	return nil, p.errorf("unknown value set value type %v", obj["type"])
+}
+
+func (p *shexjParser) exclusions(value interface{}) ([]Exclusion, error) {
+	items, _ := value.([]interface{})
+	exclusions := make([]Exclusion, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			exclusions = append(exclusions, Exclusion{Value: s})
+			continue
+		}
+		obj, ok := item.(map[string]interface{})
+		stem, _ := obj["stem"].(string)
+		if !ok || stem == "" {
+			return nil, p.errorf("invalid exclusion %v", item)
+		}
+		exclusions = append(exclusions, Exclusion{Value: stem, IsStem: true})
+	}
+	return exclusions, nil
+}
+
+// numberLiteral types a JSON number as xsd:integer, xsd:decimal or xsd:double
+func numberLiteral(number string) rdf.Literal {
+	switch {
+	case strings.ContainsAny(number, "eE"):
+		return rdf.NewTypedLiteral(number, xsdDouble)
+	case strings.Contains(number, "."):
+		return rdf.NewTypedLiteral(number, xsdDecimal)
+	}
+	return rdf.NewTypedLiteral(number, xsdInteger)
+}
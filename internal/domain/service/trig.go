@@ -0,0 +1,83 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// trigWriter serializes a dataset as TriG, writing each graph with the
+// Turtle writer and declaring the used prefixes once for the whole document
+type trigWriter struct {
+	turtle *turtleWriter
+}
+
+// newTriGWriter creates a writer that knows the given prefixes
+func newTriGWriter(prefixes *rdf.PrefixMap) *trigWriter {
+	return &trigWriter{turtle: newTurtleWriter(prefixes)}
+}
+
+// Write serializes the dataset
+func (w *trigWriter) Write(dataset *rdf.Dataset) string {
+	w.markSharedBlankNodes(dataset)
+
+	var body strings.Builder
+	if dataset.Default().Len() > 0 {
+		body.WriteString(w.block("", dataset.Default()))
+	}
+	for _, name := range dataset.GraphNames() {
+		graph, _ := dataset.NamedGraph(name)
+		if body.Len() > 0 {
+			body.WriteString("\n")
+		}
+		body.WriteString(w.block(w.turtle.term(name)+" ", graph))
+	}
+
+	var out strings.Builder
+	out.WriteString(w.turtle.prefixDeclarations())
+	if len(w.turtle.used) > 0 && body.Len() > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString(body.String())
+	return out.String()
+}
+
+// block writes one graph wrapped in braces with its statements indented
+func (w *trigWriter) block(label string, graph *rdf.Graph) string {
+	statements := strings.TrimSuffix(w.turtle.writeGraph(graph), "\n")
+	if statements == "" {
+		return label + "{ }\n"
+	}
+	return label + "{\n    " + strings.ReplaceAll(statements, "\n", "\n    ") + "\n}\n"
+}
+
+// markSharedBlankNodes stops blank nodes used by several graphs, or used as
+// graph names, from being inlined so their labels connect the graphs
+func (w *trigWriter) markSharedBlankNodes(dataset *rdf.Dataset) {
+	seen := make(map[rdf.BlankNode]int)
+	count := func(graph *rdf.Graph) {
+		inGraph := make(map[rdf.BlankNode]bool)
+		for _, t := range graph.Triples() {
+			for _, term := range []rdf.Term{t.Subject, t.Object} {
+				if b, ok := term.(rdf.BlankNode); ok && !inGraph[b] {
+					inGraph[b] = true
+					seen[b]++
+				}
+			}
+		}
+	}
+
+	count(dataset.Default())
+	for _, name := range dataset.GraphNames() {
+		if b, ok := name.(rdf.BlankNode); ok {
+			w.turtle.shared[b] = true
+		}
+		graph, _ := dataset.NamedGraph(name)
+		count(graph)
+	}
+	for b, n := range seen {
+		if n > 1 {
+			w.turtle.shared[b] = true
+		}
+	}
+}
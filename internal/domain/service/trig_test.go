@@ -0,0 +1,204 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestDatasetFormats(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService()
+
+	alice := rdf.IRI("https://example.com/alice")
+	foafName := rdf.IRI("http://xmlns.com/foaf/0.1/name")
+	profile := rdf.IRI("https://example.com/profile")
+	contacts := rdf.IRI("https://example.com/contacts")
+
+	trig := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+PREFIX ex: <https://example.com/>
+
+# default graph
+ex:alice a foaf:Person .
+
+ex:profile {
+    ex:alice foaf:name "Alice" ;
+        foaf:homepage <https://alice.example.com/#me> .
+}
+
+GRAPH <https://example.com/contacts> {
+    ex:alice foaf:knows [ foaf:name "Bob {not a block}." ] ;
+        foaf:knows _:carol .
+    _:carol foaf:name "Carol"
+}
+`
+
+	t.Run("parses TriG into default and named graphs", func(t *testing.T) {
+		// Act
+		dataset, err := rdfService.ParseDataset(trig, string(service.FormatTriG))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, dataset.Default().Len())
+		assert.Equal(t, []rdf.Term{contacts, profile}, dataset.GraphNames())
+
+		profileGraph, _ := dataset.NamedGraph(profile)
+		assert.True(t, profileGraph.Contains(rdf.Triple{Subject: alice, Predicate: foafName, Object: rdf.NewLiteral("Alice")}))
+
+		contactsGraph, _ := dataset.NamedGraph(contacts)
+		assert.Equal(t, 4, contactsGraph.Len())
+		assert.Len(t, contactsGraph.Match(nil, foafName, rdf.NewLiteral("Bob {not a block}.")), 1)
+	})
+
+	t.Run("round trips TriG through N-Quads", func(t *testing.T) {
+		// Arrange
+		dataset, err := rdfService.ParseDataset(trig, string(service.FormatTriG))
+		require.NoError(t, err)
+
+		// Act
+		nquads, err := rdfService.SerializeDataset(dataset, string(service.FormatNQuads))
+		require.NoError(t, err)
+		parsed, err := rdfService.ParseDataset(nquads, string(service.FormatNQuads))
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, dataset.String(), parsed.String())
+		assert.Contains(t, nquads, `<https://example.com/alice> <http://xmlns.com/foaf/0.1/name> "Alice" <https://example.com/profile> .`)
+	})
+
+	t.Run("writes deterministic TriG that parses back to the same dataset", func(t *testing.T) {
+		// Arrange
+		dataset, err := rdfService.ParseDataset(trig, string(service.FormatTriG))
+		require.NoError(t, err)
+
+		// Act
+		first, err := rdfService.SerializeDataset(dataset, string(service.FormatTriG))
+		require.NoError(t, err)
+		second, err := rdfService.SerializeDataset(dataset, string(service.FormatTriG))
+		require.NoError(t, err)
+		parsed, err := rdfService.ParseDataset(first, string(service.FormatTriG))
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, first, second)
+		assert.Contains(t, first, "@prefix foaf: <http://xmlns.com/foaf/0.1/> .")
+		assert.Contains(t, first, "<https://example.com/profile> {\n")
+		assert.Equal(t, dataset.Len(), parsed.Len())
+		assert.Equal(t, dataset.GraphNames(), parsed.GraphNames())
+	})
+
+	t.Run("keeps blank nodes shared between graphs labelled", func(t *testing.T) {
+		// Arrange
+		dataset := rdf.NewDataset()
+		shared := rdf.BlankNode("shared")
+		require.NoError(t, dataset.Add(profile, rdf.Triple{Subject: alice, Predicate: foafName, Object: shared}))
+		require.NoError(t, dataset.Add(contacts, rdf.Triple{Subject: alice, Predicate: foafName, Object: shared}))
+
+		// Act
+		result, err := rdfService.SerializeDataset(dataset, string(service.FormatTriG))
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotContains(t, result, "[]")
+		assert.Contains(t, result, "_:shared")
+	})
+
+	t.Run("returns the union of all graphs when parsed as a graph", func(t *testing.T) {
+		// Act
+		graph, err := rdfService.ParseGraph(trig, string(service.FormatTriG))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 7, graph.Len())
+	})
+
+	t.Run("converts N-Quads to JSON-LD with named graphs", func(t *testing.T) {
+		// Arrange
+		nquads := `<https://example.com/alice> <http://xmlns.com/foaf/0.1/name> "Alice" <https://example.com/profile> .
+`
+
+		// Act
+		result, err := rdfService.ConvertFormat(nquads, string(service.FormatNQuads), string(service.FormatJSONLD))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Contains(t, result, `"@id": "https://example.com/profile"`)
+		assert.Contains(t, result, `"@graph"`)
+	})
+
+	t.Run("refuses to write named graphs in a triple format", func(t *testing.T) {
+		// Arrange
+		dataset, err := rdfService.ParseDataset(trig, string(service.FormatTriG))
+		require.NoError(t, err)
+
+		// Act
+		_, err = rdfService.SerializeDataset(dataset, string(service.FormatTurtle))
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("reports unterminated graph blocks", func(t *testing.T) {
+		// Act
+		_, err := rdfService.ParseDataset(`<https://example.com/g> { <https://example.com/s> <https://example.com/p> "o" .`, string(service.FormatTriG))
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("reports unterminated statements", func(t *testing.T) {
+		// Act
+		_, number := rdfService.ParseDataset("0", string(service.FormatTriG))
+		_, unterminated := rdfService.ParseDataset(`<https://example.com/s> <https://example.com/p> "o"`, string(service.FormatTriG))
+
+		// Assert
+		assert.Error(t, number)
+		assert.ErrorContains(t, unterminated, "unterminated statement")
+	})
+
+	t.Run("rejects malformed input without crashing", func(t *testing.T) {
+		cases := []struct {
+			format string
+			data   string
+		}{
+			{string(service.FormatNQuads), ".0000"},
+			{string(service.FormatNQuads), "<https://example.com/s> <https://example.com/p> .0000 ."},
+			{string(service.FormatNQuads), "<https://example.com/s> <https://example.com/p> \"o\" <https://example.com/g> <https://example.com/h> ."},
+			{string(service.FormatNTriples), ".0000"},
+			{string(service.FormatNTriples), "<s> <https://example.com/p> \"o\" ."},
+			{string(service.FormatTriG), ".3\\\x85>\xc76\xe8."},
+			{string(service.FormatTriG), "<https://example.com/g> { <https://example.com/s> <https://example.com/p> [ <https://example.com/q> } ."},
+		}
+		for _, c := range cases {
+			// Act
+			_, err := rdfService.ParseDataset(c.data, c.format)
+
+			// Assert
+			assert.Error(t, err, "%s: %q", c.format, c.data)
+		}
+	})
+
+	t.Run("shares blank node labels between graphs", func(t *testing.T) {
+		// Arrange
+		data := `_:b1 <https://example.com/p> "default" .
+<https://example.com/g> { _:b1 <https://example.com/p> "named" . [] <https://example.com/p> "anonymous" }`
+
+		// Act
+		dataset, err := rdfService.ParseDataset(data, string(service.FormatTriG))
+
+		// Assert
+		require.NoError(t, err)
+		named, _ := dataset.NamedGraph(rdf.IRI("https://example.com/g"))
+		shared := dataset.Default().Triples()[0].Subject
+		assert.Len(t, named.Match(shared, nil, nil), 1)
+		assert.Len(t, named.Match(nil, nil, rdf.NewLiteral("anonymous")), 1)
+		assert.Empty(t, named.Match(shared, nil, rdf.NewLiteral("anonymous")))
+	})
+
+	t.Run("lists the dataset formats as supported", func(t *testing.T) {
+		assert.Contains(t, rdfService.SupportedFormats(), string(service.FormatTriG))
+		assert.Contains(t, rdfService.SupportedFormats(), string(service.FormatNQuads))
+	})
+}
@@ -0,0 +1,1031 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+// maxTurtleNesting bounds how deeply blank node property lists and
+// collections may nest, so that a document cannot exhaust the stack
+const maxTurtleNesting = 256
+
+// endOfInput is returned by the lexer once the stream is exhausted
+const endOfInput rune = -1
+
+// absoluteIRI matches IRIs that start with a scheme
+var absoluteIRI = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+// parseTurtle reads a Turtle, N3, TriG, N-Triples or N-Quads document from
+// r into a dataset, resolving relative IRIs against base unless the
+// document declares its own. The document is read one token at a time and
+// each triple is checked against the limiter as soon as it is read, so an
+// oversized document fails without being held in memory. Parsing happens in
+// the caller's goroutine and every token consumes input, so malformed
+// documents fail rather than hang or crash.
+func parseTurtle(r io.Reader, format RDFFormat, base string, limiter *tripleLimiter) (*rdf.Dataset, error) {
+	lexer := &turtleLexer{in: bufio.NewReader(r), line: 1, column: 1, format: format}
+	if limiter != nil {
+		lexer.maxLiteral = limiter.limits.MaxLiteralLength
+	}
+	p := &turtleParser{
+		lexer:    lexer,
+		format:   format,
+		base:     base,
+		prefixes: make(map[string]string),
+		labels:   make(map[string]rdf.BlankNode),
+		used:     make(map[rdf.BlankNode]bool),
+		dataset:  rdf.NewDataset(),
+		limiter:  limiter,
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	for p.tok.kind != ttlEOF {
+		var err error
+		if format == FormatNTriples || format == FormatNQuads {
+			err = p.parseLine()
+		} else {
+			err = p.parseStatement()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.dataset, nil
+}
+
+// turtleTokenKind identifies a token of the Turtle family of formats
+type turtleTokenKind int
+
+const (
+	ttlEOF turtleTokenKind = iota
+	ttlIRI
+	ttlPName
+	ttlBlankNode
+	ttlString
+	ttlLangTag // also @prefix and @base
+	ttlInteger
+	ttlDecimal
+	ttlDouble
+	ttlWord // a bare name such as a, true, PREFIX or GRAPH
+	ttlPunct
+)
+
+// turtleToken is a lexical token with its position. The value of a
+// prefixed name is its local name, and prefix holds the prefix.
+type turtleToken struct {
+	kind   turtleTokenKind
+	value  string
+	prefix string
+	quote  rune
+	long   bool
+	line   int
+	column int
+}
+
+func (t turtleToken) String() string {
+	switch t.kind {
+	case ttlEOF:
+		return "end of input"
+	case ttlIRI:
+		return "<" + t.value + ">"
+	case ttlPName:
+		return t.prefix + ":" + t.value
+	case ttlBlankNode:
+		return "_:" + t.value
+	case ttlString:
+		return "a string"
+	case ttlLangTag:
+		return "@" + t.value
+	default:
+		return fmt.Sprintf("%q", t.value)
+	}
+}
+
+// turtleLexer reads tokens from a stream, looking ahead only as far as a
+// token needs
+type turtleLexer struct {
+	in         *bufio.Reader
+	ahead      []rune
+	line       int
+	column     int
+	err        error // a failure to read the stream, reported in place of syntax errors
+	format     RDFFormat
+	maxLiteral int
+}
+
+func (l *turtleLexer) errorf(format string, args ...interface{}) error {
+	if l.err != nil {
+		return l.err
+	}
+	return NewValidationErrorWithPosition(l.format, l.line, l.column, fmt.Sprintf(format, args...), nil)
+}
+
+// peek returns the rune offset runes ahead without consuming it
+func (l *turtleLexer) peek(offset int) rune {
+	for len(l.ahead) <= offset {
+		if l.err != nil {
+			return endOfInput
+		}
+		r, size, err := l.in.ReadRune()
+		if err == io.EOF {
+			return endOfInput
+		}
+		if err != nil {
+			l.err = err
+			return endOfInput
+		}
+		if r == utf8.RuneError && size == 1 {
+			l.err = NewValidationErrorWithPosition(l.format, l.line, l.column, "invalid UTF-8", nil)
+			return endOfInput
+		}
+		l.ahead = append(l.ahead, r)
+	}
+	return l.ahead[offset]
+}
+
+// advance consumes and returns the next rune
+func (l *turtleLexer) advance() rune {
+	r := l.peek(0)
+	if r == endOfInput {
+		return r
+	}
+	l.ahead = l.ahead[1:]
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+// dots returns the length of the run of '.' at the current position
+func (l *turtleLexer) dots() int {
+	n := 0
+	for l.peek(n) == '.' {
+		n++
+	}
+	return n
+}
+
+// skipSpace skips whitespace and comments
+func (l *turtleLexer) skipSpace() {
+	for {
+		switch r := l.peek(0); r {
+		case ' ', '\t', '\r', '\n':
+			l.advance()
+		case '#':
+			for r := l.peek(0); r != '\n' && r != '\r' && r != endOfInput; r = l.peek(0) {
+				l.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *turtleLexer) next() (turtleToken, error) {
+	l.skipSpace()
+	tok := turtleToken{line: l.line, column: l.column}
+	r := l.peek(0)
+	var err error
+	switch {
+	case r == endOfInput:
+		tok.kind = ttlEOF
+		return tok, l.err
+	case r == '<':
+		tok.kind = ttlIRI
+		tok.value, err = l.readIRI()
+	case r == '"' || r == '\'':
+		tok.kind, tok.quote = ttlString, r
+		tok.value, tok.long, err = l.readString()
+	case r == '@':
+		l.advance()
+		tok.kind = ttlLangTag
+		tok.value, err = l.readLangTag()
+	case r == '_' && l.peek(1) == ':':
+		l.advance()
+		l.advance()
+		tok.kind = ttlBlankNode
+		tok.value, err = l.readBlankNodeLabel()
+	case isDigitRune(r) || (r == '.' && isDigitRune(l.peek(1))) ||
+		((r == '+' || r == '-') && (isDigitRune(l.peek(1)) || (l.peek(1) == '.' && isDigitRune(l.peek(2))))):
+		tok.kind, tok.value = l.readNumber()
+	case r == '^' && l.peek(1) == '^':
+		l.advance()
+		l.advance()
+		tok.kind, tok.value = ttlPunct, "^^"
+	case strings.ContainsRune(".;,[](){}", r):
+		l.advance()
+		tok.kind, tok.value = ttlPunct, string(r)
+	case r == ':' || isPNCharsBase(r):
+		tok.kind, tok.value = ttlWord, l.readPrefix()
+		if l.peek(0) == ':' {
+			l.advance()
+			tok.kind, tok.prefix = ttlPName, tok.value
+			tok.value, err = l.readLocalName()
+		}
+	default:
+		return tok, l.errorf("unexpected character %q", r)
+	}
+	return tok, err
+}
+
+func (l *turtleLexer) readIRI() (string, error) {
+	l.advance()
+	var b strings.Builder
+	for {
+		r := l.advance()
+		switch {
+		case r == '>':
+			return b.String(), nil
+		case r == endOfInput:
+			return "", l.errorf("unterminated IRI")
+		case r == '\\':
+			if c := l.peek(0); c != 'u' && c != 'U' {
+				return "", l.errorf("invalid escape sequence in IRI")
+			}
+			code, err := l.readUChar()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(code)
+		case r <= 0x20 || strings.ContainsRune("<\"{}|^`", r):
+			return "", l.errorf("invalid character %q in IRI", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// readString reads a quoted literal and reports whether it used long quotes
+func (l *turtleLexer) readString() (string, bool, error) {
+	quote := l.advance()
+	long := l.peek(0) == quote && l.peek(1) == quote
+	if long {
+		l.advance()
+		l.advance()
+	}
+
+	var b strings.Builder
+	for {
+		if l.maxLiteral > 0 && b.Len() > l.maxLiteral {
+			return "", long, limitExceeded(l.format, LimitLiteralLength, int64(l.maxLiteral))
+		}
+		r := l.advance()
+		switch {
+		case r == endOfInput:
+			return "", long, l.errorf("unterminated string")
+		case r == quote && !long:
+			return b.String(), long, nil
+		case r == quote && l.peek(0) == quote && l.peek(1) == quote:
+			l.advance()
+			l.advance()
+			return b.String(), long, nil
+		case r == '\\':
+			c, err := l.readEscape()
+			if err != nil {
+				return "", long, err
+			}
+			b.WriteRune(c)
+		case (r == '\n' || r == '\r') && !long:
+			return "", long, l.errorf("unterminated string")
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// readEscape reads the escape sequence following a backslash in a string
+func (l *turtleLexer) readEscape() (rune, error) {
+	simple := map[rune]rune{'t': '\t', 'n': '\n', 'r': '\r', 'b': '\b', 'f': '\f', '"': '"', '\'': '\'', '\\': '\\'}
+	c := l.peek(0)
+	if r, ok := simple[c]; ok {
+		l.advance()
+		return r, nil
+	}
+	if c == 'u' || c == 'U' {
+		return l.readUChar()
+	}
+	return 0, l.errorf("invalid escape sequence")
+}
+
+// readUChar reads a \u or \U escape from the 'u' on
+func (l *turtleLexer) readUChar() (rune, error) {
+	size := 4
+	if l.advance() == 'U' {
+		size = 8
+	}
+	digits := make([]rune, 0, size)
+	for i := 0; i < size && isHexRune(l.peek(0)); i++ {
+		digits = append(digits, l.advance())
+	}
+	code, err := strconv.ParseUint(string(digits), 16, 32)
+	if len(digits) != size || err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, l.errorf("invalid escape sequence")
+	}
+	return rune(code), nil
+}
+
+func (l *turtleLexer) readLangTag() (string, error) {
+	var b strings.Builder
+	for isASCIILetter(l.peek(0)) {
+		b.WriteRune(l.advance())
+	}
+	if b.Len() == 0 {
+		return "", l.errorf("expected a language tag after '@'")
+	}
+	for l.peek(0) == '-' && (isASCIILetter(l.peek(1)) || isDigitRune(l.peek(1))) {
+		b.WriteRune(l.advance())
+		for isASCIILetter(l.peek(0)) || isDigitRune(l.peek(0)) {
+			b.WriteRune(l.advance())
+		}
+	}
+	return b.String(), nil
+}
+
+func (l *turtleLexer) readBlankNodeLabel() (string, error) {
+	if r := l.peek(0); !isPNCharsU(r) && !isDigitRune(r) {
+		return "", l.errorf("expected a blank node label after '_:'")
+	}
+	var b strings.Builder
+	for {
+		r := l.peek(0)
+		switch {
+		case isPNChars(r):
+			b.WriteRune(l.advance())
+		case r == '.':
+			// A label may contain dots but not end with one
+			n := l.dots()
+			if !isPNChars(l.peek(n)) {
+				return b.String(), nil
+			}
+			for i := 0; i < n; i++ {
+				b.WriteRune(l.advance())
+			}
+		default:
+			return b.String(), nil
+		}
+	}
+}
+
+func (l *turtleLexer) readNumber() (turtleTokenKind, string) {
+	var b strings.Builder
+	digits := func() {
+		for isDigitRune(l.peek(0)) {
+			b.WriteRune(l.advance())
+		}
+	}
+
+	kind := ttlInteger
+	if r := l.peek(0); r == '+' || r == '-' {
+		b.WriteRune(l.advance())
+	}
+	digits()
+	if l.peek(0) == '.' && (isDigitRune(l.peek(1)) || l.isExponent(1)) {
+		kind = ttlDecimal
+		b.WriteRune(l.advance())
+		digits()
+	}
+	if l.isExponent(0) {
+		kind = ttlDouble
+		b.WriteRune(l.advance())
+		if r := l.peek(0); r == '+' || r == '-' {
+			b.WriteRune(l.advance())
+		}
+		digits()
+	}
+	return kind, b.String()
+}
+
+// isExponent reports whether an exponent starts offset runes ahead
+func (l *turtleLexer) isExponent(offset int) bool {
+	if r := l.peek(offset); r != 'e' && r != 'E' {
+		return false
+	}
+	if r := l.peek(offset + 1); r == '+' || r == '-' {
+		offset++
+	}
+	return isDigitRune(l.peek(offset + 1))
+}
+
+// readPrefix reads the prefix of a prefixed name, or a bare word
+func (l *turtleLexer) readPrefix() string {
+	var b strings.Builder
+	if !isPNCharsBase(l.peek(0)) {
+		return ""
+	}
+	for {
+		r := l.peek(0)
+		switch {
+		case isPNChars(r):
+			b.WriteRune(l.advance())
+		case r == '.':
+			n := l.dots()
+			if !isPNChars(l.peek(n)) {
+				return b.String()
+			}
+			for i := 0; i < n; i++ {
+				b.WriteRune(l.advance())
+			}
+		default:
+			return b.String()
+		}
+	}
+}
+
+// readLocalName reads the local part of a prefixed name, keeping percent
+// encodings and removing the backslash from escaped characters
+func (l *turtleLexer) readLocalName() (string, error) {
+	var b strings.Builder
+	for {
+		r := l.peek(0)
+		switch {
+		case isPNCharsU(r) || isDigitRune(r) || r == ':' || (b.Len() > 0 && isPNChars(r)):
+			b.WriteRune(l.advance())
+		case r == '%':
+			if !isHexRune(l.peek(1)) || !isHexRune(l.peek(2)) {
+				return "", l.errorf("invalid percent encoding in local name")
+			}
+			for i := 0; i < 3; i++ {
+				b.WriteRune(l.advance())
+			}
+		case r == '\\':
+			if !strings.ContainsRune("_~.-!$&'()*+,;=/?#@%", l.peek(1)) {
+				return "", l.errorf("invalid escape sequence in local name")
+			}
+			l.advance()
+			b.WriteRune(l.advance())
+		case r == '.' && b.Len() > 0:
+			// A local name may contain dots but not end with one
+			n := l.dots()
+			if c := l.peek(n); !isPNChars(c) && c != ':' && c != '%' && c != '\\' {
+				return b.String(), nil
+			}
+			for i := 0; i < n; i++ {
+				b.WriteRune(l.advance())
+			}
+		default:
+			return b.String(), nil
+		}
+	}
+}
+
+func isDigitRune(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexRune(r rune) bool {
+	return isDigitRune(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func isASCIILetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// isPNCharsBase reports whether r may start a prefix (PN_CHARS_BASE)
+func isPNCharsBase(r rune) bool {
+	return isASCIILetter(r) ||
+		(r >= 0xC0 && r <= 0xD6) || (r >= 0xD8 && r <= 0xF6) || (r >= 0xF8 && r <= 0x2FF) ||
+		(r >= 0x370 && r <= 0x37D) || (r >= 0x37F && r <= 0x1FFF) || (r >= 0x200C && r <= 0x200D) ||
+		(r >= 0x2070 && r <= 0x218F) || (r >= 0x2C00 && r <= 0x2FEF) || (r >= 0x3001 && r <= 0xD7FF) ||
+		(r >= 0xF900 && r <= 0xFDCF) || (r >= 0xFDF0 && r <= 0xFFFD) || (r >= 0x10000 && r <= 0xEFFFF)
+}
+
+// isPNCharsU reports whether r may start a local name or blank node label (PN_CHARS_U)
+func isPNCharsU(r rune) bool {
+	return r == '_' || isPNCharsBase(r)
+}
+
+// isPNChars reports whether r may continue a name (PN_CHARS)
+func isPNChars(r rune) bool {
+	return isPNCharsU(r) || isDigitRune(r) || r == '-' || r == 0xB7 ||
+		(r >= 0x300 && r <= 0x36F) || (r >= 0x203F && r <= 0x2040)
+}
+
+// turtleParser is a recursive descent parser over a stream of tokens. Blank
+// node labels are scoped to the whole document, so in TriG and N-Quads a
+// label used in several graphs names the same node.
+type turtleParser struct {
+	lexer     *turtleLexer
+	tok       turtleToken
+	format    RDFFormat
+	base      string
+	prefixes  map[string]string
+	labels    map[string]rdf.BlankNode
+	used      map[rdf.BlankNode]bool
+	anonymous int
+	depth     int
+	graph     rdf.Term
+	dataset   *rdf.Dataset
+	limiter   *tripleLimiter
+}
+
+// next moves to the following token
+func (p *turtleParser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// take returns the current token and moves to the following one
+func (p *turtleParser) take() (turtleToken, error) {
+	tok := p.tok
+	return tok, p.next()
+}
+
+func (p *turtleParser) errorf(format string, args ...interface{}) error {
+	return NewValidationErrorWithPosition(p.format, p.tok.line, p.tok.column, fmt.Sprintf(format, args...), nil)
+}
+
+func (p *turtleParser) isPunct(s string) bool {
+	return p.tok.kind == ttlPunct && p.tok.value == s
+}
+
+// isKeyword reports whether the token is a case-insensitive keyword
+func (p *turtleParser) isKeyword(keyword string) bool {
+	return p.tok.kind == ttlWord && strings.EqualFold(p.tok.value, keyword)
+}
+
+func (p *turtleParser) expectPunct(s string) error {
+	if p.isPunct(s) {
+		return p.next()
+	}
+	if p.tok.kind == ttlEOF {
+		switch s {
+		case ".":
+			return p.errorf("unterminated statement")
+		case "}":
+			return p.errorf("unterminated graph block")
+		}
+	}
+	return p.errorf("expected '%s', found %s", s, p.tok)
+}
+
+// add checks a triple against the limits and adds it to the current graph
+func (p *turtleParser) add(subject, predicate, object rdf.Term) error {
+	t := rdf.Triple{Subject: subject, Predicate: predicate, Object: object}
+	if err := p.limiter.check(t); err != nil {
+		return err
+	}
+	return p.dataset.Add(p.graph, t)
+}
+
+// parseStatement reads a directive, a graph block or a statement
+func (p *turtleParser) parseStatement() error {
+	switch {
+	case p.tok.kind == ttlLangTag && p.tok.value == "prefix":
+		return p.parsePrefix(true)
+	case p.tok.kind == ttlLangTag && p.tok.value == "base":
+		return p.parseBase(true)
+	case p.isKeyword("PREFIX"):
+		return p.parsePrefix(false)
+	case p.isKeyword("BASE"):
+		return p.parseBase(false)
+	case p.format == FormatTriG && p.isKeyword("GRAPH"):
+		if err := p.next(); err != nil {
+			return err
+		}
+		name, err := p.parseGraphName()
+		if err != nil {
+			return err
+		}
+		return p.parseGraphBlock(name)
+	case p.format == FormatTriG && p.isPunct("{"):
+		return p.parseGraphBlock(nil)
+	}
+	return p.parseTriples(true)
+}
+
+// parsePrefix reads a prefix declaration; the @prefix form ends with a '.'
+func (p *turtleParser) parsePrefix(terminated bool) error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	if p.tok.kind != ttlPName || p.tok.value != "" {
+		return p.errorf("expected a prefix name, found %s", p.tok)
+	}
+	prefix := p.tok.prefix
+	if err := p.next(); err != nil {
+		return err
+	}
+	if p.tok.kind != ttlIRI {
+		return p.errorf("expected an IRI, found %s", p.tok)
+	}
+	p.prefixes[prefix] = p.resolve(p.tok.value)
+	if err := p.next(); err != nil {
+		return err
+	}
+	if terminated {
+		return p.expectPunct(".")
+	}
+	return nil
+}
+
+// parseBase reads a base declaration; the @base form ends with a '.'
+func (p *turtleParser) parseBase(terminated bool) error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	if p.tok.kind != ttlIRI {
+		return p.errorf("expected an IRI, found %s", p.tok)
+	}
+	p.base = p.resolve(p.tok.value)
+	if err := p.next(); err != nil {
+		return err
+	}
+	if terminated {
+		return p.expectPunct(".")
+	}
+	return nil
+}
+
+// parseTriples reads a subject and its predicates, followed by a '.' at the
+// top level. At the top level of TriG an IRI or blank node followed by '{'
+// names a graph instead.
+func (p *turtleParser) parseTriples(top bool) error {
+	var subject rdf.Term
+	optional := false // a blank node property list may stand alone
+	label := false    // the subject could name a graph
+	var err error
+	switch {
+	case p.isPunct("["):
+		var empty bool
+		subject, empty, err = p.parseBlankNodePropertyList()
+		optional, label = !empty, empty
+	case p.isPunct("("):
+		subject, err = p.parseCollection()
+	default:
+		subject, err = p.parseNode("a subject")
+		label = true
+	}
+	if err != nil {
+		return err
+	}
+
+	if top && label && p.format == FormatTriG && p.isPunct("{") {
+		return p.parseGraphBlock(subject)
+	}
+	if !optional || !(p.isPunct(".") || p.isPunct("}") || p.tok.kind == ttlEOF) {
+		if err := p.parsePredicateObjectList(subject); err != nil {
+			return err
+		}
+	}
+	if top {
+		return p.expectPunct(".")
+	}
+	return nil
+}
+
+// parseGraphName reads the IRI or blank node after the GRAPH keyword
+func (p *turtleParser) parseGraphName() (rdf.Term, error) {
+	if p.isPunct("[") {
+		name, empty, err := p.parseBlankNodePropertyList()
+		if err == nil && !empty {
+			err = p.errorf("expected a graph name")
+		}
+		return name, err
+	}
+	return p.parseNode("a graph name")
+}
+
+// parseGraphBlock reads { ... } into the graph called name. The final '.'
+// inside a block is optional.
+func (p *turtleParser) parseGraphBlock(name rdf.Term) error {
+	if !p.isPunct("{") {
+		return p.errorf("expected '{', found %s", p.tok)
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+	p.graph = name
+	p.dataset.Graph(name)
+	for !p.isPunct("}") {
+		if p.tok.kind == ttlEOF {
+			return p.errorf("unterminated graph block")
+		}
+		if err := p.parseTriples(false); err != nil {
+			return err
+		}
+		if !p.isPunct(".") {
+			break
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+	p.graph = nil
+	return p.expectPunct("}")
+}
+
+func (p *turtleParser) parsePredicateObjectList(subject rdf.Term) error {
+	for {
+		predicate, err := p.parseVerb()
+		if err != nil {
+			return err
+		}
+		if err := p.parseObjectList(subject, predicate); err != nil {
+			return err
+		}
+		if !p.isPunct(";") {
+			return nil
+		}
+		for p.isPunct(";") {
+			if err := p.next(); err != nil {
+				return err
+			}
+		}
+		if p.tok.kind != ttlIRI && p.tok.kind != ttlPName && !(p.tok.kind == ttlWord && p.tok.value == "a") {
+			return nil
+		}
+	}
+}
+
+func (p *turtleParser) parseVerb() (rdf.Term, error) {
+	if p.tok.kind == ttlWord && p.tok.value == "a" {
+		return rdf.RDFType, p.next()
+	}
+	return p.parseIRI()
+}
+
+func (p *turtleParser) parseObjectList(subject, predicate rdf.Term) error {
+	for {
+		object, err := p.parseObject()
+		if err != nil {
+			return err
+		}
+		if err := p.add(subject, predicate, object); err != nil {
+			return err
+		}
+		if !p.isPunct(",") {
+			return nil
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *turtleParser) parseObject() (rdf.Term, error) {
+	switch {
+	case p.isPunct("["):
+		node, _, err := p.parseBlankNodePropertyList()
+		return node, err
+	case p.isPunct("("):
+		return p.parseCollection()
+	case p.tok.kind == ttlString:
+		return p.parseLiteral()
+	case p.tok.kind == ttlInteger || p.tok.kind == ttlDecimal || p.tok.kind == ttlDouble ||
+		(p.tok.kind == ttlWord && (p.tok.value == "true" || p.tok.value == "false")):
+		tok, err := p.take()
+		datatypes := map[turtleTokenKind]rdf.IRI{ttlInteger: xsdInteger, ttlDecimal: xsdDecimal, ttlDouble: xsdDouble, ttlWord: xsdBoolean}
+		return rdf.NewTypedLiteral(tok.value, datatypes[tok.kind]), err
+	}
+	return p.parseNode("an object")
+}
+
+// parseNode reads an IRI or a labelled blank node
+func (p *turtleParser) parseNode(expected string) (rdf.Term, error) {
+	switch p.tok.kind {
+	case ttlIRI, ttlPName:
+		return p.parseIRI()
+	case ttlBlankNode:
+		tok, err := p.take()
+		return p.blankNode(tok.value), err
+	}
+	return nil, p.errorf("expected %s, found %s", expected, p.tok)
+}
+
+func (p *turtleParser) parseIRI() (rdf.Term, error) {
+	var iri string
+	switch p.tok.kind {
+	case ttlIRI:
+		iri = p.resolve(p.tok.value)
+	case ttlPName:
+		namespace, ok := p.prefixes[p.tok.prefix]
+		if !ok {
+			return nil, p.errorf("undefined prefix %q", p.tok.prefix)
+		}
+		iri = namespace + p.tok.value
+	default:
+		return nil, p.errorf("expected an IRI, found %s", p.tok)
+	}
+	return rdf.IRI(iri), p.next()
+}
+
+func (p *turtleParser) parseLiteral() (rdf.Term, error) {
+	lexical := p.tok.value
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.tok.kind == ttlLangTag:
+		tok, err := p.take()
+		return rdf.NewLangLiteral(lexical, tok.value), err
+	case p.isPunct("^^"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		datatype, err := p.parseIRI()
+		if err != nil {
+			return nil, err
+		}
+		return rdf.NewTypedLiteral(lexical, datatype.(rdf.IRI)), nil
+	}
+	return rdf.NewLiteral(lexical), nil
+}
+
+// parseBlankNodePropertyList reads [ ... ] and reports whether it was empty
+func (p *turtleParser) parseBlankNodePropertyList() (rdf.Term, bool, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxTurtleNesting {
+		return nil, false, p.errorf("blank nodes and collections nest too deeply")
+	}
+
+	if err := p.next(); err != nil {
+		return nil, false, err
+	}
+	node := p.freshBlankNode()
+	if p.isPunct("]") {
+		return node, true, p.next()
+	}
+	if err := p.parsePredicateObjectList(node); err != nil {
+		return nil, false, err
+	}
+	return node, false, p.expectPunct("]")
+}
+
+// parseCollection reads ( ... ) as an RDF list
+func (p *turtleParser) parseCollection() (rdf.Term, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxTurtleNesting {
+		return nil, p.errorf("blank nodes and collections nest too deeply")
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var items []rdf.Term
+	for !p.isPunct(")") {
+		if p.tok.kind == ttlEOF {
+			return nil, p.errorf("unterminated collection")
+		}
+		item, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]rdf.Term, len(items))
+	for i := range items {
+		nodes[i] = p.freshBlankNode()
+	}
+	for i, item := range items {
+		var rest rdf.Term = rdfNil
+		if i+1 < len(nodes) {
+			rest = nodes[i+1]
+		}
+		if err := p.add(nodes[i], rdfFirst, item); err != nil {
+			return nil, err
+		}
+		if err := p.add(nodes[i], rdfRest, rest); err != nil {
+			return nil, err
+		}
+	}
+	if len(nodes) == 0 {
+		return rdfNil, nil
+	}
+	return nodes[0], nil
+}
+
+// parseLine reads an N-Triples or N-Quads statement, which only uses
+// absolute IRIs, labelled blank nodes and double-quoted literals
+func (p *turtleParser) parseLine() error {
+	subject, err := p.parseLineNode()
+	if err != nil {
+		return err
+	}
+	if p.tok.kind != ttlIRI {
+		return p.errorf("expected a predicate IRI, found %s", p.tok)
+	}
+	predicate, err := p.parseLineNode()
+	if err != nil {
+		return err
+	}
+	var object rdf.Term
+	if p.tok.kind == ttlString {
+		object, err = p.parseLineLiteral()
+	} else {
+		object, err = p.parseLineNode()
+	}
+	if err != nil {
+		return err
+	}
+
+	p.graph = nil
+	if p.format == FormatNQuads && (p.tok.kind == ttlIRI || p.tok.kind == ttlBlankNode) {
+		if p.graph, err = p.parseLineNode(); err != nil {
+			return err
+		}
+	}
+	if err := p.expectPunct("."); err != nil {
+		return err
+	}
+	return p.add(subject, predicate, object)
+}
+
+// parseLineNode reads an absolute IRI or a labelled blank node
+func (p *turtleParser) parseLineNode() (rdf.Term, error) {
+	switch p.tok.kind {
+	case ttlIRI:
+		if !absoluteIRI.MatchString(p.tok.value) {
+			return nil, p.errorf("relative IRI %s", p.tok)
+		}
+		tok, err := p.take()
+		return rdf.IRI(tok.value), err
+	case ttlBlankNode:
+		tok, err := p.take()
+		return p.blankNode(tok.value), err
+	}
+	return nil, p.errorf("expected an IRI or blank node, found %s", p.tok)
+}
+
+func (p *turtleParser) parseLineLiteral() (rdf.Term, error) {
+	if p.tok.quote != '"' || p.tok.long {
+		return nil, p.errorf("literals must be enclosed in single double quotes")
+	}
+	lexical := p.tok.value
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.tok.kind == ttlLangTag:
+		tok, err := p.take()
+		return rdf.NewLangLiteral(lexical, tok.value), err
+	case p.isPunct("^^"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != ttlIRI {
+			return nil, p.errorf("expected a datatype IRI, found %s", p.tok)
+		}
+		datatype, err := p.parseLineNode()
+		if err != nil {
+			return nil, err
+		}
+		return rdf.NewTypedLiteral(lexical, datatype.(rdf.IRI)), nil
+	}
+	return rdf.NewLiteral(lexical), nil
+}
+
+// resolve resolves an IRI reference against the current base
+func (p *turtleParser) resolve(ref string) string {
+	if absoluteIRI.MatchString(ref) {
+		return ref
+	}
+	return resolveIRI(p.base, ref)
+}
+
+// blankNode returns the node for a label written in the document
+func (p *turtleParser) blankNode(label string) rdf.BlankNode {
+	if node, ok := p.labels[label]; ok {
+		return node
+	}
+	node := rdf.BlankNode(label)
+	if p.used[node] {
+		node = p.freshBlankNode()
+	}
+	p.labels[label] = node
+	p.used[node] = true
+	return node
+}
+
+// freshBlankNode returns a node for [] and collections with a label not
+// used anywhere else in the document
+func (p *turtleParser) freshBlankNode() rdf.BlankNode {
+	for {
+		p.anonymous++
+		node := rdf.BlankNode(fmt.Sprintf("b%d", p.anonymous))
+		if !p.used[node] {
+			p.used[node] = true
+			return node
+		}
+	}
+}
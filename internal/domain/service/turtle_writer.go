@@ -0,0 +1,251 @@
+package service
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+)
+
+var (
+	turtleIntegerPattern = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	turtleDecimalPattern = regexp.MustCompile(`^[+-]?[0-9]*\.[0-9]+$`)
+	turtleDoublePattern  = regexp.MustCompile(`^[+-]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)[eE][+-]?[0-9]+$`)
+)
+
+// turtleWriter serializes a graph as human-readable Turtle. Output is
+// deterministic: subjects, predicates and objects are sorted, rdf:type is
+// written first as "a", and only prefixes that are actually used are declared.
+type turtleWriter struct {
+	prefixes *rdf.PrefixMap
+	used     map[string]string
+	graph    *rdf.Graph
+	inline   map[rdf.BlankNode]bool
+	written  map[rdf.BlankNode]bool
+
+	// shared blank nodes appear in more than one graph of a dataset and
+	// keep their labels so the graphs still refer to the same node
+	shared map[rdf.BlankNode]bool
+
+	// base is the document IRI; it and its fragments are written as
+	// relative references so the output can move to another IRI
+	base string
+}
+
+// newTurtleWriter creates a writer that knows the given prefixes
+func newTurtleWriter(prefixes *rdf.PrefixMap) *turtleWriter {
+	return &turtleWriter{
+		prefixes: prefixes,
+		used:     make(map[string]string),
+		inline:   make(map[rdf.BlankNode]bool),
+		written:  make(map[rdf.BlankNode]bool),
+		shared:   make(map[rdf.BlankNode]bool),
+	}
+}
+
+// Write serializes the graph
+func (w *turtleWriter) Write(graph *rdf.Graph) string {
+	body := w.writeGraph(graph)
+
+	var out strings.Builder
+	out.WriteString(w.prefixDeclarations())
+	if len(w.used) > 0 && body != "" {
+		out.WriteString("\n")
+	}
+	out.WriteString(body)
+	return out.String()
+}
+
+// writeGraph serializes the statements of a graph without prefix declarations
+func (w *turtleWriter) writeGraph(graph *rdf.Graph) string {
+	w.graph = graph
+	w.inline = make(map[rdf.BlankNode]bool)
+	w.written = make(map[rdf.BlankNode]bool)
+	w.findInlineBlankNodes()
+
+	var body strings.Builder
+	subjects := w.sortedSubjects()
+	for _, subject := range subjects {
+		if b, ok := subject.(rdf.BlankNode); ok && w.inline[b] {
+			continue
+		}
+		w.writeSubject(&body, subject)
+	}
+	// Blank nodes that only reference each other in a cycle never get
+	// inlined from a named subject, so write them with their labels
+	for _, subject := range subjects {
+		if b, ok := subject.(rdf.BlankNode); ok && w.inline[b] && !w.written[b] {
+			w.inline[b] = false
+			w.writeSubject(&body, subject)
+		}
+	}
+	return body.String()
+}
+
+// prefixDeclarations returns the @prefix lines for every prefix used so far
+func (w *turtleWriter) prefixDeclarations() string {
+	var out strings.Builder
+	for _, prefix := range sortedKeys(w.used) {
+		out.WriteString("@prefix " + prefix + ": <" + w.used[prefix] + "> .\n")
+	}
+	return out.String()
+}
+
+// findInlineBlankNodes marks blank nodes that are referenced exactly once as
+// an object so they can be written as nested [ ... ] property lists
+func (w *turtleWriter) findInlineBlankNodes() {
+	references := make(map[rdf.BlankNode]int)
+	for _, t := range w.graph.Triples() {
+		if b, ok := t.Object.(rdf.BlankNode); ok {
+			references[b]++
+		}
+	}
+	for b, count := range references {
+		// A blank node that is its own only referrer cannot be nested
+		if count == 1 && !w.shared[b] && !b.Equal(w.graph.Match(nil, nil, b)[0].Subject) {
+			w.inline[b] = true
+		}
+	}
+}
+
+// sortedSubjects returns the subjects of the graph in a stable order
+func (w *turtleWriter) sortedSubjects() []rdf.Term {
+	subjects := w.graph.Subjects()
+	sort.SliceStable(subjects, func(i, j int) bool {
+		return rdf.CompareTerms(subjects[i], subjects[j]) < 0
+	})
+	return subjects
+}
+
+// writeSubject writes a top-level statement block for subject
+func (w *turtleWriter) writeSubject(out *strings.Builder, subject rdf.Term) {
+	if b, ok := subject.(rdf.BlankNode); ok {
+		w.written[b] = true
+	}
+	out.WriteString(w.term(subject))
+	w.writePredicateObjectList(out, subject, "    ")
+	out.WriteString(" .\n")
+}
+
+// writePredicateObjectList writes "p o1, o2; p2 o3" for subject at the given indentation
+func (w *turtleWriter) writePredicateObjectList(out *strings.Builder, subject rdf.Term, indent string) {
+	triples := w.graph.Match(subject, nil, nil)
+	byPredicate := make(map[rdf.IRI][]rdf.Term)
+	var predicates []rdf.IRI
+	for _, t := range triples {
+		p := t.Predicate.(rdf.IRI)
+		if _, seen := byPredicate[p]; !seen {
+			predicates = append(predicates, p)
+		}
+		byPredicate[p] = append(byPredicate[p], t.Object)
+	}
+	sort.Slice(predicates, func(i, j int) bool {
+		if predicates[i] == rdf.RDFType || predicates[j] == rdf.RDFType {
+			return predicates[i] == rdf.RDFType && predicates[j] != rdf.RDFType
+		}
+		return predicates[i] < predicates[j]
+	})
+
+	for i, p := range predicates {
+		if i > 0 {
+			out.WriteString(" ;")
+		}
+		out.WriteString("\n" + indent)
+		if p == rdf.RDFType {
+			out.WriteString("a")
+		} else {
+			out.WriteString(w.term(p))
+		}
+
+		objects := byPredicate[p]
+		sort.SliceStable(objects, func(i, j int) bool {
+			return rdf.CompareTerms(objects[i], objects[j]) < 0
+		})
+		for j, o := range objects {
+			if j > 0 {
+				out.WriteString(",\n" + indent + "    ")
+			} else {
+				out.WriteString(" ")
+			}
+			w.writeObject(out, o, indent)
+		}
+	}
+}
+
+// writeObject writes an object term, nesting inlinable blank nodes
+func (w *turtleWriter) writeObject(out *strings.Builder, object rdf.Term, indent string) {
+	b, ok := object.(rdf.BlankNode)
+	if !ok || !w.inline[b] {
+		out.WriteString(w.term(object))
+		return
+	}
+
+	w.written[b] = true
+	if len(w.graph.Match(b, nil, nil)) == 0 {
+		out.WriteString("[]")
+		return
+	}
+	out.WriteString("[")
+	w.writePredicateObjectList(out, b, indent+"    ")
+	out.WriteString("\n" + indent + "]")
+}
+
+// term returns the Turtle representation of a term
+func (w *turtleWriter) term(term rdf.Term) string {
+	switch t := term.(type) {
+	case rdf.IRI:
+		return w.iri(t)
+	case rdf.Literal:
+		return w.literal(t)
+	default:
+		return term.String()
+	}
+}
+
+// iri returns a relative reference for the base document, a prefixed name
+// when a known namespace matches, otherwise <iri>
+func (w *turtleWriter) iri(iri rdf.IRI) string {
+	if relative, ok := relativeToDocument(string(iri), w.base); ok {
+		return "<" + relative + ">"
+	}
+	namespace, local, ok := rdf.SplitIRI(string(iri))
+	if ok && !strings.HasSuffix(local, ".") {
+		if prefix, known := w.prefixes.PrefixFor(namespace); known {
+			w.used[prefix] = namespace
+			return prefix + ":" + local
+		}
+	}
+	return iri.String()
+}
+
+// literal returns the Turtle representation of a literal, using the
+// abbreviated forms for numbers and booleans when the lexical form allows
+func (w *turtleWriter) literal(l rdf.Literal) string {
+	if l.Language != "" {
+		return l.String()
+	}
+
+	switch dt := l.EffectiveDatatype(); dt {
+	case rdf.XSDString:
+		return l.String()
+	case rdf.XSDNamespace + "integer":
+		if turtleIntegerPattern.MatchString(l.Lexical) {
+			return l.Lexical
+		}
+	case rdf.XSDNamespace + "decimal":
+		if turtleDecimalPattern.MatchString(l.Lexical) {
+			return l.Lexical
+		}
+	case rdf.XSDNamespace + "double":
+		if turtleDoublePattern.MatchString(l.Lexical) {
+			return l.Lexical
+		}
+	case rdf.XSDNamespace + "boolean":
+		if l.Lexical == "true" || l.Lexical == "false" {
+			return l.Lexical
+		}
+	}
+
+	return `"` + rdf.EscapeString(l.Lexical) + `"^^` + w.iri(l.EffectiveDatatype())
+}
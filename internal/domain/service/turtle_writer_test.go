@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+func TestTurtleSerialization(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService()
+
+	ntriples := `<https://example.com/alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://xmlns.com/foaf/0.1/Person> .
+<https://example.com/alice> <http://xmlns.com/foaf/0.1/name> "Alice \"Al\" Smith\nJr." .
+<https://example.com/alice> <http://xmlns.com/foaf/0.1/knows> <https://example.com/carol> .
+<https://example.com/alice> <http://xmlns.com/foaf/0.1/knows> <https://example.com/bob> .
+<https://example.com/alice> <http://xmlns.com/foaf/0.1/age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<https://example.com/alice> <http://xmlns.com/foaf/0.1/birthday> "1980-01-01"^^<http://www.w3.org/2001/XMLSchema#date> .
+<https://example.com/alice> <https://vocab.example.com/terms#active> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<https://example.com/alice> <http://www.w3.org/2006/vcard/ns#hasAddress> _:addr .
+_:addr <http://www.w3.org/2006/vcard/ns#locality> "Kingston"@en .`
+
+	t.Run("writes prefixes, grouping and abbreviations", func(t *testing.T) {
+		// Act
+		result, err := rdfService.ConvertFormat(ntriples, string(service.FormatNTriples), string(service.FormatTurtle))
+
+		// Assert
+		require.NoError(t, err)
+		expected := `@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+@prefix vcard: <http://www.w3.org/2006/vcard/ns#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+<https://example.com/alice>
+    a foaf:Person ;
+    vcard:hasAddress [
+        vcard:locality "Kingston"@en
+    ] ;
+    foaf:age 42 ;
+    foaf:birthday "1980-01-01"^^xsd:date ;
+    foaf:knows <https://example.com/bob>,
+        <https://example.com/carol> ;
+    foaf:name "Alice \"Al\" Smith\nJr." ;
+    <https://vocab.example.com/terms#active> true .
+`
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("is deterministic regardless of input order", func(t *testing.T) {
+		// Arrange
+		graph, err := rdfService.ParseGraph(ntriples, string(service.FormatNTriples))
+		require.NoError(t, err)
+		triples := graph.Triples()
+		reversed := rdf.NewGraph()
+		for i := len(triples) - 1; i >= 0; i-- {
+			reversed.Add(triples[i])
+		}
+
+		// Act
+		first, err := rdfService.SerializeGraph(graph, string(service.FormatTurtle))
+		require.NoError(t, err)
+		second, err := rdfService.SerializeGraph(reversed, string(service.FormatTurtle))
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("parses back to the same graph", func(t *testing.T) {
+		// Arrange
+		original, err := rdfService.ParseGraph(ntriples, string(service.FormatNTriples))
+		require.NoError(t, err)
+
+		// Act
+		result, err := rdfService.SerializeGraph(original, string(service.FormatTurtle))
+		require.NoError(t, err)
+		roundTripped, err := rdfService.ParseGraph(result, string(service.FormatTurtle))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, original.Len(), roundTripped.Len())
+		assert.Len(t, roundTripped.Match(nil, nil, rdf.NewLiteral("Alice \"Al\" Smith\nJr.")), 1)
+		assert.Len(t, roundTripped.Match(nil, nil, rdf.NewTypedLiteral("true", rdf.XSDNamespace+"boolean")), 1)
+		assert.Len(t, roundTripped.Match(nil, nil, rdf.NewLangLiteral("Kingston", "en")), 1)
+	})
+
+	t.Run("uses configured prefixes", func(t *testing.T) {
+		// Arrange
+		configured := service.NewStandardRDFValidationServiceWithOptions(service.RDFServiceOptions{
+			Prefixes: []rdf.Namespace{{Prefix: "ex", IRI: "https://vocab.example.com/terms#"}},
+		})
+
+		// Act
+		result, err := configured.ConvertFormat(ntriples, string(service.FormatNTriples), string(service.FormatTurtle))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Contains(t, result, "@prefix ex: <https://vocab.example.com/terms#> .")
+		assert.Contains(t, result, "ex:active true")
+	})
+}
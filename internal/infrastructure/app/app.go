@@ -3,10 +3,14 @@ package app
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 
 	"go.uber.org/zap"
 
+	"github.com/wepala/vine-pod/internal/application/service"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
 	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
 	"github.com/wepala/vine-pod/internal/infrastructure/server"
 	"github.com/wepala/vine-pod/pkg/logger"
 )
@@ -20,8 +24,15 @@ type App struct {
 
 // New creates a new application instance
 func New(cfg *config.Config, logger logger.Logger) (*App, error) {
+	// Create the resource services
+	rdfService := domainservice.NewStandardRDFValidationService()
+	resourceRepo := repository.NewMemoryResourceRepository(rdfService)
+	blobs := repository.NewFileSystemBlobStore(filepath.Join(cfg.Solid.DataPath, "blobs"))
+	resourceSvc := service.NewResourceService(resourceRepo, blobs, rdfService, logger)
+	solidSvc := service.NewSolidService(cfg, logger, resourceSvc, rdfService)
+
 	// Create Kratos HTTP server
-	srv, err := server.NewSimpleKratosServer(cfg, logger)
+	srv, err := server.NewSimpleKratosServer(cfg, logger, solidSvc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kratos server: %w", err)
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,6 +12,7 @@ import (
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
+	Blob     BlobConfig
 	LogLevel string
 	Solid    SolidConfig
 }
@@ -34,11 +36,55 @@ type DatabaseConfig struct {
 	ConnMaxIdleTime time.Duration // Connection maximum idle time
 }
 
+// BlobConfig holds the configuration of the store for the bytes of non-RDF resources
+type BlobConfig struct {
+	Driver string // "filesystem" (below Solid.DataPath), "memory" or "s3"
+	S3     S3Config
+}
+
+// S3Config holds the configuration of an S3-compatible blob store
+type S3Config struct {
+	Endpoint        string        // Base URL of the service, e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Region          string        // Region requests are signed for
+	Bucket          string        // Bucket holding the blobs
+	Prefix          string        // Prepended to the key of every object
+	AccessKeyID     string        // Access key requests are signed with
+	SecretAccessKey string        // Secret of the access key
+	SessionToken    string        // Token of temporary credentials, if any
+	PathStyle       bool          // Name the bucket in the path rather than the host, as MinIO expects
+	PartSize        int64         // Size of each part of a multipart upload, at least 5 MiB
+	PresignSize     int64         // Blobs of at least this many bytes are downloaded from presigned URLs; 0 serves all through the pod
+	PresignExpiry   time.Duration // How long presigned URLs stay valid
+	DeleteTag       string        // key=value tag marking deleted objects for a bucket lifecycle rule to expire; empty deletes them at once
+}
+
 // SolidConfig holds Solid protocol specific configuration
 type SolidConfig struct {
 	DataPath    string
 	AllowOrigin string
 	EnableCORS  bool
+	RDFPrefixes map[string]string // Extra prefix -> namespace bindings used when serializing RDF
+
+	JSONLDOffline         bool   // Reject JSON-LD contexts that are not bundled or registered
+	JSONLDContextManifest string // JSON file mapping context URLs to local context files
+	JSONLDContextCache    int    // Number of remotely fetched contexts kept in memory
+
+	MaxRDFBytes            int64 // Largest RDF document accepted, in bytes
+	MaxRDFTriples          int   // Most triples accepted in one document
+	MaxJSONLDDepth         int   // Deepest nesting of JSON-LD objects and arrays
+	MaxXMLEntityExpansions int   // Most DTD entity expansions in an RDF/XML document
+	MaxLiteralLength       int   // Longest literal accepted, in bytes
+
+	IdentityStrategy string // How a document's resource ID is chosen: "target", "document", "primary-topic" or "subject"
+
+	TrashRetention     time.Duration // How long deleted resources are kept before they are purged; 0 keeps them forever
+	TrashPurgeInterval time.Duration // How often expired resources are purged
+
+	UploadExpiry        time.Duration // How long an upload is kept without receiving any bytes; 0 keeps it until it is completed or aborted
+	UploadSweepInterval time.Duration // How often expired uploads are discarded
+
+	Storage       string        // Where resources are kept: "memory" or "filesystem" (below DataPath/pods)
+	WatchInterval time.Duration // How often files are checked for edits made outside the pod in filesystem storage; 0 disables it
 }
 
 // Load reads configuration from environment variables and returns Config
@@ -59,11 +105,50 @@ func Load() (*Config, error) {
 			ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", "5m"),
 			ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", "5m"),
 		},
+		Blob: BlobConfig{
+			Driver: getEnv("BLOB_DRIVER", "filesystem"),
+			S3: S3Config{
+				Endpoint:        getEnv("BLOB_S3_ENDPOINT", "https://s3.amazonaws.com"),
+				Region:          getEnv("BLOB_S3_REGION", "us-east-1"),
+				Bucket:          getEnv("BLOB_S3_BUCKET", ""),
+				Prefix:          getEnv("BLOB_S3_PREFIX", "blobs/"),
+				AccessKeyID:     getEnv("BLOB_S3_ACCESS_KEY_ID", ""),
+				SecretAccessKey: getEnv("BLOB_S3_SECRET_ACCESS_KEY", ""),
+				SessionToken:    getEnv("BLOB_S3_SESSION_TOKEN", ""),
+				PathStyle:       getEnvBool("BLOB_S3_PATH_STYLE", false),
+				PartSize:        getEnvInt64("BLOB_S3_PART_SIZE", 16<<20),
+				PresignSize:     getEnvInt64("BLOB_S3_PRESIGN_SIZE", 64<<20),
+				PresignExpiry:   getEnvDuration("BLOB_S3_PRESIGN_EXPIRY", "15m"),
+				DeleteTag:       getEnv("BLOB_S3_DELETE_TAG", ""),
+			},
+		},
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 		Solid: SolidConfig{
 			DataPath:    getEnv("SOLID_DATA_PATH", "./data"),
 			AllowOrigin: getEnv("SOLID_ALLOW_ORIGIN", "*"),
 			EnableCORS:  getEnvBool("SOLID_ENABLE_CORS", true),
+			RDFPrefixes: getEnvMap("SOLID_RDF_PREFIXES"),
+
+			JSONLDOffline:         getEnvBool("SOLID_JSONLD_OFFLINE", true),
+			JSONLDContextManifest: getEnv("SOLID_JSONLD_CONTEXT_MANIFEST", ""),
+			JSONLDContextCache:    getEnvInt("SOLID_JSONLD_CONTEXT_CACHE_SIZE", 64),
+
+			MaxRDFBytes:            getEnvInt64("SOLID_MAX_RDF_BYTES", 16<<20),
+			MaxRDFTriples:          getEnvInt("SOLID_MAX_RDF_TRIPLES", 1_000_000),
+			MaxJSONLDDepth:         getEnvInt("SOLID_MAX_JSONLD_DEPTH", 128),
+			MaxXMLEntityExpansions: getEnvInt("SOLID_MAX_XML_ENTITY_EXPANSIONS", 1_000),
+			MaxLiteralLength:       getEnvInt("SOLID_MAX_LITERAL_LENGTH", 1<<20),
+
+			IdentityStrategy: getEnv("SOLID_IDENTITY_STRATEGY", "target"),
+
+			TrashRetention:     getEnvDuration("SOLID_TRASH_RETENTION", "720h"),
+			TrashPurgeInterval: getEnvDuration("SOLID_TRASH_PURGE_INTERVAL", "1h"),
+
+			UploadExpiry:        getEnvDuration("SOLID_UPLOAD_EXPIRY", "24h"),
+			UploadSweepInterval: getEnvDuration("SOLID_UPLOAD_SWEEP_INTERVAL", "1h"),
+
+			Storage:       getEnv("SOLID_STORAGE", "memory"),
+			WatchInterval: getEnvDuration("SOLID_WATCH_INTERVAL", "5s"),
 		},
 	}
 
@@ -87,6 +172,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := strconv.ParseBool(value); err == nil {
@@ -96,6 +190,19 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// getEnvMap parses a comma separated list of key=value pairs
+func getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || name == "" || value == "" {
+			continue
+		}
+		result[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return result
+}
+
 func getEnvDuration(key, defaultValue string) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := time.ParseDuration(value); err == nil {
@@ -97,3 +97,19 @@ func TestGetEnvDuration(t *testing.T) {
 		t.Errorf("Expected duration %v, got %v", expected, duration)
 	}
 }
+
+func TestGetEnvMap(t *testing.T) {
+	os.Setenv("TEST_MAP", "ex=https://example.com/ns#, org = https://org.example.com/,invalid")
+	defer os.Unsetenv("TEST_MAP")
+
+	values := getEnvMap("TEST_MAP")
+	if len(values) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(values))
+	}
+	if values["ex"] != "https://example.com/ns#" {
+		t.Errorf("Expected ex binding, got '%s'", values["ex"])
+	}
+	if values["org"] != "https://org.example.com/" {
+		t.Errorf("Expected org binding, got '%s'", values["org"])
+	}
+}
@@ -12,8 +12,12 @@ var AppModule = fx.Module("app",
 
 	// Infrastructure modules
 	DatabaseModule,
+	RepositoryModule,
 	// Service modules
 	ServicesModule,
+	TrashModule,
+	UploadModule,
+	WatchModule,
 
 	// Server module (includes lifecycle management)
 	ServerModule,
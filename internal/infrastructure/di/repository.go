@@ -0,0 +1,42 @@
+package di
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"go.uber.org/fx"
+
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	infrarepository "github.com/wepala/vine-pod/internal/infrastructure/repository"
+)
+
+// RepositoryModule provides resource persistence
+var RepositoryModule = fx.Module("repository",
+	fx.Provide(NewResourceRepository, NewBlobStore),
+)
+
+// NewResourceRepository creates the configured repository that stores resource events
+func NewResourceRepository(cfg *config.Config, blobs repository.BlobStore, rdfService domainservice.RDFValidationService) (repository.ResourceRepository, error) {
+	switch cfg.Solid.Storage {
+	case "memory":
+		return infrarepository.NewMemoryResourceRepository(rdfService), nil
+	case "filesystem":
+		return infrarepository.NewFileSystemResourceRepository(filepath.Join(cfg.Solid.DataPath, "pods"), blobs, rdfService), nil
+	}
+	return nil, fmt.Errorf("unknown storage %q", cfg.Solid.Storage)
+}
+
+// NewBlobStore creates the configured store for the bytes of non-RDF resources
+func NewBlobStore(cfg *config.Config) (repository.BlobStore, error) {
+	switch cfg.Blob.Driver {
+	case "filesystem":
+		return infrarepository.NewFileSystemBlobStore(filepath.Join(cfg.Solid.DataPath, "blobs")), nil
+	case "memory":
+		return infrarepository.NewMemoryBlobStore(), nil
+	case "s3":
+		return infrarepository.NewS3BlobStore(cfg.Blob.S3)
+	}
+	return nil, fmt.Errorf("unknown blob store %q", cfg.Blob.Driver)
+}
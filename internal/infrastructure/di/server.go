@@ -6,6 +6,7 @@ import (
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
+	"github.com/wepala/vine-pod/internal/application/service"
 	"github.com/wepala/vine-pod/internal/infrastructure/config"
 	"github.com/wepala/vine-pod/internal/infrastructure/server"
 	"github.com/wepala/vine-pod/pkg/logger"
@@ -18,8 +19,8 @@ var ServerModule = fx.Module("server",
 )
 
 // NewKratosServer creates a new Kratos server instance
-func NewKratosServer(cfg *config.Config, logger logger.Logger) (*server.SimpleKratosServer, error) {
-	return server.NewSimpleKratosServer(cfg, logger)
+func NewKratosServer(cfg *config.Config, logger logger.Logger, solidSvc *service.SolidService) (*server.SimpleKratosServer, error) {
+	return server.NewSimpleKratosServer(cfg, logger, solidSvc)
 }
 
 // RegisterServerLifecycle registers server lifecycle hooks with Fx
@@ -1,9 +1,14 @@
 package di
 
 import (
+	"sort"
+
 	"go.uber.org/fx"
 
 	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/domain/rdf"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	domainservice "github.com/wepala/vine-pod/internal/domain/service"
 	"github.com/wepala/vine-pod/internal/infrastructure/config"
 	"github.com/wepala/vine-pod/pkg/logger"
 )
@@ -14,6 +19,8 @@ var ServicesModule = fx.Module("services",
 		NewHealthService,
 		NewVersionService,
 		NewSolidService,
+		NewResourceService,
+		NewRDFValidationService,
 	),
 )
 
@@ -28,6 +35,53 @@ func NewVersionService(cfg *config.Config, logger logger.Logger) *service.Versio
 }
 
 // NewSolidService creates a new solid service
-func NewSolidService(cfg *config.Config, logger logger.Logger) *service.SolidService {
-	return service.NewSolidService(cfg, logger)
+func NewSolidService(cfg *config.Config, logger logger.Logger, resources *service.ResourceService, rdfService domainservice.RDFValidationService) *service.SolidService {
+	return service.NewSolidService(cfg, logger, resources, rdfService)
+}
+
+// NewResourceService creates the service that creates and replaces
+// resources, keeping uploads for the configured expiry
+func NewResourceService(cfg *config.Config, repo repository.ResourceRepository, blobs repository.BlobStore, rdfService domainservice.RDFValidationService, logger logger.Logger) *service.ResourceService {
+	resources := service.NewResourceService(repo, blobs, rdfService, logger)
+	resources.ExpireUploadsAfter(cfg.Solid.UploadExpiry)
+	return resources
+}
+
+// NewRDFValidationService creates the RDF validation service with the configured
+// prefixes, JSON-LD context loader, parse limits and identity strategy
+func NewRDFValidationService(cfg *config.Config) (domainservice.RDFValidationService, error) {
+	prefixes := make([]rdf.Namespace, 0, len(cfg.Solid.RDFPrefixes))
+	for prefix, namespace := range cfg.Solid.RDFPrefixes {
+		prefixes = append(prefixes, rdf.Namespace{Prefix: prefix, IRI: namespace})
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return prefixes[i].Prefix < prefixes[j].Prefix
+	})
+
+	identity, err := domainservice.ParseIdentityStrategy(cfg.Solid.IdentityStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	loader, err := domainservice.NewContextDocumentLoader(domainservice.ContextLoaderOptions{
+		Offline:      cfg.Solid.JSONLDOffline,
+		ManifestPath: cfg.Solid.JSONLDContextManifest,
+		CacheSize:    cfg.Solid.JSONLDContextCache,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return domainservice.NewStandardRDFValidationServiceWithOptions(domainservice.RDFServiceOptions{
+		Prefixes:       prefixes,
+		DocumentLoader: loader,
+		Limits: &domainservice.ParseLimits{
+			MaxBytes:            cfg.Solid.MaxRDFBytes,
+			MaxTriples:          cfg.Solid.MaxRDFTriples,
+			MaxJSONDepth:        cfg.Solid.MaxJSONLDDepth,
+			MaxEntityExpansions: cfg.Solid.MaxXMLEntityExpansions,
+			MaxLiteralLength:    cfg.Solid.MaxLiteralLength,
+		},
+		Identity: identity,
+	}), nil
 }
@@ -0,0 +1,46 @@
+package di
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+// TrashModule purges deleted resources once their retention window ends
+var TrashModule = fx.Module("trash",
+	fx.Provide(NewTrashPurger),
+	fx.Invoke(RegisterTrashLifecycle),
+)
+
+// NewTrashPurger creates the purger configured by the trash settings
+func NewTrashPurger(cfg *config.Config, resources *service.ResourceService, logger logger.Logger) *service.TrashPurger {
+	return service.NewTrashPurger(resources, cfg.Solid.TrashRetention, cfg.Solid.TrashPurgeInterval, logger)
+}
+
+// RegisterTrashLifecycle runs the purger for as long as the application
+func RegisterTrashLifecycle(lc fx.Lifecycle, purger *service.TrashPurger) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				purger.Run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
@@ -0,0 +1,46 @@
+package di
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+// UploadModule discards uploads that stopped receiving bytes once they expire
+var UploadModule = fx.Module("upload",
+	fx.Provide(NewUploadSweeper),
+	fx.Invoke(RegisterUploadLifecycle),
+)
+
+// NewUploadSweeper creates the sweeper configured by the upload settings
+func NewUploadSweeper(cfg *config.Config, resources *service.ResourceService, logger logger.Logger) *service.UploadSweeper {
+	return service.NewUploadSweeper(resources, cfg.Solid.UploadSweepInterval, logger)
+}
+
+// RegisterUploadLifecycle runs the sweeper for as long as the application
+func RegisterUploadLifecycle(lc fx.Lifecycle, sweeper *service.UploadSweeper) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				sweeper.Run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
@@ -0,0 +1,49 @@
+package di
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+
+	"github.com/wepala/vine-pod/internal/application/service"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/pkg/logger"
+)
+
+// WatchModule brings edits made to stored files from outside the pod into the pod
+var WatchModule = fx.Module("watch",
+	fx.Provide(NewEditWatcher),
+	fx.Invoke(RegisterWatchLifecycle),
+)
+
+// NewEditWatcher creates the watcher of the repository, which only has
+// edits to watch for when it keeps resources in files
+func NewEditWatcher(cfg *config.Config, resources *service.ResourceService, repo repository.ResourceRepository, logger logger.Logger) *service.EditWatcher {
+	source, _ := repo.(repository.EditSource)
+	return service.NewEditWatcher(resources, source, cfg.Solid.WatchInterval, logger)
+}
+
+// RegisterWatchLifecycle runs the watcher for as long as the application
+func RegisterWatchLifecycle(lc fx.Lifecycle, watcher *service.EditWatcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				watcher.Run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
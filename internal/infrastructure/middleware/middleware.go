@@ -65,8 +65,8 @@ func CORS(cfg *config.Config) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if cfg.Solid.EnableCORS {
 				w.Header().Set("Access-Control-Allow-Origin", cfg.Solid.AllowOrigin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH, MOVE, COPY")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Destination, Range, If-Range, Upload-Length, Upload-Offset")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 				// Handle preflight requests
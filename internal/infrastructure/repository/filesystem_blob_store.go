@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/wepala/vine-pod/internal/domain/repository"
+)
+
+// stagedSuffix is appended to the file of a blob while it is staged
+const stagedSuffix = ".part"
+
+// FileSystemBlobStore keeps blobs as files below a root directory, spread
+// over sub-directories named by the first two characters of their keys
+type FileSystemBlobStore struct {
+	root string
+
+	// staging serializes appends to each staged blob
+	staging sync.Map
+
+	// records serializes conditional writes
+	records sync.Mutex
+}
+
+// NewFileSystemBlobStore creates a blob store rooted at root. Directories
+// are created as blobs are stored.
+func NewFileSystemBlobStore(root string) *FileSystemBlobStore {
+	return &FileSystemBlobStore{root: root}
+}
+
+// Put streams r to a temporary file that is renamed to the blob's path once
+// complete, so readers never see a partial blob
+func (s *FileSystemBlobStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create blob %s: %w", key, err)
+	}
+	written, err := io.Copy(file, &contextReader{ctx: ctx, r: r})
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return 0, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	return written, nil
+}
+
+// Get opens the file of the blob stored under key
+func (s *FileSystemBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob %s: %w", key, err)
+	}
+	return file, nil
+}
+
+// GetRange opens the file of the blob stored under key at offset
+func (s *FileSystemBlobStore) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	reader, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	file := reader.(*os.File)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(file, length), Closer: file}, nil
+}
+
+// Append writes r to the end of the staged file of the blob, syncing what
+// was written even when reading fails
+func (s *FileSystemBlobStore) Append(ctx context.Context, key string, offset int64, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	lock, _ := s.staging.LoadOrStore(key, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	flags := os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return 0, fmt.Errorf("failed to create blob directory: %w", err)
+		}
+		flags |= os.O_CREATE
+	}
+	file, err := os.OpenFile(path+stagedSuffix, flags, 0o644)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("%w: %s is not staged", repository.ErrBlobOffset, key)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stage blob %s: %w", key, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stage blob %s: %w", key, err)
+	}
+	if info.Size() != offset {
+		return info.Size(), fmt.Errorf("%w: %s holds %d bytes, not %d", repository.ErrBlobOffset, key, info.Size(), offset)
+	}
+
+	written, err := io.Copy(file, &contextReader{ctx: ctx, r: r})
+	if syncErr := file.Sync(); err == nil {
+		err = syncErr
+	}
+	size := offset + written
+	if err != nil {
+		return size, fmt.Errorf("failed to stage blob %s: %w", key, err)
+	}
+	return size, nil
+}
+
+// Staged returns the size of the staged file of the blob
+func (s *FileSystemBlobStore) Staged(ctx context.Context, key string) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path + stagedSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stage blob %s: %w", key, err)
+	}
+	return info.Size(), nil
+}
+
+// Commit renames the staged file of the blob to its path
+func (s *FileSystemBlobStore) Commit(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(path+stagedSuffix, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to commit blob %s: %w", key, err)
+	}
+	s.staging.Delete(key)
+	return nil
+}
+
+// Delete removes the file of the blob stored or staged under key
+func (s *FileSystemBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	for _, file := range []string{path, path + stagedSuffix} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete blob %s: %w", key, err)
+		}
+	}
+	s.staging.Delete(key)
+	return nil
+}
+
+// GetTagged reads the file of the blob stored under key and tags it with
+// the digest of its bytes
+func (s *FileSystemBlobStore) GetTagged(ctx context.Context, key string) ([]byte, string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+	return data, blobTag(data), nil
+}
+
+// PutIf replaces the file of the blob when its bytes still have tag. The
+// check and the write are atomic for the writers of this process, which is
+// the only one using the directory.
+func (s *FileSystemBlobStore) PutIf(ctx context.Context, key string, tag string, data []byte) error {
+	s.records.Lock()
+	defer s.records.Unlock()
+
+	_, current, err := s.GetTagged(ctx, key)
+	switch {
+	case errors.Is(err, repository.ErrBlobNotFound):
+		if tag != "" {
+			return fmt.Errorf("%w: %s", repository.ErrBlobChanged, key)
+		}
+	case err != nil:
+		return err
+	case current != tag:
+		return fmt.Errorf("%w: %s", repository.ErrBlobChanged, key)
+	}
+	_, err = s.Put(ctx, key, bytes.NewReader(data))
+	return err
+}
+
+// path returns the file of the blob stored under key
+func (s *FileSystemBlobStore) path(key string) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, key[:2], key), nil
+}
+
+// checkBlobKey limits keys to at least three letters, digits, '-' and '_',
+// so they cannot escape the place blobs are kept
+func checkBlobKey(key string) error {
+	if len(key) < 3 {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
+
+// blobTag returns the tag of a blob's bytes for conditional writes
+func blobTag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// contextReader stops reading once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// limitedReadCloser reads part of a file and closes the whole file
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+var _ repository.BlobStore = (*FileSystemBlobStore)(nil)
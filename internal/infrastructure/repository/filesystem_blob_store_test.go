@@ -0,0 +1,166 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+)
+
+func TestFileSystemBlobStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("streams blobs to files and back", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		store := repository.NewFileSystemBlobStore(root)
+
+		// Act
+		written, err := store.Put(ctx, "0a1b2c", strings.NewReader("hello"))
+		require.NoError(t, err)
+		reader, err := store.Get(ctx, "0a1b2c")
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), written)
+		assert.Equal(t, "hello", string(data))
+		assert.FileExists(t, filepath.Join(root, "0a", "0a1b2c"))
+	})
+
+	t.Run("leaves nothing behind when a write fails", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		store := repository.NewFileSystemBlobStore(root)
+		failing := io.MultiReader(strings.NewReader("partial"), &failingReader{})
+
+		// Act
+		_, err := store.Put(ctx, "0a1b2c", failing)
+
+		// Assert
+		require.Error(t, err)
+		entries, err := os.ReadDir(filepath.Join(root, "0a"))
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		_, err = store.Get(ctx, "0a1b2c")
+		assert.ErrorIs(t, err, domainrepository.ErrBlobNotFound)
+	})
+
+	t.Run("deletes blobs", func(t *testing.T) {
+		// Arrange
+		store := repository.NewFileSystemBlobStore(t.TempDir())
+		_, err := store.Put(ctx, "0a1b2c", strings.NewReader("hello"))
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, store.Delete(ctx, "0a1b2c"))
+
+		// Assert
+		_, err = store.Get(ctx, "0a1b2c")
+		assert.ErrorIs(t, err, domainrepository.ErrBlobNotFound)
+		assert.NoError(t, store.Delete(ctx, "0a1b2c"))
+	})
+
+	t.Run("reads ranges of blobs", func(t *testing.T) {
+		// Arrange
+		store := repository.NewFileSystemBlobStore(t.TempDir())
+		_, err := store.Put(ctx, "0a1b2c", strings.NewReader("hello world"))
+		require.NoError(t, err)
+
+		// Act
+		reader, err := store.GetRange(ctx, "0a1b2c", 6, 3)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "wor", string(data))
+	})
+
+	t.Run("stages appended bytes until they are committed", func(t *testing.T) {
+		// Arrange
+		store := repository.NewFileSystemBlobStore(t.TempDir())
+		_, err := store.Append(ctx, "0a1b2c", 0, strings.NewReader("hello "))
+		require.NoError(t, err)
+
+		// Act
+		interrupted, appendErr := store.Append(ctx, "0a1b2c", 6, io.MultiReader(strings.NewReader("wo"), &failingReader{}))
+		staged, err := store.Staged(ctx, "0a1b2c")
+		require.NoError(t, err)
+		_, getErr := store.Get(ctx, "0a1b2c")
+		_, mismatch := store.Append(ctx, "0a1b2c", 6, strings.NewReader("world"))
+		size, err := store.Append(ctx, "0a1b2c", staged, strings.NewReader("rld"))
+		require.NoError(t, err)
+		require.NoError(t, store.Commit(ctx, "0a1b2c"))
+		reader, err := store.Get(ctx, "0a1b2c")
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Error(t, appendErr)
+		assert.Equal(t, int64(8), interrupted, "bytes read before the failure are kept")
+		assert.Equal(t, int64(8), staged)
+		assert.ErrorIs(t, getErr, domainrepository.ErrBlobNotFound, "staged blobs are not visible")
+		assert.ErrorIs(t, mismatch, domainrepository.ErrBlobOffset)
+		assert.Equal(t, int64(11), size)
+		assert.Equal(t, "hello world", string(data))
+		_, err = store.Staged(ctx, "0a1b2c")
+		assert.ErrorIs(t, err, domainrepository.ErrBlobNotFound)
+	})
+
+	t.Run("writes records only while they are unchanged", func(t *testing.T) {
+		// Arrange
+		store := repository.NewFileSystemBlobStore(t.TempDir())
+		require.NoError(t, store.PutIf(ctx, "0a1b2c_refs", "", []byte("1")))
+		data, tag, err := store.GetTagged(ctx, "0a1b2c_refs")
+		require.NoError(t, err)
+
+		// Act
+		created := store.PutIf(ctx, "0a1b2c_refs", "", []byte("1"))
+		updated := store.PutIf(ctx, "0a1b2c_refs", tag, []byte("2"))
+		stale := store.PutIf(ctx, "0a1b2c_refs", tag, []byte("3"))
+		missing := store.PutIf(ctx, "3c2b1a_refs", tag, []byte("1"))
+		latest, _, getErr := store.GetTagged(ctx, "0a1b2c_refs")
+
+		// Assert
+		assert.Equal(t, "1", string(data))
+		assert.ErrorIs(t, created, domainrepository.ErrBlobChanged)
+		assert.NoError(t, updated)
+		assert.ErrorIs(t, stale, domainrepository.ErrBlobChanged)
+		assert.ErrorIs(t, missing, domainrepository.ErrBlobChanged)
+		require.NoError(t, getErr)
+		assert.Equal(t, "2", string(latest))
+	})
+
+	t.Run("rejects keys that could escape the root", func(t *testing.T) {
+		// Arrange
+		store := repository.NewFileSystemBlobStore(t.TempDir())
+
+		// Act
+		_, err := store.Put(ctx, "../../etc/passwd", strings.NewReader("x"))
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+// failingReader fails every read
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -0,0 +1,1087 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/event"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+const (
+	// sidecarSuffix ends the name of the file holding the metadata and
+	// events of a resource. A container's sidecar is in its own directory.
+	sidecarSuffix = ".vine.json"
+
+	// tempSuffix ends the name of files being written
+	tempSuffix = ".vine-tmp"
+)
+
+// fileExtensions maps media types to the extension of the files holding
+// them, where Go's own table is missing or ambiguous
+var fileExtensions = map[string]string{
+	"text/turtle":           ".ttl",
+	"application/ld+json":   ".jsonld",
+	"application/rdf+xml":   ".rdf",
+	"application/n-triples": ".nt",
+	"application/n-quads":   ".nq",
+	"application/trig":      ".trig",
+	"text/n3":               ".n3",
+	"text/plain":            ".txt",
+	"text/html":             ".html",
+	"text/markdown":         ".md",
+	"text/csv":              ".csv",
+	"application/json":      ".json",
+	"application/xml":       ".xml",
+	"application/pdf":       ".pdf",
+	"image/png":             ".png",
+	"image/jpeg":            ".jpg",
+	"image/gif":             ".gif",
+	"image/svg+xml":         ".svg",
+	"image/webp":            ".webp",
+	"audio/mpeg":            ".mp3",
+	"video/mp4":             ".mp4",
+}
+
+// FileSystemResourceRepository mirrors pods on disk: containers are
+// directories and resources are files, named with an extension for their
+// media type. Below root, a resource at https://pod.example.com/notes/today
+// holding Turtle is the file https/pod.example.com/notes/today$.ttl, and a
+// file whose name already has the right extension keeps it. A container's
+// own triples are in the file "$.ttl" in its directory. Each resource has a
+// sidecar file, named after it with the suffix .vine.json, holding its URI,
+// media type and events, so pods can be copied with rsync and edited in
+// place. Deleted resources keep their sidecar but not their file.
+type FileSystemResourceRepository struct {
+	mu         sync.RWMutex
+	root       string
+	blobs      repository.BlobStore
+	rdfService service.RDFValidationService
+}
+
+// sidecar is the metadata of a resource stored next to its file
+type sidecar struct {
+	URI         string         `json:"uri"`
+	ID          string         `json:"id"`
+	ContentType string         `json:"contentType,omitempty"`
+	File        string         `json:"file,omitempty"` // Name of the file holding the resource; empty once it is deleted
+	Key         string         `json:"key,omitempty"`  // Blob the file was copied from, for non-RDF resources
+	Size        int64          `json:"size,omitempty"`
+	Modified    time.Time      `json:"modified,omitempty"`
+	SHA256      string         `json:"sha256,omitempty"`
+	Deleted     time.Time      `json:"deleted,omitempty"` // When the resource was deleted; zero while it is live
+	Events      []event.Record `json:"events"`
+}
+
+// location is where a resource is kept: its directory, and its name within
+// it, which is empty for containers
+type location struct {
+	dir  string
+	name string
+}
+
+// sidecar returns the path of the sidecar of the resource
+func (l location) sidecar() string {
+	return filepath.Join(l.dir, l.name+sidecarSuffix)
+}
+
+// NewFileSystemResourceRepository creates a repository keeping pods below
+// root. The bytes of non-RDF resources are copied from blobs into their files.
+func NewFileSystemResourceRepository(root string, blobs repository.BlobStore, rdfService service.RDFValidationService) *FileSystemResourceRepository {
+	return &FileSystemResourceRepository{
+		root:       root,
+		blobs:      blobs,
+		rdfService: rdfService,
+	}
+}
+
+// Save appends the resource's uncommitted events to its sidecar and writes
+// its current state to its file
+func (r *FileSystemResourceRepository) Save(ctx context.Context, resource entity.Resource) error {
+	uri := resource.GetURI()
+	if uri == "" {
+		return errors.New("cannot save a resource without a URI")
+	}
+	loc, err := r.locate(uri)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car, err := r.readSidecar(loc)
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		car = &sidecar{URI: uri}
+	} else if err != nil {
+		return err
+	}
+	car.ID = resource.ID()
+	if err := r.append(ctx, nil, loc, car, resource); err != nil {
+		return err
+	}
+	resource.MarkEventsAsCommitted()
+	return nil
+}
+
+// SaveAll checks that the sidecars of moved resources exist and their new
+// URIs are free before applying any change. Moved resources are written at
+// their new location before what they left behind is removed. Every file
+// the batch replaces or removes is kept until it is done, so a failure part
+// way puts them back.
+func (r *FileSystemResourceRepository) SaveAll(ctx context.Context, changes []repository.Change) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vacated := make(map[string]bool)
+	for _, change := range changes {
+		switch {
+		case change.Discard:
+			vacated[change.Resource.GetURI()] = true
+		case change.From != "":
+			vacated[change.From] = true
+		}
+	}
+	locations := make([]location, len(changes))
+	for i, change := range changes {
+		uri := change.Resource.GetURI()
+		if uri == "" {
+			return errors.New("cannot save a resource without a URI")
+		}
+		loc, err := r.locate(uri)
+		if err != nil {
+			return err
+		}
+		locations[i] = loc
+		if change.Discard {
+			if _, err := r.readSidecar(loc); err != nil {
+				return fmt.Errorf("cannot discard %s: %w", uri, err)
+			}
+			continue
+		}
+		if change.From == "" || change.From == uri {
+			continue
+		}
+		from, err := r.locate(change.From)
+		if err != nil {
+			return err
+		}
+		if _, err := r.readSidecar(from); err != nil {
+			return fmt.Errorf("cannot move %s: %w", change.From, err)
+		}
+		if _, err := os.Stat(loc.sidecar()); err == nil && !vacated[uri] {
+			return fmt.Errorf("cannot move %s to %s: %w", change.From, uri, repository.ErrResourceExists)
+		}
+	}
+
+	j := &journal{saved: make(map[string]string)}
+	defer func() {
+		if err != nil {
+			j.undo()
+		}
+	}()
+
+	// Discarded resources go first, so their URIs are free for the batch
+	var vacatedDirs []string
+	for i, change := range changes {
+		if !change.Discard {
+			continue
+		}
+		car, err := r.readSidecar(locations[i])
+		if err != nil {
+			return err
+		}
+		if err := r.remove(j, locations[i], car.File); err != nil {
+			return err
+		}
+		if locations[i].name == "" {
+			vacatedDirs = append(vacatedDirs, locations[i].dir)
+		}
+	}
+
+	// Read every moved sidecar before writing any, so resources can move
+	// into URIs vacated by the same batch
+	type source struct {
+		loc  location
+		file string
+	}
+	moved := make(map[string]*sidecar)
+	var sources []source
+	for _, change := range changes {
+		uri := change.Resource.GetURI()
+		if change.Discard || change.From == "" || change.From == uri {
+			continue
+		}
+		from, _ := r.locate(change.From)
+		car, err := r.readSidecar(from)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source{loc: from, file: car.File})
+		if car.ID == change.From {
+			car.ID = uri
+		}
+		car.URI = uri
+		car.File, car.Key, car.SHA256 = "", "", ""
+		moved[uri] = car
+	}
+
+	written := make(map[string]*sidecar)
+	for i, change := range changes {
+		if change.Discard {
+			continue
+		}
+		car, ok := moved[change.Resource.GetURI()]
+		if !ok {
+			var err error
+			if car, err = r.readSidecar(locations[i]); errors.Is(err, repository.ErrResourceNotFound) {
+				car = &sidecar{URI: change.Resource.GetURI()}
+			} else if err != nil {
+				return err
+			}
+			car.ID = change.Resource.ID()
+		}
+		if err := r.append(ctx, j, locations[i], car, change.Resource); err != nil {
+			return err
+		}
+		written[locations[i].sidecar()] = car
+	}
+
+	// Remove what moved resources left behind, but not what took its place
+	for _, src := range sources {
+		if car, ok := written[src.loc.sidecar()]; ok {
+			if car.File != src.file {
+				if err := j.removeFile(src.loc.dir, src.file); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := r.remove(j, src.loc, src.file); err != nil {
+			return err
+		}
+		if src.loc.name == "" {
+			vacatedDirs = append(vacatedDirs, src.loc.dir)
+		}
+	}
+
+	j.commit()
+	for _, change := range changes {
+		if !change.Discard {
+			change.Resource.MarkEventsAsCommitted()
+		}
+	}
+	// Directories of moved containers go once their members have moved out
+	sort.Sort(sort.Reverse(sort.StringSlice(vacatedDirs)))
+	for _, dir := range vacatedDirs {
+		os.Remove(dir)
+	}
+	return nil
+}
+
+// GetByID rebuilds the resource with the given aggregate ID
+func (r *FileSystemResourceRepository) GetByID(ctx context.Context, id string) (entity.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	car, err := r.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.rebuild(car)
+}
+
+// GetByURI rebuilds the resource stored at uri
+func (r *FileSystemResourceRepository) GetByURI(ctx context.Context, uri string) (entity.Resource, error) {
+	loc, err := r.locate(uri)
+	if err != nil {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	car, err := r.readSidecar(loc)
+	if err != nil {
+		return nil, err
+	}
+	return r.rebuild(car)
+}
+
+// Delete removes a resource, its file and its sidecar
+func (r *FileSystemResourceRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	car, err := r.find(id)
+	if err != nil {
+		return err
+	}
+	loc, err := r.locate(car.URI)
+	if err != nil {
+		return err
+	}
+	if err := r.remove(nil, loc, car.File); err != nil {
+		return err
+	}
+	if loc.name == "" {
+		// Only an empty directory goes; members still there keep it
+		os.Remove(loc.dir)
+	}
+	return nil
+}
+
+// List returns resources ordered by URI
+func (r *FileSystemResourceRepository) List(ctx context.Context, limit, offset int) ([]entity.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cars, err := r.sidecars()
+	if err != nil {
+		return nil, err
+	}
+	if offset >= len(cars) {
+		return []entity.Resource{}, nil
+	}
+	cars = cars[offset:]
+	if limit > 0 && limit < len(cars) {
+		cars = cars[:limit]
+	}
+	return r.rebuildAll(cars)
+}
+
+// FindByContainer returns the direct children of containerURI ordered by
+// URI: the sidecars in its directory and the containers below it
+func (r *FileSystemResourceRepository) FindByContainer(ctx context.Context, containerURI string) ([]entity.Resource, error) {
+	if !strings.HasSuffix(containerURI, "/") {
+		containerURI += "/"
+	}
+	container, err := r.locate(containerURI)
+	if err != nil {
+		return []entity.Resource{}, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries, err := os.ReadDir(container.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []entity.Resource{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cars []*sidecar
+	for _, entry := range entries {
+		loc := location{dir: container.dir, name: strings.TrimSuffix(entry.Name(), sidecarSuffix)}
+		switch {
+		case entry.IsDir():
+			loc = location{dir: filepath.Join(container.dir, entry.Name())}
+		case loc.name == "" || loc.name == entry.Name():
+			continue
+		}
+		car, err := r.readSidecar(loc)
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cars = append(cars, car)
+	}
+	sort.Slice(cars, func(i, j int) bool { return cars[i].URI < cars[j].URI })
+	return r.rebuildAll(cars)
+}
+
+// FindDeletedBefore returns the tombstones deleted before cutoff ordered by
+// URI, going by the deletion time recorded in their sidecars so that only
+// their events are replayed
+func (r *FileSystemResourceRepository) FindDeletedBefore(ctx context.Context, cutoff time.Time) ([]entity.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cars, err := r.sidecars()
+	if err != nil {
+		return nil, err
+	}
+	var deleted []*sidecar
+	for _, car := range cars {
+		if car.Deleted.IsZero() && car.File == "" {
+			// Sidecars written before deletion times were recorded
+			events, err := car.events()
+			if err != nil {
+				return nil, err
+			}
+			car.Deleted = deletedAt(car.Deleted, events)
+		}
+		if !car.Deleted.IsZero() && car.Deleted.Before(cutoff) {
+			deleted = append(deleted, car)
+		}
+	}
+	return r.rebuildAll(deleted)
+}
+
+// LoadEvents returns every event of the resource with the given aggregate ID
+func (r *FileSystemResourceRepository) LoadEvents(ctx context.Context, aggregateID string) ([]domain.Event, error) {
+	return r.LoadEventsFromVersion(ctx, aggregateID, 0)
+}
+
+// LoadEventsFromVersion returns the events of a resource from version onwards
+func (r *FileSystemResourceRepository) LoadEventsFromVersion(ctx context.Context, aggregateID string, version int) ([]domain.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	car, err := r.find(aggregateID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := car.events()
+	if err != nil {
+		return nil, err
+	}
+	var from []domain.Event
+	for _, evt := range events {
+		if evt.Version() >= version {
+			from = append(from, evt)
+		}
+	}
+	return from, nil
+}
+
+// ExternalEdits compares the files below root with their sidecars. A file
+// that changed, a file without a sidecar, a directory without one and a
+// missing file of a live resource are each an edit.
+func (r *FileSystemResourceRepository) ExternalEdits(ctx context.Context) ([]repository.ExternalEdit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var edits []repository.ExternalEdit
+	err := filepath.WalkDir(r.root, func(dir string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && dir == r.root {
+			return filepath.SkipAll
+		}
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		containerURI, ok := r.containerURI(dir)
+		if !ok {
+			return nil
+		}
+		_, err = os.Stat(filepath.Join(dir, sidecarSuffix))
+		managed := err == nil
+		if !managed && dir != r.root && strings.HasPrefix(entry.Name(), ".") {
+			// Hidden directories such as .git are left alone unless the pod made them
+			return filepath.SkipDir
+		}
+
+		found, err := r.editsIn(dir, containerURI)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, found...)
+		if !managed && !hasContainerFile(found, containerURI) && strings.Count(strings.TrimSuffix(containerURI, "/"), "/") > 2 {
+			edits = append(edits, repository.ExternalEdit{URI: containerURI, Version: "directory"})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// editsIn returns the edits to the resources kept in dir, which is the
+// directory of the container at containerURI
+func (r *FileSystemResourceRepository) editsIn(dir string, containerURI string) ([]repository.ExternalEdit, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []repository.ExternalEdit
+	cars := make(map[string]*sidecar)
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), sidecarSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		car, err := r.readSidecar(location{dir: dir, name: name})
+		if err != nil {
+			return nil, err
+		}
+		cars[name] = car
+		if car.File == "" {
+			continue
+		}
+		referenced[car.File] = true
+
+		path := filepath.Join(dir, car.File)
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			edits = append(edits, repository.ExternalEdit{URI: car.URI, Removed: true, Version: "removed"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if changed, err := car.changed(path, info); err != nil || !changed {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		edits = append(edits, fileEdit(car.URI, car.ContentType, path, info))
+	}
+
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || referenced[file] || strings.HasSuffix(file, sidecarSuffix) || ignoredFile(file) {
+			continue
+		}
+		name, mediaType := parseFileName(file)
+		if car, ok := cars[name]; ok && car.File != "" {
+			// The resource is already kept in another file
+			continue
+		}
+		uri := containerURI + (&url.URL{Path: name}).EscapedPath()
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, fileEdit(uri, mediaType, filepath.Join(dir, file), info))
+	}
+	return edits, nil
+}
+
+// append adds the resource's uncommitted events to car and writes the
+// resource's state to its file, keeping what it replaces in j. It fails
+// with ErrResourceConflict when car holds events stored since the resource
+// was read. Saving nothing new leaves a file as it is, even when it was
+// edited since. The caller marks the events as committed.
+func (r *FileSystemResourceRepository) append(ctx context.Context, j *journal, loc location, car *sidecar, resource entity.Resource) error {
+	stored := 0
+	if len(car.Events) > 0 {
+		stored = car.Events[len(car.Events)-1].Version
+	}
+	if err := checkVersion(resource, stored); err != nil {
+		return err
+	}
+	uncommitted := resource.UncommittedEvents()
+	if len(uncommitted) == 0 && car.File != "" {
+		return nil
+	}
+	for _, evt := range uncommitted {
+		record, err := event.NewRecord(evt)
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %w", car.URI, err)
+		}
+		car.Events = append(car.Events, record)
+	}
+	car.Deleted = deletedAt(car.Deleted, uncommitted)
+	if err := j.mkdirAll(loc.dir); err != nil {
+		return fmt.Errorf("failed to save %s: %w", car.URI, err)
+	}
+	if err := r.writeFile(ctx, j, loc, car); err != nil {
+		return fmt.Errorf("failed to save %s: %w", car.URI, err)
+	}
+	if err := r.writeSidecar(j, loc, car); err != nil {
+		return fmt.Errorf("failed to save %s: %w", car.URI, err)
+	}
+	return nil
+}
+
+// writeFile writes the current state of the resource recorded by car to
+// its file, unless the file already holds it, and records the file in car
+func (r *FileSystemResourceRepository) writeFile(ctx context.Context, j *journal, loc location, car *sidecar) error {
+	current, err := r.rebuild(car)
+	if err != nil {
+		return err
+	}
+	previous := car.File
+	if current.IsDeleted() {
+		car.File, car.Key, car.Size, car.Modified, car.SHA256 = "", "", 0, time.Time{}, ""
+		return j.removeFile(loc.dir, previous)
+	}
+
+	car.ContentType = current.GetContentType()
+	file := fileName(loc.name, car.ContentType)
+	var body io.Reader
+	if current.IsNonRDF() {
+		key := current.GetContent().Key
+		if file == previous && key == car.Key {
+			return nil
+		}
+		reader, err := r.blobs.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		body, car.Key = reader, key
+	} else {
+		hash := sha256.Sum256([]byte(current.GetData()))
+		if file == previous && hex.EncodeToString(hash[:]) == car.SHA256 {
+			return nil
+		}
+		body, car.Key = strings.NewReader(current.GetData()), ""
+	}
+
+	path := filepath.Join(loc.dir, file)
+	if car.SHA256, err = j.write(path, body); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	car.File, car.Size, car.Modified = file, info.Size(), info.ModTime()
+	if previous != "" && previous != file {
+		return j.removeFile(loc.dir, previous)
+	}
+	return nil
+}
+
+// remove deletes the sidecar of the resource kept at loc and its file,
+// keeping them in j
+func (r *FileSystemResourceRepository) remove(j *journal, loc location, file string) error {
+	if err := j.removeFile(loc.dir, file); err != nil {
+		return err
+	}
+	return j.removeFile(loc.dir, loc.name+sidecarSuffix)
+}
+
+// find returns the sidecar of the resource with the given aggregate ID.
+// Until identity is taken from the URI, several resources may share an
+// ID; the first in URI order wins.
+func (r *FileSystemResourceRepository) find(id string) (*sidecar, error) {
+	// Most resources are identified by their URI
+	if loc, err := r.locate(id); err == nil {
+		if car, err := r.readSidecar(loc); err == nil && car.ID == id {
+			return car, nil
+		}
+	}
+	cars, err := r.sidecars()
+	if err != nil {
+		return nil, err
+	}
+	for _, car := range cars {
+		if car.ID == id {
+			return car, nil
+		}
+	}
+	return nil, repository.ErrResourceNotFound
+}
+
+// sidecars reads every sidecar below root, ordered by URI
+func (r *FileSystemResourceRepository) sidecars() ([]*sidecar, error) {
+	var cars []*sidecar
+	err := filepath.WalkDir(r.root, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == r.root {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := strings.CutSuffix(entry.Name(), sidecarSuffix)
+		if entry.IsDir() || !ok {
+			return nil
+		}
+		car, err := r.readSidecar(location{dir: filepath.Dir(path), name: name})
+		if err != nil {
+			return err
+		}
+		cars = append(cars, car)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(cars, func(i, j int) bool { return cars[i].URI < cars[j].URI })
+	return cars, nil
+}
+
+// readSidecar reads the sidecar of the resource kept at loc, returning
+// ErrResourceNotFound when there is none
+func (r *FileSystemResourceRepository) readSidecar(loc location) (*sidecar, error) {
+	data, err := os.ReadFile(loc.sidecar())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, repository.ErrResourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var car sidecar
+	if err := json.Unmarshal(data, &car); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", loc.sidecar(), err)
+	}
+	return &car, nil
+}
+
+// writeSidecar replaces the sidecar of the resource kept at loc, keeping
+// the previous one in j
+func (r *FileSystemResourceRepository) writeSidecar(j *journal, loc location, car *sidecar) error {
+	data, err := json.MarshalIndent(car, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = j.write(loc.sidecar(), strings.NewReader(string(data)))
+	return err
+}
+
+// rebuild replays the events recorded by car
+func (r *FileSystemResourceRepository) rebuild(car *sidecar) (entity.Resource, error) {
+	events, err := car.events()
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewBasicResourceFromHistory(car.ID, events, r.rdfService), nil
+}
+
+// rebuildAll replays the events recorded by each sidecar
+func (r *FileSystemResourceRepository) rebuildAll(cars []*sidecar) ([]entity.Resource, error) {
+	resources := make([]entity.Resource, 0, len(cars))
+	for _, car := range cars {
+		resource, err := r.rebuild(car)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// locate returns where the resource at uri is kept: below root, in a
+// directory for its scheme, one for its host and one for each segment of
+// its path. URIs that would lead out of their host's directory are refused.
+func (r *FileSystemResourceRepository) locate(uri string) (location, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" ||
+		strings.Contains(u.Host, "/") || strings.Contains(strings.ToLower(u.EscapedPath()), "%2f") {
+		return location{}, fmt.Errorf("cannot keep %s on disk", uri)
+	}
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	name := segments[len(segments)-1]
+	segments = segments[:len(segments)-1]
+	for _, segment := range append(segments, name) {
+		if segment == "." || segment == ".." || strings.ContainsAny(segment, "\\\x00") ||
+			strings.HasSuffix(segment, sidecarSuffix) || strings.HasSuffix(segment, tempSuffix) {
+			return location{}, fmt.Errorf("cannot keep %s on disk", uri)
+		}
+	}
+	for _, segment := range segments {
+		if segment == "" {
+			return location{}, fmt.Errorf("cannot keep %s on disk", uri)
+		}
+	}
+	for _, part := range []string{u.Scheme, u.Host} {
+		if part == "." || part == ".." || strings.ContainsAny(part, "/\\\x00") {
+			return location{}, fmt.Errorf("cannot keep %s on disk", uri)
+		}
+	}
+	dir := filepath.Join(append([]string{r.root, u.Scheme, u.Host}, segments...)...)
+	// Whatever the URI holds, its files must stay inside its host's directory
+	rel, err := filepath.Rel(filepath.Join(r.root, u.Scheme, u.Host), filepath.Join(dir, name))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return location{}, fmt.Errorf("cannot keep %s on disk", uri)
+	}
+	return location{dir: dir, name: name}, nil
+}
+
+// containerURI returns the URI of the container kept in dir, or false when
+// dir is above the directories of hosts
+func (r *FileSystemResourceRepository) containerURI(dir string) (string, bool) {
+	rel, err := filepath.Rel(r.root, dir)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || rel == "." {
+		return "", false
+	}
+	path := "/"
+	if len(parts) > 2 {
+		path += strings.Join(parts[2:], "/") + "/"
+	}
+	return parts[0] + "://" + parts[1] + (&url.URL{Path: path}).EscapedPath(), true
+}
+
+// events returns the events recorded in the sidecar
+func (s *sidecar) events() ([]domain.Event, error) {
+	events := make([]domain.Event, 0, len(s.Events))
+	for _, record := range s.Events {
+		evt, err := record.Event()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the events of %s: %w", s.URI, err)
+		}
+		events = append(events, evt)
+	}
+	return events, nil
+}
+
+// changed reports whether the file at path differs from what the sidecar
+// recorded writing. Files only touched keep their content hash.
+func (s *sidecar) changed(path string, info fs.FileInfo) (bool, error) {
+	if info.Size() == s.Size && info.ModTime().Equal(s.Modified) {
+		return false, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)) != s.SHA256, nil
+}
+
+// fileEdit returns the edit replacing the resource at uri with the file at path
+func fileEdit(uri string, contentType string, path string, info fs.FileInfo) repository.ExternalEdit {
+	return repository.ExternalEdit{
+		URI:         uri,
+		ContentType: contentType,
+		Version:     fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano()),
+		Open:        func() (io.ReadCloser, error) { return os.Open(path) },
+	}
+}
+
+// hasContainerFile reports whether edits include the file of the container at uri
+func hasContainerFile(edits []repository.ExternalEdit, uri string) bool {
+	for _, edit := range edits {
+		if edit.URI == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// fileName returns the name of the file holding a resource called name of
+// contentType. Names that already end in an extension of contentType are
+// kept; others get "$" and one. Containers, whose name is empty, are "$.ttl".
+func fileName(name string, contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	if name != "" && !strings.Contains(name, "$") && mediaTypeOf(filepath.Ext(name)) == mediaType {
+		return name
+	}
+	return name + "$" + extensionOf(mediaType)
+}
+
+// parseFileName returns the name of the resource a file holds and its
+// media type, undoing fileName
+func parseFileName(file string) (name string, mediaType string) {
+	if i := strings.LastIndex(file, "$"); i >= 0 && (i == len(file)-1 || file[i+1] == '.') {
+		return file[:i], mediaTypeOf(file[i+1:])
+	}
+	return file, mediaTypeOf(filepath.Ext(file))
+}
+
+// extensionOf returns the extension of files holding mediaType, or "" when
+// it has none
+func extensionOf(mediaType string) string {
+	if ext, ok := fileExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// mediaTypeOf returns the media type of files with the extension ext
+func mediaTypeOf(ext string) string {
+	if ext == "" {
+		return "application/octet-stream"
+	}
+	ext = strings.ToLower(ext)
+	for mediaType, known := range fileExtensions {
+		if known == ext {
+			return mediaType
+		}
+	}
+	if mediaType, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil {
+		return mediaType
+	}
+	return "application/octet-stream"
+}
+
+// ignoredFile reports whether file is left alone by ExternalEdits, such as
+// the temporary files of editors and of the repository itself
+func ignoredFile(file string) bool {
+	return strings.HasSuffix(file, tempSuffix) || strings.HasSuffix(file, "~") || strings.HasSuffix(file, ".swp") ||
+		strings.HasPrefix(file, ".#") || file == ".DS_Store"
+}
+
+// writeAtomically replaces the file at path with everything read from body
+// and returns the hex encoded SHA-256 hash of what it wrote
+func writeAtomically(path string, body io.Reader) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*"+tempSuffix)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), body); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// removeFile deletes the file called name in dir, which need not exist
+func removeFile(dir string, name string) error {
+	if name == "" {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// journal keeps the files a batch replaces or removes, and the directories
+// it creates, so a batch that fails part way can be undone. A nil journal
+// keeps nothing.
+type journal struct {
+	saved map[string]string // Path of each replaced or removed file to its copy; "" when it did not exist
+	paths []string          // Keys of saved, in the order they were first changed
+	dirs  []string          // Directories created, parents first
+}
+
+// keep copies the file at path before it is first changed. The copy is a
+// hard link where the file system allows it.
+func (j *journal) keep(path string) error {
+	if j == nil {
+		return nil
+	}
+	if _, ok := j.saved[path]; ok {
+		return nil
+	}
+	original, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		j.saved[path] = ""
+		j.paths = append(j.paths, path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer original.Close()
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*"+tempSuffix)
+	if err != nil {
+		return err
+	}
+	backup := file.Name()
+	file.Close()
+	if err := os.Remove(backup); err != nil || os.Link(path, backup) != nil {
+		if _, err := writeAtomically(backup, original); err != nil {
+			return err
+		}
+	}
+	j.saved[path] = backup
+	j.paths = append(j.paths, path)
+	return nil
+}
+
+// write replaces the file at path like writeAtomically, keeping it first
+func (j *journal) write(path string, body io.Reader) (string, error) {
+	if err := j.keep(path); err != nil {
+		return "", err
+	}
+	return writeAtomically(path, body)
+}
+
+// removeFile deletes the file called name in dir like removeFile, keeping
+// it first
+func (j *journal) removeFile(dir string, name string) error {
+	if name == "" {
+		return nil
+	}
+	if err := j.keep(filepath.Join(dir, name)); err != nil {
+		return err
+	}
+	return removeFile(dir, name)
+}
+
+// mkdirAll creates dir and any missing parents, recording which it created
+func (j *journal) mkdirAll(dir string) error {
+	var missing []string
+	for path := dir; ; path = filepath.Dir(path) {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		missing = append(missing, path)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if j != nil {
+		for i := len(missing) - 1; i >= 0; i-- {
+			j.dirs = append(j.dirs, missing[i])
+		}
+	}
+	return nil
+}
+
+// undo puts back every file kept and removes the directories created
+func (j *journal) undo() {
+	for i := len(j.paths) - 1; i >= 0; i-- {
+		path := j.paths[i]
+		if backup := j.saved[path]; backup != "" {
+			os.Rename(backup, path)
+		} else {
+			os.Remove(path)
+		}
+	}
+	for i := len(j.dirs) - 1; i >= 0; i-- {
+		os.Remove(j.dirs[i])
+	}
+}
+
+// commit deletes the copies of the files kept
+func (j *journal) commit() {
+	for _, path := range j.paths {
+		if backup := j.saved[path]; backup != "" {
+			os.Remove(backup)
+		}
+	}
+}
+
+var (
+	_ repository.ResourceRepository = (*FileSystemResourceRepository)(nil)
+	_ repository.EditSource         = (*FileSystemResourceRepository)(nil)
+)
@@ -0,0 +1,327 @@
+package repository_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+)
+
+func TestFileSystemResourceRepository(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService()
+	ctx := context.Background()
+
+	newResource := func(uri, turtle string) entity.Resource {
+		return entity.NewBasicResourceWithValidator(rdfService).FromTurtle(turtle).WithURI(uri)
+	}
+
+	t.Run("mirrors resources as files with sidecars", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		repo := repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService)
+		turtle := `<https://pod.example.com/notes/today> <http://purl.org/dc/terms/title> "Today" .`
+
+		// Act
+		err := repo.Save(ctx, newResource("https://pod.example.com/notes/today", turtle))
+
+		// Assert
+		require.NoError(t, err)
+		dir := filepath.Join(root, "https", "pod.example.com", "notes")
+		data, err := os.ReadFile(filepath.Join(dir, "today$.ttl"))
+		require.NoError(t, err)
+		assert.Equal(t, turtle, string(data))
+		assert.FileExists(t, filepath.Join(dir, "today.vine.json"))
+	})
+
+	t.Run("rebuilds resources after reopening the directory", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		resource := newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)
+		require.NoError(t, repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService).Save(ctx, resource))
+		reopened := repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService)
+
+		// Act
+		byURI, err := reopened.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		byID, err := reopened.GetByID(ctx, resource.ID())
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, resource.ID(), byURI.ID())
+		assert.Equal(t, resource.GetData(), byURI.GetData())
+		assert.Equal(t, resource.GetData(), byID.GetData())
+		assert.False(t, byURI.HasUncommittedEvents())
+	})
+
+	t.Run("rejects saves of resources changed since they were read", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewFileSystemResourceRepository(t.TempDir(), repository.NewMemoryBlobStore(), rdfService)
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)))
+		first, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		second, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		first.Update(`<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "B" .`, "text/turtle")
+		second.Update(`<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "C" .`, "text/turtle")
+
+		// Act
+		saved := repo.Save(ctx, first)
+		stale := repo.Save(ctx, second)
+		taken := repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "D" .`))
+
+		// Assert
+		require.NoError(t, saved)
+		assert.ErrorIs(t, stale, domainrepository.ErrResourceConflict)
+		assert.ErrorIs(t, taken, domainrepository.ErrResourceConflict)
+		reloaded, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		assert.Contains(t, reloaded.GetData(), `"B"`)
+	})
+
+	t.Run("copies non-RDF content into files named for its type", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		blobs := repository.NewMemoryBlobStore()
+		repo := repository.NewFileSystemResourceRepository(root, blobs, rdfService)
+		for key, body := range map[string]string{"0a": "hello", "0b": "\x89PNG"} {
+			_, err := blobs.Put(ctx, key, strings.NewReader(body))
+			require.NoError(t, err)
+		}
+		text := entity.NewBasicResource().WithBase("https://pod.example.com/files/hello.txt").
+			FromContent(entity.Content{Key: "0a", Size: 5}, "text/plain").WithURI("https://pod.example.com/files/hello.txt")
+		photo := entity.NewBasicResource().WithBase("https://pod.example.com/files/photo").
+			FromContent(entity.Content{Key: "0b", Size: 4}, "image/png").WithURI("https://pod.example.com/files/photo")
+
+		// Act
+		require.NoError(t, repo.Save(ctx, text))
+		err := repo.Save(ctx, photo)
+
+		// Assert
+		require.NoError(t, err)
+		dir := filepath.Join(root, "https", "pod.example.com", "files")
+		data, err := os.ReadFile(filepath.Join(dir, "hello.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+		data, err = os.ReadFile(filepath.Join(dir, "photo$.png"))
+		require.NoError(t, err)
+		assert.Equal(t, "\x89PNG", string(data))
+	})
+
+	t.Run("finds resources deleted before a cutoff", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		repo := repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService)
+		for _, uri := range []string{"https://pod.example.com/notes/a", "https://pod.example.com/notes/b", "https://pod.example.com/notes/c"} {
+			require.NoError(t, repo.Save(ctx, newResource(uri, `<`+uri+`> <http://purl.org/dc/terms/title> "x" .`)))
+		}
+		for _, uri := range []string{"https://pod.example.com/notes/a", "https://pod.example.com/notes/b"} {
+			loaded, err := repo.GetByURI(ctx, uri)
+			require.NoError(t, err)
+			require.NoError(t, repo.Save(ctx, loaded.Delete()))
+		}
+		restored, err := repo.GetByURI(ctx, "https://pod.example.com/notes/b")
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, restored.Restore()))
+		repo = repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService)
+
+		// Act
+		deleted, err := repo.FindDeletedBefore(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		early, err := repo.FindDeletedBefore(ctx, time.Now().Add(-time.Hour))
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		assert.Equal(t, "https://pod.example.com/notes/a", deleted[0].GetURI())
+		assert.True(t, deleted[0].IsDeleted())
+		assert.Empty(t, early)
+	})
+
+	t.Run("finds the direct children of containers", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewFileSystemResourceRepository(t.TempDir(), repository.NewMemoryBlobStore(), rdfService)
+		for _, uri := range []string{"https://pod.example.com/notes/a", "https://pod.example.com/notes/archive/", "https://pod.example.com/notes/archive/b"} {
+			require.NoError(t, repo.Save(ctx, newResource(uri, `<`+uri+`> <http://purl.org/dc/terms/title> "x" .`)))
+		}
+
+		// Act
+		children, err := repo.FindByContainer(ctx, "https://pod.example.com/notes/")
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, children, 2)
+		assert.Equal(t, "https://pod.example.com/notes/a", children[0].GetURI())
+		assert.Equal(t, "https://pod.example.com/notes/archive/", children[1].GetURI())
+	})
+
+	t.Run("moves files to new URIs", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		repo := repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService)
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)))
+		loaded, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+
+		// Act
+		err = repo.SaveAll(ctx, []domainrepository.Change{
+			{Resource: loaded.WithURI("https://pod.example.com/archive/a"), From: "https://pod.example.com/notes/a"},
+		})
+
+		// Assert
+		require.NoError(t, err)
+		_, err = repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		assert.ErrorIs(t, err, domainrepository.ErrResourceNotFound)
+		assert.NoFileExists(t, filepath.Join(root, "https", "pod.example.com", "notes", "a$.ttl"))
+		assert.FileExists(t, filepath.Join(root, "https", "pod.example.com", "archive", "a$.ttl"))
+		moved, err := repo.GetByURI(ctx, "https://pod.example.com/archive/a")
+		require.NoError(t, err)
+		events, err := repo.LoadEvents(ctx, moved.ID())
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+	})
+
+	t.Run("keeps every file when a batch fails part way", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		repo := repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService)
+		for _, uri := range []string{"https://pod.example.com/notes/a", "https://pod.example.com/notes/b"} {
+			require.NoError(t, repo.Save(ctx, newResource(uri, `<`+uri+`> <http://purl.org/dc/terms/title> "x" .`)))
+		}
+		a, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		b, err := repo.GetByURI(ctx, "https://pod.example.com/notes/b")
+		require.NoError(t, err)
+		// The bytes of this resource are not in the blob store, so it cannot be written
+		missing := entity.NewBasicResource().WithBase("https://pod.example.com/files/missing.txt").
+			FromContent(entity.Content{Key: "missing", Size: 1}, "text/plain").WithURI("https://pod.example.com/files/missing.txt")
+		notes := filepath.Join(root, "https", "pod.example.com", "notes")
+		files := func() map[string]string {
+			entries, err := os.ReadDir(notes)
+			require.NoError(t, err)
+			contents := make(map[string]string, len(entries))
+			for _, entry := range entries {
+				data, err := os.ReadFile(filepath.Join(notes, entry.Name()))
+				require.NoError(t, err)
+				contents[entry.Name()] = string(data)
+			}
+			return contents
+		}
+		before := files()
+
+		// Act
+		err = repo.SaveAll(ctx, []domainrepository.Change{
+			{Resource: b, Discard: true},
+			{Resource: a.WithURI("https://pod.example.com/archive/a"), From: "https://pod.example.com/notes/a"},
+			{Resource: missing},
+		})
+
+		// Assert
+		require.Error(t, err)
+		assert.Equal(t, before, files())
+		assert.NoDirExists(t, filepath.Join(root, "https", "pod.example.com", "archive"))
+		assert.NoDirExists(t, filepath.Join(root, "https", "pod.example.com", "files"))
+		for _, uri := range []string{"https://pod.example.com/notes/a", "https://pod.example.com/notes/b"} {
+			_, err := repo.GetByURI(ctx, uri)
+			assert.NoError(t, err, uri)
+		}
+	})
+
+	t.Run("reports files edited outside the pod", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		repo := repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService)
+		for _, uri := range []string{"https://pod.example.com/notes/", "https://pod.example.com/notes/a", "https://pod.example.com/notes/b"} {
+			require.NoError(t, repo.Save(ctx, newResource(uri, `<`+uri+`> <http://purl.org/dc/terms/title> "x" .`)))
+		}
+		dir := filepath.Join(root, "https", "pod.example.com", "notes")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a$.ttl"), []byte(`<> <http://purl.org/dc/terms/title> "edited" .`), 0o644))
+		require.NoError(t, os.Remove(filepath.Join(dir, "b$.ttl")))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "c.md"), []byte("# New"), 0o644))
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "drafts"), 0o755))
+
+		// Act
+		edits, err := repo.ExternalEdits(ctx)
+
+		// Assert
+		require.NoError(t, err)
+		found := make(map[string]domainrepository.ExternalEdit)
+		for _, edit := range edits {
+			found[edit.URI] = edit
+		}
+		require.Len(t, found, 4)
+		edited := found["https://pod.example.com/notes/a"]
+		assert.Equal(t, "text/turtle", edited.ContentType)
+		require.NotNil(t, edited.Open)
+		body, err := edited.Open()
+		require.NoError(t, err)
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, `<> <http://purl.org/dc/terms/title> "edited" .`, string(data))
+		assert.True(t, found["https://pod.example.com/notes/b"].Removed)
+		assert.Equal(t, "text/markdown", found["https://pod.example.com/notes/c.md"].ContentType)
+		assert.Nil(t, found["https://pod.example.com/notes/drafts/"].Open)
+	})
+
+	t.Run("reports nothing for files the pod wrote", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewFileSystemResourceRepository(t.TempDir(), repository.NewMemoryBlobStore(), rdfService)
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/", `<https://pod.example.com/notes/> a <http://www.w3.org/ns/ldp#BasicContainer> .`)))
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)))
+
+		// Act
+		edits, err := repo.ExternalEdits(ctx)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, edits)
+	})
+
+	t.Run("keeps URIs inside the directory of their host", func(t *testing.T) {
+		// Arrange
+		root := t.TempDir()
+		repo := repository.NewFileSystemResourceRepository(root, repository.NewMemoryBlobStore(), rdfService)
+		secret := `<https://pod.example.com/secret> <http://purl.org/dc/terms/title> "Secret" .`
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/secret", secret)))
+
+		for _, uri := range []string{
+			"http://../https/pod.example.com/secret",
+			"http://./https/pod.example.com/secret",
+			"https://pod.example.com/notes/..%5Csecret",
+			"https://pod.example.com/notes/a%00b",
+		} {
+			// Act
+			_, getErr := repo.GetByURI(ctx, uri)
+			saveErr := repo.Save(ctx, newResource(uri, `<> <http://purl.org/dc/terms/title> "Overwritten" .`))
+
+			// Assert
+			assert.Error(t, getErr, uri)
+			assert.Error(t, saveErr, uri)
+		}
+		data, err := os.ReadFile(filepath.Join(root, "https", "pod.example.com", "secret$.ttl"))
+		require.NoError(t, err)
+		assert.Equal(t, secret, string(data))
+	})
+
+	t.Run("rejects URIs that cannot be mapped to files", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewFileSystemResourceRepository(t.TempDir(), repository.NewMemoryBlobStore(), rdfService)
+
+		// Act
+		err := repo.Save(ctx, newResource("https://pod.example.com/notes/a.vine.json", `<https://pod.example.com/notes/a.vine.json> <http://purl.org/dc/terms/title> "A" .`))
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
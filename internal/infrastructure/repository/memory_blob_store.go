@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/wepala/vine-pod/internal/domain/repository"
+)
+
+// MemoryBlobStore keeps blobs in memory. It suits tests and pods whose
+// non-RDF resources need not survive a restart.
+type MemoryBlobStore struct {
+	mu     sync.RWMutex
+	blobs  map[string][]byte
+	staged map[string][]byte
+}
+
+// NewMemoryBlobStore creates an empty in-memory blob store
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{
+		blobs:  make(map[string][]byte),
+		staged: make(map[string][]byte),
+	}
+}
+
+// Put reads r to the end and keeps its bytes under key
+func (s *MemoryBlobStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	data, err := io.ReadAll(&contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return 0, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = data
+	return int64(len(data)), nil
+}
+
+// Get returns a reader of the bytes kept under key
+func (s *MemoryBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// GetRange returns a reader of part of the bytes kept under key
+func (s *MemoryBlobStore) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	return io.NopCloser(io.NewSectionReader(bytes.NewReader(data), offset, length)), nil
+}
+
+// Append reads r to the end and adds its bytes to those staged under key,
+// keeping what was read when reading fails
+func (s *MemoryBlobStore) Append(ctx context.Context, key string, offset int64, r io.Reader) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	staged, ok := s.staged[key]
+	if int64(len(staged)) != offset || (!ok && offset != 0) {
+		return int64(len(staged)), fmt.Errorf("%w: %s holds %d bytes, not %d", repository.ErrBlobOffset, key, len(staged), offset)
+	}
+
+	data, err := io.ReadAll(&contextReader{ctx: ctx, r: r})
+	s.staged[key] = append(staged, data...)
+	size := int64(len(s.staged[key]))
+	if err != nil {
+		return size, fmt.Errorf("failed to stage blob %s: %w", key, err)
+	}
+	return size, nil
+}
+
+// Staged returns the number of bytes staged under key
+func (s *MemoryBlobStore) Staged(ctx context.Context, key string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	staged, ok := s.staged[key]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	return int64(len(staged)), nil
+}
+
+// Commit keeps the bytes staged under key as its blob
+func (s *MemoryBlobStore) Commit(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	staged, ok := s.staged[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	s.blobs[key] = staged
+	delete(s.staged, key)
+	return nil
+}
+
+// Delete forgets the bytes kept or staged under key
+func (s *MemoryBlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	delete(s.staged, key)
+	return nil
+}
+
+// GetTagged returns a copy of the bytes kept under key with their tag
+func (s *MemoryBlobStore) GetTagged(ctx context.Context, key string) ([]byte, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	return bytes.Clone(data), blobTag(data), nil
+}
+
+// PutIf keeps data under key when the bytes kept there still have tag
+func (s *MemoryBlobStore) PutIf(ctx context.Context, key string, tag string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.blobs[key]
+	if ok != (tag != "") || ok && blobTag(current) != tag {
+		return fmt.Errorf("%w: %s", repository.ErrBlobChanged, key)
+	}
+	s.blobs[key] = bytes.Clone(data)
+	return nil
+}
+
+var _ repository.BlobStore = (*MemoryBlobStore)(nil)
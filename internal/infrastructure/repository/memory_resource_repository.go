@@ -0,0 +1,340 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wepala/vine-os/core/pericarp/pkg/domain"
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	"github.com/wepala/vine-pod/internal/domain/event"
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/internal/domain/service"
+)
+
+// MemoryResourceRepository keeps resource event streams in memory. Resources
+// are rebuilt from their events on every read, so callers never share state.
+type MemoryResourceRepository struct {
+	mu         sync.RWMutex
+	streams    map[string][]domain.Event // events by resource URI
+	ids        map[string]string         // aggregate ID by resource URI
+	rdfService service.RDFValidationService
+}
+
+// NewMemoryResourceRepository creates an empty in-memory repository
+func NewMemoryResourceRepository(rdfService service.RDFValidationService) *MemoryResourceRepository {
+	return &MemoryResourceRepository{
+		streams:    make(map[string][]domain.Event),
+		ids:        make(map[string]string),
+		rdfService: rdfService,
+	}
+}
+
+// Save appends the resource's uncommitted events to its stream
+func (r *MemoryResourceRepository) Save(ctx context.Context, resource entity.Resource) error {
+	uri := resource.GetURI()
+	if uri == "" {
+		return errors.New("cannot save a resource without a URI")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := checkVersion(resource, lastVersion(r.streams[uri])); err != nil {
+		return err
+	}
+	r.append(resource, resource.ID())
+	return nil
+}
+
+// SaveAll applies every change under a single lock, after checking that the
+// streams of moved resources exist, their new URIs are free and no stream
+// changed since it was read. A resource identified by the URI it moved from
+// is identified by its new URI.
+func (r *MemoryResourceRepository) SaveAll(ctx context.Context, changes []repository.Change) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vacated := make(map[string]bool)
+	for _, change := range changes {
+		switch {
+		case change.Discard:
+			vacated[change.Resource.GetURI()] = true
+		case change.From != "":
+			vacated[change.From] = true
+		}
+	}
+	for _, change := range changes {
+		uri := change.Resource.GetURI()
+		if uri == "" {
+			return errors.New("cannot save a resource without a URI")
+		}
+		if change.Discard {
+			if _, ok := r.streams[uri]; !ok {
+				return fmt.Errorf("cannot discard %s: %w", uri, repository.ErrResourceNotFound)
+			}
+			continue
+		}
+		// A resource saved where the batch frees a URI starts a new stream
+		var stored []domain.Event
+		switch {
+		case change.From != "":
+			stored = r.streams[change.From]
+		case !vacated[uri]:
+			stored = r.streams[uri]
+		}
+		if err := checkVersion(change.Resource, lastVersion(stored)); err != nil {
+			return err
+		}
+		if change.From == "" || change.From == uri {
+			continue
+		}
+		if _, ok := r.streams[change.From]; !ok {
+			return fmt.Errorf("cannot move %s: %w", change.From, repository.ErrResourceNotFound)
+		}
+		if _, taken := r.streams[uri]; taken && !vacated[uri] {
+			return fmt.Errorf("cannot move %s to %s: %w", change.From, uri, repository.ErrResourceExists)
+		}
+	}
+
+	for _, change := range changes {
+		if change.Discard {
+			delete(r.streams, change.Resource.GetURI())
+			delete(r.ids, change.Resource.GetURI())
+		}
+	}
+
+	// Take moved streams out before putting them back, so resources can move
+	// into URIs vacated by the same batch
+	type stream struct {
+		events []domain.Event
+		id     string
+	}
+	moved := make(map[string]stream)
+	for _, change := range changes {
+		uri := change.Resource.GetURI()
+		if change.Discard || change.From == "" || change.From == uri {
+			continue
+		}
+		id := r.ids[change.From]
+		if id == change.From {
+			id = uri
+		}
+		moved[uri] = stream{events: r.streams[change.From], id: id}
+		delete(r.streams, change.From)
+		delete(r.ids, change.From)
+	}
+	for uri, s := range moved {
+		r.streams[uri] = s.events
+		r.ids[uri] = s.id
+	}
+
+	for _, change := range changes {
+		if change.Discard {
+			continue
+		}
+		id := change.Resource.ID()
+		if s, ok := moved[change.Resource.GetURI()]; ok {
+			id = s.id
+		}
+		r.append(change.Resource, id)
+	}
+	return nil
+}
+
+// GetByID rebuilds the resource with the given aggregate ID
+func (r *MemoryResourceRepository) GetByID(ctx context.Context, id string) (entity.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	uri, ok := r.uriFor(id)
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+	return r.rebuild(id, uri)
+}
+
+// GetByURI rebuilds the resource stored at uri
+func (r *MemoryResourceRepository) GetByURI(ctx context.Context, uri string) (entity.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.rebuild(r.ids[uri], uri)
+}
+
+// Delete removes a resource and its events
+func (r *MemoryResourceRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uri, ok := r.uriFor(id)
+	if !ok {
+		return repository.ErrResourceNotFound
+	}
+	delete(r.streams, uri)
+	delete(r.ids, uri)
+	return nil
+}
+
+// List returns resources ordered by URI
+func (r *MemoryResourceRepository) List(ctx context.Context, limit, offset int) ([]entity.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	uris := r.sortedURIs(func(string) bool { return true })
+	if offset >= len(uris) {
+		return []entity.Resource{}, nil
+	}
+	uris = uris[offset:]
+	if limit > 0 && limit < len(uris) {
+		uris = uris[:limit]
+	}
+	return r.rebuildAll(uris)
+}
+
+// FindByContainer returns the direct children of containerURI ordered by URI
+func (r *MemoryResourceRepository) FindByContainer(ctx context.Context, containerURI string) ([]entity.Resource, error) {
+	if !strings.HasSuffix(containerURI, "/") {
+		containerURI += "/"
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	uris := r.sortedURIs(func(uri string) bool {
+		name, ok := strings.CutPrefix(uri, containerURI)
+		return ok && name != "" && !strings.Contains(strings.TrimSuffix(name, "/"), "/")
+	})
+	return r.rebuildAll(uris)
+}
+
+// FindDeletedBefore returns the tombstones deleted before cutoff ordered by
+// URI. Only their streams are replayed.
+func (r *MemoryResourceRepository) FindDeletedBefore(ctx context.Context, cutoff time.Time) ([]entity.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	uris := r.sortedURIs(func(uri string) bool {
+		deleted := deletedAt(time.Time{}, r.streams[uri])
+		return !deleted.IsZero() && deleted.Before(cutoff)
+	})
+	return r.rebuildAll(uris)
+}
+
+// LoadEvents returns every event of the resource with the given aggregate ID
+func (r *MemoryResourceRepository) LoadEvents(ctx context.Context, aggregateID string) ([]domain.Event, error) {
+	return r.LoadEventsFromVersion(ctx, aggregateID, 0)
+}
+
+// LoadEventsFromVersion returns the events of a resource from version onwards
+func (r *MemoryResourceRepository) LoadEventsFromVersion(ctx context.Context, aggregateID string, version int) ([]domain.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	uri, ok := r.uriFor(aggregateID)
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+	var events []domain.Event
+	for _, evt := range r.streams[uri] {
+		if evt.Version() >= version {
+			events = append(events, evt)
+		}
+	}
+	return events, nil
+}
+
+// append adds the resource's uncommitted events to the stream at its URI
+// and records the aggregate ID it is known by
+func (r *MemoryResourceRepository) append(resource entity.Resource, id string) {
+	uri := resource.GetURI()
+	r.streams[uri] = append(r.streams[uri], resource.UncommittedEvents()...)
+	r.ids[uri] = id
+	resource.MarkEventsAsCommitted()
+}
+
+// lastVersion returns the version of the last event of a stream, or 0 for
+// an empty one
+func lastVersion(events []domain.Event) int {
+	if len(events) == 0 {
+		return 0
+	}
+	return events[len(events)-1].Version()
+}
+
+// checkVersion returns ErrResourceConflict unless the stream of resource
+// still ends at the version it was read at, the one before its first
+// uncommitted event
+func checkVersion(resource entity.Resource, stored int) error {
+	uncommitted := resource.UncommittedEvents()
+	if len(uncommitted) == 0 {
+		return nil
+	}
+	if expected := uncommitted[0].Version() - 1; stored != expected {
+		return fmt.Errorf("%w: %s is at version %d, not %d", repository.ErrResourceConflict, resource.GetURI(), stored, expected)
+	}
+	return nil
+}
+
+// deletedAt returns when events leave a resource deleted at deleted deleted,
+// or the zero time when they leave it live
+func deletedAt(deleted time.Time, events []domain.Event) time.Time {
+	for _, evt := range events {
+		switch evt.(type) {
+		case *event.ResourceDeletedEvent:
+			deleted = evt.OccurredAt()
+		case *event.ResourceRestoredEvent:
+			deleted = time.Time{}
+		}
+	}
+	return deleted
+}
+
+// rebuild replays the stream stored at uri
+func (r *MemoryResourceRepository) rebuild(id string, uri string) (entity.Resource, error) {
+	events, ok := r.streams[uri]
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+	return entity.NewBasicResourceFromHistory(id, events, r.rdfService), nil
+}
+
+// rebuildAll replays the streams stored at each URI
+func (r *MemoryResourceRepository) rebuildAll(uris []string) ([]entity.Resource, error) {
+	resources := make([]entity.Resource, 0, len(uris))
+	for _, uri := range uris {
+		resource, err := r.rebuild(r.ids[uri], uri)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// uriFor returns the URI of a resource with the given aggregate ID. Until
+// identity is taken from the URI, several resources may share an ID; the
+// first in URI order wins.
+func (r *MemoryResourceRepository) uriFor(id string) (string, bool) {
+	uris := r.sortedURIs(func(uri string) bool { return r.ids[uri] == id })
+	if len(uris) == 0 {
+		return "", false
+	}
+	return uris[0], true
+}
+
+// sortedURIs returns the stored URIs accepted by keep in order
+func (r *MemoryResourceRepository) sortedURIs(keep func(string) bool) []string {
+	uris := make([]string, 0, len(r.streams))
+	for uri := range r.streams {
+		if keep(uri) {
+			uris = append(uris, uri)
+		}
+	}
+	sort.Strings(uris)
+	return uris
+}
@@ -0,0 +1,189 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wepala/vine-pod/internal/domain/entity"
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/internal/domain/service"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+)
+
+func TestMemoryResourceRepository(t *testing.T) {
+	rdfService := service.NewStandardRDFValidationService()
+	ctx := context.Background()
+
+	newResource := func(uri, turtle string) entity.Resource {
+		return entity.NewBasicResourceWithValidator(rdfService).FromTurtle(turtle).WithURI(uri)
+	}
+
+	t.Run("rebuilds saved resources from their events", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		resource := newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)
+		require.NoError(t, repo.Save(ctx, resource))
+
+		// Act
+		loaded, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotSame(t, resource, loaded)
+		assert.Equal(t, resource.ID(), loaded.ID())
+		assert.Equal(t, resource.GetData(), loaded.GetData())
+		assert.False(t, loaded.HasUncommittedEvents())
+	})
+
+	t.Run("appends updates to the stream", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)))
+		loaded, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+
+		// Act
+		loaded.Update(`<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "B" .`, "text/turtle")
+		require.NoError(t, repo.Save(ctx, loaded))
+
+		// Assert
+		events, err := repo.LoadEvents(ctx, loaded.ID())
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+		reloaded, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		assert.Contains(t, reloaded.GetData(), `"B"`)
+	})
+
+	t.Run("rejects saves of resources changed since they were read", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)))
+		first, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		second, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		first.Update(`<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "B" .`, "text/turtle")
+		second.Update(`<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "C" .`, "text/turtle")
+
+		// Act
+		saved := repo.Save(ctx, first)
+		stale := repo.Save(ctx, second)
+		taken := repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "D" .`))
+
+		// Assert
+		require.NoError(t, saved)
+		assert.ErrorIs(t, stale, domainrepository.ErrResourceConflict)
+		assert.ErrorIs(t, taken, domainrepository.ErrResourceConflict)
+		reloaded, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+		assert.Contains(t, reloaded.GetData(), `"B"`)
+	})
+
+	t.Run("finds resources deleted before a cutoff", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		for _, uri := range []string{"https://pod.example.com/notes/a", "https://pod.example.com/notes/b", "https://pod.example.com/notes/c"} {
+			require.NoError(t, repo.Save(ctx, newResource(uri, `<`+uri+`> <http://purl.org/dc/terms/title> "x" .`)))
+		}
+		for _, uri := range []string{"https://pod.example.com/notes/a", "https://pod.example.com/notes/b"} {
+			loaded, err := repo.GetByURI(ctx, uri)
+			require.NoError(t, err)
+			require.NoError(t, repo.Save(ctx, loaded.Delete()))
+		}
+		restored, err := repo.GetByURI(ctx, "https://pod.example.com/notes/b")
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, restored.Restore()))
+
+		// Act
+		deleted, err := repo.FindDeletedBefore(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		early, err := repo.FindDeletedBefore(ctx, time.Now().Add(-time.Hour))
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		assert.Equal(t, "https://pod.example.com/notes/a", deleted[0].GetURI())
+		assert.True(t, deleted[0].IsDeleted())
+		assert.Empty(t, early)
+	})
+
+	t.Run("finds the direct children of a container", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		for _, uri := range []string{
+			"https://pod.example.com/notes/",
+			"https://pod.example.com/notes/a",
+			"https://pod.example.com/notes/archive/",
+			"https://pod.example.com/notes/archive/old",
+		} {
+			require.NoError(t, repo.Save(ctx, newResource(uri, `<`+uri+`> <http://purl.org/dc/terms/title> "x" .`)))
+		}
+
+		// Act
+		children, err := repo.FindByContainer(ctx, "https://pod.example.com/notes/")
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, children, 2)
+		assert.Equal(t, "https://pod.example.com/notes/a", children[0].GetURI())
+		assert.Equal(t, "https://pod.example.com/notes/archive/", children[1].GetURI())
+	})
+
+	t.Run("moves streams to new URIs", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)))
+		loaded, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+
+		// Act
+		err = repo.SaveAll(ctx, []domainrepository.Change{
+			{Resource: loaded.WithURI("https://pod.example.com/archive/a"), From: "https://pod.example.com/notes/a"},
+		})
+
+		// Assert
+		require.NoError(t, err)
+		_, err = repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		assert.ErrorIs(t, err, domainrepository.ErrResourceNotFound)
+		moved, err := repo.GetByURI(ctx, "https://pod.example.com/archive/a")
+		require.NoError(t, err)
+		events, err := repo.LoadEvents(ctx, moved.ID())
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+	})
+
+	t.Run("saves nothing when a move would overwrite a resource", func(t *testing.T) {
+		// Arrange
+		repo := repository.NewMemoryResourceRepository(rdfService)
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/a", `<https://pod.example.com/notes/a> <http://purl.org/dc/terms/title> "A" .`)))
+		require.NoError(t, repo.Save(ctx, newResource("https://pod.example.com/notes/b", `<https://pod.example.com/notes/b> <http://purl.org/dc/terms/title> "B" .`)))
+		loaded, err := repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		require.NoError(t, err)
+
+		// Act
+		err = repo.SaveAll(ctx, []domainrepository.Change{
+			{Resource: newResource("https://pod.example.com/notes/c", `<https://pod.example.com/notes/c> <http://purl.org/dc/terms/title> "C" .`)},
+			{Resource: loaded.WithURI("https://pod.example.com/notes/b"), From: "https://pod.example.com/notes/a"},
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, domainrepository.ErrResourceExists)
+		_, err = repo.GetByURI(ctx, "https://pod.example.com/notes/c")
+		assert.ErrorIs(t, err, domainrepository.ErrResourceNotFound)
+		_, err = repo.GetByURI(ctx, "https://pod.example.com/notes/a")
+		assert.NoError(t, err)
+	})
+
+	t.Run("returns ErrResourceNotFound for unknown resources", func(t *testing.T) {
+		// Act
+		_, err := repository.NewMemoryResourceRepository(rdfService).GetByURI(ctx, "https://pod.example.com/missing")
+
+		// Assert
+		assert.ErrorIs(t, err, domainrepository.ErrResourceNotFound)
+	})
+}
@@ -0,0 +1,682 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+)
+
+// minS3PartSize is the smallest part S3 accepts in a multipart upload,
+// other than the last
+const minS3PartSize = 5 << 20
+
+// S3BlobStore keeps blobs as objects in a bucket of an S3-compatible
+// service, such as Amazon S3 or MinIO. Blobs larger than a part are
+// uploaded in parts as they are read. A staged blob is a multipart upload
+// of its complete parts plus a staging object holding the bytes of the
+// part still being filled, so appends of any size survive restarts.
+type S3BlobStore struct {
+	config   config.S3Config
+	endpoint *url.URL
+	signer   *s3Signer
+	client   *http.Client
+
+	// staging serializes appends to each staged blob
+	staging sync.Map
+
+	// buffers recycles buffers of a part's size between writes
+	buffers sync.Pool
+}
+
+// s3Staging is the progress of a staged blob
+type s3Staging struct {
+	uploadID string // The multipart upload of its complete parts, once there is one
+	uploaded int64  // Bytes in complete parts
+	pending  int64  // Bytes in the staging object
+}
+
+// NewS3BlobStore creates a blob store keeping blobs in the configured bucket
+func NewS3BlobStore(cfg config.S3Config) (*S3BlobStore, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("an S3 bucket is required")
+	}
+	if cfg.PartSize < minS3PartSize {
+		return nil, fmt.Errorf("S3 part size must be at least %d bytes, not %d", minS3PartSize, cfg.PartSize)
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.DeleteTag != "" {
+		if name, _, ok := strings.Cut(cfg.DeleteTag, "="); !ok || name == "" {
+			return nil, fmt.Errorf("S3 delete tag must be key=value, not %q", cfg.DeleteTag)
+		}
+	}
+
+	store := &S3BlobStore{
+		config:   cfg,
+		endpoint: endpoint,
+		signer: &s3Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			sessionToken:    cfg.SessionToken,
+			region:          cfg.Region,
+		},
+		client: &http.Client{},
+	}
+	store.buffers.New = func() any {
+		buffer := make([]byte, cfg.PartSize)
+		return &buffer
+	}
+	return store, nil
+}
+
+// Put uploads blobs no larger than a part in one request, and larger ones
+// in parts as they are read. A failed multipart upload is aborted.
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	if err := checkBlobKey(key); err != nil {
+		return 0, err
+	}
+	object := s.object(key)
+	reader := &contextReader{ctx: ctx, r: r}
+	pooled := s.buffers.Get().(*[]byte)
+	defer s.buffers.Put(pooled)
+	buffer := *pooled
+
+	n, err := io.ReadFull(reader, buffer)
+	if isEOF(err) {
+		if err := s.putObject(ctx, object, buffer[:n], nil); err != nil {
+			return 0, fmt.Errorf("failed to write blob %s: %w", key, err)
+		}
+		return int64(n), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+
+	uploadID, err := s.createUpload(ctx, object)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	var parts []s3Part
+	var written int64
+	for err == nil {
+		etag, uploadErr := s.uploadPart(ctx, object, uploadID, len(parts)+1, buffer[:n])
+		if uploadErr != nil {
+			err = uploadErr
+			break
+		}
+		parts = append(parts, s3Part{PartNumber: len(parts) + 1, ETag: etag})
+		written += int64(n)
+
+		if n, err = io.ReadFull(reader, buffer); isEOF(err) && n > 0 {
+			// The last part may be smaller than the others
+			err = nil
+		}
+	}
+	if isEOF(err) {
+		err = s.completeUpload(ctx, object, uploadID, parts)
+	}
+	if err != nil {
+		s.abortUpload(ctx, object, uploadID)
+		return 0, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	return written, nil
+}
+
+// Get downloads the object of the blob
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, err
+	}
+	response, err := s.do(ctx, http.MethodGet, s.object(key), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// GetRange downloads a range of the object of the blob
+func (s *S3BlobStore) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, err
+	}
+	header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}}
+	response, err := s.do(ctx, http.MethodGet, s.object(key), nil, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// Append uploads every complete part of what the staged blob holds once r
+// is read, and keeps the rest in its staging object
+func (s *S3BlobStore) Append(ctx context.Context, key string, offset int64, r io.Reader) (int64, error) {
+	if err := checkBlobKey(key); err != nil {
+		return 0, err
+	}
+	lock, _ := s.staging.LoadOrStore(key, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	state, staged, err := s.stagingState(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if !staged && offset != 0 {
+		return 0, fmt.Errorf("%w: %s is not staged", repository.ErrBlobOffset, key)
+	}
+	if size := state.uploaded + state.pending; size != offset {
+		return size, fmt.Errorf("%w: %s holds %d bytes, not %d", repository.ErrBlobOffset, key, size, offset)
+	}
+
+	pooled := s.buffers.Get().(*[]byte)
+	defer s.buffers.Put(pooled)
+	buffer := (*pooled)[:0]
+	if state.pending > 0 {
+		if buffer, err = s.readPending(ctx, key, buffer); err != nil {
+			return offset, err
+		}
+		// Reading may have grown the buffer, but parts keep their size
+		buffer = buffer[:len(buffer):s.config.PartSize]
+	}
+
+	// Bytes read before a failure are still staged
+	object := s.object(key)
+	reader := &contextReader{ctx: ctx, r: r}
+	var failure error
+	for failure == nil {
+		n, err := io.ReadFull(reader, buffer[len(buffer):cap(buffer)])
+		buffer = buffer[:len(buffer)+n]
+		if err != nil && !isEOF(err) {
+			failure = err
+		}
+		if len(buffer) < cap(buffer) {
+			break
+		}
+
+		if state.uploadID == "" {
+			if state.uploadID, err = s.createUpload(ctx, object); err != nil {
+				failure = err
+				break
+			}
+		}
+		if _, err := s.uploadPart(ctx, object, state.uploadID, s.partNumber(state.uploaded), buffer); err != nil {
+			failure = err
+			break
+		}
+		state.uploaded += int64(len(buffer))
+		buffer = buffer[:0]
+	}
+
+	state.pending = int64(len(buffer))
+	if err := s.writeStaging(ctx, key, state, buffer); err != nil {
+		return offset, fmt.Errorf("failed to stage blob %s: %w", key, err)
+	}
+	size := state.uploaded + state.pending
+	if failure != nil {
+		return size, fmt.Errorf("failed to stage blob %s: %w", key, failure)
+	}
+	return size, nil
+}
+
+// Staged returns the bytes in complete parts and in the staging object
+func (s *S3BlobStore) Staged(ctx context.Context, key string) (int64, error) {
+	if err := checkBlobKey(key); err != nil {
+		return 0, err
+	}
+	state, staged, err := s.stagingState(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if !staged {
+		return 0, fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	return state.uploaded + state.pending, nil
+}
+
+// Commit uploads the staging object as the last part and completes the
+// multipart upload, or uploads it as the object when no part was complete
+func (s *S3BlobStore) Commit(ctx context.Context, key string) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+	lock, _ := s.staging.LoadOrStore(key, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	state, staged, err := s.stagingState(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !staged {
+		return fmt.Errorf("%w: %s", repository.ErrBlobNotFound, key)
+	}
+	pending, err := s.readPending(ctx, key, nil)
+	if err != nil {
+		return err
+	}
+
+	object := s.object(key)
+	if state.uploadID == "" {
+		err = s.putObject(ctx, object, pending, nil)
+	} else {
+		err = s.completeStaged(ctx, object, state, pending)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to commit blob %s: %w", key, err)
+	}
+	if err := s.deleteObject(ctx, s.stagingObject(key)); err != nil {
+		return fmt.Errorf("failed to commit blob %s: %w", key, err)
+	}
+	s.staging.Delete(key)
+	return nil
+}
+
+// Delete removes the object of the blob, or tags it for a bucket lifecycle
+// rule to expire when a delete tag is configured. A staged blob's
+// multipart upload is aborted and its staging object removed.
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+	object := s.object(key)
+	var err error
+	if s.config.DeleteTag != "" {
+		err = s.tagObject(ctx, object, s.config.DeleteTag)
+	} else {
+		err = s.deleteObject(ctx, object)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete blob %s: %w", key, err)
+	}
+
+	state, staged, err := s.stagingState(ctx, key)
+	if err != nil || !staged {
+		return err
+	}
+	if state.uploadID != "" {
+		if err := s.abortUpload(ctx, object, state.uploadID); err != nil {
+			return fmt.Errorf("failed to delete blob %s: %w", key, err)
+		}
+	}
+	if err := s.deleteObject(ctx, s.stagingObject(key)); err != nil {
+		return fmt.Errorf("failed to delete blob %s: %w", key, err)
+	}
+	s.staging.Delete(key)
+	return nil
+}
+
+// GetTagged downloads the object of the blob with its entity tag
+func (s *S3BlobStore) GetTagged(ctx context.Context, key string) ([]byte, string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, "", err
+	}
+	response, err := s.do(ctx, http.MethodGet, s.object(key), nil, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+	return data, response.Header.Get("ETag"), nil
+}
+
+// PutIf uploads data as the object of the blob with If-Match, or with
+// If-None-Match when the blob must not exist, so S3 rejects the write when
+// another one got there first
+func (s *S3BlobStore) PutIf(ctx context.Context, key string, tag string, data []byte) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+	header := http.Header{"If-None-Match": {"*"}}
+	if tag != "" {
+		header = http.Header{"If-Match": {tag}}
+	}
+	if err := s.putObject(ctx, s.object(key), data, header); err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	return nil
+}
+
+// Link presigns a download of blobs of at least the configured presign size
+func (s *S3BlobStore) Link(ctx context.Context, key string, size int64, contentType string) (string, error) {
+	if s.config.PresignSize <= 0 || size < s.config.PresignSize {
+		return "", nil
+	}
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	if contentType != "" {
+		query.Set("response-content-type", contentType)
+	}
+	return s.signer.presign(http.MethodGet, s.objectURL(s.object(key), query), s.config.PresignExpiry, time.Now()).String(), nil
+}
+
+// object returns the name of the object holding the blob stored under key
+func (s *S3BlobStore) object(key string) string {
+	return s.config.Prefix + key
+}
+
+// stagingObject returns the name of the object holding the bytes of the
+// part of a staged blob that is still being filled
+func (s *S3BlobStore) stagingObject(key string) string {
+	return s.config.Prefix + "staging/" + key
+}
+
+// partNumber returns the number of the part starting after uploaded bytes
+func (s *S3BlobStore) partNumber(uploaded int64) int {
+	return int(uploaded/s.config.PartSize) + 1
+}
+
+// stagingState reads the progress of a staged blob from its staging
+// object. staged is false when the blob is not staged.
+func (s *S3BlobStore) stagingState(ctx context.Context, key string) (state s3Staging, staged bool, err error) {
+	response, err := s.do(ctx, http.MethodHead, s.stagingObject(key), nil, nil, nil)
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return s3Staging{}, false, nil
+	}
+	if err != nil {
+		return s3Staging{}, false, err
+	}
+	response.Body.Close()
+
+	state.uploadID = response.Header.Get("X-Amz-Meta-Upload-Id")
+	if uploaded := response.Header.Get("X-Amz-Meta-Uploaded"); uploaded != "" {
+		if state.uploaded, err = strconv.ParseInt(uploaded, 10, 64); err != nil {
+			return s3Staging{}, false, fmt.Errorf("staged blob %s records %q uploaded bytes", key, uploaded)
+		}
+	}
+	state.pending = response.ContentLength
+	return state, true, nil
+}
+
+// writeStaging records the progress of a staged blob and the bytes of the
+// part being filled
+func (s *S3BlobStore) writeStaging(ctx context.Context, key string, state s3Staging, pending []byte) error {
+	header := http.Header{"X-Amz-Meta-Uploaded": {strconv.FormatInt(state.uploaded, 10)}}
+	if state.uploadID != "" {
+		header.Set("X-Amz-Meta-Upload-Id", state.uploadID)
+	}
+	return s.putObject(ctx, s.stagingObject(key), pending, header)
+}
+
+// readPending appends the bytes of the staging object of a staged blob to buffer
+func (s *S3BlobStore) readPending(ctx context.Context, key string, buffer []byte) ([]byte, error) {
+	response, err := s.do(ctx, http.MethodGet, s.stagingObject(key), nil, nil, nil)
+	if err != nil {
+		return buffer, err
+	}
+	defer response.Body.Close()
+	pending := bytes.NewBuffer(buffer)
+	if _, err := pending.ReadFrom(response.Body); err != nil {
+		return buffer, fmt.Errorf("failed to read staged blob %s: %w", key, err)
+	}
+	return pending.Bytes(), nil
+}
+
+// completeStaged uploads the pending bytes of a staged blob as its last
+// part and completes its multipart upload
+func (s *S3BlobStore) completeStaged(ctx context.Context, object string, state s3Staging, pending []byte) error {
+	if len(pending) > 0 {
+		if _, err := s.uploadPart(ctx, object, state.uploadID, s.partNumber(state.uploaded), pending); err != nil {
+			return err
+		}
+	}
+	parts, err := s.listParts(ctx, object, state.uploadID)
+	if err != nil {
+		return err
+	}
+	return s.completeUpload(ctx, object, state.uploadID, parts)
+}
+
+// putObject uploads body as object
+func (s *S3BlobStore) putObject(ctx context.Context, object string, body []byte, header http.Header) error {
+	if body == nil {
+		body = []byte{}
+	}
+	response, err := s.do(ctx, http.MethodPut, object, nil, header, body)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// deleteObject removes object, which need not exist
+func (s *S3BlobStore) deleteObject(ctx context.Context, object string) error {
+	response, err := s.do(ctx, http.MethodDelete, object, nil, nil, nil)
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// tagObject replaces the tags of object, which need not exist, with tag
+func (s *S3BlobStore) tagObject(ctx context.Context, object string, tag string) error {
+	name, value, _ := strings.Cut(tag, "=")
+	body, err := xml.Marshal(s3Tagging{Tags: []s3Tag{{Key: name, Value: value}}})
+	if err != nil {
+		return err
+	}
+	checksum := md5.Sum(body)
+	header := http.Header{"Content-Md5": {base64.StdEncoding.EncodeToString(checksum[:])}}
+
+	response, err := s.do(ctx, http.MethodPut, object, url.Values{"tagging": {""}}, header, body)
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// createUpload starts a multipart upload of object and returns its ID
+func (s *S3BlobStore) createUpload(ctx context.Context, object string) (string, error) {
+	response, err := s.do(ctx, http.MethodPost, object, url.Values{"uploads": {""}}, nil, []byte{})
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(response.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("S3 did not start a multipart upload of %s", object)
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart uploads data as a part of a multipart upload and returns its entity tag
+func (s *S3BlobStore) uploadPart(ctx context.Context, object string, uploadID string, number int, data []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+	response, err := s.do(ctx, http.MethodPut, object, query, nil, data)
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+	return response.Header.Get("ETag"), nil
+}
+
+// listParts returns the parts uploaded to a multipart upload
+func (s *S3BlobStore) listParts(ctx context.Context, object string, uploadID string) ([]s3Part, error) {
+	var parts []s3Part
+	marker := 0
+	for {
+		query := url.Values{"uploadId": {uploadID}, "part-number-marker": {strconv.Itoa(marker)}}
+		response, err := s.do(ctx, http.MethodGet, object, query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			IsTruncated          bool     `xml:"IsTruncated"`
+			NextPartNumberMarker int      `xml:"NextPartNumberMarker"`
+			Parts                []s3Part `xml:"Part"`
+		}
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the parts of %s: %w", object, err)
+		}
+		parts = append(parts, result.Parts...)
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+// completeUpload assembles the parts of a multipart upload into its object
+func (s *S3BlobStore) completeUpload(ctx context.Context, object string, uploadID string, parts []s3Part) error {
+	body, err := xml.Marshal(s3CompleteUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	response, err := s.do(ctx, http.MethodPost, object, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Completing can fail after S3 has responded 200 OK
+	var result struct {
+		XMLName xml.Name
+		s3Error
+	}
+	if err := xml.NewDecoder(response.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to complete the upload of %s: %w", object, err)
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("failed to complete the upload of %s: %s: %s", object, result.Code, result.Message)
+	}
+	return nil
+}
+
+// abortUpload discards a multipart upload and its parts
+func (s *S3BlobStore) abortUpload(ctx context.Context, object string, uploadID string) error {
+	response, err := s.do(ctx, http.MethodDelete, object, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if errors.Is(err, repository.ErrBlobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// objectURL returns the URL of object with query
+func (s *S3BlobStore) objectURL(object string, query url.Values) *url.URL {
+	u := *s.endpoint
+	if s.config.PathStyle {
+		u.Path = s.endpoint.Path + "/" + s.config.Bucket + "/" + object
+	} else {
+		u.Host = s.config.Bucket + "." + s.endpoint.Host
+		u.Path = s.endpoint.Path + "/" + object
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	u.RawQuery = s3CanonicalQuery(query)
+	return &u
+}
+
+// do signs and sends a request about object, returning the response when
+// it succeeds. Missing objects and uploads are reported as ErrBlobNotFound,
+// and failed preconditions as ErrBlobChanged.
+func (s *S3BlobStore) do(ctx context.Context, method string, object string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, s.objectURL(object, query).String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	s.signer.sign(request, s3PayloadHash(body), time.Now())
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s %s: %w", method, object, err)
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", repository.ErrBlobNotFound, object)
+	}
+	var failure s3Error
+	if err := xml.NewDecoder(io.LimitReader(response.Body, 64<<10)).Decode(&failure); err != nil || failure.Code == "" {
+		failure.Code = ""
+	}
+	// A failed precondition, or a conditional write racing another, means
+	// the object changed
+	if response.StatusCode == http.StatusPreconditionFailed || failure.Code == "ConditionalRequestConflict" {
+		return nil, fmt.Errorf("%w: %s", repository.ErrBlobChanged, object)
+	}
+	if failure.Code == "" {
+		return nil, fmt.Errorf("S3 %s %s: %s", method, object, response.Status)
+	}
+	return nil, fmt.Errorf("S3 %s %s: %s: %s", method, object, failure.Code, failure.Message)
+}
+
+// isEOF reports whether err marks the end of what io.ReadFull could read
+func isEOF(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// s3Error is the body of a failed S3 request
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// s3Part is an uploaded part of a multipart upload
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// s3CompleteUpload lists the parts that make up an object
+type s3CompleteUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []s3Part `xml:"Part"`
+}
+
+// s3Tagging is the tag set of an object
+type s3Tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Tags    []s3Tag  `xml:"TagSet>Tag"`
+}
+
+// s3Tag is a tag of an object
+type s3Tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+var (
+	_ repository.BlobStore  = (*S3BlobStore)(nil)
+	_ repository.BlobLinker = (*S3BlobStore)(nil)
+)
@@ -0,0 +1,475 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domainrepository "github.com/wepala/vine-pod/internal/domain/repository"
+	"github.com/wepala/vine-pod/internal/infrastructure/config"
+	"github.com/wepala/vine-pod/internal/infrastructure/repository"
+)
+
+func TestS3BlobStore(t *testing.T) {
+	ctx := context.Background()
+	const partSize = 5 << 20
+
+	withBucket := func(t *testing.T, configure func(*config.S3Config)) (*repository.S3BlobStore, *fakeS3) {
+		bucket := newFakeS3("pod")
+		server := httptest.NewServer(bucket)
+		t.Cleanup(server.Close)
+		cfg := config.S3Config{
+			Endpoint:        server.URL,
+			Region:          "us-east-1",
+			Bucket:          "pod",
+			Prefix:          "blobs/",
+			AccessKeyID:     "minio",
+			SecretAccessKey: "minio-secret",
+			PathStyle:       true,
+			PartSize:        partSize,
+			PresignExpiry:   time.Minute,
+		}
+		if configure != nil {
+			configure(&cfg)
+		}
+		store, err := repository.NewS3BlobStore(cfg)
+		require.NoError(t, err)
+		return store, bucket
+	}
+
+	read := func(t *testing.T, reader io.ReadCloser, err error) string {
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("stores small blobs as single objects", func(t *testing.T) {
+		// Arrange
+		store, bucket := withBucket(t, nil)
+
+		// Act
+		written, err := store.Put(ctx, "0a1b2c", strings.NewReader("hello world"))
+		require.NoError(t, err)
+		reader, getErr := store.Get(ctx, "0a1b2c")
+		data := read(t, reader, getErr)
+		reader, getErr = store.GetRange(ctx, "0a1b2c", 6, 5)
+		part := read(t, reader, getErr)
+
+		// Assert
+		assert.Equal(t, int64(11), written)
+		assert.Equal(t, "hello world", data)
+		assert.Equal(t, "world", part)
+		assert.Contains(t, bucket.names(), "blobs/0a1b2c")
+		assert.Zero(t, bucket.multipartUploads())
+	})
+
+	t.Run("uploads large blobs in parts", func(t *testing.T) {
+		// Arrange
+		store, bucket := withBucket(t, nil)
+		content := bytes.Repeat([]byte("0123456789abcdef"), (2*partSize+partSize/2)/16)
+
+		// Act
+		written, err := store.Put(ctx, "0a1b2c", bytes.NewReader(content))
+		require.NoError(t, err)
+		reader, getErr := store.Get(ctx, "0a1b2c")
+		data := read(t, reader, getErr)
+
+		// Assert
+		assert.Equal(t, int64(len(content)), written)
+		assert.Equal(t, string(content), data)
+		assert.Equal(t, 1, bucket.multipartUploads())
+		assert.Empty(t, bucket.pendingUploads(), "the upload is complete")
+	})
+
+	t.Run("stages appended blobs until they are committed", func(t *testing.T) {
+		// Arrange
+		store, _ := withBucket(t, nil)
+		first := bytes.Repeat([]byte("a"), 3<<20)
+		second := bytes.Repeat([]byte("b"), 4<<20)
+
+		// Act
+		_, err := store.Append(ctx, "0a1b2c", 0, bytes.NewReader(first))
+		require.NoError(t, err)
+		size, err := store.Append(ctx, "0a1b2c", int64(len(first)), bytes.NewReader(second))
+		require.NoError(t, err)
+		_, visibleErr := store.Get(ctx, "0a1b2c")
+		staged, err := store.Staged(ctx, "0a1b2c")
+		require.NoError(t, err)
+		_, replayErr := store.Append(ctx, "0a1b2c", 0, bytes.NewReader(first))
+		require.NoError(t, store.Commit(ctx, "0a1b2c"))
+		reader, getErr := store.Get(ctx, "0a1b2c")
+		data := read(t, reader, getErr)
+
+		// Assert
+		assert.Equal(t, int64(7<<20), size)
+		assert.Equal(t, int64(7<<20), staged)
+		assert.ErrorIs(t, visibleErr, domainrepository.ErrBlobNotFound, "staged blobs are not visible")
+		assert.ErrorIs(t, replayErr, domainrepository.ErrBlobOffset)
+		assert.Equal(t, string(first)+string(second), data)
+		_, err = store.Staged(ctx, "0a1b2c")
+		assert.ErrorIs(t, err, domainrepository.ErrBlobNotFound)
+	})
+
+	t.Run("resumes staged blobs after a restart", func(t *testing.T) {
+		// Arrange
+		var cfg config.S3Config
+		store, bucket := withBucket(t, func(c *config.S3Config) { cfg = *c })
+		content := bytes.Repeat([]byte("c"), partSize+10)
+		_, err := store.Append(ctx, "0a1b2c", 0, io.MultiReader(bytes.NewReader(content[:partSize+5]), &failingReader{}))
+		require.Error(t, err)
+		restarted, err := repository.NewS3BlobStore(cfg)
+		require.NoError(t, err)
+
+		// Act
+		staged, err := restarted.Staged(ctx, "0a1b2c")
+		require.NoError(t, err)
+		_, err = restarted.Append(ctx, "0a1b2c", staged, bytes.NewReader(content[staged:]))
+		require.NoError(t, err)
+		require.NoError(t, restarted.Commit(ctx, "0a1b2c"))
+		reader, getErr := restarted.Get(ctx, "0a1b2c")
+		data := read(t, reader, getErr)
+
+		// Assert
+		assert.Equal(t, int64(partSize+5), staged, "bytes read before the failure are kept")
+		assert.Equal(t, string(content), data)
+		assert.Empty(t, bucket.pendingUploads())
+	})
+
+	t.Run("deletes blobs and their staged uploads", func(t *testing.T) {
+		// Arrange
+		store, bucket := withBucket(t, nil)
+		_, err := store.Put(ctx, "0a1b2c", strings.NewReader("hello"))
+		require.NoError(t, err)
+		_, err = store.Append(ctx, "3d4e5f", 0, bytes.NewReader(make([]byte, partSize+1)))
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, store.Delete(ctx, "0a1b2c"))
+		require.NoError(t, store.Delete(ctx, "3d4e5f"))
+		_, err = store.Get(ctx, "0a1b2c")
+
+		// Assert
+		assert.ErrorIs(t, err, domainrepository.ErrBlobNotFound)
+		assert.Empty(t, bucket.names())
+		assert.Empty(t, bucket.pendingUploads())
+	})
+
+	t.Run("tags deleted blobs for a lifecycle rule to expire", func(t *testing.T) {
+		// Arrange
+		store, bucket := withBucket(t, func(cfg *config.S3Config) { cfg.DeleteTag = "vine-pod=deleted" })
+		_, err := store.Put(ctx, "0a1b2c", strings.NewReader("hello"))
+		require.NoError(t, err)
+
+		// Act
+		err = store.Delete(ctx, "0a1b2c")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"vine-pod": "deleted"}, bucket.tags("blobs/0a1b2c"))
+	})
+
+	t.Run("links to large blobs with presigned URLs", func(t *testing.T) {
+		// Arrange
+		store, _ := withBucket(t, func(cfg *config.S3Config) { cfg.PresignSize = 10 })
+		_, err := store.Put(ctx, "0a1b2c", strings.NewReader("hello world"))
+		require.NoError(t, err)
+
+		// Act
+		small, err := store.Link(ctx, "0a1b2c", 9, "text/plain")
+		require.NoError(t, err)
+		large, err := store.Link(ctx, "0a1b2c", 11, "text/plain")
+		require.NoError(t, err)
+		response, err := http.Get(large)
+		require.NoError(t, err)
+		data := read(t, response.Body, nil)
+
+		// Assert
+		assert.Empty(t, small, "small blobs are served by the pod")
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "hello world", data)
+		assert.Equal(t, "text/plain", response.Header.Get("Content-Type"))
+	})
+
+	t.Run("writes records only while they are unchanged", func(t *testing.T) {
+		// Arrange
+		store, _ := withBucket(t, nil)
+		require.NoError(t, store.PutIf(ctx, "0a1b2c_refs", "", []byte("1")))
+		data, tag, err := store.GetTagged(ctx, "0a1b2c_refs")
+		require.NoError(t, err)
+
+		// Act
+		created := store.PutIf(ctx, "0a1b2c_refs", "", []byte("1"))
+		updated := store.PutIf(ctx, "0a1b2c_refs", tag, []byte("2"))
+		stale := store.PutIf(ctx, "0a1b2c_refs", tag, []byte("3"))
+		latest, _, getErr := store.GetTagged(ctx, "0a1b2c_refs")
+
+		// Assert
+		assert.Equal(t, "1", string(data))
+		assert.NotEmpty(t, tag)
+		assert.ErrorIs(t, created, domainrepository.ErrBlobChanged)
+		assert.NoError(t, updated)
+		assert.ErrorIs(t, stale, domainrepository.ErrBlobChanged)
+		require.NoError(t, getErr)
+		assert.Equal(t, "2", string(latest))
+	})
+
+	t.Run("rejects incomplete configurations", func(t *testing.T) {
+		// Act
+		_, noBucket := repository.NewS3BlobStore(config.S3Config{Endpoint: "http://localhost:9000", PartSize: partSize})
+		_, smallParts := repository.NewS3BlobStore(config.S3Config{Endpoint: "http://localhost:9000", Bucket: "pod", PartSize: 1024})
+		_, badTag := repository.NewS3BlobStore(config.S3Config{Endpoint: "http://localhost:9000", Bucket: "pod", PartSize: partSize, DeleteTag: "deleted"})
+
+		// Assert
+		assert.Error(t, noBucket)
+		assert.Error(t, smallParts)
+		assert.Error(t, badTag)
+	})
+}
+
+// fakeS3 is an in-memory stand-in for one bucket of an S3-compatible
+// service, addressed path-style. It accepts any signed request.
+type fakeS3 struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	uploads map[string]*fakeUpload
+	started int
+}
+
+type fakeObject struct {
+	data []byte
+	meta http.Header
+	tags map[string]string
+}
+
+type fakeUpload struct {
+	object string
+	parts  map[int][]byte
+}
+
+// etag returns the entity tag of the object's bytes
+func (o *fakeObject) etag() string {
+	return fmt.Sprintf(`"%x"`, md5.Sum(o.data))
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{bucket: bucket, objects: map[string]*fakeObject{}, uploads: map[string]*fakeUpload{}}
+}
+
+// names returns the names of the objects in the bucket
+func (f *fakeS3) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := make([]string, 0, len(f.objects))
+	for name := range f.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// multipartUploads returns how many multipart uploads were started
+func (f *fakeS3) multipartUploads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.started
+}
+
+// pendingUploads returns the IDs of multipart uploads neither completed nor aborted
+func (f *fakeS3) pendingUploads() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make([]string, 0, len(f.uploads))
+	for id := range f.uploads {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// tags returns the tags of an object
+func (f *fakeS3) tags(name string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if object, ok := f.objects[name]; ok {
+		return object.tags
+	}
+	return nil
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Signature") == "" && !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") {
+		f.fail(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	name, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		f.fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, tagging := query["tagging"]
+	_, uploads := query["uploads"]
+	uploadID := query.Get("uploadId")
+	switch {
+	case r.Method == http.MethodPost && uploads:
+		f.started++
+		id := fmt.Sprintf("upload-%d", f.started)
+		f.uploads[id] = &fakeUpload{object: name, parts: map[int][]byte{}}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case uploadID != "":
+		upload, ok := f.uploads[uploadID]
+		if !ok || upload.object != name {
+			f.fail(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		f.serveUpload(w, r, uploadID, upload, body)
+	case r.Method == http.MethodPut && tagging:
+		object, ok := f.objects[name]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		var request struct {
+			Tags []struct {
+				Key   string `xml:"Key"`
+				Value string `xml:"Value"`
+			} `xml:"TagSet>Tag"`
+		}
+		if r.Header.Get("Content-Md5") == "" || xml.Unmarshal(body, &request) != nil {
+			f.fail(w, http.StatusBadRequest, "InvalidRequest")
+			return
+		}
+		object.tags = map[string]string{}
+		for _, tag := range request.Tags {
+			object.tags[tag.Key] = tag.Value
+		}
+	case r.Method == http.MethodPut:
+		if current, ok := f.objects[name]; r.Header.Get("If-None-Match") == "*" && ok ||
+			r.Header.Get("If-Match") != "" && (!ok || r.Header.Get("If-Match") != current.etag()) {
+			f.fail(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		meta := http.Header{}
+		for header, values := range r.Header {
+			if strings.HasPrefix(header, "X-Amz-Meta-") {
+				meta[header] = values
+			}
+		}
+		f.objects[name] = &fakeObject{data: body, meta: meta}
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := f.objects[name]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		for header, values := range object.meta {
+			w.Header()[header] = values
+		}
+		if contentType := query.Get("response-content-type"); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Header().Set("ETag", object.etag())
+		data := object.data
+		status := http.StatusOK
+		if rng := r.Header.Get("Range"); rng != "" {
+			var first, last int
+			if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &first, &last); err != nil || first >= len(data) {
+				f.fail(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+				return
+			}
+			data = data[first:min(last+1, len(data))]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// serveUpload serves the requests about a multipart upload
+func (f *fakeS3) serveUpload(w http.ResponseWriter, r *http.Request, id string, upload *fakeUpload, body []byte) {
+	switch r.Method {
+	case http.MethodPut:
+		number, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		if err != nil || number < 1 || number > 10000 {
+			f.fail(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		upload.parts[number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, id, number))
+	case http.MethodGet:
+		numbers := make([]int, 0, len(upload.parts))
+		for number := range upload.parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		fmt.Fprint(w, "<ListPartsResult><IsTruncated>false</IsTruncated>")
+		for _, number := range numbers {
+			fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"%s-%d"</ETag></Part>`, number, id, number)
+		}
+		fmt.Fprint(w, "</ListPartsResult>")
+	case http.MethodPost:
+		var request struct {
+			Parts []struct {
+				PartNumber int `xml:"PartNumber"`
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &request); err != nil || len(request.Parts) == 0 {
+			f.fail(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var data []byte
+		for i, part := range request.Parts {
+			chunk, ok := upload.parts[part.PartNumber]
+			if !ok || (i < len(request.Parts)-1 && len(chunk) < 5<<20) {
+				f.fail(w, http.StatusBadRequest, "InvalidPart")
+				return
+			}
+			data = append(data, chunk...)
+		}
+		f.objects[upload.object] = &fakeObject{data: data}
+		delete(f.uploads, id)
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case http.MethodDelete:
+		delete(f.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) fail(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, url.QueryEscape(http.StatusText(status)))
+}
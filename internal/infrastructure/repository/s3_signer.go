@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3Algorithm names the AWS Signature Version 4 scheme
+	s3Algorithm = "AWS4-HMAC-SHA256"
+
+	// s3UnsignedPayload replaces the payload hash of presigned requests
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// s3EmptyPayload is the SHA-256 hash of an empty body
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// s3Signer signs requests to S3 with AWS Signature Version 4
+type s3Signer struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+}
+
+// sign adds the headers authenticating request, whose body hashes to
+// payloadHash, as sent at now
+func (s *s3Signer) sign(request *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	request.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	names := make([]string, 0, len(request.Header)+1)
+	headers := map[string]string{"host": request.URL.Host}
+	names = append(names, "host")
+	for name, values := range request.Header {
+		name = strings.ToLower(name)
+		if name == "authorization" || name == "user-agent" || name == "content-length" {
+			continue
+		}
+		headers[name] = strings.Join(values, ",")
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	scope := s.scope(now)
+	signature := s.signature(now, strings.Join([]string{
+		request.Method,
+		s3EscapePath(request.URL.Path),
+		s3CanonicalQuery(request.URL.Query()),
+		canonical.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n"))
+	request.Header.Set("Authorization", s3Algorithm+" Credential="+s.accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// presign returns u with the query parameters that let anyone send a
+// request with method to it until expires after now
+func (s *s3Signer) presign(method string, u *url.URL, expires time.Duration, now time.Time) *url.URL {
+	now = now.UTC()
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.accessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.sessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	signature := s.signature(now, strings.Join([]string{
+		method,
+		s3EscapePath(u.Path),
+		s3CanonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n"))
+
+	presigned := *u
+	presigned.RawQuery = s3CanonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return &presigned
+}
+
+// scope returns the credential scope of requests signed at now
+func (s *s3Signer) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// signature signs canonicalRequest with a key derived for the day of now
+func (s *s3Signer) signature(now time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format("20060102T150405Z"),
+		s.scope(now),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + s.secretAccessKey)
+	for _, part := range []string{now.Format("20060102"), s.region, "s3", "aws4_request", stringToSign} {
+		key = s3HMAC(key, part)
+	}
+	return hex.EncodeToString(key)
+}
+
+// s3HMAC returns the HMAC-SHA256 of data under key
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3PayloadHash returns the hex encoded SHA-256 hash of body
+func s3PayloadHash(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
+
+// s3CanonicalQuery encodes query sorted by name and value, as signatures expect
+func s3CanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3EscapePath encodes every character of path but unreserved ones and '/'
+func s3EscapePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return s3Escape(path, false)
+}
+
+// s3Escape percent-encodes every byte of s other than letters, digits,
+// '-', '_', '.', '~' and, unless slash is set, '/'
+func s3Escape(s string, slash bool) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !slash) {
+			escaped.WriteByte(c)
+			continue
+		}
+		escaped.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return escaped.String()
+}
@@ -25,7 +25,7 @@ type SimpleKratosServer struct {
 }
 
 // NewSimpleKratosServer creates a new simplified Kratos HTTP server
-func NewSimpleKratosServer(cfg *config.Config, logger logger.Logger) (*SimpleKratosServer, error) {
+func NewSimpleKratosServer(cfg *config.Config, logger logger.Logger, solidSvc *service.SolidService) (*SimpleKratosServer, error) {
 	// Create Kratos logger adapter
 	kratosLogger := kratoslog.With(zaplog.NewLogger(logger.GetZapLogger()),
 		"service.name", "vine-pod",
@@ -35,7 +35,6 @@ func NewSimpleKratosServer(cfg *config.Config, logger logger.Logger) (*SimpleKra
 	// Create services
 	healthSvc := service.NewHealthService(cfg, logger)
 	versionSvc := service.NewVersionService(cfg, logger)
-	solidSvc := service.NewSolidService(cfg, logger)
 
 	// Create Kratos HTTP server with middleware
 	srv := kratoshttp.NewServer(
@@ -67,7 +66,7 @@ func NewSimpleKratosServer(cfg *config.Config, logger logger.Logger) (*SimpleKra
 		} else {
 			// Handle Solid protocol requests
 			switch r.Method {
-			case http.MethodGet:
+			case http.MethodGet, http.MethodHead:
 				if err := solidSvc.GetResource(r.Context(), w, r); err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 				}
@@ -83,6 +82,18 @@ func NewSimpleKratosServer(cfg *config.Config, logger logger.Logger) (*SimpleKra
 				if err := solidSvc.DeleteResource(r.Context(), w, r); err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 				}
+			case http.MethodPatch:
+				if err := solidSvc.PatchResource(r.Context(), w, r); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+			case "MOVE":
+				if err := solidSvc.MoveResource(r.Context(), w, r); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+			case "COPY":
+				if err := solidSvc.CopyResource(r.Context(), w, r); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
 			default:
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			}